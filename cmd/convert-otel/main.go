@@ -1,59 +1,123 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
 	"os"
-
 	"strings"
 
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
 	"github.com/pixelvide/otel-aws-log-parser/cmd/lambda/adapter"
 	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/exporter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/filter"
 	"github.com/pixelvide/otel-aws-log-parser/pkg/parser"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/processor"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/version"
 )
 
 func main() {
-	if len(os.Args) < 2 {
-		fmt.Fprintf(os.Stderr, "Usage: %s <log-file-path>\n", os.Args[0])
+	logType := flag.String("type", "", "log type: alb or waf (default detected from the file name, else alb)")
+	profile := flag.String("profile", "", "AWS profile for s3:// input (default the SDK's)")
+	region := flag.String("region", "", "AWS region for s3:// input (default the profile's)")
+	filterExpr := flag.String("filter", "", "only convert entries matching this filter rule expression, e.g. 'elb_status_code >= 500'")
+	outputFormat := flag.String("output-format", "otlp-json", "output format: otlp-json, otlp-proto, ndjson, csv or parquet")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [-type alb|waf] [-filter EXPR] [-output-format F] [-profile P] [-region R] <log-file-path|s3://bucket/key|->\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "Example: %s /path/to/alb.log.gz\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Example: aws s3 cp s3://bucket/alb.log.gz - | %s -type alb -\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Example: %s -output-format parquet /path/to/alb.log.gz > alb.parquet\n", os.Args[0])
+	}
+	flag.Parse()
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	switch *outputFormat {
+	case "otlp-json", "otlp-proto", "ndjson", "csv", "parquet":
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown -output-format %q, expected otlp-json, otlp-proto, ndjson, csv or parquet\n", *outputFormat)
 		os.Exit(1)
 	}
+	var expr *filter.Expr
+	if *filterExpr != "" {
+		var err error
+		if expr, err = filter.Compile(*filterExpr); err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid -filter: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	// Filter names resolve through the function's adapters, so expressions behave as in FILTER_RULES
+	matches := func(entry adapter.LogAdapter) bool {
+		return expr == nil || expr.Match(processor.FilterEnv(entry))
+	}
+
+	// "-" reads stdin and s3:// URIs stream the object; compression is detected from the content
+	filePath := flag.Arg(0)
+	input, err := openInput(filePath, *profile, *region)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening %s: %v\n", filePath, err)
+		os.Exit(1)
+	}
+	defer input.Close()
+	if *logType == "" {
+		switch lower := strings.ToLower(filePath); {
+		case strings.Contains(lower, "waflogs"):
+			*logType = "waf"
+		case strings.Contains(lower, "_net."):
+			*logType = "nlb"
+		default:
+			*logType = "alb"
+		}
+	}
 
-	filePath := os.Args[1]
 	var adapters []adapter.LogAdapter
 
-	if strings.Contains(strings.ToLower(filePath), "waflogs") {
-		fmt.Fprintf(os.Stderr, "Detected WAF log file\n")
-		entries, err := parser.ParseWAFLogFile(filePath)
+	switch *logType {
+	case "waf":
+		fmt.Fprintf(os.Stderr, "Converting WAF logs\n")
+		entries, err := parser.ParseWAFLogReader(input)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error parsing WAF file: %v\n", err)
 			os.Exit(1)
 		}
 		for _, e := range entries {
-			adapters = append(adapters, wapAdapter{e})
+			if matches(&processor.WAFAdapter{WAFLogEntry: e}) {
+				adapters = append(adapters, wapAdapter{e})
+			}
 		}
-	} else if strings.Contains(strings.ToLower(filePath), "_net.") {
-		fmt.Fprintf(os.Stderr, "Detected NLB log file\n")
-		// NLB parser works line by line usually, need to read file
-		// For demo simplicity, reuse ParseLogFile if it was generic, but it's not.
-		// Let's implement simple file reading for NLB here or skip if too complex.
-		// Actually, let's just implement WAF for now as requested by user.
+	case "nlb":
 		fmt.Fprintf(os.Stderr, "NLB file support not fully implemented in CLI yet\n")
 		os.Exit(1)
-	} else {
-		fmt.Fprintf(os.Stderr, "Assuming ALB log file\n")
-		entries, err := parser.ParseLogFile(filePath)
+	case "alb":
+		fmt.Fprintf(os.Stderr, "Converting ALB logs\n")
+		entries, err := parser.ParseLogReader(input)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error parsing ALB file: %v\n", err)
 			os.Exit(1)
 		}
 		for _, e := range entries {
-			adapters = append(adapters, albAdapter{e})
+			if matches(processor.ALBAdapter{ALBLogEntry: e}) {
+				adapters = append(adapters, albAdapter{e})
+			}
 		}
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown -type %q, expected alb or waf\n", *logType)
+		os.Exit(1)
 	}
 
-	fmt.Fprintf(os.Stderr, "Parsed %d log entries\n", len(adapters))
-	fmt.Fprintf(os.Stderr, "Converting to OTLP format...\n\n")
+	if expr != nil {
+		fmt.Fprintf(os.Stderr, "Parsed %d log entries matching the filter\n", len(adapters))
+	} else {
+		fmt.Fprintf(os.Stderr, "Parsed %d log entries\n", len(adapters))
+	}
+	fmt.Fprintf(os.Stderr, "Converting to %s...\n\n", *outputFormat)
 
 	// Group by resource
 	grouped := make(map[string]*resourceGroup)
@@ -86,7 +150,7 @@ func main() {
 				{
 					Scope: converter.Scope{
 						Name:    "lb-log-parser",
-						Version: "1.0.0",
+						Version: version.Info().String(),
 					},
 					LogRecords: group.LogRecords,
 				},
@@ -94,16 +158,71 @@ func main() {
 		})
 	}
 
-	// Output as JSON
-	encoder := json.NewEncoder(os.Stdout)
-	encoder.SetIndent("", "  ")
-
-	if err := encoder.Encode(payload); err != nil {
-		fmt.Fprintf(os.Stderr, "Error encoding JSON: %v\n", err)
+	if err := writeOutput(os.Stdout, *outputFormat, payload); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", *outputFormat, err)
 		os.Exit(1)
 	}
 }
 
+// writeOutput writes the payload as OTLP (indented JSON or an ExportLogsServiceRequest
+// protobuf) or as flat records, one per log entry
+func writeOutput(w io.Writer, format string, payload converter.OTLPPayload) error {
+	switch format {
+	case "otlp-json":
+		body, err := converter.MarshalJSON(payload)
+		if err != nil {
+			return err
+		}
+		var buf bytes.Buffer
+		if err := json.Indent(&buf, body, "", "  "); err != nil {
+			return err
+		}
+		buf.WriteByte('\n')
+		_, err = buf.WriteTo(w)
+		return err
+	case "otlp-proto":
+		body, err := converter.MarshalProto(payload)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(body)
+		return err
+	default:
+		// Flat rows are buffered so a failed conversion leaves no partial output behind
+		var buf bytes.Buffer
+		if err := exporter.WriteRecords(&buf, format, payload.ResourceLogs); err != nil {
+			return err
+		}
+		_, err := buf.WriteTo(w)
+		return err
+	}
+}
+
+// openInput opens a local file, stdin ("-") or an s3://bucket/key object, read with the
+// credentials of profile (default the SDK's) in region
+func openInput(path, profile, region string) (io.ReadCloser, error) {
+	if path == "-" {
+		return io.NopCloser(os.Stdin), nil
+	}
+	if !processor.IsS3URI(path) {
+		return os.Open(path)
+	}
+	bucket, key, err := processor.ParseS3URI(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := config.LoadDefaultConfig(context.Background(), config.WithSharedConfigProfile(profile), config.WithRegion(region))
+	if err != nil {
+		return nil, err
+	}
+	store := &processor.S3Store{Client: s3.NewFromConfig(cfg)}
+	obj, err := store.GetObject(context.Background(), bucket, key)
+	if err != nil {
+		return nil, err
+	}
+	return obj.Body, nil
+}
+
 type resourceGroup struct {
 	ResourceAttrs []converter.OTelAttribute
 	LogRecords    []converter.OTelLogRecord
@@ -115,11 +234,7 @@ type albAdapter struct {
 }
 
 func (a albAdapter) GetResourceKey() string {
-	arn := a.TargetGroupARN
-	if arn == "" || arn == "-" {
-		arn = a.ChosenCertARN
-	}
-	return arn
+	return a.ELB
 }
 
 func (a albAdapter) GetResourceAttributes() []converter.OTelAttribute {
@@ -0,0 +1,18 @@
+// Command daemon polls an SQS queue of S3 notifications outside Lambda, on ECS, EKS or a plain
+// host, until SIGTERM (see app.RunDaemon). It shares the pipeline of the Lambda function but
+// not its runtime.
+package main
+
+import (
+	"log/slog"
+	"os"
+
+	"github.com/pixelvide/otel-aws-log-parser/internal/app"
+)
+
+func main() {
+	if err := app.RunDaemon(os.Args[1:]); err != nil {
+		slog.New(slog.NewJSONHandler(os.Stdout, nil)).Error("Daemon failed", "error", err)
+		os.Exit(1)
+	}
+}
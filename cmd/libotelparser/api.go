@@ -0,0 +1,68 @@
+// Command libotelparser exposes the line parsers and OTel converters to non-Go tooling,
+// either as a C shared library (-buildmode=c-shared) or as a WebAssembly module (GOOS=js GOARCH=wasm).
+//
+// Both bindings return JSON strings:
+//   - parseLine(format, line):   {"entry": {...}}, {"entry": null} for blank/comment lines, or {"error": "..."}
+//   - convertLine(format, line): an OTLP/JSON logs payload ({"resourceLogs": [...]}) or {"error": "..."}
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/processor"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/version"
+)
+
+func main() {
+	serve()
+}
+
+type parseResult struct {
+	Entry any    `json:"entry"`
+	Error string `json:"error,omitempty"`
+}
+
+type errorResult struct {
+	Error string `json:"error"`
+}
+
+func parseLineJSON(format, line string) string {
+	entry, err := processor.ParseLine(format, line)
+	if err != nil {
+		return toJSON(errorResult{Error: err.Error()})
+	}
+	return toJSON(parseResult{Entry: entry})
+}
+
+func convertLineJSON(format, line string) string {
+	a, err := processor.AdapterForLine(format, line)
+	if err != nil {
+		return toJSON(errorResult{Error: err.Error()})
+	}
+	if a == nil {
+		// Blank and comment lines: the OTLP marshaler would omit the empty list
+		return `{"resourceLogs":[]}`
+	}
+
+	payload := converter.OTLPPayload{ResourceLogs: []converter.ResourceLog{{
+		Resource: converter.ResourceAttributes{Attributes: a.GetResourceAttributes()},
+		ScopeLogs: []converter.ScopeLog{{
+			Scope:      converter.Scope{Name: "otel-aws-log-parser", Version: version.Info().String()},
+			LogRecords: []converter.OTelLogRecord{a.ToOTel()},
+		}},
+	}}}
+	body, err := converter.MarshalJSON(payload)
+	if err != nil {
+		return toJSON(errorResult{Error: err.Error()})
+	}
+	return string(body)
+}
+
+func toJSON(v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		b, _ = json.Marshal(errorResult{Error: err.Error()})
+	}
+	return string(b)
+}
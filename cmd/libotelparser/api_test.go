@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+)
+
+const albLine = `h2 2025-12-04T00:55:01.294082Z app/test/12345 192.168.1.1:12345 10.0.0.1:80 0.001 0.010 0.001 200 200 100 500 "GET https://example.com:443/api/test HTTP/2.0" "TestAgent/1.0" ECDHE-RSA-AES128-GCM-SHA256 TLSv1.2 arn:aws:elasticloadbalancing:us-east-1:123456789012:targetgroup/test/abc "Root=1-58337262-36d228ad5d99923122bbe354" "example.com" "-" 0 2025-12-04T00:55:01.283000Z "forward" "-" "-" "10.0.0.1:80" "200" "-" "-" TID_1`
+
+func TestParseLineJSON(t *testing.T) {
+	var got struct {
+		Entry map[string]any `json:"entry"`
+		Error string         `json:"error"`
+	}
+	if err := json.Unmarshal([]byte(parseLineJSON("alb", albLine)), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Error != "" || got.Entry == nil {
+		t.Fatalf("parseLineJSON() = %+v, want an entry", got)
+	}
+
+	if out := parseLineJSON("alb", "#comment"); out != `{"entry":null}` {
+		t.Errorf("parseLineJSON(comment) = %s, want null entry", out)
+	}
+	if out := parseLineJSON("syslog", "x"); !strings.Contains(out, `"error"`) {
+		t.Errorf("parseLineJSON(unknown format) = %s, want error", out)
+	}
+}
+
+func TestConvertLineJSON(t *testing.T) {
+	var payload converter.OTLPPayload
+	if err := json.Unmarshal([]byte(convertLineJSON("alb", albLine)), &payload); err != nil {
+		t.Fatal(err)
+	}
+	if len(payload.ResourceLogs) != 1 {
+		t.Fatalf("got %d resource logs, want 1", len(payload.ResourceLogs))
+	}
+	record := payload.ResourceLogs[0].ScopeLogs[0].LogRecords[0]
+	if record.TraceID != "5833726236d228ad5d99923122bbe354" {
+		t.Errorf("TraceID = %q, want 5833726236d228ad5d99923122bbe354", record.TraceID)
+	}
+
+	if out := convertLineJSON("alb", "#comment"); out != `{"resourceLogs":[]}` {
+		t.Errorf("convertLineJSON(comment) = %s, want no resource logs", out)
+	}
+	if out := convertLineJSON("alb", "not a log line"); !strings.Contains(out, `"error"`) {
+		t.Errorf("convertLineJSON(invalid) = %s, want error", out)
+	}
+}
@@ -0,0 +1,33 @@
+//go:build cgo && !js
+
+package main
+
+/*
+#include <stdlib.h>
+*/
+import "C"
+
+import "unsafe"
+
+func serve() {}
+
+// ParseLogLine parses one line; the returned string must be released with FreeString
+//
+//export ParseLogLine
+func ParseLogLine(format, line *C.char) *C.char {
+	return C.CString(parseLineJSON(C.GoString(format), C.GoString(line)))
+}
+
+// ConvertLogLine converts one line to an OTLP/JSON payload; the returned string must be released with FreeString
+//
+//export ConvertLogLine
+func ConvertLogLine(format, line *C.char) *C.char {
+	return C.CString(convertLineJSON(C.GoString(format), C.GoString(line)))
+}
+
+// FreeString releases a string returned by this library
+//
+//export FreeString
+func FreeString(s *C.char) {
+	C.free(unsafe.Pointer(s))
+}
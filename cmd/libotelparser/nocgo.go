@@ -0,0 +1,13 @@
+//go:build !cgo && !js
+
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+func serve() {
+	fmt.Fprintln(os.Stderr, "libotelparser must be built with -buildmode=c-shared (CGO_ENABLED=1) or GOOS=js GOARCH=wasm")
+	os.Exit(1)
+}
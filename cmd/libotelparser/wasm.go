@@ -0,0 +1,24 @@
+//go:build js && wasm
+
+package main
+
+import "syscall/js"
+
+// serve registers otelParser.parseLine/convertLine on the JS global object and keeps the module alive
+func serve() {
+	wrap := func(fn func(format, line string) string) js.Func {
+		return js.FuncOf(func(this js.Value, args []js.Value) any {
+			if len(args) != 2 {
+				return toJSON(errorResult{Error: "expected (format, line)"})
+			}
+			return fn(args[0].String(), args[1].String())
+		})
+	}
+
+	js.Global().Set("otelParser", js.ValueOf(map[string]any{
+		"parseLine":   wrap(parseLineJSON),
+		"convertLine": wrap(convertLineJSON),
+	}))
+
+	select {}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/parser"
+)
+
+// lineParsers are the parsers bench can run, by -type name
+var lineParsers = map[string]func(string) error{
+	"alb":        func(line string) error { _, err := parser.ParseLogLine(line); return err },
+	"nlb":        func(line string) error { _, err := parser.ParseNLBLogLine(line); return err },
+	"waf":        func(line string) error { _, err := parser.ParseWAFLogLine(line); return err },
+	"cloudfront": func(line string) error { _, err := parser.ParseCloudFrontLogLine(line); return err },
+}
+
+// runBench implements the bench command, which parses a file's lines repeatedly with each
+// worker count and reports throughput, allocations and per-line latency, to catch parser
+// regressions before a release:
+//
+//	parse-demo bench [-type alb[,nlb,...]] [-n 5] [-workers 1,4] <log-file-path|s3://bucket/key|->
+func runBench(args []string) error {
+	fs := flag.NewFlagSet("bench", flag.ExitOnError)
+	types := fs.String("type", "", "comma-separated parsers to run: alb, nlb, waf, cloudfront (default detected from the file name, else alb)")
+	iterations := fs.Int("n", 5, "times each parser goes through the file")
+	workerList := fs.String("workers", "1,"+strconv.Itoa(runtime.GOMAXPROCS(0)), "comma-separated worker counts to run with")
+	profile := fs.String("profile", "", "AWS profile for s3:// input (default the SDK's)")
+	region := fs.String("region", "", "AWS region for s3:// input (default the profile's)")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s bench [-type alb|nlb|waf|cloudfront,...] [-n N] [-workers 1,4,...] [-profile P] [-region R] <log-file-path|s3://bucket/key|->\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Example: %s bench -n 10 -workers 1,2,4,8 /path/to/alb.log.gz\n", os.Args[0])
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(1)
+	}
+	if *iterations < 1 {
+		return fmt.Errorf("-n must be at least 1")
+	}
+	var workers []int
+	for _, s := range strings.Split(*workerList, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil || n < 1 {
+			return fmt.Errorf("invalid -workers %q, want positive counts like 1,4", *workerList)
+		}
+		workers = append(workers, n)
+	}
+
+	path := fs.Arg(0)
+	if *types == "" {
+		*types = "alb"
+		if strings.Contains(strings.ToLower(path), "waflogs") {
+			*types = "waf"
+		}
+	}
+	var names []string
+	for _, name := range strings.Split(*types, ",") {
+		name = strings.TrimSpace(name)
+		if lineParsers[name] == nil {
+			return fmt.Errorf("unknown -type %q, expected alb, nlb, waf or cloudfront", name)
+		}
+		names = append(names, name)
+	}
+
+	lines, err := readLines(path, *profile, *region)
+	if err != nil {
+		return err
+	}
+	if len(lines) == 0 {
+		return fmt.Errorf("%s has no log lines", path)
+	}
+	fmt.Fprintf(os.Stderr, "Benchmarking %d lines x %d iterations\n\n", len(lines), *iterations)
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "Parser\tWorkers\tLines/s\tAllocs/line\tBytes/line\tp50\tp99\tErrors\t\n")
+	for _, name := range names {
+		for _, n := range workers {
+			r := benchmark(lineParsers[name], lines, *iterations, n)
+			fmt.Fprintf(tw, "%s\t%d\t%.0f\t%.1f\t%.0f\t%s\t%s\t%d\t\n", name, n, r.linesPerSec(),
+				float64(r.mallocs)/float64(r.lines), float64(r.bytes)/float64(r.lines), r.p50, r.p99, r.errors)
+		}
+	}
+	return tw.Flush()
+}
+
+// readLines reads the decompressed lines of a file, without blank lines and # comments
+func readLines(path, profile, region string) ([]string, error) {
+	input, err := openInput(path, profile, region)
+	if err != nil {
+		return nil, err
+	}
+	defer input.Close()
+	r, err := parser.Decompress(input)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var lines []string
+	for _, line := range strings.Split(string(content), "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+	return lines, nil
+}
+
+// benchResult is one parser's run at one worker count
+type benchResult struct {
+	lines   int
+	errors  int
+	elapsed time.Duration
+	// mallocs and bytes are the heap allocations of the whole run
+	mallocs  uint64
+	bytes    uint64
+	p50, p99 time.Duration
+}
+
+func (r benchResult) linesPerSec() float64 {
+	return float64(r.lines) / r.elapsed.Seconds()
+}
+
+// benchmark parses the lines iterations times, split between workers. Latencies are recorded
+// into preallocated slices so that recording them does not show up as allocations.
+func benchmark(parse func(string) error, lines []string, iterations, workers int) benchResult {
+	total := len(lines) * iterations
+	latencies := make([][]time.Duration, workers)
+	errs := make([]int, workers)
+	for w := range latencies {
+		latencies[w] = make([]time.Duration, 0, total/workers+1)
+	}
+
+	runtime.GC()
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			// Worker w takes every workers-th line of every iteration
+			for i := w; i < total; i += workers {
+				t := time.Now()
+				if parse(lines[i%len(lines)]) != nil {
+					errs[w]++
+				}
+				latencies[w] = append(latencies[w], time.Since(t))
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&after)
+
+	all := slices.Concat(latencies...)
+	slices.Sort(all)
+	r := benchResult{
+		lines:   total,
+		elapsed: elapsed,
+		mallocs: after.Mallocs - before.Mallocs,
+		bytes:   after.TotalAlloc - before.TotalAlloc,
+		p50:     all[(len(all)-1)*50/100],
+		p99:     all[(len(all)-1)*99/100],
+	}
+	for _, n := range errs {
+		r.errors += n
+	}
+	return r
+}
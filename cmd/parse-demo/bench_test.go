@@ -0,0 +1,33 @@
+package main
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+)
+
+func TestBenchmark(t *testing.T) {
+	lines := []string{"ok", "bad", "ok"}
+	var calls atomic.Int64
+	parse := func(line string) error {
+		calls.Add(1)
+		if line == "bad" {
+			return errors.New("no match")
+		}
+		return nil
+	}
+
+	for _, workers := range []int{1, 2, 5} {
+		calls.Store(0)
+		r := benchmark(parse, lines, 4, workers)
+		if r.lines != 12 || calls.Load() != 12 {
+			t.Errorf("workers=%d: lines = %d, calls = %d, want 12", workers, r.lines, calls.Load())
+		}
+		if r.errors != 4 {
+			t.Errorf("workers=%d: errors = %d, want 4", workers, r.errors)
+		}
+		if r.p99 < r.p50 {
+			t.Errorf("workers=%d: p99 %s below p50 %s", workers, r.p99, r.p50)
+		}
+	}
+}
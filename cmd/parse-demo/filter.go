@@ -0,0 +1,39 @@
+package main
+
+import (
+	"github.com/pixelvide/otel-aws-log-parser/cmd/lambda/adapter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/filter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/parser"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/processor"
+)
+
+// compileFilter compiles a -filter expression; the empty expression keeps every entry
+func compileFilter(src string) (*filter.Expr, error) {
+	if src == "" {
+		return nil, nil
+	}
+	return filter.Compile(src)
+}
+
+// filterEntries keeps the entries expr matches. Names resolve through the function's adapter
+// for the entry, so an expression behaves the same here as in FILTER_RULES.
+func filterEntries[T any](expr *filter.Expr, entries []T, wrap func(T) adapter.LogAdapter) []T {
+	if expr == nil {
+		return entries
+	}
+	kept := entries[:0]
+	for _, e := range entries {
+		if expr.Match(processor.FilterEnv(wrap(e))) {
+			kept = append(kept, e)
+		}
+	}
+	return kept
+}
+
+func albEntry(e *parser.ALBLogEntry) adapter.LogAdapter { return processor.ALBAdapter{ALBLogEntry: e} }
+
+func wafEntry(e *parser.WAFLogEntry) adapter.LogAdapter { return &processor.WAFAdapter{WAFLogEntry: e} }
+
+func cloudFrontEntry(e *parser.CloudFrontLogEntry) adapter.LogAdapter {
+	return processor.CloudFrontAdapter{CloudFrontLogEntry: e}
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/parser"
+)
+
+// Synthetic identities: the documentation account and the RFC 5737 address ranges, so
+// generated logs never point at real resources or clients
+const (
+	synthAccount = "123456789012"
+	synthRegion  = "us-east-1"
+	synthALB     = "app/synthetic-alb/50dc6c495c0c9188"
+	synthNLB     = "net/synthetic-nlb/1234567890abcdef"
+	synthHost    = "www.example.com"
+	synthCFHost  = "d111111abcdef8.cloudfront.net"
+)
+
+var (
+	synthPaths      = []string{"/", "/index.html", "/api/orders", "/api/orders/42", "/api/users", "/login", "/static/app.js", "/static/logo.png", "/search", "/healthz"}
+	synthMethods    = []string{"GET", "GET", "GET", "GET", "POST", "POST", "PUT", "DELETE"}
+	synthUserAgents = []string{
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0 Safari/537.36",
+		"Mozilla/5.0 (Macintosh; Intel Mac OS X 14_2) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.2 Safari/605.1.15",
+		"Mozilla/5.0 (iPhone; CPU iPhone OS 17_2 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Mobile/15E148",
+		"curl/8.4.0",
+		"python-requests/2.31.0",
+	}
+	synthClientNets = []string{"192.0.2.", "198.51.100.", "203.0.113."}
+	synthEdges      = []string{"IAD89-C1", "LHR61-P2", "FRA56-C2", "NRT20-P1"}
+)
+
+// logGenerators write one synthetic line of each log type
+var logGenerators = map[string]func(*logGenerator, time.Time) string{
+	"alb":        (*logGenerator).alb,
+	"nlb":        (*logGenerator).nlb,
+	"cloudfront": (*logGenerator).cloudFront,
+	"waf":        (*logGenerator).waf,
+}
+
+// runGenerate implements the generate command, which writes realistic synthetic logs for load
+// testing the function and collectors without real customer data:
+//
+//	parse-demo generate -type alb|nlb|cloudfront|waf [-lines N] [-error-rate 0.05] [-from T] [-to T] [-seed S] [-o FILE]
+func runGenerate(args []string) error {
+	fs := flag.NewFlagSet("generate", flag.ExitOnError)
+	logType := fs.String("type", "alb", "log type: alb, nlb, cloudfront or waf")
+	lines := fs.Int("lines", 10000, "number of log lines")
+	errorRate := fs.Float64("error-rate", 0.05, "share of failed requests: 5xx responses, WAF blocks or NLB TLS handshake failures")
+	fromFlag := fs.String("from", "", "first timestamp, RFC 3339 (default an hour before -to)")
+	toFlag := fs.String("to", "", "last timestamp, RFC 3339 (default now)")
+	seed := fs.Uint64("seed", 0, "random seed for reproducible output (default random)")
+	output := fs.String("o", "-", "output file, gzipped when it ends in .gz; - for stdout")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s generate [-type alb|nlb|cloudfront|waf] [-lines N] [-error-rate R] [-from T] [-to T] [-seed S] [-o FILE]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Example: %s generate -type alb -lines 100000 -error-rate 0.02 -o alb.log.gz\n", os.Args[0])
+	}
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		fs.Usage()
+		os.Exit(1)
+	}
+	if logGenerators[*logType] == nil {
+		return fmt.Errorf("unknown -type %q, expected alb, nlb, cloudfront or waf", *logType)
+	}
+	if *lines < 1 {
+		return fmt.Errorf("-lines must be at least 1")
+	}
+	if *errorRate < 0 || *errorRate > 1 {
+		return fmt.Errorf("-error-rate must be between 0 and 1")
+	}
+	to := time.Now().UTC()
+	if *toFlag != "" {
+		t, err := time.Parse(time.RFC3339, *toFlag)
+		if err != nil {
+			return fmt.Errorf("invalid -to: %w", err)
+		}
+		to = t.UTC()
+	}
+	from := to.Add(-time.Hour)
+	if *fromFlag != "" {
+		t, err := time.Parse(time.RFC3339, *fromFlag)
+		if err != nil {
+			return fmt.Errorf("invalid -from: %w", err)
+		}
+		from = t.UTC()
+	}
+	if !from.Before(to) {
+		return fmt.Errorf("-from must be before -to")
+	}
+	if *seed == 0 {
+		*seed = rand.Uint64()
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "-" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+		if strings.HasSuffix(*output, ".gz") {
+			gz := gzip.NewWriter(f)
+			defer gz.Close()
+			w = gz
+		}
+	}
+
+	g := &logGenerator{rng: rand.New(rand.NewPCG(*seed, *seed)), errorRate: *errorRate}
+	bw := bufio.NewWriter(w)
+	if err := g.generate(bw, *logType, *lines, from, to); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Generated %d %s lines from %s to %s (seed %d)\n", *lines, *logType, from.Format(time.RFC3339), to.Format(time.RFC3339), *seed)
+	return bw.Flush()
+}
+
+// logGenerator produces log lines from a seeded source
+type logGenerator struct {
+	rng       *rand.Rand
+	errorRate float64
+}
+
+// generate writes n lines with timestamps spread in order across [from, to)
+func (g *logGenerator) generate(w io.Writer, logType string, n int, from, to time.Time) error {
+	line := logGenerators[logType]
+	if logType == "cloudfront" {
+		fmt.Fprintln(w, "#Version: 1.0")
+		fmt.Fprintln(w, "#Fields: date time x-edge-location sc-bytes c-ip cs-method cs(Host) cs-uri-stem sc-status cs(Referer) cs(User-Agent) cs-uri-query cs(Cookie) x-edge-result-type x-edge-request-id x-host-header cs-protocol cs-bytes time-taken x-forwarded-for ssl-protocol ssl-cipher x-edge-response-result-type cs-protocol-version fle-status fle-encrypted-fields c-port time-to-first-byte x-edge-detailed-result-type sc-content-type sc-content-len sc-range-start sc-range-end")
+	}
+	step := float64(to.Sub(from)) / float64(n)
+	for i := 0; i < n; i++ {
+		t := from.Add(time.Duration((float64(i) + g.rng.Float64()) * step))
+		if _, err := fmt.Fprintln(w, line(g, t)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *logGenerator) pick(values []string) string { return values[g.rng.IntN(len(values))] }
+
+func (g *logGenerator) clientIP() string {
+	return fmt.Sprintf("%s%d", g.pick(synthClientNets), 1+g.rng.IntN(254))
+}
+
+func (g *logGenerator) hex(n int) string {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(g.rng.IntN(256))
+	}
+	return hex.EncodeToString(b)
+}
+
+// status returns a response status: 5xx at the error rate, then about 5% 4xx and 5% 3xx
+func (g *logGenerator) status() int {
+	if g.rng.Float64() < g.errorRate {
+		return []int{500, 502, 503, 504}[g.rng.IntN(4)]
+	}
+	switch r := g.rng.Float64(); {
+	case r < 0.05:
+		return []int{400, 401, 403, 404, 404, 404}[g.rng.IntN(6)]
+	case r < 0.10:
+		return []int{301, 302, 304}[g.rng.IntN(3)]
+	}
+	return []int{200, 200, 200, 201, 204}[g.rng.IntN(5)]
+}
+
+// latency returns a long-tailed response time in seconds
+func (g *logGenerator) latency() float64 {
+	return 0.002 + g.rng.ExpFloat64()*0.05
+}
+
+func (g *logGenerator) alb(t time.Time) string {
+	status := g.status()
+	method, path := g.pick(synthMethods), g.pick(synthPaths)
+	target := fmt.Sprintf("10.0.%d.%d:8080", g.rng.IntN(4), 10+g.rng.IntN(20))
+	targetTime, responseTime := fmt.Sprintf("%.3f", g.latency()), "0.000"
+	targetStatus := fmt.Sprint(status)
+	if status >= 500 {
+		// The load balancer answered: the target failed, timed out or was missing
+		targetTime, responseTime, targetStatus = "-1", "-1", "-"
+	}
+	traceID := fmt.Sprintf("Root=1-%08x-%s", t.Unix(), g.hex(12))
+	return fmt.Sprintf(`https %s %s %s:%d %s 0.000 %s %s %d %s %d %d "%s https://%s:443%s HTTP/1.1" "%s" ECDHE-RSA-AES128-GCM-SHA256 TLSv1.2 arn:aws:elasticloadbalancing:%s:%s:targetgroup/synthetic-targets/73e2d6bc24d8a067 "%s" "%s" "arn:aws:acm:%s:%s:certificate/12345678-1234-1234-1234-123456789012" 0 %s "forward" "-" "-" "%s" "%s" "-" "-" TID_%s`,
+		t.Format("2006-01-02T15:04:05.000000Z"), synthALB, g.clientIP(), 1024+g.rng.IntN(64000), target,
+		targetTime, responseTime, status, targetStatus, 200+g.rng.IntN(800), 300+g.rng.IntN(20000),
+		method, synthHost, path, g.pick(synthUserAgents), synthRegion, synthAccount,
+		traceID, synthHost, synthRegion, synthAccount, t.Format("2006-01-02T15:04:05.000000Z"),
+		target, targetStatus, g.hex(16))
+}
+
+func (g *logGenerator) nlb(t time.Time) string {
+	handshake, alert, sent := fmt.Sprintf("%.3f", g.latency()/4), "-", 1000+g.rng.IntN(50000)
+	if g.rng.Float64() < g.errorRate {
+		handshake, alert, sent = "-", "0x28", 0
+	}
+	ts := t.Format("2006-01-02T15:04:05.000000Z")
+	return fmt.Sprintf("tls 2.0 %s %s listener/%s/abcdef0123456789 %s:%d 10.0.%d.%d:443 %.3f %s %d %d %s arn:aws:acm:%s:%s:certificate/12345678-1234-1234-1234-123456789012 - ECDHE-RSA-AES128-GCM-SHA256 tlsv12 - %s h2 h2 \"h2\",\"http/1.1\" %s",
+		ts, synthNLB, strings.TrimPrefix(synthNLB, "net/"), g.clientIP(), 1024+g.rng.IntN(64000), g.rng.IntN(4), 10+g.rng.IntN(20),
+		g.latency(), handshake, 200+g.rng.IntN(2000), sent, alert, synthRegion, synthAccount, synthHost, ts)
+}
+
+func (g *logGenerator) cloudFront(t time.Time) string {
+	status := g.status()
+	result := "Hit"
+	switch {
+	case status >= 500:
+		result = "Error"
+	case g.rng.Float64() < 0.3:
+		result = "Miss"
+	}
+	path, taken := g.pick(synthPaths), g.latency()
+	fields := []string{
+		t.Format("2006-01-02"), t.Format("15:04:05"), g.pick(synthEdges), fmt.Sprint(300 + g.rng.IntN(20000)), g.clientIP(),
+		g.pick(synthMethods), synthCFHost, path, fmt.Sprint(status), "-", url.PathEscape(g.pick(synthUserAgents)), "-", "-",
+		result, g.hex(28), synthHost, "https", fmt.Sprint(100 + g.rng.IntN(700)), fmt.Sprintf("%.3f", taken), "-",
+		"TLSv1.3", "TLS_AES_128_GCM_SHA256", result, "HTTP/2.0", "-", "-", fmt.Sprint(1024 + g.rng.IntN(64000)),
+		fmt.Sprintf("%.3f", taken*0.8), result, "text/html", fmt.Sprint(200 + g.rng.IntN(20000)), "-", "-",
+	}
+	return strings.Join(fields, "\t")
+}
+
+func (g *logGenerator) waf(t time.Time) string {
+	entry := parser.WAFLogEntry{
+		Timestamp:           t.UnixMilli(),
+		FormatVersion:       1,
+		WebACLID:            fmt.Sprintf("arn:aws:wafv2:%s:%s:regional/webacl/synthetic-acl/%s", synthRegion, synthAccount, "a1b2c3d4"),
+		TerminatingRuleID:   "Default_Action",
+		TerminatingRuleType: "REGULAR",
+		Action:              "ALLOW",
+		HTTPSourceName:      "ALB",
+		// Real logs carry empty lists rather than nulls
+		TerminatingRuleMatchDetails: []parser.MatchDetail{},
+		RuleGroupList:               []parser.RuleGroup{},
+		NonTerminatingMatchingRules: []parser.NonTerminatingRule{},
+		HTTPSourceID:                fmt.Sprintf("%s-%s", synthAccount, synthALB),
+		HTTPRequest: parser.HTTPRequest{
+			ClientIP:    g.clientIP(),
+			Country:     []string{"US", "DE", "GB", "JP", "BR"}[g.rng.IntN(5)],
+			Headers:     []parser.Header{{Name: "Host", Value: synthHost}, {Name: "User-Agent", Value: g.pick(synthUserAgents)}},
+			URI:         g.pick(synthPaths),
+			HTTPVersion: "HTTP/1.1",
+			HTTPMethod:  g.pick(synthMethods),
+			RequestID:   g.hex(8),
+		},
+	}
+	if g.rng.Float64() < g.errorRate {
+		entry.TerminatingRuleID = []string{"AWS-AWSManagedRulesCommonRuleSet", "AWS-AWSManagedRulesSQLiRuleSet", "AWS-AWSManagedRulesKnownBadInputsRuleSet"}[g.rng.IntN(3)]
+		entry.TerminatingRuleType = "MANAGED_RULE_GROUP"
+		entry.Action = "BLOCK"
+		code := 403
+		entry.ResponseCodeSent = &code
+	}
+	b, _ := json.Marshal(entry)
+	return string(b)
+}
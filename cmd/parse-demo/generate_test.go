@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"math/rand/v2"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/parser"
+)
+
+func TestLogGenerator_Parses(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(time.Hour)
+	const n = 2000
+
+	tests := []struct {
+		logType string
+		// failed reports whether a parsed line is one of the generated failures
+		failed func(line string) (bool, error)
+	}{
+		{"alb", func(line string) (bool, error) {
+			e, err := parser.ParseLogLine(line)
+			return err == nil && e.ELBStatusCode >= 500, err
+		}},
+		{"nlb", func(line string) (bool, error) {
+			e, err := parser.ParseNLBLogLine(line)
+			return err == nil && e.IncomingTLSAlert != "", err
+		}},
+		{"cloudfront", func(line string) (bool, error) {
+			e, err := parser.ParseCloudFrontLogLine(line)
+			return err == nil && e.SCStatus >= 500, err
+		}},
+		{"waf", func(line string) (bool, error) {
+			e, err := parser.ParseWAFLogLine(line)
+			return err == nil && e.Action == "BLOCK", err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.logType, func(t *testing.T) {
+			g := &logGenerator{rng: rand.New(rand.NewPCG(1, 1)), errorRate: 0.1}
+			var buf bytes.Buffer
+			if err := g.generate(&buf, tt.logType, n, from, to); err != nil {
+				t.Fatal(err)
+			}
+
+			failures, count := 0, 0
+			for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+				if strings.HasPrefix(line, "#") {
+					continue
+				}
+				count++
+				failed, err := tt.failed(line)
+				if err != nil {
+					t.Fatalf("generated line does not parse: %v\n%s", err, line)
+				}
+				if failed {
+					failures++
+				}
+			}
+			if count != n {
+				t.Errorf("generated %d lines, want %d", count, n)
+			}
+			if rate := float64(failures) / n; rate < 0.07 || rate > 0.13 {
+				t.Errorf("failure rate = %.3f, want about 0.1", rate)
+			}
+		})
+	}
+}
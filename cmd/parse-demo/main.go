@@ -1,39 +1,108 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
 	"github.com/pixelvide/otel-aws-log-parser/pkg/parser"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/processor"
 )
 
 func main() {
-	if len(os.Args) < 2 {
-		fmt.Fprintf(os.Stderr, "Usage: %s <log-file-path>\n", os.Args[0])
+	if len(os.Args) > 1 && os.Args[1] == "stats" {
+		if err := runStats(os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "bench" {
+		if err := runBench(os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "generate" {
+		if err := runGenerate(os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	logType := flag.String("type", "", "log type: alb, waf or cloudfront (default detected from the file name, else alb)")
+	profile := flag.String("profile", "", "AWS profile for s3:// input (default the SDK's)")
+	region := flag.String("region", "", "AWS region for s3:// input (default the profile's)")
+	filterExpr := flag.String("filter", "", "only output entries matching this filter rule expression, e.g. 'elb_status_code >= 500'")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [-type alb|waf|cloudfront] [-filter EXPR] [-profile P] [-region R] <log-file-path|s3://bucket/key|->\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "Example: %s /path/to/alb.log.gz\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Example: aws s3 cp s3://bucket/waf.log.gz - | %s -type waf -\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Example: %s -filter 'elb_status_code >= 500 && target_processing_time > 1.0' /path/to/alb.log.gz\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Summarize instead: %s stats -h\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Benchmark the parsers: %s bench -h\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Generate synthetic logs: %s generate -h\n", os.Args[0])
+	}
+	flag.Parse()
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	expr, err := compileFilter(*filterExpr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid -filter: %v\n", err)
 		os.Exit(1)
 	}
 
-	filePath := os.Args[1]
+	// "-" reads stdin and s3:// URIs stream the object; compression is detected from the content
+	filePath := flag.Arg(0)
+	input, err := openInput(filePath, *profile, *region)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening %s: %v\n", filePath, err)
+		os.Exit(1)
+	}
+	defer input.Close()
+	if *logType == "" && strings.Contains(strings.ToLower(filePath), "waflogs") {
+		*logType = "waf"
+	}
 
 	var entries interface{}
-	var count int
-	var err error
+	var count, matched int
 
-	if strings.Contains(strings.ToLower(filePath), "waflogs") {
-		fmt.Fprintf(os.Stderr, "Detected WAF log file\n")
+	switch *logType {
+	case "waf":
+		fmt.Fprintf(os.Stderr, "Parsing WAF logs\n")
 		var wafEntries []*parser.WAFLogEntry
-		wafEntries, err = parser.ParseWAFLogFile(filePath)
-		entries = wafEntries
+		wafEntries, err = parser.ParseWAFLogReader(input)
 		count = len(wafEntries)
-	} else {
-		fmt.Fprintf(os.Stderr, "Assuming ALB log file\n")
+		wafEntries = filterEntries(expr, wafEntries, wafEntry)
+		entries, matched = wafEntries, len(wafEntries)
+	case "cloudfront":
+		fmt.Fprintf(os.Stderr, "Parsing CloudFront logs\n")
+		var cfEntries []*parser.CloudFrontLogEntry
+		cfEntries, err = parser.ParseCloudFrontLogReader(input)
+		count = len(cfEntries)
+		cfEntries = filterEntries(expr, cfEntries, cloudFrontEntry)
+		entries, matched = cfEntries, len(cfEntries)
+	case "", "alb":
+		fmt.Fprintf(os.Stderr, "Parsing ALB logs\n")
 		var albEntries []*parser.ALBLogEntry
-		albEntries, err = parser.ParseLogFile(filePath)
-		entries = albEntries
+		albEntries, err = parser.ParseLogReader(input)
 		count = len(albEntries)
+		albEntries = filterEntries(expr, albEntries, albEntry)
+		entries, matched = albEntries, len(albEntries)
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown -type %q, expected alb, waf or cloudfront\n", *logType)
+		os.Exit(1)
 	}
 
 	if err != nil {
@@ -42,7 +111,11 @@ func main() {
 	}
 
 	// Print results
-	fmt.Fprintf(os.Stderr, "Parsed %d log entries from %s\n\n", count, filePath)
+	fmt.Fprintf(os.Stderr, "Parsed %d log entries from %s\n", count, filePath)
+	if expr != nil {
+		fmt.Fprintf(os.Stderr, "%d match the filter\n", matched)
+	}
+	fmt.Fprintln(os.Stderr)
 
 	// Output entries as JSON
 	encoder := json.NewEncoder(os.Stdout)
@@ -53,3 +126,28 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// openInput opens a local file, stdin ("-") or an s3://bucket/key object, read with the
+// credentials of profile (default the SDK's) in region
+func openInput(path, profile, region string) (io.ReadCloser, error) {
+	if path == "-" {
+		return io.NopCloser(os.Stdin), nil
+	}
+	if !processor.IsS3URI(path) {
+		return os.Open(path)
+	}
+	bucket, key, err := processor.ParseS3URI(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := config.LoadDefaultConfig(context.Background(), config.WithSharedConfigProfile(profile), config.WithRegion(region))
+	if err != nil {
+		return nil, err
+	}
+	store := &processor.S3Store{Client: s3.NewFromConfig(cfg)}
+	obj, err := store.GetObject(context.Background(), bucket, key)
+	if err != nil {
+		return nil, err
+	}
+	return obj.Body, nil
+}
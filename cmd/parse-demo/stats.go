@@ -0,0 +1,237 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/filter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/parser"
+)
+
+// runStats implements the stats command, which summarizes one or more log files in the terminal
+// without sending anything anywhere:
+//
+//	parse-demo stats [-type alb|waf|cloudfront] [-top N] [-filter EXPR] <log-file-path|s3://bucket/key|->...
+func runStats(args []string) error {
+	fs := flag.NewFlagSet("stats", flag.ExitOnError)
+	logType := fs.String("type", "", "log type: alb, waf or cloudfront (default detected from the file name, else alb)")
+	top := fs.Int("top", 10, "number of URLs and client IPs to list")
+	filterExpr := fs.String("filter", "", "only count entries matching this filter rule expression, e.g. 'elb_status_code >= 500'")
+	profile := fs.String("profile", "", "AWS profile for s3:// input (default the SDK's)")
+	region := fs.String("region", "", "AWS region for s3:// input (default the profile's)")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s stats [-type alb|waf|cloudfront] [-top N] [-filter EXPR] [-profile P] [-region R] <log-file-path|s3://bucket/key|->...\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Example: %s stats /path/to/*.log.gz\n", os.Args[0])
+	}
+	fs.Parse(args)
+	if fs.NArg() < 1 {
+		fs.Usage()
+		os.Exit(1)
+	}
+	expr, err := compileFilter(*filterExpr)
+	if err != nil {
+		return fmt.Errorf("invalid -filter: %w", err)
+	}
+
+	s := newLogStats()
+	for _, path := range fs.Args() {
+		if err := s.addFile(path, *logType, expr, *profile, *region); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return s.write(os.Stdout, *top)
+}
+
+// statsRequest is the part of a log entry the report looks at; latency is -1 when unknown
+type statsRequest struct {
+	status   int
+	url      string
+	clientIP string
+	latency  float64
+	sent     int64
+	received int64
+}
+
+// logStats aggregates requests across files
+type logStats struct {
+	total     int
+	classes   map[string]int
+	urls      map[string]int
+	clients   map[string]int
+	latencies []float64
+	sent      int64
+	received  int64
+}
+
+func newLogStats() *logStats {
+	return &logStats{classes: map[string]int{}, urls: map[string]int{}, clients: map[string]int{}}
+}
+
+// addFile parses one input of the given type and adds the entries expr matches
+func (s *logStats) addFile(path, logType string, expr *filter.Expr, profile, region string) error {
+	input, err := openInput(path, profile, region)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+	if logType == "" && strings.Contains(strings.ToLower(path), "waflogs") {
+		logType = "waf"
+	}
+
+	switch logType {
+	case "waf":
+		entries, err := parser.ParseWAFLogReader(input)
+		if err != nil {
+			return err
+		}
+		for _, e := range filterEntries(expr, entries, wafEntry) {
+			r := statsRequest{url: e.HTTPRequest.URI, clientIP: e.HTTPRequest.ClientIP, latency: -1}
+			if e.ResponseCodeSent != nil {
+				r.status = *e.ResponseCodeSent
+			}
+			s.add(r)
+		}
+	case "cloudfront":
+		entries, err := parser.ParseCloudFrontLogReader(input)
+		if err != nil {
+			return err
+		}
+		for _, e := range filterEntries(expr, entries, cloudFrontEntry) {
+			s.add(statsRequest{status: e.SCStatus, url: e.CSURIStem, clientIP: e.CIP, latency: e.TimeTaken, sent: e.SCBytes, received: e.CSBytes})
+		}
+	case "", "alb":
+		entries, err := parser.ParseLogReader(input)
+		if err != nil {
+			return err
+		}
+		for _, e := range filterEntries(expr, entries, albEntry) {
+			s.add(albRequest(e))
+		}
+	default:
+		return fmt.Errorf("unknown -type %q, expected alb, waf or cloudfront", logType)
+	}
+	return nil
+}
+
+// albRequest sums the three processing times; the load balancer logs -1 for all of them when
+// the request never got a response, so the latency is unknown then
+func albRequest(e *parser.ALBLogEntry) statsRequest {
+	latency := -1.0
+	if e.RequestProcessingTime >= 0 && e.TargetProcessingTime >= 0 && e.ResponseProcessingTime >= 0 {
+		latency = e.RequestProcessingTime + e.TargetProcessingTime + e.ResponseProcessingTime
+	}
+	url, _, _ := strings.Cut(e.RequestURL, "?")
+	return statsRequest{status: e.ELBStatusCode, url: url, clientIP: e.ClientIP, latency: latency, sent: e.SentBytes, received: e.ReceivedBytes}
+}
+
+func (s *logStats) add(r statsRequest) {
+	s.total++
+	s.classes[statusClass(r.status)]++
+	if r.url != "" {
+		s.urls[r.url]++
+	}
+	if r.clientIP != "" {
+		s.clients[r.clientIP]++
+	}
+	if r.latency >= 0 {
+		s.latencies = append(s.latencies, r.latency)
+	}
+	s.sent += r.sent
+	s.received += r.received
+}
+
+// statusClass buckets a status code as "2xx", "5xx", etc., and "-" when there was none
+func statusClass(status int) string {
+	if status < 100 || status > 599 {
+		return "-"
+	}
+	return fmt.Sprintf("%dxx", status/100)
+}
+
+// write prints the report; sections without data, like latency for WAF logs, are left out
+func (s *logStats) write(w io.Writer, top int) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Requests\t%d\n", s.total)
+	if s.sent > 0 || s.received > 0 {
+		fmt.Fprintf(tw, "Bytes sent\t%s\n", formatBytes(s.sent))
+		fmt.Fprintf(tw, "Bytes received\t%s\n", formatBytes(s.received))
+	}
+
+	fmt.Fprintf(tw, "\nStatus\tRequests\tShare\n")
+	classes := make([]string, 0, len(s.classes))
+	for c := range s.classes {
+		classes = append(classes, c)
+	}
+	sort.Strings(classes)
+	for _, c := range classes {
+		fmt.Fprintf(tw, "%s\t%d\t%.1f%%\n", c, s.classes[c], 100*float64(s.classes[c])/float64(s.total))
+	}
+
+	if len(s.latencies) > 0 {
+		sort.Float64s(s.latencies)
+		fmt.Fprintf(tw, "\nLatency\tSeconds\n")
+		for _, p := range []float64{50, 90, 95, 99, 100} {
+			name := fmt.Sprintf("p%g", p)
+			if p == 100 {
+				name = "max"
+			}
+			fmt.Fprintf(tw, "%s\t%.3f\n", name, percentile(s.latencies, p))
+		}
+	}
+
+	writeTop(tw, "URL", s.urls, top)
+	writeTop(tw, "Client IP", s.clients, top)
+	return tw.Flush()
+}
+
+// writeTop lists the n most frequent keys, ties broken alphabetically
+func writeTop(w io.Writer, title string, counts map[string]int, n int) {
+	if len(counts) == 0 || n <= 0 {
+		return
+	}
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	if len(keys) > n {
+		keys = keys[:n]
+	}
+	fmt.Fprintf(w, "\nTop %s\tRequests\n", title)
+	for _, k := range keys {
+		fmt.Fprintf(w, "%s\t%d\n", k, counts[k])
+	}
+}
+
+// percentile returns the nearest-rank p-th percentile of sorted values
+func percentile(sorted []float64, p float64) float64 {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
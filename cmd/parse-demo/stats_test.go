@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/parser"
+)
+
+func TestLogStats_Report(t *testing.T) {
+	s := newLogStats()
+	s.add(albRequest(&parser.ALBLogEntry{ELBStatusCode: 200, RequestURL: "https://example.com:443/a?x=1", ClientIP: "10.0.0.1", TargetProcessingTime: 0.2, SentBytes: 2048}))
+	s.add(albRequest(&parser.ALBLogEntry{ELBStatusCode: 200, RequestURL: "https://example.com:443/a?x=2", ClientIP: "10.0.0.2", TargetProcessingTime: 0.4, SentBytes: 1024}))
+	s.add(albRequest(&parser.ALBLogEntry{ELBStatusCode: 502, RequestURL: "https://example.com:443/b", ClientIP: "10.0.0.1", RequestProcessingTime: -1, TargetProcessingTime: -1, ResponseProcessingTime: -1}))
+
+	if len(s.latencies) != 2 {
+		t.Errorf("latencies = %v, want the failed request left out", s.latencies)
+	}
+
+	var buf bytes.Buffer
+	if err := s.write(&buf, 1); err != nil {
+		t.Fatal(err)
+	}
+	report := buf.String()
+	for _, want := range []string{
+		"Requests        3",
+		"Bytes sent      3.0 KiB",
+		"2xx     2         66.7%",
+		"5xx     1         33.3%",
+		"p50      0.200",
+		"max      0.400",
+		// The query string is dropped, so both requests to /a count
+		"https://example.com:443/a  2",
+		"10.0.0.1       2",
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report is missing %q:\n%s", want, report)
+		}
+	}
+	if strings.Contains(report, "/b") || strings.Contains(report, "10.0.0.2") {
+		t.Errorf("report lists more than the top entry:\n%s", report)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	values := []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	tests := []struct {
+		p    float64
+		want float64
+	}{
+		{0, 1},
+		{50, 5},
+		{90, 9},
+		{99, 10},
+		{100, 10},
+	}
+	for _, tt := range tests {
+		if got := percentile(values, tt.p); got != tt.want {
+			t.Errorf("percentile(%g) = %g, want %g", tt.p, got, tt.want)
+		}
+	}
+}
+
+func TestStatusClass(t *testing.T) {
+	tests := map[int]string{200: "2xx", 304: "3xx", 460: "4xx", 503: "5xx", 0: "-"}
+	for status, want := range tests {
+		if got := statusClass(status); got != want {
+			t.Errorf("statusClass(%d) = %q, want %q", status, got, want)
+		}
+	}
+}
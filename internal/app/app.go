@@ -0,0 +1,1022 @@
+// Package app is the log pipeline shared by the Lambda function (cmd/lambda) and the SQS
+// daemon (cmd/daemon): the event handlers, the stream sink and the commands run by the
+// binaries. Configure reads the environment before any of them runs.
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/pixelvide/otel-aws-log-parser/cmd/lambda/adapter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/config"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/exporter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/parser"
+	logpipeline "github.com/pixelvide/otel-aws-log-parser/pkg/pipeline"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/processor"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/tracing"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/useragent"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/version"
+)
+
+var (
+	store           processor.ObjectStore
+	s3Pool          *processor.S3ClientPool
+	exp             exporter.Exporter
+	metricsExp      exporter.MetricsExporter
+	divertExp       exporter.Exporter
+	dlq             exporter.DeadLetterQueue
+	replayExp       exporter.Exporter
+	exportBreaker   *exporter.CircuitBreaker
+	pipeline        *pipelineSwitch
+	maxBatchSize    int
+	logger          *slog.Logger
+	maxConcurrent   int
+	readOpts        processor.ReadOptions
+	cardLimit       int
+	cardAction      converter.CardinalityAction
+	priorityLanes   bool
+	aggregateMode   string
+	continueOnError bool
+	kinesisFormat   string
+	kinesisDecor    entryDecorator
+	logsDecor       entryDecorator
+	userAgents      *useragent.Cache
+	anonymizer      *converter.IPAnonymizer
+	streamBuffer    int
+	deadlineMargin  time.Duration
+	checkpoints     processor.CheckpointStore
+	dedup           processor.DedupStore
+	checkpointEvery int64
+	sampler         *processor.ObjectSampler
+	registry        *processor.Registry
+	processors      []string
+	largeObjects    *largeObjectQueue
+)
+
+func init() {
+	// Initialize structured logger (JSON format)
+	logger = slog.New(slog.NewJSONHandler(os.Stdout, nil))
+	slog.SetDefault(logger)
+}
+
+// Configure reads the environment and builds the store, processors and exporters. The Lambda
+// function and the server run it on start; commands run it once their flags are parsed, so
+// -h and flag errors never depend on the environment.
+func Configure() error {
+	build := version.Info()
+	logger.Info("Cold start", "version", build.Version, "commit", build.Commit, "build_date", build.BuildDate, "go_version", build.GoVersion)
+
+	if err := loadConfigFile(); err != nil {
+		return fmt.Errorf("failed to load CONFIG_FILE: %w", err)
+	}
+	var err error
+	if appConfig, err = newAppConfigSource(); err != nil {
+		return fmt.Errorf("failed to create AppConfig client: %w", err)
+	}
+	if err := loadDynamicSettings(context.Background()); err != nil {
+		return fmt.Errorf("failed to load AppConfig configuration: %w", err)
+	}
+
+	// Validate the core settings up front so a misconfigured function fails with every problem
+	// at once instead of on its first batch
+	cfg, err := config.Load(settings)
+	if err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+	dryRun = newDryRunExporter()
+
+	// Initialize object store: a local directory (sandbox/fixtures) or S3
+	if dir := getEnv("LOCAL_SOURCE_DIR", ""); dir != "" {
+		store = &processor.LocalStore{Root: dir}
+	} else {
+		awsCfg, err := awsConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load AWS config: %w", err)
+		}
+		bucketRoles, err := processor.ParseBucketRoles(getEnv("S3_ASSUME_ROLE_BUCKETS", ""))
+		if err != nil {
+			return fmt.Errorf("invalid S3_ASSUME_ROLE_BUCKETS: %w", err)
+		}
+		// Log buckets of other accounts are read through an assumed role
+		s3Pool = &processor.S3ClientPool{
+			Config:      awsCfg,
+			Role:        getEnv("S3_ASSUME_ROLE_ARN", ""),
+			BucketRoles: bucketRoles,
+			ExternalID:  getEnv("S3_ASSUME_ROLE_EXTERNAL_ID", ""),
+		}
+		sseKey, err := loadSSECustomerKey()
+		if err != nil {
+			return fmt.Errorf("invalid S3_SSE_C_KEY: %w", err)
+		}
+		store = &processor.S3Store{
+			ClientFor:       s3Pool.Client,
+			SSECustomerKey:  sseKey,
+			PartSize:        int64(getEnvInt("S3_PART_SIZE_MB", 16)) << 20,
+			PartConcurrency: getEnvInt("S3_PART_CONCURRENCY", 1),
+		}
+	}
+
+	// Load configuration from environment
+	maxBatchSize = cfg.MaxBatchSize
+	batchWindow = time.Duration(getEnvInt("BATCH_WINDOW_SECONDS", 0)) * time.Second
+	maxConcurrent = cfg.MaxConcurrent
+	cardLimit = getEnvInt("CARDINALITY_LIMIT", 100)
+	cardAction = converter.CardinalityAction(getEnv("CARDINALITY_ACTION", string(converter.CardinalityWarn)))
+	if statusSeverity, err := converter.ParseStatusSeverity(getEnv("STATUS_SEVERITY_MAP", "")); err != nil {
+		return fmt.Errorf("invalid STATUS_SEVERITY_MAP: %w", err)
+	} else {
+		converter.SetStatusSeverity(statusSeverity)
+	}
+	converter.SetKeepPlaceholders(getEnvBool("KEEP_PLACEHOLDER_ATTRIBUTES", false))
+	if err := processor.SetALBGrouping(strings.ToLower(getEnv("GROUP_BY", processor.GroupByELB))); err != nil {
+		return fmt.Errorf("invalid GROUP_BY: %w", err)
+	}
+	converter.SetRedactor(converter.NewRedactor(redactNames("REDACT_PARAMS", converter.DefaultRedactParams), redactNames("REDACT_COOKIES", []string{"*"})))
+	if getEnvBool("USER_AGENT_ENRICHMENT", false) {
+		userAgents = &useragent.Cache{Size: getEnvInt("USER_AGENT_CACHE_SIZE", 10000)}
+	}
+	priorityLanes = getEnvBool("PRIORITY_LANES", false)
+	continueOnError = getEnvBool("CONTINUE_ON_ERROR", false)
+	kinesisFormat = strings.ToLower(getEnv("KINESIS_LOG_FORMAT", processor.FormatAuto))
+	if kinesisFormat != processor.FormatAuto && !slices.Contains(processor.Formats, kinesisFormat) {
+		return fmt.Errorf("invalid KINESIS_LOG_FORMAT %q, expected one of %v", kinesisFormat, append([]string{processor.FormatAuto}, processor.Formats...))
+	}
+	if anonymizer, err = loadIPAnonymizer(); err != nil {
+		return fmt.Errorf("invalid IP_ANONYMIZATION: %w", err)
+	}
+	if geoDB, err = loadGeoIP(); err != nil {
+		return fmt.Errorf("failed to load GEOIP_DATABASES: %w", err)
+	}
+	if threatFeed, err = loadThreatFeed(); err != nil {
+		return fmt.Errorf("failed to load THREAT_IP_LISTS: %w", err)
+	}
+	if attrMappings, err = loadAttributeMappings(); err != nil {
+		return fmt.Errorf("invalid attribute mapping: %w", err)
+	}
+	if filterRules, err = loadFilterRules(); err != nil {
+		return fmt.Errorf("invalid filter rules: %w", err)
+	}
+	if resourceTags, err = newResourceTags(); err != nil {
+		return fmt.Errorf("failed to create ELB client: %w", err)
+	}
+	if distributions, err = newDistributions(); err != nil {
+		return fmt.Errorf("failed to create CloudFront client: %w", err)
+	}
+	if kinesisDecor, err = sourceDecorator("KINESIS"); err != nil {
+		return fmt.Errorf("invalid KINESIS decoration settings: %w", err)
+	}
+	if logsDecor, err = sourceDecorator("CLOUDWATCH_LOGS"); err != nil {
+		return fmt.Errorf("invalid CLOUDWATCH_LOGS decoration settings: %w", err)
+	}
+	streamBuffer = cfg.StreamBuffer
+	deadlineMargin = cfg.DeadlineMargin
+	// A dry run leaves the tables alone, so the objects it reads are still processed for real later
+	if table := getEnv("CHECKPOINT_TABLE", ""); table != "" && dryRun == nil {
+		awsCfg, err := awsConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load AWS config: %w", err)
+		}
+		checkpoints = &processor.DynamoCheckpointStore{
+			Client: dynamodb.NewFromConfig(awsCfg),
+			Table:  table,
+			TTL:    time.Duration(getEnvInt("CHECKPOINT_TTL_HOURS", 24)) * time.Hour,
+		}
+		checkpointEvery = int64(getEnvInt("CHECKPOINT_EVERY_LINES", 100000))
+	}
+	if table := getEnv("DEDUP_TABLE", ""); table != "" && dryRun == nil {
+		awsCfg, err := awsConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load AWS config: %w", err)
+		}
+		dedup = &processor.DynamoDedupStore{
+			Client: dynamodb.NewFromConfig(awsCfg),
+			Table:  table,
+			TTL:    time.Duration(getEnvInt("DEDUP_TTL_HOURS", 72)) * time.Hour,
+			Lease:  time.Duration(getEnvInt("DEDUP_LEASE_SECONDS", 900)) * time.Second,
+		}
+	}
+
+	sampler, err = processor.ParseObjectSampling(getEnv("OBJECT_SAMPLING", ""))
+	if err != nil {
+		return fmt.Errorf("invalid OBJECT_SAMPLING: %w", err)
+	}
+
+	// Guard rails against oversized objects and re-delivery of historical logs
+	var limits processor.ObjectLimits
+	limits.MaxBytes = int64(getEnvInt("MAX_OBJECT_BYTES", 0))
+	if age := getEnv("MAX_OBJECT_AGE", ""); age != "" {
+		if limits.MaxAge, err = time.ParseDuration(age); err != nil || limits.MaxAge <= 0 {
+			return fmt.Errorf("invalid MAX_OBJECT_AGE %q, expected a positive duration such as 168h", age)
+		}
+	}
+	if queueURL := getEnv("LARGE_OBJECT_QUEUE_URL", ""); queueURL != "" && dryRun == nil {
+		awsCfg, err := awsConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load AWS config: %w", err)
+		}
+		largeObjects = &largeObjectQueue{Client: sqs.NewFromConfig(awsCfg), QueueURL: queueURL}
+	}
+
+	retryBudget = newRetryBudget()
+	exportLimiter = exporter.NewRateLimiter(float64(getEnvInt("MAX_EXPORT_RPS", 0)), int64(getEnvInt("MAX_EXPORT_BYTES_PER_SEC", 0)))
+	optional := getEnvList("EXPORTER_OPTIONAL")
+	exp, err = newExporters(strings.Join(cfg.Exporters, ","), optional)
+	if err != nil {
+		return fmt.Errorf("failed to initialize exporter: %w", err)
+	}
+	if dlq, err = newDeadLetterQueue(); err != nil {
+		return fmt.Errorf("failed to initialize dead-letter queue: %w", err)
+	}
+	replayExp = exp
+	if threshold := getEnvInt("CIRCUIT_BREAKER_THRESHOLD", 0); threshold > 0 {
+		// Inside the dead-letter queue, so the batches of a tripped breaker are dead-lettered
+		exportBreaker = exporter.NewCircuitBreaker(exp, threshold, logger)
+		exp = exportBreaker
+	}
+	if dlq != nil {
+		// Batches the exporters give up on are parked for replay-dlq instead of failing the event
+		exp = exporter.NewDeadLetterExporter(exp, dlq, logger)
+	}
+	if kind := getEnv("PIPELINE_DIVERT_EXPORTER", ""); kind != "" {
+		if divertExp, err = newExporters(kind, optional); err != nil {
+			return fmt.Errorf("failed to initialize divert exporter: %w", err)
+		}
+	}
+	if name := getEnv("PIPELINE_SWITCH_PARAMETER", ""); name != "" {
+		awsCfg, err := awsConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load AWS config: %w", err)
+		}
+		ttl := time.Duration(getEnvInt("PIPELINE_SWITCH_CACHE_SECONDS", 30)) * time.Second
+		pipeline = newSSMSwitch(ssm.NewFromConfig(awsCfg), name, ttl)
+	}
+	albSpans = getEnvBool("ALB_SPANS", false)
+	if getEnvBool("TRACES_ENABLED", false) {
+		tracer = &tracing.Tracer{}
+	}
+	if tracer != nil || albSpans {
+		if tracesExp, err = newTracesExporter(); err != nil {
+			return fmt.Errorf("failed to initialize traces exporter: %w", err)
+		}
+	}
+	if selfMetrics, flushSelf, err = newSelfMetrics(); err != nil {
+		return fmt.Errorf("failed to initialize self metrics: %w", err)
+	}
+	aggregateMode = strings.ToLower(getEnv("AGGREGATE_MODE", aggregateLogs))
+	if !slices.Contains([]string{aggregateLogs, aggregateMetrics, aggregateBoth}, aggregateMode) {
+		return fmt.Errorf("invalid AGGREGATE_MODE %q", aggregateMode)
+	}
+	if getEnvBool("METRICS_ENABLED", false) || aggregateMode != aggregateLogs {
+		if metricsExp, err = newMetricsExporter(); err != nil {
+			return fmt.Errorf("failed to initialize metrics exporter: %w", err)
+		}
+	}
+
+	sourceAttributes := settings.OneOf("SOURCE_ATTRIBUTES", string(processor.SourceAttributesOff),
+		string(processor.SourceAttributesOff), string(processor.SourceAttributesResource), string(processor.SourceAttributesRecord))
+	sourceLink := settings.OneOf("SOURCE_LINK", string(processor.SourceLinkOff),
+		string(processor.SourceLinkOff), string(processor.SourceLinkURI), string(processor.SourceLinkPresigned))
+	readOpts = processor.ReadOptions{
+		MaxBatchSize:     maxBatchSize,
+		MaxConcurrent:    maxConcurrent,
+		RecordSequence:   getEnvBool("RECORD_SEQUENCE", false),
+		Ordered:          getEnvBool("ORDERED", false),
+		SourceAttributes: processor.SourceAttributesMode(sourceAttributes),
+		SourceLink:       processor.SourceLinkMode(sourceLink),
+		SourceLinkTTL:    time.Duration(getEnvInt("SOURCE_LINK_TTL_SECONDS", int(processor.DefaultSourceLinkTTL/time.Second))) * time.Second,
+		Limits:           limits,
+		GeoIP:            geoDB,
+		Tags:             resourceTags,
+		Distributions:    distributions,
+		UserAgents:       userAgents,
+		Anonymizer:       anonymizer,
+	}
+	// Priority lanes send error records ahead of the rest, which is the opposite of ordering
+	if readOpts.Ordered && priorityLanes {
+		return fmt.Errorf("ORDERED cannot be combined with PRIORITY_LANES, which exports priority records ahead of the others")
+	}
+
+	processors, processorOverrides = cfg.Processors, cfg.Overrides
+	if registry, err = buildRegistry(); err != nil {
+		return fmt.Errorf("invalid processor settings: %w", err)
+	}
+
+	// Feature settings read above are checked as they are read; malformed numbers and booleans
+	// no longer fall back to their defaults silently
+	if err := settings.Err(); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+	logger.Info("Effective configuration", "config", settings.Effective())
+	if dryRun != nil {
+		logger.Warn("Dry run: nothing is exported and the checkpoint, dedup and large object queues are not used")
+	}
+	return nil
+}
+
+// buildRegistry creates the processors enabled by PROCESSORS_ENABLED; objects of the others are
+// skipped. <TYPE>_KEY_PATTERN and FORCE_PROCESSOR route objects the processors would not match.
+func buildRegistry() (*processor.Registry, error) {
+	reg := processor.NewRegistry()
+	// Registered in a fixed order, as the first processor matching a key wins
+	for _, name := range config.Processors {
+		if !slices.Contains(processors, name) {
+			continue
+		}
+		prefix := strings.ToUpper(name)
+		var p processor.LogProcessor
+		var err error
+		switch name {
+		case "alb":
+			proc := &processor.ALBProcessor{}
+			proc.ReadOptions, err = processorReadOptions(prefix, processor.DefaultMaxLineBytes, processor.ALBAdapter{ALBLogEntry: &parser.ALBLogEntry{}})
+			p = proc
+		case "nlb":
+			proc := &processor.NLBProcessor{}
+			proc.ReadOptions, err = processorReadOptions(prefix, processor.DefaultMaxLineBytes, processor.NLBAdapter{NLBLogEntry: &parser.NLBLogEntry{}})
+			p = proc
+		case "cloudfront":
+			proc := &processor.CloudFrontProcessor{}
+			proc.ReadOptions, err = processorReadOptions(prefix, processor.DefaultMaxLineBytes, processor.CloudFrontAdapter{CloudFrontLogEntry: &parser.CloudFrontLogEntry{}})
+			p = proc
+		case "waf":
+			// WAF records embed full request headers and match details and routinely exceed 1MB
+			proc := &processor.WAFProcessor{}
+			proc.ReadOptions, err = processorReadOptions(prefix, 16*1024*1024, &processor.WAFAdapter{WAFLogEntry: &parser.WAFLogEntry{}})
+			p = proc
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		reg.Register(p)
+	}
+
+	// Key patterns and forced buckets take precedence over the processors' own matching
+	for _, name := range config.Processors {
+		pattern := getEnv(strings.ToUpper(name)+"_KEY_PATTERN", "")
+		if pattern == "" || !slices.Contains(processors, name) {
+			continue
+		}
+		if err := reg.MatchKeys(name, pattern); err != nil {
+			return nil, err
+		}
+	}
+	forced, err := processor.ParseBucketProcessors(getEnv("FORCE_PROCESSOR", ""))
+	if err != nil {
+		return nil, err
+	}
+	for bucket, name := range forced {
+		if err := reg.Force(bucket, name); err != nil {
+			return nil, fmt.Errorf("FORCE_PROCESSOR %s: %w", bucket, err)
+		}
+	}
+	return reg, nil
+}
+
+// processorReadOptions applies the <PREFIX>_MAX_BATCH_SIZE, <PREFIX>_MAX_CONCURRENT,
+// <PREFIX>_SCANNER_BUFFER_BYTES, <PREFIX>_MAX_LINE_BYTES and <PREFIX>_GROUP_KEY overrides and
+// gives the processor its own read stats; sample is an empty entry used to validate the group
+// key fields
+func processorReadOptions(prefix string, defaultMaxLine int, sample adapter.LogAdapter) (processor.ReadOptions, error) {
+	opts := readOpts
+	if overrides, ok := processorOverrides[strings.ToLower(prefix)]; ok {
+		opts.MaxBatchSize, opts.MaxConcurrent = overrides.MaxBatchSize, overrides.MaxConcurrent
+	}
+	opts.ScannerBufferBytes = getEnvInt(prefix+"_SCANNER_BUFFER_BYTES", processor.DefaultScannerBufferBytes)
+	opts.MaxLineBytes = getEnvInt(prefix+"_MAX_LINE_BYTES", defaultMaxLine)
+	opts.Stats = readStats.source(strings.ToLower(prefix))
+	opts.Mapping = attrMappings.For(strings.ToLower(prefix))
+	opts.Filter = filterRules.For(strings.ToLower(prefix))
+	opts.Identity = processorIdentity(prefix)
+	opts.Threats = sourceThreats(prefix)
+	body, err := processorBodyFormat(prefix)
+	if err != nil {
+		return opts, fmt.Errorf("invalid %s_LOG_BODY_FORMAT: %w", prefix, err)
+	}
+	opts.Body = body
+	if opts.Sampler, err = processorSampler(prefix); err != nil {
+		return opts, fmt.Errorf("invalid %s sample rates: %w", prefix, err)
+	}
+
+	groupKey, err := processor.ParseGroupKey(getEnv(prefix+"_GROUP_KEY", ""))
+	if err == nil && groupKey != nil {
+		err = groupKey.Validate(sample)
+	}
+	if err != nil {
+		return opts, fmt.Errorf("invalid %s_GROUP_KEY: %w", prefix, err)
+	}
+	opts.GroupKey = groupKey
+	return opts, nil
+}
+
+// processorIdentity reads the service and scope overrides of a processor: <prefix>_SERVICE_NAME
+// and friends, falling back to the unprefixed variables shared by all processors. Nil when none
+// are set, leaving each processor's default service.name.
+func processorIdentity(prefix string) *processor.Identity {
+	env := func(name string) string {
+		return getEnv(prefix+"_"+name, getEnv(name, ""))
+	}
+	id := &processor.Identity{
+		ServiceName:      env("SERVICE_NAME"),
+		ServiceNamespace: env("SERVICE_NAMESPACE"),
+		Environment:      env("DEPLOYMENT_ENVIRONMENT"),
+		Scope:            converter.Scope{Name: env("SCOPE_NAME"), Version: env("SCOPE_VERSION")},
+	}
+	if id.Empty() {
+		return nil
+	}
+	return id
+}
+
+// sqsResponse is the partial batch response with the invocation summary alongside; Lambda
+// only reads batchItemFailures
+type sqsResponse struct {
+	events.SQSEventResponse
+	Summary *invocationSummary `json:"summary,omitempty"`
+}
+
+func handler(ctx context.Context, sqsEvent events.SQSEvent) (sqsResponse, error) {
+	response := sqsResponse{SQSEventResponse: events.SQSEventResponse{
+		BatchItemFailures: []events.SQSBatchItemFailure{},
+	}}
+	defer reportUnrecognized()
+
+	logsExp, metrics, ok := pipelineExporters(ctx, "sqs", len(sqsEvent.Records))
+	if !ok {
+		return response, nil
+	}
+	ctx, start := beginInvocation(ctx, "sqs")
+
+	// New objects and batches are only started while enough of the invocation time remains
+	launchCtx, cancel := launchContext(ctx, deadlineMargin)
+	defer cancel()
+
+	// Entries stream from the object readers straight into the sink, which exports full
+	// batches as soon as its buffer fills
+	sink := newStreamSink(ctx, logsExp, metrics, streamBuffer)
+
+	logger.Info("Lambda triggered", "sqs_record_count", len(sqsEvent.Records))
+
+	var resumed checkpointSet
+	var claims objectClaims
+
+	// Messages are independent: a failed one is reported for redelivery and the others go on
+	var g errgroup.Group
+	g.SetLimit(maxConcurrent)
+	var mu sync.Mutex
+
+	// Messages whose records went into the sink; they are only reported as failed when the export fails
+	var exported []string
+	// Objects that could not be processed, as bucket/key
+	var failedKeys []string
+
+	// failMessage reports a message for redelivery through the partial batch response
+	failMessage := func(messageID string) {
+		mu.Lock()
+		defer mu.Unlock()
+		response.BatchItemFailures = append(response.BatchItemFailures, events.SQSBatchItemFailure{
+			ItemIdentifier: messageID,
+		})
+	}
+
+	// handleMessage reads the objects of one message into the sink
+	handleMessage := func(record events.SQSMessage) {
+		// Out of time: leave the message for redelivery rather than starting on it
+		if launchCtx.Err() != nil {
+			logger.Warn("Skipping message, deadline safety margin reached", "message_id", record.MessageId, "cause", context.Cause(launchCtx))
+			failMessage(record.MessageId)
+			return
+		}
+
+		// Parse Body as S3 Event
+		s3Records, err := processor.ParseS3Notification(logger, []byte(record.Body))
+		if err != nil {
+			logger.Warn("Failed to parse SQS body, skipping message", "message_id", record.MessageId, "error", err)
+			return
+		}
+
+		// Usually one SQS message contains one S3 event (EventBridge wrapper)
+		// But a notification may carry several objects, so handle all
+		msgFailed := false
+		msgEntries := 0
+		var msgErrs []error
+
+		for _, s3Record := range s3Records {
+			bucket := s3Record.S3.Bucket.Name
+			key := s3Record.S3.Object.Key
+
+			if bucket == "" || key == "" {
+				logger.Warn("Skipping record with empty bucket or key", "message_id", record.MessageId)
+				continue
+			}
+
+			log := logger.With("bucket", bucket, "key", key, "message_id", record.MessageId)
+			n, err := handleObject(launchCtx, log, sink, &claims, &resumed, bucket, key, s3Record.S3.Object.ETag, s3Record.AWSRegion)
+			msgEntries += n
+			if errors.Is(err, errObjectBusy) {
+				log.Warn("Object is being processed by another invocation, leaving message for redelivery")
+				msgFailed = true
+				break
+			}
+			if err != nil {
+				log.Error("Error processing S3 object", "error", err)
+				msgFailed = true
+				mu.Lock()
+				failedKeys = append(failedKeys, bucket+"/"+key)
+				mu.Unlock()
+				if !continueOnError {
+					break // Stop processing this SQS message, mark as failed
+				}
+				msgErrs = append(msgErrs, fmt.Errorf("%s/%s: %w", bucket, key, err))
+			}
+		}
+
+		if len(msgErrs) > 1 {
+			logger.Error("Multiple objects failed in message", "message_id", record.MessageId, "failed", len(msgErrs), "error", errors.Join(msgErrs...))
+		}
+
+		if msgFailed {
+			failMessage(record.MessageId)
+		} else if msgEntries > 0 {
+			mu.Lock()
+			exported = append(exported, record.MessageId)
+			mu.Unlock()
+		}
+	}
+
+	for _, record := range sqsEvent.Records {
+		g.Go(func() error {
+			// A panic fails only this message, which is then redelivered
+			if err := recovered("message "+record.MessageId, func() error {
+				handleMessage(record)
+				return nil
+			})(); err != nil {
+				failMessage(record.MessageId)
+			}
+			return nil
+		})
+	}
+
+	g.Wait()
+
+	// Send the remaining buffered entries to OTLP
+	if err := sink.Close(launchCtx); err != nil {
+		// Records of all messages are mixed in the export batches, so every message that
+		// contributed records is retried; messages without records are still deleted
+		logger.Error("Error sending to OTLP", "error", err, "exporters", exportSummary(), "failed_messages", len(exported), "failed_keys", failedKeys)
+		claims.settle(ctx, false)
+		for _, messageID := range exported {
+			failMessage(messageID)
+		}
+		response.Summary = summarizeInvocation(ctx, "sqs", len(sqsEvent.Records), len(response.BatchItemFailures), start)
+		return response, nil
+	}
+	claims.settle(ctx, true)
+
+	// Objects that completed are fully exported now, so their checkpoints are no longer needed
+	resumed.clear(ctx)
+
+	logger.Info("Lambda execution completed", "failures", len(response.BatchItemFailures), "failed_keys", failedKeys, "exporters", exportSummary())
+	response.Summary = summarizeInvocation(ctx, "sqs", len(sqsEvent.Records), len(response.BatchItemFailures), start)
+	return response, nil
+}
+
+// errObjectBusy is returned for an object that another invocation holds a dedup claim on
+var errObjectBusy = errors.New("object is being processed by another invocation")
+
+// handleObject runs one S3 object through sampling, processor lookup and the dedup table, then
+// feeds its entries into the sink. It returns how many entries were added; skipped objects
+// return 0 and no error. version identifies the object version for dedup and checkpoints,
+// usually the ETag. region is the bucket's region as reported by the event, if any.
+func handleObject(ctx context.Context, log *slog.Logger, sink *streamSink, claims *objectClaims, resumed *checkpointSet, bucket, key, version, region string) (int, error) {
+	log.Info("Processing S3 object")
+	s3Pool.NoteBucketRegion(bucket, region)
+
+	if !sampler.Keep(bucket, key) {
+		log.Info("Skipping object: sampled out")
+		return 0, nil
+	}
+
+	// Find matching processor
+	proc := registry.Find(bucket, key)
+	if proc == nil {
+		log.Info("Skipping object: no matching processor found")
+		return 0, nil
+	}
+
+	// Skip object versions that were already exported
+	claim, claimID := claims.claim(ctx, log, bucket, key, version)
+	switch claim {
+	case processor.ClaimDone:
+		log.Info("Skipping object: already processed")
+		return 0, nil
+	case processor.ClaimBusy:
+		return 0, errObjectBusy
+	}
+
+	ctx, span := tracing.Start(ctx, "s3.object")
+	span.SetString("aws.s3.bucket", bucket)
+	span.SetString("aws.s3.key", key)
+	span.SetString("processor", proc.Name())
+
+	var stats *processor.ReadStats
+	if dryRun != nil {
+		stats = &processor.ReadStats{}
+		ctx = processor.WithObjectStats(ctx, stats)
+	}
+
+	// Process logs
+	n, err := processObject(ctx, proc, sink, bucket, key, version, resumed)
+	// Only an object read to the end without error is done; one that failed part way, or was
+	// skipped or routed by the limits, is released so it can be claimed again
+	claims.done(claimID, err == nil)
+	err = applyObjectLimits(ctx, log, bucket, key, version, region, err)
+	if stats != nil {
+		log.Info("Dry run: object processed", "processor", proc.Name(), "entries", n, "stats", stats.Take(), "error", err)
+	}
+
+	span.SetInt("entries", int64(n))
+	span.SetError(err)
+	span.End()
+	return n, err
+}
+
+// pipelineExporters returns the exporters selected by the pipeline switch, which lets operators
+// halt or divert ingestion without touching triggers. ok is false when the events should be
+// acknowledged without exporting anything.
+func pipelineExporters(ctx context.Context, source string, records int) (logsExp exporter.Exporter, metrics exporter.MetricsExporter, ok bool) {
+	switch pipeline.Mode(ctx) {
+	case pipelinePaused:
+		logger.Warn("Pipeline paused, acknowledging events without exporting", "event_source", source, "record_count", records)
+		return nil, nil, false
+	case pipelineDivert:
+		if divertExp == nil {
+			logger.Warn("Pipeline diverted but PIPELINE_DIVERT_EXPORTER is not set, acknowledging events without exporting", "event_source", source, "record_count", records)
+			return nil, nil, false
+		}
+		logger.Info("Pipeline diverted, exporting to the divert exporter only")
+		return countedExporter{divertExp}, nil, true
+	}
+	return countedExporter{exp}, metricsExp, true
+}
+
+// processObject feeds the entries of one object into the sink, streaming them when the
+// processor supports it, and returns how many it added. Streamed objects resume from and
+// save checkpoints when enabled; completed ones are added to resumed so their checkpoints
+// can be cleared.
+func processObject(ctx context.Context, proc processor.LogProcessor, sink *streamSink, bucket, key, etag string, resumed *checkpointSet) (int, error) {
+	added := 0
+	// Conversion is spread over every emit; its span covers the first to the last one
+	var convertStart time.Time
+	var convertBusy time.Duration
+	defer func() {
+		if added > 0 {
+			_, span := tracing.StartAt(ctx, "convert", convertStart)
+			span.SetInt("entries", int64(added))
+			span.SetInt("convert.busy_ms", convertBusy.Milliseconds())
+			span.End()
+		}
+	}()
+	emit := func(entry adapter.LogAdapter) error {
+		start := time.Now()
+		if convertStart.IsZero() {
+			convertStart = start
+		}
+		err := sink.AddFrom(ctx, strings.ToLower(proc.Name()), entry)
+		convertBusy += time.Since(start)
+		if err != nil {
+			return err
+		}
+		added++
+		return nil
+	}
+	if sp, ok := proc.(processor.StreamProcessor); ok {
+		oc := newObjectCheckpoint(ctx, sink, bucket, key, etag)
+		var cp *processor.Checkpoint
+		if oc != nil {
+			cp = oc.cp
+		}
+		if err := sp.ProcessStream(ctx, logger, store, bucket, key, emit, cp); err != nil {
+			return added, err
+		}
+		resumed.add(oc)
+		return added, nil
+	}
+
+	entries, err := proc.Process(ctx, logger, store, bucket, key)
+	if err != nil {
+		return 0, err
+	}
+	for _, entry := range entries {
+		if err := emit(entry); err != nil {
+			return added, err
+		}
+	}
+	return added, nil
+}
+
+// reportUnrecognized warns once per invocation about enum values the converters did not know
+func reportUnrecognized() {
+	for key, values := range converter.TakeUnrecognized() {
+		logger.Warn("Unrecognized values passed through", "attribute", key, "values", values)
+	}
+}
+
+// objectMessage wraps an EventBridge S3 event for one object in an SQS message, for running
+// objects that were not delivered by a notification through handler
+func objectMessage(id, bucket, key, etag string) (events.SQSMessage, error) {
+	var ev processor.EventBridgeS3Event
+	ev.Source = "aws.s3"
+	ev.DetailType = "Object Created"
+	ev.Detail.Bucket.Name = bucket
+	ev.Detail.Object.Key = key
+	ev.Detail.Object.ETag = etag
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return events.SQSMessage{}, err
+	}
+	return events.SQSMessage{MessageId: id, Body: string(body)}, nil
+}
+
+// convertAndSend groups the entries by resource and exports them through logsExp; metrics, when
+// non-nil, receives the record counts derived while grouping
+func convertAndSend(ctx context.Context, logsExp exporter.Exporter, metrics exporter.MetricsExporter, entries []adapter.LogAdapter) error {
+	sink := newStreamSink(ctx, logsExp, metrics, streamBuffer)
+	for _, entry := range entries {
+		if err := sink.Add(ctx, entry); err != nil {
+			sink.Close(ctx)
+			return err
+		}
+	}
+	return sink.Close(ctx)
+}
+
+// sendMetrics exports the record counts and RED metrics of every resource group, one request
+// per resource
+func sendMetrics(ctx context.Context, metricsExp exporter.MetricsExporter, grouped map[string]*resourceGroup) error {
+	g, ctx := newWorkerGroup(ctx)
+
+	for resKey, group := range grouped {
+		metrics := append(group.Counter.Metrics(), group.RED.Metrics()...)
+		if len(metrics) == 0 {
+			continue
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		rm := buildResourceMetric(group.ResourceAttrs, metrics)
+		g.Go(recovered("send metrics", func() error {
+			if err := metricsExp.ExportMetrics(ctx, rm); err != nil {
+				logger.Error("Failed to send metrics", "resource_key", resKey, "error", err)
+				return fmt.Errorf("failed to send metrics for %s: %w", resKey, err)
+			}
+			return nil
+		}))
+	}
+
+	if err := g.Wait(); err != nil {
+		return err
+	}
+
+	logger.Info("Successfully sent metrics", "resource_groups", len(grouped))
+	return nil
+}
+
+// sendSpans exports the spans synthesized from every resource group, in batches of
+// maxBatchSize, one request per batch
+func sendSpans(ctx context.Context, tracesExp exporter.TracesExporter, grouped map[string]*resourceGroup) error {
+	g, ctx := newWorkerGroup(ctx)
+
+	total := 0
+launch:
+	for resKey, group := range grouped {
+		for i := 0; i < len(group.Spans); i += maxBatchSize {
+			if ctx.Err() != nil {
+				break launch
+			}
+			end := min(i+maxBatchSize, len(group.Spans))
+			total += end - i
+
+			rs := converter.ResourceSpans{
+				Resource:   converter.ResourceAttributes{Attributes: group.ResourceAttrs},
+				ScopeSpans: []converter.ScopeSpans{{Scope: group.Scope, Spans: group.Spans[i:end]}},
+			}
+			g.Go(recovered("send spans", func() error {
+				if err := tracesExp.ExportSpans(ctx, rs); err != nil {
+					logger.Error("Failed to send spans", "resource_key", resKey, "error", err)
+					return fmt.Errorf("failed to send spans for %s: %w", resKey, err)
+				}
+				return nil
+			}))
+		}
+	}
+
+	if err := g.Wait(); err != nil {
+		return err
+	}
+
+	if total > 0 {
+		logger.Info("Successfully sent spans", "spans", total, "resource_groups", len(grouped))
+	}
+	return nil
+}
+
+// splitPriorityLanes partitions every resource group into a priority and a bulk group,
+// omitting groups that end up empty
+func splitPriorityLanes(grouped map[string]*resourceGroup) (high, bulk map[string]*resourceGroup) {
+	high = make(map[string]*resourceGroup)
+	bulk = make(map[string]*resourceGroup)
+
+	for resKey, group := range grouped {
+		highRecords, bulkRecords := converter.PartitionByPriority(group.LogRecords)
+		if len(highRecords) > 0 {
+			high[resKey] = &resourceGroup{Group: logpipeline.Group{Scope: group.Scope, ResourceAttrs: group.ResourceAttrs, LogRecords: highRecords}, Processor: group.Processor}
+		}
+		if len(bulkRecords) > 0 {
+			bulk[resKey] = &resourceGroup{Group: logpipeline.Group{Scope: group.Scope, ResourceAttrs: group.ResourceAttrs, LogRecords: bulkRecords}, Processor: group.Processor}
+		}
+	}
+	return high, bulk
+}
+
+// sendLane exports all resource groups of one lane in batches and waits for them to finish.
+// No batch is started once launchCtx is done; started batches are exported on ctx, and the
+// first failed batch cancels the others. With ORDERED, the batches of a group are exported one
+// at a time in record order; only different groups are exported concurrently.
+func sendLane(launchCtx, ctx context.Context, logsExp exporter.Exporter, lane string, grouped map[string]*resourceGroup) (int, error) {
+	g, ctx := newWorkerGroup(ctx)
+
+	totalSent := 0
+	var sentLock sync.Mutex
+	var stopped error
+
+	// Send each group in batches
+launch:
+	for resKey, group := range grouped {
+		groupLog := logger.With("resource_key", resKey, "total_logs", len(group.LogRecords), "lane", lane)
+		groupLog.Info("Processing resource group")
+
+		send := func(bID int, batch []converter.OTelLogRecord) error {
+			if launchCtx.Err() != nil {
+				return fmt.Errorf("batch %d not started: %w", bID, context.Cause(launchCtx))
+			}
+			// Another batch failed while this one waited for a worker
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			groupLog.Info("Sending batch", "batch_id", bID, "batch_size", len(batch))
+
+			if err := logsExp.Export(withProcessor(ctx, group.Processor), group.ResourceLog(batch)); err != nil {
+				groupLog.Error("Failed to send batch", "batch_id", bID, "error", err)
+				return fmt.Errorf("failed to send batch %d: %w", bID, err)
+			}
+
+			sentLock.Lock()
+			totalSent += len(batch)
+			sentLock.Unlock()
+			return nil
+		}
+
+		batches := splitBatches(group.LogRecords, group.batchSize())
+		if readOpts.Ordered {
+			// One worker sends the group's batches one after another, so they arrive in order
+			if ctx.Err() != nil {
+				break launch
+			}
+			g.Go(recovered("send resource group "+resKey, func() error {
+				for i, batch := range batches {
+					if err := send(i+1, batch); err != nil {
+						return err
+					}
+				}
+				return nil
+			}))
+			continue
+		}
+
+		for i, batch := range batches {
+			// A failed batch has cancelled ctx; its error is returned by Wait
+			if ctx.Err() != nil {
+				break launch
+			}
+
+			// Stop launching batches once the invocation is out of time
+			if launchCtx.Err() != nil {
+				stopped = fmt.Errorf("stopped before exporting all batches: %w", context.Cause(launchCtx))
+				break launch
+			}
+
+			bID := i + 1
+			g.Go(recovered(fmt.Sprintf("send batch %d", bID), func() error {
+				return send(bID, batch)
+			}))
+		}
+	}
+
+	// Wait for all batches to complete
+	if err := g.Wait(); err != nil {
+		return totalSent, err
+	}
+	if stopped != nil {
+		return totalSent, stopped
+	}
+
+	if lane != "default" {
+		logger.Info("Lane exported", "lane", lane, "total_sent", totalSent, "resource_groups", len(grouped))
+	}
+	return totalSent, nil
+}
+
+func buildResourceMetric(resourceAttrs []converter.OTelAttribute, metrics []converter.Metric) converter.ResourceMetric {
+	return converter.ResourceMetric{
+		Resource: converter.ResourceAttributes{
+			Attributes: resourceAttrs,
+		},
+		ScopeMetrics: []converter.ScopeMetric{
+			{
+				Scope: converter.Scope{
+					Name:    "otel-aws-log-parser",
+					Version: version.Info().String(),
+				},
+				Metrics: metrics,
+			},
+		},
+	}
+}
+
+// defaultScope is the instrumentation scope of records whose processor sets none
+// resourceGroup is a pipeline group with the metrics, spans and processor of the Lambda's
+// export lanes
+type resourceGroup struct {
+	logpipeline.Group
+	// Counter derives metrics from LogRecords; nil unless METRICS_ENABLED
+	Counter *converter.RecordCounter
+	// RED derives request, error and duration metrics; nil unless AGGREGATE_MODE is metrics or both
+	RED *converter.REDAggregator
+	// Spans are synthesized from ALB records when ALB_SPANS is set
+	Spans []converter.Span
+	// Processor names the S3 log processor of the records, empty for other sources
+	Processor string
+}
+
+// held counts the records and spans waiting in the group
+func (g *resourceGroup) held() int {
+	return len(g.LogRecords) + len(g.Spans)
+}
+
+// ServerAddr returns SERVER_ADDR, the address to serve HTTP on instead of starting the Lambda
+// runtime (see RunServer); empty when unset. Configure must have run.
+func ServerAddr() string {
+	return getEnv("SERVER_ADDR", "")
+}
+
+// settings reads every variable, so malformed values fail the cold start and the effective
+// configuration can be logged
+var settings = config.New(nil)
+
+func getEnv(key, defaultValue string) string {
+	return settings.String(key, defaultValue)
+}
+
+// getEnvList splits a comma-separated variable into trimmed, non-empty items
+func getEnvList(key string) []string {
+	return settings.List(key)
+}
+
+// redactNames reads a list of names to redact; unset means defaults and "none" means nothing
+func redactNames(key string, defaults []string) []string {
+	names := getEnvList(key)
+	switch {
+	case len(names) == 0:
+		return defaults
+	case len(names) == 1 && strings.EqualFold(names[0], "none"):
+		return nil
+	}
+	return names
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	return settings.Int(key, defaultValue)
+}
+
+func getEnvBool(key string, defaultValue bool) bool {
+	return settings.Bool(key, defaultValue)
+}
@@ -0,0 +1,247 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/appconfigdata"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/config"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/processor"
+)
+
+var (
+	// appConfig is the AppConfig profile of the dynamic settings; nil unless APPCONFIG_APPLICATION
+	appConfig *appConfigSource
+	// dynamicMu is held for reading by every invocation and for writing while dynamic settings
+	// are applied, so an invocation sees one set of filters, samplers and routes throughout
+	dynamicMu sync.RWMutex
+	// routeReloaders rebuild the routes of each OTLP router; see routedOTLPExporter
+	routeReloaders []func() (commit func(), err error)
+)
+
+// isDynamicSetting reports whether AppConfig may change a variable: the filter rules, the OTLP
+// routing table and the sample rates (SAMPLE_2XX or <PREFIX>_SAMPLE_2XX)
+func isDynamicSetting(key string) bool {
+	if key == "FILTER_RULES" || key == "OTLP_ROUTES" {
+		return true
+	}
+	for _, class := range processor.StatusClasses {
+		if key == "SAMPLE_"+strings.ToUpper(class) || strings.HasSuffix(key, "_SAMPLE_"+strings.ToUpper(class)) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseDynamicSettings reads an AppConfig configuration, YAML or JSON keyed like CONFIG_FILE
+func parseDynamicSettings(data []byte) (map[string]string, error) {
+	values, err := config.ParseFile(data)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range slices.Sorted(maps.Keys(values)) {
+		if !isDynamicSetting(key) {
+			return nil, fmt.Errorf("%s cannot be set through AppConfig, only FILTER_RULES, OTLP_ROUTES and sample rates", key)
+		}
+	}
+	return values, nil
+}
+
+// appConfigDataAPI is the part of the AppConfig Data client appConfigSource uses
+type appConfigDataAPI interface {
+	StartConfigurationSession(ctx context.Context, in *appconfigdata.StartConfigurationSessionInput, optFns ...func(*appconfigdata.Options)) (*appconfigdata.StartConfigurationSessionOutput, error)
+	GetLatestConfiguration(ctx context.Context, in *appconfigdata.GetLatestConfigurationInput, optFns ...func(*appconfigdata.Options)) (*appconfigdata.GetLatestConfigurationOutput, error)
+}
+
+// appConfigSource polls one AppConfig configuration profile through the AppConfig Data API.
+// Polls are spaced by the larger of interval and the interval AppConfig asks for, and a poll
+// only returns data when the deployed configuration changed.
+type appConfigSource struct {
+	client      appConfigDataAPI
+	application string
+	environment string
+	profile     string
+	interval    time.Duration
+	now         func() time.Time
+
+	mu    sync.Mutex
+	token *string
+	next  time.Time
+}
+
+// newAppConfigSource creates the source of APPCONFIG_APPLICATION, APPCONFIG_ENVIRONMENT and
+// APPCONFIG_PROFILE, or returns nil when no application is set
+func newAppConfigSource() (*appConfigSource, error) {
+	application := getEnv("APPCONFIG_APPLICATION", "")
+	if application == "" {
+		return nil, nil
+	}
+	awsCfg, err := awsConfig()
+	if err != nil {
+		return nil, err
+	}
+	return &appConfigSource{
+		client:      appconfigdata.NewFromConfig(awsCfg),
+		application: application,
+		environment: getEnv("APPCONFIG_ENVIRONMENT", ""),
+		profile:     getEnv("APPCONFIG_PROFILE", ""),
+		interval:    time.Duration(getEnvInt("APPCONFIG_POLL_SECONDS", 60)) * time.Second,
+		now:         time.Now,
+	}, nil
+}
+
+// poll fetches the configuration when a poll is due. It returns nil data when no poll was due
+// or the configuration has not changed since the last poll.
+func (s *appConfigSource) poll(ctx context.Context) (data []byte, version string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if now.Before(s.next) {
+		return nil, "", nil
+	}
+	// A failed poll is retried at the next interval, with a new session in case the token expired
+	s.next = now.Add(s.interval)
+
+	if s.token == nil {
+		out, err := s.client.StartConfigurationSession(ctx, &appconfigdata.StartConfigurationSessionInput{
+			ApplicationIdentifier:                aws.String(s.application),
+			EnvironmentIdentifier:                aws.String(s.environment),
+			ConfigurationProfileIdentifier:       aws.String(s.profile),
+			RequiredMinimumPollIntervalInSeconds: aws.Int32(int32(s.interval / time.Second)),
+		})
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to start AppConfig session: %w", err)
+		}
+		s.token = out.InitialConfigurationToken
+	}
+
+	out, err := s.client.GetLatestConfiguration(ctx, &appconfigdata.GetLatestConfigurationInput{ConfigurationToken: s.token})
+	if err != nil {
+		s.token = nil
+		return nil, "", fmt.Errorf("failed to get AppConfig configuration: %w", err)
+	}
+	s.token = out.NextPollConfigurationToken
+	if wait := time.Duration(out.NextPollIntervalInSeconds) * time.Second; wait > s.interval {
+		s.next = now.Add(wait)
+	}
+	if len(out.Configuration) == 0 {
+		return nil, "", nil
+	}
+	return out.Configuration, aws.ToString(out.VersionLabel), nil
+}
+
+// loadDynamicSettings fetches the AppConfig configuration at cold start, before the settings it
+// may change are read
+func loadDynamicSettings(ctx context.Context) error {
+	if appConfig == nil {
+		return nil
+	}
+	data, version, err := appConfig.poll(ctx)
+	if err != nil || data == nil {
+		return err
+	}
+	values, err := parseDynamicSettings(data)
+	if err != nil {
+		return err
+	}
+	settings.SetOverrides(values)
+	logger.Info("Loaded AppConfig configuration", "version", version, "keys", slices.Sorted(maps.Keys(values)))
+	return nil
+}
+
+// refreshDynamicSettings polls AppConfig and applies a changed configuration. It waits for the
+// invocations in flight to finish. A configuration that cannot be fetched or applied is logged
+// and the current settings are kept.
+func refreshDynamicSettings(ctx context.Context) {
+	if appConfig == nil {
+		return
+	}
+	data, version, err := appConfig.poll(ctx)
+	if err != nil {
+		logger.Warn("Failed to poll AppConfig, keeping the current settings", "error", err)
+		return
+	}
+	if data == nil {
+		return
+	}
+	values, err := parseDynamicSettings(data)
+	if err == nil {
+		dynamicMu.Lock()
+		defer dynamicMu.Unlock()
+		previous := settings.Overrides()
+		settings.SetOverrides(values)
+		if err = applyDynamicSettings(); err != nil {
+			settings.SetOverrides(previous)
+		}
+	}
+	if err != nil {
+		logger.Error("Invalid AppConfig configuration, keeping the current settings", "version", version, "error", err)
+		return
+	}
+	logger.Info("Applied AppConfig configuration", "version", version, "keys", slices.Sorted(maps.Keys(values)))
+}
+
+// applyDynamicSettings rebuilds everything the dynamic settings feed: the filter rules, the
+// processors and decorators holding them and the samplers, and the OTLP routes. Nothing is
+// replaced unless all of them build.
+func applyDynamicSettings() error {
+	rules, err := loadFilterRules()
+	if err != nil {
+		return fmt.Errorf("invalid filter rules: %w", err)
+	}
+	previousRules := filterRules
+	filterRules = rules
+	reg, err := buildRegistry()
+	var kinesis, logs entryDecorator
+	if err == nil {
+		kinesis, err = sourceDecorator("KINESIS")
+	}
+	if err == nil {
+		logs, err = sourceDecorator("CLOUDWATCH_LOGS")
+	}
+	commits := make([]func(), 0, len(routeReloaders))
+	for _, reload := range routeReloaders {
+		if err != nil {
+			break
+		}
+		var commit func()
+		if commit, err = reload(); err == nil {
+			commits = append(commits, commit)
+		}
+	}
+	if err != nil {
+		filterRules = previousRules
+		return err
+	}
+
+	registry, kinesisDecor, logsDecor = reg, kinesis, logs
+	for _, commit := range commits {
+		commit()
+	}
+	return nil
+}
+
+// watchDynamicSettings refreshes the dynamic settings every poll interval until ctx is done,
+// for server mode, where the function may sit idle between requests
+func watchDynamicSettings(ctx context.Context) {
+	if appConfig == nil {
+		return
+	}
+	ticker := time.NewTicker(appConfig.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			refreshDynamicSettings(ctx)
+		}
+	}
+}
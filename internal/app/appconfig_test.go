@@ -0,0 +1,102 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/appconfigdata"
+)
+
+// fakeAppConfig serves the next of configs on every poll; an empty config means unchanged
+type fakeAppConfig struct {
+	configs  []string
+	err      error
+	sessions int
+	polls    int
+}
+
+func (f *fakeAppConfig) StartConfigurationSession(ctx context.Context, in *appconfigdata.StartConfigurationSessionInput, optFns ...func(*appconfigdata.Options)) (*appconfigdata.StartConfigurationSessionOutput, error) {
+	f.sessions++
+	return &appconfigdata.StartConfigurationSessionOutput{InitialConfigurationToken: aws.String("token-0")}, nil
+}
+
+func (f *fakeAppConfig) GetLatestConfiguration(ctx context.Context, in *appconfigdata.GetLatestConfigurationInput, optFns ...func(*appconfigdata.Options)) (*appconfigdata.GetLatestConfigurationOutput, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	config := f.configs[f.polls]
+	f.polls++
+	return &appconfigdata.GetLatestConfigurationOutput{
+		Configuration:              []byte(config),
+		NextPollConfigurationToken: aws.String("token-1"),
+		VersionLabel:               aws.String("v1"),
+	}, nil
+}
+
+func TestAppConfigSource_Poll(t *testing.T) {
+	now := time.Unix(0, 0)
+	client := &fakeAppConfig{configs: []string{"sample_2xx: 0.1", "", "sample_2xx: 0.2"}}
+	s := &appConfigSource{client: client, interval: time.Minute, now: func() time.Time { return now }}
+	ctx := context.Background()
+
+	if data, version, err := s.poll(ctx); err != nil || string(data) != "sample_2xx: 0.1" || version != "v1" {
+		t.Fatalf("poll() = %q, %q, %v, want the first configuration", data, version, err)
+	}
+	// Not due yet
+	if data, _, _ := s.poll(ctx); data != nil || client.polls != 1 {
+		t.Errorf("poll() = %q after %d polls, want no poll within the interval", data, client.polls)
+	}
+	now = now.Add(time.Minute)
+	if data, _, err := s.poll(ctx); data != nil || err != nil {
+		t.Errorf("poll() = %q, %v, want nil for an unchanged configuration", data, err)
+	}
+
+	// A failed poll starts a new session next time
+	client.err = errors.New("expired token")
+	now = now.Add(time.Minute)
+	if _, _, err := s.poll(ctx); err == nil {
+		t.Error("poll() error = nil, want the fetch error")
+	}
+	client.err = nil
+	now = now.Add(time.Minute)
+	if data, _, err := s.poll(ctx); err != nil || string(data) != "sample_2xx: 0.2" || client.sessions != 2 {
+		t.Errorf("poll() = %q, %v with %d sessions, want the new configuration from a new session", data, err, client.sessions)
+	}
+}
+
+func TestRefreshDynamicSettings(t *testing.T) {
+	logger = slog.New(slog.NewJSONHandler(io.Discard, nil))
+	now := time.Unix(0, 0)
+	client := &fakeAppConfig{configs: []string{"kinesis_sample_2xx: 0.1", "exporter: s3", "sample_2xx: 2"}}
+	appConfig = &appConfigSource{client: client, interval: time.Minute, now: func() time.Time { return now }}
+	t.Cleanup(func() {
+		appConfig = nil
+		settings.SetOverrides(nil)
+		if err := applyDynamicSettings(); err != nil {
+			t.Errorf("applyDynamicSettings() error = %v", err)
+		}
+	})
+	ctx := context.Background()
+
+	refreshDynamicSettings(ctx)
+	if kinesisDecor.sampler == nil || kinesisDecor.sampler.Rates["2xx"] != 0.1 {
+		t.Fatalf("kinesis sampler = %+v, want 2xx sampled at 0.1", kinesisDecor.sampler)
+	}
+
+	// Settings AppConfig may not change and invalid rates keep the applied configuration
+	for range 2 {
+		now = now.Add(time.Minute)
+		refreshDynamicSettings(ctx)
+		if kinesisDecor.sampler == nil || kinesisDecor.sampler.Rates["2xx"] != 0.1 {
+			t.Errorf("kinesis sampler = %+v, want the previous configuration kept", kinesisDecor.sampler)
+		}
+		if got := settings.Overrides()["KINESIS_SAMPLE_2XX"]; got != "0.1" {
+			t.Errorf("KINESIS_SAMPLE_2XX override = %q, want the previous configuration kept", got)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package app
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/config"
+)
+
+// awsConfig is shared by the function's AWS clients, so that they all retry and time out as
+// configured. It is loaded on first use, after the configuration is read.
+var awsConfig = sync.OnceValues(func() (aws.Config, error) {
+	return config.LoadDefaultConfig(context.Background(), sdkOptions()...)
+})
+
+// sdkOptions reads the SDK retry and timeout settings. Calls retry in the adaptive mode unless
+// AWS_SDK_RETRY_MODE is "standard": once a service throttles, the client also slows down its
+// first attempts instead of only backing off its retries. Unset values keep the SDK's
+// defaults: 3 attempts and no timeouts beyond the invocation's context.
+func sdkOptions() []func(*config.LoadOptions) error {
+	mode := settings.OneOf("AWS_SDK_RETRY_MODE", "adaptive", "adaptive", "standard")
+	attempts := func(o *retry.StandardOptions) {}
+	if settings.IsSet("AWS_SDK_MAX_RETRIES") {
+		maxAttempts := settings.IntRange("AWS_SDK_MAX_RETRIES", 3, 0, 20) + 1
+		attempts = func(o *retry.StandardOptions) { o.MaxAttempts = maxAttempts }
+	}
+	opts := []func(*config.LoadOptions) error{
+		config.WithRetryer(func() aws.Retryer {
+			if mode == "standard" {
+				return retry.NewStandard(attempts)
+			}
+			return retry.NewAdaptiveMode(func(o *retry.AdaptiveModeOptions) {
+				o.StandardOptions = append(o.StandardOptions, attempts)
+			})
+		}),
+	}
+
+	// Timeouts apply to connecting and to the response headers only: a client-wide timeout
+	// would also cut off the streaming of large objects
+	connect := time.Duration(settings.IntRange("AWS_SDK_CONNECT_TIMEOUT_SECONDS", 0, 0, 300)) * time.Second
+	response := time.Duration(settings.IntRange("AWS_SDK_RESPONSE_TIMEOUT_SECONDS", 0, 0, 900)) * time.Second
+	if connect > 0 || response > 0 {
+		client := awshttp.NewBuildableClient().WithTransportOptions(func(t *http.Transport) {
+			if connect > 0 {
+				t.TLSHandshakeTimeout = connect
+			}
+			t.ResponseHeaderTimeout = response
+		})
+		if connect > 0 {
+			client = client.WithDialerOptions(func(d *net.Dialer) { d.Timeout = connect })
+		}
+		opts = append(opts, config.WithHTTPClient(client))
+	}
+	return opts
+}
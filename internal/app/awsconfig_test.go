@@ -0,0 +1,59 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/config"
+)
+
+// loadSDKOptions applies sdkOptions to empty load options
+func loadSDKOptions(t *testing.T) config.LoadOptions {
+	t.Helper()
+	var o config.LoadOptions
+	for _, opt := range sdkOptions() {
+		if err := opt(&o); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return o
+}
+
+func TestSDKOptions(t *testing.T) {
+	t.Setenv("AWS_SDK_RETRY_MODE", "")
+	t.Setenv("AWS_SDK_MAX_RETRIES", "")
+	t.Setenv("AWS_SDK_CONNECT_TIMEOUT_SECONDS", "")
+	t.Setenv("AWS_SDK_RESPONSE_TIMEOUT_SECONDS", "")
+	o := loadSDKOptions(t)
+	retryer := o.Retryer()
+	if _, ok := retryer.(*retry.AdaptiveMode); !ok || retryer.MaxAttempts() != retry.DefaultMaxAttempts {
+		t.Errorf("retryer = %T with %d attempts, want the adaptive mode with SDK defaults", retryer, retryer.MaxAttempts())
+	}
+	if o.HTTPClient != nil {
+		t.Errorf("HTTP client = %T, want the SDK default when no timeout is set", o.HTTPClient)
+	}
+
+	t.Setenv("AWS_SDK_RETRY_MODE", "standard")
+	t.Setenv("AWS_SDK_MAX_RETRIES", "7")
+	t.Setenv("AWS_SDK_CONNECT_TIMEOUT_SECONDS", "2")
+	t.Setenv("AWS_SDK_RESPONSE_TIMEOUT_SECONDS", "30")
+	o = loadSDKOptions(t)
+	retryer = o.Retryer()
+	if _, ok := retryer.(*retry.Standard); !ok || retryer.MaxAttempts() != 8 {
+		t.Errorf("retryer = %T with %d attempts, want the standard mode with 8", retryer, retryer.MaxAttempts())
+	}
+	client, ok := o.HTTPClient.(*awshttp.BuildableClient)
+	if !ok {
+		t.Fatalf("HTTP client = %T, want one with timeouts", o.HTTPClient)
+	}
+	// The client itself has no timeout, which would cut off streamed object bodies
+	if client.GetTimeout() != 0 {
+		t.Errorf("client timeout = %s, want none", client.GetTimeout())
+	}
+	transport := client.GetTransport()
+	if transport.ResponseHeaderTimeout != 30*time.Second || transport.TLSHandshakeTimeout != 2*time.Second || client.GetDialer().Timeout != 2*time.Second {
+		t.Errorf("response header timeout %s, TLS handshake timeout %s, dial timeout %s", transport.ResponseHeaderTimeout, transport.TLSHandshakeTimeout, client.GetDialer().Timeout)
+	}
+}
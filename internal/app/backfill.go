@@ -0,0 +1,360 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/processor"
+)
+
+// RunBackfill implements the backfill command, which loads historical logs: every object under
+// s3://bucket/prefix last modified within the date range (inclusive, UTC) runs through the same
+// pipeline as an S3 notification would. Progress is saved after every batch, to a local file
+// or to a DynamoDB table with a string partition key named "id", and running the same command
+// again resumes the job, retrying the objects that failed first:
+//
+//	bootstrap backfill -bucket B [-prefix P] -from 2024-01-01 -to 2024-03-31 [-batch N] [-concurrency N] [-state backfill-state.json|dynamodb:<table>]
+func RunBackfill(args []string) error {
+	fs := flag.NewFlagSet("backfill", flag.ContinueOnError)
+	bucket := fs.String("bucket", "", "bucket to read")
+	prefix := fs.String("prefix", "", "key prefix to read, e.g. AWSLogs/123456789012/elasticloadbalancing/us-east-1/2024/")
+	fromDay := fs.String("from", "", "first day, YYYY-MM-DD")
+	toDay := fs.String("to", "", "last day, YYYY-MM-DD")
+	batch := fs.Int("batch", 100, "objects per batch; progress is saved after each")
+	concurrency := fs.Int("concurrency", 0, "objects processed at once (default MAX_CONCURRENT)")
+	statePath := fs.String("state", "backfill-state.json", "progress file, or dynamodb:<table>")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *bucket == "" || *fromDay == "" || *toDay == "" {
+		return fmt.Errorf("-bucket, -from and -to are required")
+	}
+	from, err := time.Parse(time.DateOnly, *fromDay)
+	if err != nil {
+		return fmt.Errorf("invalid -from: %w", err)
+	}
+	to, err := time.Parse(time.DateOnly, *toDay)
+	if err != nil {
+		return fmt.Errorf("invalid -to: %w", err)
+	}
+	if to.Before(from) {
+		return fmt.Errorf("-to is before -from")
+	}
+	if *batch <= 0 || *concurrency < 0 {
+		return fmt.Errorf("-batch must be positive and -concurrency must not be negative")
+	}
+
+	if err := Configure(); err != nil {
+		return err
+	}
+	if s3Pool == nil {
+		return fmt.Errorf("backfill reads from S3 and does not support LOCAL_SOURCE_DIR")
+	}
+	if *concurrency > 0 {
+		maxConcurrent = *concurrency
+	}
+
+	job := backfillState{Bucket: *bucket, Prefix: *prefix, From: *fromDay, To: *toDay}
+	var store backfillStore = fileBackfillStore{path: *statePath}
+	if table, ok := strings.CutPrefix(*statePath, "dynamodb:"); ok {
+		awsCfg, err := awsConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load AWS config: %w", err)
+		}
+		store = dynamoBackfillStore{client: dynamodb.NewFromConfig(awsCfg), table: table, id: job.id()}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	b := &backfill{
+		client: s3Pool.Client(*bucket),
+		store:  store,
+		batch:  *batch,
+		from:   from,
+		to:     to.AddDate(0, 0, 1),
+	}
+	return b.run(ctx, job)
+}
+
+// backfillObject is one object of a backfill
+type backfillObject struct {
+	Key  string `json:"key"`
+	ETag string `json:"etag,omitempty"`
+}
+
+// backfillState is the progress of a backfill job
+type backfillState struct {
+	Bucket string `json:"bucket"`
+	Prefix string `json:"prefix"`
+	From   string `json:"from"`
+	To     string `json:"to"`
+	// After is the last key handled; the listing resumes after it
+	After string `json:"after,omitempty"`
+	// Failed are retried when the job is resumed
+	Failed    []backfillObject `json:"failed,omitempty"`
+	Processed int64            `json:"processed"`
+	Done      bool             `json:"done"`
+}
+
+// id identifies the job in a shared state table
+func (s backfillState) id() string {
+	return "backfill:" + s.Bucket + "/" + s.Prefix + "#" + s.From + ".." + s.To
+}
+
+// backfillStore loads and saves the state of one backfill job
+type backfillStore interface {
+	// Load returns nil when the job has not started
+	Load(ctx context.Context) (*backfillState, error)
+	Save(ctx context.Context, state *backfillState) error
+}
+
+// fileBackfillStore keeps the state in a local JSON file, replaced atomically on every save
+type fileBackfillStore struct {
+	path string
+}
+
+func (s fileBackfillStore) Load(ctx context.Context) (*backfillState, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backfill state: %w", err)
+	}
+	var state backfillState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("invalid backfill state %s: %w", s.path, err)
+	}
+	return &state, nil
+}
+
+func (s fileBackfillStore) Save(ctx context.Context, state *backfillState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".backfill-state-*")
+	if err != nil {
+		return fmt.Errorf("failed to save backfill state: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save backfill state: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save backfill state: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to save backfill state: %w", err)
+	}
+	return nil
+}
+
+// dynamoBackfillStore keeps the state as JSON in the "state" attribute of the item id
+type dynamoBackfillStore struct {
+	client processor.DynamoDBAPI
+	table  string
+	id     string
+}
+
+func (s dynamoBackfillStore) Load(ctx context.Context) (*backfillState, error) {
+	out, err := s.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(s.table),
+		Key:            map[string]types.AttributeValue{"id": &types.AttributeValueMemberS{Value: s.id}},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load backfill state: %w", err)
+	}
+	attr, ok := out.Item["state"].(*types.AttributeValueMemberS)
+	if !ok {
+		return nil, nil
+	}
+	var state backfillState
+	if err := json.Unmarshal([]byte(attr.Value), &state); err != nil {
+		return nil, fmt.Errorf("invalid backfill state %s: %w", s.id, err)
+	}
+	return &state, nil
+}
+
+func (s dynamoBackfillStore) Save(ctx context.Context, state *backfillState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	_, err = s.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(s.table),
+		Item: map[string]types.AttributeValue{
+			"id":    &types.AttributeValueMemberS{Value: s.id},
+			"state": &types.AttributeValueMemberS{Value: string(data)},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save backfill state: %w", err)
+	}
+	return nil
+}
+
+// backfill runs the objects of a job through handler one batch at a time, in key order
+type backfill struct {
+	client s3.ListObjectsV2APIClient
+	store  backfillStore
+	batch  int
+	// from and to bound the objects' last modified time, to exclusive
+	from, to time.Time
+
+	state *backfillState
+}
+
+// run starts or resumes job. An interrupt stops it after the batch in flight; the objects still
+// failing at the end are reported as an error.
+func (b *backfill) run(ctx context.Context, job backfillState) error {
+	state, err := b.store.Load(ctx)
+	if err != nil {
+		return err
+	}
+	switch {
+	case state == nil:
+		state = &job
+	case state.id() != job.id():
+		return fmt.Errorf("the backfill state is of another job (%s); use another -state", state.id())
+	case state.Done && len(state.Failed) == 0:
+		logger.Info("Backfill already complete", "processed", state.Processed)
+		return nil
+	default:
+		logger.Info("Resuming backfill", "after", state.After, "processed", state.Processed, "failed", len(state.Failed))
+	}
+	b.state = state
+
+	// Objects that failed earlier are retried first
+	retry := state.Failed
+	state.Failed = nil
+	for len(retry) > 0 {
+		n := min(b.batch, len(retry))
+		if err := b.process(ctx, retry[:n], false); err != nil {
+			state.Failed = append(state.Failed, retry...)
+			b.store.Save(context.WithoutCancel(ctx), state)
+			return err
+		}
+		retry = retry[n:]
+	}
+
+	if !state.Done {
+		var pending []backfillObject
+		err = b.list(ctx, func(obj backfillObject) error {
+			pending = append(pending, obj)
+			if len(pending) < b.batch {
+				return nil
+			}
+			err := b.process(ctx, pending, true)
+			pending = nil
+			return err
+		})
+		if err == nil && len(pending) > 0 {
+			err = b.process(ctx, pending, true)
+		}
+		if err != nil {
+			return err
+		}
+		state.Done = true
+		if err := b.store.Save(ctx, state); err != nil {
+			return err
+		}
+	}
+
+	logger.Info("Backfill complete", "processed", state.Processed, "failed", len(state.Failed))
+	if len(state.Failed) > 0 {
+		return fmt.Errorf("%d objects failed; run the backfill again to retry them", len(state.Failed))
+	}
+	return nil
+}
+
+// list calls fn for every object of the job after the saved key, in key order
+func (b *backfill) list(ctx context.Context, fn func(backfillObject) error) error {
+	input := &s3.ListObjectsV2Input{Bucket: aws.String(b.state.Bucket), Prefix: aws.String(b.state.Prefix)}
+	if b.state.After != "" {
+		input.StartAfter = aws.String(b.state.After)
+	}
+	pages := s3.NewListObjectsV2Paginator(b.client, input)
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list s3://%s/%s: %w", b.state.Bucket, b.state.Prefix, err)
+		}
+		for _, obj := range page.Contents {
+			modified := aws.ToTime(obj.LastModified)
+			if modified.Before(b.from) || !modified.Before(b.to) {
+				continue
+			}
+			// Listings quote the ETag, S3 events do not
+			if err := fn(backfillObject{Key: aws.ToString(obj.Key), ETag: strings.Trim(aws.ToString(obj.ETag), `"`)}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// process runs one batch through handler and saves the progress; advance moves the listing
+// position past the batch. The batch is not cancelled by an interrupt, which is only noticed
+// before the next batch starts.
+func (b *backfill) process(ctx context.Context, objects []backfillObject, advance bool) error {
+	if ctx.Err() != nil {
+		return fmt.Errorf("backfill interrupted, run it again to resume: %w", ctx.Err())
+	}
+
+	event := events.SQSEvent{}
+	for i, obj := range objects {
+		msg, err := objectMessage(strconv.Itoa(i), b.state.Bucket, obj.Key, obj.ETag)
+		if err != nil {
+			return err
+		}
+		event.Records = append(event.Records, msg)
+	}
+
+	dynamicMu.RLock()
+	resp, err := handler(context.WithoutCancel(ctx), event)
+	dynamicMu.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	failed := make(map[string]bool, len(resp.BatchItemFailures))
+	for _, f := range resp.BatchItemFailures {
+		failed[f.ItemIdentifier] = true
+	}
+	for i, obj := range objects {
+		if failed[strconv.Itoa(i)] {
+			b.state.Failed = append(b.state.Failed, obj)
+		} else {
+			b.state.Processed++
+		}
+	}
+	if advance {
+		b.state.After = objects[len(objects)-1].Key
+	}
+	if err := b.store.Save(context.WithoutCancel(ctx), b.state); err != nil {
+		return err
+	}
+	logger.Info("Backfill progress", "after", b.state.After, "processed", b.state.Processed, "failed", len(b.state.Failed))
+	return nil
+}
@@ -0,0 +1,93 @@
+package app
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/exporter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/processor"
+)
+
+// fakeListing lists the objects after StartAfter in one page
+type fakeListing struct {
+	objects []types.Object
+}
+
+func (f *fakeListing) ListObjectsV2(ctx context.Context, in *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	page := &s3.ListObjectsV2Output{}
+	for _, obj := range f.objects {
+		if aws.ToString(obj.Key) > aws.ToString(in.StartAfter) {
+			page.Contents = append(page.Contents, obj)
+		}
+	}
+	return page, nil
+}
+
+func listed(key, day string) types.Object {
+	modified, _ := time.Parse(time.DateOnly, day)
+	return types.Object{Key: aws.String(key), ETag: aws.String(`"` + key + `-etag"`), LastModified: aws.Time(modified.Add(time.Hour))}
+}
+
+func TestBackfill_Run(t *testing.T) {
+	logger = slog.New(slog.NewJSONHandler(io.Discard, nil))
+	maxBatchSize = 10
+	maxConcurrent = 2
+
+	prevRegistry, prevExp := registry, exp
+	defer func() { registry, exp = prevRegistry, prevExp }()
+	registry = processor.NewRegistry()
+	registry.Register(fakeProcessor{})
+	exp = exporter.ExporterFunc(func(ctx context.Context, logs converter.ResourceLog) error { return nil })
+
+	listing := &fakeListing{objects: []types.Object{
+		listed("a", "2024-01-01"), listed("bad", "2024-01-02"), listed("c", "2024-02-01"), listed("d", "2024-01-31"), listed("e", "2024-01-15"),
+	}}
+	store := fileBackfillStore{path: filepath.Join(t.TempDir(), "state.json")}
+	job := backfillState{Bucket: "logs", From: "2024-01-01", To: "2024-01-31"}
+	newBackfill := func() *backfill {
+		return &backfill{client: listing, store: store, batch: 2, from: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), to: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)}
+	}
+
+	// Interrupted before the first batch: nothing is recorded
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := newBackfill().run(ctx, job); err == nil {
+		t.Fatal("run() error = nil, want the interrupt")
+	}
+
+	if err := newBackfill().run(context.Background(), job); err == nil {
+		t.Fatal("run() error = nil, want the failed object reported")
+	}
+	state, err := store.Load(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// c is outside the range
+	if !state.Done || state.Processed != 3 || state.After != "e" || len(state.Failed) != 1 || state.Failed[0] != (backfillObject{Key: "bad", ETag: "bad-etag"}) {
+		t.Errorf("state = %+v, want a, d and e processed and bad failed", state)
+	}
+
+	// Resuming retries the failed object only
+	listing.objects = nil
+	if err := newBackfill().run(context.Background(), job); err == nil {
+		t.Fatal("run() error = nil, want bad to fail again")
+	}
+	if state, _ = store.Load(context.Background()); state.Processed != 3 || len(state.Failed) != 1 {
+		t.Errorf("state after resume = %+v", state)
+	}
+
+	other := job
+	other.Prefix = "AWSLogs/"
+	if err := newBackfill().run(context.Background(), other); err == nil {
+		t.Error("run() error = nil, want the state of another job rejected")
+	}
+}
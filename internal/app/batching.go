@@ -0,0 +1,65 @@
+package app
+
+import (
+	"cmp"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+)
+
+// batchWindow, when set (BATCH_WINDOW_SECONDS), keeps records whose timestamps fall in
+// different windows out of the same batch
+var batchWindow time.Duration
+
+// windowedRecord is a record with the index of the batch window its timestamp falls in
+type windowedRecord struct {
+	window int64
+	record converter.OTelLogRecord
+}
+
+// splitBatches splits the records of a resource group into batches of at most size records.
+// With a batch window, the records are first ordered by the window their timestamp falls in,
+// keeping their order within a window, so every batch covers a single window. The batches
+// share the records' backing array.
+func splitBatches(records []converter.OTelLogRecord, size int) [][]converter.OTelLogRecord {
+	if len(records) == 0 {
+		return nil
+	}
+	if size <= 0 {
+		size = len(records)
+	}
+	if batchWindow <= 0 {
+		return slices.Collect(slices.Chunk(records, size))
+	}
+
+	windowed := make([]windowedRecord, len(records))
+	for i, record := range records {
+		windowed[i] = windowedRecord{window: recordWindow(record), record: record}
+	}
+	slices.SortStableFunc(windowed, func(a, b windowedRecord) int { return cmp.Compare(a.window, b.window) })
+	for i := range windowed {
+		records[i] = windowed[i].record
+	}
+
+	var batches [][]converter.OTelLogRecord
+	start := 0
+	for i := 1; i <= len(records); i++ {
+		if i == len(records) || i-start == size || windowed[i].window != windowed[start].window {
+			batches = append(batches, records[start:i])
+			start = i
+		}
+	}
+	return batches
+}
+
+// recordWindow returns the index of the batch window a record's timestamp falls in; records
+// without a timestamp share window 0
+func recordWindow(record converter.OTelLogRecord) int64 {
+	nanos, err := strconv.ParseInt(record.TimeUnixNano, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return nanos / int64(batchWindow)
+}
@@ -0,0 +1,57 @@
+package app
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+)
+
+func TestSplitBatches(t *testing.T) {
+	oldSize, oldWindow := maxBatchSize, batchWindow
+	t.Cleanup(func() { maxBatchSize, batchWindow = oldSize, oldWindow })
+
+	// Records named after their second; minute windows put 10s and 50s apart from 70s and 80s
+	records := func(seconds ...int) []converter.OTelLogRecord {
+		out := make([]converter.OTelLogRecord, len(seconds))
+		for i, sec := range seconds {
+			body := strconv.Itoa(sec)
+			out[i] = converter.OTelLogRecord{TimeUnixNano: strconv.FormatInt(int64(sec)*int64(time.Second), 10), Body: converter.OTelAnyValue{StringValue: &body}}
+		}
+		return out
+	}
+	format := func(batches [][]converter.OTelLogRecord) string {
+		var parts []string
+		for _, batch := range batches {
+			var bodies []string
+			for _, record := range batch {
+				bodies = append(bodies, record.Body.Text())
+			}
+			parts = append(parts, strings.Join(bodies, ","))
+		}
+		return strings.Join(parts, " | ")
+	}
+
+	tests := []struct {
+		name   string
+		size   int
+		window time.Duration
+		in     []int
+		want   string
+	}{
+		{name: "size only", size: 2, in: []int{10, 70, 50, 80, 20}, want: "10,70 | 50,80 | 20"},
+		{name: "windows", size: 10, window: time.Minute, in: []int{10, 70, 50, 80, 20}, want: "10,50,20 | 70,80"},
+		{name: "windows and size", size: 2, window: time.Minute, in: []int{10, 70, 50, 80, 20}, want: "10,50 | 20 | 70,80"},
+		{name: "unlimited size", size: 0, window: time.Minute, in: []int{70, 10}, want: "10 | 70"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			batchWindow = tt.window
+			if got := format(splitBatches(records(tt.in...), tt.size)); got != tt.want {
+				t.Errorf("splitBatches() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package app
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/processor"
+)
+
+// objectCheckpoint is the checkpoint state of one object during an invocation
+type objectCheckpoint struct {
+	id string
+	cp *processor.Checkpoint
+	// stored is set once a checkpoint exists in the table for id
+	stored bool
+}
+
+// newObjectCheckpoint loads the checkpoint of an object version and arranges for new ones to
+// be saved after flushing the sink, so a saved line count never runs ahead of the export.
+// It returns nil when checkpointing is disabled or the event carries no ETag.
+func newObjectCheckpoint(ctx context.Context, sink *streamSink, bucket, key, etag string) *objectCheckpoint {
+	if checkpoints == nil || etag == "" {
+		return nil
+	}
+
+	oc := &objectCheckpoint{id: processor.CheckpointID(bucket, key, etag)}
+	lines, err := checkpoints.LoadCheckpoint(ctx, oc.id)
+	if err != nil {
+		// Starting over only costs duplicates
+		logger.Warn("Failed to load checkpoint, reading object from the start", "bucket", bucket, "key", key, "error", err)
+	}
+	oc.stored = lines > 0
+
+	oc.cp = &processor.Checkpoint{
+		Lines: lines,
+		Every: checkpointEvery,
+		Save: func(ctx context.Context, lines int64) error {
+			if err := sink.Flush(ctx); err != nil {
+				return err
+			}
+			if err := checkpoints.SaveCheckpoint(ctx, oc.id, lines); err != nil {
+				return err
+			}
+			oc.stored = true
+			logger.Info("Checkpoint saved", "bucket", bucket, "key", key, "lines", lines)
+			return nil
+		},
+	}
+	return oc
+}
+
+// checkpointSet collects the checkpoints of objects that completed; they are deleted only
+// after the sink has exported everything
+type checkpointSet struct {
+	mu  sync.Mutex
+	ids []string
+}
+
+func (s *checkpointSet) add(oc *objectCheckpoint) {
+	if oc == nil || !oc.stored {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ids = append(s.ids, oc.id)
+}
+
+// clear deletes the collected checkpoints; a leftover checkpoint only expires via its TTL
+func (s *checkpointSet) clear(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range s.ids {
+		if err := checkpoints.DeleteCheckpoint(ctx, id); err != nil {
+			logger.Warn("Failed to delete checkpoint", "id", id, "error", err)
+		}
+	}
+	s.ids = nil
+}
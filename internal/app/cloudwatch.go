@@ -0,0 +1,83 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/pixelvide/otel-aws-log-parser/cmd/lambda/adapter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/processor"
+)
+
+// cloudWatchLogsHandler exports the events of a CloudWatch Logs subscription filter that
+// targets the function directly. Messages are parsed with the format auto-detector; the ones
+// that match no known format are exported as plain text. Subscriptions invoke asynchronously,
+// so a failed export is returned as an error and retried by Lambda.
+func cloudWatchLogsHandler(ctx context.Context, event events.CloudwatchLogsEvent) error {
+	defer reportUnrecognized()
+
+	data, err := event.AWSLogs.Parse()
+	if err != nil {
+		// A payload that cannot be decoded never will be; retrying it only delays the next one
+		logger.Warn("Failed to decode CloudWatch Logs payload, skipping", "error", err)
+		return nil
+	}
+	if data.MessageType != "DATA_MESSAGE" {
+		logger.Info("Ignoring CloudWatch Logs message", "message_type", data.MessageType)
+		return nil
+	}
+
+	logsExp, metrics, ok := pipelineExporters(ctx, "cloudwatch_logs", len(data.LogEvents))
+	if !ok {
+		return nil
+	}
+	ctx, start := beginInvocation(ctx, "cloudwatch_logs")
+
+	launchCtx, cancel := launchContext(ctx, deadlineMargin)
+	defer cancel()
+
+	log := logger.With("log_group", data.LogGroup, "log_stream", data.LogStream)
+	log.Info("Lambda triggered", "log_event_count", len(data.LogEvents))
+
+	sink := newStreamSink(ctx, logsExp, metrics, streamBuffer)
+	for _, entry := range cloudWatchEntries(data, processor.FormatAuto, logsDecor) {
+		if err := sink.Add(launchCtx, entry); err != nil {
+			break
+		}
+	}
+	if err := sink.Close(launchCtx); err != nil {
+		log.Error("Error sending to OTLP", "error", err, "exporters", exportSummary())
+		summarizeInvocation(ctx, "cloudwatch_logs", len(data.LogEvents), len(data.LogEvents), start)
+		return fmt.Errorf("failed to export CloudWatch Logs events: %w", err)
+	}
+
+	log.Info("Lambda execution completed", "exporters", exportSummary())
+	summarizeInvocation(ctx, "cloudwatch_logs", len(data.LogEvents), 0, start)
+	return nil
+}
+
+// cloudWatchEntries parses the log events of a subscription payload, tagging each entry with
+// its log group and stream and decorating it for the source that received it
+func cloudWatchEntries(data events.CloudwatchLogsData, format string, decor entryDecorator) []adapter.LogAdapter {
+	src := processor.LogGroupSource{Owner: data.Owner, LogGroup: data.LogGroup, LogStream: data.LogStream}
+	entries := make([]adapter.LogAdapter, 0, len(data.LogEvents))
+	start := time.Now()
+	var dropped [processor.SampledOut + 1]int64
+	for _, ev := range data.LogEvents {
+		entry := processor.AdapterForMessage(format, src, time.UnixMilli(ev.Timestamp), ev.Message)
+		if entry == nil {
+			continue
+		}
+		entry, verdict := decor.apply(entry, ev.Message)
+		if verdict != processor.Admitted {
+			dropped[verdict]++
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	// Messages that match no format are still exported, so none count as parse failures
+	readStats.source("cloudwatch_logs").Add(processor.ReadCounts{Lines: int64(len(data.LogEvents)), Entries: int64(len(entries)), Filtered: dropped[processor.Filtered], SampledOut: dropped[processor.SampledOut], ParseTime: time.Since(start)})
+	return entries
+}
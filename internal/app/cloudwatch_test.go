@@ -0,0 +1,56 @@
+package app
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/exporter"
+)
+
+func TestInvoke_CloudWatchLogs(t *testing.T) {
+	logger = slog.New(slog.NewJSONHandler(io.Discard, nil))
+	maxBatchSize = 10
+	maxConcurrent = 2
+
+	prevExp := exp
+	defer func() { exp = prevExp }()
+
+	data := base64.StdEncoding.EncodeToString(cloudWatchPayload(t, "DATA_MESSAGE", testNLBLine, "plain application log"))
+	payload := []byte(`{"awslogs":{"data":"` + data + `"}}`)
+
+	tests := []struct {
+		name         string
+		exportErr    error
+		wantExported int
+		wantErr      bool
+	}{
+		{"exports parsed and plain messages", nil, 2, false},
+		{"export failure is retried", errors.New("backend down"), 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var exported atomic.Int64
+			exp = exporter.ExporterFunc(func(ctx context.Context, logs converter.ResourceLog) error {
+				if tt.exportErr != nil {
+					return tt.exportErr
+				}
+				exported.Add(int64(len(logs.ScopeLogs[0].LogRecords)))
+				return nil
+			})
+
+			_, err := Invoke(context.Background(), payload)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Invoke() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := exported.Load(); got != int64(tt.wantExported) {
+				t.Errorf("exported %d records, want %d", got, tt.wantExported)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/config"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/processor"
+)
+
+// loadConfigFile reads the YAML or JSON configuration named by CONFIG_FILE into settings, so
+// variables the environment leaves unset are taken from it. CONFIG_FILE is a path bundled with
+// the function, an s3://bucket/key object or an ssm:<parameter name> parameter. It runs before
+// the object store exists, so S3 is read with the function's own credentials and the SDK's
+// default retries.
+func loadConfigFile() error {
+	location := getEnv("CONFIG_FILE", "")
+	if location == "" {
+		return nil
+	}
+	data, err := readConfigFile(location)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", location, err)
+	}
+	values, err := config.ParseFile(data)
+	if err != nil {
+		return err
+	}
+	settings.SetFile(values)
+	logger.Info("Loaded config file", "location", location, "keys", len(values))
+	return nil
+}
+
+// readConfigFile fetches the configuration from a local path, S3 or SSM Parameter Store
+func readConfigFile(location string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	if !processor.IsS3URI(location) && !strings.HasPrefix(location, "ssm:") {
+		return os.ReadFile(location)
+	}
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if name, ok := strings.CutPrefix(location, "ssm:"); ok {
+		out, err := ssm.NewFromConfig(awsCfg).GetParameter(ctx, &ssm.GetParameterInput{
+			Name:           aws.String(name),
+			WithDecryption: aws.Bool(true),
+		})
+		if err != nil {
+			return nil, err
+		}
+		return []byte(aws.ToString(out.Parameter.Value)), nil
+	}
+
+	bucket, key, err := processor.ParseS3URI(location)
+	if err != nil {
+		return nil, err
+	}
+	out, err := s3.NewFromConfig(awsCfg).GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer out.Body.Close()
+	return io.ReadAll(out.Body)
+}
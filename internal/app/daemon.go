@@ -0,0 +1,215 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/version"
+)
+
+// daemonRetryDelay is the pause after a failed receive before polling again
+const daemonRetryDelay = 5 * time.Second
+
+// RunDaemon implements the daemon binary (cmd/daemon), for running on ECS, EKS or a plain host
+// instead of Lambda. It long-polls DAEMON_QUEUE_URL, a queue of S3 notifications like the one the function
+// is triggered by, and serves a health endpoint on DAEMON_HEALTH_ADDR until SIGTERM:
+//
+//	daemon
+func RunDaemon(args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("daemon takes no arguments, it is configured through the environment")
+	}
+	if err := Configure(); err != nil {
+		return err
+	}
+	queueURL := getEnv("DAEMON_QUEUE_URL", "")
+	if queueURL == "" {
+		return fmt.Errorf("DAEMON_QUEUE_URL is required")
+	}
+	awsCfg, err := awsConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load AWS config: %w", err)
+	}
+	d := &sqsDaemon{
+		client:      sqs.NewFromConfig(awsCfg),
+		queueURL:    queueURL,
+		maxMessages: int32(settings.IntRange("DAEMON_MAX_MESSAGES", 10, 1, 10)),
+		wait:        int32(settings.IntRange("DAEMON_WAIT_SECONDS", 20, 0, 20)),
+		timeout:     time.Duration(settings.IntRange("DAEMON_BATCH_TIMEOUT_SECONDS", 900, 1, 43200)) * time.Second,
+	}
+	healthAddr := getEnv("DAEMON_HEALTH_ADDR", ":8080")
+	if err := settings.Err(); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	server := &http.Server{Addr: healthAddr, Handler: d.healthHandler(), ReadHeaderTimeout: 10 * time.Second}
+	serverErr := make(chan error, 1)
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+			stop()
+		}
+	}()
+
+	logger.Info("Daemon polling SQS", "queue_url", queueURL, "health_addr", healthAddr, "max_messages", d.maxMessages)
+	d.run(ctx)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	server.Shutdown(shutdownCtx)
+	logger.Info("Daemon stopped")
+	select {
+	case err := <-serverErr:
+		return fmt.Errorf("health endpoint: %w", err)
+	default:
+		return nil
+	}
+}
+
+// sqsAPI is the part of the SQS client the daemon and the large object queue use
+type sqsAPI interface {
+	SendMessage(ctx context.Context, in *sqs.SendMessageInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageOutput, error)
+	ReceiveMessage(ctx context.Context, in *sqs.ReceiveMessageInput, optFns ...func(*sqs.Options)) (*sqs.ReceiveMessageOutput, error)
+	DeleteMessageBatch(ctx context.Context, in *sqs.DeleteMessageBatchInput, optFns ...func(*sqs.Options)) (*sqs.DeleteMessageBatchOutput, error)
+}
+
+// sqsDaemon receives batches of S3 notifications from an SQS queue and runs them through
+// handler one batch at a time, the way the Lambda SQS trigger does: the messages of a batch
+// that succeed are deleted, and the failed ones become visible again after the queue's
+// visibility timeout. Objects within a batch are processed MAX_CONCURRENT at a time.
+type sqsDaemon struct {
+	client      sqsAPI
+	queueURL    string
+	maxMessages int32
+	wait        int32
+	// timeout bounds each batch, like the function timeout; set the queue's visibility timeout
+	// above it
+	timeout time.Duration
+
+	mu       sync.Mutex
+	draining bool
+	lastErr  error
+}
+
+// run polls until ctx is done. A batch in flight when ctx ends is finished first.
+func (d *sqsDaemon) run(ctx context.Context) {
+	// AppConfig changes are applied between batches, and while the queue is idle
+	go watchDynamicSettings(ctx)
+	go func() {
+		<-ctx.Done()
+		d.mu.Lock()
+		d.draining = true
+		d.mu.Unlock()
+	}()
+	for ctx.Err() == nil {
+		out, err := d.client.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+			QueueUrl:            aws.String(d.queueURL),
+			MaxNumberOfMessages: d.maxMessages,
+			WaitTimeSeconds:     d.wait,
+		})
+		if ctx.Err() != nil {
+			break
+		}
+		d.setErr(err)
+		if err != nil {
+			logger.Error("Failed to receive SQS messages", "error", err)
+			select {
+			case <-ctx.Done():
+			case <-time.After(daemonRetryDelay):
+			}
+			continue
+		}
+		if len(out.Messages) > 0 {
+			d.process(ctx, out.Messages)
+		}
+	}
+}
+
+// process runs one received batch through handler and deletes the messages that succeeded.
+// The batch is not cancelled by shutdown, only by the batch timeout.
+func (d *sqsDaemon) process(ctx context.Context, messages []types.Message) {
+	event := events.SQSEvent{}
+	for _, m := range messages {
+		event.Records = append(event.Records, events.SQSMessage{
+			MessageId:     aws.ToString(m.MessageId),
+			ReceiptHandle: aws.ToString(m.ReceiptHandle),
+			Body:          aws.ToString(m.Body),
+			EventSource:   "aws:sqs",
+		})
+	}
+
+	batchCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), d.timeout)
+	defer cancel()
+	refreshDynamicSettings(batchCtx)
+	dynamicMu.RLock()
+	resp, err := handler(batchCtx, event)
+	dynamicMu.RUnlock()
+	if err != nil {
+		logger.Error("Batch failed, leaving its messages for redelivery", "error", err, "message_count", len(messages))
+		return
+	}
+
+	failed := make(map[string]bool, len(resp.BatchItemFailures))
+	for _, f := range resp.BatchItemFailures {
+		failed[f.ItemIdentifier] = true
+	}
+	var entries []types.DeleteMessageBatchRequestEntry
+	for _, record := range event.Records {
+		if !failed[record.MessageId] {
+			entries = append(entries, types.DeleteMessageBatchRequestEntry{Id: aws.String(record.MessageId), ReceiptHandle: aws.String(record.ReceiptHandle)})
+		}
+	}
+	if len(entries) == 0 {
+		return
+	}
+	out, err := d.client.DeleteMessageBatch(batchCtx, &sqs.DeleteMessageBatchInput{QueueUrl: aws.String(d.queueURL), Entries: entries})
+	if err != nil {
+		logger.Error("Failed to delete processed SQS messages, they will be redelivered", "error", err, "message_count", len(entries))
+		return
+	}
+	for _, f := range out.Failed {
+		logger.Warn("Failed to delete processed SQS message, it will be redelivered", "message_id", aws.ToString(f.Id), "error", aws.ToString(f.Message))
+	}
+}
+
+func (d *sqsDaemon) setErr(err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.lastErr = err
+}
+
+// healthHandler serves GET /healthz, which fails while the last receive failed and once the
+// daemon is shutting down, and GET /version
+func (d *sqsDaemon) healthHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		d.mu.Lock()
+		draining, lastErr := d.draining, d.lastErr
+		d.mu.Unlock()
+		switch {
+		case draining:
+			writeJSON(w, http.StatusServiceUnavailable, map[string]any{"status": "draining", "version": version.Info()})
+		case lastErr != nil:
+			writeJSON(w, http.StatusServiceUnavailable, map[string]any{"status": "error", "error": lastErr.Error(), "version": version.Info()})
+		default:
+			writeJSON(w, http.StatusOK, map[string]any{"status": "ok", "version": version.Info()})
+		}
+	})
+	mux.Handle("/version", version.Handler())
+	return mux
+}
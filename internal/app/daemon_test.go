@@ -0,0 +1,103 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/exporter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/processor"
+)
+
+// fakeQueue serves batches, then cancels the daemon
+type fakeQueue struct {
+	sqsAPI
+	batches [][]types.Message
+	cancel  context.CancelFunc
+	deleted []string
+}
+
+func (f *fakeQueue) ReceiveMessage(ctx context.Context, in *sqs.ReceiveMessageInput, optFns ...func(*sqs.Options)) (*sqs.ReceiveMessageOutput, error) {
+	if len(f.batches) == 0 {
+		f.cancel()
+		return nil, ctx.Err()
+	}
+	batch := f.batches[0]
+	f.batches = f.batches[1:]
+	return &sqs.ReceiveMessageOutput{Messages: batch}, nil
+}
+
+func (f *fakeQueue) DeleteMessageBatch(ctx context.Context, in *sqs.DeleteMessageBatchInput, optFns ...func(*sqs.Options)) (*sqs.DeleteMessageBatchOutput, error) {
+	for _, e := range in.Entries {
+		f.deleted = append(f.deleted, aws.ToString(e.Id))
+	}
+	return &sqs.DeleteMessageBatchOutput{}, nil
+}
+
+func sqsMessage(id, key string) types.Message {
+	m := s3Message(id, key)
+	return types.Message{MessageId: aws.String(m.MessageId), ReceiptHandle: aws.String("rh-" + id), Body: aws.String(m.Body)}
+}
+
+func TestSQSDaemon_Run(t *testing.T) {
+	logger = slog.New(slog.NewJSONHandler(io.Discard, nil))
+	maxBatchSize = 10
+	maxConcurrent = 2
+
+	prevRegistry, prevExp := registry, exp
+	defer func() { registry, exp = prevRegistry, prevExp }()
+	registry = processor.NewRegistry()
+	registry.Register(fakeProcessor{})
+	exp = exporter.ExporterFunc(func(ctx context.Context, logs converter.ResourceLog) error { return nil })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	queue := &fakeQueue{
+		batches: [][]types.Message{
+			{sqsMessage("m1", "good"), sqsMessage("m2", "bad")},
+			{sqsMessage("m3", "empty")},
+		},
+		cancel: cancel,
+	}
+	d := &sqsDaemon{client: queue, queueURL: "queue", maxMessages: 10, timeout: time.Minute}
+	d.run(ctx)
+
+	// The bad object's message is left for redelivery
+	sort.Strings(queue.deleted)
+	if len(queue.deleted) != 2 || queue.deleted[0] != "m1" || queue.deleted[1] != "m3" {
+		t.Errorf("deleted = %v, want [m1 m3]", queue.deleted)
+	}
+}
+
+func TestSQSDaemon_Health(t *testing.T) {
+	d := &sqsDaemon{}
+	health := func() int {
+		rec := httptest.NewRecorder()
+		d.healthHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+		return rec.Code
+	}
+
+	if code := health(); code != http.StatusOK {
+		t.Errorf("healthz = %d, want 200", code)
+	}
+	d.setErr(errors.New("access denied"))
+	if code := health(); code != http.StatusServiceUnavailable {
+		t.Errorf("healthz after a failed receive = %d, want 503", code)
+	}
+	d.setErr(nil)
+	d.draining = true
+	if code := health(); code != http.StatusServiceUnavailable {
+		t.Errorf("healthz while draining = %d, want 503", code)
+	}
+}
@@ -0,0 +1,21 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// errDeadlineMargin is the cause reported when work stops because the invocation is about to time out
+var errDeadlineMargin = errors.New("remaining Lambda time is below the deadline safety margin")
+
+// launchContext returns the context that gates starting new work: it ends with ctx, or margin
+// before the invocation deadline so batches already in flight can finish and the handler can
+// report what was left unsent. Exports themselves keep running on ctx.
+func launchContext(ctx context.Context, margin time.Duration) (context.Context, context.CancelFunc) {
+	deadline, ok := ctx.Deadline()
+	if !ok || margin <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithDeadlineCause(ctx, deadline.Add(-margin), errDeadlineMargin)
+}
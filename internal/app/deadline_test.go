@@ -0,0 +1,106 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/exporter"
+)
+
+func TestLaunchContext(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+
+	launchCtx, stop := launchContext(ctx, time.Minute)
+	defer stop()
+	deadline, ok := launchCtx.Deadline()
+	if !ok || time.Until(deadline) > 59*time.Minute+time.Second {
+		t.Errorf("launch deadline = %v, want one minute before the invocation deadline", deadline)
+	}
+
+	launchCtx, stop = launchContext(ctx, 2*time.Hour)
+	defer stop()
+	<-launchCtx.Done()
+	if !errors.Is(context.Cause(launchCtx), errDeadlineMargin) {
+		t.Errorf("cause = %v, want %v", context.Cause(launchCtx), errDeadlineMargin)
+	}
+	if ctx.Err() != nil {
+		t.Error("launch context cancelled the invocation context")
+	}
+
+	launchCtx, stop = launchContext(context.Background(), time.Minute)
+	defer stop()
+	if _, ok := launchCtx.Deadline(); ok {
+		t.Error("launch context has a deadline without an invocation deadline")
+	}
+}
+
+func TestStreamSink_StopsAtDeadlineMargin(t *testing.T) {
+	logger = slog.New(slog.NewJSONHandler(io.Discard, nil))
+	maxBatchSize = 2
+	maxConcurrent = 1
+
+	exported := 0
+	sink := newStreamSink(context.Background(), exporter.ExporterFunc(func(ctx context.Context, logs converter.ResourceLog) error {
+		exported += len(logs.ScopeLogs[0].LogRecords)
+		return nil
+	}), nil, 0)
+
+	launchCtx, cancel := context.WithCancelCause(context.Background())
+	for i := 0; i < 3; i++ {
+		if err := sink.Add(launchCtx, fakeEntry{9}); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+	cancel(errDeadlineMargin)
+
+	if err := sink.Add(launchCtx, fakeEntry{9}); !errors.Is(err, errDeadlineMargin) {
+		t.Errorf("Add() after the margin error = %v, want %v", err, errDeadlineMargin)
+	}
+	if err := sink.Close(launchCtx); !errors.Is(err, errDeadlineMargin) {
+		t.Errorf("Close() error = %v, want %v", err, errDeadlineMargin)
+	}
+	if exported != 0 {
+		t.Errorf("exported %d records after the margin was reached, want 0", exported)
+	}
+}
+
+func TestStreamSink_CloseFailsAfterDroppedBatch(t *testing.T) {
+	logger = slog.New(slog.NewJSONHandler(io.Discard, nil))
+	maxBatchSize = 2
+	maxConcurrent = 1
+
+	exported := 0
+	release := make(chan struct{})
+	sink := newStreamSink(context.Background(), exporter.ExporterFunc(func(ctx context.Context, logs converter.ResourceLog) error {
+		<-release
+		exported += len(logs.ScopeLogs[0].LogRecords)
+		return nil
+	}), nil, 2)
+
+	// The first batch holds the only export slot, so the second one waits until the margin
+	launchCtx, cancel := context.WithTimeoutCause(context.Background(), 50*time.Millisecond, errDeadlineMargin)
+	defer cancel()
+	for i := 0; i < 3; i++ {
+		if err := sink.Add(launchCtx, fakeEntry{9}); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+	if err := sink.Add(launchCtx, fakeEntry{9}); !errors.Is(err, errDeadlineMargin) {
+		t.Fatalf("Add() completing a batch after the margin error = %v, want %v", err, errDeadlineMargin)
+	}
+	close(release)
+
+	// Nothing is left buffered, yet the dropped batch must fail the invocation
+	if err := sink.Close(context.Background()); !errors.Is(err, errDeadlineMargin) {
+		t.Errorf("Close() error = %v, want %v", err, errDeadlineMargin)
+	}
+	if exported != 2 {
+		t.Errorf("exported %d records, want 2", exported)
+	}
+}
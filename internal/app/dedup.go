@@ -0,0 +1,69 @@
+package app
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/processor"
+)
+
+// objectClaims tracks the objects claimed in the dedup table during one invocation
+type objectClaims struct {
+	mu sync.Mutex
+	// processed maps each claimed id to whether its object was processed without error
+	processed map[string]bool
+}
+
+// claim claims an object version before it is processed. It returns the id to report back
+// through done, or "" when dedup is disabled, the event has no ETag, or the table is
+// unreachable; in those cases the object is processed anyway.
+func (c *objectClaims) claim(ctx context.Context, log *slog.Logger, bucket, key, etag string) (processor.ClaimResult, string) {
+	if dedup == nil || etag == "" {
+		return processor.ClaimAcquired, ""
+	}
+	id := processor.CheckpointID(bucket, key, etag)
+	result, err := dedup.Claim(ctx, id)
+	if err != nil {
+		log.Warn("Dedup check failed, processing object anyway", "error", err)
+		return processor.ClaimAcquired, ""
+	}
+	if result == processor.ClaimAcquired {
+		c.mu.Lock()
+		if c.processed == nil {
+			c.processed = make(map[string]bool)
+		}
+		c.processed[id] = false
+		c.mu.Unlock()
+	}
+	return result, id
+}
+
+// done records whether a claimed object was processed successfully
+func (c *objectClaims) done(id string, ok bool) {
+	if id == "" {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.processed[id] = ok
+}
+
+// settle marks objects done once their records are exported and releases the rest so a
+// redelivery can claim them again
+func (c *objectClaims) settle(ctx context.Context, exported bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for id, ok := range c.processed {
+		var err error
+		if exported && ok {
+			err = dedup.Complete(ctx, id)
+		} else {
+			err = dedup.Release(ctx, id)
+		}
+		if err != nil {
+			logger.Warn("Failed to update dedup table", "id", id, "error", err)
+		}
+	}
+	c.processed = nil
+}
@@ -0,0 +1,108 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/pixelvide/otel-aws-log-parser/cmd/lambda/adapter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/exporter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/processor"
+)
+
+// partialProcessor streams one entry per object. "partial" fails after its first entry, as a
+// truncated read does, and "large" is rejected by the size limit before it is read.
+type partialProcessor struct{}
+
+func (partialProcessor) Name() string                    { return "partial" }
+func (partialProcessor) Matches(bucket, key string) bool { return true }
+func (partialProcessor) Process(ctx context.Context, logger *slog.Logger, store processor.ObjectStore, bucket, key string) ([]adapter.LogAdapter, error) {
+	return nil, errors.New("not used")
+}
+func (partialProcessor) ProcessStream(ctx context.Context, logger *slog.Logger, store processor.ObjectStore, bucket, key string, emit processor.EmitFunc, cp *processor.Checkpoint) error {
+	if key == "large" {
+		return processor.ErrObjectTooLarge
+	}
+	if err := emit(fakeEntry{9}); err != nil {
+		return err
+	}
+	if key == "partial" {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+
+// fakeDedup grants every claim and records how each one was settled
+type fakeDedup struct {
+	mu        sync.Mutex
+	completed map[string]bool
+	released  map[string]bool
+}
+
+func (f *fakeDedup) Claim(ctx context.Context, id string) (processor.ClaimResult, error) {
+	return processor.ClaimAcquired, nil
+}
+
+func (f *fakeDedup) Complete(ctx context.Context, id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.completed[id] = true
+	return nil
+}
+
+func (f *fakeDedup) Release(ctx context.Context, id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.released[id] = true
+	return nil
+}
+
+func TestHandler_DedupSettle(t *testing.T) {
+	logger = slog.New(slog.NewJSONHandler(io.Discard, nil))
+	maxBatchSize = 10
+	maxConcurrent = 2
+
+	prevRegistry, prevExp, prevDedup := registry, exp, dedup
+	defer func() { registry, exp, dedup = prevRegistry, prevExp, prevDedup }()
+	registry = processor.NewRegistry()
+	registry.Register(partialProcessor{})
+	exp = exporter.ExporterFunc(func(ctx context.Context, logs converter.ResourceLog) error { return nil })
+
+	store := &fakeDedup{completed: map[string]bool{}, released: map[string]bool{}}
+	dedup = store
+
+	message := func(id, key string) events.SQSMessage {
+		return events.SQSMessage{
+			MessageId: id,
+			Body:      `{"source":"aws.s3","detail":{"bucket":{"name":"logs"},"object":{"key":"` + key + `","etag":"e1"}}}`,
+		}
+	}
+	event := events.SQSEvent{Records: []events.SQSMessage{
+		message("m1", "good"), message("m2", "partial"), message("m3", "large"),
+	}}
+	if _, err := handler(context.Background(), event); err != nil {
+		t.Fatalf("handler() error = %v", err)
+	}
+
+	tests := []struct {
+		key       string
+		completed bool
+	}{
+		{"good", true},
+		{"partial", false},
+		// Skipped by the size limit without being read
+		{"large", false},
+	}
+	for _, tt := range tests {
+		id := processor.CheckpointID("logs", tt.key, "e1")
+		if store.completed[id] != tt.completed || store.released[id] == tt.completed {
+			t.Errorf("%s: completed=%v released=%v, want completed=%v", tt.key, store.completed[id], store.released[id], tt.completed)
+		}
+	}
+}
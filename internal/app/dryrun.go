@@ -0,0 +1,77 @@
+package app
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+)
+
+// dryRun replaces every exporter when DRY_RUN is set: objects are still downloaded, parsed,
+// converted and batched, but the batches are logged instead of sent; nil otherwise
+var dryRun *dryRunExporter
+
+// dryRunExporter stands in for the logs, metrics and traces exporters in a dry run. It logs
+// every batch it is given, with the first sample log records of the invocation.
+type dryRunExporter struct {
+	sample  int64
+	sampled atomic.Int64
+}
+
+// newDryRunExporter returns the dry-run exporter of DRY_RUN and DRY_RUN_SAMPLE_RECORDS, or nil
+func newDryRunExporter() *dryRunExporter {
+	if !getEnvBool("DRY_RUN", false) {
+		return nil
+	}
+	return &dryRunExporter{sample: int64(getEnvInt("DRY_RUN_SAMPLE_RECORDS", 5))}
+}
+
+// reset starts a new invocation's sample
+func (d *dryRunExporter) reset() {
+	if d != nil {
+		d.sampled.Store(0)
+	}
+}
+
+// Export logs the batch and the records still needed for the invocation's sample
+func (d *dryRunExporter) Export(ctx context.Context, logs converter.ResourceLog) error {
+	records := 0
+	var sample []converter.OTelLogRecord
+	for _, sl := range logs.ScopeLogs {
+		records += len(sl.LogRecords)
+		for _, record := range sl.LogRecords {
+			if d.sampled.Add(1) > d.sample {
+				break
+			}
+			sample = append(sample, record)
+		}
+	}
+	args := []any{"records", records, "resource", logs.Resource.Attributes}
+	if len(sample) > 0 {
+		args = append(args, "sample", sample)
+	}
+	logger.Info("Dry run: log batch not exported", args...)
+	return nil
+}
+
+// ExportMetrics logs the names of the metrics that would have been sent
+func (d *dryRunExporter) ExportMetrics(ctx context.Context, metrics converter.ResourceMetric) error {
+	var names []string
+	for _, sm := range metrics.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			names = append(names, m.Name)
+		}
+	}
+	logger.Info("Dry run: metrics not exported", "metrics", names)
+	return nil
+}
+
+// ExportSpans logs how many spans would have been sent
+func (d *dryRunExporter) ExportSpans(ctx context.Context, spans converter.ResourceSpans) error {
+	count := 0
+	for _, ss := range spans.ScopeSpans {
+		count += len(ss.Spans)
+	}
+	logger.Info("Dry run: spans not exported", "spans", count)
+	return nil
+}
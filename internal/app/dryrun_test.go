@@ -0,0 +1,55 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+)
+
+func TestDryRunExporter_Sample(t *testing.T) {
+	var buf bytes.Buffer
+	logger = slog.New(slog.NewJSONHandler(&buf, nil))
+
+	batch := converter.ResourceLog{ScopeLogs: []converter.ScopeLog{{LogRecords: make([]converter.OTelLogRecord, 2)}}}
+	d := &dryRunExporter{sample: 3}
+	samples := func() []int {
+		var got []int
+		dec := json.NewDecoder(&buf)
+		for dec.More() {
+			var line struct {
+				Records int               `json:"records"`
+				Sample  []json.RawMessage `json:"sample"`
+			}
+			if err := dec.Decode(&line); err != nil {
+				t.Fatal(err)
+			}
+			if line.Records != 2 {
+				t.Errorf("records = %d, want 2", line.Records)
+			}
+			got = append(got, len(line.Sample))
+		}
+		return got
+	}
+
+	for range 3 {
+		if err := d.Export(context.Background(), batch); err != nil {
+			t.Fatalf("Export() error = %v", err)
+		}
+	}
+	if got := samples(); len(got) != 3 || got[0] != 2 || got[1] != 1 || got[2] != 0 {
+		t.Errorf("sampled records per batch = %v, want [2 1 0]", got)
+	}
+
+	// A new invocation samples again
+	d.reset()
+	if err := d.Export(context.Background(), batch); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if got := samples(); len(got) != 1 || got[0] != 2 {
+		t.Errorf("sampled records after reset = %v, want [2]", got)
+	}
+}
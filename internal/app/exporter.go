@@ -0,0 +1,523 @@
+package app
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/firehose"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/exporter"
+)
+
+// newExporters builds the exporters named in a comma-separated list such as "otlp,s3". A single
+// kind is returned as is; several are combined in a FanOut where the kinds listed in optional
+// only log their failures.
+func newExporters(kinds string, optional []string) (exporter.Exporter, error) {
+	var members []exporter.FanOutMember
+	for _, kind := range strings.Split(kinds, ",") {
+		kind = strings.TrimSpace(kind)
+		if kind == "" {
+			continue
+		}
+		e, err := newExporter(kind)
+		if err != nil {
+			return nil, fmt.Errorf("%s exporter: %w", kind, err)
+		}
+		members = append(members, exporter.FanOutMember{Name: kind, Exporter: e, Optional: slices.Contains(optional, kind)})
+	}
+	if dryRun != nil && len(members) > 0 {
+		// Built anyway so their settings are checked
+		return dryRun, nil
+	}
+
+	switch len(members) {
+	case 0:
+		return nil, fmt.Errorf("no exporter configured")
+	case 1:
+		return members[0].Exporter, nil
+	}
+	return exporter.NewFanOut(logger, members...), nil
+}
+
+// exportSummary returns the per-exporter record counts of this invocation, or nil without fan-out
+func exportSummary() map[string]exporter.ExportCounts {
+	var summary map[string]exporter.ExportCounts
+	for _, e := range []exporter.Exporter{exp, divertExp} {
+		if fan, ok := e.(*exporter.FanOut); ok {
+			if summary == nil {
+				summary = make(map[string]exporter.ExportCounts)
+			}
+			for name, counts := range fan.TakeCounts() {
+				summary[name] = counts
+			}
+		}
+	}
+	return summary
+}
+
+// retryBudget caps the backoff time of all export retries in an invocation when
+// RETRY_BUDGET_SECONDS is set
+var retryBudget *exporter.RetryBudget
+
+// newRetryBudget returns the budget of RETRY_BUDGET_SECONDS, or nil (per-batch retries only)
+// when it is unset or zero
+func newRetryBudget() *exporter.RetryBudget {
+	seconds := getEnvInt("RETRY_BUDGET_SECONDS", 0)
+	if seconds <= 0 {
+		return nil
+	}
+	return exporter.NewRetryBudget(time.Duration(seconds) * time.Second)
+}
+
+// maxRetryAfter returns MAX_RETRY_AFTER_SECONDS, the longest server Retry-After exports wait
+// for; 0 leaves the wait to the invocation deadline and the retry budget
+func maxRetryAfter() time.Duration {
+	return time.Duration(settings.IntRange("MAX_RETRY_AFTER_SECONDS", 0, 0, 3600)) * time.Second
+}
+
+// exportLimiter paces the requests of all OTLP exporters when MAX_EXPORT_RPS or
+// MAX_EXPORT_BYTES_PER_SEC is set
+var exportLimiter *exporter.RateLimiter
+
+// newExporter builds an exporter of the given kind (EXPORTER, or PIPELINE_DIVERT_EXPORTER for the
+// divert sink) from environment configuration
+func newExporter(kind string) (exporter.Exporter, error) {
+	retry := exporter.RetryPolicy{
+		MaxRetries:    getEnvInt("MAX_RETRIES", 3),
+		BaseDelay:     time.Second,
+		OnRetry:       exportTally.retry,
+		Budget:        retryBudget,
+		MaxRetryAfter: maxRetryAfter(),
+	}
+	partial := exporter.PartialSuccessAction(getEnv("OTLP_PARTIAL_SUCCESS", string(exporter.PartialSuccessLog)))
+	compression := getEnv("OTLP_COMPRESSION", "none")
+
+	switch kind {
+	case "otlp":
+		base, err := newOTLPExporter(nil, retry, partial, compression)
+		if err != nil {
+			return nil, err
+		}
+		routed, err := routedOTLPExporter(base, retry, partial, compression)
+		if err != nil {
+			return nil, err
+		}
+		return processorOTLPExporter(routed, retry, partial, compression)
+	case "firehose":
+		awsCfg, err := awsConfig()
+		if err != nil {
+			return nil, err
+		}
+		return exporter.NewFirehoseExporter(firehose.NewFromConfig(awsCfg), exporter.FirehoseConfig{
+			DeliveryStream: getEnv("FIREHOSE_DELIVERY_STREAM", ""),
+			Format:         getEnv("FIREHOSE_FORMAT", "ndjson"),
+			Retry:          retry,
+			Logger:         logger,
+		})
+	case "loki":
+		client, err := sharedHTTPClient()
+		if err != nil {
+			return nil, err
+		}
+		pass, err := basicAuthPassword()
+		if err != nil {
+			return nil, err
+		}
+		return exporter.NewLokiExporter(exporter.LokiConfig{
+			Endpoint:      getEnv("LOKI_ENDPOINT", "http://localhost:3100/loki/api/v1/push"),
+			Labels:        getEnvList("LOKI_LABELS"),
+			LevelLabel:    getEnvBool("LOKI_LEVEL_LABEL", true),
+			TenantID:      getEnv("LOKI_TENANT_ID", ""),
+			Compression:   compression,
+			BasicAuthUser: getEnv("BASIC_AUTH_USERNAME", ""),
+			BasicAuthPass: pass,
+			Client:        client,
+			Retry:         retry,
+			Logger:        logger,
+		})
+	case "opensearch":
+		client, err := sharedHTTPClient()
+		if err != nil {
+			return nil, err
+		}
+		pass, err := basicAuthPassword()
+		if err != nil {
+			return nil, err
+		}
+		cfg := exporter.OpenSearchConfig{
+			Endpoint:       getEnv("OPENSEARCH_ENDPOINT", ""),
+			Index:          getEnv("OPENSEARCH_INDEX", "aws-logs-%{yyyy.MM.dd}"),
+			BasicAuthUser:  getEnv("BASIC_AUTH_USERNAME", ""),
+			BasicAuthPass:  pass,
+			Client:         client,
+			Retry:          retry,
+			PartialSuccess: partial,
+			Logger:         logger,
+		}
+		if getEnvBool("OPENSEARCH_SIGV4", false) {
+			cfg.SigV4 = &exporter.SigV4Config{
+				Region:  getEnv("AWS_REGION", ""),
+				Service: getEnv("OPENSEARCH_SIGV4_SERVICE", "es"),
+			}
+		}
+		return exporter.NewOpenSearchExporter(cfg)
+	case "kafka":
+		cfg := exporter.KafkaConfig{
+			Brokers:       getEnvList("KAFKA_BROKERS"),
+			Topic:         getEnv("KAFKA_TOPIC", "aws-logs"),
+			Format:        getEnv("KAFKA_FORMAT", "ndjson"),
+			KeyAttribute:  getEnv("KAFKA_KEY_ATTRIBUTE", ""),
+			SASLMechanism: getEnv("KAFKA_SASL_MECHANISM", ""),
+			SASLUser:      getEnv("KAFKA_SASL_USERNAME", ""),
+			SASLPass:      getEnv("KAFKA_SASL_PASSWORD", ""),
+			TLS:           getEnvBool("KAFKA_TLS", false),
+			TLSSkipVerify: getEnvBool("KAFKA_TLS_SKIP_VERIFY", false),
+			Compression:   getEnv("KAFKA_COMPRESSION", "none"),
+			Retry:         retry,
+			Logger:        logger,
+		}
+		writer, err := exporter.NewKafkaWriter(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return exporter.NewKafkaExporter(writer, cfg)
+	case "s3":
+		awsCfg, err := awsConfig()
+		if err != nil {
+			return nil, err
+		}
+		return exporter.NewS3ArchiveExporter(s3.NewFromConfig(awsCfg), exporter.S3ArchiveConfig{
+			Bucket:    getEnv("S3_ARCHIVE_BUCKET", ""),
+			Prefix:    getEnv("S3_ARCHIVE_PREFIX", ""),
+			Partition: getEnv("S3_ARCHIVE_PARTITION", exporter.DefaultS3ArchivePartition),
+			Format:    getEnv("S3_ARCHIVE_FORMAT", "ndjson"),
+			Retry:     retry,
+			Logger:    logger,
+		})
+	default:
+		return nil, fmt.Errorf("unknown EXPORTER %q", kind)
+	}
+}
+
+// newOTLPExporter builds the OTLP logs exporter (gRPC or HTTP per OTLP_PROTOCOL); a route
+// overrides its endpoint, adds to its headers and replaces its basic auth credentials
+func newOTLPExporter(route *otlpRoute, retry exporter.RetryPolicy, partial exporter.PartialSuccessAction, compression string) (exporter.Exporter, error) {
+	oauth2, err := otlpOAuth2()
+	if err != nil {
+		return nil, err
+	}
+	headers, err := exporter.ParseHeaders(getEnv("OTLP_HEADERS", ""))
+	if err != nil {
+		return nil, fmt.Errorf("invalid OTLP_HEADERS: %w", err)
+	}
+	grpcEndpoint := getEnv("OTLP_GRPC_ENDPOINT", "localhost:4317")
+	httpEndpoint := getEnv("SIGNOZ_OTLP_ENDPOINT", "http://localhost:4318/v1/logs")
+	pass, err := basicAuthPassword()
+	if err != nil {
+		return nil, err
+	}
+	user := getEnv("BASIC_AUTH_USERNAME", "")
+	if route != nil {
+		if route.Endpoint != "" {
+			grpcEndpoint, httpEndpoint = route.Endpoint, route.Endpoint
+		}
+		for key, value := range route.Headers {
+			headers[key] = value
+		}
+		if route.BasicAuthUser != "" {
+			user, pass = route.BasicAuthUser, route.BasicAuthPass
+		}
+	}
+	if getEnv("OTLP_PROTOCOL", "http") == "grpc" {
+		cfg, err := otlpGRPCConfig(grpcEndpoint)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Compression = compression
+		cfg.BasicAuthUser, cfg.BasicAuthPass = user, pass
+		cfg.Headers = headers
+		cfg.OAuth2 = oauth2
+		cfg.Retry = retry
+		cfg.PartialSuccess = partial
+		return exporter.NewOTLPGRPCExporter(cfg)
+	}
+	client, err := sharedHTTPClient()
+	if err != nil {
+		return nil, err
+	}
+	return exporter.NewOTLPHTTPExporter(exporter.OTLPHTTPConfig{
+		Endpoint:       httpEndpoint,
+		Encoding:       getEnv("OTLP_ENCODING", "json"),
+		Compression:    compression,
+		BasicAuthUser:  user,
+		BasicAuthPass:  pass,
+		Headers:        headers,
+		SigV4:          otlpSigV4(),
+		OAuth2:         oauth2,
+		Client:         client,
+		Retry:          retry,
+		PartialSuccess: partial,
+		Logger:         logger,
+		RateLimit:      exportLimiter,
+	}), nil
+}
+
+// newMetricsExporter builds the OTLP metrics exporter over OTLP_PROTOCOL. Endpoint and retries
+// are independent of the logs exporter. Over HTTP the endpoint defaults to SIGNOZ_OTLP_ENDPOINT
+// with /v1/logs -> /v1/metrics; over gRPC to OTLP_GRPC_ENDPOINT, which serves every signal.
+func newMetricsExporter() (exporter.MetricsExporter, error) {
+	maxRetries := getEnvInt("METRICS_MAX_RETRIES", getEnvInt("MAX_RETRIES", 3))
+	if getEnv("OTLP_PROTOCOL", "http") == "grpc" {
+		cfg, err := otlpGRPCSignalConfig("OTLP_METRICS_ENDPOINT", maxRetries)
+		if err != nil {
+			return nil, err
+		}
+		if dryRun != nil {
+			return dryRun, nil
+		}
+		return exporter.NewOTLPGRPCMetricsExporter(cfg)
+	}
+	cfg, err := otlpSignalConfig("/v1/metrics", "OTLP_METRICS_ENDPOINT", maxRetries)
+	if err != nil {
+		return nil, err
+	}
+	if dryRun != nil {
+		return dryRun, nil
+	}
+	return exporter.NewOTLPHTTPMetricsExporter(cfg), nil
+}
+
+// newTracesExporter builds the OTLP/HTTP exporter for the function's own spans; the endpoint
+// defaults to SIGNOZ_OTLP_ENDPOINT with /v1/logs -> /v1/traces
+func newTracesExporter() (exporter.TracesExporter, error) {
+	cfg, err := otlpSignalConfig("/v1/traces", "OTLP_TRACES_ENDPOINT", getEnvInt("MAX_RETRIES", 3))
+	if err != nil {
+		return nil, err
+	}
+	if dryRun != nil {
+		return dryRun, nil
+	}
+	return exporter.NewOTLPHTTPTracesExporter(cfg), nil
+}
+
+// otlpSignalConfig is the OTLP/HTTP config of a signal other than logs: the logs endpoint's
+// auth, headers and compression, sent to endpointEnv or the logs endpoint with its path
+// replaced by path
+func otlpSignalConfig(path, endpointEnv string, maxRetries int) (exporter.OTLPHTTPConfig, error) {
+	oauth2, err := otlpOAuth2()
+	if err != nil {
+		return exporter.OTLPHTTPConfig{}, err
+	}
+	client, err := sharedHTTPClient()
+	if err != nil {
+		return exporter.OTLPHTTPConfig{}, err
+	}
+	pass, err := basicAuthPassword()
+	if err != nil {
+		return exporter.OTLPHTTPConfig{}, err
+	}
+	headers, err := exporter.ParseHeaders(getEnv("OTLP_HEADERS", ""))
+	if err != nil {
+		return exporter.OTLPHTTPConfig{}, fmt.Errorf("invalid OTLP_HEADERS: %w", err)
+	}
+
+	logsEndpoint := getEnv("SIGNOZ_OTLP_ENDPOINT", "http://localhost:4318/v1/logs")
+	defaultEndpoint := strings.TrimSuffix(logsEndpoint, "/v1/logs") + path
+
+	return exporter.OTLPHTTPConfig{
+		Endpoint:      getEnv(endpointEnv, defaultEndpoint),
+		Encoding:      getEnv("OTLP_ENCODING", "json"),
+		Compression:   getEnv("OTLP_COMPRESSION", "none"),
+		BasicAuthUser: getEnv("BASIC_AUTH_USERNAME", ""),
+		BasicAuthPass: pass,
+		Headers:       headers,
+		SigV4:         otlpSigV4(),
+		OAuth2:        oauth2,
+		Client:        client,
+		Retry: exporter.RetryPolicy{
+			MaxRetries:    maxRetries,
+			BaseDelay:     time.Second,
+			Budget:        retryBudget,
+			MaxRetryAfter: maxRetryAfter(),
+		},
+		PartialSuccess: exporter.PartialSuccessLog,
+		Logger:         logger,
+		RateLimit:      exportLimiter,
+	}, nil
+}
+
+// otlpGRPCSignalConfig is the OTLP/gRPC config of a signal other than logs: the logs
+// connection's TLS, auth and headers, sent to endpointEnv or OTLP_GRPC_ENDPOINT
+func otlpGRPCSignalConfig(endpointEnv string, maxRetries int) (exporter.OTLPGRPCConfig, error) {
+	oauth2, err := otlpOAuth2()
+	if err != nil {
+		return exporter.OTLPGRPCConfig{}, err
+	}
+	pass, err := basicAuthPassword()
+	if err != nil {
+		return exporter.OTLPGRPCConfig{}, err
+	}
+	headers, err := exporter.ParseHeaders(getEnv("OTLP_HEADERS", ""))
+	if err != nil {
+		return exporter.OTLPGRPCConfig{}, fmt.Errorf("invalid OTLP_HEADERS: %w", err)
+	}
+	cfg, err := otlpGRPCConfig(getEnv(endpointEnv, getEnv("OTLP_GRPC_ENDPOINT", "localhost:4317")))
+	if err != nil {
+		return exporter.OTLPGRPCConfig{}, err
+	}
+	cfg.Compression = getEnv("OTLP_COMPRESSION", "none")
+	cfg.BasicAuthUser, cfg.BasicAuthPass = getEnv("BASIC_AUTH_USERNAME", ""), pass
+	cfg.Headers = headers
+	cfg.OAuth2 = oauth2
+	cfg.Retry = exporter.RetryPolicy{
+		MaxRetries:    maxRetries,
+		BaseDelay:     time.Second,
+		Budget:        retryBudget,
+		MaxRetryAfter: maxRetryAfter(),
+	}
+	cfg.PartialSuccess = exporter.PartialSuccessLog
+	return cfg, nil
+}
+
+// otlpGRPCConfig is the transport part of an OTLP/gRPC config for endpoint: TLS, keepalive,
+// logging and the shared rate limit
+func otlpGRPCConfig(endpoint string) (exporter.OTLPGRPCConfig, error) {
+	tlsConfig, err := otlpTLS()
+	if err != nil {
+		return exporter.OTLPGRPCConfig{}, err
+	}
+	return exporter.OTLPGRPCConfig{
+		Endpoint:         endpoint,
+		Insecure:         getEnvBool("OTLP_GRPC_INSECURE", false),
+		TLSServerName:    getEnv("OTLP_GRPC_TLS_SERVER_NAME", ""),
+		TLSSkipVerify:    getEnvBool("OTLP_GRPC_TLS_SKIP_VERIFY", false),
+		TLS:              tlsConfig,
+		KeepaliveSeconds: getEnvInt("OTLP_GRPC_KEEPALIVE_SECONDS", 0),
+		Logger:           logger,
+		RateLimit:        exportLimiter,
+	}, nil
+}
+
+// newDeadLetterQueue returns the queue for batches that exhausted their retries: an S3 prefix
+// when DLQ_S3_BUCKET is set, an SQS queue when DLQ_SQS_URL is set, or nil
+func newDeadLetterQueue() (exporter.DeadLetterQueue, error) {
+	bucket, queueURL := getEnv("DLQ_S3_BUCKET", ""), getEnv("DLQ_SQS_URL", "")
+	if bucket != "" && queueURL != "" {
+		return nil, fmt.Errorf("set only one of DLQ_S3_BUCKET and DLQ_SQS_URL")
+	}
+	if bucket == "" && queueURL == "" {
+		return nil, nil
+	}
+	awsCfg, err := awsConfig()
+	if err != nil {
+		return nil, err
+	}
+	if bucket != "" {
+		return &exporter.S3DeadLetterQueue{
+			Client: s3.NewFromConfig(awsCfg),
+			Bucket: bucket,
+			Prefix: getEnv("DLQ_S3_PREFIX", "dead-letters"),
+		}, nil
+	}
+	return &exporter.SQSDeadLetterQueue{
+		Client:            sqs.NewFromConfig(awsCfg),
+		QueueURL:          queueURL,
+		VisibilityTimeout: 5 * time.Minute,
+	}, nil
+}
+
+// otlpSigV4 returns the SigV4 settings for OTLP/HTTP requests, or nil unless OTLP_SIGV4 is set.
+// Requests are signed with the Lambda role's credentials.
+func otlpSigV4() *exporter.SigV4Config {
+	if !getEnvBool("OTLP_SIGV4", false) {
+		return nil
+	}
+	return &exporter.SigV4Config{
+		Region:  getEnv("OTLP_SIGV4_REGION", getEnv("AWS_REGION", "")),
+		Service: getEnv("OTLP_SIGV4_SERVICE", "execute-api"),
+	}
+}
+
+// otlpOAuth2 returns the OAuth2 client-credentials settings for OTLP export, or nil unless
+// OTLP_OAUTH2_TOKEN_URL is set. The client secret may come from Secrets Manager.
+func otlpOAuth2() (*exporter.OAuth2Config, error) {
+	tokenURL := getEnv("OTLP_OAUTH2_TOKEN_URL", "")
+	if tokenURL == "" {
+		return nil, nil
+	}
+	secret, err := getSecretEnv("OTLP_OAUTH2_CLIENT_SECRET")
+	if err != nil {
+		return nil, err
+	}
+	return &exporter.OAuth2Config{
+		TokenURL:     tokenURL,
+		ClientID:     getEnv("OTLP_OAUTH2_CLIENT_ID", ""),
+		ClientSecret: secret,
+		Scopes:       getEnvList("OTLP_OAUTH2_SCOPES"),
+	}, nil
+}
+
+// basicAuthPass is the password shared by every exporter with basic auth, resolved by
+// basicAuthPassword
+var basicAuthPass *string
+
+// basicAuthPassword returns BASIC_AUTH_PASSWORD or, when BASIC_AUTH_PASSWORD_ARN is set instead,
+// the secret it names. Secrets Manager is read once, however many exporters use the password.
+func basicAuthPassword() (string, error) {
+	if basicAuthPass != nil {
+		return *basicAuthPass, nil
+	}
+	pass, err := getSecretEnv("BASIC_AUTH_PASSWORD")
+	if err != nil {
+		return "", err
+	}
+	basicAuthPass = &pass
+	return pass, nil
+}
+
+// httpClient is the pooled client shared by every HTTP exporter, built by sharedHTTPClient
+var httpClient *http.Client
+
+// sharedHTTPClient returns the shared HTTP client, creating it from the HTTP_CLIENT_* settings
+// and the OTLP_TLS_* material on first use
+func sharedHTTPClient() (*http.Client, error) {
+	if httpClient != nil {
+		return httpClient, nil
+	}
+	tlsConfig, err := otlpTLS()
+	if err != nil {
+		return nil, err
+	}
+	httpClient = exporter.NewHTTPClient(exporter.HTTPClientConfig{
+		Timeout:             time.Duration(getEnvInt("HTTP_CLIENT_TIMEOUT_SECONDS", 30)) * time.Second,
+		MaxIdleConns:        getEnvInt("HTTP_CLIENT_MAX_IDLE_CONNS", 100),
+		MaxIdleConnsPerHost: getEnvInt("HTTP_CLIENT_MAX_IDLE_CONNS_PER_HOST", 20),
+		IdleConnTimeout:     time.Duration(getEnvInt("HTTP_CLIENT_IDLE_CONN_TIMEOUT_SECONDS", 90)) * time.Second,
+		DisableHTTP2:        !getEnvBool("HTTP_CLIENT_HTTP2", true),
+		TLS:                 tlsConfig,
+	})
+	return httpClient, nil
+}
+
+// otlpTLS builds the TLS settings for OTLP export from OTLP_TLS_CA, OTLP_TLS_CERT and
+// OTLP_TLS_KEY, or nil when none is set
+func otlpTLS() (*tls.Config, error) {
+	var m exporter.TLSMaterial
+	var err error
+	if m.CAPEM, err = getPEMEnv("OTLP_TLS_CA"); err != nil {
+		return nil, err
+	}
+	if m.CertPEM, err = getPEMEnv("OTLP_TLS_CERT"); err != nil {
+		return nil, err
+	}
+	if m.KeyPEM, err = getPEMEnv("OTLP_TLS_KEY"); err != nil {
+		return nil, err
+	}
+	return exporter.NewTLSConfig(m)
+}
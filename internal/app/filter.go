@@ -0,0 +1,43 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/filter"
+)
+
+// filterRules are the filter rules of every processor, from FILTER_RULES_FILE or FILTER_RULES
+var filterRules filter.Config
+
+// loadFilterRules reads the rules as YAML or JSON from FILTER_RULES_FILE, or inline from
+// FILTER_RULES. Route rules may only name exporters listed in EXPORTER.
+func loadFilterRules() (filter.Config, error) {
+	data := []byte(getEnv("FILTER_RULES", ""))
+	if path := getEnv("FILTER_RULES_FILE", ""); path != "" {
+		var err error
+		if data, err = os.ReadFile(path); err != nil {
+			return nil, err
+		}
+	}
+	if len(strings.TrimSpace(string(data))) == 0 {
+		return nil, nil
+	}
+	rules, err := filter.ParseConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse filter rules: %w", err)
+	}
+
+	var kinds []string
+	for _, kind := range strings.Split(getEnv("EXPORTER", "otlp"), ",") {
+		kinds = append(kinds, strings.TrimSpace(kind))
+	}
+	for _, name := range rules.Routes() {
+		if !slices.Contains(kinds, name) {
+			return nil, fmt.Errorf("route to %q, which is not in EXPORTER", name)
+		}
+	}
+	return rules, nil
+}
@@ -0,0 +1,62 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/geoip"
+)
+
+// geoDB enriches client addresses when GEOIP_DATABASES is set
+var geoDB *geoip.DB
+
+// loadGeoIP opens the MaxMind DB files listed in GEOIP_DATABASES, each a local path (e.g. from
+// a Lambda layer under /opt) or an s3://bucket/key URI read through the object store. None
+// listed means no GeoIP enrichment.
+func loadGeoIP() (*geoip.DB, error) {
+	locations := getEnvList("GEOIP_DATABASES")
+	if len(locations) == 0 {
+		return nil, nil
+	}
+	files := make([][]byte, 0, len(locations))
+	for _, location := range locations {
+		data, err := readLocation(location)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", location, err)
+		}
+		files = append(files, data)
+	}
+	db, err := geoip.New(files...)
+	if err != nil {
+		return nil, err
+	}
+	db.CacheSize = getEnvInt("GEOIP_CACHE_SIZE", 10000)
+	for i, r := range db.Readers {
+		logger.Info("Loaded GeoIP database", "location", locations[i], "database_type", r.DatabaseType)
+	}
+	return db, nil
+}
+
+// readLocation reads a local file or an s3://bucket/key object through the object store
+func readLocation(location string) ([]byte, error) {
+	rest, ok := strings.CutPrefix(location, "s3://")
+	if !ok {
+		return os.ReadFile(location)
+	}
+	bucket, key, ok := strings.Cut(rest, "/")
+	if !ok || bucket == "" || key == "" {
+		return nil, fmt.Errorf("invalid S3 URI, want s3://bucket/key")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	obj, err := store.GetObject(ctx, bucket, key)
+	if err != nil {
+		return nil, err
+	}
+	defer obj.Body.Close()
+	return io.ReadAll(obj.Body)
+}
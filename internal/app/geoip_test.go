@@ -0,0 +1,23 @@
+package app
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadGeoIP(t *testing.T) {
+	t.Setenv("GEOIP_DATABASES", "")
+	if db, err := loadGeoIP(); db != nil || err != nil {
+		t.Errorf("loadGeoIP() = %v, %v; want nothing when unset", db, err)
+	}
+
+	for _, location := range []string{
+		filepath.Join(t.TempDir(), "missing.mmdb"),
+		"s3://bucket-only",
+	} {
+		t.Setenv("GEOIP_DATABASES", location)
+		if _, err := loadGeoIP(); err == nil {
+			t.Errorf("loadGeoIP(%q) succeeded", location)
+		}
+	}
+}
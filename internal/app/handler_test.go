@@ -0,0 +1,210 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/pixelvide/otel-aws-log-parser/cmd/lambda/adapter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/exporter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/processor"
+)
+
+// fakeProcessor returns one entry per object, fails for the key "bad" and yields nothing for "empty"
+type fakeProcessor struct{}
+
+func (fakeProcessor) Name() string                    { return "fake" }
+func (fakeProcessor) Matches(bucket, key string) bool { return true }
+func (fakeProcessor) Process(ctx context.Context, logger *slog.Logger, store processor.ObjectStore, bucket, key string) ([]adapter.LogAdapter, error) {
+	switch key {
+	case "bad":
+		return nil, errors.New("corrupt object")
+	case "empty":
+		return nil, nil
+	}
+	return []adapter.LogAdapter{fakeEntry{9}}, nil
+}
+
+func s3Message(id, key string) events.SQSMessage {
+	return events.SQSMessage{
+		MessageId: id,
+		Body:      `{"source":"aws.s3","detail":{"bucket":{"name":"logs"},"object":{"key":"` + key + `"}}}`,
+	}
+}
+
+func failedIDs(resp events.SQSEventResponse) []string {
+	var ids []string
+	for _, f := range resp.BatchItemFailures {
+		ids = append(ids, f.ItemIdentifier)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func TestHandler_PartialBatchResponse(t *testing.T) {
+	logger = slog.New(slog.NewJSONHandler(io.Discard, nil))
+	maxBatchSize = 10
+	maxConcurrent = 2
+
+	prevRegistry, prevExp := registry, exp
+	defer func() { registry, exp = prevRegistry, prevExp }()
+	registry = processor.NewRegistry()
+	registry.Register(fakeProcessor{})
+
+	event := events.SQSEvent{Records: []events.SQSMessage{
+		s3Message("m1", "good"), s3Message("m2", "bad"), s3Message("m3", "empty"), s3Message("m4", "good"),
+	}}
+
+	tests := []struct {
+		name      string
+		exportErr error
+		want      []string
+	}{
+		{"only the bad object is retried", nil, []string{"m2"}},
+		{"export failure retries messages with records", errors.New("backend down"), []string{"m1", "m2", "m4"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exp = exporter.ExporterFunc(func(ctx context.Context, logs converter.ResourceLog) error {
+				return tt.exportErr
+			})
+			resp, err := handler(context.Background(), event)
+			if err != nil {
+				t.Fatalf("handler() error = %v, want failures reported per message", err)
+			}
+			got := failedIDs(resp.SQSEventResponse)
+			if len(got) != len(tt.want) {
+				t.Fatalf("failed messages = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("failed messages = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestHandler_ContinueOnError(t *testing.T) {
+	logger = slog.New(slog.NewJSONHandler(io.Discard, nil))
+	maxBatchSize = 10
+	maxConcurrent = 2
+
+	prevRegistry, prevExp := registry, exp
+	defer func() { registry, exp, continueOnError = prevRegistry, prevExp, false }()
+	registry = processor.NewRegistry()
+	registry.Register(fakeProcessor{})
+
+	// One S3 notification carrying a failing object ahead of a good one
+	event := events.SQSEvent{Records: []events.SQSMessage{{
+		MessageId: "m1",
+		Body:      `{"Records":[{"s3":{"bucket":{"name":"logs"},"object":{"key":"bad"}}},{"s3":{"bucket":{"name":"logs"},"object":{"key":"good"}}}]}`,
+	}}}
+
+	for _, tt := range []struct {
+		continueOnError bool
+		wantExported    int
+	}{
+		{false, 0},
+		{true, 1},
+	} {
+		continueOnError = tt.continueOnError
+		exported := 0
+		exp = exporter.ExporterFunc(func(ctx context.Context, logs converter.ResourceLog) error {
+			exported += len(logs.ScopeLogs[0].LogRecords)
+			return nil
+		})
+
+		resp, err := handler(context.Background(), event)
+		if err != nil {
+			t.Fatalf("handler() error = %v", err)
+		}
+		if got := failedIDs(resp.SQSEventResponse); len(got) != 1 || got[0] != "m1" {
+			t.Errorf("continueOnError=%v: failed messages = %v, want [m1]", tt.continueOnError, got)
+		}
+		if exported != tt.wantExported {
+			t.Errorf("continueOnError=%v: exported %d records, want %d", tt.continueOnError, exported, tt.wantExported)
+		}
+	}
+}
+
+func TestInvoke_EventBridge(t *testing.T) {
+	logger = slog.New(slog.NewJSONHandler(io.Discard, nil))
+	maxBatchSize = 10
+	maxConcurrent = 2
+
+	prevRegistry, prevExp := registry, exp
+	defer func() { registry, exp = prevRegistry, prevExp }()
+	registry = processor.NewRegistry()
+	registry.Register(fakeProcessor{})
+
+	exported := 0
+	exp = exporter.ExporterFunc(func(ctx context.Context, logs converter.ResourceLog) error {
+		exported += len(logs.ScopeLogs[0].LogRecords)
+		return nil
+	})
+
+	for _, tt := range []struct {
+		key     string
+		wantErr bool
+	}{
+		{"good", false},
+		{"bad", true},
+	} {
+		payload := []byte(`{"id":"ev-1","source":"aws.s3","detail-type":"Object Created","detail":{"bucket":{"name":"logs"},"object":{"key":"` + tt.key + `"}}}`)
+		if _, err := Invoke(context.Background(), payload); (err != nil) != tt.wantErr {
+			t.Errorf("Invoke(%s) error = %v, wantErr %v", tt.key, err, tt.wantErr)
+		}
+	}
+	if exported != 1 {
+		t.Errorf("exported %d records, want 1", exported)
+	}
+}
+
+func TestServeInvoke_DuringDynamicSettings(t *testing.T) {
+	logger = slog.New(slog.NewJSONHandler(io.Discard, nil))
+	maxBatchSize = 10
+	maxConcurrent = 2
+
+	prevRegistry, prevExp := registry, exp
+	defer func() { registry, exp = prevRegistry, prevExp }()
+	registry = processor.NewRegistry()
+	registry.Register(fakeProcessor{})
+	exp = exporter.ExporterFunc(func(ctx context.Context, logs converter.ResourceLog) error { return nil })
+
+	// Run with -race: requests must not see the settings while AppConfig replaces them
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for range 20 {
+			dynamicMu.Lock()
+			err := applyDynamicSettings()
+			dynamicMu.Unlock()
+			if err != nil {
+				t.Errorf("applyDynamicSettings() error = %v", err)
+				return
+			}
+		}
+	}()
+	for range 20 {
+		body := `{"Records":[{"messageId":"m1","eventSource":"aws:sqs","body":` + strconv.Quote(s3Message("m1", "good").Body) + `}]}`
+		w := httptest.NewRecorder()
+		serveInvoke(w, httptest.NewRequest(http.MethodPost, "/invoke", strings.NewReader(body)))
+		if w.Code != http.StatusOK {
+			t.Errorf("serveInvoke() status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+		}
+	}
+	wg.Wait()
+}
@@ -0,0 +1,97 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// Invoke routes a raw Lambda event to the handler of its event source
+func Invoke(ctx context.Context, payload json.RawMessage) (any, error) {
+	// AppConfig changes are applied between invocations
+	refreshDynamicSettings(ctx)
+	dynamicMu.RLock()
+	defer dynamicMu.RUnlock()
+
+	switch eventSource(payload) {
+	case "aws.s3":
+		return nil, eventBridgeHandler(ctx, payload)
+	case "aws:s3-batch":
+		var event s3BatchEvent
+		if err := json.Unmarshal(payload, &event); err != nil {
+			return nil, fmt.Errorf("invalid S3 Batch Operations event: %w", err)
+		}
+		return s3BatchHandler(ctx, event)
+	case "aws:logs":
+		var event events.CloudwatchLogsEvent
+		if err := json.Unmarshal(payload, &event); err != nil {
+			return nil, fmt.Errorf("invalid CloudWatch Logs event: %w", err)
+		}
+		return nil, cloudWatchLogsHandler(ctx, event)
+	case "aws:kinesis":
+		var event events.KinesisEvent
+		if err := json.Unmarshal(payload, &event); err != nil {
+			return nil, fmt.Errorf("invalid Kinesis event: %w", err)
+		}
+		return kinesisHandler(ctx, event)
+	default:
+		var event events.SQSEvent
+		if err := json.Unmarshal(payload, &event); err != nil {
+			return nil, fmt.Errorf("invalid SQS event: %w", err)
+		}
+		return handler(ctx, event)
+	}
+}
+
+// eventSource returns the eventSource of the first record of a batched event, e.g. "aws:sqs"
+// or "aws:kinesis", "aws:logs" for a CloudWatch Logs subscription, "aws:s3-batch" for an S3
+// Batch Operations job, "aws.s3" for an EventBridge S3 event invoking the function directly,
+// and "" otherwise
+func eventSource(payload []byte) string {
+	var probe struct {
+		Records []struct {
+			EventSource string `json:"eventSource"`
+		} `json:"Records"`
+		AWSLogs                 *json.RawMessage `json:"awslogs"`
+		InvocationSchemaVersion string           `json:"invocationSchemaVersion"`
+		Source                  string           `json:"source"`
+	}
+	if err := json.Unmarshal(payload, &probe); err != nil {
+		return ""
+	}
+	if probe.AWSLogs != nil {
+		return "aws:logs"
+	}
+	if probe.InvocationSchemaVersion != "" {
+		return "aws:s3-batch"
+	}
+	if probe.Source == "aws.s3" {
+		return probe.Source
+	}
+	if len(probe.Records) == 0 {
+		return ""
+	}
+	return probe.Records[0].EventSource
+}
+
+// eventBridgeHandler processes a single EventBridge S3 event delivered by a rule targeting the
+// function, without SQS in between. It runs the event through handler as a one-message batch;
+// rule targets invoke asynchronously, so a failure is returned as an error for Lambda (and the
+// rule's retry policy) to retry.
+func eventBridgeHandler(ctx context.Context, payload []byte) error {
+	var meta struct {
+		ID string `json:"id"`
+	}
+	json.Unmarshal(payload, &meta)
+
+	resp, err := handler(ctx, events.SQSEvent{Records: []events.SQSMessage{{MessageId: meta.ID, Body: string(payload)}}})
+	if err != nil {
+		return err
+	}
+	if len(resp.BatchItemFailures) > 0 {
+		return fmt.Errorf("failed to process EventBridge event %s", meta.ID)
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package app
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/pixelvide/otel-aws-log-parser/cmd/lambda/adapter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/processor"
+)
+
+// kinesisHandler exports the log lines carried by Kinesis Data Stream records, skipping the S3
+// hop. A record holds either raw log lines, optionally gzipped, or a CloudWatch Logs
+// subscription payload (always gzipped). Lines are parsed with KINESIS_LOG_FORMAT; subscription
+// messages that do not parse are exported as plain text, like cloudWatchLogsHandler does.
+//
+// Records are handled in shard order. With ReportBatchItemFailures enabled on the event source
+// mapping, Lambda resumes the shard from the first reported sequence number.
+func kinesisHandler(ctx context.Context, event events.KinesisEvent) (events.KinesisEventResponse, error) {
+	response := events.KinesisEventResponse{
+		BatchItemFailures: []events.KinesisBatchItemFailure{},
+	}
+	defer reportUnrecognized()
+
+	logsExp, metrics, ok := pipelineExporters(ctx, "kinesis", len(event.Records))
+	if !ok {
+		return response, nil
+	}
+	ctx, start := beginInvocation(ctx, "kinesis")
+
+	launchCtx, cancel := launchContext(ctx, deadlineMargin)
+	defer cancel()
+
+	sink := newStreamSink(ctx, logsExp, metrics, streamBuffer)
+
+	logger.Info("Lambda triggered", "kinesis_record_count", len(event.Records))
+
+	// Leading records that were fully handed to the sink, or skipped as undecodable
+	done := 0
+
+	for i, record := range event.Records {
+		seq := record.Kinesis.SequenceNumber
+		log := logger.With("sequence_number", seq, "partition_key", record.Kinesis.PartitionKey)
+
+		// Out of time: this and every later record are left for the retry
+		if launchCtx.Err() != nil {
+			log.Warn("Skipping remaining records, deadline safety margin reached", "remaining", len(event.Records)-i, "cause", context.Cause(launchCtx))
+			break
+		}
+
+		entries, skipped, err := decodeKinesisRecord(record.Kinesis.Data, kinesisFormat)
+		if err != nil {
+			// A record that cannot be decoded never will be; retrying it would block the shard
+			log.Warn("Failed to decode Kinesis record, skipping", "error", err)
+			done++
+			continue
+		}
+		if skipped > 0 {
+			log.Warn("Skipped unparseable lines", "skipped", skipped, "format", kinesisFormat)
+		}
+
+		for _, entry := range entries {
+			if err = sink.Add(launchCtx, entry); err != nil {
+				break
+			}
+		}
+		if err != nil {
+			log.Error("Error exporting Kinesis record", "error", err)
+			break
+		}
+		done++
+	}
+
+	// Lambda resumes the shard from the lowest failed sequence number, so reporting the first
+	// record left behind redelivers it and everything after it
+	if err := sink.Close(launchCtx); err != nil {
+		logger.Error("Error sending to OTLP", "error", err, "exporters", exportSummary())
+		done = 0
+	}
+	for _, record := range event.Records[done:] {
+		response.BatchItemFailures = append(response.BatchItemFailures, events.KinesisBatchItemFailure{ItemIdentifier: record.Kinesis.SequenceNumber})
+	}
+
+	logger.Info("Lambda execution completed", "failures", len(response.BatchItemFailures), "exporters", exportSummary())
+	summarizeInvocation(ctx, "kinesis", len(event.Records), len(response.BatchItemFailures), start)
+	return response, nil
+}
+
+// gzipMagic starts every gzip stream
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// decodeKinesisRecord turns the data of one Kinesis record into entries. It returns the number
+// of lines that did not parse as format.
+func decodeKinesisRecord(data []byte, format string) ([]adapter.LogAdapter, int, error) {
+	if bytes.HasPrefix(data, gzipMagic) {
+		gz, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to decompress record: %w", err)
+		}
+		defer gz.Close()
+		if data, err = io.ReadAll(gz); err != nil {
+			return nil, 0, fmt.Errorf("failed to decompress record: %w", err)
+		}
+	}
+
+	// CloudWatch Logs subscriptions deliver one JSON document per record
+	var cwl events.CloudwatchLogsData
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) && json.Unmarshal(data, &cwl) == nil && cwl.MessageType != "" {
+		if cwl.MessageType != "DATA_MESSAGE" {
+			// CONTROL_MESSAGE records only check that the destination is reachable
+			return nil, 0, nil
+		}
+		return cloudWatchEntries(cwl, format, kinesisDecor), 0, nil
+	}
+
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	// A Kinesis record is at most 1 MiB, so a single line never exceeds the record
+	scanner.Buffer(nil, len(data)+1)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to read record lines: %w", err)
+	}
+	entries, skipped := parseLines(format, lines)
+	return entries, skipped, nil
+}
+
+// parseLines parses every line as format, dropping blank and comment lines and counting the
+// ones that fail to parse
+func parseLines(format string, lines []string) ([]adapter.LogAdapter, int) {
+	entries := make([]adapter.LogAdapter, 0, len(lines))
+	skipped := 0
+	var dropped [processor.SampledOut + 1]int64
+	start := time.Now()
+	defer func() {
+		readStats.source("kinesis").Add(processor.ReadCounts{Lines: int64(len(lines)), ParseFailures: int64(skipped), Entries: int64(len(entries)), Filtered: dropped[processor.Filtered], SampledOut: dropped[processor.SampledOut], ParseTime: time.Since(start)})
+	}()
+	for _, line := range lines {
+		entry, err := processor.AdapterForLine(format, line)
+		if err != nil {
+			skipped++
+			continue
+		}
+		if entry == nil {
+			continue
+		}
+		entry, verdict := kinesisDecor.apply(entry, line)
+		if verdict != processor.Admitted {
+			dropped[verdict]++
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, skipped
+}
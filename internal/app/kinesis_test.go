@@ -0,0 +1,154 @@
+package app
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/exporter"
+)
+
+const testNLBLine = "tls 2.0 2023-10-01T00:00:00.000000Z app/net-lb/1234567890abcdef listener/net-lb/1234567890abcdef/1234567890abcdef 1.2.3.4:12345 5.6.7.8:80 0.001 0.002 100 200 - arn:aws:acm:us-east-1:123456789012:certificate/12345678-1234-1234-1234-123456789012 - ECDHE-RSA-AES128-GCM-SHA256 TLSv1.2 - example.com h2 - - 2023-10-01T00:00:00.000000Z"
+
+func gzipBytes(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func cloudWatchPayload(t *testing.T, messageType string, messages ...string) []byte {
+	t.Helper()
+	data := events.CloudwatchLogsData{MessageType: messageType, LogGroup: "/aws/nlb", LogStream: "stream-1"}
+	for _, msg := range messages {
+		data.LogEvents = append(data.LogEvents, events.CloudwatchLogsLogEvent{Message: msg})
+	}
+	body, err := json.Marshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return gzipBytes(t, body)
+}
+
+func TestDecodeKinesisRecord(t *testing.T) {
+	raw := []byte(testNLBLine + "\n\nnot a log line\n" + testNLBLine + "\n")
+
+	tests := []struct {
+		name        string
+		data        []byte
+		format      string
+		wantEntries int
+		wantSkipped int
+		wantErr     bool
+	}{
+		{"raw lines", raw, "auto", 2, 1, false},
+		{"gzipped raw lines", gzipBytes(t, raw), "nlb", 2, 1, false},
+		{"cloudwatch logs data", cloudWatchPayload(t, "DATA_MESSAGE", testNLBLine, testNLBLine), "auto", 2, 0, false},
+		{"cloudwatch logs control", cloudWatchPayload(t, "CONTROL_MESSAGE", "CWL CONTROL MESSAGE: Checking health of destination Kinesis stream."), "auto", 0, 0, false},
+		{"corrupt gzip", []byte{0x1f, 0x8b, 0x00}, "auto", 0, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, skipped, err := decodeKinesisRecord(tt.data, tt.format)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodeKinesisRecord() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(entries) != tt.wantEntries || skipped != tt.wantSkipped {
+				t.Errorf("decodeKinesisRecord() = %d entries, %d skipped, want %d, %d", len(entries), skipped, tt.wantEntries, tt.wantSkipped)
+			}
+		})
+	}
+}
+
+func kinesisRecord(seq string, data []byte) events.KinesisEventRecord {
+	return events.KinesisEventRecord{EventSource: "aws:kinesis", Kinesis: events.KinesisRecord{SequenceNumber: seq, Data: data}}
+}
+
+func TestKinesisHandler(t *testing.T) {
+	logger = slog.New(slog.NewJSONHandler(io.Discard, nil))
+	maxBatchSize = 10
+	maxConcurrent = 2
+	kinesisFormat = "auto"
+
+	prevExp := exp
+	defer func() { exp = prevExp }()
+
+	event := events.KinesisEvent{Records: []events.KinesisEventRecord{
+		kinesisRecord("1", []byte(testNLBLine)),
+		kinesisRecord("2", []byte{0x1f, 0x8b, 0x00}),
+		kinesisRecord("3", cloudWatchPayload(t, "DATA_MESSAGE", testNLBLine, testNLBLine)),
+	}}
+
+	tests := []struct {
+		name         string
+		exportErr    error
+		wantExported int
+		wantFailed   []string
+	}{
+		{"undecodable record is skipped", nil, 3, nil},
+		{"export failure retries the batch", errors.New("backend down"), 0, []string{"1", "2", "3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var exported atomic.Int64
+			exp = exporter.ExporterFunc(func(ctx context.Context, logs converter.ResourceLog) error {
+				if tt.exportErr != nil {
+					return tt.exportErr
+				}
+				exported.Add(int64(len(logs.ScopeLogs[0].LogRecords)))
+				return nil
+			})
+
+			resp, err := kinesisHandler(context.Background(), event)
+			if err != nil {
+				t.Fatalf("kinesisHandler() error = %v", err)
+			}
+			if got := exported.Load(); got != int64(tt.wantExported) {
+				t.Errorf("exported %d records, want %d", got, tt.wantExported)
+			}
+			var failed []string
+			for _, f := range resp.BatchItemFailures {
+				failed = append(failed, f.ItemIdentifier)
+			}
+			if len(failed) != len(tt.wantFailed) {
+				t.Fatalf("failed records = %v, want %v", failed, tt.wantFailed)
+			}
+			for i := range failed {
+				if failed[i] != tt.wantFailed[i] {
+					t.Errorf("failed records = %v, want %v", failed, tt.wantFailed)
+				}
+			}
+		})
+	}
+}
+
+func TestEventSource(t *testing.T) {
+	payload := []byte(`{"Records":[{"eventSource":"aws:kinesis","kinesis":{"sequenceNumber":"1","data":""}}]}`)
+	if got := eventSource(payload); got != "aws:kinesis" {
+		t.Errorf("eventSource() = %q, want aws:kinesis", got)
+	}
+	if got := eventSource([]byte(`{"awslogs":{"data":""}}`)); got != "aws:logs" {
+		t.Errorf("eventSource() = %q, want aws:logs", got)
+	}
+	if got := eventSource([]byte(`{"source":"aws.s3"}`)); got != "aws.s3" {
+		t.Errorf("eventSource() = %q, want aws.s3", got)
+	}
+	if got := eventSource([]byte(`{"source":"aws.ec2"}`)); got != "" {
+		t.Errorf("eventSource() = %q, want empty", got)
+	}
+}
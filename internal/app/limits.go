@@ -0,0 +1,75 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/processor"
+)
+
+// largeObjectQueue receives the objects over MAX_OBJECT_BYTES as S3 event notifications, so
+// a deployment sized for them (more memory, a longer timeout, no size limit) can consume the
+// queue with the same handler
+type largeObjectQueue struct {
+	Client   sqsAPI
+	QueueURL string
+}
+
+// Send publishes the object. version is carried as the ETag, which the consumer keys dedup
+// and checkpoints on just like this invocation would have.
+func (q *largeObjectQueue) Send(ctx context.Context, bucket, key, version, region string) error {
+	body, err := json.Marshal(events.S3Event{Records: []events.S3EventRecord{{
+		EventVersion: "2.1",
+		EventSource:  "aws:s3",
+		AWSRegion:    region,
+		EventTime:    time.Now().UTC(),
+		S3: events.S3Entity{
+			Bucket: events.S3Bucket{Name: bucket},
+			// Notification keys are URL-encoded
+			Object: events.S3Object{Key: url.QueryEscape(key), ETag: version},
+		},
+	}}})
+	if err != nil {
+		return err
+	}
+	_, err = q.Client.SendMessage(ctx, &sqs.SendMessageInput{
+		QueueUrl:    aws.String(q.QueueURL),
+		MessageBody: aws.String(string(body)),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send to large object queue: %w", err)
+	}
+	return nil
+}
+
+// applyObjectLimits handles the objects rejected by MAX_OBJECT_BYTES and MAX_OBJECT_AGE. Old
+// objects are dropped and large ones are routed to LARGE_OBJECT_QUEUE_URL, or dropped when it
+// is not set. Any other err is returned unchanged; so is a failure to route the object, so
+// the event is retried rather than losing it.
+func applyObjectLimits(ctx context.Context, log *slog.Logger, bucket, key, version, region string, err error) error {
+	switch {
+	case errors.Is(err, processor.ErrObjectTooOld):
+		log.Warn("Skipping object: older than MAX_OBJECT_AGE", "reason", err)
+		return nil
+	case errors.Is(err, processor.ErrObjectTooLarge):
+		if largeObjects == nil {
+			log.Warn("Skipping object: larger than MAX_OBJECT_BYTES", "reason", err)
+			return nil
+		}
+		if err := largeObjects.Send(ctx, bucket, key, version, region); err != nil {
+			return err
+		}
+		log.Info("Routed object to the large object queue", "reason", err)
+		return nil
+	}
+	return err
+}
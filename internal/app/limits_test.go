@@ -0,0 +1,86 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/processor"
+)
+
+type fakeSQS struct {
+	sqsAPI
+	bodies []string
+	err    error
+}
+
+func (f *fakeSQS) SendMessage(ctx context.Context, in *sqs.SendMessageInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageOutput, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	f.bodies = append(f.bodies, aws.ToString(in.MessageBody))
+	return &sqs.SendMessageOutput{}, nil
+}
+
+func TestApplyObjectLimits(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	tooLarge := fmt.Errorf("%w: 200 bytes, limit 100", processor.ErrObjectTooLarge)
+	tooOld := fmt.Errorf("%w: last modified 48h0m0s ago, limit 24h0m0s", processor.ErrObjectTooOld)
+	other := errors.New("boom")
+
+	t.Cleanup(func() { largeObjects = nil })
+
+	tests := []struct {
+		name    string
+		queue   *fakeSQS
+		err     error
+		wantErr error
+		sent    int
+	}{
+		{"no error", nil, nil, nil, 0},
+		{"other error", nil, other, other, 0},
+		{"too old is dropped", &fakeSQS{}, tooOld, nil, 0},
+		{"too large without queue is dropped", nil, tooLarge, nil, 0},
+		{"too large is routed", &fakeSQS{}, tooLarge, nil, 1},
+		{"routing failure is retried", &fakeSQS{err: errors.New("throttled")}, tooLarge, errors.New("throttled"), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			largeObjects = nil
+			if tt.queue != nil {
+				largeObjects = &largeObjectQueue{Client: tt.queue, QueueURL: "https://sqs.example/large"}
+			}
+			err := applyObjectLimits(context.Background(), log, "bucket", "logs/a b.gz", "etag1", "eu-west-1", tt.err)
+			if (err == nil) != (tt.wantErr == nil) {
+				t.Fatalf("applyObjectLimits() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.queue != nil && len(tt.queue.bodies) != tt.sent {
+				t.Errorf("sent %d messages, want %d", len(tt.queue.bodies), tt.sent)
+			}
+		})
+	}
+}
+
+func TestLargeObjectQueue_RoundTrip(t *testing.T) {
+	fake := &fakeSQS{}
+	q := &largeObjectQueue{Client: fake, QueueURL: "https://sqs.example/large"}
+	if err := q.Send(context.Background(), "bucket", "logs/a b+c.gz", "etag1", "eu-west-1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The consumer reads the message as an S3 event notification
+	records, err := processor.ParseS3Notification(slog.New(slog.NewTextHandler(io.Discard, nil)), []byte(fake.bodies[0]))
+	if err != nil || len(records) != 1 {
+		t.Fatalf("processor.ParseS3Notification() = %v, %v", records, err)
+	}
+	got := records[0]
+	if got.S3.Bucket.Name != "bucket" || got.S3.Object.Key != "logs/a b+c.gz" || got.S3.Object.ETag != "etag1" || got.AWSRegion != "eu-west-1" {
+		t.Errorf("record = %+v", got)
+	}
+}
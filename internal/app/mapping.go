@@ -0,0 +1,117 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/pixelvide/otel-aws-log-parser/cmd/lambda/adapter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/filter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/geoip"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/processor"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/reputation"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/useragent"
+)
+
+// attrMappings are the attribute mappings of every processor, from ATTRIBUTE_MAPPING_FILE or
+// ATTRIBUTE_MAPPING
+var attrMappings converter.AttributeMappings
+
+// loadAttributeMappings reads the mappings as YAML or JSON from ATTRIBUTE_MAPPING_FILE, or
+// inline from ATTRIBUTE_MAPPING. Neither set means no mapping.
+func loadAttributeMappings() (converter.AttributeMappings, error) {
+	data := []byte(getEnv("ATTRIBUTE_MAPPING", ""))
+	if path := getEnv("ATTRIBUTE_MAPPING_FILE", ""); path != "" {
+		var err error
+		if data, err = os.ReadFile(path); err != nil {
+			return nil, err
+		}
+	}
+	if len(strings.TrimSpace(string(data))) == 0 {
+		return nil, nil
+	}
+	mappings, err := converter.ParseAttributeMappings(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse attribute mapping: %w", err)
+	}
+	return mappings, nil
+}
+
+// entryDecorator applies a source's filter, sampling, tags, enrichment, attribute mapping and identity to entries that are not
+// read by an S3 processor (Kinesis, CloudWatch Logs)
+type entryDecorator struct {
+	filter     filter.Rules
+	sampler    *processor.RecordSampler
+	tags       *processor.ResourceTags
+	geoIP      *geoip.DB
+	threats    *reputation.Feed
+	userAgents *useragent.Cache
+	anonymizer *converter.IPAnonymizer
+	mapping    *converter.AttributeMapping
+	body       *processor.BodyFormat
+	identity   *processor.Identity
+}
+
+// sourceDecorator reads the decorator of a source; prefix is its environment variable prefix
+func sourceDecorator(prefix string) (entryDecorator, error) {
+	body, err := processorBodyFormat(prefix)
+	if err != nil {
+		return entryDecorator{}, err
+	}
+	sampler, err := processorSampler(prefix)
+	if err != nil {
+		return entryDecorator{}, err
+	}
+	return entryDecorator{filter: filterRules.For(strings.ToLower(prefix)), sampler: sampler, tags: resourceTags, geoIP: geoDB, threats: sourceThreats(prefix), userAgents: userAgents, anonymizer: anonymizer, mapping: attrMappings.For(strings.ToLower(prefix)), body: body, identity: processorIdentity(prefix)}, nil
+}
+
+// apply decorates an entry parsed from raw, in the order ReadOptions applies them to S3 entries.
+// Entries the filter or sampler drop come back nil, with the verdict saying which.
+func (d entryDecorator) apply(entry adapter.LogAdapter, raw string) (adapter.LogAdapter, processor.Verdict) {
+	adm := processor.Admit(d.filter, d.sampler, entry)
+	if adm.Verdict != processor.Admitted {
+		return nil, adm.Verdict
+	}
+	entry = processor.ApplyResourceTags(entry, d.tags)
+	entry = adm.Apply(entry)
+	entry = processor.ApplyGeoIP(entry, d.geoIP)
+	entry = processor.ApplyThreatIntel(entry, d.threats)
+	entry = processor.ApplyUserAgents(entry, d.userAgents)
+	entry = processor.ApplyAnonymizer(entry, d.anonymizer)
+	entry = processor.ApplyMapping(entry, d.mapping)
+	entry = d.body.Apply(entry, raw)
+	return d.identity.Apply(entry), processor.Admitted
+}
+
+// processorSampler reads the <prefix>_SAMPLE_2XX ... <prefix>_SAMPLE_5XX rates, falling back
+// to SAMPLE_2XX ... SAMPLE_5XX; unset classes keep every record
+func processorSampler(prefix string) (*processor.RecordSampler, error) {
+	rates := map[string]float64{}
+	for _, class := range processor.StatusClasses {
+		key := "SAMPLE_" + strings.ToUpper(class)
+		value := getEnv(prefix+"_"+key, getEnv(key, ""))
+		if value == "" {
+			continue
+		}
+		rate, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", key, err)
+		}
+		rates[class] = rate
+	}
+	return processor.NewRecordSampler(rates)
+}
+
+// processorBodyFormat reads <prefix>_LOG_BODY_FORMAT and <prefix>_LOG_BODY_TEMPLATE, falling
+// back to LOG_BODY_FORMAT and LOG_BODY_TEMPLATE; raw bodies are scrubbed by the IP anonymizer
+func processorBodyFormat(prefix string) (*processor.BodyFormat, error) {
+	mode := getEnv(prefix+"_LOG_BODY_FORMAT", getEnv("LOG_BODY_FORMAT", ""))
+	tmpl := getEnv(prefix+"_LOG_BODY_TEMPLATE", getEnv("LOG_BODY_TEMPLATE", ""))
+	body, err := processor.ParseBodyFormat(mode, tmpl)
+	if body != nil {
+		body.Anonymizer = anonymizer
+	}
+	return body, err
+}
@@ -0,0 +1,98 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+)
+
+// pipelineMode is the operator-controlled state of the pipeline
+type pipelineMode string
+
+const (
+	// pipelineOn processes and exports normally
+	pipelineOn pipelineMode = "on"
+	// pipelinePaused acknowledges events without reading or exporting anything
+	pipelinePaused pipelineMode = "paused"
+	// pipelineDivert processes events but exports only to the divert exporter
+	pipelineDivert pipelineMode = "divert"
+)
+
+// pipelineSwitch reads the pipeline mode from an SSM parameter, caching it for ttl so a
+// burst of invocations does not hit SSM on every event
+type pipelineSwitch struct {
+	fetch func(ctx context.Context) (string, error)
+	ttl   time.Duration
+	now   func() time.Time
+
+	mu        sync.Mutex
+	mode      pipelineMode
+	fetchedAt time.Time
+}
+
+// ssmAPI is the part of the SSM client pipelineSwitch uses
+type ssmAPI interface {
+	GetParameter(ctx context.Context, in *ssm.GetParameterInput, optFns ...func(*ssm.Options)) (*ssm.GetParameterOutput, error)
+}
+
+// newSSMSwitch creates a switch backed by the named SSM parameter
+func newSSMSwitch(client ssmAPI, name string, ttl time.Duration) *pipelineSwitch {
+	return &pipelineSwitch{
+		fetch: func(ctx context.Context) (string, error) {
+			out, err := client.GetParameter(ctx, &ssm.GetParameterInput{
+				Name:           aws.String(name),
+				WithDecryption: aws.Bool(true),
+			})
+			if err != nil {
+				var notFound *types.ParameterNotFound
+				if errors.As(err, &notFound) {
+					return string(pipelineOn), nil
+				}
+				return "", err
+			}
+			return aws.ToString(out.Parameter.Value), nil
+		},
+		ttl:  ttl,
+		now:  time.Now,
+		mode: pipelineOn,
+	}
+}
+
+// Mode returns the current mode. A nil switch is always on. When the parameter cannot be read
+// the last known mode is kept, so an SSM outage neither halts nor resumes ingestion.
+func (s *pipelineSwitch) Mode(ctx context.Context) pipelineMode {
+	if s == nil {
+		return pipelineOn
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.fetchedAt.IsZero() && s.now().Sub(s.fetchedAt) < s.ttl {
+		return s.mode
+	}
+	s.fetchedAt = s.now()
+
+	value, err := s.fetch(ctx)
+	if err != nil {
+		logger.Warn("Failed to read pipeline switch, keeping last mode", "mode", s.mode, "error", err)
+		return s.mode
+	}
+
+	switch mode := pipelineMode(strings.ToLower(strings.TrimSpace(value))); mode {
+	case pipelineOn, pipelinePaused, pipelineDivert:
+		if mode != s.mode {
+			logger.Info("Pipeline mode changed", "from", s.mode, "to", mode)
+		}
+		s.mode = mode
+	default:
+		logger.Warn("Unknown pipeline switch value, keeping last mode", "value", value, "mode", s.mode)
+	}
+	return s.mode
+}
@@ -0,0 +1,65 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestPipelineSwitch_Mode(t *testing.T) {
+	logger = slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	now := time.Unix(0, 0)
+	value, fetchErr, fetches := "paused", error(nil), 0
+	s := &pipelineSwitch{
+		fetch: func(ctx context.Context) (string, error) {
+			fetches++
+			return value, fetchErr
+		},
+		ttl:  30 * time.Second,
+		now:  func() time.Time { return now },
+		mode: pipelineOn,
+	}
+	ctx := context.Background()
+
+	if got := s.Mode(ctx); got != pipelinePaused {
+		t.Errorf("Mode() = %q, want paused", got)
+	}
+
+	// Cached within the TTL
+	value = "on"
+	now = now.Add(10 * time.Second)
+	if got := s.Mode(ctx); got != pipelinePaused || fetches != 1 {
+		t.Errorf("Mode() = %q after %d fetches, want cached paused", got, fetches)
+	}
+
+	now = now.Add(30 * time.Second)
+	if got := s.Mode(ctx); got != pipelineOn {
+		t.Errorf("Mode() = %q, want on after the cache expired", got)
+	}
+
+	// Read failures and unknown values keep the last mode
+	value = " DIVERT "
+	now = now.Add(time.Minute)
+	if got := s.Mode(ctx); got != pipelineDivert {
+		t.Errorf("Mode() = %q, want divert", got)
+	}
+	fetchErr = errors.New("throttled")
+	now = now.Add(time.Minute)
+	if got := s.Mode(ctx); got != pipelineDivert {
+		t.Errorf("Mode() = %q on fetch error, want last mode divert", got)
+	}
+	value, fetchErr = "maintenance", nil
+	now = now.Add(time.Minute)
+	if got := s.Mode(ctx); got != pipelineDivert {
+		t.Errorf("Mode() = %q for unknown value, want last mode divert", got)
+	}
+
+	var disabled *pipelineSwitch
+	if got := disabled.Mode(ctx); got != pipelineOn {
+		t.Errorf("nil switch Mode() = %q, want on", got)
+	}
+}
@@ -0,0 +1,72 @@
+package app
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/config"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/exporter"
+)
+
+// processorOverrides are the settings of each enabled S3 log processor, keyed by its lowercase
+// name (see config.ProcessorOverrides)
+var processorOverrides map[string]config.ProcessorOverrides
+
+// batchSize returns the batch size of the group's processor (<PREFIX>_MAX_BATCH_SIZE)
+func (g *resourceGroup) batchSize() int {
+	if overrides, ok := processorOverrides[g.Processor]; ok {
+		return overrides.MaxBatchSize
+	}
+	return maxBatchSize
+}
+
+// processorKey carries the processor of an exported batch in its context
+type processorKey struct{}
+
+// withProcessor tags the exports made on ctx with the processor of their records
+func withProcessor(ctx context.Context, name string) context.Context {
+	if name == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, processorKey{}, name)
+}
+
+// processorExporter sends the batches of processors with their own OTLP endpoint
+// (<PREFIX>_OTLP_ENDPOINT) to it, and every other batch to the default exporter
+type processorExporter struct {
+	fallback    exporter.Exporter
+	byProcessor map[string]exporter.Exporter
+}
+
+// Export sends the batch to the exporter of the processor it was tagged with
+func (e processorExporter) Export(ctx context.Context, logs converter.ResourceLog) error {
+	if name, ok := ctx.Value(processorKey{}).(string); ok {
+		if exp, ok := e.byProcessor[name]; ok {
+			return exp.Export(ctx, logs)
+		}
+	}
+	return e.fallback.Export(ctx, logs)
+}
+
+// processorOTLPExporter wraps the OTLP exporter so processors with an endpoint override get
+// their own exporter, sharing every other OTLP setting; base is returned when none has one
+func processorOTLPExporter(base exporter.Exporter, retry exporter.RetryPolicy, partial exporter.PartialSuccessAction, compression string) (exporter.Exporter, error) {
+	byProcessor := map[string]exporter.Exporter{}
+	for _, name := range processors {
+		endpoint := processorOverrides[name].OTLPEndpoint
+		if endpoint == "" {
+			continue
+		}
+		e, err := newOTLPExporter(&otlpRoute{Name: name, Endpoint: endpoint}, retry, partial, compression)
+		if err != nil {
+			return nil, fmt.Errorf("%s processor: %w", name, err)
+		}
+		byProcessor[name] = e
+	}
+	if len(byProcessor) == 0 {
+		return base, nil
+	}
+	logger.Info("Per-processor OTLP endpoints enabled", "processors", len(byProcessor))
+	return processorExporter{fallback: base, byProcessor: byProcessor}, nil
+}
@@ -0,0 +1,61 @@
+package app
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/config"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/exporter"
+)
+
+func TestStreamSink_ProcessorOverrides(t *testing.T) {
+	logger = slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	var mu sync.Mutex
+	got := map[string][]int{}
+	recorder := func(name string) exporter.Exporter {
+		return exporter.ExporterFunc(func(ctx context.Context, logs converter.ResourceLog) error {
+			mu.Lock()
+			defer mu.Unlock()
+			got[name] = append(got[name], len(logs.ScopeLogs[0].LogRecords))
+			return nil
+		})
+	}
+
+	previous := processorOverrides
+	defer func() { processorOverrides = previous }()
+	processorOverrides = map[string]config.ProcessorOverrides{"waf": {MaxBatchSize: 2}, "alb": {MaxBatchSize: 3}}
+	maxBatchSize = 3
+	maxConcurrent = 1
+
+	logsExp := processorExporter{fallback: recorder("default"), byProcessor: map[string]exporter.Exporter{"waf": recorder("waf")}}
+	sink := newStreamSink(context.Background(), logsExp, nil, 0)
+	for range 5 {
+		if err := sink.AddFrom(context.Background(), "waf", fakeEntry{9}); err != nil {
+			t.Fatalf("AddFrom() error = %v", err)
+		}
+	}
+	for range 4 {
+		if err := sink.AddFrom(context.Background(), "alb", fakeEntry{9}); err != nil {
+			t.Fatalf("AddFrom() error = %v", err)
+		}
+	}
+	if err := sink.Close(context.Background()); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	// The WAF and ALB entries share a resource key but are grouped and batched apart
+	slices.Sort(got["waf"])
+	slices.Sort(got["default"])
+	if !slices.Equal(got["waf"], []int{1, 2, 2}) {
+		t.Errorf("waf batches = %v, want [1 2 2] on its own exporter", got["waf"])
+	}
+	if !slices.Equal(got["default"], []int{1, 3}) {
+		t.Errorf("default batches = %v, want [1 3]", got["default"])
+	}
+}
@@ -0,0 +1,42 @@
+package app
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/exporter"
+)
+
+// RunReplayDLQ implements the replay-dlq command. It uses the same environment as the
+// function, so the batches go to the configured EXPORTER and are read from DLQ_S3_BUCKET or
+// DLQ_SQS_URL:
+//
+//	bootstrap replay-dlq [-limit N]
+func RunReplayDLQ(args []string) error {
+	fs := flag.NewFlagSet("replay-dlq", flag.ContinueOnError)
+	limit := fs.Int("limit", 0, "replay at most this many dead letters (0 = all)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := Configure(); err != nil {
+		return err
+	}
+
+	if dryRun != nil {
+		return fmt.Errorf("replay-dlq deletes the dead letters it replays: unset DRY_RUN")
+	}
+	if dlq == nil {
+		return fmt.Errorf("no dead-letter queue configured: set DLQ_S3_BUCKET or DLQ_SQS_URL")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	n, err := exporter.ReplayDeadLetters(ctx, dlq, replayExp, *limit, logger)
+	logger.Info("Dead letters replayed", "count", n)
+	return err
+}
@@ -0,0 +1,115 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/exporter"
+)
+
+// otlpRoute is one entry of the OTLP routing table: the resources it matches and where their
+// records go instead of the default OTLP endpoint
+type otlpRoute struct {
+	Name           string `yaml:"name"`
+	LoadBalancer   string `yaml:"load_balancer"`
+	AccountID      string `yaml:"account_id"`
+	TargetGroupARN string `yaml:"target_group_arn"`
+
+	Endpoint      string            `yaml:"endpoint"`
+	Headers       map[string]string `yaml:"headers"`
+	BasicAuthUser string            `yaml:"basic_auth_username"`
+	BasicAuthPass string            `yaml:"basic_auth_password"`
+	// BasicAuthPassARN names a Secrets Manager secret holding the password
+	BasicAuthPassARN string `yaml:"basic_auth_password_arn"`
+}
+
+// match returns the attributes a record needs to take the route
+func (r otlpRoute) match() map[string]string {
+	match := map[string]string{}
+	for key, value := range map[string]string{
+		"aws.lb.name":              r.LoadBalancer,
+		"cloud.account.id":         r.AccountID,
+		"aws.alb.target_group_arn": r.TargetGroupARN,
+	} {
+		if value != "" {
+			match[key] = value
+		}
+	}
+	return match
+}
+
+// loadOTLPRoutes reads the routing table, a YAML or JSON list of routes, from OTLP_ROUTES_FILE
+// or inline from OTLP_ROUTES. Passwords named by basic_auth_password_arn are read here.
+func loadOTLPRoutes() ([]otlpRoute, error) {
+	data := []byte(getEnv("OTLP_ROUTES", ""))
+	if path := getEnv("OTLP_ROUTES_FILE", ""); path != "" {
+		var err error
+		if data, err = os.ReadFile(path); err != nil {
+			return nil, err
+		}
+	}
+	if len(strings.TrimSpace(string(data))) == 0 {
+		return nil, nil
+	}
+	var routes []otlpRoute
+	if err := yaml.Unmarshal(data, &routes); err != nil {
+		return nil, fmt.Errorf("failed to parse OTLP routes: %w", err)
+	}
+	for i := range routes {
+		r := &routes[i]
+		if r.Name == "" {
+			r.Name = fmt.Sprintf("route-%d", i+1)
+		}
+		if len(r.match()) == 0 {
+			return nil, fmt.Errorf("route %s: needs load_balancer, account_id or target_group_arn", r.Name)
+		}
+		if r.BasicAuthPassARN != "" {
+			pass, err := readSecret(r.BasicAuthPassARN)
+			if err != nil {
+				return nil, fmt.Errorf("route %s: failed to read basic_auth_password_arn: %w", r.Name, err)
+			}
+			r.BasicAuthPass = pass
+		}
+	}
+	return routes, nil
+}
+
+// routedOTLPExporter wraps the default OTLP exporter in a router when OTLP_ROUTES or
+// OTLP_ROUTES_FILE is set, so one function can serve the backends of several teams. Records
+// no route matches keep going to the default exporter. With AppConfig the router is always
+// created, as the routes may be set later.
+func routedOTLPExporter(base exporter.Exporter, retry exporter.RetryPolicy, partial exporter.PartialSuccessAction, compression string) (exporter.Exporter, error) {
+	build := func() ([]exporter.Route, error) {
+		table, err := loadOTLPRoutes()
+		if err != nil {
+			return nil, err
+		}
+		routes := make([]exporter.Route, 0, len(table))
+		for i := range table {
+			e, err := newOTLPExporter(&table[i], retry, partial, compression)
+			if err != nil {
+				return nil, fmt.Errorf("route %s: %w", table[i].Name, err)
+			}
+			routes = append(routes, exporter.Route{Name: table[i].Name, Match: table[i].match(), Exporter: e})
+		}
+		return routes, nil
+	}
+
+	routes, err := build()
+	if err != nil || (len(routes) == 0 && appConfig == nil) {
+		return base, err
+	}
+	logger.Info("OTLP routing enabled", "routes", len(routes))
+	router := exporter.NewRouter(base, routes...)
+	routeReloaders = append(routeReloaders, func() (func(), error) {
+		routes, err := build()
+		if err != nil {
+			return nil, fmt.Errorf("invalid OTLP routes: %w", err)
+		}
+		return func() { router.SetRoutes(routes...) }, nil
+	})
+	return router, nil
+}
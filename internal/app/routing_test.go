@@ -0,0 +1,25 @@
+package app
+
+import "testing"
+
+func TestLoadOTLPRoutes(t *testing.T) {
+	t.Setenv("OTLP_ROUTES", `[{"load_balancer": "app/team-a/1", "target_group_arn": "tg-a", "headers": {"X-Scope-OrgID": "team-a"}}, {"name": "b", "account_id": "111122223333", "endpoint": "https://b.example.com/v1/logs"}]`)
+	routes, err := loadOTLPRoutes()
+	if err != nil {
+		t.Fatalf("loadOTLPRoutes() error = %v", err)
+	}
+	if len(routes) != 2 || routes[0].Name != "route-1" || routes[1].Name != "b" {
+		t.Fatalf("routes = %+v, want route-1 and b", routes)
+	}
+	if m := routes[0].match(); len(m) != 2 || m["aws.lb.name"] != "app/team-a/1" || m["aws.alb.target_group_arn"] != "tg-a" {
+		t.Errorf("route-1 match = %v", m)
+	}
+	if routes[0].Headers["X-Scope-OrgID"] != "team-a" {
+		t.Errorf("route-1 headers = %v", routes[0].Headers)
+	}
+
+	t.Setenv("OTLP_ROUTES", `[{"endpoint": "https://c.example.com/v1/logs"}]`)
+	if _, err := loadOTLPRoutes(); err == nil {
+		t.Error("loadOTLPRoutes() accepted a route that matches nothing")
+	}
+}
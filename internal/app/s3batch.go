@@ -0,0 +1,181 @@
+package app
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/smithy-go"
+)
+
+// S3 Batch Operations result codes
+const (
+	batchSucceeded        = "Succeeded"
+	batchTemporaryFailure = "TemporaryFailure"
+	batchPermanentFailure = "PermanentFailure"
+)
+
+// s3BatchEvent is an S3 Batch Operations invocation. Schema 1.0 identifies the bucket by ARN
+// and URL-encodes keys; schema 2.0 adds the plain bucket name and sends keys as-is.
+type s3BatchEvent struct {
+	InvocationSchemaVersion string `json:"invocationSchemaVersion"`
+	InvocationID            string `json:"invocationId"`
+	Job                     struct {
+		ID string `json:"id"`
+	} `json:"job"`
+	Tasks []s3BatchTask `json:"tasks"`
+}
+
+type s3BatchTask struct {
+	TaskID      string `json:"taskId"`
+	S3Key       string `json:"s3Key"`
+	S3VersionID string `json:"s3VersionId"`
+	S3BucketARN string `json:"s3BucketArn"`
+	S3Bucket    string `json:"s3Bucket"`
+}
+
+// bucketAndKey resolves the object of a task
+func (t s3BatchTask) bucketAndKey(schema string) (string, string, error) {
+	bucket := t.S3Bucket
+	if bucket == "" {
+		bucket = t.S3BucketARN[strings.LastIndex(t.S3BucketARN, ":")+1:]
+	}
+	key := t.S3Key
+	if schema == "1.0" {
+		var err error
+		if key, err = url.QueryUnescape(key); err != nil {
+			return "", "", fmt.Errorf("invalid key %q: %w", t.S3Key, err)
+		}
+	}
+	if bucket == "" || key == "" {
+		return "", "", errors.New("task has no bucket or key")
+	}
+	return bucket, key, nil
+}
+
+// s3BatchHandler processes the objects of an S3 Batch Operations job, which is how months of
+// historical logs are backfilled: point a job with a manifest of the log objects at the
+// function. Each task is answered with a result code. TemporaryFailure makes Batch Operations
+// retry the task; objects that are gone or unreadable are reported as PermanentFailure so the
+// job report lists them.
+//
+// Tasks carry no ETag, so the object version ID (when the bucket is versioned) is what the
+// dedup table and checkpoints key on.
+func s3BatchHandler(ctx context.Context, event s3BatchEvent) (events.S3BatchJobResponse, error) {
+	response := events.S3BatchJobResponse{
+		InvocationSchemaVersion: event.InvocationSchemaVersion,
+		TreatMissingKeysAs:      batchPermanentFailure,
+		InvocationID:            event.InvocationID,
+		Results:                 make([]events.S3BatchJobResult, 0, len(event.Tasks)),
+	}
+	defer reportUnrecognized()
+
+	result := func(task s3BatchTask, code, msg string) {
+		response.Results = append(response.Results, events.S3BatchJobResult{TaskID: task.TaskID, ResultCode: code, ResultString: msg})
+	}
+
+	logsExp, metrics, ok := pipelineExporters(ctx, "s3_batch", len(event.Tasks))
+	if !ok {
+		// Paused or diverted without a target: let the job retry later instead of skipping the backfill
+		for _, task := range event.Tasks {
+			result(task, batchTemporaryFailure, "pipeline paused")
+		}
+		return response, nil
+	}
+	ctx, start := beginInvocation(ctx, "s3_batch")
+
+	launchCtx, cancel := launchContext(ctx, deadlineMargin)
+	defer cancel()
+
+	sink := newStreamSink(ctx, logsExp, metrics, streamBuffer)
+
+	logger.Info("Lambda triggered", "job_id", event.Job.ID, "task_count", len(event.Tasks))
+
+	var resumed checkpointSet
+	var claims objectClaims
+
+	// Indexes into response.Results of tasks whose records went into the sink
+	var exported []int
+
+	for _, task := range event.Tasks {
+		log := logger.With("job_id", event.Job.ID, "task_id", task.TaskID)
+
+		if launchCtx.Err() != nil {
+			log.Warn("Skipping task, deadline safety margin reached", "cause", context.Cause(launchCtx))
+			result(task, batchTemporaryFailure, "deadline safety margin reached")
+			continue
+		}
+
+		bucket, key, err := task.bucketAndKey(event.InvocationSchemaVersion)
+		if err != nil {
+			log.Warn("Invalid task", "error", err)
+			result(task, batchPermanentFailure, err.Error())
+			continue
+		}
+
+		log = log.With("bucket", bucket, "key", key)
+		n, err := handleObject(launchCtx, log, sink, &claims, &resumed, bucket, key, task.S3VersionID, "")
+		switch {
+		case errors.Is(err, errObjectBusy):
+			log.Warn("Object is being processed by another invocation, retrying later")
+			result(task, batchTemporaryFailure, err.Error())
+		case err != nil:
+			log.Error("Error processing S3 object", "error", err)
+			result(task, batchResultCode(err), err.Error())
+		case n == 0:
+			result(task, batchSucceeded, "no records")
+		default:
+			exported = append(exported, len(response.Results))
+			result(task, batchSucceeded, fmt.Sprintf("exported %d records", n))
+		}
+	}
+
+	if err := sink.Close(launchCtx); err != nil {
+		logger.Error("Error sending to OTLP", "error", err, "exporters", exportSummary(), "failed_tasks", len(exported))
+		claims.settle(ctx, false)
+		for _, i := range exported {
+			response.Results[i].ResultCode = batchTemporaryFailure
+			response.Results[i].ResultString = err.Error()
+		}
+		summarizeInvocation(ctx, "s3_batch", len(event.Tasks), batchFailures(response), start)
+		return response, nil
+	}
+	claims.settle(ctx, true)
+	resumed.clear(ctx)
+
+	logger.Info("Lambda execution completed", "job_id", event.Job.ID, "exporters", exportSummary())
+	summarizeInvocation(ctx, "s3_batch", len(event.Tasks), batchFailures(response), start)
+	return response, nil
+}
+
+// batchFailures counts the tasks not answered with Succeeded
+func batchFailures(response events.S3BatchJobResponse) int {
+	n := 0
+	for _, r := range response.Results {
+		if r.ResultCode != batchSucceeded {
+			n++
+		}
+	}
+	return n
+}
+
+// batchResultCode reports objects that cannot be read or decoded as permanent failures and
+// everything else, e.g. throttling, as temporary so Batch Operations retries the task
+func batchResultCode(err error) string {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.ErrorCode() {
+		case "NoSuchKey", "NoSuchBucket", "AccessDenied":
+			return batchPermanentFailure
+		}
+	}
+	if errors.Is(err, fs.ErrNotExist) || errors.Is(err, gzip.ErrHeader) {
+		return batchPermanentFailure
+	}
+	return batchTemporaryFailure
+}
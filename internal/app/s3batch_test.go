@@ -0,0 +1,101 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/aws/smithy-go"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/exporter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/processor"
+)
+
+func TestS3BatchTask_BucketAndKey(t *testing.T) {
+	tests := []struct {
+		name       string
+		schema     string
+		task       s3BatchTask
+		wantBucket string
+		wantKey    string
+		wantErr    bool
+	}{
+		{"schema 1.0 decodes the key", "1.0", s3BatchTask{S3BucketARN: "arn:aws:s3:::logs", S3Key: "AWSLogs/a+b%3Dc.log.gz"}, "logs", "AWSLogs/a b=c.log.gz", false},
+		{"schema 2.0 keeps the key", "2.0", s3BatchTask{S3Bucket: "logs", S3Key: "AWSLogs/a+b.log.gz"}, "logs", "AWSLogs/a+b.log.gz", false},
+		{"missing key", "2.0", s3BatchTask{S3Bucket: "logs"}, "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bucket, key, err := tt.task.bucketAndKey(tt.schema)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("bucketAndKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if bucket != tt.wantBucket || key != tt.wantKey {
+				t.Errorf("bucketAndKey() = %q, %q, want %q, %q", bucket, key, tt.wantBucket, tt.wantKey)
+			}
+		})
+	}
+}
+
+func TestBatchResultCode(t *testing.T) {
+	missing := fmt.Errorf("failed to get S3 object: %w", &smithy.GenericAPIError{Code: "NoSuchKey", Message: "gone"})
+	throttled := fmt.Errorf("failed to get S3 object: %w", &smithy.GenericAPIError{Code: "SlowDown", Message: "slow down"})
+
+	if got := batchResultCode(missing); got != batchPermanentFailure {
+		t.Errorf("NoSuchKey = %s, want %s", got, batchPermanentFailure)
+	}
+	if got := batchResultCode(throttled); got != batchTemporaryFailure {
+		t.Errorf("SlowDown = %s, want %s", got, batchTemporaryFailure)
+	}
+}
+
+func TestS3BatchHandler(t *testing.T) {
+	logger = slog.New(slog.NewJSONHandler(io.Discard, nil))
+	maxBatchSize = 10
+	maxConcurrent = 2
+
+	prevRegistry, prevExp := registry, exp
+	defer func() { registry, exp = prevRegistry, prevExp }()
+	registry = processor.NewRegistry()
+	registry.Register(fakeProcessor{})
+
+	event := s3BatchEvent{InvocationSchemaVersion: "1.0", InvocationID: "inv-1"}
+	for i, key := range []string{"good", "bad", "empty"} {
+		event.Tasks = append(event.Tasks, s3BatchTask{TaskID: fmt.Sprintf("t%d", i), S3BucketARN: "arn:aws:s3:::logs", S3Key: key})
+	}
+
+	tests := []struct {
+		name      string
+		exportErr error
+		want      []string
+	}{
+		{"per task results", nil, []string{batchSucceeded, batchTemporaryFailure, batchSucceeded}},
+		{"export failure retries tasks with records", errors.New("backend down"), []string{batchTemporaryFailure, batchTemporaryFailure, batchSucceeded}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exp = exporter.ExporterFunc(func(ctx context.Context, logs converter.ResourceLog) error {
+				return tt.exportErr
+			})
+			resp, err := s3BatchHandler(context.Background(), event)
+			if err != nil {
+				t.Fatalf("s3BatchHandler() error = %v", err)
+			}
+			if resp.InvocationID != "inv-1" || resp.InvocationSchemaVersion != "1.0" {
+				t.Errorf("response does not echo the invocation: %+v", resp)
+			}
+			if len(resp.Results) != len(tt.want) {
+				t.Fatalf("got %d results, want %d", len(resp.Results), len(tt.want))
+			}
+			for i, res := range resp.Results {
+				if res.TaskID != event.Tasks[i].TaskID || res.ResultCode != tt.want[i] {
+					t.Errorf("result %d = %s %s, want %s %s", i, res.TaskID, res.ResultCode, event.Tasks[i].TaskID, tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package app
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+)
+
+// secretsClient is created on first use so deployments without secrets need no extra permissions
+var secretsClient *secretsmanager.Client
+
+// getSecretEnv returns the value of key or, when <key>_ARN is set instead, the secret string
+// stored in Secrets Manager under that ARN. Secrets are read once, at cold start, and redacted
+// from the effective configuration.
+func getSecretEnv(key string) (string, error) {
+	if value := settings.Secret(key, ""); value != "" {
+		return value, nil
+	}
+	arn := getEnv(key+"_ARN", "")
+	if arn == "" {
+		return "", nil
+	}
+	value, err := readSecret(arn)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s_ARN: %w", key, err)
+	}
+	return value, nil
+}
+
+// readSecret returns the secret string stored in Secrets Manager under arn
+func readSecret(arn string) (string, error) {
+	if secretsClient == nil {
+		awsCfg, err := awsConfig()
+		if err != nil {
+			return "", err
+		}
+		secretsClient = secretsmanager.NewFromConfig(awsCfg)
+	}
+	out, err := secretsClient.GetSecretValue(context.Background(), &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(arn),
+	})
+	if err != nil {
+		return "", err
+	}
+	return aws.ToString(out.SecretString), nil
+}
+
+// getPEMEnv returns PEM material configured as inline PEM, a file path, or (via <key>_ARN) a
+// Secrets Manager secret holding the PEM
+func getPEMEnv(key string) ([]byte, error) {
+	value, err := getSecretEnv(key)
+	if err != nil || value == "" {
+		return nil, err
+	}
+	if strings.Contains(value, "-----BEGIN ") {
+		return []byte(value), nil
+	}
+	data, err := os.ReadFile(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", key, err)
+	}
+	return data, nil
+}
+
+// loadSSECustomerKey returns the base64-encoded SSE-C key configured in S3_SSE_C_KEY, or in the
+// secret named by S3_SSE_C_KEY_ARN, after checking it decodes to a 256-bit key
+func loadSSECustomerKey() (string, error) {
+	value, err := getSecretEnv("S3_SSE_C_KEY")
+	if err != nil || value == "" {
+		return "", err
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+	if err != nil {
+		return "", fmt.Errorf("key is not base64: %w", err)
+	}
+	if len(key) != 32 {
+		return "", fmt.Errorf("key is %d bytes, SSE-C needs a 256-bit key", len(key))
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// loadIPAnonymizer reads IP_ANONYMIZATION, with the salt of the hash mode in
+// IP_ANONYMIZATION_SALT or the secret named by IP_ANONYMIZATION_SALT_ARN
+func loadIPAnonymizer() (*converter.IPAnonymizer, error) {
+	mode := getEnv("IP_ANONYMIZATION", converter.IPAnonymizationOff)
+	salt := ""
+	if strings.EqualFold(mode, converter.IPAnonymizationHash) {
+		var err error
+		if salt, err = getSecretEnv("IP_ANONYMIZATION_SALT"); err != nil {
+			return nil, err
+		}
+	}
+	return converter.ParseIPAnonymization(mode, salt)
+}
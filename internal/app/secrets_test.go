@@ -0,0 +1,15 @@
+package app
+
+import "testing"
+
+func TestLoadIPAnonymizer_SaltRedacted(t *testing.T) {
+	t.Setenv("IP_ANONYMIZATION", "hash")
+	t.Setenv("IP_ANONYMIZATION_SALT", "pepper")
+
+	if _, err := loadIPAnonymizer(); err != nil {
+		t.Fatalf("loadIPAnonymizer() error = %v", err)
+	}
+	if got := settings.Effective()["IP_ANONYMIZATION_SALT"]; got != "[redacted]" {
+		t.Errorf("Effective()[IP_ANONYMIZATION_SALT] = %q, want [redacted]", got)
+	}
+}
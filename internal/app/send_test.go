@@ -0,0 +1,570 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pixelvide/otel-aws-log-parser/cmd/lambda/adapter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/exporter"
+	logpipeline "github.com/pixelvide/otel-aws-log-parser/pkg/pipeline"
+)
+
+type fakeEntry struct {
+	severity int
+}
+
+func (e fakeEntry) GetResourceKey() string                           { return "lb" }
+func (e fakeEntry) GetResourceAttributes() []converter.OTelAttribute { return nil }
+func (e fakeEntry) ToOTel() converter.OTelLogRecord {
+	return converter.OTelLogRecord{TimeUnixNano: "1700000000000000000", SeverityNumber: e.severity}
+}
+
+func TestConvertAndSend_PriorityLanes(t *testing.T) {
+	logger = slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	var mu sync.Mutex
+	var batches [][]int
+	exp = exporter.ExporterFunc(func(ctx context.Context, logs converter.ResourceLog) error {
+		var severities []int
+		for _, record := range logs.ScopeLogs[0].LogRecords {
+			severities = append(severities, record.SeverityNumber)
+		}
+		mu.Lock()
+		batches = append(batches, severities)
+		mu.Unlock()
+		return nil
+	})
+
+	maxBatchSize = 2
+	maxConcurrent = 1
+	priorityLanes = true
+	defer func() { priorityLanes = false }()
+
+	entries := []adapter.LogAdapter{
+		fakeEntry{9}, fakeEntry{9}, fakeEntry{17}, fakeEntry{9}, fakeEntry{17},
+	}
+	if err := convertAndSend(context.Background(), exp, metricsExp, entries); err != nil {
+		t.Fatalf("convertAndSend() error = %v", err)
+	}
+
+	if len(batches) != 3 {
+		t.Fatalf("got %d batches, want 3", len(batches))
+	}
+	for _, severity := range batches[0] {
+		if severity != 17 {
+			t.Errorf("first batch = %v, want only priority records", batches[0])
+		}
+	}
+	for _, batch := range batches[1:] {
+		for _, severity := range batch {
+			if severity == 17 {
+				t.Errorf("bulk batch %v contains a priority record", batch)
+			}
+		}
+	}
+}
+
+type fakeMetricsExporter func(ctx context.Context, metrics converter.ResourceMetric) error
+
+func (f fakeMetricsExporter) ExportMetrics(ctx context.Context, metrics converter.ResourceMetric) error {
+	return f(ctx, metrics)
+}
+
+func TestConvertAndSend_LogsAndMetrics(t *testing.T) {
+	logger = slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	var mu sync.Mutex
+	logCount := 0
+	exp = exporter.ExporterFunc(func(ctx context.Context, logs converter.ResourceLog) error {
+		mu.Lock()
+		logCount += len(logs.ScopeLogs[0].LogRecords)
+		mu.Unlock()
+		return nil
+	})
+
+	var got []converter.Metric
+	metricsExp = fakeMetricsExporter(func(ctx context.Context, metrics converter.ResourceMetric) error {
+		mu.Lock()
+		got = append(got, metrics.ScopeMetrics[0].Metrics...)
+		mu.Unlock()
+		return nil
+	})
+	defer func() { metricsExp = nil }()
+
+	maxBatchSize = 2
+	maxConcurrent = 2
+
+	entries := []adapter.LogAdapter{fakeEntry{9}, fakeEntry{9}, fakeEntry{17}}
+	if err := convertAndSend(context.Background(), exp, metricsExp, entries); err != nil {
+		t.Fatalf("convertAndSend() error = %v", err)
+	}
+
+	if logCount != 3 {
+		t.Errorf("exported %d log records, want 3", logCount)
+	}
+	if len(got) != 1 || got[0].Name != "aws.log.records" {
+		t.Fatalf("exported metrics = %+v, want one aws.log.records", got)
+	}
+	total := 0
+	for _, p := range got[0].Sum.DataPoints {
+		n, _ := strconv.Atoi(p.AsInt)
+		total += n
+	}
+	if total != 3 {
+		t.Errorf("metric counts total %d, want 3", total)
+	}
+}
+
+type fakeHTTPEntry struct {
+	status int
+}
+
+func (e fakeHTTPEntry) GetResourceKey() string                           { return "lb" }
+func (e fakeHTTPEntry) GetResourceAttributes() []converter.OTelAttribute { return nil }
+func (e fakeHTTPEntry) ToOTel() converter.OTelLogRecord {
+	status := strconv.Itoa(e.status)
+	return converter.OTelLogRecord{TimeUnixNano: "1700000000000000000", Attributes: []converter.OTelAttribute{
+		{Key: "http.response.status_code", Value: converter.OTelAnyValue{IntValue: &status}},
+	}}
+}
+
+func TestConvertAndSend_AggregateMetricsOnly(t *testing.T) {
+	logger = slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	logCount := 0
+	exp = exporter.ExporterFunc(func(ctx context.Context, logs converter.ResourceLog) error {
+		logCount += len(logs.ScopeLogs[0].LogRecords)
+		return nil
+	})
+
+	var mu sync.Mutex
+	got := map[string]converter.Metric{}
+	metricsExp = fakeMetricsExporter(func(ctx context.Context, metrics converter.ResourceMetric) error {
+		mu.Lock()
+		for _, m := range metrics.ScopeMetrics[0].Metrics {
+			got[m.Name] = m
+		}
+		mu.Unlock()
+		return nil
+	})
+	aggregateMode = aggregateMetrics
+	defer func() { metricsExp, aggregateMode = nil, "" }()
+
+	maxBatchSize = 2
+	maxConcurrent = 2
+
+	entries := []adapter.LogAdapter{fakeHTTPEntry{200}, fakeHTTPEntry{200}, fakeHTTPEntry{503}}
+	if err := convertAndSend(context.Background(), exp, metricsExp, entries); err != nil {
+		t.Fatalf("convertAndSend() error = %v", err)
+	}
+
+	if logCount != 0 {
+		t.Errorf("exported %d log records, want none in metrics mode", logCount)
+	}
+	requests, ok := got["aws.log.requests"]
+	if !ok || len(requests.Sum.DataPoints) != 2 {
+		t.Fatalf("exported metrics = %+v, want aws.log.requests for 2xx and 5xx", got)
+	}
+	if errors, ok := got["aws.log.errors"]; !ok || errors.Sum.DataPoints[0].AsInt != "1" {
+		t.Errorf("aws.log.errors = %+v, want 1", errors)
+	}
+}
+
+func TestConvertAndSend_AggregateMetricsOverGRPC(t *testing.T) {
+	logger = slog.New(slog.NewJSONHandler(io.Discard, nil))
+	exp = exporter.ExporterFunc(func(ctx context.Context, logs converter.ResourceLog) error { return nil })
+	aggregateMode = aggregateMetrics
+	defer func() { metricsExp, aggregateMode = nil, "" }()
+	maxBatchSize = 2
+	maxConcurrent = 2
+
+	tests := []struct {
+		name     string
+		override bool
+	}{
+		{"OTLP_GRPC_ENDPOINT", false},
+		{"OTLP_METRICS_ENDPOINT override", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			received := grpcMetricsCollector(t)
+			if tt.override {
+				// The logs endpoint is unreachable, so only the override can receive the metrics
+				t.Setenv("OTLP_METRICS_ENDPOINT", os.Getenv("OTLP_GRPC_ENDPOINT"))
+				t.Setenv("OTLP_GRPC_ENDPOINT", "127.0.0.1:1")
+			}
+			var err error
+			if metricsExp, err = newMetricsExporter(); err != nil {
+				t.Fatalf("newMetricsExporter() error = %v", err)
+			}
+
+			entries := []adapter.LogAdapter{fakeHTTPEntry{200}, fakeHTTPEntry{503}}
+			if err := convertAndSend(context.Background(), exp, metricsExp, entries); err != nil {
+				t.Fatalf("convertAndSend() error = %v", err)
+			}
+
+			req := <-received
+			names := map[string]bool{}
+			for _, m := range req.ResourceMetrics[0].ScopeMetrics[0].Metrics {
+				names[m.Name] = true
+			}
+			if !names["aws.log.requests"] || !names["aws.log.errors"] {
+				t.Errorf("metrics = %v, want aws.log.requests and aws.log.errors", names)
+			}
+		})
+	}
+}
+
+type fakeTracesExporter func(ctx context.Context, spans converter.ResourceSpans) error
+
+func (f fakeTracesExporter) ExportSpans(ctx context.Context, spans converter.ResourceSpans) error {
+	return f(ctx, spans)
+}
+
+type fakeALBEntry struct {
+	traceID string
+}
+
+func (e fakeALBEntry) GetResourceKey() string                           { return "lb" }
+func (e fakeALBEntry) GetResourceAttributes() []converter.OTelAttribute { return nil }
+func (e fakeALBEntry) ToOTel() converter.OTelLogRecord {
+	record := converter.OTelLogRecord{TimeUnixNano: "1700000000000000000", TraceID: e.traceID}
+	if e.traceID != "" {
+		record.SpanID = "0123456789abcdef"
+	}
+	return record
+}
+
+func TestConvertAndSend_ALBSpans(t *testing.T) {
+	logger = slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	exp = exporter.ExporterFunc(func(ctx context.Context, logs converter.ResourceLog) error { return nil })
+
+	var mu sync.Mutex
+	var batches []int
+	tracesExp = fakeTracesExporter(func(ctx context.Context, spans converter.ResourceSpans) error {
+		mu.Lock()
+		batches = append(batches, len(spans.ScopeSpans[0].Spans))
+		mu.Unlock()
+		return nil
+	})
+	albSpans = true
+	defer func() { tracesExp, albSpans = nil, false }()
+
+	maxBatchSize = 2
+	maxConcurrent = 1
+
+	trace := "5833726236d228ad5d99923122bbe354"
+	entries := []adapter.LogAdapter{fakeALBEntry{trace}, fakeALBEntry{""}, fakeALBEntry{trace}, fakeALBEntry{trace}}
+	if err := convertAndSend(context.Background(), exp, nil, entries); err != nil {
+		t.Fatalf("convertAndSend() error = %v", err)
+	}
+
+	sort.Ints(batches)
+	if len(batches) != 2 || batches[0] != 1 || batches[1] != 2 {
+		t.Errorf("span batches = %v, want 3 spans in batches of 2", batches)
+	}
+}
+
+func TestStreamSink_StreamsSpans(t *testing.T) {
+	logger = slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	var spansSent atomic.Int64
+	tracesExp = fakeTracesExporter(func(ctx context.Context, spans converter.ResourceSpans) error {
+		spansSent.Add(int64(len(spans.ScopeSpans[0].Spans)))
+		return nil
+	})
+	albSpans = true
+	defer func() { tracesExp, albSpans = nil, false }()
+
+	maxBatchSize = 2
+	maxConcurrent = 1
+
+	logsExp := exporter.ExporterFunc(func(ctx context.Context, logs converter.ResourceLog) error { return nil })
+	sink := newStreamSink(context.Background(), logsExp, nil, 4)
+	trace := "5833726236d228ad5d99923122bbe354"
+	for range 6 {
+		if err := sink.Add(context.Background(), fakeALBEntry{trace}); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+
+	// Spans count towards the buffer and leave with the records instead of waiting for Close
+	sink.mu.Lock()
+	sink.waitIdle()
+	held, streamed := sink.buffered, spansSent.Load()
+	sink.mu.Unlock()
+	if held >= 4 || streamed == 0 {
+		t.Errorf("before Close: %d buffered, %d spans sent, want spans streamed under buffer pressure", held, streamed)
+	}
+
+	if err := sink.Close(context.Background()); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if got := spansSent.Load(); got != 6 {
+		t.Errorf("sent %d spans, want 6", got)
+	}
+}
+
+func TestStreamSink_CloseUnlocked(t *testing.T) {
+	logger = slog.New(slog.NewJSONHandler(io.Discard, nil))
+	maxBatchSize = 2
+	maxConcurrent = 2
+
+	// Close must not hold the sink's lock while it exports
+	var sink *streamSink
+	var locked atomic.Bool
+	logsExp := exporter.ExporterFunc(func(ctx context.Context, logs converter.ResourceLog) error {
+		if !sink.mu.TryLock() {
+			locked.Store(true)
+			return nil
+		}
+		sink.mu.Unlock()
+		return nil
+	})
+	sink = newStreamSink(context.Background(), logsExp, nil, 0)
+	for range 3 {
+		if err := sink.Add(context.Background(), fakeEntry{9}); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+	if err := sink.Close(context.Background()); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if locked.Load() {
+		t.Error("Close() held the sink lock while exporting")
+	}
+}
+
+func TestStreamSink_FlushesUnderBufferPressure(t *testing.T) {
+	logger = slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	var mu sync.Mutex
+	var batchSizes []int
+	maxBatchSize = 3
+	maxConcurrent = 1
+
+	maxBatchSize = 2
+	maxConcurrent = 1
+
+	sink := newStreamSink(context.Background(), exporter.ExporterFunc(func(ctx context.Context, logs converter.ResourceLog) error {
+		mu.Lock()
+		batchSizes = append(batchSizes, len(logs.ScopeLogs[0].LogRecords))
+		mu.Unlock()
+		return nil
+	}), nil, 4)
+
+	for i := 0; i < 10; i++ {
+		if err := sink.Add(context.Background(), fakeEntry{9}); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+		sink.mu.Lock()
+		buffered := sink.buffered
+		sink.mu.Unlock()
+		if buffered >= 4 {
+			t.Fatalf("after %d entries %d records are buffered, want fewer than 4", i+1, buffered)
+		}
+	}
+	if err := sink.Close(context.Background()); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	total := 0
+	for _, size := range batchSizes {
+		if size > 3 {
+			t.Errorf("batch of %d records exceeds MAX_BATCH_SIZE", size)
+		}
+		total += size
+	}
+	if total != 10 || len(batchSizes) < 3 {
+		t.Errorf("batches = %v, want 10 records over at least 3 batches", batchSizes)
+	}
+}
+
+func TestStreamSink_StopsOnExportError(t *testing.T) {
+	logger = slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	sink := newStreamSink(context.Background(), exporter.ExporterFunc(func(ctx context.Context, logs converter.ResourceLog) error {
+		return errors.New("backend down")
+	}), nil, 2)
+
+	var err error
+	for i := 0; i < 100 && err == nil; i++ {
+		err = sink.Add(context.Background(), fakeEntry{9})
+	}
+	if err == nil {
+		t.Fatal("Add() kept accepting entries after the export failed")
+	}
+	if err := sink.Close(context.Background()); err == nil {
+		t.Error("Close() error = nil, want the export failure")
+	}
+}
+
+func TestStreamSink_Flush(t *testing.T) {
+	logger = slog.New(slog.NewJSONHandler(io.Discard, nil))
+	maxBatchSize = 2
+	maxConcurrent = 2
+
+	var mu sync.Mutex
+	exported := 0
+	sink := newStreamSink(context.Background(), exporter.ExporterFunc(func(ctx context.Context, logs converter.ResourceLog) error {
+		mu.Lock()
+		exported += len(logs.ScopeLogs[0].LogRecords)
+		mu.Unlock()
+		return nil
+	}), nil, 0)
+
+	for i := 0; i < 5; i++ {
+		if err := sink.Add(context.Background(), fakeEntry{9}); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+	if err := sink.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	mu.Lock()
+	if exported != 5 {
+		t.Errorf("exported %d records after Flush, want 5", exported)
+	}
+	mu.Unlock()
+
+	if err := sink.Close(context.Background()); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if exported != 5 {
+		t.Errorf("exported %d records after Close, want 5 (nothing re-sent)", exported)
+	}
+}
+
+type fakeSeqEntry struct {
+	seq int
+}
+
+func (e fakeSeqEntry) GetResourceKey() string                           { return "lb" }
+func (e fakeSeqEntry) GetResourceAttributes() []converter.OTelAttribute { return nil }
+func (e fakeSeqEntry) ToOTel() converter.OTelLogRecord {
+	return converter.OTelLogRecord{TimeUnixNano: strconv.Itoa(e.seq)}
+}
+
+func TestStreamSink_Ordered(t *testing.T) {
+	logger = slog.New(slog.NewJSONHandler(io.Discard, nil))
+	maxBatchSize = 2
+	maxConcurrent = 4
+	readOpts.Ordered = true
+	defer func() { readOpts.Ordered = false }()
+
+	tests := []struct {
+		name        string
+		maxBuffered int
+	}{
+		{"streamed while adding", 4},
+		{"sent by Close", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var got []int
+			sink := newStreamSink(context.Background(), exporter.ExporterFunc(func(ctx context.Context, logs converter.ResourceLog) error {
+				records := logs.ScopeLogs[0].LogRecords
+				// Earlier batches take longer, so concurrent exports would finish out of order
+				first, _ := strconv.Atoi(records[0].TimeUnixNano)
+				time.Sleep(time.Duration(30-first) * 100 * time.Microsecond)
+				mu.Lock()
+				for _, record := range records {
+					n, _ := strconv.Atoi(record.TimeUnixNano)
+					got = append(got, n)
+				}
+				mu.Unlock()
+				return nil
+			}), nil, tt.maxBuffered)
+
+			for i := 0; i < 25; i++ {
+				if err := sink.Add(context.Background(), fakeSeqEntry{i}); err != nil {
+					t.Fatalf("Add() error = %v", err)
+				}
+			}
+			if err := sink.Close(context.Background()); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+
+			if len(got) != 25 || !sort.IntsAreSorted(got) {
+				t.Errorf("exported %v, want 0..24 in order", got)
+			}
+		})
+	}
+}
+
+func TestStreamSink_ProcessorIdentity(t *testing.T) {
+	logger = slog.New(slog.NewJSONHandler(io.Discard, nil))
+	maxBatchSize = 10
+	maxConcurrent = 1
+	t.Setenv("SERVICE_NAME", "edge")
+	t.Setenv("DEPLOYMENT_ENVIRONMENT", "prod")
+	t.Setenv("ALB_SERVICE_NAME", "edge-alb")
+	t.Setenv("ALB_SCOPE_NAME", "alb")
+
+	if id := processorIdentity("NLB"); id.ServiceName != "edge" || id.Scope.Name != "" {
+		t.Errorf("NLB identity = %+v, want the shared SERVICE_NAME only", id)
+	}
+	id := processorIdentity("ALB")
+
+	var got []converter.ResourceLog
+	sink := newStreamSink(context.Background(), exporter.ExporterFunc(func(ctx context.Context, logs converter.ResourceLog) error {
+		got = append(got, logs)
+		return nil
+	}), nil, 0)
+	if err := sink.Add(context.Background(), id.Apply(fakeEntry{9})); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err := sink.Close(context.Background()); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	if len(got) != 1 {
+		t.Fatalf("exported %d batches, want 1", len(got))
+	}
+	if scope := got[0].ScopeLogs[0].Scope; scope.Name != "alb" || scope.Version != logpipeline.DefaultScope().Version {
+		t.Errorf("scope = %+v, want alb with the parser's version", scope)
+	}
+	attrs := map[string]string{}
+	for _, attr := range got[0].Resource.Attributes {
+		attrs[attr.Key] = *attr.Value.StringValue
+	}
+	if attrs["service.name"] != "edge-alb" || attrs["deployment.environment"] != "prod" {
+		t.Errorf("resource attributes = %v", attrs)
+	}
+}
+
+func TestConvertAndSend_ExporterPanic(t *testing.T) {
+	prevLogger, prevBatchSize, prevConcurrent := logger, maxBatchSize, maxConcurrent
+	t.Cleanup(func() { logger, maxBatchSize, maxConcurrent = prevLogger, prevBatchSize, prevConcurrent })
+	logger = slog.New(slog.NewJSONHandler(io.Discard, nil))
+	maxBatchSize = 1
+	maxConcurrent = 1
+
+	calls := 0
+	panicking := exporter.ExporterFunc(func(ctx context.Context, logs converter.ResourceLog) error {
+		calls++
+		panic("bad record")
+	})
+
+	entries := []adapter.LogAdapter{fakeEntry{9}, fakeEntry{9}, fakeEntry{9}}
+	err := convertAndSend(context.Background(), panicking, nil, entries)
+	if err == nil || !strings.Contains(err.Error(), "panic: bad record") {
+		t.Fatalf("convertAndSend() error = %v, want the recovered panic", err)
+	}
+	if calls != 1 {
+		t.Errorf("exporter called %d times, want no batch started after the failure", calls)
+	}
+}
@@ -0,0 +1,214 @@
+package app
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/processor"
+)
+
+// headerFlags collects repeated -header key=value flags
+type headerFlags []string
+
+func (h *headerFlags) String() string { return strings.Join(*h, ",") }
+
+func (h *headerFlags) Set(value string) error {
+	if !strings.Contains(value, "=") {
+		return fmt.Errorf("want key=value, got %q", value)
+	}
+	*h = append(*h, value)
+	return nil
+}
+
+// otlpFlags are the exporter flags of the commands that export to an OTLP endpoint
+type otlpFlags struct {
+	endpoint string
+	protocol string
+	headers  headerFlags
+}
+
+func (o *otlpFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.endpoint, "endpoint", "", "OTLP endpoint (default SIGNOZ_OTLP_ENDPOINT, or OTLP_GRPC_ENDPOINT with -protocol grpc)")
+	fs.StringVar(&o.protocol, "protocol", "", "http or grpc (default OTLP_PROTOCOL)")
+	fs.Var(&o.headers, "header", "extra OTLP header as key=value with a percent-encoded value, repeatable; added to OTLP_HEADERS")
+}
+
+// apply overrides the environment with the flags and rebuilds the OTLP exporter from both. It
+// runs after Configure, so the flags also win over CONFIG_FILE and AppConfig.
+func (o *otlpFlags) apply() error {
+	overrides := maps.Clone(settings.Overrides())
+	if overrides == nil {
+		overrides = map[string]string{}
+	}
+	overrides["EXPORTER"] = "otlp"
+	if o.protocol != "" {
+		if o.protocol != "http" && o.protocol != "grpc" {
+			return fmt.Errorf("invalid -protocol %q, expected http or grpc", o.protocol)
+		}
+		overrides["OTLP_PROTOCOL"] = o.protocol
+	}
+	if o.endpoint != "" {
+		if getEnv("OTLP_PROTOCOL", "http") == "grpc" || o.protocol == "grpc" {
+			overrides["OTLP_GRPC_ENDPOINT"] = o.endpoint
+		} else {
+			overrides["SIGNOZ_OTLP_ENDPOINT"] = o.endpoint
+		}
+	}
+	if len(o.headers) > 0 {
+		all := append(getEnvList("OTLP_HEADERS"), o.headers...)
+		overrides["OTLP_HEADERS"] = strings.Join(all, ",")
+	}
+	settings.SetOverrides(overrides)
+	var err error
+	if exp, err = newExporters("otlp", nil); err != nil {
+		return fmt.Errorf("otlp exporter: %w", err)
+	}
+	return nil
+}
+
+// RunSend implements the send command, which exports log files through the same processors,
+// batching, retries and OTLP exporter as the function, to reproduce production issues from a
+// laptop. Paths may be files, directories (their files, not recursively) or glob patterns, or
+// s3://bucket/key objects read with -profile and -region. Processors are matched on the file's
+// path as if it were an S3 key, so files downloaded with their S3 layout match as in
+// production; -processor picks one instead.
+//
+//	bootstrap send [-endpoint URL|host:port] [-protocol http|grpc] [-header key=value]... [-processor alb] [-profile P] [-region R] PATH|s3://bucket/key...
+func RunSend(args []string) error {
+	fs := flag.NewFlagSet("send", flag.ContinueOnError)
+	var otlp otlpFlags
+	otlp.register(fs)
+	procName := fs.String("processor", "", "processor of every file: alb, nlb, cloudfront or waf (default by path)")
+	profile := fs.String("profile", "", "AWS profile for s3:// objects (default the SDK's)")
+	region := fs.String("region", "", "AWS region for s3:// objects (default the profile's)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("no files given")
+	}
+
+	files := fs.Args()
+	s3Objects := slices.ContainsFunc(files, processor.IsS3URI)
+	if s3Objects && slices.ContainsFunc(files, func(path string) bool { return !processor.IsS3URI(path) }) {
+		return fmt.Errorf("s3:// objects and local files cannot be sent together")
+	}
+	if !s3Objects {
+		var err error
+		if files, err = sendFiles(files); err != nil {
+			return err
+		}
+	}
+
+	if err := Configure(); err != nil {
+		return err
+	}
+	if err := otlp.apply(); err != nil {
+		return err
+	}
+
+	// Local files are read through a store rooted at /, the first path element as the bucket
+	store = &processor.LocalStore{Root: "/"}
+	if s3Objects {
+		opts := append(sdkOptions(), config.WithSharedConfigProfile(*profile), config.WithRegion(*region))
+		awsCfg, err := config.LoadDefaultConfig(context.Background(), opts...)
+		if err != nil {
+			return err
+		}
+		store = &processor.S3Store{Client: s3.NewFromConfig(awsCfg)}
+	}
+	event := events.SQSEvent{}
+	for i, file := range files {
+		bucket, key := localObject(file)
+		if s3Objects {
+			var err error
+			if bucket, key, err = processor.ParseS3URI(file); err != nil {
+				return err
+			}
+		}
+		if *procName != "" {
+			if err := registry.Force(bucket, *procName); err != nil {
+				return fmt.Errorf("-processor: %w", err)
+			}
+		} else if registry.Find(bucket, key) == nil {
+			return fmt.Errorf("no processor matches %s, pick one with -processor", file)
+		}
+		msg, err := objectMessage(strconv.Itoa(i), bucket, key, "")
+		if err != nil {
+			return err
+		}
+		event.Records = append(event.Records, msg)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	logger.Info("Sending files", "files", len(files))
+	resp, err := handler(ctx, event)
+	if err != nil {
+		return err
+	}
+	var failed []string
+	for _, f := range resp.BatchItemFailures {
+		i, _ := strconv.Atoi(f.ItemIdentifier)
+		failed = append(failed, files[i])
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to send %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// sendFiles expands the paths of the send command to absolute file paths
+func sendFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, pattern := range paths {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no such file: %s", pattern)
+		}
+		for _, match := range matches {
+			info, err := os.Stat(match)
+			if err != nil {
+				return nil, err
+			}
+			if !info.IsDir() {
+				files = append(files, match)
+				continue
+			}
+			entries, err := os.ReadDir(match)
+			if err != nil {
+				return nil, err
+			}
+			for _, entry := range entries {
+				if !entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+					files = append(files, filepath.Join(match, entry.Name()))
+				}
+			}
+		}
+	}
+	for i, file := range files {
+		abs, err := filepath.Abs(file)
+		if err != nil {
+			return nil, err
+		}
+		files[i] = abs
+	}
+	return files, nil
+}
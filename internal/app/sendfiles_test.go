@@ -0,0 +1,65 @@
+package app
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+const albLine = `http 2018-07-02T22:23:00.186641Z app/my-loadbalancer/50dc6c495c0c9188 192.168.131.39:2817 10.0.0.1:80 0.000 0.001 0.000 200 200 34 366 "GET http://www.example.com:80/ HTTP/1.1" "curl/7.46.0" - - arn:aws:elasticloadbalancing:us-east-2:123456789012:targetgroup/my-targets/73e2d6bc24d8a067 "Root=1-58337262-36d228ad5d99923122bbe354" "-" "-" 0 2018-07-02T22:22:48.364000Z "forward" "-" "-" "10.0.0.1:80" "200" "-" "-" TID_1234
+`
+
+func TestRunSend(t *testing.T) {
+	logger = slog.New(slog.NewJSONHandler(io.Discard, nil))
+	maxBatchSize = 10
+	maxConcurrent = 2
+
+	var mu sync.Mutex
+	var tenants []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		tenants = append(tenants, r.Header.Get("X-Scope-OrgID"))
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	// RunSend configures itself from the environment, as the command does
+	prevRegistry, prevExp, prevStore, prevOverrides := registry, exp, store, settings.Overrides()
+	t.Cleanup(func() {
+		registry, exp, store = prevRegistry, prevExp, prevStore
+		settings.SetOverrides(prevOverrides)
+	})
+
+	dir := t.TempDir()
+	for _, name := range []string{"one.log", "two.log", ".hidden"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(albLine), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := RunSend([]string{"-endpoint", server.URL + "/v1/logs", "-header", "x-scope-orgid=tenant1", "-processor", "alb", dir})
+	if err != nil {
+		t.Fatalf("RunSend() error = %v", err)
+	}
+	if len(tenants) == 0 {
+		t.Fatal("no export request received")
+	}
+	for _, tenant := range tenants {
+		if tenant != "tenant1" {
+			t.Errorf("X-Scope-OrgID = %q, want tenant1", tenant)
+		}
+	}
+
+	if err := RunSend([]string{filepath.Join(dir, "*.missing")}); err == nil {
+		t.Error("RunSend() error = nil, want an error for a pattern matching nothing")
+	}
+	if err := RunSend([]string{"s3://logs/one.log", dir}); err == nil {
+		t.Error("RunSend() error = nil, want an error for s3:// objects mixed with local files")
+	}
+}
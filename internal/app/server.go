@@ -0,0 +1,146 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/processor"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/version"
+)
+
+// RunServer serves the handler over plain HTTP instead of the Lambda runtime API.
+// Server mode is meant for local sandboxes and non-Lambda deployments:
+//
+//	GET  /healthz        liveness probe, includes the build version
+//	GET  /version        build info
+//	POST /invoke         an SQS, Kinesis, CloudWatch Logs or S3 Batch Operations event, or a
+//	                     single EventBridge S3 event
+//	POST /fixtures/load  process every object under LOCAL_SOURCE_DIR
+func RunServer(addr string) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{"status": "ok", "version": version.Info()})
+	})
+	mux.Handle("/version", version.Handler())
+	mux.HandleFunc("/invoke", serveInvoke)
+	mux.HandleFunc("/fixtures/load", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		resp, err := loadFixtures(r.Context())
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, resp)
+	})
+
+	server := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if getEnvBool("FIXTURES_LOAD_ON_START", false) {
+		go func() {
+			if _, err := loadFixtures(ctx); err != nil {
+				logger.Error("Failed to load fixtures", "error", err)
+			}
+		}()
+	}
+
+	// Idle servers still pick up AppConfig changes
+	go watchDynamicSettings(ctx)
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	logger.Info("Server mode listening", "addr", addr)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func serveInvoke(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var resp any
+	switch eventSource(body) {
+	case "", "aws:sqs", "aws.s3":
+		var sqsEvent events.SQSEvent
+		if err := json.Unmarshal(body, &sqsEvent); err != nil || len(sqsEvent.Records) == 0 {
+			// Treat the body as a single message, e.g. a bare EventBridge S3 event, so the
+			// per-message response is returned
+			sqsEvent = events.SQSEvent{Records: []events.SQSMessage{{MessageId: "local-0", Body: string(body)}}}
+		}
+		// Held like Invoke does, so AppConfig changes wait for the request
+		dynamicMu.RLock()
+		resp, err = handler(r.Context(), sqsEvent)
+		dynamicMu.RUnlock()
+	default:
+		resp, err = Invoke(r.Context(), body)
+	}
+	if err != nil {
+		writeJSON(w, http.StatusBadGateway, map[string]any{"error": err.Error(), "response": resp})
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// loadFixtures runs every object of the local store through the handler, as if
+// an S3 notification had been received for each of them
+func loadFixtures(ctx context.Context) (sqsResponse, error) {
+	local, ok := store.(*processor.LocalStore)
+	if !ok {
+		return sqsResponse{}, fmt.Errorf("fixture loading requires LOCAL_SOURCE_DIR")
+	}
+
+	objects, err := local.List()
+	if err != nil {
+		return sqsResponse{}, fmt.Errorf("failed to list fixtures: %w", err)
+	}
+
+	sqsEvent := events.SQSEvent{}
+	for i, obj := range objects {
+		msg, err := objectMessage(fmt.Sprintf("fixture-%d", i), obj[0], obj[1], "")
+		if err != nil {
+			return sqsResponse{}, err
+		}
+		sqsEvent.Records = append(sqsEvent.Records, msg)
+	}
+
+	logger.Info("Loading fixtures", "root", local.Root, "object_count", len(objects))
+	dynamicMu.RLock()
+	defer dynamicMu.RUnlock()
+	return handler(ctx, sqsEvent)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
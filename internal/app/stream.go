@@ -0,0 +1,428 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+
+	"github.com/pixelvide/otel-aws-log-parser/cmd/lambda/adapter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/exporter"
+	logpipeline "github.com/pixelvide/otel-aws-log-parser/pkg/pipeline"
+)
+
+// Aggregate modes (AGGREGATE_MODE)
+const (
+	// aggregateLogs exports log records only
+	aggregateLogs = "logs"
+	// aggregateMetrics exports RED metrics per resource group instead of log records
+	aggregateMetrics = "metrics"
+	// aggregateBoth exports log records and RED metrics
+	aggregateBoth = "both"
+)
+
+// streamSink converts entries into resource groups while objects are still being parsed.
+// Once more than maxBuffered records and ALB spans are held, full batches are cut from the
+// groups and exported in the background; Add blocks while maxConcurrent of those exports are in flight,
+// which in turn pauses the object readers. Memory therefore scales with STREAM_BUFFER_RECORDS
+// rather than with the size of the objects in the SQS batch.
+//
+// Records exported early are not withdrawn if a later object in the batch fails, so a retried
+// message can deliver some records twice (at-least-once).
+//
+// The ctx given to Add and Close only gates starting new exports (see launchContext); exports
+// already started run on the sink's own context so they are not cut off mid-request.
+//
+// With ORDERED, the log batches of a resource group are exported one at a time in the order
+// they were cut, so the records of each object arrive in line order. Records of objects read
+// concurrently still interleave within a group.
+type streamSink struct {
+	ctx         context.Context
+	logsExp     exporter.Exporter
+	metrics     exporter.MetricsExporter
+	guard       *converter.CardinalityGuard
+	maxBuffered int
+
+	mu       sync.Mutex
+	groups   map[string]*resourceGroup
+	buffered int
+	added    int
+	sent     int
+	dropped  int
+	err      error
+
+	// pending counts batches cut from the groups whose export has not finished; idle is
+	// signalled whenever it drops
+	pending  int
+	idle     *sync.Cond
+	inflight chan struct{}
+	// tails holds, per resource group, the done channel of its last cut log batch when ordered
+	tails map[string]chan struct{}
+}
+
+// streamBatch is a batch of log records or, when spans is set, of ALB spans cut from a
+// resource group for an early export
+type streamBatch struct {
+	resKey    string
+	processor string
+	logs      converter.ResourceLog
+	spans     *converter.ResourceSpans
+	size      int
+	// after and done order the log batches of a group when ordered: the batch starts once
+	// after is closed and closes done when its export has finished or been dropped
+	after <-chan struct{}
+	done  chan struct{}
+}
+
+// newStreamSink creates a sink whose exports run on ctx; maxBuffered <= 0 buffers everything until Close
+func newStreamSink(ctx context.Context, logsExp exporter.Exporter, metrics exporter.MetricsExporter, maxBuffered int) *streamSink {
+	// Guard resource attributes against runaway cardinality (one resource group per distinct set)
+	guard := converter.NewCardinalityGuard(cardLimit, cardAction)
+	guard.OnExceeded = func(key string, limit int) {
+		logger.Warn("High-cardinality resource attribute detected", "attribute", key, "limit", limit, "action", cardAction)
+	}
+
+	concurrent := maxConcurrent
+	if concurrent < 1 {
+		concurrent = 1
+	}
+
+	s := &streamSink{
+		ctx:         ctx,
+		logsExp:     logsExp,
+		metrics:     metrics,
+		guard:       guard,
+		maxBuffered: maxBuffered,
+		groups:      make(map[string]*resourceGroup),
+		inflight:    make(chan struct{}, concurrent),
+		tails:       make(map[string]chan struct{}),
+	}
+	s.idle = sync.NewCond(&s.mu)
+	return s
+}
+
+// Add converts one entry into its resource group. It returns the first early export error so
+// callers stop parsing once the backend is failing.
+func (s *streamSink) Add(ctx context.Context, entry adapter.LogAdapter) error {
+	return s.AddFrom(ctx, "", entry)
+}
+
+// AddFrom is Add for an entry read by the named S3 log processor, whose records are grouped
+// apart from other processors' and batched and exported with its overrides
+func (s *streamSink) AddFrom(ctx context.Context, proc string, entry adapter.LogAdapter) error {
+	if ctx.Err() != nil {
+		return context.Cause(ctx)
+	}
+
+	resKey := entry.GetResourceKey()
+	if proc != "" {
+		resKey = proc + ":" + resKey
+	}
+	logRecord := entry.ToOTel()
+
+	s.mu.Lock()
+	if s.err != nil {
+		err := s.err
+		s.mu.Unlock()
+		return err
+	}
+
+	group, exists := s.groups[resKey]
+	if !exists {
+		group = &resourceGroup{Group: logpipeline.NewGroup(entry, logpipeline.DefaultScope()), Processor: proc}
+		group.ResourceAttrs = s.guard.Apply(group.ResourceAttrs)
+		if s.metrics != nil {
+			group.Counter = converter.NewRecordCounter()
+			if aggregateMode == aggregateMetrics || aggregateMode == aggregateBoth {
+				group.RED = converter.NewREDAggregator()
+			}
+		}
+		s.groups[resKey] = group
+	}
+
+	if group.Counter != nil {
+		group.Counter.Add(logRecord)
+	}
+	if group.RED != nil {
+		group.RED.Add(logRecord)
+	}
+	if albSpans && tracesExp != nil {
+		if span, ok := converter.ALBSpan(logRecord); ok {
+			group.Spans = append(group.Spans, span)
+			s.buffered++
+		}
+	}
+	s.added++
+	// In metrics mode the record ends in the aggregates and is not exported itself
+	if group.RED == nil || aggregateMode != aggregateMetrics {
+		group.LogRecords = append(group.LogRecords, logRecord)
+		s.buffered++
+	}
+
+	var batches []streamBatch
+	if s.maxBuffered > 0 && s.buffered >= s.maxBuffered {
+		batches = s.cutBatches()
+	}
+	s.mu.Unlock()
+
+	return s.exportAll(ctx, batches)
+}
+
+// Flush exports everything buffered and returns once all records added so far, including
+// batches cut by other callers, have been exported
+func (s *streamSink) Flush(ctx context.Context) error {
+	s.mu.Lock()
+	var batches []streamBatch
+	for resKey, group := range s.groups {
+		if len(group.LogRecords) > 0 || len(group.Spans) > 0 {
+			batches = append(batches, s.cut(resKey, group, len(group.LogRecords))...)
+		}
+	}
+	s.mu.Unlock()
+
+	if err := s.exportAll(ctx, batches); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.waitIdle()
+	return s.err
+}
+
+// cutBatches removes full batches from every group, and the largest group when none is full
+// yet. With priority lanes, priority records are moved to the front so they leave first.
+// Callers must hold s.mu.
+func (s *streamSink) cutBatches() []streamBatch {
+	keys := make([]string, 0, len(s.groups))
+	for resKey := range s.groups {
+		keys = append(keys, resKey)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return s.groups[keys[i]].held() > s.groups[keys[j]].held()
+	})
+
+	var batches []streamBatch
+	for _, resKey := range keys {
+		group := s.groups[resKey]
+		if priorityLanes {
+			high, bulk := converter.PartitionByPriority(group.LogRecords)
+			group.LogRecords = append(high, bulk...)
+		}
+		if size := group.batchSize(); size > 0 && len(group.LogRecords) >= size {
+			batches = append(batches, s.cut(resKey, group, len(group.LogRecords)/size*size)...)
+		}
+	}
+
+	if len(batches) == 0 && len(keys) > 0 {
+		group := s.groups[keys[0]]
+		batches = s.cut(keys[0], group, len(group.LogRecords))
+	}
+	return batches
+}
+
+// cut moves the first n records and all spans of a group into batches (see pipeline.Group.Take).
+// Callers must hold s.mu.
+func (s *streamSink) cut(resKey string, group *resourceGroup, n int) []streamBatch {
+	var batches []streamBatch
+	for _, batch := range splitBatches(group.Take(n), group.batchSize()) {
+		b := streamBatch{resKey: resKey, processor: group.Processor, logs: group.ResourceLog(batch), size: len(batch)}
+		if readOpts.Ordered {
+			b.after, b.done = s.tails[resKey], make(chan struct{})
+			s.tails[resKey] = b.done
+		}
+		batches = append(batches, b)
+	}
+	s.buffered -= n
+
+	for spans := range slices.Chunk(group.Spans, max(maxBatchSize, 1)) {
+		batches = append(batches, streamBatch{resKey: resKey, processor: group.Processor, spans: &converter.ResourceSpans{
+			Resource:   converter.ResourceAttributes{Attributes: group.ResourceAttrs},
+			ScopeSpans: []converter.ScopeSpans{{Scope: group.Scope, Spans: spans}},
+		}, size: len(spans)})
+	}
+	s.buffered -= len(group.Spans)
+	group.Spans = nil
+
+	s.pending += len(batches)
+	return batches
+}
+
+// exportAll starts the batches in order; once one cannot be started, it and the rest are
+// counted as dropped and the sink fails, so Close reports the loss even when nothing is left
+// buffered. An ordered batch is started only after the previous batch of its group finished,
+// without holding an export slot while it waits.
+func (s *streamSink) exportAll(ctx context.Context, batches []streamBatch) error {
+	for i, batch := range batches {
+		if batch.after != nil {
+			select {
+			case <-batch.after:
+			case <-ctx.Done():
+			}
+		}
+		if ctx.Err() == nil {
+			select {
+			case s.inflight <- struct{}{}:
+			case <-ctx.Done():
+			}
+		}
+		if ctx.Err() != nil {
+			s.mu.Lock()
+			for _, dropped := range batches[i:] {
+				if dropped.spans == nil {
+					s.dropped += dropped.size
+				}
+				if dropped.done != nil {
+					close(dropped.done)
+				}
+				s.pending--
+			}
+			if s.err == nil {
+				s.err = fmt.Errorf("streamed batches dropped before export: %w", context.Cause(ctx))
+			}
+			s.idle.Broadcast()
+			s.mu.Unlock()
+			return context.Cause(ctx)
+		}
+		go s.export(batch)
+	}
+	return nil
+}
+
+// export sends one batch and releases its export slot
+func (s *streamSink) export(batch streamBatch) {
+	defer func() { <-s.inflight }()
+	if batch.done != nil {
+		defer close(batch.done)
+	}
+
+	log := logger.With("resource_key", batch.resKey, "lane", "stream")
+	var err error
+	if batch.spans != nil {
+		log.Info("Sending spans", "spans", batch.size)
+		if err = tracesExp.ExportSpans(s.ctx, *batch.spans); err != nil {
+			err = fmt.Errorf("failed to send streamed spans: %w", err)
+		}
+	} else {
+		log.Info("Sending batch", "batch_size", batch.size)
+		if err = s.logsExp.Export(withProcessor(s.ctx, batch.processor), batch.logs); err != nil {
+			err = fmt.Errorf("failed to send streamed batch: %w", err)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pending--
+	s.idle.Broadcast()
+	if err != nil {
+		log.Error("Failed to send batch", "error", err)
+		if s.err == nil {
+			s.err = err
+		}
+		return
+	}
+	if batch.spans == nil {
+		s.sent += batch.size
+	}
+}
+
+// waitIdle blocks until no cut batch is left unexported. Callers must hold s.mu.
+func (s *streamSink) waitIdle() {
+	for s.pending > 0 {
+		s.idle.Wait()
+	}
+}
+
+// Close waits for the early exports, then sends the remaining records through the regular
+// lanes together with the metrics and the remaining spans of every resource group. When ctx
+// ends first, no further batches are started and the number of records left unsent is logged.
+// The groups are taken from the sink before they are sent, so s.mu is not held while
+// exporting.
+func (s *streamSink) Close(ctx context.Context) error {
+	s.mu.Lock()
+	s.waitIdle()
+	added, sent, dropped, err := s.added, s.sent, s.dropped, s.err
+	grouped := s.groups
+	s.groups = make(map[string]*resourceGroup)
+	s.tails = make(map[string]chan struct{})
+	s.buffered = 0
+	s.mu.Unlock()
+
+	if added == 0 {
+		return nil
+	}
+	if err != nil {
+		reportUnsent(added, sent, dropped, err)
+		return err
+	}
+
+	logger.Info("Grouped logs", "resource_group_count", len(grouped), "high_cardinality_attributes", s.guard.Exceeded(), "streamed", sent)
+
+	// With priority lanes, error/blocked records are fully exported before the bulk of the traffic
+	lanes := []map[string]*resourceGroup{grouped}
+	laneNames := []string{"default"}
+	if !holdsRecords(grouped) {
+		lanes, laneNames = nil, nil
+	} else if priorityLanes {
+		high, bulk := splitPriorityLanes(grouped)
+		lanes = []map[string]*resourceGroup{high, bulk}
+		laneNames = []string{"priority", "bulk"}
+	}
+
+	// Metrics and spans derived during grouping are exported alongside the log lanes
+	metricsErr := make(chan error, 1)
+	if s.metrics != nil && ctx.Err() == nil {
+		go func() { metricsErr <- sendMetrics(s.ctx, s.metrics, grouped) }()
+	} else {
+		metricsErr <- nil
+	}
+
+	spansErr := make(chan error, 1)
+	if albSpans && tracesExp != nil && ctx.Err() == nil {
+		go func() { spansErr <- sendSpans(s.ctx, tracesExp, grouped) }()
+	} else {
+		spansErr <- nil
+	}
+
+	totalSent := sent
+	var logsErr error
+	for i, lane := range lanes {
+		n, err := sendLane(ctx, s.ctx, s.logsExp, laneNames[i], lane)
+		totalSent += n
+		if err != nil {
+			logsErr = err
+			break
+		}
+	}
+
+	if err := <-metricsErr; err != nil && logsErr == nil {
+		return err
+	}
+	if err := <-spansErr; err != nil && logsErr == nil {
+		return err
+	}
+	if logsErr != nil {
+		reportUnsent(added, totalSent, dropped, logsErr)
+		return logsErr
+	}
+
+	logger.Info("Successfully sent all logs", "total_sent", totalSent, "resource_groups", len(grouped))
+	return nil
+}
+
+// holdsRecords reports whether any group has log records left to send
+func holdsRecords(grouped map[string]*resourceGroup) bool {
+	for _, group := range grouped {
+		if len(group.LogRecords) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// reportUnsent logs how many of the added records were not exported
+func reportUnsent(added, sent, dropped int, err error) {
+	logger.Warn("Records left unsent", "unsent", added-sent, "sent", sent, "dropped_in_flight", dropped, "cause", err)
+}
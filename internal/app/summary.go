@@ -0,0 +1,213 @@
+package app
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/exporter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/processor"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/tracing"
+)
+
+// invocationSummary is what one invocation did, logged as a single "Invocation summary" event
+// for alerting and capacity planning. Stage durations are summed over concurrent objects and
+// batches, so they can exceed the total.
+type invocationSummary struct {
+	EventSource            string         `json:"event_source"`
+	EventRecords           int            `json:"event_records"`
+	Failures               int            `json:"failures"`
+	ObjectsProcessed       int64          `json:"objects_processed"`
+	BytesRead              int64          `json:"bytes_read"`
+	LinesParsed            int64          `json:"lines_parsed"`
+	ParseFailures          int64          `json:"parse_failures"`
+	RecordsFiltered        int64          `json:"records_filtered"`
+	RecordsSampledOut      int64          `json:"records_sampled_out"`
+	RecordsExported        int64          `json:"records_exported"`
+	Batches                int64          `json:"batches"`
+	FailedBatches          int64          `json:"failed_batches"`
+	RetriedBatches         int64          `json:"retried_batches"`
+	Retries                int64          `json:"retries"`
+	RetryBudgetRemainingMs *int64         `json:"retry_budget_remaining_ms,omitempty"`
+	CircuitOpen            bool           `json:"circuit_open,omitempty"`
+	DurationMs             stageDurations `json:"duration_ms"`
+}
+
+type stageDurations struct {
+	Download int64 `json:"download"`
+	Parse    int64 `json:"parse"`
+	Export   int64 `json:"export"`
+	Total    int64 `json:"total"`
+}
+
+// exportCounts are the log batches handed to the exporter during an invocation
+type exportCounts struct {
+	records, batches, failed, retried, retries int64
+	elapsed                                    time.Duration
+}
+
+// exportStats accumulates exportCounts from concurrent exports and retries
+type exportStats struct {
+	mu     sync.Mutex
+	counts exportCounts
+}
+
+func (s *exportStats) batch(records int, elapsed time.Duration, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.counts.batches++
+	s.counts.elapsed += elapsed
+	if err != nil {
+		s.counts.failed++
+		return
+	}
+	s.counts.records += int64(records)
+}
+
+// retry is the RetryPolicy.OnRetry hook; every batch makes its first retry once
+func (s *exportStats) retry(attempt int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.counts.retries++
+	if attempt == 1 {
+		s.counts.retried++
+	}
+}
+
+func (s *exportStats) take() exportCounts {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := s.counts
+	s.counts = exportCounts{}
+	return c
+}
+
+// readSources holds the read counts of each processor and of the Kinesis and CloudWatch Logs
+// parsers
+type readSources struct {
+	mu    sync.Mutex
+	stats map[string]*processor.ReadStats
+}
+
+// source returns the stats of one source, creating them on first use
+func (r *readSources) source(name string) *processor.ReadStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.stats == nil {
+		r.stats = make(map[string]*processor.ReadStats)
+	}
+	s, ok := r.stats[name]
+	if !ok {
+		s = &processor.ReadStats{}
+		r.stats[name] = s
+	}
+	return s
+}
+
+// take returns and resets the counts of every source that read anything
+func (r *readSources) take() map[string]processor.ReadCounts {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	counts := make(map[string]processor.ReadCounts)
+	for name, s := range r.stats {
+		if c := s.Take(); c != (processor.ReadCounts{}) {
+			counts[name] = c
+		}
+	}
+	return counts
+}
+
+var (
+	readStats   readSources
+	exportTally exportStats
+)
+
+// countedExporter records every batch it passes on in exportTally
+type countedExporter struct {
+	exporter.Exporter
+}
+
+func (e countedExporter) Export(ctx context.Context, logs converter.ResourceLog) error {
+	records := 0
+	for _, scope := range logs.ScopeLogs {
+		records += len(scope.LogRecords)
+	}
+	ctx, span := tracing.Start(ctx, "export batch")
+	span.SetInt("records", int64(records))
+	start := time.Now()
+	err := e.Exporter.Export(ctx, logs)
+	elapsed := time.Since(start)
+	span.SetError(err)
+	span.End()
+	exportTally.batch(records, elapsed, err)
+	observeExport(logs, elapsed, err)
+	return err
+}
+
+// beginInvocation discards counts left over from earlier invocations, starts the invocation's
+// trace and returns the start time
+func beginInvocation(ctx context.Context, source string) (context.Context, time.Time) {
+	readStats.take()
+	exportTally.take()
+	selfMetrics.Take()
+	tracer.Take()
+	dryRun.reset()
+	if retryBudget != nil {
+		retryBudget.Reset()
+	}
+	if exportBreaker != nil {
+		exportBreaker.Reset()
+	}
+	return startTrace(ctx, source), time.Now()
+}
+
+// summarizeInvocation collects the counts since beginInvocation, logs them as one event and
+// flushes the self-telemetry metrics and the trace
+func summarizeInvocation(ctx context.Context, source string, records, failures int, start time.Time) *invocationSummary {
+	sources := readStats.take()
+	var read processor.ReadCounts
+	for _, c := range sources {
+		read.Objects += c.Objects
+		read.Bytes += c.Bytes
+		read.Lines += c.Lines
+		read.ParseFailures += c.ParseFailures
+		read.Entries += c.Entries
+		read.Filtered += c.Filtered
+		read.SampledOut += c.SampledOut
+		read.DownloadTime += c.DownloadTime
+		read.ParseTime += c.ParseTime
+	}
+	exported := exportTally.take()
+	summary := &invocationSummary{
+		EventSource:       source,
+		EventRecords:      records,
+		Failures:          failures,
+		ObjectsProcessed:  read.Objects,
+		BytesRead:         read.Bytes,
+		LinesParsed:       read.Lines,
+		ParseFailures:     read.ParseFailures,
+		RecordsFiltered:   read.Filtered,
+		RecordsSampledOut: read.SampledOut,
+		RecordsExported:   exported.records,
+		Batches:           exported.batches,
+		FailedBatches:     exported.failed,
+		RetriedBatches:    exported.retried,
+		Retries:           exported.retries,
+		DurationMs: stageDurations{
+			Download: read.DownloadTime.Milliseconds(),
+			Parse:    read.ParseTime.Milliseconds(),
+			Export:   exported.elapsed.Milliseconds(),
+			Total:    time.Since(start).Milliseconds(),
+		},
+	}
+	if retryBudget != nil {
+		remaining := retryBudget.Remaining().Milliseconds()
+		summary.RetryBudgetRemainingMs = &remaining
+	}
+	summary.CircuitOpen = exportBreaker != nil && exportBreaker.Open()
+	logger.Info("Invocation summary", "summary", summary)
+	flushSelfMetrics(ctx, summary, sources)
+	finishTrace(ctx, summary)
+	return summary
+}
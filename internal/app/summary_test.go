@@ -0,0 +1,72 @@
+package app
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/exporter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/processor"
+)
+
+func TestHandler_Summary(t *testing.T) {
+	logger = slog.New(slog.NewJSONHandler(io.Discard, nil))
+	maxBatchSize = 10
+	maxConcurrent = 2
+
+	prevRegistry, prevExp := registry, exp
+	defer func() { registry, exp = prevRegistry, prevExp }()
+	registry = processor.NewRegistry()
+	registry.Register(fakeProcessor{})
+
+	// The exporter retries once before accepting each batch
+	retry := exporter.RetryPolicy{OnRetry: exportTally.retry}
+	exp = exporter.ExporterFunc(func(ctx context.Context, logs converter.ResourceLog) error {
+		retry.OnRetry(1)
+		return nil
+	})
+
+	event := events.SQSEvent{Records: []events.SQSMessage{
+		s3Message("m1", "good"), s3Message("m2", "bad"), s3Message("m3", "good"),
+	}}
+	resp, err := handler(context.Background(), event)
+	if err != nil {
+		t.Fatalf("handler() error = %v", err)
+	}
+
+	s := resp.Summary
+	if s == nil {
+		t.Fatal("handler() returned no summary")
+	}
+	if s.EventSource != "sqs" || s.EventRecords != 3 || s.Failures != 1 {
+		t.Errorf("summary = %+v, want 3 sqs records with 1 failure", s)
+	}
+	if s.RecordsExported != 2 || s.Batches == 0 || s.FailedBatches != 0 {
+		t.Errorf("summary = %+v, want 2 records exported without failed batches", s)
+	}
+	if s.RetriedBatches != s.Batches || s.Retries != s.Batches {
+		t.Errorf("summary = %+v, want every batch retried once", s)
+	}
+}
+
+func TestExportStats(t *testing.T) {
+	var s exportStats
+	s.retry(1)
+	s.retry(2)
+	s.retry(1)
+	s.batch(5, 0, nil)
+	s.batch(3, 0, io.ErrUnexpectedEOF)
+
+	got := s.take()
+	want := exportCounts{records: 5, batches: 2, failed: 1, retried: 2, retries: 3}
+	if got != want {
+		t.Errorf("take() = %+v, want %+v", got, want)
+	}
+	if got := s.take(); got != (exportCounts{}) {
+		t.Errorf("take() after reset = %+v, want zero", got)
+	}
+}
@@ -0,0 +1,71 @@
+package app
+
+import (
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudfront"
+	elbv2 "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/processor"
+)
+
+// resourceTags attaches load balancer and target group tags when ELB_TAGS is set
+var resourceTags *processor.ResourceTags
+
+// newResourceTags returns the tag lookup for the tag keys in ELB_TAGS, or nil when none are
+// listed. Clients are created per region on first use.
+func newResourceTags() (*processor.ResourceTags, error) {
+	keys := getEnvList("ELB_TAGS")
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	awsCfg, err := awsConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	var mu sync.Mutex
+	clients := map[string]processor.ELBV2API{}
+	return &processor.ResourceTags{
+		ClientFor: func(region string) processor.ELBV2API {
+			mu.Lock()
+			defer mu.Unlock()
+			client, ok := clients[region]
+			if !ok {
+				client = elbv2.NewFromConfig(awsCfg, func(o *elbv2.Options) { o.Region = region })
+				clients[region] = client
+			}
+			return client
+		},
+		Keys: keys,
+		TTL:  time.Duration(getEnvInt("ELB_TAGS_TTL_SECONDS", int(processor.DefaultTagTTL/time.Second))) * time.Second,
+		OnError: func(arns []string, err error) {
+			logger.Warn("Failed to describe ELB tags", "resources", arns, "error", err)
+		},
+	}, nil
+}
+
+// distributions attaches CloudFront distribution metadata when CLOUDFRONT_METADATA is set
+var distributions *processor.Distributions
+
+// newDistributions returns the distribution lookup, or nil unless CLOUDFRONT_METADATA is set.
+// CLOUDFRONT_TAGS selects the distribution tags attached.
+func newDistributions() (*processor.Distributions, error) {
+	if !getEnvBool("CLOUDFRONT_METADATA", false) {
+		return nil, nil
+	}
+	awsCfg, err := awsConfig()
+	if err != nil {
+		return nil, err
+	}
+	return &processor.Distributions{
+		// CloudFront is a global service served from us-east-1
+		Client:  cloudfront.NewFromConfig(awsCfg, func(o *cloudfront.Options) { o.Region = "us-east-1" }),
+		TagKeys: getEnvList("CLOUDFRONT_TAGS"),
+		TTL:     time.Duration(getEnvInt("CLOUDFRONT_METADATA_TTL_SECONDS", int(processor.DefaultTagTTL/time.Second))) * time.Second,
+		OnError: func(id string, err error) {
+			logger.Warn("Failed to look up CloudFront distribution", "distribution_id", id, "error", err)
+		},
+	}, nil
+}
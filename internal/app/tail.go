@@ -0,0 +1,273 @@
+package app
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/processor"
+)
+
+// RunTail implements the tail command, which follows a local directory that log files are
+// synced to (aws s3 sync, rsync, a shared mount) and exports new files through the function's
+// pipeline as they arrive. Files ending in .log are followed as they grow; compressed ones are
+// exported once. Files already there at start are skipped unless -from-start is given.
+//
+//	bootstrap tail [-endpoint URL|host:port] [-protocol http|grpc] [-header key=value]... [-processor alb] [-interval 5s] [-from-start] DIR
+//
+// The directory is polled rather than watched with inotify, which misses the writes of
+// network and FUSE mounts.
+func RunTail(args []string) error {
+	fs := flag.NewFlagSet("tail", flag.ContinueOnError)
+	var otlp otlpFlags
+	otlp.register(fs)
+	procName := fs.String("processor", "", "processor of every file: alb, nlb, cloudfront or waf (default by path)")
+	interval := fs.Duration("interval", 5*time.Second, "how often to look for new files")
+	fromStart := fs.Bool("from-start", false, "also export the files already in the directory")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("tail takes one directory")
+	}
+	dir, err := filepath.Abs(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if info, err := os.Stat(dir); err != nil {
+		return err
+	} else if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+	if *interval <= 0 {
+		return fmt.Errorf("-interval must be positive")
+	}
+	if err := Configure(); err != nil {
+		return err
+	}
+	if err := otlp.apply(); err != nil {
+		return err
+	}
+
+	t := &tailer{dir: dir, processor: *procName, files: map[string]*tailFile{}}
+	if !*fromStart {
+		if err := t.skipExisting(); err != nil {
+			return err
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	logger.Info("Following directory", "dir", dir, "interval", interval.String())
+	for {
+		if err := t.poll(ctx); err != nil {
+			logger.Error("Failed to scan directory", "dir", dir, "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			logger.Info("Tail stopped")
+			return nil
+		case <-time.After(*interval):
+		}
+	}
+}
+
+// tailFile is what the tailer knows about one file
+type tailFile struct {
+	// size and modTime are from the last scan; a file is exported once they stop changing
+	size    int64
+	modTime time.Time
+	// exported is how many bytes of the file went out; compressed files go whole or not at all
+	exported int64
+	// skip is set for files no processor matches, which are reported once
+	skip bool
+}
+
+type tailer struct {
+	dir       string
+	processor string
+	files     map[string]*tailFile
+}
+
+// tailable reports whether a file name looks like a log file: .log, or compressed .gz
+func tailable(name string) bool {
+	return !strings.HasPrefix(name, ".") && (strings.HasSuffix(name, ".log") || strings.HasSuffix(name, ".gz"))
+}
+
+// scan returns the log files under dir, skipping hidden directories
+func (t *tailer) scan() (map[string]os.FileInfo, error) {
+	found := map[string]os.FileInfo{}
+	err := filepath.WalkDir(t.dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != t.dir && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || !tailable(d.Name()) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		found[path] = info
+		return nil
+	})
+	return found, err
+}
+
+// skipExisting marks the files already in the directory as exported
+func (t *tailer) skipExisting() error {
+	found, err := t.scan()
+	if err != nil {
+		return err
+	}
+	for path, info := range found {
+		t.files[path] = &tailFile{size: info.Size(), modTime: info.ModTime(), exported: info.Size()}
+	}
+	return nil
+}
+
+// poll scans the directory and exports the files, or the new lines of files, that did not
+// change since the previous scan, so files still being written are left for the next one.
+// Failed files are retried on the next poll.
+func (t *tailer) poll(ctx context.Context) error {
+	found, err := t.scan()
+	if err != nil {
+		return err
+	}
+
+	var ready []string
+	ranges := map[string]tailRange{}
+	for path, info := range found {
+		f, ok := t.files[path]
+		if !ok {
+			t.files[path] = &tailFile{size: info.Size(), modTime: info.ModTime()}
+			continue
+		}
+		stable := f.size == info.Size() && f.modTime.Equal(info.ModTime())
+		f.size, f.modTime = info.Size(), info.ModTime()
+		if !stable || f.skip || f.size == f.exported {
+			continue
+		}
+		if f.size < f.exported {
+			logger.Warn("File shrank, exporting it again from the start", "file", path)
+			f.exported = 0
+		}
+		if f.exported > 0 && !strings.HasSuffix(path, ".log") {
+			logger.Warn("Compressed file changed after it was exported, skipping", "file", path)
+			f.exported = f.size
+			continue
+		}
+		bucket, key := localObject(path)
+		if t.processor != "" {
+			if err := registry.Force(bucket, t.processor); err != nil {
+				return fmt.Errorf("-processor: %w", err)
+			}
+		} else if registry.Find(bucket, key) == nil {
+			logger.Warn("No processor matches file, skipping it, pick one with -processor", "file", path)
+			f.skip = true
+			continue
+		}
+		ready = append(ready, path)
+		ranges[path] = tailRange{from: f.exported, to: f.size}
+	}
+	// A deleted file is forgotten, so a new file by the same name is exported
+	for path := range t.files {
+		if _, ok := found[path]; !ok {
+			delete(t.files, path)
+		}
+	}
+	if len(ready) == 0 {
+		return nil
+	}
+
+	event := events.SQSEvent{}
+	for i, path := range ready {
+		bucket, key := localObject(path)
+		msg, err := objectMessage(strconv.Itoa(i), bucket, key, "")
+		if err != nil {
+			return err
+		}
+		event.Records = append(event.Records, msg)
+	}
+	store = &tailStore{ranges: ranges}
+
+	// Like the daemon, a batch in flight is finished on shutdown
+	logger.Info("Exporting files", "files", len(ready))
+	resp, err := handler(context.WithoutCancel(ctx), event)
+	if err != nil {
+		return err
+	}
+	failed := map[string]bool{}
+	for _, f := range resp.BatchItemFailures {
+		i, _ := strconv.Atoi(f.ItemIdentifier)
+		failed[ready[i]] = true
+		logger.Error("Failed to export file, retrying on the next poll", "file", ready[i])
+	}
+	for _, path := range ready {
+		if !failed[path] {
+			t.files[path].exported = t.files[path].size
+		}
+	}
+	return nil
+}
+
+// localObject splits an absolute path into the bucket and key a store rooted at / reads it by
+func localObject(path string) (bucket, key string) {
+	bucket, key, _ = strings.Cut(strings.TrimPrefix(filepath.ToSlash(path), "/"), "/")
+	return bucket, key
+}
+
+// tailRange is the part of a file a batch exports: the bytes added since the last export, up
+// to the size the file was scanned at, so lines written meanwhile are left for the next poll
+type tailRange struct {
+	from, to int64
+}
+
+// tailStore reads local files like a LocalStore rooted at /, limited to their ranges
+type tailStore struct {
+	// ranges are keyed by path and only read during a batch
+	ranges map[string]tailRange
+}
+
+func (s *tailStore) GetObject(ctx context.Context, bucket, key string) (*processor.Object, error) {
+	path := filepath.FromSlash("/" + bucket + "/" + key)
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open local object: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to stat local object: %w", err)
+	}
+	r, ok := s.ranges[path]
+	if !ok {
+		r.to = info.Size()
+	}
+	if _, err := f.Seek(r.from, io.SeekStart); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to seek local object: %w", err)
+	}
+	body := struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(f, r.to-r.from), f}
+	return &processor.Object{Body: body, LastModified: info.ModTime(), Size: r.to - r.from}, nil
+}
@@ -0,0 +1,107 @@
+package app
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/pixelvide/otel-aws-log-parser/cmd/lambda/adapter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/exporter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/processor"
+)
+
+// lineProcessor records the lines it reads from the store
+type lineProcessor struct {
+	mu    *sync.Mutex
+	lines *[]string
+}
+
+func (lineProcessor) Name() string                    { return "lines" }
+func (lineProcessor) Matches(bucket, key string) bool { return strings.HasSuffix(key, ".log") }
+func (p lineProcessor) Process(ctx context.Context, logger *slog.Logger, store processor.ObjectStore, bucket, key string) ([]adapter.LogAdapter, error) {
+	obj, err := store.GetObject(ctx, bucket, key)
+	if err != nil {
+		return nil, err
+	}
+	defer obj.Body.Close()
+	data, err := io.ReadAll(obj.Body)
+	if err != nil {
+		return nil, err
+	}
+	var entries []adapter.LogAdapter
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, line := range strings.Fields(string(data)) {
+		*p.lines = append(*p.lines, line)
+		entries = append(entries, fakeEntry{9})
+	}
+	return entries, nil
+}
+
+func TestTailer_Poll(t *testing.T) {
+	logger = slog.New(slog.NewJSONHandler(io.Discard, nil))
+	maxBatchSize = 10
+	maxConcurrent = 2
+
+	prevRegistry, prevExp, prevStore := registry, exp, store
+	t.Cleanup(func() { registry, exp, store = prevRegistry, prevExp, prevStore })
+	var mu sync.Mutex
+	var lines []string
+	registry = processor.NewRegistry()
+	registry.Register(lineProcessor{mu: &mu, lines: &lines})
+	exp = exporter.ExporterFunc(func(ctx context.Context, logs converter.ResourceLog) error { return nil })
+
+	dir := t.TempDir()
+	tl := &tailer{dir: dir, files: map[string]*tailFile{}}
+	write := func(name, content string, flags int) {
+		f, err := os.OpenFile(filepath.Join(dir, name), flags|os.O_WRONLY|os.O_CREATE, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		if _, err := f.WriteString(content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	poll := func(want ...string) {
+		t.Helper()
+		lines = nil
+		if err := tl.poll(context.Background()); err != nil {
+			t.Fatalf("poll() error = %v", err)
+		}
+		if strings.Join(lines, ",") != strings.Join(want, ",") {
+			t.Errorf("exported lines %v, want %v", lines, want)
+		}
+	}
+
+	write("old.log", "old\n", os.O_TRUNC)
+	if err := tl.skipExisting(); err != nil {
+		t.Fatal(err)
+	}
+
+	write("alb.log", "a\nb\n", os.O_TRUNC)
+	write("notes.txt", "ignored\n", os.O_TRUNC)
+	// A new file is exported once it stopped changing, the existing one not at all
+	poll()
+	poll("a", "b")
+	poll()
+
+	// Appended lines are exported on their own once the file settles again
+	write("alb.log", "c\n", os.O_APPEND)
+	poll()
+	poll("c")
+
+	// Files no processor matches are skipped
+	write("cloudfront.gz", "x\n", os.O_TRUNC)
+	poll()
+	poll()
+	if !tl.files[filepath.Join(dir, "cloudfront.gz")].skip {
+		t.Error("unmatched file was not marked skipped")
+	}
+}
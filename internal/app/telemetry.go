@@ -0,0 +1,111 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/processor"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/telemetry"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/version"
+)
+
+var (
+	// selfMetrics records the function's own processing metrics; nil unless SELF_METRICS is set
+	selfMetrics *telemetry.Recorder
+	// flushSelf sends what selfMetrics recorded during an invocation
+	flushSelf func(ctx context.Context, snap telemetry.Snapshot) error
+)
+
+// newSelfMetrics sets up SELF_METRICS: "emf" writes CloudWatch embedded metric format lines to
+// stdout, "otlp" exports OTLP metrics to the metrics endpoint (see newMetricsExporter)
+func newSelfMetrics() (*telemetry.Recorder, func(context.Context, telemetry.Snapshot) error, error) {
+	switch mode := getEnv("SELF_METRICS", "off"); mode {
+	case "off":
+		return nil, nil, nil
+	case "emf":
+		emf := &telemetry.EMF{Writer: os.Stdout, Namespace: getEnv("SELF_METRICS_NAMESPACE", "OtelAwsLogParser")}
+		if name := getEnv("AWS_LAMBDA_FUNCTION_NAME", ""); name != "" {
+			emf.Dimensions = []telemetry.Attr{{Key: "FunctionName", Value: name}}
+		}
+		return telemetry.NewRecorder(), func(_ context.Context, snap telemetry.Snapshot) error {
+			return emf.Write(snap)
+		}, nil
+	case "otlp":
+		metricsExp, err := newMetricsExporter()
+		if err != nil {
+			return nil, nil, err
+		}
+		resource := selfResource()
+		return telemetry.NewRecorder(), func(ctx context.Context, snap telemetry.Snapshot) error {
+			return metricsExp.ExportMetrics(ctx, buildResourceMetric(resource, snap.Metrics()))
+		}, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown SELF_METRICS %q, expected off, emf or otlp", mode)
+	}
+}
+
+// selfResource identifies the function itself as the source of its OTLP metrics
+func selfResource() []converter.OTelAttribute {
+	attrs := []converter.OTelAttribute{
+		{Key: "service.name", Value: converter.OTelAnyValue{StringValue: aws.String("otel-aws-log-parser")}},
+		{Key: "service.version", Value: converter.OTelAnyValue{StringValue: aws.String(version.Info().Version)}},
+	}
+	for _, env := range []struct{ key, attr string }{
+		{"AWS_LAMBDA_FUNCTION_NAME", "faas.name"},
+		{"AWS_REGION", "cloud.region"},
+	} {
+		if v := getEnv(env.key, ""); v != "" {
+			attrs = append(attrs, converter.OTelAttribute{Key: env.attr, Value: converter.OTelAnyValue{StringValue: aws.String(v)}})
+		}
+	}
+	return attrs
+}
+
+// observeExport records the latency and output size of one exported batch. The size is that
+// of the batch as OTLP/JSON, whatever the exporter's wire format.
+func observeExport(logs converter.ResourceLog, elapsed time.Duration, err error) {
+	if selfMetrics == nil {
+		return
+	}
+	outcome := "ok"
+	if err != nil {
+		outcome = "error"
+	}
+	selfMetrics.Observe("logparser.export.duration", telemetry.UnitMilliseconds, float64(elapsed.Microseconds())/1000, telemetry.Attr{Key: "outcome", Value: outcome})
+	if err == nil {
+		if body, err := json.Marshal(logs); err == nil {
+			selfMetrics.Add("logparser.bytes_out", telemetry.UnitBytes, int64(len(body)))
+		}
+	}
+}
+
+// flushSelfMetrics adds the invocation's counts to selfMetrics and sends everything recorded
+// since the last flush. Failures are only logged; they never fail the invocation.
+func flushSelfMetrics(ctx context.Context, summary *invocationSummary, sources map[string]processor.ReadCounts) {
+	if selfMetrics == nil {
+		return
+	}
+	for name, c := range sources {
+		attr := telemetry.Attr{Key: "processor", Value: name}
+		selfMetrics.Add("logparser.objects", telemetry.UnitCount, c.Objects, attr)
+		selfMetrics.Add("logparser.bytes_in", telemetry.UnitBytes, c.Bytes, attr)
+		selfMetrics.Add("logparser.lines", telemetry.UnitCount, c.Lines, attr)
+		selfMetrics.Add("logparser.parse_failures", telemetry.UnitCount, c.ParseFailures, attr)
+		selfMetrics.Add("logparser.records.filtered", telemetry.UnitCount, c.Filtered, attr)
+		selfMetrics.Add("logparser.records.sampled_out", telemetry.UnitCount, c.SampledOut, attr)
+	}
+	selfMetrics.Add("logparser.records.exported", telemetry.UnitCount, summary.RecordsExported)
+	selfMetrics.Add("logparser.export.failed_batches", telemetry.UnitCount, summary.FailedBatches)
+	selfMetrics.Add("logparser.export.retries", telemetry.UnitCount, summary.Retries)
+	selfMetrics.Observe("logparser.invocation.duration", telemetry.UnitMilliseconds, float64(summary.DurationMs.Total), telemetry.Attr{Key: "event_source", Value: summary.EventSource})
+
+	if err := flushSelf(ctx, selfMetrics.Take()); err != nil {
+		logger.Warn("Failed to flush self metrics", "error", err)
+	}
+}
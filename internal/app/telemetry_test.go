@@ -0,0 +1,126 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"testing"
+	"time"
+
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"google.golang.org/grpc"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+	logpipeline "github.com/pixelvide/otel-aws-log-parser/pkg/pipeline"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/processor"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/telemetry"
+)
+
+func TestFlushSelfMetrics(t *testing.T) {
+	logger = slog.New(slog.NewJSONHandler(io.Discard, nil))
+	var flushed []telemetry.Snapshot
+	selfMetrics = telemetry.NewRecorder()
+	flushSelf = func(ctx context.Context, snap telemetry.Snapshot) error {
+		flushed = append(flushed, snap)
+		return errors.New("collector down") // only logged
+	}
+	defer func() { selfMetrics, flushSelf = nil, nil }()
+
+	group := logpipeline.Group{Scope: logpipeline.DefaultScope()}
+	batch := group.ResourceLog([]converter.OTelLogRecord{fakeEntry{9}.ToOTel()})
+	observeExport(batch, 30*time.Millisecond, nil)
+	observeExport(batch, 5*time.Millisecond, errors.New("timeout"))
+
+	summary := &invocationSummary{EventSource: "sqs", RecordsExported: 2, FailedBatches: 1, Retries: 3, DurationMs: stageDurations{Total: 1200}}
+	flushSelfMetrics(context.Background(), summary, map[string]processor.ReadCounts{
+		"alb": {Objects: 1, Bytes: 512, Lines: 10, ParseFailures: 1},
+	})
+
+	if len(flushed) != 1 {
+		t.Fatalf("flushed %d snapshots, want 1", len(flushed))
+	}
+	counters := make(map[string]int64)
+	for _, c := range flushed[0].Counters {
+		name := c.Name
+		for _, a := range c.Attrs {
+			name += "|" + a.Value
+		}
+		counters[name] = c.Value
+	}
+	for name, want := range map[string]int64{
+		"logparser.lines|alb":             10,
+		"logparser.parse_failures|alb":    1,
+		"logparser.bytes_in|alb":          512,
+		"logparser.records.exported":      2,
+		"logparser.export.failed_batches": 1,
+		"logparser.export.retries":        3,
+	} {
+		if counters[name] != want {
+			t.Errorf("%s = %d, want %d", name, counters[name], want)
+		}
+	}
+	if counters["logparser.bytes_out"] == 0 {
+		t.Error("logparser.bytes_out not recorded for the successful batch")
+	}
+
+	histograms := make(map[string]int64)
+	for _, h := range flushed[0].Histograms {
+		histograms[h.Name+"|"+h.Attrs[0].Value] = h.Count
+	}
+	if histograms["logparser.export.duration|ok"] != 1 || histograms["logparser.export.duration|error"] != 1 || histograms["logparser.invocation.duration|sqs"] != 1 {
+		t.Errorf("histograms = %v", histograms)
+	}
+}
+
+type fakeMetricsService struct {
+	colmetricspb.UnimplementedMetricsServiceServer
+	received chan *colmetricspb.ExportMetricsServiceRequest
+}
+
+func (s *fakeMetricsService) Export(ctx context.Context, req *colmetricspb.ExportMetricsServiceRequest) (*colmetricspb.ExportMetricsServiceResponse, error) {
+	s.received <- req
+	return &colmetricspb.ExportMetricsServiceResponse{}, nil
+}
+
+// grpcMetricsCollector serves the OTLP/gRPC metrics service and points OTLP_PROTOCOL=grpc and
+// OTLP_GRPC_ENDPOINT at it
+func grpcMetricsCollector(t *testing.T) chan *colmetricspb.ExportMetricsServiceRequest {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	server := grpc.NewServer()
+	fake := &fakeMetricsService{received: make(chan *colmetricspb.ExportMetricsServiceRequest, 1)}
+	colmetricspb.RegisterMetricsServiceServer(server, fake)
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	t.Setenv("OTLP_PROTOCOL", "grpc")
+	t.Setenv("OTLP_GRPC_ENDPOINT", lis.Addr().String())
+	t.Setenv("OTLP_GRPC_INSECURE", "true")
+	t.Setenv("SIGNOZ_OTLP_ENDPOINT", "http://127.0.0.1:1/v1/logs")
+	return fake.received
+}
+
+func TestNewSelfMetrics_OTLPOverGRPC(t *testing.T) {
+	logger = slog.New(slog.NewJSONHandler(io.Discard, nil))
+	received := grpcMetricsCollector(t)
+	t.Setenv("SELF_METRICS", "otlp")
+
+	recorder, flush, err := newSelfMetrics()
+	if err != nil {
+		t.Fatalf("newSelfMetrics() error = %v", err)
+	}
+	recorder.Add("logparser.records.exported", "{record}", 2)
+	if err := flush(context.Background(), recorder.Take()); err != nil {
+		t.Fatalf("flush() error = %v", err)
+	}
+
+	req := <-received
+	if got := req.ResourceMetrics[0].ScopeMetrics[0].Metrics[0].Name; got != "logparser.records.exported" {
+		t.Errorf("metric = %s, want logparser.records.exported", got)
+	}
+}
@@ -0,0 +1,72 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/reputation"
+)
+
+// threatFeed flags client addresses on the reputation lists in THREAT_IP_LISTS
+var threatFeed *reputation.Feed
+
+// loadThreatFeed loads the reputation lists in THREAT_IP_LISTS, each a local path or an
+// s3://bucket/key URI, optionally prefixed with its label as label=location; unlabelled lists
+// are labelled after their file name. The lists are reloaded every THREAT_IP_REFRESH_SECONDS.
+// None listed means no reputation checks.
+func loadThreatFeed() (*reputation.Feed, error) {
+	locations := getEnvList("THREAT_IP_LISTS")
+	if len(locations) == 0 {
+		return nil, nil
+	}
+	feed := &reputation.Feed{
+		Load: func(ctx context.Context) (*reputation.List, error) {
+			list := reputation.NewList()
+			for _, location := range locations {
+				label, loc, ok := strings.Cut(location, "=")
+				if !ok {
+					loc = location
+					label = strings.TrimSuffix(path.Base(loc), path.Ext(loc))
+				}
+				data, err := readLocation(loc)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %w", loc, err)
+				}
+				entries, err := list.Add(strings.ToLower(label), data)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %w", loc, err)
+				}
+				logger.Debug("Loaded IP reputation list", "location", loc, "label", label, "entries", entries)
+			}
+			return list, nil
+		},
+		Refresh: time.Duration(getEnvInt("THREAT_IP_REFRESH_SECONDS", 3600)) * time.Second,
+		OnError: func(err error) {
+			logger.Warn("Failed to refresh IP reputation lists, keeping the previous ones", "error", err)
+		},
+	}
+	if err := feed.Start(context.Background()); err != nil {
+		return nil, err
+	}
+	return feed, nil
+}
+
+// sourceThreats returns the feed for sources listed in THREAT_IP_SOURCES (default waf), by the
+// lowercase environment prefix of their processor, e.g. waf, kinesis or cloudwatch_logs
+func sourceThreats(prefix string) *reputation.Feed {
+	if threatFeed == nil {
+		return nil
+	}
+	sources := getEnvList("THREAT_IP_SOURCES")
+	if len(sources) == 0 {
+		sources = []string{"waf"}
+	}
+	if !slices.Contains(sources, strings.ToLower(prefix)) {
+		return nil
+	}
+	return threatFeed
+}
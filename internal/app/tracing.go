@@ -0,0 +1,60 @@
+package app
+
+import (
+	"context"
+
+	"github.com/aws/aws-lambda-go/lambdacontext"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/exporter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/tracing"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/version"
+)
+
+var (
+	// tracer records spans of each invocation when TRACES_ENABLED is set: one root span, a span
+	// per object with its get, parse and convert stages, and one per export batch
+	tracer    *tracing.Tracer
+	tracesExp exporter.TracesExporter
+	// albSpans synthesizes a server span from every ALB record with a trace ID (ALB_SPANS),
+	// exported to tracesExp with the invocation's other signals
+	albSpans bool
+)
+
+// startTrace starts the root span of an invocation
+func startTrace(ctx context.Context, source string) context.Context {
+	ctx, span := tracer.Root(ctx, source+" invocation")
+	span.SetString("faas.trigger", source)
+	if lc, ok := lambdacontext.FromContext(ctx); ok {
+		span.SetString("faas.invocation_id", lc.AwsRequestID)
+	}
+	return ctx
+}
+
+// finishTrace ends the root span with the invocation's summary and exports the spans. A failed
+// export is only logged.
+func finishTrace(ctx context.Context, summary *invocationSummary) {
+	root := tracing.FromContext(ctx)
+	if root == nil {
+		return
+	}
+	root.SetInt("objects", summary.ObjectsProcessed)
+	root.SetInt("records.exported", summary.RecordsExported)
+	root.SetInt("failures", int64(summary.Failures))
+	root.End()
+
+	spans := tracer.Take()
+	if len(spans) == 0 || tracesExp == nil {
+		return
+	}
+	rs := converter.ResourceSpans{
+		Resource: converter.ResourceAttributes{Attributes: selfResource()},
+		ScopeSpans: []converter.ScopeSpans{{
+			Scope: converter.Scope{Name: "otel-aws-log-parser", Version: version.Info().String()},
+			Spans: spans,
+		}},
+	}
+	if err := tracesExp.ExportSpans(ctx, rs); err != nil {
+		logger.Warn("Failed to export traces", "error", err, "spans", len(spans))
+	}
+}
@@ -0,0 +1,31 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// newWorkerGroup returns a group running at most maxConcurrent goroutines at once. Its context
+// is cancelled by the first goroutine that returns an error, so the remaining work stops early.
+func newWorkerGroup(ctx context.Context) (*errgroup.Group, context.Context) {
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(maxConcurrent)
+	return g, ctx
+}
+
+// recovered runs fn and turns a panic into an error, logged with its stack, so that one bad
+// record fails its own unit of work instead of crashing the invocation
+func recovered(task string, fn func() error) func() error {
+	return func() (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				logger.Error("Recovered from panic", "task", task, "panic", r, "stack", string(debug.Stack()))
+				err = fmt.Errorf("%s: panic: %v", task, r)
+			}
+		}()
+		return fn()
+	}
+}
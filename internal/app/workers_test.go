@@ -0,0 +1,38 @@
+package app
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestRecovered(t *testing.T) {
+	logger = slog.New(slog.NewJSONHandler(io.Discard, nil))
+	errFailed := errors.New("failed")
+
+	tests := []struct {
+		name    string
+		fn      func() error
+		wantErr string
+	}{
+		{"success", func() error { return nil }, ""},
+		{"error", func() error { return errFailed }, "failed"},
+		{"panic", func() error { panic("bad record") }, "export: panic: bad record"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := recovered("export", tt.fn)()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("recovered() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("recovered() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
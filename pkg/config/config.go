@@ -0,0 +1,144 @@
+package config
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// Exporters are the EXPORTER kinds
+var Exporters = []string{"otlp", "firehose", "loki", "opensearch", "kafka", "s3"}
+
+// Processors are the S3 log processors PROCESSORS_ENABLED can enable
+var Processors = []string{"alb", "nlb", "cloudfront", "waf"}
+
+// ProcessorOverrides are the settings one S3 log processor can override, read from variables
+// prefixed with its name (ALB_MAX_BATCH_SIZE, WAF_OTLP_ENDPOINT)
+type ProcessorOverrides struct {
+	// MaxBatchSize is the batch size of its records, defaulting to MAX_BATCH_SIZE
+	MaxBatchSize int
+	// MaxConcurrent is the number of its parse workers per object, defaulting to MAX_CONCURRENT
+	MaxConcurrent int
+	// OTLPEndpoint, when set, receives its records instead of the OTLP exporter's endpoint
+	OTLPEndpoint string
+}
+
+// Config holds the core export settings, loaded and checked together at cold start. Feature
+// settings are read where they are used, through the same Env.
+type Config struct {
+	// Exporters are the sinks of EXPORTER, e.g. ["otlp", "s3"]
+	Exporters []string
+	// OTLPProtocol is "http" or "grpc"
+	OTLPProtocol string
+	// LogsEndpoint is the OTLP/HTTP logs endpoint (SIGNOZ_OTLP_ENDPOINT)
+	LogsEndpoint string
+	// GRPCEndpoint is the OTLP/gRPC collector address (OTLP_GRPC_ENDPOINT)
+	GRPCEndpoint string
+	// Processors are the S3 log processors enabled by PROCESSORS_ENABLED, all by default
+	Processors []string
+	// Overrides holds the overrides of every enabled processor, keyed by name
+	Overrides      map[string]ProcessorOverrides
+	MaxBatchSize   int
+	MaxConcurrent  int
+	MaxRetries     int
+	StreamBuffer   int
+	DeadlineMargin time.Duration
+}
+
+// Load reads the core settings and validates them: value syntax and ranges, settings the
+// chosen exporters require, and options that cannot be combined. It returns the problems
+// recorded in env so far, so a failed Load lists everything wrong at once.
+func Load(env *Env) (Config, error) {
+	cfg := Config{
+		Exporters:      env.List("EXPORTER"),
+		OTLPProtocol:   env.OneOf("OTLP_PROTOCOL", "http", "http", "grpc"),
+		MaxBatchSize:   env.IntRange("MAX_BATCH_SIZE", 500, 1, 100000),
+		MaxConcurrent:  env.IntRange("MAX_CONCURRENT", 10, 1, 1000),
+		MaxRetries:     env.IntRange("MAX_RETRIES", 3, 0, 100),
+		StreamBuffer:   env.IntRange("STREAM_BUFFER_RECORDS", 20000, 0, 10000000),
+		DeadlineMargin: time.Duration(env.IntRange("DEADLINE_SAFETY_MARGIN_MS", 5000, 0, 900000)) * time.Millisecond,
+	}
+	if len(cfg.Exporters) == 0 {
+		cfg.Exporters = []string{"otlp"}
+	}
+	if cfg.Processors = env.List("PROCESSORS_ENABLED"); len(cfg.Processors) == 0 {
+		cfg.Processors = Processors
+	}
+	cfg.Overrides = make(map[string]ProcessorOverrides, len(cfg.Processors))
+	for _, name := range cfg.Processors {
+		if !slices.Contains(Processors, name) {
+			env.Errorf("PROCESSORS_ENABLED", "unknown processor %q, want one of %s", name, strings.Join(Processors, ", "))
+			continue
+		}
+		prefix := strings.ToUpper(name) + "_"
+		overrides := ProcessorOverrides{
+			MaxBatchSize:  env.IntRange(prefix+"MAX_BATCH_SIZE", cfg.MaxBatchSize, 1, 100000),
+			MaxConcurrent: env.IntRange(prefix+"MAX_CONCURRENT", cfg.MaxConcurrent, 1, 1000),
+		}
+		if cfg.OTLPProtocol == "grpc" {
+			overrides.OTLPEndpoint = env.HostPort(prefix+"OTLP_ENDPOINT", "")
+		} else {
+			overrides.OTLPEndpoint = env.URL(prefix+"OTLP_ENDPOINT", "")
+		}
+		cfg.Overrides[name] = overrides
+	}
+	kinds := append(slices.Clone(cfg.Exporters), env.List("PIPELINE_DIVERT_EXPORTER")...)
+	for _, kind := range kinds {
+		if !slices.Contains(Exporters, kind) {
+			env.Errorf("EXPORTER", "unknown exporter %q, want one of %s", kind, strings.Join(Exporters, ", "))
+		}
+	}
+	for _, kind := range env.List("EXPORTER_OPTIONAL") {
+		if !slices.Contains(kinds, kind) {
+			env.Errorf("EXPORTER_OPTIONAL", "%q is not a configured exporter", kind)
+		}
+	}
+
+	if slices.Contains(kinds, "otlp") {
+		if cfg.OTLPProtocol == "grpc" {
+			cfg.GRPCEndpoint = env.HostPort("OTLP_GRPC_ENDPOINT", "localhost:4317")
+			// gRPC servers answer more frequent pings with GOAWAY too_many_pings
+			if keepalive := env.Int("OTLP_GRPC_KEEPALIVE_SECONDS", 0); keepalive != 0 && keepalive < 300 {
+				env.Errorf("OTLP_GRPC_KEEPALIVE_SECONDS", "%d must be 0 (off) or at least 300", keepalive)
+			}
+		} else {
+			env.OneOf("OTLP_ENCODING", "json", "json", "protobuf")
+		}
+		env.OneOf("OTLP_COMPRESSION", "none", "none", "gzip")
+		env.OneOf("OTLP_PARTIAL_SUCCESS", "log", "log", "retry", "fail")
+		if env.Bool("OTLP_SIGV4", false) && env.IsSet("OTLP_OAUTH2_TOKEN_URL") {
+			env.Errorf("OTLP_SIGV4", "cannot be combined with OTLP_OAUTH2_TOKEN_URL, both sign the Authorization header")
+		}
+		env.Exclusive("OTLP_ROUTES", "OTLP_ROUTES_FILE")
+	}
+	// The metrics and traces exporters derive their endpoints from the logs endpoint
+	cfg.LogsEndpoint = env.URL("SIGNOZ_OTLP_ENDPOINT", "http://localhost:4318/v1/logs")
+	env.URL("OTLP_METRICS_ENDPOINT", "")
+	env.URL("OTLP_TRACES_ENDPOINT", "")
+	env.URL("OTLP_OAUTH2_TOKEN_URL", "")
+
+	if slices.Contains(kinds, "firehose") {
+		env.Require("FIREHOSE_DELIVERY_STREAM", "by the firehose exporter")
+	}
+	if slices.Contains(kinds, "opensearch") {
+		env.Require("OPENSEARCH_ENDPOINT", "by the opensearch exporter")
+		env.URL("OPENSEARCH_ENDPOINT", "")
+	}
+	if slices.Contains(kinds, "loki") {
+		env.URL("LOKI_ENDPOINT", "")
+	}
+	if slices.Contains(kinds, "kafka") {
+		env.Require("KAFKA_BROKERS", "by the kafka exporter")
+	}
+
+	if env.IsSet("APPCONFIG_APPLICATION") {
+		env.Require("APPCONFIG_ENVIRONMENT", "with APPCONFIG_APPLICATION")
+		env.Require("APPCONFIG_PROFILE", "with APPCONFIG_APPLICATION")
+		// AppConfig rejects polls more frequent than every 15 seconds
+		env.IntRange("APPCONFIG_POLL_SECONDS", 60, 15, 86400)
+	}
+
+	env.Exclusive("DLQ_S3_BUCKET", "DLQ_SQS_URL")
+	env.Exclusive("BASIC_AUTH_PASSWORD", "BASIC_AUTH_PASSWORD_ARN")
+	return cfg, env.Err()
+}
@@ -0,0 +1,147 @@
+package config
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadDefaults(t *testing.T) {
+	cfg, err := Load(mapEnv(nil))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !slices.Equal(cfg.Exporters, []string{"otlp"}) || cfg.OTLPProtocol != "http" || cfg.LogsEndpoint != "http://localhost:4318/v1/logs" {
+		t.Errorf("Load() = %+v, want the otlp/http defaults", cfg)
+	}
+	if cfg.MaxBatchSize != 500 || cfg.MaxConcurrent != 10 || cfg.MaxRetries != 3 || cfg.DeadlineMargin != 5*time.Second {
+		t.Errorf("Load() = %+v, want the default limits", cfg)
+	}
+}
+
+func TestLoadProcessorOverrides(t *testing.T) {
+	cfg, err := Load(mapEnv(map[string]string{
+		"PROCESSORS_ENABLED": "alb,waf",
+		"MAX_BATCH_SIZE":     "200",
+		"ALB_MAX_BATCH_SIZE": "1000",
+		"WAF_OTLP_ENDPOINT":  "https://waf-collector.example.com/v1/logs",
+		"NLB_MAX_BATCH_SIZE": "not read",
+	}))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	want := map[string]ProcessorOverrides{
+		"alb": {MaxBatchSize: 1000, MaxConcurrent: 10},
+		"waf": {MaxBatchSize: 200, MaxConcurrent: 10, OTLPEndpoint: "https://waf-collector.example.com/v1/logs"},
+	}
+	if len(cfg.Overrides) != len(want) {
+		t.Errorf("Overrides = %+v, want %+v", cfg.Overrides, want)
+	}
+	for name, overrides := range want {
+		if cfg.Overrides[name] != overrides {
+			t.Errorf("Overrides[%s] = %+v, want %+v", name, cfg.Overrides[name], overrides)
+		}
+	}
+}
+
+func TestLoadValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		vars    map[string]string
+		wantErr []string
+	}{
+		{
+			name: "valid grpc",
+			vars: map[string]string{"EXPORTER": "otlp, s3", "OTLP_PROTOCOL": "grpc", "OTLP_GRPC_ENDPOINT": "collector:4317", "MAX_RETRIES": "0"},
+		},
+		{
+			name:    "unknown exporter",
+			vars:    map[string]string{"EXPORTER": "otlp,splunk"},
+			wantErr: []string{`EXPORTER: unknown exporter "splunk"`},
+		},
+		{
+			name:    "unknown processor",
+			vars:    map[string]string{"PROCESSORS_ENABLED": "alb,vpc"},
+			wantErr: []string{`PROCESSORS_ENABLED: unknown processor "vpc"`},
+		},
+		{
+			name:    "processor overrides",
+			vars:    map[string]string{"ALB_MAX_BATCH_SIZE": "0", "WAF_OTLP_ENDPOINT": "waf-collector:4318"},
+			wantErr: []string{"ALB_MAX_BATCH_SIZE: 0 is out of range", "WAF_OTLP_ENDPOINT: "},
+		},
+		{
+			name:    "optional exporter not configured",
+			vars:    map[string]string{"EXPORTER_OPTIONAL": "s3"},
+			wantErr: []string{`EXPORTER_OPTIONAL: "s3" is not a configured exporter`},
+		},
+		{
+			name:    "bad endpoint and batch size reported together",
+			vars:    map[string]string{"SIGNOZ_OTLP_ENDPOINT": "localhost:4318", "MAX_BATCH_SIZE": "0"},
+			wantErr: []string{"MAX_BATCH_SIZE: 0 is out of range", "SIGNOZ_OTLP_ENDPOINT: "},
+		},
+		{
+			name:    "grpc keepalive below the server minimum",
+			vars:    map[string]string{"OTLP_PROTOCOL": "grpc", "OTLP_GRPC_KEEPALIVE_SECONDS": "30"},
+			wantErr: []string{"OTLP_GRPC_KEEPALIVE_SECONDS: 30 must be 0 (off) or at least 300"},
+		},
+		{
+			name:    "grpc endpoint without port",
+			vars:    map[string]string{"OTLP_PROTOCOL": "grpc", "OTLP_GRPC_ENDPOINT": "collector"},
+			wantErr: []string{"OTLP_GRPC_ENDPOINT: "},
+		},
+		{
+			name:    "silently ignored integer",
+			vars:    map[string]string{"MAX_CONCURRENT": "ten"},
+			wantErr: []string{`MAX_CONCURRENT: invalid integer "ten"`},
+		},
+		{
+			name:    "firehose without stream",
+			vars:    map[string]string{"EXPORTER": "firehose"},
+			wantErr: []string{"FIREHOSE_DELIVERY_STREAM: required by the firehose exporter"},
+		},
+		{
+			name:    "divert exporter requirements",
+			vars:    map[string]string{"PIPELINE_DIVERT_EXPORTER": "opensearch"},
+			wantErr: []string{"OPENSEARCH_ENDPOINT: required by the opensearch exporter"},
+		},
+		{
+			name:    "incomplete appconfig",
+			vars:    map[string]string{"APPCONFIG_APPLICATION": "log-parser", "APPCONFIG_PROFILE": "ingest", "APPCONFIG_POLL_SECONDS": "5"},
+			wantErr: []string{"APPCONFIG_ENVIRONMENT: required with APPCONFIG_APPLICATION", "APPCONFIG_POLL_SECONDS: 5 is out of range"},
+		},
+		{
+			name:    "two dead-letter queues",
+			vars:    map[string]string{"DLQ_S3_BUCKET": "dlq", "DLQ_SQS_URL": "https://sqs.us-east-1.amazonaws.com/123456789012/dlq"},
+			wantErr: []string{"DLQ_S3_BUCKET: cannot be combined with DLQ_SQS_URL"},
+		},
+		{
+			name:    "sigv4 and oauth2",
+			vars:    map[string]string{"OTLP_SIGV4": "true", "OTLP_OAUTH2_TOKEN_URL": "https://auth.example.com/token"},
+			wantErr: []string{"OTLP_SIGV4: cannot be combined with OTLP_OAUTH2_TOKEN_URL"},
+		},
+		{
+			name: "sigv4 disabled with oauth2",
+			vars: map[string]string{"OTLP_SIGV4": "false", "OTLP_OAUTH2_TOKEN_URL": "https://auth.example.com/token"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(mapEnv(tt.vars))
+			if len(tt.wantErr) == 0 {
+				if err != nil {
+					t.Fatalf("Load() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Load() error = nil, want %q", tt.wantErr)
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Load() error = %v, want it to contain %q", err, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,282 @@
+// Package config reads the parser's settings from environment variables. Unlike plain
+// os.Getenv parsing, a malformed value is an error rather than a silent fallback to the
+// default: an Env collects every problem, so a cold start fails once with all of them, and
+// remembers the values it handed out so the effective configuration can be logged.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Env reads variables through Lookup, which defaults to os.LookupEnv, falling back to the values
+// of a configuration file (see SetFile). Overrides (see SetOverrides) take precedence over both.
+// Empty variables count as unset. It is safe for concurrent use.
+type Env struct {
+	lookup func(key string) (string, bool)
+
+	mu        sync.Mutex
+	errs      map[string]error
+	values    map[string]string
+	secrets   map[string]bool
+	file      map[string]string
+	overrides map[string]string
+}
+
+// New creates an Env reading variables through lookup; nil reads the process environment
+func New(lookup func(key string) (string, bool)) *Env {
+	if lookup == nil {
+		lookup = os.LookupEnv
+	}
+	return &Env{lookup: lookup, errs: map[string]error{}, values: map[string]string{}, secrets: map[string]bool{}}
+}
+
+// raw returns the value of a set, non-empty variable
+func (e *Env) raw(key string) (string, bool) {
+	e.mu.Lock()
+	override, overridden := e.overrides[key]
+	file := e.file[key]
+	e.mu.Unlock()
+	if overridden {
+		return override, override != ""
+	}
+	if value, ok := e.lookup(key); ok && value != "" {
+		return value, true
+	}
+	return file, file != ""
+}
+
+// SetOverrides replaces the values that take precedence over the environment, e.g. settings
+// changed at runtime. An empty override unsets its variable.
+func (e *Env) SetOverrides(values map[string]string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.overrides = values
+}
+
+// Overrides returns the current overrides
+func (e *Env) Overrides() map[string]string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.overrides
+}
+
+// record remembers the effective value of key
+func (e *Env) record(key, value string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.values[key] = value
+}
+
+// Errorf records a problem with key; only the first problem of each key is kept
+func (e *Env) Errorf(key, format string, args ...any) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, ok := e.errs[key]; !ok {
+		e.errs[key] = fmt.Errorf("%s: %s", key, fmt.Sprintf(format, args...))
+	}
+}
+
+// IsSet reports whether key is set to a non-empty value
+func (e *Env) IsSet(key string) bool {
+	_, ok := e.raw(key)
+	return ok
+}
+
+// String returns the value of key, or def when it is unset
+func (e *Env) String(key, def string) string {
+	value, ok := e.raw(key)
+	if !ok {
+		value = def
+	}
+	e.record(key, value)
+	return value
+}
+
+// Secret is String for a variable holding a credential whatever its name, e.g. a hash salt:
+// Effective redacts it
+func (e *Env) Secret(key, def string) string {
+	e.mu.Lock()
+	e.secrets[key] = true
+	e.mu.Unlock()
+	return e.String(key, def)
+}
+
+// Int returns the integer value of key, or def when it is unset or malformed
+func (e *Env) Int(key string, def int) int {
+	value, ok := e.raw(key)
+	if !ok {
+		e.record(key, strconv.Itoa(def))
+		return def
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		e.Errorf(key, "invalid integer %q", value)
+		return def
+	}
+	e.record(key, strconv.Itoa(n))
+	return n
+}
+
+// IntRange is Int with the value required to be within [min, max]
+func (e *Env) IntRange(key string, def, min, max int) int {
+	n := e.Int(key, def)
+	if n < min || n > max {
+		e.Errorf(key, "%d is out of range [%d, %d]", n, min, max)
+		return def
+	}
+	return n
+}
+
+// Bool returns the boolean value of key (1, t, true, 0, f, false, ...), or def when it is unset
+// or malformed
+func (e *Env) Bool(key string, def bool) bool {
+	value, ok := e.raw(key)
+	if !ok {
+		e.record(key, strconv.FormatBool(def))
+		return def
+	}
+	b, err := strconv.ParseBool(strings.TrimSpace(value))
+	if err != nil {
+		e.Errorf(key, "invalid boolean %q", value)
+		return def
+	}
+	e.record(key, strconv.FormatBool(b))
+	return b
+}
+
+// List splits a comma-separated variable into trimmed, non-empty items
+func (e *Env) List(key string) []string {
+	value, _ := e.raw(key)
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	e.record(key, strings.Join(items, ","))
+	return items
+}
+
+// OneOf returns the value of key, which must be one of allowed, or def when unset
+func (e *Env) OneOf(key, def string, allowed ...string) string {
+	value := e.String(key, def)
+	if !slices.Contains(allowed, value) {
+		e.Errorf(key, "%q is not one of %s", value, strings.Join(allowed, ", "))
+		return def
+	}
+	return value
+}
+
+// URL returns the value of key, which must be an absolute http or https URL, or def when unset
+func (e *Env) URL(key, def string) string {
+	value := e.String(key, def)
+	if value == "" {
+		return value
+	}
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		e.Errorf(key, "%q is not an http(s) URL", redactURL(value))
+	}
+	return value
+}
+
+// HostPort returns the value of key, which must be a host:port address, or def when unset
+func (e *Env) HostPort(key, def string) string {
+	value := e.String(key, def)
+	if value == "" {
+		return value
+	}
+	if _, port, err := net.SplitHostPort(value); err != nil || port == "" {
+		e.Errorf(key, "%q is not a host:port address", value)
+	}
+	return value
+}
+
+// Require records an error when key is unset; reason says what needs it
+func (e *Env) Require(key, reason string) {
+	if !e.IsSet(key) {
+		e.Errorf(key, "required %s", reason)
+	}
+}
+
+// Exclusive records an error when more than one of keys is set
+func (e *Env) Exclusive(keys ...string) {
+	var set []string
+	for _, key := range keys {
+		if e.IsSet(key) {
+			set = append(set, key)
+		}
+	}
+	if len(set) > 1 {
+		e.Errorf(set[0], "cannot be combined with %s", strings.Join(set[1:], ", "))
+	}
+}
+
+// Err returns every recorded problem, ordered by variable, or nil
+func (e *Env) Err() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	var errs []error
+	for _, key := range slices.Sorted(maps.Keys(e.errs)) {
+		errs = append(errs, e.errs[key])
+	}
+	return errors.Join(errs...)
+}
+
+// Effective returns the value of every variable read so far, defaults included, with secrets
+// and URL credentials redacted
+func (e *Env) Effective() map[string]string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	out := make(map[string]string, len(e.values))
+	for key, value := range e.values {
+		switch {
+		case value == "":
+			continue
+		case IsSecret(key) || e.secrets[key]:
+			out[key] = "[redacted]"
+		default:
+			out[key] = redactURL(value)
+		}
+	}
+	return out
+}
+
+// IsSecret reports whether a variable holds a credential by its name: passwords, secrets,
+// tokens, keys, hash salts, header lists (which carry API keys) and the inline OTLP_ROUTES table (whose
+// routes carry passwords and headers). <KEY>_ARN variables only point to secrets and are not.
+func IsSecret(key string) bool {
+	if key == "OTLP_ROUTES" {
+		return true
+	}
+	if strings.HasSuffix(key, "_ARN") || strings.HasSuffix(key, "_URL") {
+		return false
+	}
+	for _, marker := range []string{"PASSWORD", "SECRET", "SALT", "HEADERS", "CREDENTIALS"} {
+		if strings.Contains(key, marker) {
+			return true
+		}
+	}
+	return strings.HasSuffix(key, "_TOKEN") || (strings.HasSuffix(key, "_KEY") && !strings.HasSuffix(key, "_GROUP_KEY"))
+}
+
+// redactURL hides the password of a URL with credentials; other values are returned as is
+func redactURL(value string) string {
+	if !strings.Contains(value, "@") {
+		return value
+	}
+	u, err := url.Parse(value)
+	if err != nil || u.User == nil {
+		return value
+	}
+	return u.Redacted()
+}
@@ -0,0 +1,74 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ParseFile reads a YAML or JSON configuration file into variable values. Keys are variable
+// names in any case, with '-' and '.' read as '_' (exporter, max-batch-size, OTLP_ROUTES).
+// Scalars become their text, lists of scalars comma-separated lists, and anything structured
+// (filter_rules, attribute_mapping, otlp_routes) the JSON its variable already accepts.
+func ParseFile(data []byte) (map[string]string, error) {
+	var doc map[string]any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+	values := make(map[string]string, len(doc))
+	for key, value := range doc {
+		name := strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(key))
+		text, err := fileValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("config file key %q: %w", key, err)
+		}
+		if _, dup := values[name]; dup {
+			return nil, fmt.Errorf("config file key %q: %s is set twice", key, name)
+		}
+		values[name] = text
+	}
+	return values, nil
+}
+
+// fileValue renders one configuration value as its variable would hold it
+func fileValue(value any) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case map[string]any:
+		data, err := json.Marshal(v)
+		return string(data), err
+	case []any:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			switch item.(type) {
+			case map[string]any, []any:
+				data, err := json.Marshal(v)
+				return string(data), err
+			}
+			text, err := fileValue(item)
+			if err != nil {
+				return "", err
+			}
+			if strings.Contains(text, ",") {
+				return "", fmt.Errorf("list item %q contains a comma", text)
+			}
+			items = append(items, text)
+		}
+		return strings.Join(items, ","), nil
+	default:
+		return fmt.Sprint(v), nil
+	}
+}
+
+// SetFile supplies values for variables the environment leaves unset, so a configuration file
+// holds the settings and an environment variable can still override one of them
+func (e *Env) SetFile(values map[string]string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.file = values
+}
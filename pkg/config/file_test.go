@@ -0,0 +1,84 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseFile(t *testing.T) {
+	data := []byte(`
+exporter: [otlp, s3]
+max-batch-size: 1000
+OTLP_PROTOCOL: grpc
+metrics.enabled: true
+sample_rate: 0.5
+processors_enabled:
+  - alb
+  - waf
+filter_rules:
+  alb:
+    drop:
+      - field: http.request.path
+        equals: /health
+`)
+	values, err := ParseFile(data)
+	if err != nil {
+		t.Fatalf("ParseFile() error = %v", err)
+	}
+	want := map[string]string{
+		"EXPORTER":           "otlp,s3",
+		"MAX_BATCH_SIZE":     "1000",
+		"OTLP_PROTOCOL":      "grpc",
+		"METRICS_ENABLED":    "true",
+		"SAMPLE_RATE":        "0.5",
+		"PROCESSORS_ENABLED": "alb,waf",
+		"FILTER_RULES":       `{"alb":{"drop":[{"equals":"/health","field":"http.request.path"}]}}`,
+	}
+	if len(values) != len(want) {
+		t.Errorf("ParseFile() = %v, want %v", values, want)
+	}
+	for key, value := range want {
+		if values[key] != value {
+			t.Errorf("ParseFile()[%s] = %q, want %q", key, values[key], value)
+		}
+	}
+}
+
+func TestParseFileErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{name: "not a mapping", data: "- otlp", wantErr: "failed to parse config file"},
+		{name: "duplicate variable", data: "max-batch-size: 1\nMAX_BATCH_SIZE: 2", wantErr: "MAX_BATCH_SIZE is set twice"},
+		{name: "comma in list item", data: `exporter: ["otlp,s3"]`, wantErr: "contains a comma"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseFile([]byte(tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("ParseFile() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEnvFileFallback(t *testing.T) {
+	env := mapEnv(map[string]string{"EXPORTER": "s3", "MAX_RETRIES": ""})
+	env.SetFile(map[string]string{"EXPORTER": "otlp", "MAX_RETRIES": "5", "PROCESSORS_ENABLED": "alb"})
+
+	cfg, err := Load(env)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(cfg.Exporters) != 1 || cfg.Exporters[0] != "s3" {
+		t.Errorf("Exporters = %v, want the environment to override the file", cfg.Exporters)
+	}
+	if cfg.MaxRetries != 5 {
+		t.Errorf("MaxRetries = %d, want 5 from the file", cfg.MaxRetries)
+	}
+	if len(cfg.Processors) != 1 || cfg.Processors[0] != "alb" {
+		t.Errorf("Processors = %v, want [alb]", cfg.Processors)
+	}
+}
@@ -0,0 +1,116 @@
+package converter
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/netip"
+	"regexp"
+	"strings"
+)
+
+// IP anonymization modes
+const (
+	IPAnonymizationOff = "off"
+	// IPAnonymizationTruncate zeroes the last octet of IPv4 addresses and all but the first 48
+	// bits of IPv6 addresses
+	IPAnonymizationTruncate = "truncate"
+	// IPAnonymizationHash replaces addresses with a salted HMAC-SHA256, so one client keeps
+	// one pseudonym
+	IPAnonymizationHash = "hash"
+)
+
+// clientIPKeys are the attributes that hold client addresses; x_forwarded_for is a list
+var clientIPKeys = map[string]bool{
+	"client.address":                 true,
+	"aws.cloudfront.x_forwarded_for": true,
+}
+
+// ipCandidate matches runs that may be an address, optionally with a port
+var ipCandidate = regexp.MustCompile(`[0-9A-Fa-f.:\[\]]{7,}`)
+
+// IPAnonymizer pseudonymizes client addresses before export
+type IPAnonymizer struct {
+	Mode string
+	Salt []byte
+}
+
+// ParseIPAnonymization validates a mode; hash needs a salt. Off returns nil.
+func ParseIPAnonymization(mode, salt string) (*IPAnonymizer, error) {
+	switch mode = strings.ToLower(mode); mode {
+	case "", IPAnonymizationOff:
+		return nil, nil
+	case IPAnonymizationTruncate:
+		return &IPAnonymizer{Mode: mode}, nil
+	case IPAnonymizationHash:
+		if salt == "" {
+			return nil, errors.New("hash anonymization needs a salt")
+		}
+		return &IPAnonymizer{Mode: mode, Salt: []byte(salt)}, nil
+	}
+	return nil, fmt.Errorf("unknown IP anonymization mode %q (want off, truncate or hash)", mode)
+}
+
+// Address anonymizes one address. Values that are not addresses are hashed in hash mode and
+// kept in truncate mode, where there is nothing to truncate.
+func (a *IPAnonymizer) Address(value string) string {
+	addr, err := netip.ParseAddr(value)
+	if a.Mode == IPAnonymizationHash {
+		if err == nil {
+			value = addr.Unmap().String()
+		}
+		mac := hmac.New(sha256.New, a.Salt)
+		mac.Write([]byte(value))
+		return hex.EncodeToString(mac.Sum(nil)[:16])
+	}
+	if err != nil {
+		return value
+	}
+	addr = addr.Unmap()
+	bits := 48
+	if addr.Is4() {
+		bits = 24
+	}
+	prefix, _ := addr.Prefix(bits)
+	return prefix.Addr().String()
+}
+
+// Records anonymizes the client address attributes, reusing the slice
+func (a *IPAnonymizer) Records(attrs []OTelAttribute) []OTelAttribute {
+	if a == nil {
+		return attrs
+	}
+	for i, attr := range attrs {
+		if !clientIPKeys[attr.Key] || attr.Value.StringValue == nil {
+			continue
+		}
+		parts := strings.Split(*attr.Value.StringValue, ",")
+		for j, part := range parts {
+			parts[j] = a.Address(strings.TrimSpace(part))
+		}
+		attrs[i].Value = stringValue(strings.Join(parts, ", "))
+	}
+	return attrs
+}
+
+// Scrub anonymizes every address in free text, such as a raw log line, keeping ports
+func (a *IPAnonymizer) Scrub(text string) string {
+	if a == nil {
+		return text
+	}
+	return ipCandidate.ReplaceAllStringFunc(text, func(match string) string {
+		if addr, err := netip.ParseAddr(match); err == nil {
+			return a.Address(addr.String())
+		}
+		if ap, err := netip.ParseAddrPort(match); err == nil {
+			host := a.Address(ap.Addr().String())
+			if ap.Addr().Is6() {
+				host = "[" + host + "]"
+			}
+			return fmt.Sprintf("%s:%d", host, ap.Port())
+		}
+		return match
+	})
+}
@@ -0,0 +1,81 @@
+package converter
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseIPAnonymization(t *testing.T) {
+	for _, tt := range []struct {
+		mode, salt string
+		wantNil    bool
+		wantErr    bool
+	}{
+		{"", "", true, false},
+		{"off", "", true, false},
+		{"TRUNCATE", "", false, false},
+		{"hash", "pepper", false, false},
+		{"hash", "", true, true},
+		{"mask", "", true, true},
+	} {
+		a, err := ParseIPAnonymization(tt.mode, tt.salt)
+		if (err != nil) != tt.wantErr || (a == nil) != tt.wantNil {
+			t.Errorf("ParseIPAnonymization(%q, %q) = %v, %v", tt.mode, tt.salt, a, err)
+		}
+	}
+}
+
+func TestIPAnonymizer_Address(t *testing.T) {
+	truncate := &IPAnonymizer{Mode: IPAnonymizationTruncate}
+	tests := []struct {
+		in, want string
+	}{
+		{"203.0.113.77", "203.0.113.0"},
+		{"::ffff:203.0.113.77", "203.0.113.0"},
+		{"2001:db8:1234:5678::1", "2001:db8:1234::"},
+		{"not-an-ip", "not-an-ip"},
+	}
+	for _, tt := range tests {
+		if got := truncate.Address(tt.in); got != tt.want {
+			t.Errorf("truncate %q = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+
+	hash := &IPAnonymizer{Mode: IPAnonymizationHash, Salt: []byte("pepper")}
+	a, b := hash.Address("203.0.113.77"), hash.Address("::ffff:203.0.113.77")
+	if a != b || len(a) != 32 || strings.Contains(a, "203") {
+		t.Errorf("hash = %q and %q, want one 32 character pseudonym", a, b)
+	}
+	if other := (&IPAnonymizer{Mode: IPAnonymizationHash, Salt: []byte("salt")}).Address("203.0.113.77"); other == a {
+		t.Error("hash does not depend on the salt")
+	}
+}
+
+func TestIPAnonymizer_Records(t *testing.T) {
+	attrs := []OTelAttribute{
+		{Key: "client.address", Value: stringValue("203.0.113.77")},
+		{Key: "aws.cloudfront.x_forwarded_for", Value: stringValue("198.51.100.9, 192.0.2.200")},
+		{Key: "server.socket.address", Value: stringValue("10.0.0.1")},
+	}
+	var none *IPAnonymizer
+	if got := none.Records(attrs); *got[0].Value.StringValue != "203.0.113.77" {
+		t.Errorf("nil anonymizer changed client.address to %q", *got[0].Value.StringValue)
+	}
+
+	got := (&IPAnonymizer{Mode: IPAnonymizationTruncate}).Records(attrs)
+	want := []string{"203.0.113.0", "198.51.100.0, 192.0.2.0", "10.0.0.1"}
+	for i, w := range want {
+		if v := *got[i].Value.StringValue; v != w {
+			t.Errorf("%s = %q, want %q", got[i].Key, v, w)
+		}
+	}
+}
+
+func TestIPAnonymizer_Scrub(t *testing.T) {
+	a := &IPAnonymizer{Mode: IPAnonymizationTruncate}
+	in := `2018-07-02T22:23:00.186641Z app/lb 192.168.131.39:2817 [2001:db8::7]:443 "curl/7.46.0" 10.0.0.1`
+	want := `2018-07-02T22:23:00.186641Z app/lb 192.168.131.0:2817 [2001:db8::]:443 "curl/7.46.0" 10.0.0.0`
+	if got := a.Scrub(in); got != want {
+		t.Errorf("Scrub() =\n%s\nwant\n%s", got, want)
+	}
+}
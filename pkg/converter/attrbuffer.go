@@ -0,0 +1,97 @@
+package converter
+
+import "sync"
+
+// valueKind tells how a buffered attribute's value is stored
+type valueKind uint8
+
+const (
+	kindString valueKind = iota
+	kindInt
+	kindDouble
+	kindOther
+)
+
+// bufferedAttr is an attribute whose value is held inline until the record is finished
+type bufferedAttr struct {
+	key   string
+	kind  valueKind
+	text  string       // string values, and int values in decimal
+	num   float64      // double values
+	value OTelAnyValue // values of kindOther, such as arrays
+}
+
+// attrBuffer collects the attributes of one record. Values stay in the buffer while the record
+// is built, so adding an attribute allocates nothing, and finish copies them out at their
+// final size. Buffers come from attrBuffers and are reused across records.
+type attrBuffer struct {
+	attrs []bufferedAttr
+}
+
+// attrBuffers holds buffers large enough for any record the converters build
+var attrBuffers = sync.Pool{
+	New: func() any { return &attrBuffer{attrs: make([]bufferedAttr, 0, 64)} },
+}
+
+func newAttrBuffer() *attrBuffer {
+	return attrBuffers.Get().(*attrBuffer)
+}
+
+func (b *attrBuffer) addString(key, value string) {
+	b.attrs = append(b.attrs, bufferedAttr{key: key, kind: kindString, text: value})
+}
+
+func (b *attrBuffer) addInt(key, decimal string) {
+	b.attrs = append(b.attrs, bufferedAttr{key: key, kind: kindInt, text: decimal})
+}
+
+func (b *attrBuffer) addDouble(key string, value float64) {
+	b.attrs = append(b.attrs, bufferedAttr{key: key, kind: kindDouble, num: value})
+}
+
+// add adds an attribute whose value was built by the caller
+func (b *attrBuffer) add(attr OTelAttribute) {
+	b.attrs = append(b.attrs, bufferedAttr{key: attr.Key, kind: kindOther, value: attr.Value})
+}
+
+// finish returns the attributes and puts the buffer back in the pool. The string, int and
+// double values of a record share one backing array each, which takes three allocations
+// instead of one per attribute.
+func (b *attrBuffer) finish() []OTelAttribute {
+	var texts, nums int
+	for i := range b.attrs {
+		switch b.attrs[i].kind {
+		case kindString, kindInt:
+			texts++
+		case kindDouble:
+			nums++
+		}
+	}
+
+	attrs := make([]OTelAttribute, len(b.attrs))
+	textValues := make([]string, 0, texts)
+	numValues := make([]float64, 0, nums)
+	for i := range b.attrs {
+		a := &b.attrs[i]
+		attrs[i].Key = a.key
+		switch a.kind {
+		case kindString:
+			textValues = append(textValues, a.text)
+			attrs[i].Value.StringValue = &textValues[len(textValues)-1]
+		case kindInt:
+			textValues = append(textValues, a.text)
+			attrs[i].Value.IntValue = &textValues[len(textValues)-1]
+		case kindDouble:
+			numValues = append(numValues, a.num)
+			attrs[i].Value.DoubleValue = &numValues[len(numValues)-1]
+		default:
+			attrs[i].Value = a.value
+		}
+	}
+
+	// Drop the references to the entry's strings before the buffer is reused
+	clear(b.attrs)
+	b.attrs = b.attrs[:0]
+	attrBuffers.Put(b)
+	return attrs
+}
@@ -0,0 +1,41 @@
+package converter
+
+import "testing"
+
+func TestAttrBuffer(t *testing.T) {
+	b := newAttrBuffer()
+	addAttr(b, "http.request.method", "GET")
+	addAttr(b, "url.query", "-")
+	addIntAttr(b, "http.response.status_code", 200)
+	addFloatAttr(b, "aws.lb.request_processing_time", 0.5)
+	flag := true
+	b.add(OTelAttribute{Key: "flag", Value: OTelAnyValue{BoolValue: &flag}})
+	first := b.finish()
+
+	// A reused buffer must not change the attributes handed out before
+	b = newAttrBuffer()
+	addAttr(b, "http.request.method", "POST")
+	addIntAttr(b, "http.response.status_code", 500)
+	second := b.finish()
+
+	want := map[string]string{
+		"http.request.method":            "GET",
+		"http.response.status_code":      "200",
+		"aws.lb.request_processing_time": "0.5",
+		"flag":                           "true",
+	}
+	if len(first) != len(want) {
+		t.Fatalf("finish() returned %d attributes, want %d: %+v", len(first), len(want), first)
+	}
+	for _, attr := range first {
+		if got := attr.Value.Text(); got != want[attr.Key] {
+			t.Errorf("%s = %q, want %q", attr.Key, got, want[attr.Key])
+		}
+	}
+	if first[1].Value.IntValue == nil || first[1].Value.StringValue != nil {
+		t.Errorf("status code value = %+v, want an int", first[1].Value)
+	}
+	if len(second) != 2 || second[0].Value.Text() != "POST" || second[1].Value.Text() != "500" {
+		t.Errorf("second record = %+v", second)
+	}
+}
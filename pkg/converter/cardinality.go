@@ -0,0 +1,126 @@
+package converter
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sync"
+)
+
+// CardinalityAction determines what happens to values of an attribute key that exceeded its limit
+type CardinalityAction string
+
+const (
+	CardinalityWarn CardinalityAction = "warn"
+	CardinalityHash CardinalityAction = "hash"
+	CardinalityDrop CardinalityAction = "drop"
+)
+
+// CardinalityGuard tracks the number of distinct values emitted per attribute key and
+// flags keys that exceed Limit. Once a key is flagged, its subsequent values are kept
+// (warn), replaced by a short hash (hash), or removed (drop).
+type CardinalityGuard struct {
+	Limit  int
+	Action CardinalityAction
+	// OnExceeded is called once per key, the first time it crosses Limit
+	OnExceeded func(key string, limit int)
+
+	mu       sync.Mutex
+	seen     map[string]map[string]struct{}
+	exceeded map[string]bool
+}
+
+// NewCardinalityGuard creates a guard; a limit <= 0 disables tracking
+func NewCardinalityGuard(limit int, action CardinalityAction) *CardinalityGuard {
+	return &CardinalityGuard{
+		Limit:    limit,
+		Action:   action,
+		seen:     make(map[string]map[string]struct{}),
+		exceeded: make(map[string]bool),
+	}
+}
+
+// Apply records the attribute values and returns the attributes with the configured action applied
+func (g *CardinalityGuard) Apply(attrs []OTelAttribute) []OTelAttribute {
+	if g == nil || g.Limit <= 0 {
+		return attrs
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	result := attrs[:0:0]
+	for _, attr := range attrs {
+		value := attributeValueString(attr.Value)
+
+		if !g.exceeded[attr.Key] {
+			values, ok := g.seen[attr.Key]
+			if !ok {
+				values = make(map[string]struct{})
+				g.seen[attr.Key] = values
+			}
+			if _, dup := values[value]; !dup {
+				if len(values) >= g.Limit {
+					g.exceeded[attr.Key] = true
+					// Release the tracked values; the key stays flagged for the guard's lifetime
+					delete(g.seen, attr.Key)
+					if g.OnExceeded != nil {
+						g.OnExceeded(attr.Key, g.Limit)
+					}
+				} else {
+					values[value] = struct{}{}
+				}
+			}
+		}
+
+		if !g.exceeded[attr.Key] {
+			result = append(result, attr)
+			continue
+		}
+
+		switch g.Action {
+		case CardinalityDrop:
+			continue
+		case CardinalityHash:
+			result = append(result, OTelAttribute{Key: attr.Key, Value: stringValue(hashValue(value))})
+		default:
+			result = append(result, attr)
+		}
+	}
+
+	return result
+}
+
+// Exceeded returns the keys that crossed the limit
+func (g *CardinalityGuard) Exceeded() []string {
+	if g == nil {
+		return nil
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	keys := make([]string, 0, len(g.exceeded))
+	for k := range g.exceeded {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func attributeValueString(v OTelAnyValue) string {
+	switch {
+	case v.StringValue != nil:
+		return *v.StringValue
+	case v.IntValue != nil:
+		return *v.IntValue
+	case v.DoubleValue != nil:
+		return fmt.Sprintf("%g", *v.DoubleValue)
+	case v.BoolValue != nil:
+		return fmt.Sprintf("%t", *v.BoolValue)
+	}
+	return ""
+}
+
+func hashValue(s string) string {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return fmt.Sprintf("%016x", h.Sum64())
+}
@@ -0,0 +1,75 @@
+package converter
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestCardinalityGuard(t *testing.T) {
+	tests := []struct {
+		action    CardinalityAction
+		wantKept  bool
+		wantValue func(original string) string
+	}{
+		{action: CardinalityWarn, wantKept: true, wantValue: func(v string) string { return v }},
+		{action: CardinalityHash, wantKept: true, wantValue: hashValue},
+		{action: CardinalityDrop, wantKept: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.action), func(t *testing.T) {
+			guard := NewCardinalityGuard(3, tt.action)
+			var flagged []string
+			guard.OnExceeded = func(key string, limit int) { flagged = append(flagged, key) }
+
+			for i := 0; i < 3; i++ {
+				attrs := guard.Apply([]OTelAttribute{
+					{Key: "url.query", Value: stringValue(fmt.Sprintf("q=%d", i))},
+					{Key: "cloud.provider", Value: stringValue("aws")},
+				})
+				if len(attrs) != 2 {
+					t.Fatalf("Apply() under limit returned %d attributes, want 2", len(attrs))
+				}
+			}
+
+			attrs := guard.Apply([]OTelAttribute{
+				{Key: "url.query", Value: stringValue("q=overflow")},
+				{Key: "cloud.provider", Value: stringValue("aws")},
+			})
+
+			if len(flagged) != 1 || flagged[0] != "url.query" {
+				t.Errorf("OnExceeded called for %v, want [url.query]", flagged)
+			}
+
+			var query *OTelAttribute
+			for i := range attrs {
+				if attrs[i].Key == "url.query" {
+					query = &attrs[i]
+				}
+			}
+
+			if !tt.wantKept {
+				if query != nil {
+					t.Error("url.query should have been dropped")
+				}
+				return
+			}
+			if query == nil {
+				t.Fatal("url.query missing")
+			}
+			if got, want := *query.Value.StringValue, tt.wantValue("q=overflow"); got != want {
+				t.Errorf("url.query = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestCardinalityGuard_Disabled(t *testing.T) {
+	guard := NewCardinalityGuard(0, CardinalityDrop)
+	for i := 0; i < 10; i++ {
+		attrs := guard.Apply([]OTelAttribute{{Key: "k", Value: stringValue(fmt.Sprint(i))}})
+		if len(attrs) != 1 {
+			t.Fatalf("disabled guard modified attributes: %v", attrs)
+		}
+	}
+}
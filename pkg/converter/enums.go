@@ -0,0 +1,95 @@
+package converter
+
+import (
+	"sort"
+	"sync"
+)
+
+// Known values of enum-like log fields. AWS adds values over time (new result types, new WAF
+// actions); anything not listed is still passed through unchanged, flagged with a
+// "<key>.unrecognized" = true companion attribute and reported via TakeUnrecognized.
+var (
+	albTypes = enumSet("http", "https", "h2", "grpcs", "ws", "wss")
+
+	albClassifications = enumSet("Acceptable", "Ambiguous", "Severe")
+
+	nlbTypes = enumSet("tls")
+
+	cloudFrontResultTypes = enumSet(
+		"Hit", "RefreshHit", "Miss", "LimitExceeded", "CapacityExceeded", "Error", "Redirect",
+		"FunctionGeneratedResponse", "OriginShieldHit",
+	)
+
+	cloudFrontDetailedResultTypes = enumSet(
+		"Hit", "RefreshHit", "Miss", "LimitExceeded", "CapacityExceeded", "Error", "Redirect",
+		"FunctionGeneratedResponse", "OriginShieldHit", "AbortedOrigin", "ClientCommError",
+		"ClientGeoBlocked", "ClientHungUpRequest", "InvalidRequest", "InvalidRequestBlocked",
+		"InvalidRequestCertificate", "InvalidRequestHeader", "InvalidRequestMethod",
+		"OriginCommError", "OriginConnectError", "OriginContentRangeLengthError", "OriginDnsError",
+		"OriginError", "OriginHeaderTooBigError", "OriginInvalidResponseError", "OriginReadError",
+		"OriginWriteError", "OriginZeroSizeObjectError", "SlowReaderOriginError",
+		"LambdaExecutionError", "LambdaLimitExceededError", "LambdaValidationError",
+		"LambdaResponseTooLarge", "FunctionExecutionError", "FunctionThrottledError",
+	)
+
+	cloudFrontProtocols = enumSet("http", "https", "ws", "wss")
+
+	wafActions = enumSet("ALLOW", "BLOCK", "COUNT", "CAPTCHA", "CHALLENGE")
+
+	wafTerminatingRuleTypes = enumSet("REGULAR", "RATE_BASED", "GROUP", "MANAGED_RULE_GROUP")
+)
+
+func enumSet(values ...string) map[string]struct{} {
+	set := make(map[string]struct{}, len(values))
+	for _, v := range values {
+		set[v] = struct{}{}
+	}
+	return set
+}
+
+// unrecognized collects the unknown enum values seen since the last TakeUnrecognized
+var unrecognized = struct {
+	sync.Mutex
+	values map[string]map[string]struct{}
+}{values: make(map[string]map[string]struct{})}
+
+// addEnumAttr adds an enum-like attribute and flags values outside the known set
+func addEnumAttr(attrs *attrBuffer, key, value string, known map[string]struct{}) {
+	if IsPlaceholder(value) {
+		return
+	}
+	addAttr(attrs, key, value)
+	if _, ok := known[value]; ok {
+		return
+	}
+
+	flag := true
+	attrs.add(OTelAttribute{Key: key + ".unrecognized", Value: OTelAnyValue{BoolValue: &flag}})
+
+	unrecognized.Lock()
+	if unrecognized.values[key] == nil {
+		unrecognized.values[key] = make(map[string]struct{})
+	}
+	unrecognized.values[key][value] = struct{}{}
+	unrecognized.Unlock()
+}
+
+// TakeUnrecognized returns the unknown enum values seen per attribute key since the previous
+// call and resets the set, so callers can warn once per invocation
+func TakeUnrecognized() map[string][]string {
+	unrecognized.Lock()
+	defer unrecognized.Unlock()
+
+	if len(unrecognized.values) == 0 {
+		return nil
+	}
+	out := make(map[string][]string, len(unrecognized.values))
+	for key, values := range unrecognized.values {
+		for v := range values {
+			out[key] = append(out[key], v)
+		}
+		sort.Strings(out[key])
+	}
+	unrecognized.values = make(map[string]map[string]struct{})
+	return out
+}
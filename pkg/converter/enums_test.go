@@ -0,0 +1,83 @@
+package converter
+
+import (
+	"testing"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/parser"
+)
+
+func findAttr(attrs []OTelAttribute, key string) (OTelAttribute, bool) {
+	for _, attr := range attrs {
+		if attr.Key == key {
+			return attr, true
+		}
+	}
+	return OTelAttribute{}, false
+}
+
+func TestEnumAttributes_Unrecognized(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		known   string
+		unknown string
+		convert func(value string) OTelLogRecord
+	}{
+		{"ALB type", "aws.alb.type", "h2", "h3", func(v string) OTelLogRecord {
+			return ConvertToOTel(&parser.ALBLogEntry{Type: v})
+		}},
+		{"ALB classification", "aws.alb.classification", "Ambiguous", "Suspicious", func(v string) OTelLogRecord {
+			return ConvertToOTel(&parser.ALBLogEntry{Type: "h2", Classification: v})
+		}},
+		{"NLB type", "aws.nlb.type", "tls", "quic", func(v string) OTelLogRecord {
+			return ConvertNLBToOTel(&parser.NLBLogEntry{Type: v})
+		}},
+		{"CloudFront result type", "aws.cloudfront.result_type", "Hit", "EdgeComputed", func(v string) OTelLogRecord {
+			return ConvertCloudFrontToOTel(&parser.CloudFrontLogEntry{XEdgeResultType: v})
+		}},
+		{"CloudFront response result type", "aws.cloudfront.response_result_type", "Miss", "EdgeComputed", func(v string) OTelLogRecord {
+			return ConvertCloudFrontToOTel(&parser.CloudFrontLogEntry{XEdgeResponseResultType: v})
+		}},
+		{"CloudFront detailed result type", "aws.cloudfront.detailed_result_type", "OriginDnsError", "OriginQuantumError", func(v string) OTelLogRecord {
+			return ConvertCloudFrontToOTel(&parser.CloudFrontLogEntry{XEdgeDetailedResultType: v})
+		}},
+		{"CloudFront protocol", "url.scheme", "https", "quic", func(v string) OTelLogRecord {
+			return ConvertCloudFrontToOTel(&parser.CloudFrontLogEntry{CSProtocol: v})
+		}},
+		{"WAF action", "aws.waf.action", "CAPTCHA", "QUARANTINE", func(v string) OTelLogRecord {
+			return ConvertWAFToOTel(&parser.WAFLogEntry{Action: v})
+		}},
+		{"WAF terminating rule type", "aws.waf.terminating_rule_type", "MANAGED_RULE_GROUP", "ML_MODEL", func(v string) OTelLogRecord {
+			return ConvertWAFToOTel(&parser.WAFLogEntry{Action: "ALLOW", TerminatingRuleType: v})
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			TakeUnrecognized()
+
+			record := tt.convert(tt.known)
+			if _, ok := findAttr(record.Attributes, tt.key+".unrecognized"); ok {
+				t.Errorf("known value %q flagged as unrecognized", tt.known)
+			}
+
+			record = tt.convert(tt.unknown)
+			attr, ok := findAttr(record.Attributes, tt.key)
+			if !ok || attr.Value.StringValue == nil || *attr.Value.StringValue != tt.unknown {
+				t.Errorf("unknown value %q was not passed through", tt.unknown)
+			}
+			flag, ok := findAttr(record.Attributes, tt.key+".unrecognized")
+			if !ok || flag.Value.BoolValue == nil || !*flag.Value.BoolValue {
+				t.Errorf("%s.unrecognized=true missing for %q", tt.key, tt.unknown)
+			}
+
+			seen := TakeUnrecognized()
+			if len(seen[tt.key]) != 1 || seen[tt.key][0] != tt.unknown {
+				t.Errorf("TakeUnrecognized() = %v, want %s: [%s]", seen, tt.key, tt.unknown)
+			}
+			if again := TakeUnrecognized(); again != nil {
+				t.Errorf("TakeUnrecognized() after drain = %v, want nil", again)
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package converter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// AttributeMapping rewrites the attributes of a processor's records before export. Drop runs
+// first, then Rename, then Add; a key ending in "*" matches every key with that prefix.
+type AttributeMapping struct {
+	// Drop removes record and resource attributes
+	Drop []string `yaml:"drop"`
+	// Rename maps old keys to new ones on records and resources
+	Rename map[string]string `yaml:"rename"`
+	// Add sets static resource attributes, replacing any of the same key
+	Add map[string]string `yaml:"add"`
+}
+
+// AttributeMappings holds the mapping of each processor, keyed by processor name ("alb",
+// "cloudfront", "kinesis", ...); the "*" entry applies to all of them
+type AttributeMappings map[string]*AttributeMapping
+
+// ParseAttributeMappings reads mappings from YAML or JSON, e.g.
+//
+//	{"*": {"drop": ["aws.alb.trace_id"]}, "alb": {"rename": {"aws.lb.name": "loadbalancer.name"}}}
+func ParseAttributeMappings(data []byte) (AttributeMappings, error) {
+	var mappings AttributeMappings
+	if err := yaml.Unmarshal(data, &mappings); err != nil {
+		return nil, err
+	}
+	for name, m := range mappings {
+		if m == nil {
+			continue
+		}
+		for from, to := range m.Rename {
+			if to == "" || strings.HasSuffix(from, "*") {
+				return nil, fmt.Errorf("%s: invalid rename %q -> %q", name, from, to)
+			}
+		}
+		for key := range m.Add {
+			if key == "" {
+				return nil, fmt.Errorf("%s: empty attribute key in add", name)
+			}
+		}
+	}
+	return mappings, nil
+}
+
+// For returns the processor's mapping merged over the "*" one, or nil when neither exists
+func (ms AttributeMappings) For(processor string) *AttributeMapping {
+	shared, own := ms["*"], ms[processor]
+	if shared == nil {
+		return own
+	}
+	if own == nil {
+		return shared
+	}
+	merged := &AttributeMapping{
+		Drop:   append(append([]string(nil), shared.Drop...), own.Drop...),
+		Rename: map[string]string{},
+		Add:    map[string]string{},
+	}
+	for _, src := range []*AttributeMapping{shared, own} {
+		for k, v := range src.Rename {
+			merged.Rename[k] = v
+		}
+		for k, v := range src.Add {
+			merged.Add[k] = v
+		}
+	}
+	return merged
+}
+
+func (m *AttributeMapping) drops(key string) bool {
+	for _, pattern := range m.Drop {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(key, prefix) {
+				return true
+			}
+		} else if key == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// Records applies Drop and Rename to record attributes, reusing the slice
+func (m *AttributeMapping) Records(attrs []OTelAttribute) []OTelAttribute {
+	if m == nil {
+		return attrs
+	}
+	out := attrs[:0]
+	for _, attr := range attrs {
+		if m.drops(attr.Key) {
+			continue
+		}
+		if to, ok := m.Rename[attr.Key]; ok {
+			attr.Key = to
+		}
+		out = append(out, attr)
+	}
+	return out
+}
+
+// Resource applies Drop, Rename and Add to resource attributes, returning a new slice
+func (m *AttributeMapping) Resource(attrs []OTelAttribute) []OTelAttribute {
+	if m == nil {
+		return attrs
+	}
+	out := m.Records(append([]OTelAttribute(nil), attrs...))
+	keys := make([]string, 0, len(m.Add))
+	for key := range m.Add {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		out = append(withoutKey(out, key), OTelAttribute{Key: key, Value: stringValue(m.Add[key])})
+	}
+	return out
+}
+
+func withoutKey(attrs []OTelAttribute, key string) []OTelAttribute {
+	out := attrs[:0]
+	for _, attr := range attrs {
+		if attr.Key != key {
+			out = append(out, attr)
+		}
+	}
+	return out
+}
@@ -0,0 +1,87 @@
+package converter
+
+import (
+	"reflect"
+	"testing"
+)
+
+func attrKeys(attrs []OTelAttribute) []string {
+	keys := make([]string, 0, len(attrs))
+	for _, attr := range attrs {
+		keys = append(keys, attr.Key)
+	}
+	return keys
+}
+
+func TestAttributeMappings(t *testing.T) {
+	yamlSpec := `
+"*":
+  drop: [aws.alb.trace_id]
+  add: {team: edge}
+alb:
+  drop: ["tls.*"]
+  rename: {aws.lb.name: loadbalancer.name}
+  add: {team: payments, tier: "1"}
+`
+	jsonSpec := `{"*": {"drop": ["aws.alb.trace_id"], "add": {"team": "edge"}},
+		"alb": {"drop": ["tls.*"], "rename": {"aws.lb.name": "loadbalancer.name"}, "add": {"team": "payments", "tier": "1"}}}`
+
+	for name, spec := range map[string]string{"yaml": yamlSpec, "json": jsonSpec} {
+		t.Run(name, func(t *testing.T) {
+			mappings, err := ParseAttributeMappings([]byte(spec))
+			if err != nil {
+				t.Fatalf("ParseAttributeMappings() error = %v", err)
+			}
+
+			alb := mappings.For("alb")
+			resource := alb.Resource([]OTelAttribute{
+				{Key: "cloud.provider", Value: stringValue("aws")},
+				{Key: "aws.lb.name", Value: stringValue("app/test")},
+				{Key: "team", Value: stringValue("unknown")},
+			})
+			if got, want := attrKeys(resource), []string{"cloud.provider", "loadbalancer.name", "team", "tier"}; !reflect.DeepEqual(got, want) {
+				t.Errorf("resource keys = %v, want %v", got, want)
+			}
+			if team, _ := findAttr(resource, "team"); *team.Value.StringValue != "payments" {
+				t.Errorf("team = %s, want the processor's value", *team.Value.StringValue)
+			}
+
+			records := alb.Records([]OTelAttribute{
+				{Key: "http.request.method", Value: stringValue("GET")},
+				{Key: "tls.cipher_suite", Value: stringValue("ECDHE")},
+				{Key: "tls.protocol.version", Value: stringValue("TLSv1.2")},
+				{Key: "aws.alb.trace_id", Value: stringValue("Root=1-0-0")},
+			})
+			if got, want := attrKeys(records), []string{"http.request.method"}; !reflect.DeepEqual(got, want) {
+				t.Errorf("record keys = %v, want %v", got, want)
+			}
+
+			if nlb := mappings.For("nlb"); nlb != mappings["*"] {
+				t.Errorf("For(nlb) = %+v, want the shared mapping", nlb)
+			}
+		})
+	}
+}
+
+func TestAttributeMapping_Nil(t *testing.T) {
+	var m *AttributeMapping
+	attrs := []OTelAttribute{{Key: "a", Value: stringValue("1")}}
+	if got := m.Resource(attrs); !reflect.DeepEqual(got, attrs) {
+		t.Errorf("nil Resource() = %v", got)
+	}
+	if got := AttributeMappings(nil).For("alb"); got != nil {
+		t.Errorf("For() on no mappings = %+v, want nil", got)
+	}
+}
+
+func TestParseAttributeMappings_Invalid(t *testing.T) {
+	for _, spec := range []string{
+		`alb: [drop]`,
+		`{"alb": {"rename": {"aws.lb.name": ""}}}`,
+		`{"alb": {"rename": {"aws.*": "x"}}}`,
+	} {
+		if _, err := ParseAttributeMappings([]byte(spec)); err == nil {
+			t.Errorf("ParseAttributeMappings(%q) succeeded, want error", spec)
+		}
+	}
+}
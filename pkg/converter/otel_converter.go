@@ -1,11 +1,18 @@
+// Package converter turns parsed AWS log entries into OpenTelemetry log records, metrics and
+// spans. OTelLogRecord, ResourceLog and the metric and span types are the pipeline's working
+// model, which processors, filters and sinks read and rewrite; they are not a wire format. Every
+// OTLP encoding goes through collector pdata (ToLogs, ToMetrics, ToTraces) and its marshalers.
 package converter
 
 import (
 	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"net/url"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/pixelvide/otel-aws-log-parser/pkg/parser"
@@ -13,13 +20,19 @@ import (
 
 // OTelLogRecord represents an OpenTelemetry log record
 type OTelLogRecord struct {
-	TimeUnixNano   string            `json:"timeUnixNano"`
-	SeverityNumber int               `json:"severityNumber"`
-	SeverityText   string            `json:"severityText"`
-	Body           map[string]string `json:"body"`
-	Attributes     []OTelAttribute   `json:"attributes"`
-	TraceID        string            `json:"traceId"`
-	SpanID         string            `json:"spanId"`
+	TimeUnixNano string `json:"timeUnixNano"`
+	// ObservedTimeUnixNano is when the record was parsed, TimeUnixNano when the request happened
+	ObservedTimeUnixNano string          `json:"observedTimeUnixNano,omitempty"`
+	SeverityNumber       int             `json:"severityNumber"`
+	SeverityText         string          `json:"severityText"`
+	Body                 OTelAnyValue    `json:"body"`
+	Attributes           []OTelAttribute `json:"attributes"`
+	TraceID              string          `json:"traceId"`
+	SpanID               string          `json:"spanId"`
+	// Flags holds the W3C trace flags of the request's trace context in its lowest byte
+	Flags uint32 `json:"flags,omitempty"`
+	// Routes, when set, limits the exporters the record goes to; it is never exported
+	Routes []string `json:"-"`
 }
 
 // OTelAttribute represents a key-value attribute
@@ -34,6 +47,82 @@ type OTelAnyValue struct {
 	IntValue    *string  `json:"intValue,omitempty"`
 	DoubleValue *float64 `json:"doubleValue,omitempty"`
 	BoolValue   *bool    `json:"boolValue,omitempty"`
+	// KvlistValue is a map, e.g. a structured log body
+	KvlistValue *OTelKeyValueList `json:"kvlistValue,omitempty"`
+	// ArrayValue is a list, e.g. the labels of a WAF request
+	ArrayValue *OTelArrayValue `json:"arrayValue,omitempty"`
+}
+
+// OTelKeyValueList is the value of a map-typed OTelAnyValue
+type OTelKeyValueList struct {
+	Values []OTelAttribute `json:"values"`
+}
+
+// OTelArrayValue is the value of a list-typed OTelAnyValue
+type OTelArrayValue struct {
+	Values []OTelAnyValue `json:"values"`
+}
+
+// Text renders the value as a string: scalars in their usual form, maps and lists as JSON
+func (v OTelAnyValue) Text() string {
+	switch {
+	case v.StringValue != nil:
+		return *v.StringValue
+	case v.IntValue != nil:
+		return *v.IntValue
+	case v.DoubleValue != nil:
+		return strconv.FormatFloat(*v.DoubleValue, 'f', -1, 64)
+	case v.BoolValue != nil:
+		return strconv.FormatBool(*v.BoolValue)
+	case v.KvlistValue != nil:
+		out, _ := json.Marshal(v.KvlistValue.Plain())
+		return string(out)
+	case v.ArrayValue != nil:
+		out, _ := json.Marshal(v.ArrayValue.Plain())
+		return string(out)
+	}
+	return ""
+}
+
+// Plain converts the list into a map of plain Go values, e.g. for JSON encoding
+func (l *OTelKeyValueList) Plain() map[string]any {
+	out := make(map[string]any, len(l.Values))
+	for _, attr := range l.Values {
+		out[attr.Key] = attr.Value.Plain()
+	}
+	return out
+}
+
+// Plain converts the value into a plain Go value: string, int64 (or the raw string if it does
+// not parse), float64, bool, map[string]any or []any
+func (v OTelAnyValue) Plain() any {
+	switch {
+	case v.StringValue != nil:
+		return *v.StringValue
+	case v.IntValue != nil:
+		if i, err := strconv.ParseInt(*v.IntValue, 10, 64); err == nil {
+			return i
+		}
+		return *v.IntValue
+	case v.DoubleValue != nil:
+		return *v.DoubleValue
+	case v.BoolValue != nil:
+		return *v.BoolValue
+	case v.KvlistValue != nil:
+		return v.KvlistValue.Plain()
+	case v.ArrayValue != nil:
+		return v.ArrayValue.Plain()
+	}
+	return nil
+}
+
+// Plain converts the list into a slice of plain Go values, e.g. for JSON encoding
+func (l *OTelArrayValue) Plain() []any {
+	out := make([]any, len(l.Values))
+	for i, v := range l.Values {
+		out[i] = v.Plain()
+	}
+	return out
 }
 
 // ResourceAttributes represents resource-level attributes
@@ -67,23 +156,39 @@ type OTLPPayload struct {
 // ParseTraceID extracts W3C trace ID from ALB trace ID
 // ALB format: Root=1-58337262-36d228ad5d99923122bbe354
 // W3C format: 5833726236d228ad5d99923122bbe354 (32 hex chars)
+//
+// The value may be a full X-Amzn-Trace-Id header ("Self=...;Root=...;Parent=...;Sampled=1"),
+// in which case the Root field is used, or a bare "1-58337262-36d228ad5d99923122bbe354"
 func ParseTraceID(albTraceID string) string {
 	if albTraceID == "" || albTraceID == "-" {
 		return ""
 	}
 
-	// Remove "Root=" prefix
-	albTraceID = strings.TrimPrefix(albTraceID, "Root=")
+	root := ""
+	for _, field := range strings.Split(albTraceID, ";") {
+		key, value, ok := strings.Cut(strings.TrimSpace(field), "=")
+		if !ok {
+			// No key: a bare X-Ray trace ID
+			if root == "" {
+				root = key
+			}
+			continue
+		}
+		if strings.EqualFold(key, "Root") {
+			root = value
+			break
+		}
+	}
 
 	// Split by hyphens: ['1', '58337262', '36d228ad5d99923122bbe354']
-	parts := strings.Split(albTraceID, "-")
+	parts := strings.Split(root, "-")
 
-	if len(parts) >= 3 {
+	if len(parts) == 3 && parts[0] == "1" && len(parts[1]) == 8 {
 		// Combine timestamp (8 chars) + unique ID (24 chars) = 32 chars
 		traceID := parts[1] + parts[2]
 
 		// Validate it's 32 hex characters
-		if len(traceID) == 32 && isHex(traceID) {
+		if len(traceID) == 32 && isHex(traceID) && traceID != strings.Repeat("0", 32) {
 			return strings.ToLower(traceID)
 		}
 	}
@@ -91,6 +196,26 @@ func ParseTraceID(albTraceID string) string {
 	return ""
 }
 
+// TraceFlagsSampled is the W3C sampled trace flag, as set in OTelLogRecord.Flags
+const TraceFlagsSampled uint32 = 0x01
+
+// ParseTraceFlags returns the W3C trace flags of an X-Amzn-Trace-Id header: TraceFlagsSampled
+// when it carries Sampled=1, else 0
+func ParseTraceFlags(header string) uint32 {
+	for _, field := range strings.Split(header, ";") {
+		key, value, ok := strings.Cut(strings.TrimSpace(field), "=")
+		if ok && strings.EqualFold(key, "Sampled") && value == "1" {
+			return TraceFlagsSampled
+		}
+	}
+	return 0
+}
+
+// observedNow is the observed timestamp of records converted now
+func observedNow() string {
+	return strconv.FormatInt(time.Now().UnixNano(), 10)
+}
+
 func isHex(s string) bool {
 	for _, c := range s {
 		if !((c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')) {
@@ -100,7 +225,8 @@ func isHex(s string) bool {
 	return true
 }
 
-// ParseRequestURL extracts HTTP attributes from URL
+// ParseRequestURL extracts the url.* semantic-convention attributes from a request URL, plus
+// server.address from its host
 func ParseRequestURL(requestURL string) map[string]string {
 	attrs := make(map[string]string)
 
@@ -114,23 +240,31 @@ func ParseRequestURL(requestURL string) map[string]string {
 	}
 
 	if u.Scheme != "" {
-		attrs["http.scheme"] = u.Scheme
+		attrs["url.scheme"] = u.Scheme
+	}
+	if host := u.Hostname(); host != "" {
+		attrs["server.address"] = host
 	}
-
 	if u.Path != "" {
 		attrs["url.path"] = u.Path
-
-		if u.RawQuery != "" {
-			attrs["url.query"] = u.RawQuery
-			attrs["http.target"] = u.Path + "?" + u.RawQuery
-		} else {
-			attrs["http.target"] = u.Path
-		}
+	}
+	if u.RawQuery != "" {
+		attrs["url.query"] = u.RawQuery
 	}
 
 	return attrs
 }
 
+// httpProtocolVersion turns a request protocol such as "HTTP/2.0" into the
+// network.protocol.version value ("2")
+func httpProtocolVersion(proto string) string {
+	version := strings.TrimPrefix(strings.ToUpper(proto), "HTTP/")
+	if major, ok := strings.CutSuffix(version, ".0"); ok && major != "1" {
+		return major
+	}
+	return version
+}
+
 // ExtractResourceAttributes extracts cloud resource attributes from ALB entry
 func ExtractResourceAttributes(entry *parser.ALBLogEntry) []OTelAttribute {
 	attrs := []OTelAttribute{
@@ -169,116 +303,111 @@ func ConvertToOTel(entry *parser.ALBLogEntry) OTelLogRecord {
 	attributes := buildAttributes(entry)
 
 	// Determine severity
-	severityText := "INFO"
-	severityNumber := 9
-
-	if entry.ELBStatusCode >= 500 {
-		severityText = "ERROR"
-		severityNumber = 17
-	} else if entry.ELBStatusCode >= 400 {
-		severityText = "WARN"
-		severityNumber = 13
-	}
+	severity := SeverityForStatus(entry.ELBStatusCode)
 
 	// Build body
-	bodyContent := fmt.Sprintf("%s %s %s", entry.RequestVerb, entry.RequestURL, entry.RequestProto)
+	bodyContent := entry.RequestVerb + " " + ActiveRedactor().URL(entry.RequestURL) + " " + entry.RequestProto
 
 	// Parse trace ID
 	traceID := ParseTraceID(entry.TraceID)
 
 	// Generate a random Span ID (16 hex chars)
-	// This makes the log entry appear as a span in the trace
-	spanID := generateSpanID()
+	// This makes the log entry appear as a span in the trace. A span ID without a trace ID
+	// is invalid, so requests without one carry neither.
+	spanID := ""
+	var flags uint32
+	if traceID != "" {
+		spanID = generateSpanID()
+		flags = ParseTraceFlags(entry.TraceID)
+	}
 
 	return OTelLogRecord{
-		TimeUnixNano:   fmt.Sprintf("%d", timeUnixNano),
-		SeverityNumber: severityNumber,
-		SeverityText:   severityText,
-		Body:           map[string]string{"stringValue": bodyContent},
-		Attributes:     attributes,
-		TraceID:        traceID,
-		SpanID:         spanID,
+		TimeUnixNano:         strconv.FormatInt(timeUnixNano, 10),
+		ObservedTimeUnixNano: observedNow(),
+		SeverityNumber:       severity.Number,
+		SeverityText:         severity.Text,
+		Body:                 stringValue(bodyContent),
+		Attributes:           attributes,
+		TraceID:              traceID,
+		SpanID:               spanID,
+		Flags:                flags,
 	}
 }
 
 // generateSpanID generates a random 8-byte hex string (16 chars)
 func generateSpanID() string {
-	b := make([]byte, 8)
-	// Use time as seed for simple randomness, or crypto/rand for better
-	// For high-throughput logs, math/rand seeded once is faster,
-	// but here we'll just use a simple hex generation from random bytes
-	// Note: In a real high-perf scenario, use a proper random source
-	// For now, using a simple pseudo-random approach based on time is sufficient
-	// or just reading from crypto/rand
-
-	// Using a simple fast approach:
-	// We need 16 hex chars.
-	// Let's use crypto/rand properly
-	_, err := rand.Read(b)
-	if err != nil {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
 		// Fallback if rand fails (unlikely)
 		return fmt.Sprintf("%016x", time.Now().UnixNano())
 	}
-	return fmt.Sprintf("%x", b)
+	return hex.EncodeToString(b[:])
 }
 
 func buildAttributes(entry *parser.ALBLogEntry) []OTelAttribute {
-	attrs := []OTelAttribute{}
+	attrs := newAttrBuffer()
 
 	// HTTP attributes
-	addAttr(&attrs, "http.request.method", entry.RequestVerb)
-	addIntAttr(&attrs, "http.response.status_code", entry.ELBStatusCode)
-	addInt64Attr(&attrs, "http.request.body.size", entry.ReceivedBytes)
-	addInt64Attr(&attrs, "http.response.body.size", entry.SentBytes)
-	addAttr(&attrs, "url.full", entry.RequestURL)
+	addAttr(attrs, "http.request.method", entry.RequestVerb)
+	addIntAttr(attrs, "http.response.status_code", entry.ELBStatusCode)
+	addInt64Attr(attrs, "http.request.body.size", entry.ReceivedBytes)
+	addInt64Attr(attrs, "http.response.body.size", entry.SentBytes)
+	requestURL := ActiveRedactor().URL(entry.RequestURL)
+	addAttr(attrs, "url.full", requestURL)
 
-	// Parse URL for additional attributes
-	urlAttrs := ParseRequestURL(entry.RequestURL)
-	for k, v := range urlAttrs {
-		addAttr(&attrs, k, v)
+	// Parse URL for additional attributes; the host only stands in for a missing domain_name
+	urlAttrs := ParseRequestURL(requestURL)
+	for _, k := range []string{"url.scheme", "url.path", "url.query"} {
+		addAttr(attrs, k, urlAttrs[k])
 	}
 
 	// Network attributes
-	addAttr(&attrs, "network.protocol.name", "http")
-	addAttr(&attrs, "network.protocol.version", entry.RequestProto)
+	addAttr(attrs, "network.protocol.name", "http")
+	if !IsPlaceholder(entry.RequestProto) {
+		addAttr(attrs, "network.protocol.version", httpProtocolVersion(entry.RequestProto))
+	}
 
 	// Client attributes
-	addAttr(&attrs, "client.address", entry.ClientIP)
-	addIntAttr(&attrs, "client.port", entry.ClientPort)
+	addAttr(attrs, "client.address", entry.ClientIP)
+	addIntAttr(attrs, "client.port", entry.ClientPort)
 
 	// Server attributes
-	addAttr(&attrs, "server.address", entry.DomainName)
-	addAttr(&attrs, "server.socket.address", entry.TargetIP)
-	addIntAttr(&attrs, "server.socket.port", entry.TargetPort)
+	if !IsPlaceholder(entry.DomainName) {
+		addAttr(attrs, "server.address", entry.DomainName)
+	} else {
+		addAttr(attrs, "server.address", urlAttrs["server.address"])
+	}
+	addAttr(attrs, "server.socket.address", entry.TargetIP)
+	addIntAttr(attrs, "server.socket.port", entry.TargetPort)
 
 	// User agent
-	addAttr(&attrs, "user_agent.original", entry.UserAgent)
+	addAttr(attrs, "user_agent.original", entry.UserAgent)
 
 	// TLS attributes
-	addAttr(&attrs, "tls.cipher_suite", entry.SSLCipher)
-	addAttr(&attrs, "tls.protocol.version", entry.SSLProtocol)
+	addAttr(attrs, "tls.cipher_suite", entry.SSLCipher)
+	addAttr(attrs, "tls.protocol.version", entry.SSLProtocol)
 
 	// AWS-specific attributes
-	addAttr(&attrs, "aws.alb.type", entry.Type)
-	addFloatAttr(&attrs, "aws.alb.request_processing_time", entry.RequestProcessingTime)
-	addFloatAttr(&attrs, "aws.alb.target_processing_time", entry.TargetProcessingTime)
-	addFloatAttr(&attrs, "aws.alb.response_processing_time", entry.ResponseProcessingTime)
-	addAttr(&attrs, "aws.alb.target_status_code", entry.TargetStatusCode)
-	addAttr(&attrs, "aws.alb.target_group_arn", entry.TargetGroupARN)
-	addAttr(&attrs, "aws.alb.trace_id", entry.TraceID)
-	addAttr(&attrs, "aws.alb.chosen_cert_arn", entry.ChosenCertARN)
-	addAttr(&attrs, "aws.alb.matched_rule_priority", entry.MatchedRulePriority)
-	addAttr(&attrs, "aws.alb.request_creation_time", entry.RequestCreationTime)
-	addAttr(&attrs, "aws.alb.actions_executed", entry.ActionsExecuted)
-	addAttr(&attrs, "aws.alb.redirect_url", entry.RedirectURL)
-	addAttr(&attrs, "aws.alb.lambda_error_reason", entry.LambdaErrorReason)
-	addAttr(&attrs, "aws.alb.target_port_list", entry.TargetPortList)
-	addAttr(&attrs, "aws.alb.target_status_code_list", entry.TargetStatusCodeList)
-	addAttr(&attrs, "aws.alb.classification", entry.Classification)
-	addAttr(&attrs, "aws.alb.classification_reason", entry.ClassificationReason)
-	addAttr(&attrs, "aws.alb.conn_trace_id", entry.ConnTraceID)
-
-	return attrs
+	addEnumAttr(attrs, "aws.alb.type", entry.Type, albTypes)
+	addFloatAttr(attrs, "aws.alb.request_processing_time", entry.RequestProcessingTime)
+	addFloatAttr(attrs, "aws.alb.target_processing_time", entry.TargetProcessingTime)
+	addFloatAttr(attrs, "aws.alb.response_processing_time", entry.ResponseProcessingTime)
+	addIntOrStringAttr(attrs, "aws.alb.target_status_code", entry.TargetStatusCode)
+	addAttr(attrs, "aws.alb.target_group_arn", entry.TargetGroupARN)
+	addAttr(attrs, "aws.alb.trace_id", entry.TraceID)
+	addAttr(attrs, "aws.alb.chosen_cert_arn", entry.ChosenCertARN)
+	addAttr(attrs, "aws.alb.matched_rule_priority", entry.MatchedRulePriority)
+	addAttr(attrs, "aws.alb.request_creation_time", entry.RequestCreationTime)
+	addAttr(attrs, "aws.alb.actions_executed", entry.ActionsExecuted)
+	addAttr(attrs, "aws.alb.redirect_url", entry.RedirectURL)
+	addAttr(attrs, "aws.alb.lambda_error_reason", entry.LambdaErrorReason)
+	addAttr(attrs, "aws.alb.target_port_list", entry.TargetPortList)
+	addAttr(attrs, "aws.alb.target_status_code_list", entry.TargetStatusCodeList)
+	addEnumAttr(attrs, "aws.alb.classification", entry.Classification, albClassifications)
+	addAttr(attrs, "aws.alb.classification_reason", entry.ClassificationReason)
+	addAttr(attrs, "aws.alb.conn_trace_id", entry.ConnTraceID)
+
+	return attrs.finish()
 }
 
 // Helper functions
@@ -300,7 +429,7 @@ func stringValue(s string) OTelAnyValue {
 }
 
 func intValue(i int) OTelAnyValue {
-	s := fmt.Sprintf("%d", i)
+	s := strconv.Itoa(i)
 	return OTelAnyValue{IntValue: &s}
 }
 
@@ -308,40 +437,51 @@ func floatValue(f float64) OTelAnyValue {
 	return OTelAnyValue{DoubleValue: &f}
 }
 
-func addAttr(attrs *[]OTelAttribute, key, value string) {
-	if value != "" && value != "-" {
-		*attrs = append(*attrs, OTelAttribute{
-			Key:   key,
-			Value: stringValue(value),
-		})
+// keepPlaceholders exports "-" field values as literal strings instead of omitting them
+var keepPlaceholders atomic.Bool
+
+// SetKeepPlaceholders makes the converters export "-" placeholder values (AWS's "no value")
+// as attributes; by default they are omitted like empty values
+func SetKeepPlaceholders(keep bool) {
+	keepPlaceholders.Store(keep)
+}
+
+// IsPlaceholder reports whether a field value stands for "no value" and is not exported
+func IsPlaceholder(value string) bool {
+	return value == "" || (value == "-" && !keepPlaceholders.Load())
+}
+
+func addAttr(attrs *attrBuffer, key, value string) {
+	if !IsPlaceholder(value) {
+		attrs.addString(key, value)
 	}
 }
 
-func addIntAttr(attrs *[]OTelAttribute, key string, value int) {
+func addIntAttr(attrs *attrBuffer, key string, value int) {
 	if value != 0 {
-		*attrs = append(*attrs, OTelAttribute{
-			Key:   key,
-			Value: intValue(value),
-		})
+		attrs.addInt(key, strconv.Itoa(value))
 	}
 }
 
-func addInt64Attr(attrs *[]OTelAttribute, key string, value int64) {
+func addInt64Attr(attrs *attrBuffer, key string, value int64) {
 	if value != 0 {
-		s := fmt.Sprintf("%d", value)
-		*attrs = append(*attrs, OTelAttribute{
-			Key:   key,
-			Value: OTelAnyValue{IntValue: &s},
-		})
+		attrs.addInt(key, strconv.FormatInt(value, 10))
 	}
 }
 
-func addFloatAttr(attrs *[]OTelAttribute, key string, value float64) {
+// addIntOrStringAttr adds a numeric field as an int and falls back to the string for values
+// such as a list of codes
+func addIntOrStringAttr(attrs *attrBuffer, key, value string) {
+	if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+		addInt64Attr(attrs, key, n)
+		return
+	}
+	addAttr(attrs, key, value)
+}
+
+func addFloatAttr(attrs *attrBuffer, key string, value float64) {
 	if value != 0 {
-		*attrs = append(*attrs, OTelAttribute{
-			Key:   key,
-			Value: floatValue(value),
-		})
+		attrs.addDouble(key, value)
 	}
 }
 
@@ -359,7 +499,7 @@ func ConvertNLBToOTel(entry *parser.NLBLogEntry) OTelLogRecord {
 	severityNumber := 9
 
 	// Build body
-	bodyContent := fmt.Sprintf("%s log for %s", entry.Type, entry.ELB)
+	bodyContent := entry.Type + " log for " + entry.ELB
 
 	// Generate trace and span IDs
 	// NLB doesn't have X-Amzn-Trace-Id in valid log fields usually, but sometimes has TraceID?
@@ -369,54 +509,55 @@ func ConvertNLBToOTel(entry *parser.NLBLogEntry) OTelLogRecord {
 	spanID := generateSpanID()
 
 	return OTelLogRecord{
-		TimeUnixNano:   fmt.Sprintf("%d", timeUnixNano),
-		SeverityNumber: severityNumber,
-		SeverityText:   severityText,
-		Body:           map[string]string{"stringValue": bodyContent},
-		Attributes:     attributes,
-		TraceID:        traceID,
-		SpanID:         spanID,
+		TimeUnixNano:         strconv.FormatInt(timeUnixNano, 10),
+		ObservedTimeUnixNano: observedNow(),
+		SeverityNumber:       severityNumber,
+		SeverityText:         severityText,
+		Body:                 stringValue(bodyContent),
+		Attributes:           attributes,
+		TraceID:              traceID,
+		SpanID:               spanID,
 	}
 }
 
 func buildAttributesNLB(entry *parser.NLBLogEntry) []OTelAttribute {
-	attrs := []OTelAttribute{}
+	attrs := newAttrBuffer()
 
 	// Transport attributes
-	addAttr(&attrs, "network.transport", "tcp") // Mostly TCP for NLB
-	addAttr(&attrs, "network.protocol.name", entry.Type)
-	addAttr(&attrs, "network.protocol.version", entry.Version)
+	addAttr(attrs, "network.transport", "tcp") // Mostly TCP for NLB
+	addAttr(attrs, "network.protocol.name", entry.Type)
+	addAttr(attrs, "network.protocol.version", entry.Version)
 
 	// Client attributes
-	addAttr(&attrs, "client.address", entry.ClientIP)
-	addIntAttr(&attrs, "client.port", entry.ClientPort)
+	addAttr(attrs, "client.address", entry.ClientIP)
+	addIntAttr(attrs, "client.port", entry.ClientPort)
 
 	// Server attributes
-	addAttr(&attrs, "server.address", entry.TargetIP)
-	addIntAttr(&attrs, "server.port", entry.TargetPort)
+	addAttr(attrs, "server.address", entry.TargetIP)
+	addIntAttr(attrs, "server.port", entry.TargetPort)
 
 	// TLS attributes
-	addAttr(&attrs, "tls.cipher_suite", entry.TLSCipher)
-	addAttr(&attrs, "tls.protocol.version", entry.TLSProtocolVersion)
-	addAttr(&attrs, "tls.server.name", entry.DomainName)
+	addAttr(attrs, "tls.cipher_suite", entry.TLSCipher)
+	addAttr(attrs, "tls.protocol.version", entry.TLSProtocolVersion)
+	addAttr(attrs, "tls.server.name", entry.DomainName)
 
 	// AWS-specific attributes
-	addAttr(&attrs, "aws.nlb.type", entry.Type)
-	addAttr(&attrs, "aws.nlb.listener_id", entry.ListenerID)
-	addFloatAttr(&attrs, "aws.nlb.connection_time", entry.ConnectionTime)
-	addFloatAttr(&attrs, "aws.nlb.tls_handshake_time", entry.TLSHandshakeTime)
-	addInt64Attr(&attrs, "aws.nlb.received_bytes", entry.ReceivedBytes)
-	addInt64Attr(&attrs, "aws.nlb.sent_bytes", entry.SentBytes)
-	addAttr(&attrs, "aws.nlb.incoming_tls_alert", entry.IncomingTLSAlert)
-	addAttr(&attrs, "aws.nlb.chosen_cert_arn", entry.ChosenCertARN)
-	addAttr(&attrs, "aws.nlb.chosen_cert_serial", entry.ChosenCertSerial)
-	addAttr(&attrs, "aws.nlb.tls_named_group", entry.TLSNamedGroup)
-	addAttr(&attrs, "aws.nlb.alpn_frontend_protocol", entry.ALPNFrontEndProtocol)
-	addAttr(&attrs, "aws.nlb.alpn_backend_protocol", entry.ALPNBackEndProtocol)
-	addAttr(&attrs, "aws.nlb.alpn_client_preference_list", entry.ALPNClientPreferenceList)
-	addAttr(&attrs, "aws.nlb.tls_connection_creation_time", entry.TLSConnectionCreationTime)
-
-	return attrs
+	addEnumAttr(attrs, "aws.nlb.type", entry.Type, nlbTypes)
+	addAttr(attrs, "aws.nlb.listener_id", entry.ListenerID)
+	addFloatAttr(attrs, "aws.nlb.connection_time", entry.ConnectionTime)
+	addFloatAttr(attrs, "aws.nlb.tls_handshake_time", entry.TLSHandshakeTime)
+	addInt64Attr(attrs, "aws.nlb.received_bytes", entry.ReceivedBytes)
+	addInt64Attr(attrs, "aws.nlb.sent_bytes", entry.SentBytes)
+	addAttr(attrs, "aws.nlb.incoming_tls_alert", entry.IncomingTLSAlert)
+	addAttr(attrs, "aws.nlb.chosen_cert_arn", entry.ChosenCertARN)
+	addAttr(attrs, "aws.nlb.chosen_cert_serial", entry.ChosenCertSerial)
+	addAttr(attrs, "aws.nlb.tls_named_group", entry.TLSNamedGroup)
+	addAttr(attrs, "aws.nlb.alpn_frontend_protocol", entry.ALPNFrontEndProtocol)
+	addAttr(attrs, "aws.nlb.alpn_backend_protocol", entry.ALPNBackEndProtocol)
+	addAttr(attrs, "aws.nlb.alpn_client_preference_list", entry.ALPNClientPreferenceList)
+	addAttr(attrs, "aws.nlb.tls_connection_creation_time", entry.TLSConnectionCreationTime)
+
+	return attrs.finish()
 }
 
 // ExtractResourceAttributesNLB extracts cloud resource attributes from NLB entry
@@ -450,12 +591,11 @@ func ExtractResourceAttributesNLB(entry *parser.NLBLogEntry) []OTelAttribute {
 
 // generateTraceID generates a random 16-byte hex string (32 chars)
 func generateTraceID() string {
-	b := make([]byte, 16)
-	_, err := rand.Read(b)
-	if err != nil {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
 		return fmt.Sprintf("%032x", time.Now().UnixNano())
 	}
-	return fmt.Sprintf("%x", b)
+	return hex.EncodeToString(b[:])
 }
 
 // ConvertWAFToOTel converts WAF log entry to OTLP log record
@@ -472,13 +612,16 @@ func ConvertWAFToOTel(entry *parser.WAFLogEntry) OTelLogRecord {
 		severityNumber = 13
 	}
 
-	bodyContent := fmt.Sprintf("%s %s %s", entry.HTTPRequest.HTTPMethod, entry.HTTPRequest.URI, entry.Action)
+	bodyContent := entry.HTTPRequest.HTTPMethod + " " + entry.HTTPRequest.URI + " " + entry.Action
 
 	traceID := ""
+	var flags uint32
 	// Try to extract Trace ID from headers
 	for _, h := range entry.HTTPRequest.Headers {
 		if strings.EqualFold(h.Name, "X-Amzn-Trace-Id") {
-			traceID = ParseTraceID(h.Value)
+			if traceID = ParseTraceID(h.Value); traceID != "" {
+				flags = ParseTraceFlags(h.Value)
+			}
 			break
 		}
 	}
@@ -495,51 +638,53 @@ func ConvertWAFToOTel(entry *parser.WAFLogEntry) OTelLogRecord {
 	spanID := generateSpanID()
 
 	return OTelLogRecord{
-		TimeUnixNano:   fmt.Sprintf("%d", timeUnixNano),
-		SeverityNumber: severityNumber,
-		SeverityText:   severityText,
-		Body:           map[string]string{"stringValue": bodyContent},
-		Attributes:     attributes,
-		TraceID:        traceID,
-		SpanID:         spanID,
+		TimeUnixNano:         strconv.FormatInt(timeUnixNano, 10),
+		ObservedTimeUnixNano: observedNow(),
+		SeverityNumber:       severityNumber,
+		SeverityText:         severityText,
+		Body:                 stringValue(bodyContent),
+		Attributes:           attributes,
+		TraceID:              traceID,
+		SpanID:               spanID,
+		Flags:                flags,
 	}
 }
 
 func buildAttributesWAF(entry *parser.WAFLogEntry) []OTelAttribute {
-	attrs := []OTelAttribute{}
+	attrs := newAttrBuffer()
 
 	// WAF Attributes
-	addAttr(&attrs, "aws.waf.web_acl_id", entry.WebACLID)
-	addAttr(&attrs, "aws.waf.terminating_rule_id", entry.TerminatingRuleID)
-	addAttr(&attrs, "aws.waf.terminating_rule_type", entry.TerminatingRuleType)
-	addAttr(&attrs, "aws.waf.action", entry.Action)
-	addAttr(&attrs, "aws.waf.http_source_name", entry.HTTPSourceName)
-	addAttr(&attrs, "aws.waf.http_source_id", entry.HTTPSourceID)
+	addAttr(attrs, "aws.waf.web_acl_id", entry.WebACLID)
+	addAttr(attrs, "aws.waf.terminating_rule_id", entry.TerminatingRuleID)
+	addEnumAttr(attrs, "aws.waf.terminating_rule_type", entry.TerminatingRuleType, wafTerminatingRuleTypes)
+	addEnumAttr(attrs, "aws.waf.action", entry.Action, wafActions)
+	addAttr(attrs, "aws.waf.http_source_name", entry.HTTPSourceName)
+	addAttr(attrs, "aws.waf.http_source_id", entry.HTTPSourceID)
 
 	// HTTP Attributes
 	req := entry.HTTPRequest
-	addAttr(&attrs, "http.request.method", req.HTTPMethod)
-	addAttr(&attrs, "url.path", req.URI)
-	addAttr(&attrs, "url.query", req.Args)
-	addAttr(&attrs, "network.protocol.version", req.HTTPVersion)
-	addAttr(&attrs, "client.address", req.ClientIP)
+	addAttr(attrs, "http.request.method", req.HTTPMethod)
+	addAttr(attrs, "url.path", req.URI)
+	addAttr(attrs, "url.query", ActiveRedactor().Query(req.Args))
+	addAttr(attrs, "network.protocol.version", req.HTTPVersion)
+	addAttr(attrs, "client.address", req.ClientIP)
 
 	// User Agent from headers
 	for _, h := range req.Headers {
 		if strings.EqualFold(h.Name, "User-Agent") {
-			addAttr(&attrs, "user_agent.original", h.Value)
+			addAttr(attrs, "user_agent.original", h.Value)
 		}
 		if strings.EqualFold(h.Name, "Host") {
-			addAttr(&attrs, "server.address", h.Value)
+			addAttr(attrs, "server.address", h.Value)
 		}
 	}
 
 	// Additional Details
-	addAttr(&attrs, "client.geo.country_iso_code", req.Country)
-	addInt64Attr(&attrs, "http.request.body.size", entry.RequestBodySize)
-	addInt64Attr(&attrs, "aws.waf.request_body_size_inspected", entry.RequestBodySizeInspected)
-	addAttr(&attrs, "tls.client.ja3", entry.JA3Fingerprint)
-	addAttr(&attrs, "tls.client.ja4", entry.JA4Fingerprint)
+	addAttr(attrs, "client.geo.country_iso_code", req.Country)
+	addInt64Attr(attrs, "http.request.body.size", entry.RequestBodySize)
+	addInt64Attr(attrs, "aws.waf.request_body_size_inspected", entry.RequestBodySizeInspected)
+	addAttr(attrs, "tls.client.ja3", entry.JA3Fingerprint)
+	addAttr(attrs, "tls.client.ja4", entry.JA4Fingerprint)
 
 	if len(entry.Labels) > 0 {
 		var labels []string
@@ -551,7 +696,7 @@ func buildAttributesWAF(entry *parser.WAFLogEntry) []OTelAttribute {
 		// For simplicity/compatibility, joining with comma or JSON string is often used.
 		// Using JSON for robust array representation.
 		lblBytes, _ := json.Marshal(labels)
-		addAttr(&attrs, "aws.waf.labels", string(lblBytes))
+		addAttr(attrs, "aws.waf.labels", string(lblBytes))
 	}
 
 	// Collect all processed rules
@@ -559,11 +704,11 @@ func buildAttributesWAF(entry *parser.WAFLogEntry) []OTelAttribute {
 	if len(processedRules) > 0 {
 		jsonBytes, err := json.Marshal(processedRules)
 		if err == nil {
-			addAttr(&attrs, "aws.waf.processed_rules", string(jsonBytes))
+			addAttr(attrs, "aws.waf.processed_rules", string(jsonBytes))
 		}
 	}
 
-	return attrs
+	return attrs.finish()
 }
 
 type ProcessedRule struct {
@@ -619,7 +764,7 @@ func collectProcessedRules(entry *parser.WAFLogEntry) []ProcessedRule {
 func ConvertCloudFrontToOTel(entry *parser.CloudFrontLogEntry) OTelLogRecord {
 	// Convert timestamp
 	// Date: 2019-12-04, Time: 21:02:31
-	timeStr := fmt.Sprintf("%sT%sZ", entry.Date, entry.Time)
+	timeStr := entry.Date + "T" + entry.Time + "Z"
 	t, err := time.Parse(time.RFC3339, timeStr)
 	var timeUnixNano int64
 	if err != nil {
@@ -630,17 +775,9 @@ func ConvertCloudFrontToOTel(entry *parser.CloudFrontLogEntry) OTelLogRecord {
 
 	attributes := buildAttributesCloudFront(entry)
 
-	severityText := "INFO"
-	severityNumber := 9
-	if entry.SCStatus >= 500 {
-		severityText = "ERROR"
-		severityNumber = 17
-	} else if entry.SCStatus >= 400 {
-		severityText = "WARN"
-		severityNumber = 13
-	}
+	severity := SeverityForStatus(entry.SCStatus)
 
-	bodyContent := fmt.Sprintf("%s %s %d", entry.CSMethod, entry.CSURIStem, entry.SCStatus)
+	bodyContent := entry.CSMethod + " " + entry.CSURIStem + " " + strconv.Itoa(entry.SCStatus)
 
 	traceID := ""
 	// Use x-edge-request-id as trace ID if it fits format, but it's base64 usually.
@@ -650,67 +787,73 @@ func ConvertCloudFrontToOTel(entry *parser.CloudFrontLogEntry) OTelLogRecord {
 	spanID := generateSpanID()
 
 	return OTelLogRecord{
-		TimeUnixNano:   fmt.Sprintf("%d", timeUnixNano),
-		SeverityNumber: severityNumber,
-		SeverityText:   severityText,
-		Body:           map[string]string{"stringValue": bodyContent},
-		Attributes:     attributes,
-		TraceID:        traceID,
-		SpanID:         spanID,
+		TimeUnixNano:         strconv.FormatInt(timeUnixNano, 10),
+		ObservedTimeUnixNano: observedNow(),
+		SeverityNumber:       severity.Number,
+		SeverityText:         severity.Text,
+		Body:                 stringValue(bodyContent),
+		Attributes:           attributes,
+		TraceID:              traceID,
+		SpanID:               spanID,
 	}
 }
 
 func buildAttributesCloudFront(entry *parser.CloudFrontLogEntry) []OTelAttribute {
-	attrs := []OTelAttribute{}
+	attrs := newAttrBuffer()
 
 	// HTTP Attributes
-	addAttr(&attrs, "http.request.method", entry.CSMethod)
-	addIntAttr(&attrs, "http.response.status_code", entry.SCStatus)
-	addAttr(&attrs, "url.path", entry.CSURIStem)
-	addAttr(&attrs, "url.query", entry.CSURIQuery)
-	addAttr(&attrs, "network.protocol.version", entry.CSProtocolVersion) // e.g. HTTP/2.0
-	addAttr(&attrs, "network.protocol.name", entry.CSProtocol)           // http/https
+	addAttr(attrs, "http.request.method", entry.CSMethod)
+	addIntAttr(attrs, "http.response.status_code", entry.SCStatus)
+	addAttr(attrs, "url.path", entry.CSURIStem)
+	addAttr(attrs, "url.query", ActiveRedactor().Query(entry.CSURIQuery))
+	addEnumAttr(attrs, "url.scheme", entry.CSProtocol, cloudFrontProtocols) // http/https/ws/wss
+	addAttr(attrs, "network.protocol.name", "http")
+	if !IsPlaceholder(entry.CSProtocolVersion) {
+		addAttr(attrs, "network.protocol.version", httpProtocolVersion(entry.CSProtocolVersion)) // e.g. HTTP/2.0 -> 2
+	}
 
 	// User Agent
 	decodedUA, err := url.QueryUnescape(entry.CSUserAgent)
 	if err == nil {
-		addAttr(&attrs, "user_agent.original", decodedUA)
+		addAttr(attrs, "user_agent.original", decodedUA)
 	} else {
-		addAttr(&attrs, "user_agent.original", entry.CSUserAgent)
+		addAttr(attrs, "user_agent.original", entry.CSUserAgent)
 	}
 
 	// Client
-	addAttr(&attrs, "client.address", entry.CIP)
-	addIntAttr(&attrs, "client.port", entry.CPort)
+	addAttr(attrs, "client.address", entry.CIP)
+	addIntAttr(attrs, "client.port", entry.CPort)
 
 	// Server
-	addAttr(&attrs, "server.address", entry.CSHost) // Distribution domain or CNAME
+	addAttr(attrs, "server.address", entry.CSHost) // Distribution domain or CNAME
 
 	// AWS CloudFront Specific
-	addAttr(&attrs, "aws.cloudfront.edge_location", entry.XEdgeLocation)
-	addInt64Attr(&attrs, "aws.cloudfront.sc_bytes", entry.SCBytes)
-	addInt64Attr(&attrs, "aws.cloudfront.cs_bytes", entry.CSBytes)
-	addAttr(&attrs, "aws.cloudfront.result_type", entry.XEdgeResultType)
-	addAttr(&attrs, "aws.cloudfront.request_id", entry.XEdgeRequestID)
-	addAttr(&attrs, "aws.cloudfront.host_header", entry.XHostHeader)
-	addFloatAttr(&attrs, "aws.cloudfront.time_taken", entry.TimeTaken)
-	addAttr(&attrs, "aws.cloudfront.x_forwarded_for", entry.XForwardedFor)
-	addAttr(&attrs, "aws.cloudfront.ssl_protocol", entry.SSLProtocol)
-	addAttr(&attrs, "aws.cloudfront.ssl_cipher", entry.SSLCipher)
-	addAttr(&attrs, "aws.cloudfront.response_result_type", entry.XEdgeResponseResultType)
-	addAttr(&attrs, "aws.cloudfront.fle_status", entry.FLEStatus)
-	addIntAttr(&attrs, "aws.cloudfront.fle_encrypted_fields", entry.FLEEncryptedFields)
-	addFloatAttr(&attrs, "aws.cloudfront.time_to_first_byte", entry.TimeToFirstByte)
-	addAttr(&attrs, "aws.cloudfront.detailed_result_type", entry.XEdgeDetailedResultType)
-	addAttr(&attrs, "aws.cloudfront.sc_content_type", entry.SCContentType)
-	addInt64Attr(&attrs, "aws.cloudfront.sc_content_len", entry.SCContentLen)
-	addAttr(&attrs, "aws.cloudfront.sc_range_start", entry.SCRangeStart)
-	addAttr(&attrs, "aws.cloudfront.sc_range_end", entry.SCRangeEnd)
-
-	// Cookie (often contains sensitive info, maybe mask or exclude? AWS logs it)
-	// addAttr(&attrs, "aws.cloudfront.cookie", entry.CSCookie)
-
-	return attrs
+	addAttr(attrs, "aws.cloudfront.edge_location", entry.XEdgeLocation)
+	addInt64Attr(attrs, "aws.cloudfront.sc_bytes", entry.SCBytes)
+	addInt64Attr(attrs, "aws.cloudfront.cs_bytes", entry.CSBytes)
+	addEnumAttr(attrs, "aws.cloudfront.result_type", entry.XEdgeResultType, cloudFrontResultTypes)
+	addAttr(attrs, "aws.cloudfront.request_id", entry.XEdgeRequestID)
+	addAttr(attrs, "aws.cloudfront.host_header", entry.XHostHeader)
+	addFloatAttr(attrs, "aws.cloudfront.time_taken", entry.TimeTaken)
+	addAttr(attrs, "aws.cloudfront.x_forwarded_for", entry.XForwardedFor)
+	addAttr(attrs, "aws.cloudfront.ssl_protocol", entry.SSLProtocol)
+	addAttr(attrs, "aws.cloudfront.ssl_cipher", entry.SSLCipher)
+	addEnumAttr(attrs, "aws.cloudfront.response_result_type", entry.XEdgeResponseResultType, cloudFrontResultTypes)
+	addAttr(attrs, "aws.cloudfront.fle_status", entry.FLEStatus)
+	addIntAttr(attrs, "aws.cloudfront.fle_encrypted_fields", entry.FLEEncryptedFields)
+	addFloatAttr(attrs, "aws.cloudfront.time_to_first_byte", entry.TimeToFirstByte)
+	addEnumAttr(attrs, "aws.cloudfront.detailed_result_type", entry.XEdgeDetailedResultType, cloudFrontDetailedResultTypes)
+	addAttr(attrs, "aws.cloudfront.sc_content_type", entry.SCContentType)
+	addInt64Attr(attrs, "aws.cloudfront.sc_content_len", entry.SCContentLen)
+	addAttr(attrs, "aws.cloudfront.sc_range_start", entry.SCRangeStart)
+	addAttr(attrs, "aws.cloudfront.sc_range_end", entry.SCRangeEnd)
+
+	// Cookies carry sessions, so they are only exported through the redactor
+	if r := ActiveRedactor(); r != nil {
+		addAttr(attrs, "aws.cloudfront.cookie", r.Cookie(entry.CSCookie))
+	}
+
+	return attrs.finish()
 }
 
 // ExtractResourceAttributesCloudFront extracts cloud resource attributes from CloudFront entry
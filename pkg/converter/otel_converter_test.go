@@ -2,6 +2,7 @@ package converter
 
 import (
 	"encoding/json"
+	"strconv"
 	"testing"
 
 	"github.com/pixelvide/otel-aws-log-parser/pkg/parser"
@@ -28,6 +29,26 @@ func TestParseTraceID(t *testing.T) {
 			input:    "-",
 			expected: "",
 		},
+		{
+			name:     "Full header with Self and Parent",
+			input:    "Self=1-67891234-12456789abcdef012345678;Root=1-5759E988-BD862E3FE1BE46A994272793;Parent=53995c3f42cd8ad8;Sampled=1",
+			expected: "5759e988bd862e3fe1be46a994272793",
+		},
+		{
+			name:     "Bare X-Ray ID",
+			input:    "1-58337262-36d228ad5d99923122bbe354",
+			expected: "5833726236d228ad5d99923122bbe354",
+		},
+		{
+			name:     "Unknown version",
+			input:    "Root=2-58337262-36d228ad5d99923122bbe354",
+			expected: "",
+		},
+		{
+			name:     "Not hex",
+			input:    "Root=1-58337262-36d228ad5d99923122bbe35z",
+			expected: "",
+		},
 	}
 
 	for _, tt := range tests {
@@ -40,6 +61,25 @@ func TestParseTraceID(t *testing.T) {
 	}
 }
 
+func TestParseTraceFlags(t *testing.T) {
+	tests := []struct {
+		input string
+		want  uint32
+	}{
+		{"Root=1-58337262-36d228ad5d99923122bbe354;Sampled=1", TraceFlagsSampled},
+		{"Self=1-67891234-12456789abcdef012345678;Root=1-5759E988-BD862E3FE1BE46A994272793;sampled=1", TraceFlagsSampled},
+		{"Root=1-58337262-36d228ad5d99923122bbe354;Sampled=0", 0},
+		{"Root=1-58337262-36d228ad5d99923122bbe354", 0},
+		{"-", 0},
+	}
+
+	for _, tt := range tests {
+		if got := ParseTraceFlags(tt.input); got != tt.want {
+			t.Errorf("ParseTraceFlags(%q) = %d, want %d", tt.input, got, tt.want)
+		}
+	}
+}
+
 func TestParseRequestURL(t *testing.T) {
 	tests := []struct {
 		name string
@@ -50,19 +90,20 @@ func TestParseRequestURL(t *testing.T) {
 			name: "HTTPS URL with query",
 			url:  "https://example.com:443/api/test?foo=bar",
 			want: map[string]string{
-				"http.scheme": "https",
-				"url.path":    "/api/test",
-				"url.query":   "foo=bar",
-				"http.target": "/api/test?foo=bar",
+				"url.scheme":     "https",
+				"server.address": "example.com",
+				"url.path":       "/api/test",
+				"url.query":      "foo=bar",
 			},
 		},
 		{
 			name: "HTTP URL without query",
 			url:  "http://example.com:80/",
 			want: map[string]string{
-				"http.scheme": "http",
-				"url.path":    "/",
-				"http.target": "/",
+				"url.scheme":     "http",
+				"server.address": "example.com",
+				"url.path":       "/",
+				"url.query":      "",
 			},
 		},
 	}
@@ -120,6 +161,18 @@ func TestConvertToOTel(t *testing.T) {
 	if record.TraceID != "5833726236d228ad5d99923122bbe354" {
 		t.Errorf("TraceID = %q, want 5833726236d228ad5d99923122bbe354", record.TraceID)
 	}
+	if len(record.SpanID) != 16 {
+		t.Errorf("SpanID = %q, want 16 hex chars", record.SpanID)
+	}
+	if raw, ok := findAttr(record.Attributes, "aws.alb.trace_id"); !ok || *raw.Value.StringValue != entry.TraceID {
+		t.Error("aws.alb.trace_id does not keep the raw trace ID")
+	}
+
+	// Without a trace ID the record carries no span ID either
+	entry.TraceID = "-"
+	if record := ConvertToOTel(entry); record.TraceID != "" || record.SpanID != "" {
+		t.Errorf("IDs without trace_id = %q/%q, want empty", record.TraceID, record.SpanID)
+	}
 
 	// Verify some attributes exist
 	foundMethod := false
@@ -308,3 +361,169 @@ func TestConvertWAFToOTel_ProcessedRules(t *testing.T) {
 		}
 	}
 }
+
+func TestHTTPSemanticConventions(t *testing.T) {
+	alb := ConvertToOTel(&parser.ALBLogEntry{
+		Time:                 "2025-12-04T00:55:01.294082Z",
+		ELBStatusCode:        502,
+		TargetStatusCode:     "-",
+		TargetProcessingTime: 0.25,
+		RequestVerb:          "POST",
+		RequestURL:           "https://api.example.com:443/v1/items?limit=10",
+		RequestProto:         "HTTP/2.0",
+		ClientIP:             "192.0.2.10",
+		UserAgent:            "curl/8.0",
+		DomainName:           "-",
+	})
+	cf := ConvertCloudFrontToOTel(&parser.CloudFrontLogEntry{
+		Date:              "2024-01-01",
+		Time:              "00:00:00",
+		CSMethod:          "GET",
+		SCStatus:          404,
+		CSURIStem:         "/index.html",
+		CSURIQuery:        "-",
+		CSProtocol:        "https",
+		CSProtocolVersion: "HTTP/1.1",
+		CIP:               "198.51.100.7",
+		CSHost:            "d111111abcdef8.cloudfront.net",
+		CSUserAgent:       "Mozilla/5.0%20(X11)",
+		TimeTaken:         0.002,
+	})
+
+	tests := []struct {
+		name   string
+		record OTelLogRecord
+		key    string
+		want   string
+		kind   string
+	}{
+		{"ALB method", alb, "http.request.method", "POST", "string"},
+		{"ALB status", alb, "http.response.status_code", "502", "int"},
+		{"ALB scheme", alb, "url.scheme", "https", "string"},
+		{"ALB path", alb, "url.path", "/v1/items", "string"},
+		{"ALB query", alb, "url.query", "limit=10", "string"},
+		{"ALB server from URL", alb, "server.address", "api.example.com", "string"},
+		{"ALB client", alb, "client.address", "192.0.2.10", "string"},
+		{"ALB user agent", alb, "user_agent.original", "curl/8.0", "string"},
+		{"ALB protocol version", alb, "network.protocol.version", "2", "string"},
+		{"ALB latency", alb, "aws.alb.target_processing_time", "0.25", "double"},
+		{"CloudFront method", cf, "http.request.method", "GET", "string"},
+		{"CloudFront status", cf, "http.response.status_code", "404", "int"},
+		{"CloudFront scheme", cf, "url.scheme", "https", "string"},
+		{"CloudFront path", cf, "url.path", "/index.html", "string"},
+		{"CloudFront server", cf, "server.address", "d111111abcdef8.cloudfront.net", "string"},
+		{"CloudFront client", cf, "client.address", "198.51.100.7", "string"},
+		{"CloudFront user agent", cf, "user_agent.original", "Mozilla/5.0 (X11)", "string"},
+		{"CloudFront protocol version", cf, "network.protocol.version", "1.1", "string"},
+		{"CloudFront latency", cf, "aws.cloudfront.time_taken", "0.002", "double"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attr, ok := findAttr(tt.record.Attributes, tt.key)
+			if !ok {
+				t.Fatalf("%s not set", tt.key)
+			}
+			var got, kind string
+			switch v := attr.Value; {
+			case v.StringValue != nil:
+				got, kind = *v.StringValue, "string"
+			case v.IntValue != nil:
+				got, kind = *v.IntValue, "int"
+			case v.DoubleValue != nil:
+				got, kind = strconv.FormatFloat(*v.DoubleValue, 'f', -1, 64), "double"
+			}
+			if got != tt.want || kind != tt.kind {
+				t.Errorf("%s = %s(%s), want %s(%s)", tt.key, kind, got, tt.kind, tt.want)
+			}
+		})
+	}
+
+	// Deprecated keys are gone and "-" fields are omitted
+	for _, key := range []string{"http.scheme", "http.target", "aws.alb.target_status_code"} {
+		if _, ok := findAttr(alb.Attributes, key); ok {
+			t.Errorf("ALB %s set, want it omitted", key)
+		}
+	}
+	if _, ok := findAttr(cf.Attributes, "url.query"); ok {
+		t.Error("CloudFront url.query set for \"-\"")
+	}
+	if code, ok := findAttr(ConvertToOTel(&parser.ALBLogEntry{TargetStatusCode: "200"}).Attributes, "aws.alb.target_status_code"); !ok || code.Value.IntValue == nil {
+		t.Error("aws.alb.target_status_code is not an int")
+	}
+}
+
+func TestPlaceholderAttributes(t *testing.T) {
+	alb := &parser.ALBLogEntry{
+		Time:             "2025-12-04T00:55:01.294082Z",
+		RequestVerb:      "GET",
+		RequestURL:       "-",
+		RequestProto:     "-",
+		UserAgent:        "-",
+		SSLCipher:        "-",
+		TargetStatusCode: "-",
+		DomainName:       "-",
+		Classification:   "-",
+	}
+	cf := &parser.CloudFrontLogEntry{Date: "2024-01-01", Time: "00:00:00", CSMethod: "GET", CSURIQuery: "-", XForwardedFor: "-", CSProtocolVersion: "-"}
+
+	placeholders := func(attrs []OTelAttribute) []string {
+		var keys []string
+		for _, attr := range attrs {
+			if attr.Value.StringValue != nil && *attr.Value.StringValue == "-" {
+				keys = append(keys, attr.Key)
+			}
+		}
+		return keys
+	}
+
+	for name, attrs := range map[string][]OTelAttribute{"ALB": ConvertToOTel(alb).Attributes, "CloudFront": ConvertCloudFrontToOTel(cf).Attributes} {
+		if keys := placeholders(attrs); len(keys) > 0 {
+			t.Errorf("%s exported placeholder attributes %v", name, keys)
+		}
+	}
+
+	SetKeepPlaceholders(true)
+	defer SetKeepPlaceholders(false)
+	if keys := placeholders(ConvertToOTel(alb).Attributes); len(keys) == 0 {
+		t.Error("ALB placeholders omitted with SetKeepPlaceholders(true)")
+	}
+}
+
+const (
+	benchALBLine = `http 2018-07-02T22:23:00.186641Z app/my-loadbalancer/50dc6c495c0c9188 192.168.131.39:2817 10.0.0.1:80 0.000 0.001 0.000 200 200 34 366 "GET http://www.example.com:80/ HTTP/1.1" "curl/7.46.0" - - arn:aws:elasticloadbalancing:us-east-2:123456789012:targetgroup/my-targets/73e2d6bc24d8a067 "Root=1-58337262-36d228ad5d99923122bbe354" "www.example.com" "-" 100 2018-07-02T22:22:48.364000Z "forward" "-" "-" "10.0.0.1:80" "200" "-" "-" -`
+	benchNLBLine = "tls 2.0 2023-10-01T00:00:00.000000Z app/net-lb/1234567890abcdef listener/net-lb/1234567890abcdef/1234567890abcdef 1.2.3.4:12345 5.6.7.8:80 0.001 0.002 100 200 - arn:aws:acm:us-east-1:123456789012:certificate/12345678-1234-1234-1234-123456789012 - ECDHE-RSA-AES128-GCM-SHA256 TLSv1.2 - example.com h2 - - 2023-10-01T00:00:00.000000Z"
+	benchWAFLine = `{"timestamp":1683355580000,"formatVersion":1,"webaclId":"arn:aws:wafv2:eu-west-3:111122223333:regional/webacl/TEST-WEBACL/123","terminatingRuleId":"Default_Action","terminatingRuleType":"REGULAR","action":"ALLOW","httpSourceName":"APIGW","httpSourceId":"EXAMPLE11","labels":[{"name":"awswaf:managed:aws:bot-control:bot:category:http_library"}],"httpRequest":{"clientIp":"1.2.3.4","country":"US","headers":[{"name":"Host","value":"example.com"}],"uri":"/valid","args":"","httpVersion":"HTTP/1.1","httpMethod":"GET","requestId":"request-2"}}`
+)
+
+func BenchmarkConvertToOTel(b *testing.B) {
+	entry, err := parser.ParseLogLine(benchALBLine)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = ConvertToOTel(entry)
+	}
+}
+
+func BenchmarkConvertNLBToOTel(b *testing.B) {
+	entry, err := parser.ParseNLBLogLine(benchNLBLine)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = ConvertNLBToOTel(entry)
+	}
+}
+
+func BenchmarkConvertWAFToOTel(b *testing.B) {
+	entry, err := parser.ParseWAFLogLine(benchWAFLine)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = ConvertWAFToOTel(entry)
+	}
+}
@@ -0,0 +1,148 @@
+package converter
+
+import (
+	"sort"
+	"strconv"
+)
+
+// OTLPMetricsPayload represents the complete OTLP/JSON metrics payload
+type OTLPMetricsPayload struct {
+	ResourceMetrics []ResourceMetric `json:"resourceMetrics"`
+}
+
+// ResourceMetric represents a resource with scope metrics
+type ResourceMetric struct {
+	Resource     ResourceAttributes `json:"resource"`
+	ScopeMetrics []ScopeMetric      `json:"scopeMetrics"`
+}
+
+// ScopeMetric represents a scope with metrics
+type ScopeMetric struct {
+	Scope   Scope    `json:"scope"`
+	Metrics []Metric `json:"metrics"`
+}
+
+// Metric represents an OTLP metric; sums and explicit-bucket histograms are supported
+type Metric struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description,omitempty"`
+	Unit        string     `json:"unit,omitempty"`
+	Sum         *Sum       `json:"sum,omitempty"`
+	Histogram   *Histogram `json:"histogram,omitempty"`
+}
+
+// AggregationTemporalityDelta marks data points covering only the exported interval
+const AggregationTemporalityDelta = 1
+
+// Sum represents an OTLP sum metric
+type Sum struct {
+	DataPoints             []NumberDataPoint `json:"dataPoints"`
+	AggregationTemporality int               `json:"aggregationTemporality"`
+	IsMonotonic            bool              `json:"isMonotonic"`
+}
+
+// NumberDataPoint represents a single integer data point
+type NumberDataPoint struct {
+	Attributes        []OTelAttribute `json:"attributes,omitempty"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	TimeUnixNano      string          `json:"timeUnixNano"`
+	AsInt             string          `json:"asInt"`
+}
+
+// Histogram represents an OTLP explicit-bucket histogram metric
+type Histogram struct {
+	DataPoints             []HistogramDataPoint `json:"dataPoints"`
+	AggregationTemporality int                  `json:"aggregationTemporality"`
+}
+
+// HistogramDataPoint holds the distribution of one series. BucketCounts has one more entry
+// than ExplicitBounds, the last counting values above the highest bound.
+type HistogramDataPoint struct {
+	Attributes        []OTelAttribute `json:"attributes,omitempty"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	TimeUnixNano      string          `json:"timeUnixNano"`
+	Count             string          `json:"count"`
+	Sum               float64         `json:"sum"`
+	Min               float64         `json:"min"`
+	Max               float64         `json:"max"`
+	BucketCounts      []string        `json:"bucketCounts"`
+	ExplicitBounds    []float64       `json:"explicitBounds"`
+}
+
+// RecordCounter derives record counts per severity while records are grouped, so metrics
+// come out of the same pass that builds the log batches
+type RecordCounter struct {
+	counts map[string]int64
+	window timeWindow
+}
+
+// NewRecordCounter creates an empty counter
+func NewRecordCounter() *RecordCounter {
+	return &RecordCounter{counts: make(map[string]int64)}
+}
+
+// Add counts one record and widens the observed time window
+func (c *RecordCounter) Add(record OTelLogRecord) {
+	c.counts[record.SeverityText]++
+	c.window.observe(record.TimeUnixNano)
+}
+
+// Metrics returns the counts as an "aws.log.records" delta sum with one data point per severity
+func (c *RecordCounter) Metrics() []Metric {
+	if c == nil || len(c.counts) == 0 {
+		return nil
+	}
+
+	severities := make([]string, 0, len(c.counts))
+	for severity := range c.counts {
+		severities = append(severities, severity)
+	}
+	sort.Strings(severities)
+
+	start, end := c.window.bounds()
+
+	points := make([]NumberDataPoint, 0, len(severities))
+	for _, severity := range severities {
+		points = append(points, NumberDataPoint{
+			Attributes:        []OTelAttribute{{Key: "log.severity", Value: stringValue(severity)}},
+			StartTimeUnixNano: start,
+			TimeUnixNano:      end,
+			AsInt:             strconv.FormatInt(c.counts[severity], 10),
+		})
+	}
+
+	return []Metric{{
+		Name:        "aws.log.records",
+		Description: "Log records parsed from AWS access logs",
+		Unit:        "{record}",
+		Sum: &Sum{
+			DataPoints:             points,
+			AggregationTemporality: AggregationTemporalityDelta,
+			IsMonotonic:            true,
+		},
+	}}
+}
+
+// timeWindow is the span between the earliest and latest record timestamps observed
+type timeWindow struct {
+	start int64
+	end   int64
+}
+
+func (w *timeWindow) observe(timeUnixNano string) {
+	ts, err := strconv.ParseInt(timeUnixNano, 10, 64)
+	if err != nil || ts == 0 {
+		return
+	}
+	if w.start == 0 || ts < w.start {
+		w.start = ts
+	}
+	if ts > w.end {
+		w.end = ts
+	}
+}
+
+// bounds returns the window as data point start and end times
+func (w *timeWindow) bounds() (string, string) {
+	return strconv.FormatInt(w.start, 10), strconv.FormatInt(w.end, 10)
+}
@@ -0,0 +1,34 @@
+package converter
+
+import "testing"
+
+func TestRecordCounter_Metrics(t *testing.T) {
+	c := NewRecordCounter()
+	if got := c.Metrics(); got != nil {
+		t.Errorf("Metrics() of empty counter = %+v, want nil", got)
+	}
+
+	c.Add(OTelLogRecord{TimeUnixNano: "300", SeverityText: "INFO"})
+	c.Add(OTelLogRecord{TimeUnixNano: "100", SeverityText: "ERROR"})
+	c.Add(OTelLogRecord{TimeUnixNano: "200", SeverityText: "INFO"})
+
+	metrics := c.Metrics()
+	if len(metrics) != 1 || metrics[0].Name != "aws.log.records" || metrics[0].Sum == nil {
+		t.Fatalf("Metrics() = %+v, want one aws.log.records sum", metrics)
+	}
+
+	points := metrics[0].Sum.DataPoints
+	want := map[string]string{"ERROR": "1", "INFO": "2"}
+	if len(points) != len(want) {
+		t.Fatalf("got %d data points, want %d", len(points), len(want))
+	}
+	for _, p := range points {
+		severity := *p.Attributes[0].Value.StringValue
+		if p.AsInt != want[severity] {
+			t.Errorf("count for %s = %s, want %s", severity, p.AsInt, want[severity])
+		}
+		if p.StartTimeUnixNano != "100" || p.TimeUnixNano != "300" {
+			t.Errorf("window = [%s, %s], want [100, 300]", p.StartTimeUnixNano, p.TimeUnixNano)
+		}
+	}
+}
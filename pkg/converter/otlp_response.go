@@ -0,0 +1,103 @@
+package converter
+
+import (
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/collector/pdata/plog/plogotlp"
+	"go.opentelemetry.io/collector/pdata/pmetric/pmetricotlp"
+	"go.opentelemetry.io/collector/pdata/ptrace/ptraceotlp"
+)
+
+// PartialSuccess mirrors the partial_success field of an OTLP ExportLogsServiceResponse
+type PartialSuccess struct {
+	RejectedLogRecords int64
+	ErrorMessage       string
+}
+
+// Rejected reports whether the collector rejected any records or returned a warning
+func (p PartialSuccess) Rejected() bool {
+	return p.RejectedLogRecords > 0 || p.ErrorMessage != ""
+}
+
+// Rejection mirrors the partial_success field of an OTLP metrics or traces export response:
+// Count is the number of rejected data points or spans
+type Rejection struct {
+	Count        int64
+	ErrorMessage string
+}
+
+// Rejected reports whether the collector rejected any data or returned a warning
+func (r Rejection) Rejected() bool {
+	return r.Count > 0 || r.ErrorMessage != ""
+}
+
+// exportResponse is a pdata export response of any signal
+type exportResponse interface {
+	UnmarshalProto(data []byte) error
+	UnmarshalJSON(data []byte) error
+}
+
+// unmarshalResponse decodes an OTLP/HTTP export response body by its content type
+func unmarshalResponse(resp exportResponse, contentType string, body []byte) error {
+	var err error
+	if strings.HasPrefix(contentType, "application/x-protobuf") {
+		err = resp.UnmarshalProto(body)
+	} else {
+		err = resp.UnmarshalJSON(body)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to decode export response: %w", err)
+	}
+	return nil
+}
+
+// ParseExportResponse decodes the partial_success field from an OTLP/HTTP logs export response
+// body. An empty body is a full success.
+func ParseExportResponse(contentType string, body []byte) (PartialSuccess, error) {
+	if len(body) == 0 {
+		return PartialSuccess{}, nil
+	}
+	resp := plogotlp.NewExportResponse()
+	if err := unmarshalResponse(resp, contentType, body); err != nil {
+		return PartialSuccess{}, err
+	}
+	return PartialSuccessOf(resp), nil
+}
+
+// PartialSuccessOf extracts the partial_success field of a decoded response
+func PartialSuccessOf(resp plogotlp.ExportResponse) PartialSuccess {
+	ps := resp.PartialSuccess()
+	return PartialSuccess{
+		RejectedLogRecords: ps.RejectedLogRecords(),
+		ErrorMessage:       ps.ErrorMessage(),
+	}
+}
+
+// ParseMetricsExportResponse decodes the partial_success field from an OTLP/HTTP metrics export
+// response body, counting rejected data points. An empty body is a full success.
+func ParseMetricsExportResponse(contentType string, body []byte) (Rejection, error) {
+	if len(body) == 0 {
+		return Rejection{}, nil
+	}
+	resp := pmetricotlp.NewExportResponse()
+	if err := unmarshalResponse(resp, contentType, body); err != nil {
+		return Rejection{}, err
+	}
+	ps := resp.PartialSuccess()
+	return Rejection{Count: ps.RejectedDataPoints(), ErrorMessage: ps.ErrorMessage()}, nil
+}
+
+// ParseTracesExportResponse decodes the partial_success field from an OTLP/HTTP traces export
+// response body, counting rejected spans. An empty body is a full success.
+func ParseTracesExportResponse(contentType string, body []byte) (Rejection, error) {
+	if len(body) == 0 {
+		return Rejection{}, nil
+	}
+	resp := ptraceotlp.NewExportResponse()
+	if err := unmarshalResponse(resp, contentType, body); err != nil {
+		return Rejection{}, err
+	}
+	ps := resp.PartialSuccess()
+	return Rejection{Count: ps.RejectedSpans(), ErrorMessage: ps.ErrorMessage()}, nil
+}
@@ -0,0 +1,94 @@
+package converter
+
+import (
+	"testing"
+
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestParseExportResponse(t *testing.T) {
+	protoBody, _ := proto.Marshal(&collogspb.ExportLogsServiceResponse{
+		PartialSuccess: &collogspb.ExportLogsPartialSuccess{RejectedLogRecords: 3, ErrorMessage: "too old"},
+	})
+
+	tests := []struct {
+		name         string
+		contentType  string
+		body         []byte
+		wantRejected int64
+		wantMessage  string
+		wantErr      bool
+	}{
+		{name: "Empty body", contentType: "application/json", body: nil},
+		{name: "Empty JSON object", contentType: "application/json", body: []byte(`{}`)},
+		{name: "JSON string count", contentType: "application/json", body: []byte(`{"partialSuccess":{"rejectedLogRecords":"5","errorMessage":"invalid timestamp"}}`), wantRejected: 5, wantMessage: "invalid timestamp"},
+		{name: "JSON numeric count", contentType: "application/json; charset=utf-8", body: []byte(`{"partialSuccess":{"rejectedLogRecords":2}}`), wantRejected: 2},
+		{name: "Protobuf", contentType: "application/x-protobuf", body: protoBody, wantRejected: 3, wantMessage: "too old"},
+		{name: "Garbage", contentType: "application/json", body: []byte(`not json`), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseExportResponse(tt.contentType, tt.body)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("ParseExportResponse() expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseExportResponse() error = %v", err)
+			}
+			if got.RejectedLogRecords != tt.wantRejected || got.ErrorMessage != tt.wantMessage {
+				t.Errorf("ParseExportResponse() = %+v, want %d/%q", got, tt.wantRejected, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestParseSignalExportResponses(t *testing.T) {
+	metricsBody, _ := proto.Marshal(&colmetricspb.ExportMetricsServiceResponse{
+		PartialSuccess: &colmetricspb.ExportMetricsPartialSuccess{RejectedDataPoints: 4, ErrorMessage: "stale"},
+	})
+	tracesBody, _ := proto.Marshal(&coltracepb.ExportTraceServiceResponse{
+		PartialSuccess: &coltracepb.ExportTracePartialSuccess{RejectedSpans: 2},
+	})
+
+	tests := []struct {
+		name        string
+		parse       func(string, []byte) (Rejection, error)
+		contentType string
+		body        []byte
+		want        Rejection
+		wantErr     bool
+	}{
+		{name: "Metrics empty body", parse: ParseMetricsExportResponse, contentType: "application/x-protobuf"},
+		{name: "Metrics protobuf", parse: ParseMetricsExportResponse, contentType: "application/x-protobuf", body: metricsBody, want: Rejection{Count: 4, ErrorMessage: "stale"}},
+		{name: "Metrics JSON", parse: ParseMetricsExportResponse, contentType: "application/json", body: []byte(`{"partialSuccess":{"rejectedDataPoints":"6"}}`), want: Rejection{Count: 6}},
+		{name: "Metrics ignores log count", parse: ParseMetricsExportResponse, contentType: "application/json", body: []byte(`{"partialSuccess":{"rejectedLogRecords":"6"}}`)},
+		{name: "Traces protobuf", parse: ParseTracesExportResponse, contentType: "application/x-protobuf", body: tracesBody, want: Rejection{Count: 2}},
+		{name: "Traces JSON", parse: ParseTracesExportResponse, contentType: "application/json", body: []byte(`{"partialSuccess":{"rejectedSpans":1,"errorMessage":"bad span"}}`), want: Rejection{Count: 1, ErrorMessage: "bad span"}},
+		{name: "Traces garbage", parse: ParseTracesExportResponse, contentType: "application/json", body: []byte(`not json`), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.parse(tt.contentType, tt.body)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("parse expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parse error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("parse = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package converter
+
+import (
+	"encoding/hex"
+	"strconv"
+	"strings"
+)
+
+// OTLPTracesPayload represents the complete OTLP/JSON traces payload
+type OTLPTracesPayload struct {
+	ResourceSpans []ResourceSpans `json:"resourceSpans"`
+}
+
+// ResourceSpans represents a resource with scope spans
+type ResourceSpans struct {
+	Resource   ResourceAttributes `json:"resource"`
+	ScopeSpans []ScopeSpans       `json:"scopeSpans"`
+}
+
+// ScopeSpans represents a scope with spans
+type ScopeSpans struct {
+	Scope Scope  `json:"scope"`
+	Spans []Span `json:"spans"`
+}
+
+// Span kinds and status codes of the OTLP trace model
+const (
+	SpanKindInternal = 1
+	SpanKindServer   = 2
+	SpanKindClient   = 3
+
+	StatusCodeOK    = 1
+	StatusCodeError = 2
+)
+
+// Span represents an OTLP span; IDs are hex encoded as OTLP/JSON requires
+type Span struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []OTelAttribute `json:"attributes,omitempty"`
+	Status            *SpanStatus     `json:"status,omitempty"`
+}
+
+// SpanStatus represents the outcome of a span
+type SpanStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+// albSpanAttrs are the record attributes an ALB span carries, with their key on the span; the
+// target becomes the span's peer
+var albSpanAttrs = map[string]string{
+	"http.request.method":              "http.request.method",
+	"http.response.status_code":        "http.response.status_code",
+	"url.scheme":                       "url.scheme",
+	"url.path":                         "url.path",
+	"server.address":                   "server.address",
+	"client.address":                   "client.address",
+	"server.socket.address":            "network.peer.address",
+	"server.socket.port":               "network.peer.port",
+	"aws.alb.target_group_arn":         "aws.alb.target_group_arn",
+	"aws.alb.target_status_code":       "aws.alb.target_status_code",
+	"aws.alb.request_processing_time":  "aws.alb.request_processing_time",
+	"aws.alb.target_processing_time":   "aws.alb.target_processing_time",
+	"aws.alb.response_processing_time": "aws.alb.response_processing_time",
+}
+
+// ALBSpan synthesizes the server span of the request behind an ALB log record, so requests to
+// services that are not instrumented still show up in their traces. The span takes the record's
+// trace and span IDs, which links the two, and the Parent of X-Amzn-Trace-Id when the caller
+// sent one. It ends when the ALB sent the response and lasts as long as the request, target and
+// response processing times together. Records without a trace ID give no span.
+func ALBSpan(record OTelLogRecord) (Span, bool) {
+	if record.TraceID == "" || record.SpanID == "" {
+		return Span{}, false
+	}
+	end, err := strconv.ParseInt(record.TimeUnixNano, 10, 64)
+	if err != nil {
+		return Span{}, false
+	}
+
+	span := Span{TraceID: record.TraceID, SpanID: record.SpanID, Name: "HTTP", Kind: SpanKindServer}
+	var duration float64
+	for _, attr := range record.Attributes {
+		key, ok := albSpanAttrs[attr.Key]
+		if !ok {
+			if attr.Key == "aws.alb.trace_id" {
+				span.ParentSpanID = amznParentID(attr.Value.Text())
+			}
+			continue
+		}
+		span.Attributes = append(span.Attributes, OTelAttribute{Key: key, Value: attr.Value})
+		switch {
+		case key == "http.request.method":
+			span.Name = attr.Value.Text()
+		case key == "http.response.status_code":
+			if status, err := strconv.Atoi(attr.Value.Text()); err == nil && status >= 500 {
+				span.Status = &SpanStatus{Code: StatusCodeError}
+			}
+		case strings.HasSuffix(key, "_processing_time"):
+			// -1: the request never reached that phase
+			if attr.Value.DoubleValue != nil && *attr.Value.DoubleValue > 0 {
+				duration += *attr.Value.DoubleValue
+			}
+		}
+	}
+
+	span.StartTimeUnixNano = strconv.FormatInt(end-int64(duration*1e9), 10)
+	span.EndTimeUnixNano = record.TimeUnixNano
+	return span, true
+}
+
+// amznParentID returns the Parent span ID of an X-Amzn-Trace-Id header, or ""
+func amznParentID(header string) string {
+	for _, field := range strings.Split(header, ";") {
+		key, value, ok := strings.Cut(strings.TrimSpace(field), "=")
+		if !ok || !strings.EqualFold(key, "Parent") {
+			continue
+		}
+		if _, err := hex.DecodeString(value); err == nil && len(value) == 16 {
+			return strings.ToLower(value)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,55 @@
+package converter
+
+import "testing"
+
+func TestALBSpan(t *testing.T) {
+	float := func(v float64) OTelAnyValue { return OTelAnyValue{DoubleValue: &v} }
+	record := OTelLogRecord{
+		TimeUnixNano: "1700000000500000000",
+		TraceID:      "5833726236d228ad5d99923122bbe354",
+		SpanID:       "0123456789abcdef",
+		Attributes: []OTelAttribute{
+			{Key: "http.request.method", Value: stringValue("GET")},
+			{Key: "http.response.status_code", Value: intValue(502)},
+			{Key: "server.socket.address", Value: stringValue("10.0.0.7")},
+			{Key: "server.socket.port", Value: intValue(8080)},
+			{Key: "user_agent.original", Value: stringValue("curl/8.0")},
+			{Key: "aws.alb.request_processing_time", Value: float(0.1)},
+			{Key: "aws.alb.target_processing_time", Value: float(0.25)},
+			{Key: "aws.alb.response_processing_time", Value: float(-1)},
+			{Key: "aws.alb.trace_id", Value: stringValue("Self=1-67891234-12456789abcdef012345678;Root=1-58337262-36d228ad5d99923122bbe354;Parent=53995C3F42CD8AD8;Sampled=1")},
+		},
+	}
+
+	span, ok := ALBSpan(record)
+	if !ok {
+		t.Fatal("ALBSpan() gave no span")
+	}
+	if span.TraceID != record.TraceID || span.SpanID != record.SpanID || span.ParentSpanID != "53995c3f42cd8ad8" {
+		t.Errorf("IDs = %s/%s/%s, want the record's and the header's parent", span.TraceID, span.SpanID, span.ParentSpanID)
+	}
+	if span.Name != "GET" || span.Kind != SpanKindServer {
+		t.Errorf("span = %q kind %d, want GET server span", span.Name, span.Kind)
+	}
+	if span.StartTimeUnixNano != "1700000000150000000" || span.EndTimeUnixNano != record.TimeUnixNano {
+		t.Errorf("span = [%s, %s], want 350ms ending at the record time", span.StartTimeUnixNano, span.EndTimeUnixNano)
+	}
+	if span.Status == nil || span.Status.Code != StatusCodeError {
+		t.Errorf("status = %+v, want error for a 502", span.Status)
+	}
+
+	attrs := map[string]string{}
+	for _, attr := range span.Attributes {
+		attrs[attr.Key] = attr.Value.Text()
+	}
+	if attrs["network.peer.address"] != "10.0.0.7" || attrs["network.peer.port"] != "8080" {
+		t.Errorf("peer = %s:%s, want the target 10.0.0.7:8080", attrs["network.peer.address"], attrs["network.peer.port"])
+	}
+	if _, ok := attrs["user_agent.original"]; ok {
+		t.Error("span carries user_agent.original, want only the request attributes")
+	}
+
+	if _, ok := ALBSpan(OTelLogRecord{TimeUnixNano: "1", SpanID: "0123456789abcdef"}); ok {
+		t.Error("ALBSpan() of a record without trace ID gave a span")
+	}
+}
@@ -0,0 +1,154 @@
+package converter
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+)
+
+// MarshalProto encodes the payload as an OTLP ExportLogsServiceRequest protobuf message
+func MarshalProto(payload OTLPPayload) ([]byte, error) {
+	logs, err := ToLogs(payload)
+	if err != nil {
+		return nil, err
+	}
+	return (&plog.ProtoMarshaler{}).MarshalLogs(logs)
+}
+
+// MarshalJSON encodes the payload as an OTLP/JSON ExportLogsServiceRequest
+func MarshalJSON(payload OTLPPayload) ([]byte, error) {
+	logs, err := ToLogs(payload)
+	if err != nil {
+		return nil, err
+	}
+	return (&plog.JSONMarshaler{}).MarshalLogs(logs)
+}
+
+// ToLogs converts the payload into collector pdata, which the official OTLP marshalers and
+// clients encode
+func ToLogs(payload OTLPPayload) (plog.Logs, error) {
+	logs := plog.NewLogs()
+	resourceLogs := logs.ResourceLogs()
+	resourceLogs.EnsureCapacity(len(payload.ResourceLogs))
+
+	for _, rl := range payload.ResourceLogs {
+		pdResourceLogs := resourceLogs.AppendEmpty()
+		if err := putAttributes(pdResourceLogs.Resource().Attributes(), rl.Resource.Attributes); err != nil {
+			return plog.Logs{}, err
+		}
+
+		scopeLogs := pdResourceLogs.ScopeLogs()
+		scopeLogs.EnsureCapacity(len(rl.ScopeLogs))
+		for _, sl := range rl.ScopeLogs {
+			pdScopeLogs := scopeLogs.AppendEmpty()
+			pdScopeLogs.Scope().SetName(sl.Scope.Name)
+			pdScopeLogs.Scope().SetVersion(sl.Scope.Version)
+
+			records := pdScopeLogs.LogRecords()
+			records.EnsureCapacity(len(sl.LogRecords))
+			for _, record := range sl.LogRecords {
+				if err := putLogRecord(records.AppendEmpty(), record); err != nil {
+					return plog.Logs{}, err
+				}
+			}
+		}
+	}
+
+	return logs, nil
+}
+
+func putLogRecord(dst plog.LogRecord, record OTelLogRecord) error {
+	timeUnixNano, err := strconv.ParseUint(record.TimeUnixNano, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid timeUnixNano %q: %w", record.TimeUnixNano, err)
+	}
+	dst.SetTimestamp(pcommon.Timestamp(timeUnixNano))
+	if record.ObservedTimeUnixNano != "" {
+		observed, err := strconv.ParseUint(record.ObservedTimeUnixNano, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid observedTimeUnixNano %q: %w", record.ObservedTimeUnixNano, err)
+		}
+		dst.SetObservedTimestamp(pcommon.Timestamp(observed))
+	}
+
+	dst.SetSeverityNumber(plog.SeverityNumber(record.SeverityNumber))
+	dst.SetSeverityText(record.SeverityText)
+	dst.SetFlags(plog.LogRecordFlags(record.Flags))
+
+	if err := putAttributes(dst.Attributes(), record.Attributes); err != nil {
+		return err
+	}
+	if err := putValue(dst.Body(), record.Body); err != nil {
+		return fmt.Errorf("body: %w", err)
+	}
+
+	// Trace/span IDs are hex encoded in the records but fixed-size byte arrays in pdata
+	if record.TraceID != "" {
+		var id pcommon.TraceID
+		if err := decodeID(id[:], record.TraceID); err != nil {
+			return fmt.Errorf("invalid traceId %q: %w", record.TraceID, err)
+		}
+		dst.SetTraceID(id)
+	}
+	if record.SpanID != "" {
+		var id pcommon.SpanID
+		if err := decodeID(id[:], record.SpanID); err != nil {
+			return fmt.Errorf("invalid spanId %q: %w", record.SpanID, err)
+		}
+		dst.SetSpanID(id)
+	}
+
+	return nil
+}
+
+// decodeID decodes a hex ID that must fill dst exactly
+func decodeID(dst []byte, s string) error {
+	if hex.DecodedLen(len(s)) != len(dst) {
+		return fmt.Errorf("want %d hex digits", 2*len(dst))
+	}
+	_, err := hex.Decode(dst, []byte(s))
+	return err
+}
+
+func putAttributes(dst pcommon.Map, attrs []OTelAttribute) error {
+	dst.EnsureCapacity(len(attrs))
+	for _, attr := range attrs {
+		if err := putValue(dst.PutEmpty(attr.Key), attr.Value); err != nil {
+			return fmt.Errorf("attribute %q: %w", attr.Key, err)
+		}
+	}
+	return nil
+}
+
+// putValue sets dst to v; an unset value leaves dst empty
+func putValue(dst pcommon.Value, v OTelAnyValue) error {
+	switch {
+	case v.StringValue != nil:
+		dst.SetStr(*v.StringValue)
+	case v.IntValue != nil:
+		// OTLP/JSON carries int64 as a decimal string
+		i, err := strconv.ParseInt(*v.IntValue, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid intValue %q: %w", *v.IntValue, err)
+		}
+		dst.SetInt(i)
+	case v.DoubleValue != nil:
+		dst.SetDouble(*v.DoubleValue)
+	case v.BoolValue != nil:
+		dst.SetBool(*v.BoolValue)
+	case v.KvlistValue != nil:
+		return putAttributes(dst.SetEmptyMap(), v.KvlistValue.Values)
+	case v.ArrayValue != nil:
+		values := dst.SetEmptySlice()
+		values.EnsureCapacity(len(v.ArrayValue.Values))
+		for _, item := range v.ArrayValue.Values {
+			if err := putValue(values.AppendEmpty(), item); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
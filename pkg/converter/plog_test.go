@@ -0,0 +1,171 @@
+package converter
+
+import (
+	"encoding/json"
+	"testing"
+
+	"go.opentelemetry.io/collector/pdata/plog"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/parser"
+)
+
+func albPayload() OTLPPayload {
+	entry := &parser.ALBLogEntry{
+		Type:          "https",
+		Time:          "2025-12-04T00:55:01.294082Z",
+		ELB:           "app/test/12345",
+		ClientIP:      "192.168.1.1",
+		ClientPort:    12345,
+		ELBStatusCode: 503,
+		RequestVerb:   "GET",
+		RequestURL:    "https://example.com:443/api/test",
+		TraceID:       "Root=1-58337262-36d228ad5d99923122bbe354;Sampled=1",
+	}
+
+	return OTLPPayload{
+		ResourceLogs: []ResourceLog{
+			{
+				Resource: ResourceAttributes{Attributes: ExtractResourceAttributes(entry)},
+				ScopeLogs: []ScopeLog{
+					{
+						Scope:      Scope{Name: "otel-aws-log-parser", Version: "1.0.0"},
+						LogRecords: []OTelLogRecord{ConvertToOTel(entry)},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestMarshalProto(t *testing.T) {
+	data, err := MarshalProto(albPayload())
+	if err != nil {
+		t.Fatalf("MarshalProto() error = %v", err)
+	}
+
+	var req collogspb.ExportLogsServiceRequest
+	if err := proto.Unmarshal(data, &req); err != nil {
+		t.Fatalf("Failed to unmarshal protobuf payload: %v", err)
+	}
+
+	if len(req.ResourceLogs) != 1 || len(req.ResourceLogs[0].ScopeLogs) != 1 {
+		t.Fatalf("Unexpected payload shape: %v", &req)
+	}
+
+	records := req.ResourceLogs[0].ScopeLogs[0].LogRecords
+	if len(records) != 1 {
+		t.Fatalf("Expected 1 log record, got %d", len(records))
+	}
+
+	record := records[0]
+	if record.SeverityText != "ERROR" || record.SeverityNumber != 17 {
+		t.Errorf("Severity = %s/%d, want ERROR/17", record.SeverityText, record.SeverityNumber)
+	}
+	if len(record.TraceId) != 16 || record.Flags != TraceFlagsSampled {
+		t.Errorf("TraceId length = %d, flags = %d, want 16 bytes and sampled", len(record.TraceId), record.Flags)
+	}
+	if record.ObservedTimeUnixNano == 0 {
+		t.Error("ObservedTimeUnixNano not set")
+	}
+	if record.Body.GetStringValue() != "GET https://example.com:443/api/test " {
+		t.Errorf("Body = %q", record.Body.GetStringValue())
+	}
+
+	foundStatus := false
+	for _, attr := range record.Attributes {
+		if attr.Key == "http.response.status_code" {
+			foundStatus = true
+			if attr.Value.GetIntValue() != 503 {
+				t.Errorf("http.response.status_code = %d, want 503", attr.Value.GetIntValue())
+			}
+		}
+	}
+	if !foundStatus {
+		t.Error("http.response.status_code attribute not found")
+	}
+}
+
+func TestMarshalJSON(t *testing.T) {
+	in := albPayload()
+	data, err := MarshalJSON(in)
+	if err != nil {
+		t.Fatalf("MarshalJSON() error = %v", err)
+	}
+
+	// OTLP/JSON decodes back into the records the parser built
+	var payload OTLPPayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		t.Fatalf("Failed to decode OTLP/JSON payload: %v", err)
+	}
+	want := in.ResourceLogs[0].ScopeLogs[0].LogRecords[0]
+	got := payload.ResourceLogs[0].ScopeLogs[0].LogRecords[0]
+	if got.TraceID != want.TraceID || got.SpanID != want.SpanID || got.Flags != want.Flags {
+		t.Errorf("trace context = %s/%s/%d, want %s/%s/%d", got.TraceID, got.SpanID, got.Flags, want.TraceID, want.SpanID, want.Flags)
+	}
+	if got.Body.Text() != want.Body.Text() || len(got.Attributes) != len(want.Attributes) {
+		t.Errorf("record = %+v, want %+v", got, want)
+	}
+}
+
+func TestToLogs_KvlistBody(t *testing.T) {
+	status := "503"
+	record := OTelLogRecord{
+		TimeUnixNano: "1700000000000000000",
+		Body: OTelAnyValue{KvlistValue: &OTelKeyValueList{Values: []OTelAttribute{
+			{Key: "http.request.method", Value: stringValue("GET")},
+			{Key: "http.response.status_code", Value: OTelAnyValue{IntValue: &status}},
+		}}},
+	}
+	logs, err := ToLogs(OTLPPayload{ResourceLogs: []ResourceLog{{ScopeLogs: []ScopeLog{{LogRecords: []OTelLogRecord{record}}}}}})
+	if err != nil {
+		t.Fatalf("ToLogs() error = %v", err)
+	}
+
+	kv := firstRecord(logs).Body().Map()
+	if v, ok := kv.Get("http.response.status_code"); kv.Len() != 2 || !ok || v.Int() != 503 {
+		t.Errorf("body = %v, want a two-entry kvlist", kv.AsRaw())
+	}
+	if got, want := record.Body.Text(), `{"http.request.method":"GET","http.response.status_code":503}`; got != want {
+		t.Errorf("Text() = %s, want %s", got, want)
+	}
+}
+
+func TestToLogs_ObservedTimeFlagsAndArrays(t *testing.T) {
+	record := OTelLogRecord{
+		TimeUnixNano:         "1700000000000000000",
+		ObservedTimeUnixNano: "1700000005000000000",
+		Flags:                TraceFlagsSampled,
+		Attributes: []OTelAttribute{
+			{Key: "tags", Value: OTelAnyValue{ArrayValue: &OTelArrayValue{Values: []OTelAnyValue{stringValue("a"), stringValue("b")}}}},
+		},
+	}
+	logs, err := ToLogs(OTLPPayload{ResourceLogs: []ResourceLog{{ScopeLogs: []ScopeLog{{LogRecords: []OTelLogRecord{record}}}}}})
+	if err != nil {
+		t.Fatalf("ToLogs() error = %v", err)
+	}
+
+	lr := firstRecord(logs)
+	if lr.ObservedTimestamp() != 1700000005000000000 || !lr.Flags().IsSampled() {
+		t.Errorf("observed time %d, flags %d", lr.ObservedTimestamp(), lr.Flags())
+	}
+	tags, _ := lr.Attributes().Get("tags")
+	if arr := tags.Slice(); arr.Len() != 2 || arr.At(1).Str() != "b" {
+		t.Errorf("tags = %v, want [a b]", tags.AsRaw())
+	}
+	if got, want := record.Attributes[0].Value.Text(), `["a","b"]`; got != want {
+		t.Errorf("Text() = %s, want %s", got, want)
+	}
+}
+
+func TestToLogs_InvalidTraceID(t *testing.T) {
+	record := OTelLogRecord{TimeUnixNano: "1700000000000000000", TraceID: "abcd"}
+	if _, err := ToLogs(OTLPPayload{ResourceLogs: []ResourceLog{{ScopeLogs: []ScopeLog{{LogRecords: []OTelLogRecord{record}}}}}}); err == nil {
+		t.Error("ToLogs() accepted a short trace ID")
+	}
+}
+
+func firstRecord(logs plog.Logs) plog.LogRecord {
+	return logs.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0)
+}
@@ -0,0 +1,150 @@
+package converter
+
+import (
+	"fmt"
+	"strconv"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+// MarshalMetricsProto encodes the payload as an OTLP ExportMetricsServiceRequest protobuf message
+func MarshalMetricsProto(payload OTLPMetricsPayload) ([]byte, error) {
+	metrics, err := ToMetrics(payload)
+	if err != nil {
+		return nil, err
+	}
+	return (&pmetric.ProtoMarshaler{}).MarshalMetrics(metrics)
+}
+
+// MarshalMetricsJSON encodes the payload as an OTLP/JSON ExportMetricsServiceRequest
+func MarshalMetricsJSON(payload OTLPMetricsPayload) ([]byte, error) {
+	metrics, err := ToMetrics(payload)
+	if err != nil {
+		return nil, err
+	}
+	return (&pmetric.JSONMarshaler{}).MarshalMetrics(metrics)
+}
+
+// ToMetrics converts the metrics payload into collector pdata, which the official OTLP
+// marshalers and clients encode
+func ToMetrics(payload OTLPMetricsPayload) (pmetric.Metrics, error) {
+	metrics := pmetric.NewMetrics()
+	resourceMetrics := metrics.ResourceMetrics()
+	resourceMetrics.EnsureCapacity(len(payload.ResourceMetrics))
+
+	for _, rm := range payload.ResourceMetrics {
+		pdResourceMetrics := resourceMetrics.AppendEmpty()
+		if err := putAttributes(pdResourceMetrics.Resource().Attributes(), rm.Resource.Attributes); err != nil {
+			return pmetric.Metrics{}, err
+		}
+
+		scopeMetrics := pdResourceMetrics.ScopeMetrics()
+		scopeMetrics.EnsureCapacity(len(rm.ScopeMetrics))
+		for _, sm := range rm.ScopeMetrics {
+			pdScopeMetrics := scopeMetrics.AppendEmpty()
+			pdScopeMetrics.Scope().SetName(sm.Scope.Name)
+			pdScopeMetrics.Scope().SetVersion(sm.Scope.Version)
+
+			list := pdScopeMetrics.Metrics()
+			list.EnsureCapacity(len(sm.Metrics))
+			for _, metric := range sm.Metrics {
+				if err := putMetric(list.AppendEmpty(), metric); err != nil {
+					return pmetric.Metrics{}, fmt.Errorf("metric %q: %w", metric.Name, err)
+				}
+			}
+		}
+	}
+
+	return metrics, nil
+}
+
+func putMetric(dst pmetric.Metric, metric Metric) error {
+	dst.SetName(metric.Name)
+	dst.SetDescription(metric.Description)
+	dst.SetUnit(metric.Unit)
+
+	switch {
+	case metric.Sum != nil:
+		sum := dst.SetEmptySum()
+		sum.SetAggregationTemporality(pmetric.AggregationTemporality(metric.Sum.AggregationTemporality))
+		sum.SetIsMonotonic(metric.Sum.IsMonotonic)
+		points := sum.DataPoints()
+		points.EnsureCapacity(len(metric.Sum.DataPoints))
+		for _, dp := range metric.Sum.DataPoints {
+			if err := putNumberDataPoint(points.AppendEmpty(), dp); err != nil {
+				return err
+			}
+		}
+	case metric.Histogram != nil:
+		histogram := dst.SetEmptyHistogram()
+		histogram.SetAggregationTemporality(pmetric.AggregationTemporality(metric.Histogram.AggregationTemporality))
+		points := histogram.DataPoints()
+		points.EnsureCapacity(len(metric.Histogram.DataPoints))
+		for _, dp := range metric.Histogram.DataPoints {
+			if err := putHistogramDataPoint(points.AppendEmpty(), dp); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func putNumberDataPoint(dst pmetric.NumberDataPoint, dp NumberDataPoint) error {
+	if err := putAttributes(dst.Attributes(), dp.Attributes); err != nil {
+		return err
+	}
+	if err := putPointTimes(dst.SetStartTimestamp, dst.SetTimestamp, dp.StartTimeUnixNano, dp.TimeUnixNano); err != nil {
+		return err
+	}
+	value, err := strconv.ParseInt(dp.AsInt, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid asInt %q: %w", dp.AsInt, err)
+	}
+	dst.SetIntValue(value)
+	return nil
+}
+
+func putHistogramDataPoint(dst pmetric.HistogramDataPoint, dp HistogramDataPoint) error {
+	if err := putAttributes(dst.Attributes(), dp.Attributes); err != nil {
+		return err
+	}
+	if err := putPointTimes(dst.SetStartTimestamp, dst.SetTimestamp, dp.StartTimeUnixNano, dp.TimeUnixNano); err != nil {
+		return err
+	}
+	count, err := strconv.ParseUint(dp.Count, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid count %q: %w", dp.Count, err)
+	}
+	buckets := make([]uint64, 0, len(dp.BucketCounts))
+	for _, bucket := range dp.BucketCounts {
+		n, err := strconv.ParseUint(bucket, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid bucket count %q: %w", bucket, err)
+		}
+		buckets = append(buckets, n)
+	}
+	dst.SetCount(count)
+	dst.SetSum(dp.Sum)
+	dst.SetMin(dp.Min)
+	dst.SetMax(dp.Max)
+	dst.BucketCounts().FromRaw(buckets)
+	dst.ExplicitBounds().FromRaw(dp.ExplicitBounds)
+	return nil
+}
+
+// putPointTimes parses the decimal start and end times of a data point into its setters
+func putPointTimes(setStart, setEnd func(pcommon.Timestamp), start, end string) error {
+	startNano, err := strconv.ParseUint(start, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid startTimeUnixNano %q: %w", start, err)
+	}
+	endNano, err := strconv.ParseUint(end, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid timeUnixNano %q: %w", end, err)
+	}
+	setStart(pcommon.Timestamp(startNano))
+	setEnd(pcommon.Timestamp(endNano))
+	return nil
+}
@@ -0,0 +1,66 @@
+package converter
+
+import (
+	"testing"
+
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestMarshalMetricsProto(t *testing.T) {
+	a := NewREDAggregator()
+	a.Add(redRecord("100", 200, "tg-a", 0.001, 0.02, 0))
+	a.Add(redRecord("300", 204, "tg-a", 0.001, 0.5, 0))
+
+	data, err := MarshalMetricsProto(OTLPMetricsPayload{ResourceMetrics: []ResourceMetric{{
+		ScopeMetrics: []ScopeMetric{{Scope: Scope{Name: "otel-aws-log-parser"}, Metrics: a.Metrics()}},
+	}}})
+	if err != nil {
+		t.Fatalf("MarshalMetricsProto() error = %v", err)
+	}
+	var req colmetricspb.ExportMetricsServiceRequest
+	if err := proto.Unmarshal(data, &req); err != nil {
+		t.Fatalf("Failed to unmarshal protobuf payload: %v", err)
+	}
+
+	metrics := req.ResourceMetrics[0].ScopeMetrics[0].Metrics
+	byName := map[string]*metricspb.Metric{}
+	for _, m := range metrics {
+		byName[m.Name] = m
+	}
+
+	requests := byName["aws.log.requests"].GetSum()
+	if !requests.GetIsMonotonic() || requests.GetAggregationTemporality() != metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA {
+		t.Errorf("aws.log.requests = %v, want a monotonic delta sum", requests)
+	}
+	var total int64
+	for _, p := range requests.GetDataPoints() {
+		total += p.GetAsInt()
+		if p.StartTimeUnixNano != 100 || p.TimeUnixNano != 300 {
+			t.Errorf("window = [%d, %d], want [100, 300]", p.StartTimeUnixNano, p.TimeUnixNano)
+		}
+	}
+	if total != 2 {
+		t.Errorf("aws.log.requests total = %d, want 2", total)
+	}
+
+	duration := byName["aws.log.request.duration"].GetHistogram()
+	if len(duration.GetDataPoints()) != 1 {
+		t.Fatalf("aws.log.request.duration = %v, want one series", duration)
+	}
+	p := duration.DataPoints[0]
+	if p.Count != 2 || len(p.BucketCounts) != len(p.ExplicitBounds)+1 || p.GetMax() == 0 {
+		t.Errorf("duration point = %v", p)
+	}
+}
+
+func TestToMetrics_InvalidValue(t *testing.T) {
+	payload := OTLPMetricsPayload{ResourceMetrics: []ResourceMetric{{ScopeMetrics: []ScopeMetric{{Metrics: []Metric{{
+		Name: "bad",
+		Sum:  &Sum{DataPoints: []NumberDataPoint{{StartTimeUnixNano: "1", TimeUnixNano: "2", AsInt: "x"}}},
+	}}}}}}}
+	if _, err := ToMetrics(payload); err == nil {
+		t.Error("ToMetrics() accepted a non-integer asInt")
+	}
+}
@@ -0,0 +1,28 @@
+package converter
+
+// IsPriority reports whether a record belongs in the high-priority export lane:
+// server errors (severity ERROR or above) and WAF requests that were blocked
+func IsPriority(record OTelLogRecord) bool {
+	if record.SeverityNumber >= 17 {
+		return true
+	}
+	for _, attr := range record.Attributes {
+		if attr.Key == "aws.waf.action" {
+			return attr.Value.StringValue != nil && *attr.Value.StringValue == "BLOCK"
+		}
+	}
+	return false
+}
+
+// PartitionByPriority splits records into the high-priority lane and the bulk lane,
+// preserving the original order within each
+func PartitionByPriority(records []OTelLogRecord) (high, bulk []OTelLogRecord) {
+	for _, record := range records {
+		if IsPriority(record) {
+			high = append(high, record)
+		} else {
+			bulk = append(bulk, record)
+		}
+	}
+	return high, bulk
+}
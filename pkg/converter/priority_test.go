@@ -0,0 +1,36 @@
+package converter
+
+import "testing"
+
+func TestPartitionByPriority(t *testing.T) {
+	block := "BLOCK"
+	allow := "ALLOW"
+
+	records := []OTelLogRecord{
+		{SeverityNumber: 9, SpanID: "ok"},
+		{SeverityNumber: 17, SpanID: "5xx"},
+		{SeverityNumber: 13, SpanID: "4xx"},
+		{SeverityNumber: 13, SpanID: "blocked", Attributes: []OTelAttribute{{Key: "aws.waf.action", Value: OTelAnyValue{StringValue: &block}}}},
+		{SeverityNumber: 9, SpanID: "allowed", Attributes: []OTelAttribute{{Key: "aws.waf.action", Value: OTelAnyValue{StringValue: &allow}}}},
+	}
+
+	high, bulk := PartitionByPriority(records)
+
+	ids := func(rs []OTelLogRecord) []string {
+		var out []string
+		for _, r := range rs {
+			out = append(out, r.SpanID)
+		}
+		return out
+	}
+
+	wantHigh := []string{"5xx", "blocked"}
+	wantBulk := []string{"ok", "4xx", "allowed"}
+
+	if got := ids(high); len(got) != len(wantHigh) || got[0] != wantHigh[0] || got[1] != wantHigh[1] {
+		t.Errorf("high lane = %v, want %v", got, wantHigh)
+	}
+	if got := ids(bulk); len(got) != len(wantBulk) || got[0] != wantBulk[0] || got[1] != wantBulk[1] || got[2] != wantBulk[2] {
+		t.Errorf("bulk lane = %v, want %v", got, wantBulk)
+	}
+}
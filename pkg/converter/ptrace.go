@@ -0,0 +1,101 @@
+package converter
+
+import (
+	"fmt"
+	"strconv"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// MarshalTracesProto encodes the payload as an OTLP ExportTraceServiceRequest protobuf message
+func MarshalTracesProto(payload OTLPTracesPayload) ([]byte, error) {
+	traces, err := ToTraces(payload)
+	if err != nil {
+		return nil, err
+	}
+	return (&ptrace.ProtoMarshaler{}).MarshalTraces(traces)
+}
+
+// MarshalTracesJSON encodes the payload as an OTLP/JSON ExportTraceServiceRequest
+func MarshalTracesJSON(payload OTLPTracesPayload) ([]byte, error) {
+	traces, err := ToTraces(payload)
+	if err != nil {
+		return nil, err
+	}
+	return (&ptrace.JSONMarshaler{}).MarshalTraces(traces)
+}
+
+// ToTraces converts the traces payload into collector pdata, which the official OTLP
+// marshalers and clients encode
+func ToTraces(payload OTLPTracesPayload) (ptrace.Traces, error) {
+	traces := ptrace.NewTraces()
+	resourceSpans := traces.ResourceSpans()
+	resourceSpans.EnsureCapacity(len(payload.ResourceSpans))
+
+	for _, rs := range payload.ResourceSpans {
+		pdResourceSpans := resourceSpans.AppendEmpty()
+		if err := putAttributes(pdResourceSpans.Resource().Attributes(), rs.Resource.Attributes); err != nil {
+			return ptrace.Traces{}, err
+		}
+
+		scopeSpans := pdResourceSpans.ScopeSpans()
+		scopeSpans.EnsureCapacity(len(rs.ScopeSpans))
+		for _, ss := range rs.ScopeSpans {
+			pdScopeSpans := scopeSpans.AppendEmpty()
+			pdScopeSpans.Scope().SetName(ss.Scope.Name)
+			pdScopeSpans.Scope().SetVersion(ss.Scope.Version)
+
+			spans := pdScopeSpans.Spans()
+			spans.EnsureCapacity(len(ss.Spans))
+			for _, span := range ss.Spans {
+				if err := putSpan(spans.AppendEmpty(), span); err != nil {
+					return ptrace.Traces{}, fmt.Errorf("span %q: %w", span.Name, err)
+				}
+			}
+		}
+	}
+
+	return traces, nil
+}
+
+func putSpan(dst ptrace.Span, span Span) error {
+	var traceID pcommon.TraceID
+	if err := decodeID(traceID[:], span.TraceID); err != nil {
+		return fmt.Errorf("invalid traceId %q: %w", span.TraceID, err)
+	}
+	dst.SetTraceID(traceID)
+
+	var spanID pcommon.SpanID
+	if err := decodeID(spanID[:], span.SpanID); err != nil {
+		return fmt.Errorf("invalid spanId %q: %w", span.SpanID, err)
+	}
+	dst.SetSpanID(spanID)
+
+	if span.ParentSpanID != "" {
+		var parentID pcommon.SpanID
+		if err := decodeID(parentID[:], span.ParentSpanID); err != nil {
+			return fmt.Errorf("invalid parentSpanId %q: %w", span.ParentSpanID, err)
+		}
+		dst.SetParentSpanID(parentID)
+	}
+
+	start, err := strconv.ParseUint(span.StartTimeUnixNano, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid startTimeUnixNano %q: %w", span.StartTimeUnixNano, err)
+	}
+	end, err := strconv.ParseUint(span.EndTimeUnixNano, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid endTimeUnixNano %q: %w", span.EndTimeUnixNano, err)
+	}
+
+	dst.SetName(span.Name)
+	dst.SetKind(ptrace.SpanKind(span.Kind))
+	dst.SetStartTimestamp(pcommon.Timestamp(start))
+	dst.SetEndTimestamp(pcommon.Timestamp(end))
+	if span.Status != nil {
+		dst.Status().SetCode(ptrace.StatusCode(span.Status.Code))
+		dst.Status().SetMessage(span.Status.Message)
+	}
+	return putAttributes(dst.Attributes(), span.Attributes)
+}
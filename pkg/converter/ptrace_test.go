@@ -0,0 +1,55 @@
+package converter
+
+import (
+	"testing"
+
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestMarshalTracesProto(t *testing.T) {
+	span := Span{
+		TraceID:           "5833726236d228ad5d99923122bbe354",
+		SpanID:            "0123456789abcdef",
+		ParentSpanID:      "53995c3f42cd8ad8",
+		Name:              "GET",
+		Kind:              SpanKindServer,
+		StartTimeUnixNano: "1700000000150000000",
+		EndTimeUnixNano:   "1700000000500000000",
+		Attributes:        []OTelAttribute{{Key: "http.response.status_code", Value: intValue(502)}},
+		Status:            &SpanStatus{Code: StatusCodeError},
+	}
+	data, err := MarshalTracesProto(OTLPTracesPayload{ResourceSpans: []ResourceSpans{{
+		ScopeSpans: []ScopeSpans{{Scope: Scope{Name: "otel-aws-log-parser"}, Spans: []Span{span}}},
+	}}})
+	if err != nil {
+		t.Fatalf("MarshalTracesProto() error = %v", err)
+	}
+
+	var req coltracepb.ExportTraceServiceRequest
+	if err := proto.Unmarshal(data, &req); err != nil {
+		t.Fatalf("Failed to unmarshal protobuf payload: %v", err)
+	}
+	pb := req.ResourceSpans[0].ScopeSpans[0].Spans[0]
+	if len(pb.TraceId) != 16 || len(pb.SpanId) != 8 || len(pb.ParentSpanId) != 8 {
+		t.Errorf("IDs = %x/%x/%x, want 16, 8 and 8 bytes", pb.TraceId, pb.SpanId, pb.ParentSpanId)
+	}
+	if pb.Kind != tracepb.Span_SPAN_KIND_SERVER || pb.Status.GetCode() != tracepb.Status_STATUS_CODE_ERROR {
+		t.Errorf("kind %v, status %v, want a server span with an error status", pb.Kind, pb.Status)
+	}
+	if pb.StartTimeUnixNano != 1700000000150000000 || pb.EndTimeUnixNano != 1700000000500000000 {
+		t.Errorf("span = [%d, %d]", pb.StartTimeUnixNano, pb.EndTimeUnixNano)
+	}
+	if pb.Attributes[0].Value.GetIntValue() != 502 {
+		t.Errorf("attributes = %v", pb.Attributes)
+	}
+}
+
+func TestToTraces_InvalidSpanID(t *testing.T) {
+	span := Span{TraceID: "5833726236d228ad5d99923122bbe354", SpanID: "xyz", StartTimeUnixNano: "1", EndTimeUnixNano: "2"}
+	payload := OTLPTracesPayload{ResourceSpans: []ResourceSpans{{ScopeSpans: []ScopeSpans{{Spans: []Span{span}}}}}}
+	if _, err := ToTraces(payload); err == nil {
+		t.Error("ToTraces() accepted an invalid span ID")
+	}
+}
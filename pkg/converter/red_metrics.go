@@ -0,0 +1,177 @@
+package converter
+
+import (
+	"sort"
+	"strconv"
+)
+
+// LatencyBounds are the bucket bounds of the request duration histogram, in seconds
+var LatencyBounds = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// albLatencyKeys are the ALB processing times that add up to a request's duration; -1 means
+// the phase did not complete
+var albLatencyKeys = []string{"aws.alb.request_processing_time", "aws.alb.target_processing_time", "aws.alb.response_processing_time"}
+
+// redSeries is one status class of one target group
+type redSeries struct {
+	targetGroup string
+	class       string
+}
+
+// redStats are the RED values of one series
+type redStats struct {
+	requests int64
+	errors   int64
+	// latency counts only the requests with a duration, so count may trail requests
+	count   int64
+	sum     float64
+	min     float64
+	max     float64
+	buckets []int64
+}
+
+// REDAggregator derives request rate, error and duration metrics from HTTP access log records
+// while they are grouped, per target group and status class. Records without a status code,
+// such as NLB or WAF records, are ignored.
+type REDAggregator struct {
+	series map[redSeries]*redStats
+	window timeWindow
+}
+
+// NewREDAggregator creates an empty aggregator
+func NewREDAggregator() *REDAggregator {
+	return &REDAggregator{series: make(map[redSeries]*redStats)}
+}
+
+// Add aggregates one record and widens the observed time window
+func (a *REDAggregator) Add(record OTelLogRecord) {
+	status := -1
+	var key redSeries
+	var latency float64
+	hasLatency, albPhases := false, 0
+	for _, attr := range record.Attributes {
+		switch attr.Key {
+		case "http.response.status_code":
+			if code, err := strconv.Atoi(attr.Value.Text()); err == nil {
+				status = code
+			}
+		case "aws.alb.target_group_arn":
+			key.targetGroup = attr.Value.Text()
+		case "aws.cloudfront.time_taken":
+			if attr.Value.DoubleValue != nil {
+				latency, hasLatency = *attr.Value.DoubleValue, true
+			}
+		case albLatencyKeys[0], albLatencyKeys[1], albLatencyKeys[2]:
+			if attr.Value.DoubleValue != nil && *attr.Value.DoubleValue >= 0 {
+				latency += *attr.Value.DoubleValue
+				albPhases++
+			}
+		}
+	}
+	if status < 100 {
+		return
+	}
+	if albPhases == len(albLatencyKeys) {
+		hasLatency = true
+	}
+
+	key.class = strconv.Itoa(status/100) + "xx"
+	stats, ok := a.series[key]
+	if !ok {
+		stats = &redStats{buckets: make([]int64, len(LatencyBounds)+1)}
+		a.series[key] = stats
+	}
+	stats.requests++
+	if status >= 500 {
+		stats.errors++
+	}
+	if hasLatency {
+		if stats.count == 0 || latency < stats.min {
+			stats.min = latency
+		}
+		if stats.count == 0 || latency > stats.max {
+			stats.max = latency
+		}
+		stats.count++
+		stats.sum += latency
+		stats.buckets[sort.SearchFloat64s(LatencyBounds, latency)]++
+	}
+	a.window.observe(record.TimeUnixNano)
+}
+
+// Metrics returns "aws.log.requests" and "aws.log.errors" delta sums and an
+// "aws.log.request.duration" histogram, each with one data point per target group and status
+// class. Errors are the 5xx responses.
+func (a *REDAggregator) Metrics() []Metric {
+	if a == nil || len(a.series) == 0 {
+		return nil
+	}
+
+	keys := make([]redSeries, 0, len(a.series))
+	for key := range a.series {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].targetGroup != keys[j].targetGroup {
+			return keys[i].targetGroup < keys[j].targetGroup
+		}
+		return keys[i].class < keys[j].class
+	})
+
+	start, end := a.window.bounds()
+	var requests, errors []NumberDataPoint
+	var durations []HistogramDataPoint
+	for _, key := range keys {
+		stats := a.series[key]
+		attrs := []OTelAttribute{{Key: "http.response.status_class", Value: stringValue(key.class)}}
+		if key.targetGroup != "" && key.targetGroup != "-" {
+			attrs = append(attrs, OTelAttribute{Key: "aws.alb.target_group_arn", Value: stringValue(key.targetGroup)})
+		}
+
+		requests = append(requests, NumberDataPoint{Attributes: attrs, StartTimeUnixNano: start, TimeUnixNano: end, AsInt: strconv.FormatInt(stats.requests, 10)})
+		if stats.errors > 0 {
+			errors = append(errors, NumberDataPoint{Attributes: attrs, StartTimeUnixNano: start, TimeUnixNano: end, AsInt: strconv.FormatInt(stats.errors, 10)})
+		}
+		if stats.count > 0 {
+			buckets := make([]string, len(stats.buckets))
+			for i, n := range stats.buckets {
+				buckets[i] = strconv.FormatInt(n, 10)
+			}
+			durations = append(durations, HistogramDataPoint{
+				Attributes:        attrs,
+				StartTimeUnixNano: start,
+				TimeUnixNano:      end,
+				Count:             strconv.FormatInt(stats.count, 10),
+				Sum:               stats.sum,
+				Min:               stats.min,
+				Max:               stats.max,
+				BucketCounts:      buckets,
+				ExplicitBounds:    LatencyBounds,
+			})
+		}
+	}
+
+	metrics := []Metric{{
+		Name:        "aws.log.requests",
+		Description: "HTTP requests in AWS access logs",
+		Unit:        "{request}",
+		Sum:         &Sum{DataPoints: requests, AggregationTemporality: AggregationTemporalityDelta, IsMonotonic: true},
+	}}
+	if len(errors) > 0 {
+		metrics = append(metrics, Metric{
+			Name:        "aws.log.errors",
+			Description: "HTTP requests in AWS access logs answered with a 5xx status",
+			Unit:        "{request}",
+			Sum:         &Sum{DataPoints: errors, AggregationTemporality: AggregationTemporalityDelta, IsMonotonic: true},
+		})
+	}
+	if len(durations) > 0 {
+		metrics = append(metrics, Metric{
+			Name:        "aws.log.request.duration",
+			Description: "Duration of HTTP requests in AWS access logs",
+			Unit:        "s",
+			Histogram:   &Histogram{DataPoints: durations, AggregationTemporality: AggregationTemporalityDelta},
+		})
+	}
+	return metrics
+}
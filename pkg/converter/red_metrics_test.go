@@ -0,0 +1,104 @@
+package converter
+
+import "testing"
+
+func redRecord(ts string, status int, targetGroup string, times ...float64) OTelLogRecord {
+	attrs := []OTelAttribute{{Key: "http.response.status_code", Value: intValue(status)}}
+	if targetGroup != "" {
+		attrs = append(attrs, OTelAttribute{Key: "aws.alb.target_group_arn", Value: stringValue(targetGroup)})
+	}
+	for i, v := range times {
+		attrs = append(attrs, OTelAttribute{Key: albLatencyKeys[i], Value: OTelAnyValue{DoubleValue: &v}})
+	}
+	return OTelLogRecord{TimeUnixNano: ts, Attributes: attrs}
+}
+
+func TestREDAggregator_Metrics(t *testing.T) {
+	a := NewREDAggregator()
+	if got := a.Metrics(); got != nil {
+		t.Errorf("Metrics() of empty aggregator = %+v, want nil", got)
+	}
+
+	a.Add(redRecord("200", 200, "tg-a", 0.001, 0.02, 0))
+	a.Add(redRecord("100", 204, "tg-a", 0.001, 0.2, 0))
+	a.Add(redRecord("300", 502, "tg-a", 0.001, -1, -1))
+	a.Add(redRecord("250", 503, "tg-b", 0, 3, 0))
+	a.Add(OTelLogRecord{TimeUnixNano: "50"}) // no status code: not an HTTP request
+
+	metrics := a.Metrics()
+	byName := map[string]Metric{}
+	for _, m := range metrics {
+		byName[m.Name] = m
+	}
+	if len(byName) != 3 {
+		t.Fatalf("Metrics() = %+v, want requests, errors and duration", metrics)
+	}
+
+	series := func(attrs []OTelAttribute) string {
+		key := ""
+		for _, attr := range attrs {
+			key += attr.Value.Text() + "/"
+		}
+		return key
+	}
+
+	requests := map[string]string{}
+	for _, p := range byName["aws.log.requests"].Sum.DataPoints {
+		requests[series(p.Attributes)] = p.AsInt
+		if p.StartTimeUnixNano != "100" || p.TimeUnixNano != "300" {
+			t.Errorf("window = [%s, %s], want [100, 300]", p.StartTimeUnixNano, p.TimeUnixNano)
+		}
+	}
+	wantRequests := map[string]string{"2xx/tg-a/": "2", "5xx/tg-a/": "1", "5xx/tg-b/": "1"}
+	if len(requests) != len(wantRequests) {
+		t.Errorf("requests = %v, want %v", requests, wantRequests)
+	}
+	for k, v := range wantRequests {
+		if requests[k] != v {
+			t.Errorf("requests[%s] = %s, want %s", k, requests[k], v)
+		}
+	}
+
+	if errors := byName["aws.log.errors"].Sum.DataPoints; len(errors) != 2 {
+		t.Errorf("errors = %+v, want one point per 5xx series", errors)
+	}
+
+	durations := map[string]HistogramDataPoint{}
+	for _, p := range byName["aws.log.request.duration"].Histogram.DataPoints {
+		durations[series(p.Attributes)] = p
+	}
+	// The 502 never reached its target, so it has no duration
+	if _, ok := durations["5xx/tg-a/"]; ok {
+		t.Errorf("incomplete request got a duration: %+v", durations["5xx/tg-a/"])
+	}
+	p2xx := durations["2xx/tg-a/"]
+	if p2xx.Count != "2" || p2xx.Min != 0.021 || p2xx.Max != 0.201 {
+		t.Errorf("2xx duration = %+v, want 2 requests from 0.021 to 0.201", p2xx)
+	}
+	if len(p2xx.BucketCounts) != len(LatencyBounds)+1 || p2xx.BucketCounts[2] != "1" || p2xx.BucketCounts[5] != "1" {
+		t.Errorf("2xx buckets = %v, want one in (0.01, 0.025] and one in (0.1, 0.25]", p2xx.BucketCounts)
+	}
+}
+
+func TestREDAggregator_CloudFrontTimeTaken(t *testing.T) {
+	a := NewREDAggregator()
+	taken := 0.5
+	a.Add(OTelLogRecord{TimeUnixNano: "1", Attributes: []OTelAttribute{
+		{Key: "http.response.status_code", Value: intValue(404)},
+		{Key: "aws.cloudfront.time_taken", Value: OTelAnyValue{DoubleValue: &taken}},
+	}})
+
+	for _, m := range a.Metrics() {
+		if m.Name == "aws.log.errors" {
+			t.Errorf("404 counted as an error")
+		}
+		if m.Name == "aws.log.request.duration" {
+			p := m.Histogram.DataPoints[0]
+			if p.Sum != 0.5 || p.BucketCounts[6] != "1" || len(p.Attributes) != 1 {
+				t.Errorf("duration = %+v, want 0.5s in (0.25, 0.5] without a target group", p)
+			}
+			return
+		}
+	}
+	t.Error("no aws.log.request.duration metric")
+}
@@ -0,0 +1,140 @@
+package converter
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+	"sync/atomic"
+)
+
+// RedactedValue replaces the value of a redacted query parameter or cookie
+const RedactedValue = "***"
+
+// DefaultRedactParams are the query parameters redacted unless REDACT_PARAMS says otherwise
+var DefaultRedactParams = []string{
+	"password", "passwd", "pwd", "secret", "token", "access_token", "id_token", "refresh_token",
+	"api_key", "apikey", "session", "sessionid", "sid", "auth", "authorization",
+	"x-amz-signature", "x-amz-credential", "x-amz-security-token",
+}
+
+// Redactor replaces the values of sensitive query parameters and cookies. Names match case
+// insensitively; "*" matches every name.
+type Redactor struct {
+	params     map[string]bool
+	allParams  bool
+	cookies    map[string]bool
+	allCookies bool
+	// text finds the named parameters in free text such as a raw log line
+	text *regexp.Regexp
+}
+
+// NewRedactor returns a redactor for the given parameter and cookie names, or nil when both
+// are empty
+func NewRedactor(params, cookies []string) *Redactor {
+	if len(params) == 0 && len(cookies) == 0 {
+		return nil
+	}
+	r := &Redactor{params: map[string]bool{}, cookies: map[string]bool{}}
+	var names []string
+	for _, p := range params {
+		if p == "*" {
+			r.allParams = true
+			continue
+		}
+		r.params[strings.ToLower(p)] = true
+		names = append(names, regexp.QuoteMeta(p))
+	}
+	for _, c := range cookies {
+		if c == "*" {
+			r.allCookies = true
+			continue
+		}
+		r.cookies[strings.ToLower(c)] = true
+		names = append(names, regexp.QuoteMeta(c))
+	}
+	if len(names) > 0 {
+		r.text = regexp.MustCompile(`(?i)((?:[?&;\s]|%20)(?:` + strings.Join(names, "|") + `)=)[^&;\s"#]*`)
+	}
+	return r
+}
+
+func (r *Redactor) redactsParam(name string) bool {
+	if r.allParams {
+		return true
+	}
+	if decoded, err := url.QueryUnescape(name); err == nil {
+		name = decoded
+	}
+	return r.params[strings.ToLower(name)]
+}
+
+// Query redacts the parameters of a raw query string, keeping its order and encoding
+func (r *Redactor) Query(query string) string {
+	if r == nil || query == "" || query == "-" {
+		return query
+	}
+	parts := strings.Split(query, "&")
+	for i, part := range parts {
+		if name, _, ok := strings.Cut(part, "="); ok && r.redactsParam(name) {
+			parts[i] = name + "=" + RedactedValue
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// URL redacts the query string of a URL
+func (r *Redactor) URL(u string) string {
+	if r == nil {
+		return u
+	}
+	base, query, ok := strings.Cut(u, "?")
+	if !ok {
+		return u
+	}
+	query, fragment, hasFragment := strings.Cut(query, "#")
+	out := base + "?" + r.Query(query)
+	if hasFragment {
+		out += "#" + fragment
+	}
+	return out
+}
+
+// Cookie redacts the values of a Cookie header, separated by "; " or its URL-encoded form
+func (r *Redactor) Cookie(cookie string) string {
+	if r == nil || cookie == "" || cookie == "-" {
+		return cookie
+	}
+	parts := strings.Split(cookie, ";")
+	for i, part := range parts {
+		// Keep the separator, a space or %20, in front of the name
+		trimmed := strings.TrimLeft(strings.TrimPrefix(strings.TrimLeft(part, " "), "%20"), " ")
+		lead := part[:len(part)-len(trimmed)]
+		name, _, ok := strings.Cut(trimmed, "=")
+		if ok && (r.allCookies || r.cookies[strings.ToLower(name)]) {
+			parts[i] = lead + name + "=" + RedactedValue
+		}
+	}
+	return strings.Join(parts, ";")
+}
+
+// Text redacts the named parameters and cookies wherever they appear in free text, such as a
+// raw log line. "*" cannot be applied to free text and is ignored.
+func (r *Redactor) Text(text string) string {
+	if r == nil || r.text == nil {
+		return text
+	}
+	return r.text.ReplaceAllString(text, "${1}"+RedactedValue)
+}
+
+var redactor atomic.Pointer[Redactor]
+
+// SetRedactor installs the redactor the converters apply to URLs, query strings and cookies;
+// nil turns redaction off
+func SetRedactor(r *Redactor) {
+	redactor.Store(r)
+}
+
+// ActiveRedactor is the redactor installed by SetRedactor, or nil
+func ActiveRedactor() *Redactor {
+	return redactor.Load()
+}
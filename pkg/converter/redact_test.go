@@ -0,0 +1,76 @@
+package converter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/parser"
+)
+
+func TestRedactor(t *testing.T) {
+	r := NewRedactor([]string{"token", "Password"}, []string{"session-id"})
+
+	tests := []struct {
+		name string
+		fn   func(string) string
+		in   string
+		want string
+	}{
+		{"query", r.Query, "a=1&token=abc&PASSWORD=p%40ss&b", "a=1&token=***&PASSWORD=***&b"},
+		{"query encoded name", r.Query, "to%6Ben=abc", "to%6Ben=***"},
+		{"query placeholder", r.Query, "-", "-"},
+		{"url", r.URL, "https://example.com:443/login?user=bob&token=abc#top", "https://example.com:443/login?user=bob&token=***#top"},
+		{"url without query", r.URL, "https://example.com/", "https://example.com/"},
+		{"cookie", r.Cookie, "session-id=123; theme=dark", "session-id=***; theme=dark"},
+		{"cookie encoded", r.Cookie, "theme=dark;%20session-id=123", "theme=dark;%20session-id=***"},
+		{"text", r.Text, `"GET https://example.com/?token=abc&x=1 HTTP/1.1" session-id=9`, `"GET https://example.com/?token=***&x=1 HTTP/1.1" session-id=***`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.fn(tt.in); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	all := NewRedactor([]string{"*"}, []string{"*"})
+	if got := all.Query("a=1&b=2"); got != "a=***&b=***" {
+		t.Errorf("wildcard Query() = %q", got)
+	}
+	if got := all.Cookie("a=1; b=2"); got != "a=***; b=***" {
+		t.Errorf("wildcard Cookie() = %q", got)
+	}
+	if NewRedactor(nil, nil) != nil {
+		t.Error("NewRedactor() without names is not nil")
+	}
+}
+
+func TestConvertersRedact(t *testing.T) {
+	SetRedactor(NewRedactor(DefaultRedactParams, []string{"*"}))
+	t.Cleanup(func() { SetRedactor(nil) })
+
+	alb := &parser.ALBLogEntry{Time: "2025-12-04T00:55:01.294082Z", RequestVerb: "GET", RequestURL: "https://example.com:443/cb?code=1&access_token=abc", RequestProto: "HTTP/1.1"}
+	record := ConvertToOTel(alb)
+	if body := record.Body.Text(); strings.Contains(body, "abc") {
+		t.Errorf("ALB body not redacted: %s", body)
+	}
+	for _, key := range []string{"url.full", "url.query"} {
+		if attr, ok := findAttr(record.Attributes, key); !ok || strings.Contains(*attr.Value.StringValue, "abc") {
+			t.Errorf("ALB %s not redacted", key)
+		}
+	}
+
+	cf := &parser.CloudFrontLogEntry{Date: "2024-01-01", Time: "00:00:00", CSMethod: "GET", CSURIQuery: "sid=s3cr3t&page=2", CSCookie: "session=xyz"}
+	attrs := ConvertCloudFrontToOTel(cf).Attributes
+	if attr, _ := findAttr(attrs, "url.query"); attr.Value.StringValue == nil || *attr.Value.StringValue != "sid=***&page=2" {
+		t.Errorf("CloudFront url.query = %v", attr.Value.StringValue)
+	}
+	if attr, _ := findAttr(attrs, "aws.cloudfront.cookie"); attr.Value.StringValue == nil || *attr.Value.StringValue != "session=***" {
+		t.Errorf("CloudFront cookie = %v", attr.Value.StringValue)
+	}
+
+	SetRedactor(nil)
+	if _, ok := findAttr(ConvertCloudFrontToOTel(cf).Attributes, "aws.cloudfront.cookie"); ok {
+		t.Error("cookie exported without a redactor")
+	}
+}
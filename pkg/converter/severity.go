@@ -0,0 +1,104 @@
+package converter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// Severity is an OTel log severity: the number backends filter on and its short text
+type Severity struct {
+	Number int
+	Text   string
+}
+
+var (
+	SeverityDebug = Severity{Number: 5, Text: "DEBUG"}
+	SeverityInfo  = Severity{Number: 9, Text: "INFO"}
+	SeverityWarn  = Severity{Number: 13, Text: "WARN"}
+	SeverityError = Severity{Number: 17, Text: "ERROR"}
+	SeverityFatal = Severity{Number: 21, Text: "FATAL"}
+)
+
+var severitiesByText = map[string]Severity{
+	"TRACE": {Number: 1, Text: "TRACE"},
+	"DEBUG": SeverityDebug,
+	"INFO":  SeverityInfo,
+	"WARN":  SeverityWarn,
+	"ERROR": SeverityError,
+	"FATAL": SeverityFatal,
+}
+
+// StatusSeverity maps HTTP status codes to severities: exact codes first, then status classes
+// ("4xx"), then the default of 5xx ERROR, 4xx WARN and INFO for everything else
+type StatusSeverity struct {
+	codes   map[int]Severity
+	classes map[int]Severity
+}
+
+// ParseStatusSeverity parses overrides such as "404=INFO,429=ERROR,3xx=DEBUG". Keys are status
+// codes or classes, values are TRACE, DEBUG, INFO, WARN, ERROR or FATAL. An empty spec returns nil.
+func ParseStatusSeverity(spec string) (*StatusSeverity, error) {
+	m := &StatusSeverity{codes: map[int]Severity{}, classes: map[int]Severity{}}
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		key, text, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("%q is not status=severity", item)
+		}
+		sev, ok := severitiesByText[strings.ToUpper(strings.TrimSpace(text))]
+		if !ok {
+			return nil, fmt.Errorf("unknown severity %q for %s", text, key)
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		if class, ok := strings.CutSuffix(key, "xx"); ok && len(class) == 1 && class[0] >= '1' && class[0] <= '5' {
+			m.classes[int(class[0]-'0')] = sev
+			continue
+		}
+		code, err := strconv.Atoi(key)
+		if err != nil || code < 100 || code > 599 {
+			return nil, fmt.Errorf("invalid status %q", key)
+		}
+		m.codes[code] = sev
+	}
+	if len(m.codes) == 0 && len(m.classes) == 0 {
+		return nil, nil
+	}
+	return m, nil
+}
+
+// For returns the severity of a status code; a nil mapping applies the defaults
+func (m *StatusSeverity) For(status int) Severity {
+	if m != nil {
+		if sev, ok := m.codes[status]; ok {
+			return sev
+		}
+		if sev, ok := m.classes[status/100]; ok {
+			return sev
+		}
+	}
+	switch {
+	case status >= 500:
+		return SeverityError
+	case status >= 400:
+		return SeverityWarn
+	default:
+		return SeverityInfo
+	}
+}
+
+var statusSeverity atomic.Pointer[StatusSeverity]
+
+// SetStatusSeverity installs the overrides used by the HTTP converters; nil restores the defaults
+func SetStatusSeverity(m *StatusSeverity) {
+	statusSeverity.Store(m)
+}
+
+// SeverityForStatus is the severity of an HTTP request log with the given status code
+func SeverityForStatus(status int) Severity {
+	return statusSeverity.Load().For(status)
+}
@@ -0,0 +1,63 @@
+package converter
+
+import (
+	"testing"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/parser"
+)
+
+func TestStatusSeverity(t *testing.T) {
+	overrides, err := ParseStatusSeverity("404=info, 429=ERROR, 3xx=DEBUG")
+	if err != nil {
+		t.Fatalf("ParseStatusSeverity() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		mapping *StatusSeverity
+		status  int
+		want    Severity
+	}{
+		{"default 5xx", nil, 503, SeverityError},
+		{"default 4xx", nil, 404, SeverityWarn},
+		{"default 2xx", nil, 200, SeverityInfo},
+		{"missing status", nil, 0, SeverityInfo},
+		{"code override", overrides, 404, SeverityInfo},
+		{"code override raises", overrides, 429, SeverityError},
+		{"class override", overrides, 301, SeverityDebug},
+		{"falls back to defaults", overrides, 500, SeverityError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.mapping.For(tt.status); got != tt.want {
+				t.Errorf("For(%d) = %+v, want %+v", tt.status, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseStatusSeverity_Invalid(t *testing.T) {
+	for _, spec := range []string{"404", "404=LOUD", "abc=INFO", "6xx=INFO", "99=INFO"} {
+		if _, err := ParseStatusSeverity(spec); err == nil {
+			t.Errorf("ParseStatusSeverity(%q) succeeded, want error", spec)
+		}
+	}
+	if m, err := ParseStatusSeverity(""); m != nil || err != nil {
+		t.Errorf("ParseStatusSeverity(\"\") = %v, %v, want nil, nil", m, err)
+	}
+}
+
+func TestSetStatusSeverity(t *testing.T) {
+	overrides, _ := ParseStatusSeverity("4xx=INFO")
+	SetStatusSeverity(overrides)
+	defer SetStatusSeverity(nil)
+
+	alb := ConvertToOTel(&parser.ALBLogEntry{Time: "2024-01-01T00:00:00.000000Z", ELBStatusCode: 403})
+	cf := ConvertCloudFrontToOTel(&parser.CloudFrontLogEntry{Date: "2024-01-01", Time: "00:00:00", SCStatus: 502})
+	if alb.SeverityText != "INFO" || alb.SeverityNumber != 9 {
+		t.Errorf("ALB 403 severity = %s/%d, want INFO/9", alb.SeverityText, alb.SeverityNumber)
+	}
+	if cf.SeverityText != "ERROR" || cf.SeverityNumber != 17 {
+		t.Errorf("CloudFront 502 severity = %s/%d, want ERROR/17", cf.SeverityText, cf.SeverityNumber)
+	}
+}
@@ -0,0 +1,69 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+)
+
+// ErrCircuitOpen fails the batches a tripped CircuitBreaker does not attempt
+var ErrCircuitOpen = errors.New("circuit breaker open after consecutive export failures")
+
+// CircuitBreaker stops exporting once Threshold batches in a row have failed: later batches
+// fail with ErrCircuitOpen without reaching the sink until Reset, so a down collector is not
+// hammered with every remaining batch and its retries. Wrapped in a DeadLetterExporter, those
+// batches are dead-lettered instead.
+type CircuitBreaker struct {
+	next      Exporter
+	threshold int
+	logger    *slog.Logger
+
+	mu       sync.Mutex
+	failures int
+	open     bool
+}
+
+// NewCircuitBreaker wraps next with a breaker that trips after threshold consecutive failures
+func NewCircuitBreaker(next Exporter, threshold int, logger *slog.Logger) *CircuitBreaker {
+	return &CircuitBreaker{next: next, threshold: threshold, logger: loggerOrDefault(logger)}
+}
+
+// Export sends the batch unless the breaker is open
+func (b *CircuitBreaker) Export(ctx context.Context, logs converter.ResourceLog) error {
+	if b.Open() {
+		return ErrCircuitOpen
+	}
+
+	err := b.next.Export(ctx, logs)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err == nil {
+		b.failures = 0
+		return nil
+	}
+	b.failures++
+	if b.failures >= b.threshold && !b.open {
+		b.open = true
+		b.logger.Error("Circuit breaker tripped, failing remaining batches without sending them", "consecutive_failures", b.failures)
+	}
+	return err
+}
+
+// Open reports whether the breaker has tripped
+func (b *CircuitBreaker) Open() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.open
+}
+
+// Reset closes the breaker and forgets past failures, e.g. at the start of an invocation
+func (b *CircuitBreaker) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+	b.open = false
+}
@@ -0,0 +1,86 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	calls := 0
+	var fail bool
+	breaker := NewCircuitBreaker(ExporterFunc(func(ctx context.Context, logs converter.ResourceLog) error {
+		calls++
+		if fail {
+			return errors.New("HTTP 503")
+		}
+		return nil
+	}), 2, nil)
+	export := func() error { return breaker.Export(context.Background(), converter.ResourceLog{}) }
+
+	// A success between failures resets the count
+	fail = true
+	export()
+	fail = false
+	export()
+	fail = true
+	export()
+	if breaker.Open() {
+		t.Fatal("breaker tripped without 2 consecutive failures")
+	}
+
+	export()
+	if !breaker.Open() {
+		t.Fatal("breaker not tripped after 2 consecutive failures")
+	}
+	before := calls
+	if err := export(); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Export() error = %v, want ErrCircuitOpen", err)
+	}
+	if calls != before {
+		t.Error("open breaker sent the batch")
+	}
+
+	breaker.Reset()
+	fail = false
+	if err := export(); err != nil || calls != before+1 {
+		t.Errorf("Export() after Reset error = %v, calls = %d, want the batch sent", err, calls-before)
+	}
+}
+
+func TestCircuitBreaker_DeadLettersRemainder(t *testing.T) {
+	queue := &memoryQueue{}
+	breaker := NewCircuitBreaker(ExporterFunc(func(ctx context.Context, logs converter.ResourceLog) error {
+		return errors.New("HTTP 503")
+	}), 1, nil)
+	exp := NewDeadLetterExporter(breaker, queue, nil)
+
+	for i := 0; i < 3; i++ {
+		if err := exp.Export(context.Background(), archiveTestLogs()); err != nil {
+			t.Fatalf("Export() error = %v, want the batch dead-lettered", err)
+		}
+	}
+	if len(queue.letters) != 3 || queue.letters[2].Error != ErrCircuitOpen.Error() {
+		t.Errorf("dead letters = %d, want 3 with the last failed by the open breaker", len(queue.letters))
+	}
+}
+
+// memoryQueue is a DeadLetterQueue holding letters in memory
+type memoryQueue struct {
+	letters []DeadLetter
+}
+
+func (q *memoryQueue) Put(ctx context.Context, letter DeadLetter) error {
+	q.letters = append(q.letters, letter)
+	return nil
+}
+
+func (q *memoryQueue) Receive(ctx context.Context, max int) ([]StoredDeadLetter, error) {
+	return nil, nil
+}
+
+func (q *memoryQueue) Delete(ctx context.Context, handle string) error {
+	return nil
+}
@@ -0,0 +1,54 @@
+package exporter
+
+import (
+	"crypto/tls"
+	"net/http"
+	"time"
+)
+
+// HTTPClientConfig configures the HTTP client shared by the HTTP-based exporters
+type HTTPClientConfig struct {
+	// Timeout bounds a whole request including reading the response (default 30s)
+	Timeout time.Duration
+	// MaxIdleConns and MaxIdleConnsPerHost size the keep-alive pool (defaults 100 and 20).
+	// The per-host limit should cover the export concurrency, or connections are re-dialed.
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	// IdleConnTimeout closes pooled connections unused for this long (default 90s)
+	IdleConnTimeout time.Duration
+	// DisableHTTP2 restricts TLS connections to HTTP/1.1
+	DisableHTTP2 bool
+	// TLS overrides the TLS settings (custom CA, client certificate); see NewTLSConfig
+	TLS *tls.Config
+}
+
+// NewHTTPClient creates a pooled client. Proxies are taken from HTTPS_PROXY/HTTP_PROXY/NO_PROXY.
+func NewHTTPClient(cfg HTTPClientConfig) *http.Client {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+	if cfg.MaxIdleConns <= 0 {
+		cfg.MaxIdleConns = 100
+	}
+	if cfg.MaxIdleConnsPerHost <= 0 {
+		cfg.MaxIdleConnsPerHost = 20
+	}
+	if cfg.IdleConnTimeout <= 0 {
+		cfg.IdleConnTimeout = 90 * time.Second
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyFromEnvironment
+	transport.MaxIdleConns = cfg.MaxIdleConns
+	transport.MaxIdleConnsPerHost = cfg.MaxIdleConnsPerHost
+	transport.IdleConnTimeout = cfg.IdleConnTimeout
+	if cfg.TLS != nil {
+		transport.TLSClientConfig = cfg.TLS.Clone()
+	}
+	if cfg.DisableHTTP2 {
+		transport.ForceAttemptHTTP2 = false
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+
+	return &http.Client{Timeout: cfg.Timeout, Transport: transport}
+}
@@ -0,0 +1,54 @@
+package exporter
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestNewHTTPClient_ReusesConnections(t *testing.T) {
+	var dials atomic.Int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			dials.Add(1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	client := NewHTTPClient(HTTPClientConfig{})
+	logsExp := NewOTLPHTTPExporter(OTLPHTTPConfig{Endpoint: server.URL, Client: client, Logger: discardLogger})
+	metricsExp := NewOTLPHTTPMetricsExporter(OTLPHTTPConfig{Endpoint: server.URL, Client: client, Logger: discardLogger})
+
+	for i := 0; i < 3; i++ {
+		if err := logsExp.Export(context.Background(), testResourceLog(helloRecord())); err != nil {
+			t.Fatalf("Export() error = %v", err)
+		}
+	}
+	if err := metricsExp.poster.post(context.Background(), []byte(`{}`), "application/json"); err != nil {
+		t.Fatalf("post() error = %v", err)
+	}
+
+	if got := dials.Load(); got != 1 {
+		t.Errorf("server saw %d connections, want 1 shared keep-alive connection", got)
+	}
+}
+
+func TestNewHTTPClient_Settings(t *testing.T) {
+	transport := NewHTTPClient(HTTPClientConfig{MaxIdleConnsPerHost: 50, DisableHTTP2: true}).Transport.(*http.Transport)
+	if transport.MaxIdleConnsPerHost != 50 || transport.MaxIdleConns != 100 {
+		t.Errorf("pool = %d/%d, want 100/50", transport.MaxIdleConns, transport.MaxIdleConnsPerHost)
+	}
+	if transport.ForceAttemptHTTP2 || transport.TLSNextProto == nil {
+		t.Error("HTTP/2 not disabled")
+	}
+	if transport.Proxy == nil {
+		t.Error("proxy from environment not configured")
+	}
+}
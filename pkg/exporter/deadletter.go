@@ -0,0 +1,321 @@
+package exporter
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+)
+
+// deadLetterWriteTimeout bounds a dead-letter write, which runs even after the export context ended
+const deadLetterWriteTimeout = 10 * time.Second
+
+// DeadLetter is a batch that could not be exported, with the reason
+type DeadLetter struct {
+	FailedAt time.Time `json:"failed_at"`
+	Error    string    `json:"error"`
+	// Member names the fan-out member the batch failed for; empty when it failed as a whole
+	Member string `json:"member,omitempty"`
+	// Payload is the OTLP/JSON logs request that failed
+	Payload converter.OTLPPayload `json:"payload"`
+}
+
+// StoredDeadLetter is a dead letter read back from a queue; Handle deletes it
+type StoredDeadLetter struct {
+	Handle string
+	DeadLetter
+}
+
+// DeadLetterQueue stores failed batches for a later replay
+type DeadLetterQueue interface {
+	Put(ctx context.Context, letter DeadLetter) error
+	// Receive returns up to max stored letters; an empty result means the queue is drained
+	Receive(ctx context.Context, max int) ([]StoredDeadLetter, error)
+	Delete(ctx context.Context, handle string) error
+}
+
+// DeadLetterExporter writes batches its exporter gave up on to a dead-letter queue. A batch
+// that reaches the queue counts as handled, so the source event is not retried for it. When
+// some members of a FanOut failed, each of them gets its own letter holding the records routed
+// to it, so a replay does not send the batch again to the members that delivered it.
+type DeadLetterExporter struct {
+	next   Exporter
+	queue  DeadLetterQueue
+	logger *slog.Logger
+}
+
+// NewDeadLetterExporter wraps next so that its failures are dead-lettered to queue
+func NewDeadLetterExporter(next Exporter, queue DeadLetterQueue, logger *slog.Logger) *DeadLetterExporter {
+	return &DeadLetterExporter{next: next, queue: queue, logger: loggerOrDefault(logger)}
+}
+
+// Export sends the batch and dead-letters it when sending fails
+func (e *DeadLetterExporter) Export(ctx context.Context, logs converter.ResourceLog) error {
+	err := e.next.Export(ctx, logs)
+	if err == nil {
+		return nil
+	}
+
+	// The export may have failed because ctx ended; the write still gets its own short budget
+	dlqCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), deadLetterWriteTimeout)
+	defer cancel()
+
+	failedAt := time.Now().UTC()
+	letters := []DeadLetter{{FailedAt: failedAt, Error: err.Error(), Payload: payloadOf(logs)}}
+	var fanErr *FanOutError
+	if errors.As(err, &fanErr) {
+		letters = letters[:0]
+		for _, member := range slices.Sorted(maps.Keys(fanErr.Failed)) {
+			letters = append(letters, DeadLetter{FailedAt: failedAt, Error: err.Error(), Member: member, Payload: payloadOf(fanErr.Failed[member])})
+		}
+	}
+	for _, letter := range letters {
+		if dlqErr := e.queue.Put(dlqCtx, letter); dlqErr != nil {
+			return errors.Join(err, fmt.Errorf("failed to dead-letter batch: %w", dlqErr))
+		}
+		e.logger.Warn("Batch dead-lettered", "member", letter.Member, "error", err)
+	}
+	return nil
+}
+
+// encodeDeadLetter serializes a letter as gzipped JSON
+func encodeDeadLetter(letter DeadLetter) ([]byte, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(gz).Encode(letter); err != nil {
+		return nil, fmt.Errorf("failed to marshal dead letter: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress dead letter: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeDeadLetter(r io.Reader) (DeadLetter, error) {
+	var letter DeadLetter
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return letter, fmt.Errorf("failed to decompress dead letter: %w", err)
+	}
+	defer gz.Close()
+	if err := json.NewDecoder(gz).Decode(&letter); err != nil {
+		return letter, fmt.Errorf("failed to decode dead letter: %w", err)
+	}
+	return letter, nil
+}
+
+// S3DeadLetterQueue stores each letter as a gzipped JSON object under
+// <prefix>/<yyyy>/<MM>/<dd>/<unix-ms>-<random>.json.gz
+type S3DeadLetterQueue struct {
+	Client S3API
+	Bucket string
+	Prefix string
+}
+
+func (q *S3DeadLetterQueue) Put(ctx context.Context, letter DeadLetter) error {
+	body, err := encodeDeadLetter(letter)
+	if err != nil {
+		return err
+	}
+	var id [8]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return fmt.Errorf("failed to generate object id: %w", err)
+	}
+	key := q.prefix() + fmt.Sprintf("%s/%d-%s.json.gz", letter.FailedAt.Format("2006/01/02"), letter.FailedAt.UnixMilli(), hex.EncodeToString(id[:]))
+
+	_, err = q.Client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:          aws.String(q.Bucket),
+		Key:             aws.String(key),
+		Body:            bytes.NewReader(body),
+		ContentType:     aws.String("application/json"),
+		ContentEncoding: aws.String("gzip"),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to put dead letter: %w", err)
+	}
+	return nil
+}
+
+func (q *S3DeadLetterQueue) Receive(ctx context.Context, max int) ([]StoredDeadLetter, error) {
+	list, err := q.Client.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
+		Bucket:  aws.String(q.Bucket),
+		Prefix:  aws.String(q.prefix()),
+		MaxKeys: aws.Int32(int32(max)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dead letters: %w", err)
+	}
+
+	letters := make([]StoredDeadLetter, 0, len(list.Contents))
+	for _, obj := range list.Contents {
+		out, err := q.Client.GetObject(ctx, &s3.GetObjectInput{Bucket: aws.String(q.Bucket), Key: obj.Key})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get dead letter %s: %w", aws.ToString(obj.Key), err)
+		}
+		letter, err := decodeDeadLetter(out.Body)
+		out.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", aws.ToString(obj.Key), err)
+		}
+		letters = append(letters, StoredDeadLetter{Handle: aws.ToString(obj.Key), DeadLetter: letter})
+	}
+	return letters, nil
+}
+
+func (q *S3DeadLetterQueue) Delete(ctx context.Context, handle string) error {
+	_, err := q.Client.DeleteObject(ctx, &s3.DeleteObjectInput{Bucket: aws.String(q.Bucket), Key: aws.String(handle)})
+	if err != nil {
+		return fmt.Errorf("failed to delete dead letter: %w", err)
+	}
+	return nil
+}
+
+func (q *S3DeadLetterQueue) prefix() string {
+	if p := strings.Trim(q.Prefix, "/"); p != "" {
+		return p + "/"
+	}
+	return ""
+}
+
+// sqsMaxMessageBytes is the SQS message size limit
+const sqsMaxMessageBytes = 256 * 1024
+
+// SQSAPI is the part of the SQS client SQSDeadLetterQueue uses; *sqs.Client implements it
+type SQSAPI interface {
+	SendMessage(ctx context.Context, in *sqs.SendMessageInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageOutput, error)
+	ReceiveMessage(ctx context.Context, in *sqs.ReceiveMessageInput, optFns ...func(*sqs.Options)) (*sqs.ReceiveMessageOutput, error)
+	DeleteMessage(ctx context.Context, in *sqs.DeleteMessageInput, optFns ...func(*sqs.Options)) (*sqs.DeleteMessageOutput, error)
+}
+
+// SQSDeadLetterQueue sends each letter as a base64-encoded, gzipped JSON message. Letters
+// larger than the 256 KiB SQS limit after compression are rejected; lower MAX_BATCH_SIZE or
+// use the S3 queue for very large batches.
+type SQSDeadLetterQueue struct {
+	Client   SQSAPI
+	QueueURL string
+	// VisibilityTimeout hides received letters from other readers while they are replayed
+	VisibilityTimeout time.Duration
+}
+
+func (q *SQSDeadLetterQueue) Put(ctx context.Context, letter DeadLetter) error {
+	body, err := encodeDeadLetter(letter)
+	if err != nil {
+		return err
+	}
+	encoded := base64.StdEncoding.EncodeToString(body)
+	if len(encoded) > sqsMaxMessageBytes {
+		return fmt.Errorf("dead letter is %d bytes, over the SQS limit of %d", len(encoded), sqsMaxMessageBytes)
+	}
+
+	_, err = q.Client.SendMessage(ctx, &sqs.SendMessageInput{
+		QueueUrl:    aws.String(q.QueueURL),
+		MessageBody: aws.String(encoded),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send dead letter: %w", err)
+	}
+	return nil
+}
+
+func (q *SQSDeadLetterQueue) Receive(ctx context.Context, max int) ([]StoredDeadLetter, error) {
+	if max > 10 {
+		max = 10 // SQS receive limit
+	}
+	in := &sqs.ReceiveMessageInput{
+		QueueUrl:            aws.String(q.QueueURL),
+		MaxNumberOfMessages: int32(max),
+		WaitTimeSeconds:     1,
+	}
+	if q.VisibilityTimeout > 0 {
+		in.VisibilityTimeout = int32(q.VisibilityTimeout / time.Second)
+	}
+	out, err := q.Client.ReceiveMessage(ctx, in)
+	if err != nil {
+		return nil, fmt.Errorf("failed to receive dead letters: %w", err)
+	}
+
+	letters := make([]StoredDeadLetter, 0, len(out.Messages))
+	for _, msg := range out.Messages {
+		body, err := base64.StdEncoding.DecodeString(aws.ToString(msg.Body))
+		if err != nil {
+			return nil, fmt.Errorf("message %s is not a dead letter: %w", aws.ToString(msg.MessageId), err)
+		}
+		letter, err := decodeDeadLetter(bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("message %s: %w", aws.ToString(msg.MessageId), err)
+		}
+		letters = append(letters, StoredDeadLetter{Handle: aws.ToString(msg.ReceiptHandle), DeadLetter: letter})
+	}
+	return letters, nil
+}
+
+func (q *SQSDeadLetterQueue) Delete(ctx context.Context, handle string) error {
+	_, err := q.Client.DeleteMessage(ctx, &sqs.DeleteMessageInput{QueueUrl: aws.String(q.QueueURL), ReceiptHandle: aws.String(handle)})
+	if err != nil {
+		return fmt.Errorf("failed to delete dead letter: %w", err)
+	}
+	return nil
+}
+
+// ReplayDeadLetters re-sends stored letters through exp, deleting each one that was delivered.
+// A letter for one fan-out member is only sent to that member, which requires exp to be the
+// FanOut. It stops at the first failure, leaving that letter in the queue, or after limit
+// letters when limit > 0. It returns the number of letters replayed.
+func ReplayDeadLetters(ctx context.Context, queue DeadLetterQueue, exp Exporter, limit int, logger *slog.Logger) (int, error) {
+	logger = loggerOrDefault(logger)
+	replayed := 0
+	for limit <= 0 || replayed < limit {
+		batch := 10
+		if limit > 0 && limit-replayed < batch {
+			batch = limit - replayed
+		}
+		letters, err := queue.Receive(ctx, batch)
+		if err != nil {
+			return replayed, err
+		}
+		if len(letters) == 0 {
+			break
+		}
+
+		for _, letter := range letters {
+			send := exp.Export
+			if letter.Member != "" {
+				fan, ok := exp.(*FanOut)
+				if !ok {
+					return replayed, fmt.Errorf("dead letter from %s is for exporter %q, which needs a fan-out to replay", letter.FailedAt.Format(time.RFC3339), letter.Member)
+				}
+				send = func(ctx context.Context, logs converter.ResourceLog) error {
+					return fan.ExportTo(ctx, letter.Member, logs)
+				}
+			}
+			for _, logs := range letter.Payload.ResourceLogs {
+				if err := send(ctx, logs); err != nil {
+					return replayed, fmt.Errorf("failed to replay dead letter from %s: %w", letter.FailedAt.Format(time.RFC3339), err)
+				}
+			}
+			if err := queue.Delete(ctx, letter.Handle); err != nil {
+				return replayed, err
+			}
+			replayed++
+			logger.Info("Dead letter replayed", "failed_at", letter.FailedAt, "member", letter.Member, "original_error", letter.Error)
+		}
+	}
+	return replayed, nil
+}
@@ -0,0 +1,155 @@
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+)
+
+func (f *fakeS3) ListObjectsV2(ctx context.Context, input *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	var keys []string
+	for key := range f.objects {
+		if strings.HasPrefix(key, aws.ToString(input.Prefix)) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	if max := int(aws.ToInt32(input.MaxKeys)); max > 0 && len(keys) > max {
+		keys = keys[:max]
+	}
+	out := &s3.ListObjectsV2Output{}
+	for _, key := range keys {
+		out.Contents = append(out.Contents, types.Object{Key: aws.String(key)})
+	}
+	return out, nil
+}
+
+func (f *fakeS3) GetObject(ctx context.Context, input *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	body, ok := f.objects[aws.ToString(input.Key)]
+	if !ok {
+		return nil, errors.New("NoSuchKey")
+	}
+	return &s3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(body))}, nil
+}
+
+func (f *fakeS3) DeleteObject(ctx context.Context, input *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	delete(f.objects, aws.ToString(input.Key))
+	return &s3.DeleteObjectOutput{}, nil
+}
+
+func TestDeadLetterExporter_S3Replay(t *testing.T) {
+	fake := &fakeS3{}
+	queue := &S3DeadLetterQueue{Client: fake, Bucket: "dlq", Prefix: "/failed/"}
+
+	failing := NewDeadLetterExporter(ExporterFunc(func(ctx context.Context, logs converter.ResourceLog) error {
+		return errors.New("HTTP 503")
+	}), queue, nil)
+
+	for i := 0; i < 3; i++ {
+		if err := failing.Export(context.Background(), archiveTestLogs()); err != nil {
+			t.Fatalf("Export() error = %v, want the batch dead-lettered", err)
+		}
+	}
+	if len(fake.objects) != 3 {
+		t.Fatalf("dead letters stored = %d, want 3", len(fake.objects))
+	}
+	for key := range fake.objects {
+		if !strings.HasPrefix(key, "failed/") || !strings.HasSuffix(key, ".json.gz") {
+			t.Errorf("dead letter key = %q", key)
+		}
+	}
+
+	// A failing replay leaves the letter in place
+	_, err := ReplayDeadLetters(context.Background(), queue, ExporterFunc(func(ctx context.Context, logs converter.ResourceLog) error {
+		return errors.New("still down")
+	}), 0, nil)
+	if err == nil || len(fake.objects) != 3 {
+		t.Fatalf("failed replay = %v with %d letters left, want an error and 3 letters", err, len(fake.objects))
+	}
+
+	var replayed []converter.ResourceLog
+	n, err := ReplayDeadLetters(context.Background(), queue, ExporterFunc(func(ctx context.Context, logs converter.ResourceLog) error {
+		replayed = append(replayed, logs)
+		return nil
+	}), 2, nil)
+	if err != nil || n != 2 || len(fake.objects) != 1 {
+		t.Fatalf("ReplayDeadLetters() = %d, %v with %d letters left, want 2 replayed and 1 left", n, err, len(fake.objects))
+	}
+	if got := len(replayed[0].ScopeLogs[0].LogRecords); got != 2 {
+		t.Errorf("replayed batch has %d records, want 2", got)
+	}
+}
+
+func TestDeadLetterExporter_QueueFailure(t *testing.T) {
+	fake := &fakeS3{failOnce: true}
+	exp := NewDeadLetterExporter(ExporterFunc(func(ctx context.Context, logs converter.ResourceLog) error {
+		return errors.New("HTTP 503")
+	}), &S3DeadLetterQueue{Client: fake, Bucket: "dlq"}, nil)
+
+	err := exp.Export(context.Background(), archiveTestLogs())
+	if err == nil || !strings.Contains(err.Error(), "HTTP 503") || !strings.Contains(err.Error(), "dead-letter") {
+		t.Errorf("Export() error = %v, want both the export and the dead-letter failure", err)
+	}
+}
+
+func TestDeadLetterExporter_FanOutMember(t *testing.T) {
+	fake := &fakeS3{}
+	queue := &S3DeadLetterQueue{Client: fake, Bucket: "dlq"}
+
+	var mu sync.Mutex
+	received := map[string]int{}
+	otlpDown := true
+	member := func(name string) FanOutMember {
+		return FanOutMember{Name: name, Exporter: ExporterFunc(func(ctx context.Context, logs converter.ResourceLog) error {
+			mu.Lock()
+			defer mu.Unlock()
+			if name == "otlp" && otlpDown {
+				return errors.New("HTTP 503")
+			}
+			for _, scope := range logs.ScopeLogs {
+				received[name] += len(scope.LogRecords)
+			}
+			return nil
+		})}
+	}
+	fan := NewFanOut(discardLogger, member("otlp"), member("s3"))
+
+	archived := helloRecord()
+	archived.Routes = []string{"s3"}
+	if err := NewDeadLetterExporter(fan, queue, nil).Export(context.Background(), testResourceLog(helloRecord(), archived)); err != nil {
+		t.Fatalf("Export() error = %v, want the otlp part dead-lettered", err)
+	}
+	if len(fake.objects) != 1 || received["s3"] != 2 {
+		t.Fatalf("%d letters stored, s3 received %d records, want 1 letter and 2 records", len(fake.objects), received["s3"])
+	}
+
+	// The replay only goes to the member that failed, with the records routed to it
+	otlpDown = false
+	if n, err := ReplayDeadLetters(context.Background(), queue, fan, 0, nil); err != nil || n != 1 {
+		t.Fatalf("ReplayDeadLetters() = %d, %v, want 1 replayed", n, err)
+	}
+	if received["otlp"] != 1 || received["s3"] != 2 {
+		t.Errorf("received = %v after replay, want otlp 1 and s3 unchanged at 2", received)
+	}
+
+	// A member's letter cannot be replayed through a single exporter
+	otlpDown = true
+	if err := NewDeadLetterExporter(fan, queue, nil).Export(context.Background(), testResourceLog(helloRecord())); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	single := ExporterFunc(func(ctx context.Context, logs converter.ResourceLog) error { return nil })
+	if _, err := ReplayDeadLetters(context.Background(), queue, single, 0, nil); err == nil || len(fake.objects) != 1 {
+		t.Errorf("replay through a single exporter = %v with %d letters left, want an error and the letter kept", err, len(fake.objects))
+	}
+}
@@ -0,0 +1,89 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+)
+
+// Exporter delivers one batch of converted logs (a single resource with its records) to a sink.
+// Implementations handle their own encoding, retries and authentication.
+type Exporter interface {
+	Export(ctx context.Context, logs converter.ResourceLog) error
+}
+
+// ExporterFunc adapts a plain function to the Exporter interface
+type ExporterFunc func(ctx context.Context, logs converter.ResourceLog) error
+
+// Export calls f(ctx, logs)
+func (f ExporterFunc) Export(ctx context.Context, logs converter.ResourceLog) error {
+	return f(ctx, logs)
+}
+
+// MetricsExporter delivers the metrics derived from one resource's records
+type MetricsExporter interface {
+	ExportMetrics(ctx context.Context, metrics converter.ResourceMetric) error
+}
+
+// TracesExporter sends spans of a resource
+type TracesExporter interface {
+	ExportSpans(ctx context.Context, spans converter.ResourceSpans) error
+}
+
+// PartialSuccessAction determines how an OTLP partial_success response with rejected records is handled
+type PartialSuccessAction string
+
+const (
+	// PartialSuccessLog accepts the batch as delivered and logs a warning
+	PartialSuccessLog PartialSuccessAction = "log"
+	// PartialSuccessRetry resends the whole batch while attempts remain
+	PartialSuccessRetry PartialSuccessAction = "retry"
+	// PartialSuccessFail reports the batch as failed
+	PartialSuccessFail PartialSuccessAction = "fail"
+)
+
+// ErrPartialSuccess marks a batch the collector accepted only partially
+var ErrPartialSuccess = errors.New("collector rejected part of the batch")
+
+// handlePartialSuccess logs a partial_success response and decides what to do with the batch.
+// OTLP does not say which records were rejected, so "retry" resends the whole batch while
+// attempts remain and "fail" reports the batch as failed; "log" accepts it as delivered.
+func handlePartialSuccess(logger *slog.Logger, action PartialSuccessAction, partial converter.PartialSuccess, attempt, maxRetries int) (retry bool, err error) {
+	if !partial.Rejected() {
+		return false, nil
+	}
+
+	logger.Warn("Collector returned partial success", "attempt", attempt+1, "rejected_log_records", partial.RejectedLogRecords, "error_message", partial.ErrorMessage)
+
+	if partial.RejectedLogRecords == 0 {
+		// A message without rejected records is only a warning
+		return false, nil
+	}
+
+	err = fmt.Errorf("%w: %d records rejected: %s", ErrPartialSuccess, partial.RejectedLogRecords, partial.ErrorMessage)
+	switch action {
+	case PartialSuccessRetry:
+		if attempt < maxRetries {
+			return true, err
+		}
+		logger.Warn("Accepting partially delivered batch after exhausting retries", "rejected_log_records", partial.RejectedLogRecords)
+	case PartialSuccessFail:
+		return false, err
+	}
+	return false, nil
+}
+
+// payloadOf wraps a single resource batch into an OTLP export payload
+func payloadOf(logs converter.ResourceLog) converter.OTLPPayload {
+	return converter.OTLPPayload{ResourceLogs: []converter.ResourceLog{logs}}
+}
+
+func loggerOrDefault(logger *slog.Logger) *slog.Logger {
+	if logger == nil {
+		return slog.Default()
+	}
+	return logger
+}
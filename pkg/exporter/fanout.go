@@ -0,0 +1,182 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+)
+
+// FanOutMember is one destination of a FanOut
+type FanOutMember struct {
+	Name     string
+	Exporter Exporter
+	// Optional members only log their failures; a failing required member fails the batch
+	Optional bool
+}
+
+// ExportCounts are the records one fan-out member delivered and failed to deliver
+type ExportCounts struct {
+	Succeeded int
+	Failed    int
+}
+
+// FanOut sends every batch to several exporters concurrently. Each member keeps its own retry
+// state, so an outage of one sink neither delays nor blocks the others.
+//
+// When a required member fails, the batch is reported as failed with a *FanOutError. Without
+// a dead-letter queue the batch is redelivered to every member, so sinks should tolerate
+// duplicates; a DeadLetterExporter parks it for the failed members only.
+//
+// Records with Routes only go to the members they name; the others go to every member.
+type FanOut struct {
+	members []FanOutMember
+	logger  *slog.Logger
+
+	mu     sync.Mutex
+	counts map[string]*ExportCounts
+}
+
+// FanOutError reports the required members of a FanOut that failed, with the records each of
+// them did not deliver
+type FanOutError struct {
+	// Failed maps each failed member's name to the part of the batch routed to it
+	Failed map[string]converter.ResourceLog
+	err    error
+}
+
+func (e *FanOutError) Error() string { return e.err.Error() }
+func (e *FanOutError) Unwrap() error { return e.err }
+
+// NewFanOut creates a fan-out exporter over the given members
+func NewFanOut(logger *slog.Logger, members ...FanOutMember) *FanOut {
+	return &FanOut{members: members, logger: loggerOrDefault(logger), counts: make(map[string]*ExportCounts)}
+}
+
+// Export sends the batch to all members and waits for them to finish
+func (f *FanOut) Export(ctx context.Context, logs converter.ResourceLog) error {
+	routed := false
+	for _, scope := range logs.ScopeLogs {
+		for _, record := range scope.LogRecords {
+			routed = routed || len(record.Routes) > 0
+		}
+	}
+
+	errs := make([]error, len(f.members))
+	failed := make([]bool, len(f.members))
+	memberLogs := make([]converter.ResourceLog, len(f.members))
+	var wg sync.WaitGroup
+	for i, m := range f.members {
+		memberLogs[i] = logs
+		if routed {
+			memberLogs[i] = routedTo(logs, m.Name)
+		}
+		records := 0
+		for _, scope := range memberLogs[i].ScopeLogs {
+			records += len(scope.LogRecords)
+		}
+		if records == 0 {
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, m FanOutMember) {
+			defer wg.Done()
+			err := m.Exporter.Export(ctx, memberLogs[i])
+			f.count(m.Name, records, err)
+			if err == nil {
+				return
+			}
+			if m.Optional {
+				f.logger.Warn("Optional exporter failed", "exporter", m.Name, "error", err)
+				return
+			}
+			errs[i] = fmt.Errorf("%s: %w", m.Name, err)
+			failed[i] = true
+		}(i, m)
+	}
+	wg.Wait()
+
+	err := errors.Join(errs...)
+	if err == nil {
+		return nil
+	}
+	fanErr := &FanOutError{Failed: make(map[string]converter.ResourceLog), err: err}
+	for i, m := range f.members {
+		if failed[i] {
+			fanErr.Failed[m.Name] = memberLogs[i]
+		}
+	}
+	return fanErr
+}
+
+// ExportTo sends the batch to the named member only, for replaying what that member failed
+// to deliver
+func (f *FanOut) ExportTo(ctx context.Context, name string, logs converter.ResourceLog) error {
+	for _, m := range f.members {
+		if m.Name != name {
+			continue
+		}
+		records := 0
+		for _, scope := range logs.ScopeLogs {
+			records += len(scope.LogRecords)
+		}
+		err := m.Exporter.Export(ctx, logs)
+		f.count(m.Name, records, err)
+		if err != nil {
+			return fmt.Errorf("%s: %w", m.Name, err)
+		}
+		return nil
+	}
+	return fmt.Errorf("no exporter named %q", name)
+}
+
+// routedTo returns the records of logs that go to the named member, dropping empty scopes
+func routedTo(logs converter.ResourceLog, name string) converter.ResourceLog {
+	out := converter.ResourceLog{Resource: logs.Resource}
+	for _, scope := range logs.ScopeLogs {
+		var records []converter.OTelLogRecord
+		for _, record := range scope.LogRecords {
+			if len(record.Routes) == 0 || slices.Contains(record.Routes, name) {
+				records = append(records, record)
+			}
+		}
+		if len(records) > 0 {
+			out.ScopeLogs = append(out.ScopeLogs, converter.ScopeLog{Scope: scope.Scope, LogRecords: records})
+		}
+	}
+	return out
+}
+
+func (f *FanOut) count(name string, records int, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c, ok := f.counts[name]
+	if !ok {
+		c = &ExportCounts{}
+		f.counts[name] = c
+	}
+	if err != nil {
+		c.Failed += records
+	} else {
+		c.Succeeded += records
+	}
+}
+
+// TakeCounts returns the per-member record counts accumulated since the last call and resets them
+func (f *FanOut) TakeCounts() map[string]ExportCounts {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	out := make(map[string]ExportCounts, len(f.counts))
+	for name, c := range f.counts {
+		out[name] = *c
+	}
+	f.counts = make(map[string]*ExportCounts)
+	return out
+}
@@ -0,0 +1,93 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+)
+
+func TestFanOut_Export(t *testing.T) {
+	ok := ExporterFunc(func(ctx context.Context, logs converter.ResourceLog) error { return nil })
+	down := ExporterFunc(func(ctx context.Context, logs converter.ResourceLog) error { return errors.New("connection refused") })
+
+	var archived int
+	archive := ExporterFunc(func(ctx context.Context, logs converter.ResourceLog) error {
+		archived++
+		return nil
+	})
+
+	fan := NewFanOut(discardLogger,
+		FanOutMember{Name: "otlp", Exporter: down},
+		FanOutMember{Name: "s3", Exporter: archive},
+		FanOutMember{Name: "kafka", Exporter: down, Optional: true},
+	)
+	logs := testResourceLog(helloRecord(), helloRecord())
+
+	err := fan.Export(context.Background(), logs)
+	if err == nil {
+		t.Fatal("Export() error = nil, want the required otlp failure")
+	}
+	if archived != 1 {
+		t.Errorf("archive exported %d times, want 1 despite the otlp outage", archived)
+	}
+
+	fan.members[0].Exporter = ok
+	if err := fan.Export(context.Background(), logs); err != nil {
+		t.Errorf("Export() error = %v, want optional failures ignored", err)
+	}
+
+	counts := fan.TakeCounts()
+	want := map[string]ExportCounts{
+		"otlp":  {Succeeded: 2, Failed: 2},
+		"s3":    {Succeeded: 4},
+		"kafka": {Failed: 4},
+	}
+	for name, w := range want {
+		if counts[name] != w {
+			t.Errorf("counts[%s] = %+v, want %+v", name, counts[name], w)
+		}
+	}
+	if len(fan.TakeCounts()) != 0 {
+		t.Error("TakeCounts() did not reset the counts")
+	}
+}
+
+func TestFanOut_Routes(t *testing.T) {
+	var mu sync.Mutex
+	received := map[string]int{}
+	member := func(name string) FanOutMember {
+		return FanOutMember{Name: name, Exporter: ExporterFunc(func(ctx context.Context, logs converter.ResourceLog) error {
+			mu.Lock()
+			defer mu.Unlock()
+			for _, scope := range logs.ScopeLogs {
+				received[name] += len(scope.LogRecords)
+			}
+			return nil
+		})}
+	}
+	fan := NewFanOut(discardLogger, member("otlp"), member("s3"))
+
+	archived := helloRecord()
+	archived.Routes = []string{"s3"}
+	if err := fan.Export(context.Background(), testResourceLog(helloRecord(), archived, archived)); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if received["otlp"] != 1 || received["s3"] != 3 {
+		t.Errorf("received = %v, want otlp 1 and s3 3", received)
+	}
+
+	// A member no record is routed to is not called at all
+	received = map[string]int{}
+	if err := fan.Export(context.Background(), testResourceLog(archived)); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if _, called := received["otlp"]; called {
+		t.Error("otlp called without records")
+	}
+	if counts := fan.TakeCounts(); counts["otlp"].Succeeded != 1 || counts["s3"].Succeeded != 4 {
+		t.Errorf("counts = %+v", counts)
+	}
+}
@@ -0,0 +1,163 @@
+package exporter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/firehose"
+	"github.com/aws/aws-sdk-go-v2/service/firehose/types"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+)
+
+// Firehose PutRecordBatch limits
+const (
+	firehoseMaxBatchRecords = 500
+	firehoseMaxBatchBytes   = 4 * 1024 * 1024
+	firehoseMaxRecordBytes  = 1000 * 1024
+)
+
+// FirehoseConfig configures a FirehoseExporter
+type FirehoseConfig struct {
+	DeliveryStream string
+	// Format is "ndjson" (default, one Firehose record per log record) or
+	// "otlp" (one OTLP/JSON export request per Firehose record)
+	Format string
+	Retry  RetryPolicy
+	Logger *slog.Logger
+}
+
+// FirehoseAPI is the part of the Firehose client FirehoseExporter uses; *firehose.Client
+// implements it
+type FirehoseAPI interface {
+	PutRecordBatch(ctx context.Context, in *firehose.PutRecordBatchInput, optFns ...func(*firehose.Options)) (*firehose.PutRecordBatchOutput, error)
+}
+
+// FirehoseExporter writes batches to a Kinesis Data Firehose delivery stream
+type FirehoseExporter struct {
+	cfg    FirehoseConfig
+	client FirehoseAPI
+	logger *slog.Logger
+}
+
+// NewFirehoseExporter creates a Firehose exporter using the given client
+func NewFirehoseExporter(client FirehoseAPI, cfg FirehoseConfig) (*FirehoseExporter, error) {
+	if cfg.DeliveryStream == "" {
+		return nil, fmt.Errorf("firehose delivery stream is required")
+	}
+	if cfg.Format == "" {
+		cfg.Format = "ndjson"
+	}
+	if cfg.Format != "ndjson" && cfg.Format != "otlp" {
+		return nil, fmt.Errorf("unknown firehose format %q: want ndjson or otlp", cfg.Format)
+	}
+	return &FirehoseExporter{cfg: cfg, client: client, logger: loggerOrDefault(cfg.Logger)}, nil
+}
+
+// Export encodes the batch into Firehose records and puts them, retrying failed records
+func (e *FirehoseExporter) Export(ctx context.Context, logs converter.ResourceLog) error {
+	records, err := e.encode(logs)
+	if err != nil {
+		return err
+	}
+
+	for _, chunk := range chunkFirehoseRecords(records) {
+		if err := e.putWithRetry(ctx, chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encode builds newline-terminated Firehose record payloads for the batch
+func (e *FirehoseExporter) encode(logs converter.ResourceLog) ([][]byte, error) {
+	if e.cfg.Format == "otlp" {
+		body, err := converter.MarshalJSON(payloadOf(logs))
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal payload: %w", err)
+		}
+		if len(body)+1 > firehoseMaxRecordBytes {
+			return nil, fmt.Errorf("OTLP payload of %d bytes exceeds the Firehose record limit; lower MAX_BATCH_SIZE", len(body))
+		}
+		return [][]byte{append(body, '\n')}, nil
+	}
+
+	flat := flattenRecords(logs)
+	records := make([][]byte, 0, len(flat))
+	for _, record := range flat {
+		line, err := json.Marshal(record)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal record: %w", err)
+		}
+		if len(line)+1 > firehoseMaxRecordBytes {
+			e.logger.Warn("Skipping record exceeding the Firehose record limit", "bytes", len(line))
+			continue
+		}
+		records = append(records, append(line, '\n'))
+	}
+	return records, nil
+}
+
+// chunkFirehoseRecords splits records into PutRecordBatch calls within the count and size limits
+func chunkFirehoseRecords(records [][]byte) [][][]byte {
+	var chunks [][][]byte
+	var current [][]byte
+	size := 0
+	for _, r := range records {
+		if len(current) == firehoseMaxBatchRecords || (len(current) > 0 && size+len(r) > firehoseMaxBatchBytes) {
+			chunks = append(chunks, current)
+			current, size = nil, 0
+		}
+		current = append(current, r)
+		size += len(r)
+	}
+	if len(current) > 0 {
+		chunks = append(chunks, current)
+	}
+	return chunks
+}
+
+// putWithRetry puts one chunk, resending only the records Firehose reported as failed
+func (e *FirehoseExporter) putWithRetry(ctx context.Context, records [][]byte) error {
+	pending := records
+	var lastErr error
+	for attempt := 0; attempt <= e.cfg.Retry.MaxRetries; attempt++ {
+		if attempt > 0 {
+			if err := e.cfg.Retry.wait(ctx, attempt, e.cfg.Retry.Backoff(attempt)); err != nil {
+				return fmt.Errorf("export aborted after %d attempts: %w", attempt, err)
+			}
+		}
+
+		input := &firehose.PutRecordBatchInput{DeliveryStreamName: aws.String(e.cfg.DeliveryStream)}
+		for _, r := range pending {
+			input.Records = append(input.Records, types.Record{Data: r})
+		}
+
+		out, err := e.client.PutRecordBatch(ctx, input)
+		if err != nil {
+			e.logger.Warn("Firehose put attempt failed", "attempt", attempt+1, "error", err)
+			lastErr = err
+			continue
+		}
+
+		if aws.ToInt32(out.FailedPutCount) == 0 {
+			e.logger.Info("Batch sent successfully", "attempt", attempt+1, "exporter", "firehose", "records", len(pending))
+			return nil
+		}
+
+		var failed [][]byte
+		for i, resp := range out.RequestResponses {
+			if resp.ErrorCode != nil && i < len(pending) {
+				failed = append(failed, pending[i])
+				lastErr = fmt.Errorf("%s: %s", aws.ToString(resp.ErrorCode), aws.ToString(resp.ErrorMessage))
+			}
+		}
+		e.logger.Warn("Firehose rejected part of the batch", "attempt", attempt+1, "failed", len(failed), "error", lastErr)
+		pending = failed
+	}
+
+	return fmt.Errorf("failed after %d attempts: %w", e.cfg.Retry.MaxRetries+1, lastErr)
+}
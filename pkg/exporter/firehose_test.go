@@ -0,0 +1,110 @@
+package exporter
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/firehose"
+	"github.com/aws/aws-sdk-go-v2/service/firehose/types"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+)
+
+type fakeFirehose struct {
+	calls    [][]string
+	failOnce bool
+}
+
+func (f *fakeFirehose) PutRecordBatch(ctx context.Context, input *firehose.PutRecordBatchInput, optFns ...func(*firehose.Options)) (*firehose.PutRecordBatchOutput, error) {
+	var data []string
+	out := &firehose.PutRecordBatchOutput{FailedPutCount: aws.Int32(0)}
+	for i, r := range input.Records {
+		data = append(data, string(r.Data))
+		resp := types.PutRecordBatchResponseEntry{RecordId: aws.String("id")}
+		if f.failOnce && i == 0 {
+			resp = types.PutRecordBatchResponseEntry{ErrorCode: aws.String("ServiceUnavailableException"), ErrorMessage: aws.String("slow down")}
+			out.FailedPutCount = aws.Int32(1)
+		}
+		out.RequestResponses = append(out.RequestResponses, resp)
+	}
+	f.failOnce = false
+	f.calls = append(f.calls, data)
+	return out, nil
+}
+
+func TestFirehoseExporter_NDJSON(t *testing.T) {
+	fake := &fakeFirehose{failOnce: true}
+	exp, err := NewFirehoseExporter(fake, FirehoseConfig{DeliveryStream: "logs", Retry: RetryPolicy{MaxRetries: 1}, Logger: discardLogger})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	service := "alb"
+	logs := testResourceLog(helloRecord(), helloRecord())
+	logs.Resource.Attributes = []converter.OTelAttribute{{Key: "service.name", Value: converter.OTelAnyValue{StringValue: &service}}}
+
+	if err := exp.Export(context.Background(), logs); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+
+	if len(fake.calls) != 2 || len(fake.calls[0]) != 2 || len(fake.calls[1]) != 1 {
+		t.Fatalf("calls = %v, want 2 records then the 1 failed record", fake.calls)
+	}
+
+	line := fake.calls[0][0]
+	if !strings.HasSuffix(line, "\n") {
+		t.Errorf("record %q is not newline terminated", line)
+	}
+	var got flatRecord
+	if err := json.Unmarshal([]byte(line), &got); err != nil {
+		t.Fatalf("record is not JSON: %v", err)
+	}
+	if got.Body != "hello" || got.Resource["service.name"] != "alb" {
+		t.Errorf("record = %+v, want body hello with service.name alb", got)
+	}
+}
+
+func TestFirehoseExporter_OTLP(t *testing.T) {
+	fake := &fakeFirehose{}
+	exp, err := NewFirehoseExporter(fake, FirehoseConfig{DeliveryStream: "logs", Format: "otlp", Logger: discardLogger})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := exp.Export(context.Background(), testResourceLog(helloRecord(), helloRecord())); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+
+	if len(fake.calls) != 1 || len(fake.calls[0]) != 1 {
+		t.Fatalf("calls = %v, want a single OTLP record", fake.calls)
+	}
+	var payload converter.OTLPPayload
+	if err := json.Unmarshal([]byte(fake.calls[0][0]), &payload); err != nil {
+		t.Fatalf("record is not an OTLP payload: %v", err)
+	}
+	if n := len(payload.ResourceLogs[0].ScopeLogs[0].LogRecords); n != 2 {
+		t.Errorf("payload has %d records, want 2", n)
+	}
+}
+
+func TestChunkFirehoseRecords(t *testing.T) {
+	records := make([][]byte, 1201)
+	for i := range records {
+		records[i] = []byte("x\n")
+	}
+	chunks := chunkFirehoseRecords(records)
+	if len(chunks) != 3 || len(chunks[0]) != 500 || len(chunks[2]) != 201 {
+		t.Errorf("got %d chunks, want 500/500/201", len(chunks))
+	}
+
+	big := make([][]byte, 6)
+	for i := range big {
+		big[i] = make([]byte, 900*1024)
+	}
+	if chunks := chunkFirehoseRecords(big); len(chunks) != 2 {
+		t.Errorf("got %d chunks for 5.4MB, want 2", len(chunks))
+	}
+}
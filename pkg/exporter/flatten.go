@@ -0,0 +1,86 @@
+package exporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+)
+
+// flatRecord is the self-contained JSON form of one log record used by line-oriented sinks
+type flatRecord struct {
+	Timestamp      string         `json:"timestamp"`
+	SeverityText   string         `json:"severity_text,omitempty"`
+	SeverityNumber int            `json:"severity_number,omitempty"`
+	Body           string         `json:"body"`
+	TraceID        string         `json:"trace_id,omitempty"`
+	SpanID         string         `json:"span_id,omitempty"`
+	Attributes     map[string]any `json:"attributes,omitempty"`
+	Resource       map[string]any `json:"resource,omitempty"`
+}
+
+// flattenRecords turns every record of a resource batch into a flatRecord carrying its resource attributes
+func flattenRecords(logs converter.ResourceLog) []flatRecord {
+	resource := attributeMap(logs.Resource.Attributes)
+
+	var out []flatRecord
+	for _, scope := range logs.ScopeLogs {
+		for _, record := range scope.LogRecords {
+			out = append(out, flatRecord{
+				Timestamp:      record.TimeUnixNano,
+				SeverityText:   record.SeverityText,
+				SeverityNumber: record.SeverityNumber,
+				Body:           record.Body.Text(),
+				TraceID:        record.TraceID,
+				SpanID:         record.SpanID,
+				Attributes:     attributeMap(record.Attributes),
+				Resource:       resource,
+			})
+		}
+	}
+	return out
+}
+
+// attributeMap converts OTLP attributes to plain JSON values
+func attributeMap(attrs []converter.OTelAttribute) map[string]any {
+	if len(attrs) == 0 {
+		return nil
+	}
+	m := make(map[string]any, len(attrs))
+	for _, attr := range attrs {
+		if v, ok := attributeValue(attr.Value); ok {
+			m[attr.Key] = v
+		}
+	}
+	return m
+}
+
+func attributeValue(v converter.OTelAnyValue) (any, bool) {
+	switch {
+	case v.StringValue != nil:
+		return *v.StringValue, true
+	case v.IntValue != nil:
+		if i, err := strconv.ParseInt(*v.IntValue, 10, 64); err == nil {
+			return i, true
+		}
+		return *v.IntValue, true
+	case v.DoubleValue != nil:
+		return *v.DoubleValue, true
+	case v.BoolValue != nil:
+		return *v.BoolValue, true
+	case v.ArrayValue != nil:
+		return v.ArrayValue.Plain(), true
+	}
+	return nil, false
+}
+
+// textValue renders a flattened attribute value for string-only columns: lists as JSON,
+// scalars in their usual form
+func textValue(v any) string {
+	if list, ok := v.([]any); ok {
+		out, _ := json.Marshal(list)
+		return string(out)
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,30 @@
+package exporter
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ParseHeaders parses a header list in the OTEL_EXPORTER_OTLP_HEADERS format:
+// comma-separated key=value pairs with percent-encoded values, e.g.
+// "x-scope-orgid=tenant1,signoz-access-token=abc%3D%3D"
+func ParseHeaders(s string) (map[string]string, error) {
+	headers := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid header %q: want key=value", pair)
+		}
+		decoded, err := url.PathUnescape(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for header %q: %w", key, err)
+		}
+		headers[key] = decoded
+	}
+	return headers, nil
+}
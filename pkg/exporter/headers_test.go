@@ -0,0 +1,32 @@
+package exporter
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseHeaders(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    map[string]string
+		wantErr bool
+	}{
+		{"", map[string]string{}, false},
+		{"x-scope-orgid=tenant1", map[string]string{"x-scope-orgid": "tenant1"}, false},
+		{" x-scope-orgid = tenant1 , signoz-access-token=abc%3D%3D,", map[string]string{"x-scope-orgid": "tenant1", "signoz-access-token": "abc=="}, false},
+		{"authorization=Basic dXNlcjpwYXNz", map[string]string{"authorization": "Basic dXNlcjpwYXNz"}, false},
+		{"novalue", nil, true},
+		{"=value", nil, true},
+		{"bad=%zz", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseHeaders(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseHeaders(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseHeaders(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package exporter
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/tracing"
+)
+
+// httpPoster delivers request bodies with the retry semantics shared by the HTTP exporters:
+// retryable statuses (5xx, 408, 429) back off with jitter or honor Retry-After, other
+// client errors fail fast.
+type httpPoster struct {
+	endpoint    string
+	compression string
+	basicUser   string
+	basicPass   string
+	header      http.Header
+	retry       RetryPolicy
+	limiter     *RateLimiter
+	client      *http.Client
+	logger      *slog.Logger
+	// authorize, when set, adds request credentials (SigV4 signature, bearer token) after all other headers are in place
+	authorize func(req *http.Request, body []byte) error
+	// onSuccess inspects a 2xx response; retry=true resends the body while attempts remain
+	onSuccess func(resp *http.Response, body []byte, attempt int) (retry bool, err error)
+}
+
+// gzipBody compresses an encoded payload for Content-Encoding: gzip
+func gzipBody(body []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(body); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// post sends the body with the given content type, retrying retryable failures with backoff
+func (p *httpPoster) post(ctx context.Context, body []byte, contentType string) error {
+	_, err := p.postResponse(ctx, body, contentType)
+	return err
+}
+
+// postResponse is post returning the body of the accepted (2xx) response
+func (p *httpPoster) postResponse(ctx context.Context, body []byte, contentType string) ([]byte, error) {
+	var err error
+	if p.compression == "gzip" {
+		if body, err = gzipBody(body); err != nil {
+			return nil, fmt.Errorf("failed to compress payload: %w", err)
+		}
+	}
+
+	maxRetries := p.retry.MaxRetries
+	var lastErr error
+	var retryAfter time.Duration
+	for attempt := 0; attempt <= maxRetries; attempt++ {
+		if attempt > 0 {
+			// Honor the server's Retry-After in full when given, giving up if the batch cannot
+			// wait that long, otherwise back off exponentially
+			sleep := p.retry.Backoff(attempt)
+			if retryAfter > 0 {
+				if err := p.retry.checkRetryAfter(ctx, retryAfter); err != nil {
+					return nil, fmt.Errorf("export aborted after %d attempts: %w (last error: %v)", attempt, err, lastErr)
+				}
+				sleep = retryAfter
+			}
+			if err := p.retry.wait(ctx, attempt, sleep); err != nil {
+				return nil, fmt.Errorf("export aborted after %d attempts: %w", attempt, err)
+			}
+		}
+		retryAfter = 0
+		if err := p.limiter.Wait(ctx, len(body)); err != nil {
+			return nil, fmt.Errorf("export aborted after %d attempts: %w", attempt, err)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, "POST", p.endpoint, bytes.NewBuffer(body))
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		for k, v := range p.header {
+			req.Header[k] = v
+		}
+		req.Header.Set("Content-Type", contentType)
+		// Lets the collector's own traces join the parser's trace
+		if tp := tracing.Traceparent(ctx); tp != "" {
+			req.Header.Set("traceparent", tp)
+		}
+		if p.compression == "gzip" {
+			req.Header.Set("Content-Encoding", "gzip")
+		}
+
+		if p.basicUser != "" && p.basicPass != "" {
+			req.SetBasicAuth(p.basicUser, p.basicPass)
+		}
+
+		if p.authorize != nil {
+			if err := p.authorize(req, body); err != nil {
+				return nil, fmt.Errorf("failed to authorize request: %w", err)
+			}
+		}
+
+		resp, err := p.client.Do(req)
+		if err != nil {
+			p.logger.Warn("Batch send attempt failed", "attempt", attempt+1, "error", err)
+			lastErr = err
+			continue
+		}
+
+		respBody, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			if p.onSuccess != nil {
+				retry, err := p.onSuccess(resp, respBody, attempt)
+				if retry {
+					lastErr = err
+					continue
+				}
+				if err != nil {
+					return nil, err
+				}
+			}
+			p.logger.Info("Batch sent successfully", "attempt", attempt+1, "status", resp.StatusCode)
+			return respBody, nil
+		}
+
+		lastErr = fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(respBody))
+
+		if !IsRetryableStatus(resp.StatusCode) {
+			p.logger.Error("Batch rejected with non-retryable status", "attempt", attempt+1, "status", resp.StatusCode, "response", string(respBody))
+			return nil, fmt.Errorf("non-retryable response: %w", lastErr)
+		}
+
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+			retryAfter, _ = ParseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+		}
+
+		p.logger.Warn("Batch send attempt failed", "attempt", attempt+1, "status", resp.StatusCode, "retry_after", retryAfter, "response", string(respBody))
+	}
+
+	return nil, fmt.Errorf("failed after %d attempts: %w", maxRetries+1, lastErr)
+}
@@ -0,0 +1,210 @@
+package exporter
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/segmentio/kafka-go/sasl/scram"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+)
+
+// KafkaConfig configures a KafkaExporter and the producer built by NewKafkaWriter
+type KafkaConfig struct {
+	Brokers []string
+	// Topic is a name template: "aws-logs-%{cloud.platform}" routes each log type to its own
+	// topic, date tokens such as %{yyyy.MM} use the record time (see nameTemplate)
+	Topic string
+	// Format is "ndjson" (default, one message per log record) or
+	// "otlp" (one OTLP/JSON export request per message)
+	Format string
+	// KeyAttribute names the resource attribute used as message key; empty leaves messages unkeyed
+	KeyAttribute string
+	// SASLMechanism is "", "plain", "scram-sha-256" or "scram-sha-512"
+	SASLMechanism string
+	SASLUser      string
+	SASLPass      string
+	TLS           bool
+	TLSSkipVerify bool
+	// Compression is "none" (default), "gzip", "snappy", "lz4" or "zstd"
+	Compression string
+	Retry       RetryPolicy
+	Logger      *slog.Logger
+}
+
+// KafkaWriter is the producer interface used by KafkaExporter; *kafka.Writer implements it
+type KafkaWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+}
+
+// KafkaExporter produces batches to Kafka topics
+type KafkaExporter struct {
+	cfg    KafkaConfig
+	writer KafkaWriter
+	topic  nameTemplate
+	logger *slog.Logger
+}
+
+// NewKafkaWriter creates a synchronous producer for the configured brokers, SASL and TLS settings
+func NewKafkaWriter(cfg KafkaConfig) (*kafka.Writer, error) {
+	if len(cfg.Brokers) == 0 {
+		return nil, fmt.Errorf("kafka brokers are required")
+	}
+
+	transport := &kafka.Transport{}
+	if cfg.TLS {
+		transport.TLS = &tls.Config{MinVersion: tls.VersionTLS12, InsecureSkipVerify: cfg.TLSSkipVerify}
+	}
+
+	var mechanism sasl.Mechanism
+	var err error
+	switch strings.ToLower(cfg.SASLMechanism) {
+	case "":
+	case "plain":
+		mechanism = plain.Mechanism{Username: cfg.SASLUser, Password: cfg.SASLPass}
+	case "scram-sha-256":
+		mechanism, err = scram.Mechanism(scram.SHA256, cfg.SASLUser, cfg.SASLPass)
+	case "scram-sha-512":
+		mechanism, err = scram.Mechanism(scram.SHA512, cfg.SASLUser, cfg.SASLPass)
+	default:
+		return nil, fmt.Errorf("unknown kafka SASL mechanism %q", cfg.SASLMechanism)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure kafka SASL: %w", err)
+	}
+	transport.SASL = mechanism
+
+	writer := &kafka.Writer{
+		Addr:         kafka.TCP(cfg.Brokers...),
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireAll,
+		// Retries are driven by the exporter so only failed messages are resent
+		MaxAttempts:  1,
+		BatchTimeout: 10 * time.Millisecond,
+		Transport:    transport,
+	}
+	if cfg.Compression != "" && cfg.Compression != "none" {
+		if err := writer.Compression.UnmarshalText([]byte(cfg.Compression)); err != nil {
+			return nil, fmt.Errorf("unknown kafka compression %q", cfg.Compression)
+		}
+	}
+	return writer, nil
+}
+
+// NewKafkaExporter creates a Kafka exporter using the given producer
+func NewKafkaExporter(writer KafkaWriter, cfg KafkaConfig) (*KafkaExporter, error) {
+	if cfg.Topic == "" {
+		return nil, fmt.Errorf("kafka topic is required")
+	}
+	if cfg.Format == "" {
+		cfg.Format = "ndjson"
+	}
+	if cfg.Format != "ndjson" && cfg.Format != "otlp" {
+		return nil, fmt.Errorf("unknown kafka format %q: want ndjson or otlp", cfg.Format)
+	}
+	return &KafkaExporter{cfg: cfg, writer: writer, topic: parseNameTemplate(cfg.Topic), logger: loggerOrDefault(cfg.Logger)}, nil
+}
+
+// Export encodes the batch into messages and produces them, retrying failed messages
+func (e *KafkaExporter) Export(ctx context.Context, logs converter.ResourceLog) error {
+	messages, err := e.encode(logs)
+	if err != nil {
+		return err
+	}
+	if len(messages) == 0 {
+		return nil
+	}
+	return e.writeWithRetry(ctx, messages)
+}
+
+// encode builds the messages for the batch
+func (e *KafkaExporter) encode(logs converter.ResourceLog) ([]kafka.Message, error) {
+	resource := attributeMap(logs.Resource.Attributes)
+
+	var key []byte
+	if v, ok := resource[e.cfg.KeyAttribute]; ok && e.cfg.KeyAttribute != "" {
+		key = []byte(fmt.Sprint(v))
+	}
+
+	if e.cfg.Format == "otlp" {
+		body, err := converter.MarshalJSON(payloadOf(logs))
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal payload: %w", err)
+		}
+		ts := time.Now().UTC()
+		if len(logs.ScopeLogs) > 0 && len(logs.ScopeLogs[0].LogRecords) > 0 {
+			ts = recordTime(logs.ScopeLogs[0].LogRecords[0].TimeUnixNano)
+		}
+		return []kafka.Message{{Topic: kafkaTopicName(e.topic.render(ts, resource)), Key: key, Value: body}}, nil
+	}
+
+	flat := flattenRecords(logs)
+	messages := make([]kafka.Message, 0, len(flat))
+	for _, record := range flat {
+		value, err := json.Marshal(record)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal record: %w", err)
+		}
+		topic := kafkaTopicName(e.topic.render(recordTime(record.Timestamp), resource))
+		messages = append(messages, kafka.Message{Topic: topic, Key: key, Value: value})
+	}
+	return messages, nil
+}
+
+// kafkaTopicName replaces characters Kafka does not allow in topic names with '_'
+func kafkaTopicName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '_', r == '-':
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// writeWithRetry produces the messages, resending only those the producer reported as failed
+func (e *KafkaExporter) writeWithRetry(ctx context.Context, messages []kafka.Message) error {
+	pending := messages
+	var lastErr error
+	for attempt := 0; attempt <= e.cfg.Retry.MaxRetries; attempt++ {
+		if attempt > 0 {
+			if err := e.cfg.Retry.wait(ctx, attempt, e.cfg.Retry.Backoff(attempt)); err != nil {
+				return fmt.Errorf("export aborted after %d attempts: %w", attempt, err)
+			}
+		}
+
+		err := e.writer.WriteMessages(ctx, pending...)
+		if err == nil {
+			e.logger.Info("Batch sent successfully", "attempt", attempt+1, "exporter", "kafka", "records", len(pending))
+			return nil
+		}
+		lastErr = err
+
+		var writeErrs kafka.WriteErrors
+		if !errors.As(err, &writeErrs) {
+			e.logger.Warn("Kafka write attempt failed", "attempt", attempt+1, "error", err)
+			continue
+		}
+
+		var failed []kafka.Message
+		for i, msgErr := range writeErrs {
+			if msgErr != nil && i < len(pending) {
+				failed = append(failed, pending[i])
+				lastErr = msgErr
+			}
+		}
+		e.logger.Warn("Kafka rejected part of the batch", "attempt", attempt+1, "failed", len(failed), "error", lastErr)
+		pending = failed
+	}
+
+	return fmt.Errorf("failed after %d attempts: %w", e.cfg.Retry.MaxRetries+1, lastErr)
+}
@@ -0,0 +1,107 @@
+package exporter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+)
+
+type fakeKafkaWriter struct {
+	calls    [][]kafka.Message
+	failOnce bool
+}
+
+func (f *fakeKafkaWriter) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	f.calls = append(f.calls, msgs)
+	if f.failOnce {
+		f.failOnce = false
+		errs := make(kafka.WriteErrors, len(msgs))
+		errs[0] = errors.New("leader not available")
+		return errs
+	}
+	return nil
+}
+
+func TestKafkaExporter_NDJSON(t *testing.T) {
+	fake := &fakeKafkaWriter{failOnce: true}
+	exp, err := NewKafkaExporter(fake, KafkaConfig{
+		Topic:        "aws-logs-%{cloud.platform}",
+		KeyAttribute: "aws.lb.name",
+		Retry:        RetryPolicy{MaxRetries: 1},
+		Logger:       discardLogger,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	platform, lbName := "aws_elb", "app/my-alb/123"
+	logs := testResourceLog(helloRecord(), helloRecord())
+	logs.Resource.Attributes = []converter.OTelAttribute{
+		{Key: "cloud.platform", Value: converter.OTelAnyValue{StringValue: &platform}},
+		{Key: "aws.lb.name", Value: converter.OTelAnyValue{StringValue: &lbName}},
+	}
+
+	if err := exp.Export(context.Background(), logs); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+
+	if len(fake.calls) != 2 || len(fake.calls[0]) != 2 || len(fake.calls[1]) != 1 {
+		t.Fatalf("calls = %d, want 2 messages then the 1 failed message", len(fake.calls))
+	}
+
+	msg := fake.calls[0][0]
+	if msg.Topic != "aws-logs-aws_elb" || string(msg.Key) != lbName {
+		t.Errorf("topic = %q, key = %q", msg.Topic, msg.Key)
+	}
+	var record flatRecord
+	if err := json.Unmarshal(msg.Value, &record); err != nil {
+		t.Fatalf("Invalid message value: %v", err)
+	}
+	if record.Body != "hello" || record.Resource["cloud.platform"] != platform {
+		t.Errorf("record = %+v", record)
+	}
+}
+
+func TestKafkaExporter_OTLP(t *testing.T) {
+	fake := &fakeKafkaWriter{}
+	exp, err := NewKafkaExporter(fake, KafkaConfig{Topic: "logs/%{missing}", Format: "otlp", Logger: discardLogger})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := exp.Export(context.Background(), testResourceLog(helloRecord(), helloRecord())); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+
+	if len(fake.calls) != 1 || len(fake.calls[0]) != 1 {
+		t.Fatalf("calls = %v, want one message for the batch", fake.calls)
+	}
+	msg := fake.calls[0][0]
+	if msg.Topic != "logs_unknown" {
+		t.Errorf("topic = %q, want invalid characters replaced", msg.Topic)
+	}
+	var payload converter.OTLPPayload
+	if err := json.Unmarshal(msg.Value, &payload); err != nil || len(payload.ResourceLogs[0].ScopeLogs[0].LogRecords) != 2 {
+		t.Errorf("message value is not the OTLP batch: %v", err)
+	}
+}
+
+func TestNewKafkaWriter(t *testing.T) {
+	if _, err := NewKafkaWriter(KafkaConfig{}); err == nil {
+		t.Error("expected error without brokers")
+	}
+	if _, err := NewKafkaWriter(KafkaConfig{Brokers: []string{"b:9092"}, SASLMechanism: "gssapi"}); err == nil {
+		t.Error("expected error for unsupported SASL mechanism")
+	}
+	w, err := NewKafkaWriter(KafkaConfig{Brokers: []string{"b:9092"}, SASLMechanism: "scram-sha-512", SASLUser: "u", SASLPass: "p", TLS: true, Compression: "zstd"})
+	if err != nil {
+		t.Fatalf("NewKafkaWriter() error = %v", err)
+	}
+	if w.Compression != kafka.Zstd {
+		t.Errorf("compression = %v, want zstd", w.Compression)
+	}
+}
@@ -0,0 +1,165 @@
+package exporter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+)
+
+// DefaultLokiLabels is the resource attribute allowlist used when none is configured.
+// Loki indexes every label, so only low-cardinality attributes belong here.
+var DefaultLokiLabels = []string{"service.name", "cloud.platform", "cloud.region", "cloud.account.id"}
+
+// LokiConfig configures a LokiExporter
+type LokiConfig struct {
+	// Endpoint is the push API URL, e.g. http://loki:3100/loki/api/v1/push
+	Endpoint string
+	// Labels lists the resource attributes turned into stream labels (dots become underscores)
+	Labels []string
+	// LevelLabel adds the record severity as a "level" label
+	LevelLabel bool
+	// TenantID is sent as X-Scope-OrgID for multi-tenant Loki
+	TenantID      string
+	Compression   string
+	BasicAuthUser string
+	BasicAuthPass string
+	Retry         RetryPolicy
+	// Client is the shared HTTP client (see NewHTTPClient); when nil one is created from Timeout
+	Client  *http.Client
+	Timeout time.Duration
+	Logger  *slog.Logger
+}
+
+// LokiExporter pushes batches to the Loki push API. Each batch becomes one stream per label set
+// with the log body as the line.
+type LokiExporter struct {
+	cfg    LokiConfig
+	poster *httpPoster
+}
+
+type lokiPushRequest struct {
+	Streams []lokiStream `json:"streams"`
+}
+
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+// NewLokiExporter creates a Loki exporter
+func NewLokiExporter(cfg LokiConfig) (*LokiExporter, error) {
+	if cfg.Endpoint == "" {
+		return nil, fmt.Errorf("loki endpoint is required")
+	}
+	if len(cfg.Labels) == 0 {
+		cfg.Labels = DefaultLokiLabels
+	}
+	if cfg.Client == nil {
+		cfg.Client = NewHTTPClient(HTTPClientConfig{Timeout: cfg.Timeout})
+	}
+
+	header := http.Header{}
+	if cfg.TenantID != "" {
+		header.Set("X-Scope-OrgID", cfg.TenantID)
+	}
+
+	return &LokiExporter{
+		cfg: cfg,
+		poster: &httpPoster{
+			endpoint:    cfg.Endpoint,
+			compression: cfg.Compression,
+			basicUser:   cfg.BasicAuthUser,
+			basicPass:   cfg.BasicAuthPass,
+			header:      header,
+			retry:       cfg.Retry,
+			client:      cfg.Client,
+			logger:      loggerOrDefault(cfg.Logger),
+		},
+	}, nil
+}
+
+// Export pushes the batch
+func (e *LokiExporter) Export(ctx context.Context, logs converter.ResourceLog) error {
+	body, err := json.Marshal(e.buildPush(logs))
+	if err != nil {
+		return fmt.Errorf("failed to marshal push request: %w", err)
+	}
+	return e.poster.post(ctx, body, "application/json")
+}
+
+// buildPush maps the allowlisted resource attributes to labels and each record to a [timestamp, line] entry
+func (e *LokiExporter) buildPush(logs converter.ResourceLog) lokiPushRequest {
+	resource := attributeMap(logs.Resource.Attributes)
+	base := make(map[string]string)
+	for _, key := range e.cfg.Labels {
+		if v, ok := resource[key]; ok {
+			base[lokiLabelName(key)] = fmt.Sprint(v)
+		}
+	}
+
+	streams := make(map[string]*lokiStream)
+	var order []string
+	for _, scope := range logs.ScopeLogs {
+		for _, record := range scope.LogRecords {
+			labels := base
+			streamKey := ""
+			if e.cfg.LevelLabel && record.SeverityText != "" {
+				labels = make(map[string]string, len(base)+1)
+				for k, v := range base {
+					labels[k] = v
+				}
+				labels["level"] = strings.ToLower(record.SeverityText)
+				streamKey = labels["level"]
+			}
+
+			stream, ok := streams[streamKey]
+			if !ok {
+				stream = &lokiStream{Stream: labels}
+				streams[streamKey] = stream
+				order = append(order, streamKey)
+			}
+			stream.Values = append(stream.Values, [2]string{record.TimeUnixNano, record.Body.Text()})
+		}
+	}
+
+	push := lokiPushRequest{Streams: make([]lokiStream, 0, len(order))}
+	for _, key := range order {
+		stream := streams[key]
+		sort.SliceStable(stream.Values, func(i, j int) bool {
+			return lokiTimestampLess(stream.Values[i][0], stream.Values[j][0])
+		})
+		push.Streams = append(push.Streams, *stream)
+	}
+	return push
+}
+
+// lokiLabelName converts an attribute key to a valid Loki label name ([a-zA-Z_][a-zA-Z0-9_]*)
+func lokiLabelName(key string) string {
+	var b strings.Builder
+	for i, r := range key {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_':
+			b.WriteRune(r)
+		case r >= '0' && r <= '9' && i > 0:
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
+
+// lokiTimestampLess compares nanosecond timestamp strings numerically
+func lokiTimestampLess(a, b string) bool {
+	if len(a) != len(b) {
+		return len(a) < len(b)
+	}
+	return a < b
+}
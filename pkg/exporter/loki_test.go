@@ -0,0 +1,74 @@
+package exporter
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+)
+
+func TestLokiExporter_Export(t *testing.T) {
+	var got lokiPushRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Scope-OrgID") != "team-a" {
+			t.Errorf("X-Scope-OrgID = %q, want team-a", r.Header.Get("X-Scope-OrgID"))
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("Failed to decode body: %v", err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	service, region, lbName := "alb-log-parser", "us-east-1", "app/my-alb/123"
+	logs := testResourceLog(
+		converter.OTelLogRecord{TimeUnixNano: "1700000000000000002", SeverityText: "ERROR", Body: textBody("GET /b 502")},
+		converter.OTelLogRecord{TimeUnixNano: "1700000000000000001", SeverityText: "INFO", Body: textBody("GET /a 200")},
+		converter.OTelLogRecord{TimeUnixNano: "999999999999999999", SeverityText: "ERROR", Body: textBody("GET /c 500")},
+	)
+	logs.Resource.Attributes = []converter.OTelAttribute{
+		{Key: "service.name", Value: converter.OTelAnyValue{StringValue: &service}},
+		{Key: "cloud.region", Value: converter.OTelAnyValue{StringValue: &region}},
+		{Key: "aws.lb.name", Value: converter.OTelAnyValue{StringValue: &lbName}},
+	}
+
+	exp, err := NewLokiExporter(LokiConfig{Endpoint: server.URL, LevelLabel: true, TenantID: "team-a", Logger: discardLogger})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := exp.Export(context.Background(), logs); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+
+	if len(got.Streams) != 2 {
+		t.Fatalf("got %d streams, want 2 (one per level)", len(got.Streams))
+	}
+
+	errors := got.Streams[0]
+	if errors.Stream["level"] != "error" || errors.Stream["service_name"] != "alb-log-parser" || errors.Stream["cloud_region"] != "us-east-1" {
+		t.Errorf("stream labels = %v", errors.Stream)
+	}
+	if _, ok := errors.Stream["aws_lb_name"]; ok {
+		t.Error("attribute outside the allowlist became a label")
+	}
+	if len(errors.Values) != 2 || errors.Values[0][1] != "GET /c 500" || errors.Values[1][1] != "GET /b 502" {
+		t.Errorf("values = %v, want sorted by timestamp", errors.Values)
+	}
+}
+
+func TestLokiLabelName(t *testing.T) {
+	tests := map[string]string{
+		"service.name":     "service_name",
+		"cloud.account.id": "cloud_account_id",
+		"9lives":           "_lives",
+		"k8s-pod":          "k8s_pod",
+	}
+	for in, want := range tests {
+		if got := lokiLabelName(in); got != want {
+			t.Errorf("lokiLabelName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package exporter
+
+import (
+	"context"
+	"net/http"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+// OAuth2Config enables bearer-token auth using the OAuth2 client-credentials flow
+type OAuth2Config struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+}
+
+// tokenSource returns a source that caches the token and fetches a new one shortly before it expires
+func (c OAuth2Config) tokenSource() oauth2.TokenSource {
+	cc := clientcredentials.Config{
+		ClientID:     c.ClientID,
+		ClientSecret: c.ClientSecret,
+		TokenURL:     c.TokenURL,
+		Scopes:       c.Scopes,
+	}
+	return cc.TokenSource(context.Background())
+}
+
+// requestAuthorizer returns the request hook that sets the bearer token
+func (c OAuth2Config) requestAuthorizer() func(req *http.Request, body []byte) error {
+	ts := c.tokenSource()
+	return func(req *http.Request, body []byte) error {
+		token, err := ts.Token()
+		if err != nil {
+			return err
+		}
+		token.SetAuthHeader(req)
+		return nil
+	}
+}
+
+// oauth2Credentials attaches the bearer token as per-RPC metadata
+type oauth2Credentials struct {
+	source     oauth2.TokenSource
+	requireTLS bool
+}
+
+func (c oauth2Credentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	token, err := c.source.Token()
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{"authorization": token.Type() + " " + token.AccessToken}, nil
+}
+
+func (c oauth2Credentials) RequireTransportSecurity() bool {
+	return c.requireTLS
+}
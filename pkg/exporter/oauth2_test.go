@@ -0,0 +1,54 @@
+package exporter
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOTLPHTTPExporter_OAuth2(t *testing.T) {
+	tokenRequests := 0
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokenRequests++
+		if err := r.ParseForm(); err != nil {
+			t.Fatal(err)
+		}
+		if r.Form.Get("grant_type") != "client_credentials" || r.Form.Get("scope") != "logs.write" {
+			t.Errorf("token request form = %v", r.Form)
+		}
+		if user, pass, _ := r.BasicAuth(); user != "parser" || pass != "s3cret" {
+			t.Errorf("client credentials = %q/%q", user, pass)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token":"tok-123","token_type":"Bearer","expires_in":3600}`))
+	}))
+	defer tokenServer.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer tok-123" {
+			t.Errorf("Authorization = %q, want Bearer tok-123", got)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	exp := NewOTLPHTTPExporter(OTLPHTTPConfig{
+		Endpoint: server.URL,
+		OAuth2: &OAuth2Config{
+			TokenURL:     tokenServer.URL,
+			ClientID:     "parser",
+			ClientSecret: "s3cret",
+			Scopes:       []string{"logs.write"},
+		},
+		Logger: discardLogger,
+	})
+	for i := 0; i < 2; i++ {
+		if err := exp.Export(context.Background(), testResourceLog(helloRecord())); err != nil {
+			t.Fatalf("Export() error = %v", err)
+		}
+	}
+	if tokenRequests != 1 {
+		t.Errorf("token fetched %d times, want 1 (cached)", tokenRequests)
+	}
+}
@@ -0,0 +1,180 @@
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+)
+
+// OpenSearchConfig configures an OpenSearchExporter
+type OpenSearchConfig struct {
+	// Endpoint is the cluster base URL, e.g. https://search-logs.us-east-1.es.amazonaws.com
+	Endpoint string
+	// Index is the target index template. %{yyyy.MM.dd}-style placeholders are formatted from
+	// each record's timestamp (UTC); any other %{key} is replaced by that resource attribute.
+	Index         string
+	BasicAuthUser string
+	BasicAuthPass string
+	// SigV4 signs requests for Amazon OpenSearch Service when set
+	SigV4 *SigV4Config
+	Retry RetryPolicy
+	// PartialSuccess decides what happens when documents are rejected permanently (e.g. mapping
+	// errors): log (default) accepts the rest of the batch, fail reports the batch as failed
+	PartialSuccess PartialSuccessAction
+	// Client is the shared HTTP client (see NewHTTPClient); when nil one is created from Timeout
+	Client  *http.Client
+	Timeout time.Duration
+	Logger  *slog.Logger
+}
+
+// OpenSearchExporter indexes records through the _bulk API. Items rejected with a retryable
+// status (429, 5xx) are resent with backoff; other item errors are reported per document.
+type OpenSearchExporter struct {
+	cfg    OpenSearchConfig
+	poster *httpPoster
+	index  nameTemplate
+	logger *slog.Logger
+}
+
+// openSearchDoc is the indexed document: the flattened record plus @timestamp for index patterns
+type openSearchDoc struct {
+	Timestamp string `json:"@timestamp"`
+	flatRecord
+}
+
+type bulkResponse struct {
+	Errors bool                        `json:"errors"`
+	Items  []map[string]bulkItemResult `json:"items"`
+}
+
+type bulkItemResult struct {
+	Status int `json:"status"`
+	Error  *struct {
+		Type   string `json:"type"`
+		Reason string `json:"reason"`
+	} `json:"error"`
+}
+
+// NewOpenSearchExporter creates an OpenSearch/Elasticsearch bulk exporter
+func NewOpenSearchExporter(cfg OpenSearchConfig) (*OpenSearchExporter, error) {
+	if cfg.Endpoint == "" {
+		return nil, fmt.Errorf("opensearch endpoint is required")
+	}
+	if cfg.Index == "" {
+		return nil, fmt.Errorf("opensearch index template is required")
+	}
+	if cfg.Client == nil {
+		cfg.Client = NewHTTPClient(HTTPClientConfig{Timeout: cfg.Timeout})
+	}
+
+	logger := loggerOrDefault(cfg.Logger)
+	poster := &httpPoster{
+		endpoint:  strings.TrimSuffix(cfg.Endpoint, "/") + "/_bulk",
+		basicUser: cfg.BasicAuthUser,
+		basicPass: cfg.BasicAuthPass,
+		// Bulk is retried per item below; the poster only retries whole-request failures
+		retry:  cfg.Retry,
+		client: cfg.Client,
+		logger: logger,
+	}
+	if cfg.SigV4 != nil {
+		poster.authorize = cfg.SigV4.requestSigner()
+	}
+
+	return &OpenSearchExporter{cfg: cfg, poster: poster, index: parseNameTemplate(cfg.Index), logger: logger}, nil
+}
+
+// Export bulk-indexes the batch
+func (e *OpenSearchExporter) Export(ctx context.Context, logs converter.ResourceLog) error {
+	resource := attributeMap(logs.Resource.Attributes)
+
+	type pendingDoc struct {
+		index string
+		doc   []byte
+	}
+	var pending []pendingDoc
+	for _, record := range flattenRecords(logs) {
+		ts := recordTime(record.Timestamp)
+		doc, err := json.Marshal(openSearchDoc{Timestamp: ts.Format(time.RFC3339Nano), flatRecord: record})
+		if err != nil {
+			return fmt.Errorf("failed to marshal document: %w", err)
+		}
+		pending = append(pending, pendingDoc{index: e.index.render(ts, resource), doc: doc})
+	}
+
+	var rejected int
+	var lastReason string
+	for attempt := 0; attempt <= e.cfg.Retry.MaxRetries && len(pending) > 0; attempt++ {
+		if attempt > 0 {
+			if err := e.cfg.Retry.wait(ctx, attempt, e.cfg.Retry.Backoff(attempt)); err != nil {
+				return fmt.Errorf("export aborted after %d attempts: %w", attempt, err)
+			}
+		}
+
+		var body bytes.Buffer
+		for _, p := range pending {
+			action, _ := json.Marshal(map[string]map[string]string{"create": {"_index": p.index}})
+			body.Write(action)
+			body.WriteByte('\n')
+			body.Write(p.doc)
+			body.WriteByte('\n')
+		}
+
+		respBody, err := e.poster.postResponse(ctx, body.Bytes(), "application/x-ndjson")
+		if err != nil {
+			return err
+		}
+
+		var resp bulkResponse
+		if err := json.Unmarshal(respBody, &resp); err != nil {
+			return fmt.Errorf("failed to decode bulk response: %w", err)
+		}
+		if !resp.Errors {
+			pending = nil
+			break
+		}
+
+		var retry []pendingDoc
+		for i, item := range resp.Items {
+			if i >= len(pending) {
+				break
+			}
+			for _, result := range item {
+				if result.Error == nil && result.Status < 300 {
+					continue
+				}
+				reason := ""
+				if result.Error != nil {
+					reason = result.Error.Type + ": " + result.Error.Reason
+				}
+				if IsRetryableStatus(result.Status) {
+					retry = append(retry, pending[i])
+				} else {
+					rejected++
+					lastReason = reason
+					e.logger.Warn("Document rejected", "index", pending[i].index, "status", result.Status, "error", reason)
+				}
+			}
+		}
+
+		if len(retry) > 0 {
+			e.logger.Warn("Bulk items failed with retryable status", "attempt", attempt+1, "failed", len(retry))
+		}
+		pending = retry
+	}
+
+	if len(pending) > 0 {
+		return fmt.Errorf("failed after %d attempts: %d documents not indexed", e.cfg.Retry.MaxRetries+1, len(pending))
+	}
+	if rejected > 0 && e.cfg.PartialSuccess == PartialSuccessFail {
+		return fmt.Errorf("%w: %d documents rejected: %s", ErrPartialSuccess, rejected, lastReason)
+	}
+	return nil
+}
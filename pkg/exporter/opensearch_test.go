@@ -0,0 +1,115 @@
+package exporter
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+)
+
+// bulkItems reads the action lines of a _bulk request body
+func bulkItems(t *testing.T, r *http.Request) []string {
+	var indexes []string
+	scanner := bufio.NewScanner(r.Body)
+	for i := 0; scanner.Scan(); i++ {
+		if i%2 == 1 {
+			continue
+		}
+		var action map[string]map[string]string
+		if err := json.Unmarshal(scanner.Bytes(), &action); err != nil {
+			t.Errorf("Invalid action line %q: %v", scanner.Text(), err)
+		}
+		indexes = append(indexes, action["create"]["_index"])
+	}
+	return indexes
+}
+
+func TestOpenSearchExporter_RetriesFailedItems(t *testing.T) {
+	var requests [][]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/_bulk" || r.Header.Get("Content-Type") != "application/x-ndjson" {
+			t.Errorf("unexpected request %s %s", r.URL.Path, r.Header.Get("Content-Type"))
+		}
+		items := bulkItems(t, r)
+		requests = append(requests, items)
+
+		if len(requests) == 1 {
+			// First item succeeds, second is throttled, third is a permanent mapping error
+			w.Write([]byte(`{"errors":true,"items":[
+				{"create":{"status":201}},
+				{"create":{"status":429,"error":{"type":"es_rejected_execution_exception","reason":"queue full"}}},
+				{"create":{"status":400,"error":{"type":"mapper_parsing_exception","reason":"bad field"}}}]}`))
+			return
+		}
+		w.Write([]byte(`{"errors":false,"items":[{"create":{"status":201}}]}`))
+	}))
+	defer server.Close()
+
+	logs := testResourceLog(helloRecord(), helloRecord(), helloRecord())
+	exp, err := NewOpenSearchExporter(OpenSearchConfig{
+		Endpoint: server.URL,
+		Index:    "alb-logs-%{yyyy.MM.dd}",
+		Retry:    RetryPolicy{MaxRetries: 2},
+		Logger:   discardLogger,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := exp.Export(context.Background(), logs); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+
+	if len(requests) != 2 {
+		t.Fatalf("got %d bulk requests, want 2", len(requests))
+	}
+	if len(requests[0]) != 3 || len(requests[1]) != 1 {
+		t.Errorf("request sizes = %d, %d, want 3, 1 (only the throttled item resent)", len(requests[0]), len(requests[1]))
+	}
+	if want := "alb-logs-2023.11.14"; requests[0][0] != want {
+		t.Errorf("index = %q, want %q", requests[0][0], want)
+	}
+
+	exp.cfg.PartialSuccess = PartialSuccessFail
+	requests = nil
+	if err := exp.Export(context.Background(), logs); !errors.Is(err, ErrPartialSuccess) {
+		t.Errorf("Export() error = %v, want ErrPartialSuccess for rejected documents", err)
+	}
+}
+
+func TestOpenSearchExporter_SigV4(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/") || !strings.Contains(auth, "/us-east-1/es/aws4_request") {
+			t.Errorf("Authorization = %q, want SigV4 for es in us-east-1", auth)
+		}
+		if r.Header.Get("X-Amz-Date") == "" {
+			t.Error("missing X-Amz-Date header")
+		}
+		w.Write([]byte(`{"errors":false,"items":[{"create":{"status":201}}]}`))
+	}))
+	defer server.Close()
+
+	exp, err := NewOpenSearchExporter(OpenSearchConfig{
+		Endpoint: server.URL,
+		Index:    "logs",
+		SigV4: &SigV4Config{
+			Region:      "us-east-1",
+			Service:     "es",
+			Credentials: credentials.NewStaticCredentialsProvider("AKIDEXAMPLE", "secret", ""),
+		},
+		Logger: discardLogger,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := exp.Export(context.Background(), testResourceLog(converter.OTelLogRecord{TimeUnixNano: "1700000000000000000"})); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+}
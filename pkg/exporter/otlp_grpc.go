@@ -0,0 +1,249 @@
+package exporter
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/plog/plogotlp"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pmetric/pmetricotlp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/tracing"
+)
+
+// OTLPGRPCConfig configures an OTLPGRPCExporter
+type OTLPGRPCConfig struct {
+	// Endpoint is the collector address as host:port
+	Endpoint string
+	Insecure bool
+	// TLSServerName overrides the server name used for certificate verification
+	TLSServerName string
+	TLSSkipVerify bool
+	// TLS supplies a custom CA and client certificate (see NewTLSConfig); TLSServerName and
+	// TLSSkipVerify are applied on top of it
+	TLS *tls.Config
+	// KeepaliveSeconds is the interval of keepalive pings on an active connection; 0 sends
+	// none. gRPC servers reject pings more frequent than every 5 minutes by default.
+	KeepaliveSeconds int
+	// Compression is "none" (default) or "gzip"
+	Compression   string
+	BasicAuthUser string
+	BasicAuthPass string
+	// Headers are sent as gRPC metadata on every call (see ParseHeaders)
+	Headers map[string]string
+	// OAuth2 sends a client-credentials bearer token when set
+	OAuth2         *OAuth2Config
+	Retry          RetryPolicy
+	PartialSuccess PartialSuccessAction
+	Timeout        time.Duration
+	Logger         *slog.Logger
+	// RateLimit, when set, paces every call including retries
+	RateLimit *RateLimiter
+}
+
+// OTLPGRPCExporter sends batches to an OTLP/gRPC logs endpoint over a shared connection
+type OTLPGRPCExporter struct {
+	cfg    OTLPGRPCConfig
+	conn   *grpc.ClientConn
+	client plogotlp.GRPCClient
+	md     metadata.MD
+	logger *slog.Logger
+}
+
+// basicAuthCredentials attaches HTTP basic auth as per-RPC metadata
+type basicAuthCredentials struct {
+	header     string
+	requireTLS bool
+}
+
+func (c basicAuthCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{"authorization": c.header}, nil
+}
+
+func (c basicAuthCredentials) RequireTransportSecurity() bool {
+	return c.requireTLS
+}
+
+// NewOTLPGRPCExporter creates the gRPC client; the connection is established lazily on first export
+func NewOTLPGRPCExporter(cfg OTLPGRPCConfig) (*OTLPGRPCExporter, error) {
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = "localhost:4317"
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+
+	var opts []grpc.DialOption
+	if cfg.KeepaliveSeconds > 0 {
+		// Pings only while a call is active: servers close connections that ping when idle
+		opts = append(opts, grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:    time.Duration(cfg.KeepaliveSeconds) * time.Second,
+			Timeout: 10 * time.Second,
+		}))
+	}
+
+	if cfg.Compression == "gzip" {
+		opts = append(opts, grpc.WithDefaultCallOptions(grpc.UseCompressor(gzip.Name)))
+	}
+
+	if cfg.Insecure {
+		opts = append(opts, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	} else {
+		tlsConfig := &tls.Config{}
+		if cfg.TLS != nil {
+			tlsConfig = cfg.TLS.Clone()
+		}
+		tlsConfig.ServerName = cfg.TLSServerName
+		tlsConfig.InsecureSkipVerify = cfg.TLSSkipVerify
+		opts = append(opts, grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
+	}
+
+	if cfg.BasicAuthUser != "" && cfg.BasicAuthPass != "" {
+		token := base64.StdEncoding.EncodeToString([]byte(cfg.BasicAuthUser + ":" + cfg.BasicAuthPass))
+		opts = append(opts, grpc.WithPerRPCCredentials(basicAuthCredentials{
+			header:     "Basic " + token,
+			requireTLS: !cfg.Insecure,
+		}))
+	}
+
+	if cfg.OAuth2 != nil {
+		opts = append(opts, grpc.WithPerRPCCredentials(oauth2Credentials{
+			source:     cfg.OAuth2.tokenSource(),
+			requireTLS: !cfg.Insecure,
+		}))
+	}
+
+	conn, err := grpc.NewClient(cfg.Endpoint, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gRPC client for %s: %w", cfg.Endpoint, err)
+	}
+
+	return &OTLPGRPCExporter{
+		cfg:    cfg,
+		conn:   conn,
+		client: plogotlp.NewGRPCClient(conn),
+		md:     metadata.New(cfg.Headers),
+		logger: loggerOrDefault(cfg.Logger),
+	}, nil
+}
+
+// Export sends the batch, retrying retryable status codes with backoff
+func (e *OTLPGRPCExporter) Export(ctx context.Context, logs converter.ResourceLog) error {
+	ld, err := converter.ToLogs(payloadOf(logs))
+	if err != nil {
+		return fmt.Errorf("failed to convert payload: %w", err)
+	}
+
+	req := plogotlp.NewExportRequestFromLogs(ld)
+	return e.send(ctx, (&plog.ProtoMarshaler{}).LogsSize(ld), func(callCtx context.Context) (converter.PartialSuccess, error) {
+		resp, err := e.client.Export(callCtx, req)
+		if err != nil {
+			return converter.PartialSuccess{}, err
+		}
+		return converter.PartialSuccessOf(resp), nil
+	})
+}
+
+// send runs one export call per attempt, retrying retryable status codes with backoff; size
+// paces the attempts under RateLimit
+func (e *OTLPGRPCExporter) send(ctx context.Context, size int, call func(ctx context.Context) (converter.PartialSuccess, error)) error {
+	maxRetries := e.cfg.Retry.MaxRetries
+	var lastErr error
+	for attempt := 0; attempt <= maxRetries; attempt++ {
+		if attempt > 0 {
+			if err := e.cfg.Retry.wait(ctx, attempt, e.cfg.Retry.Backoff(attempt)); err != nil {
+				return fmt.Errorf("export aborted after %d attempts: %w", attempt, err)
+			}
+		}
+
+		if err := e.cfg.RateLimit.Wait(ctx, size); err != nil {
+			return fmt.Errorf("export aborted after %d attempts: %w", attempt, err)
+		}
+
+		callCtx, cancel := context.WithTimeout(ctx, e.cfg.Timeout)
+		md := e.md
+		if tp := tracing.Traceparent(ctx); tp != "" {
+			md = metadata.Join(md, metadata.Pairs("traceparent", tp))
+		}
+		if len(md) > 0 {
+			callCtx = metadata.NewOutgoingContext(callCtx, md)
+		}
+		partial, err := call(callCtx)
+		cancel()
+
+		if err == nil {
+			retry, err := handlePartialSuccess(e.logger, e.cfg.PartialSuccess, partial, attempt, maxRetries)
+			if retry {
+				lastErr = err
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			e.logger.Info("Batch sent successfully", "attempt", attempt+1, "protocol", "grpc")
+			return nil
+		}
+
+		lastErr = err
+
+		if code := status.Code(err); !IsRetryableCode(code) {
+			e.logger.Error("Batch rejected with non-retryable status", "attempt", attempt+1, "protocol", "grpc", "code", code.String(), "error", err)
+			return fmt.Errorf("non-retryable response: %w", err)
+		}
+
+		e.logger.Warn("Batch send attempt failed", "attempt", attempt+1, "protocol", "grpc", "error", err)
+	}
+
+	return fmt.Errorf("failed after %d attempts: %w", maxRetries+1, lastErr)
+}
+
+// OTLPGRPCMetricsExporter sends derived metrics to an OTLP/gRPC metrics service. Connection,
+// auth and retries follow the config, as for logs.
+type OTLPGRPCMetricsExporter struct {
+	grpc   *OTLPGRPCExporter
+	client pmetricotlp.GRPCClient
+}
+
+// NewOTLPGRPCMetricsExporter creates the gRPC metrics client; the connection is established
+// lazily on first export
+func NewOTLPGRPCMetricsExporter(cfg OTLPGRPCConfig) (*OTLPGRPCMetricsExporter, error) {
+	exp, err := NewOTLPGRPCExporter(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &OTLPGRPCMetricsExporter{grpc: exp, client: pmetricotlp.NewGRPCClient(exp.conn)}, nil
+}
+
+// ExportMetrics sends the metrics of one resource
+func (e *OTLPGRPCMetricsExporter) ExportMetrics(ctx context.Context, metrics converter.ResourceMetric) error {
+	md, err := converter.ToMetrics(converter.OTLPMetricsPayload{ResourceMetrics: []converter.ResourceMetric{metrics}})
+	if err != nil {
+		return fmt.Errorf("failed to convert metrics payload: %w", err)
+	}
+
+	req := pmetricotlp.NewExportRequestFromMetrics(md)
+	return e.grpc.send(ctx, (&pmetric.ProtoMarshaler{}).MetricsSize(md), func(callCtx context.Context) (converter.PartialSuccess, error) {
+		resp, err := e.client.Export(callCtx, req)
+		if err != nil {
+			return converter.PartialSuccess{}, err
+		}
+		// Rejected data points are only logged: the counts are not log records
+		if ps := resp.PartialSuccess(); ps.RejectedDataPoints() > 0 || ps.ErrorMessage() != "" {
+			e.grpc.logger.Warn("Collector returned partial success for metrics", "rejected_data_points", ps.RejectedDataPoints(), "error_message", ps.ErrorMessage())
+		}
+		return converter.PartialSuccess{}, nil
+	})
+}
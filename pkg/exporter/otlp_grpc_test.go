@@ -0,0 +1,156 @@
+package exporter
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"strings"
+	"testing"
+
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+)
+
+type fakeLogsServer struct {
+	collogspb.UnimplementedLogsServiceServer
+	received chan *collogspb.ExportLogsServiceRequest
+	tenant   []string
+	// errs fail the first calls, one each
+	errs []error
+	// partial is returned once the calls succeed
+	partial *collogspb.ExportLogsPartialSuccess
+}
+
+func (s *fakeLogsServer) Export(ctx context.Context, req *collogspb.ExportLogsServiceRequest) (*collogspb.ExportLogsServiceResponse, error) {
+	if len(s.errs) > 0 {
+		err := s.errs[0]
+		s.errs = s.errs[1:]
+		return nil, err
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		s.tenant = md.Get("x-scope-orgid")
+	}
+	s.received <- req
+	return &collogspb.ExportLogsServiceResponse{PartialSuccess: s.partial}, nil
+}
+
+func TestOTLPGRPCExporter_Export(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	server := grpc.NewServer()
+	fake := &fakeLogsServer{received: make(chan *collogspb.ExportLogsServiceRequest, 1)}
+	collogspb.RegisterLogsServiceServer(server, fake)
+	go server.Serve(lis)
+	defer server.Stop()
+
+	exp, err := NewOTLPGRPCExporter(OTLPGRPCConfig{
+		Endpoint: lis.Addr().String(),
+		Insecure: true,
+		Headers:  map[string]string{"X-Scope-OrgID": "tenant1"},
+		Logger:   slog.New(slog.NewJSONHandler(io.Discard, nil)),
+	})
+	if err != nil {
+		t.Fatalf("NewOTLPGRPCExporter() error = %v", err)
+	}
+
+	record := converter.OTelLogRecord{TimeUnixNano: "1700000000000000000", SeverityText: "INFO", SeverityNumber: 9, Body: textBody("hello")}
+	if err := exp.Export(context.Background(), testResourceLog(record)); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+
+	req := <-fake.received
+	if got := req.ResourceLogs[0].ScopeLogs[0].LogRecords[0].Body.GetStringValue(); got != "hello" {
+		t.Errorf("Body = %q, want hello", got)
+	}
+	if len(fake.tenant) != 1 || fake.tenant[0] != "tenant1" {
+		t.Errorf("x-scope-orgid metadata = %v, want [tenant1]", fake.tenant)
+	}
+}
+
+func TestOTLPGRPCExporter_RetryThenPartialSuccess(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	server := grpc.NewServer()
+	fake := &fakeLogsServer{
+		received: make(chan *collogspb.ExportLogsServiceRequest, 1),
+		errs:     []error{status.Error(codes.Unavailable, "restarting")},
+		partial:  &collogspb.ExportLogsPartialSuccess{RejectedLogRecords: 1, ErrorMessage: "too old"},
+	}
+	collogspb.RegisterLogsServiceServer(server, fake)
+	go server.Serve(lis)
+	defer server.Stop()
+
+	exp, err := NewOTLPGRPCExporter(OTLPGRPCConfig{
+		Endpoint:       lis.Addr().String(),
+		Insecure:       true,
+		Retry:          RetryPolicy{MaxRetries: 1},
+		PartialSuccess: PartialSuccessFail,
+		Logger:         slog.New(slog.NewJSONHandler(io.Discard, nil)),
+	})
+	if err != nil {
+		t.Fatalf("NewOTLPGRPCExporter() error = %v", err)
+	}
+
+	record := converter.OTelLogRecord{TimeUnixNano: "1700000000000000000", Body: textBody("hello")}
+	err = exp.Export(context.Background(), testResourceLog(record))
+	if err == nil || !strings.Contains(err.Error(), "too old") {
+		t.Errorf("Export() error = %v, want the partial success of the retried call", err)
+	}
+}
+
+type fakeMetricsServer struct {
+	colmetricspb.UnimplementedMetricsServiceServer
+	received chan *colmetricspb.ExportMetricsServiceRequest
+}
+
+func (s *fakeMetricsServer) Export(ctx context.Context, req *colmetricspb.ExportMetricsServiceRequest) (*colmetricspb.ExportMetricsServiceResponse, error) {
+	s.received <- req
+	return &colmetricspb.ExportMetricsServiceResponse{}, nil
+}
+
+func TestOTLPGRPCMetricsExporter_ExportMetrics(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	server := grpc.NewServer()
+	fake := &fakeMetricsServer{received: make(chan *colmetricspb.ExportMetricsServiceRequest, 1)}
+	colmetricspb.RegisterMetricsServiceServer(server, fake)
+	go server.Serve(lis)
+	defer server.Stop()
+
+	exp, err := NewOTLPGRPCMetricsExporter(OTLPGRPCConfig{
+		Endpoint: lis.Addr().String(),
+		Insecure: true,
+		Logger:   slog.New(slog.NewJSONHandler(io.Discard, nil)),
+	})
+	if err != nil {
+		t.Fatalf("NewOTLPGRPCMetricsExporter() error = %v", err)
+	}
+
+	metric := converter.Metric{Name: "aws.log.requests", Sum: &converter.Sum{
+		DataPoints:             []converter.NumberDataPoint{{StartTimeUnixNano: "100", TimeUnixNano: "200", AsInt: "7"}},
+		AggregationTemporality: converter.AggregationTemporalityDelta,
+		IsMonotonic:            true,
+	}}
+	if err := exp.ExportMetrics(context.Background(), converter.ResourceMetric{ScopeMetrics: []converter.ScopeMetric{{Metrics: []converter.Metric{metric}}}}); err != nil {
+		t.Fatalf("ExportMetrics() error = %v", err)
+	}
+
+	req := <-fake.received
+	got := req.ResourceMetrics[0].ScopeMetrics[0].Metrics[0]
+	if got.Name != "aws.log.requests" || got.GetSum().DataPoints[0].GetAsInt() != 7 {
+		t.Errorf("metric = %v, want aws.log.requests = 7", got)
+	}
+}
@@ -0,0 +1,173 @@
+package exporter
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+)
+
+// OTLPHTTPConfig configures an OTLPHTTPExporter
+type OTLPHTTPConfig struct {
+	Endpoint string
+	// Encoding is "json" (default) or "protobuf"
+	Encoding string
+	// Compression is "none" (default) or "gzip"
+	Compression   string
+	BasicAuthUser string
+	BasicAuthPass string
+	// Headers are added to every request (see ParseHeaders)
+	Headers map[string]string
+	// SigV4 signs requests for IAM-authenticated endpoints (API Gateway, Lambda URLs) when set
+	SigV4 *SigV4Config
+	// OAuth2 sends a client-credentials bearer token when set
+	OAuth2 *OAuth2Config
+	// Client is the shared HTTP client (see NewHTTPClient); when nil one is created from
+	// Timeout and TLS
+	Client         *http.Client
+	TLS            *tls.Config
+	Retry          RetryPolicy
+	PartialSuccess PartialSuccessAction
+	Timeout        time.Duration
+	Logger         *slog.Logger
+	// RateLimit, when set, paces every request including retries
+	RateLimit *RateLimiter
+}
+
+// OTLPHTTPExporter sends batches to an OTLP/HTTP logs endpoint
+type OTLPHTTPExporter struct {
+	cfg    OTLPHTTPConfig
+	poster *httpPoster
+}
+
+// NewOTLPHTTPExporter creates an OTLP/HTTP exporter
+func NewOTLPHTTPExporter(cfg OTLPHTTPConfig) *OTLPHTTPExporter {
+	logger := loggerOrDefault(cfg.Logger)
+	return &OTLPHTTPExporter{
+		cfg: cfg,
+		poster: newOTLPHTTPPoster(cfg, func(resp *http.Response, body []byte, attempt int) (bool, error) {
+			partial, err := converter.ParseExportResponse(resp.Header.Get("Content-Type"), body)
+			if err != nil {
+				logger.Warn("Could not decode export response", "error", err)
+			}
+			return handlePartialSuccess(logger, cfg.PartialSuccess, partial, attempt, cfg.Retry.MaxRetries)
+		}),
+	}
+}
+
+// newOTLPHTTPPoster creates the poster of an OTLP/HTTP signal; onSuccess handles the signal's
+// export response
+func newOTLPHTTPPoster(cfg OTLPHTTPConfig, onSuccess func(resp *http.Response, body []byte, attempt int) (bool, error)) *httpPoster {
+	if cfg.Client == nil {
+		cfg.Client = NewHTTPClient(HTTPClientConfig{Timeout: cfg.Timeout, TLS: cfg.TLS})
+	}
+	header := http.Header{}
+	for k, v := range cfg.Headers {
+		header.Set(k, v)
+	}
+	p := &httpPoster{
+		endpoint:    cfg.Endpoint,
+		compression: cfg.Compression,
+		basicUser:   cfg.BasicAuthUser,
+		basicPass:   cfg.BasicAuthPass,
+		header:      header,
+		retry:       cfg.Retry,
+		limiter:     cfg.RateLimit,
+		client:      cfg.Client,
+		logger:      loggerOrDefault(cfg.Logger),
+		onSuccess:   onSuccess,
+	}
+	switch {
+	case cfg.SigV4 != nil:
+		p.authorize = cfg.SigV4.requestSigner()
+	case cfg.OAuth2 != nil:
+		p.authorize = cfg.OAuth2.requestAuthorizer()
+	}
+	return p
+}
+
+// encodeAs marshals the payload with the protobuf or JSON marshaler of the OTLP encoding
+func encodeAs[P any](encoding string, payload P, marshalProto, marshalJSON func(P) ([]byte, error)) ([]byte, string, error) {
+	if encoding == "protobuf" {
+		body, err := marshalProto(payload)
+		return body, "application/x-protobuf", err
+	}
+	body, err := marshalJSON(payload)
+	return body, "application/json", err
+}
+
+// rejectionLogger returns an OTLP/HTTP response handler that logs the rejected data points or
+// spans of a metrics or traces export. They are only logged: the counts are not log records.
+func rejectionLogger(logger *slog.Logger, signal, countKey string, parse func(contentType string, body []byte) (converter.Rejection, error)) func(*http.Response, []byte, int) (bool, error) {
+	return func(resp *http.Response, body []byte, attempt int) (bool, error) {
+		rejection, err := parse(resp.Header.Get("Content-Type"), body)
+		if err != nil {
+			logger.Warn("Could not decode export response", "signal", signal, "error", err)
+			return false, nil
+		}
+		if rejection.Rejected() {
+			logger.Warn("Collector returned partial success for "+signal, countKey, rejection.Count, "error_message", rejection.ErrorMessage)
+		}
+		return false, nil
+	}
+}
+
+// Export sends the batch, retrying retryable failures with backoff
+func (e *OTLPHTTPExporter) Export(ctx context.Context, logs converter.ResourceLog) error {
+	body, contentType, err := encodeAs(e.cfg.Encoding, payloadOf(logs), converter.MarshalProto, converter.MarshalJSON)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+	return e.poster.post(ctx, body, contentType)
+}
+
+// OTLPHTTPMetricsExporter sends derived metrics to an OTLP/HTTP metrics endpoint (/v1/metrics).
+// Encoding, compression, auth and retries follow the config; rejected data points are logged.
+type OTLPHTTPMetricsExporter struct {
+	cfg    OTLPHTTPConfig
+	poster *httpPoster
+}
+
+// NewOTLPHTTPMetricsExporter creates an OTLP/HTTP metrics exporter
+func NewOTLPHTTPMetricsExporter(cfg OTLPHTTPConfig) *OTLPHTTPMetricsExporter {
+	onSuccess := rejectionLogger(loggerOrDefault(cfg.Logger), "metrics", "rejected_data_points", converter.ParseMetricsExportResponse)
+	return &OTLPHTTPMetricsExporter{cfg: cfg, poster: newOTLPHTTPPoster(cfg, onSuccess)}
+}
+
+// ExportMetrics sends the metrics of one resource
+func (e *OTLPHTTPMetricsExporter) ExportMetrics(ctx context.Context, metrics converter.ResourceMetric) error {
+	payload := converter.OTLPMetricsPayload{ResourceMetrics: []converter.ResourceMetric{metrics}}
+	body, contentType, err := encodeAs(e.cfg.Encoding, payload, converter.MarshalMetricsProto, converter.MarshalMetricsJSON)
+	if err != nil {
+		return fmt.Errorf("failed to marshal metrics payload: %w", err)
+	}
+	return e.poster.post(ctx, body, contentType)
+}
+
+// OTLPHTTPTracesExporter sends the parser's own spans to an OTLP/HTTP traces endpoint
+// (/v1/traces). Encoding, compression, auth and retries follow the config; rejected spans are
+// logged.
+type OTLPHTTPTracesExporter struct {
+	cfg    OTLPHTTPConfig
+	poster *httpPoster
+}
+
+// NewOTLPHTTPTracesExporter creates an OTLP/HTTP traces exporter
+func NewOTLPHTTPTracesExporter(cfg OTLPHTTPConfig) *OTLPHTTPTracesExporter {
+	onSuccess := rejectionLogger(loggerOrDefault(cfg.Logger), "traces", "rejected_spans", converter.ParseTracesExportResponse)
+	return &OTLPHTTPTracesExporter{cfg: cfg, poster: newOTLPHTTPPoster(cfg, onSuccess)}
+}
+
+// ExportSpans sends the spans of one resource
+func (e *OTLPHTTPTracesExporter) ExportSpans(ctx context.Context, spans converter.ResourceSpans) error {
+	payload := converter.OTLPTracesPayload{ResourceSpans: []converter.ResourceSpans{spans}}
+	body, contentType, err := encodeAs(e.cfg.Encoding, payload, converter.MarshalTracesProto, converter.MarshalTracesJSON)
+	if err != nil {
+		return fmt.Errorf("failed to marshal traces payload: %w", err)
+	}
+	return e.poster.post(ctx, body, contentType)
+}
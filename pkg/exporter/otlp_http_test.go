@@ -0,0 +1,406 @@
+package exporter
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/tracing"
+)
+
+var discardLogger = slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+func testResourceLog(records ...converter.OTelLogRecord) converter.ResourceLog {
+	return converter.ResourceLog{
+		ScopeLogs: []converter.ScopeLog{{Scope: converter.Scope{Name: "test"}, LogRecords: records}},
+	}
+}
+
+func textBody(s string) converter.OTelAnyValue {
+	return converter.OTelAnyValue{StringValue: &s}
+}
+
+func helloRecord() converter.OTelLogRecord {
+	return converter.OTelLogRecord{TimeUnixNano: "1700000000000000000", Body: textBody("hello")}
+}
+
+func TestOTLPHTTPExporter_Gzip(t *testing.T) {
+	var got converter.OTLPPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Encoding") != "gzip" {
+			t.Errorf("Content-Encoding = %q, want gzip", r.Header.Get("Content-Encoding"))
+		}
+		if r.Header.Get("Signoz-Access-Token") != "abc" {
+			t.Errorf("Signoz-Access-Token = %q, want the custom header", r.Header.Get("Signoz-Access-Token"))
+		}
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			t.Errorf("Body is not gzip: %v", err)
+			return
+		}
+		if err := json.NewDecoder(gz).Decode(&got); err != nil {
+			t.Errorf("Failed to decode body: %v", err)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	exp := NewOTLPHTTPExporter(OTLPHTTPConfig{
+		Endpoint:    server.URL,
+		Compression: "gzip",
+		Headers:     map[string]string{"signoz-access-token": "abc"},
+		Logger:      discardLogger,
+	})
+	if err := exp.Export(context.Background(), testResourceLog(helloRecord())); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+
+	if len(got.ResourceLogs) != 1 || got.ResourceLogs[0].ScopeLogs[0].LogRecords[0].Body.Text() != "hello" {
+		t.Errorf("Unexpected payload received: %+v", got)
+	}
+}
+
+func TestOTLPHTTPExporter_PartialSuccess(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"partialSuccess":{"rejectedLogRecords":"2","errorMessage":"bad timestamp"}}`))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		action    PartialSuccessAction
+		wantErr   bool
+		wantCalls int
+	}{
+		{action: PartialSuccessLog, wantErr: false, wantCalls: 1},
+		{action: PartialSuccessFail, wantErr: true, wantCalls: 1},
+		{action: PartialSuccessRetry, wantErr: false, wantCalls: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.action), func(t *testing.T) {
+			calls = 0
+			exp := NewOTLPHTTPExporter(OTLPHTTPConfig{
+				Endpoint:       server.URL,
+				Retry:          RetryPolicy{MaxRetries: 1},
+				PartialSuccess: tt.action,
+				Logger:         discardLogger,
+			})
+
+			err := exp.Export(context.Background(), testResourceLog(helloRecord()))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Export() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrPartialSuccess) {
+				t.Errorf("Export() error = %v, want ErrPartialSuccess", err)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("collector called %d times, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestOTLPHTTPExporter_StatusHandling(t *testing.T) {
+	tests := []struct {
+		name      string
+		statuses  []int
+		wantErr   bool
+		wantCalls int
+	}{
+		{name: "Bad request fails fast", statuses: []int{400}, wantErr: true, wantCalls: 1},
+		{name: "Payload too large fails fast", statuses: []int{413}, wantErr: true, wantCalls: 1},
+		{name: "Throttled then accepted", statuses: []int{429, 200}, wantErr: false, wantCalls: 2},
+		{name: "Unavailable then accepted", statuses: []int{503, 200}, wantErr: false, wantCalls: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				code := tt.statuses[calls]
+				calls++
+				if code == 429 || code == 503 {
+					w.Header().Set("Retry-After", "0")
+				}
+				w.WriteHeader(code)
+			}))
+			defer server.Close()
+
+			retries := 0
+			retry := RetryPolicy{MaxRetries: 3, OnRetry: func(int) { retries++ }}
+			exp := NewOTLPHTTPExporter(OTLPHTTPConfig{Endpoint: server.URL, Retry: retry, Logger: discardLogger})
+			err := exp.Export(context.Background(), testResourceLog(helloRecord()))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Export() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("collector called %d times, want %d", calls, tt.wantCalls)
+			}
+			if retries != tt.wantCalls-1 {
+				t.Errorf("OnRetry called %d times, want %d", retries, tt.wantCalls-1)
+			}
+		})
+	}
+}
+
+func TestOTLPHTTPExporter_RetryAfterLongerThanBackoff(t *testing.T) {
+	throttled := func(calls *int) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			*calls++
+			if *calls == 1 {
+				w.Header().Set("Retry-After", "1")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+	}
+	// The policy's own backoff is at most 1ms, far below the server's 1s
+	retry := RetryPolicy{MaxRetries: 1, BaseDelay: time.Millisecond}
+
+	t.Run("waits the full Retry-After", func(t *testing.T) {
+		calls := 0
+		server := throttled(&calls)
+		defer server.Close()
+
+		exp := NewOTLPHTTPExporter(OTLPHTTPConfig{Endpoint: server.URL, Retry: retry, Logger: discardLogger})
+		start := time.Now()
+		if err := exp.Export(context.Background(), testResourceLog(helloRecord())); err != nil {
+			t.Fatalf("Export() error = %v", err)
+		}
+		if elapsed := time.Since(start); elapsed < time.Second {
+			t.Errorf("retried after %v, want the 1s Retry-After", elapsed)
+		}
+		if calls != 2 {
+			t.Errorf("collector called %d times, want 2", calls)
+		}
+	})
+
+	t.Run("gives up past the deadline", func(t *testing.T) {
+		calls := 0
+		server := throttled(&calls)
+		defer server.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+		exp := NewOTLPHTTPExporter(OTLPHTTPConfig{Endpoint: server.URL, Retry: retry, Logger: discardLogger})
+		if err := exp.Export(ctx, testResourceLog(helloRecord())); !errors.Is(err, ErrRetryAfterTooLong) {
+			t.Errorf("Export() error = %v, want ErrRetryAfterTooLong", err)
+		}
+		if calls != 1 {
+			t.Errorf("collector called %d times, want 1", calls)
+		}
+	})
+
+	t.Run("gives up past the budget", func(t *testing.T) {
+		calls := 0
+		server := throttled(&calls)
+		defer server.Close()
+
+		budgeted := retry
+		budgeted.Budget = NewRetryBudget(100 * time.Millisecond)
+		exp := NewOTLPHTTPExporter(OTLPHTTPConfig{Endpoint: server.URL, Retry: budgeted, Logger: discardLogger})
+		if err := exp.Export(context.Background(), testResourceLog(helloRecord())); !errors.Is(err, ErrRetryBudgetExhausted) {
+			t.Errorf("Export() error = %v, want ErrRetryBudgetExhausted", err)
+		}
+		if calls != 1 {
+			t.Errorf("collector called %d times, want 1", calls)
+		}
+	})
+}
+
+func TestOTLPHTTPExporter_ContextCanceled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	exp := NewOTLPHTTPExporter(OTLPHTTPConfig{Endpoint: server.URL, Retry: RetryPolicy{MaxRetries: 5, BaseDelay: time.Second}, Logger: discardLogger})
+	start := time.Now()
+	if err := exp.Export(ctx, testResourceLog(helloRecord())); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Export() error = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Export() took %v after the context expired", elapsed)
+	}
+}
+
+func TestOTLPHTTPMetricsExporter_ExportMetrics(t *testing.T) {
+	var got converter.OTLPMetricsPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/metrics" {
+			t.Errorf("path = %q, want /v1/metrics", r.URL.Path)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("Failed to decode body: %v", err)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	counter := converter.NewRecordCounter()
+	counter.Add(helloRecord())
+	metrics := converter.ResourceMetric{
+		ScopeMetrics: []converter.ScopeMetric{{Scope: converter.Scope{Name: "test"}, Metrics: counter.Metrics()}},
+	}
+
+	exp := NewOTLPHTTPMetricsExporter(OTLPHTTPConfig{Endpoint: server.URL + "/v1/metrics", Logger: discardLogger})
+	if err := exp.ExportMetrics(context.Background(), metrics); err != nil {
+		t.Fatalf("ExportMetrics() error = %v", err)
+	}
+
+	if len(got.ResourceMetrics) != 1 || got.ResourceMetrics[0].ScopeMetrics[0].Metrics[0].Name != "aws.log.records" {
+		t.Errorf("Unexpected payload received: %+v", got)
+	}
+}
+
+func TestOTLPHTTPTracesExporter_ExportSpans(t *testing.T) {
+	var got converter.OTLPTracesPayload
+	var traceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("Failed to decode body: %v", err)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	tracer := &tracing.Tracer{}
+	ctx, root := tracer.Root(context.Background(), "invocation")
+	root.End()
+
+	spans := converter.ResourceSpans{
+		ScopeSpans: []converter.ScopeSpans{{Scope: converter.Scope{Name: "test"}, Spans: tracer.Take()}},
+	}
+	exp := NewOTLPHTTPTracesExporter(OTLPHTTPConfig{Endpoint: server.URL + "/v1/traces", Logger: discardLogger})
+	if err := exp.ExportSpans(ctx, spans); err != nil {
+		t.Fatalf("ExportSpans() error = %v", err)
+	}
+
+	if len(got.ResourceSpans) != 1 || got.ResourceSpans[0].ScopeSpans[0].Spans[0].Name != "invocation" {
+		t.Errorf("Unexpected payload received: %+v", got)
+	}
+	if want := tracing.Traceparent(ctx); traceparent != want {
+		t.Errorf("traceparent = %q, want %q", traceparent, want)
+	}
+}
+
+func TestOTLPHTTPSignalExporters_ProtobufAndPartialSuccess(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logs, nil))
+
+	var contentType string
+	var body []byte
+	collector := func(response proto.Message) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			contentType = r.Header.Get("Content-Type")
+			body, _ = io.ReadAll(r.Body)
+			out, _ := proto.Marshal(response)
+			w.Header().Set("Content-Type", "application/x-protobuf")
+			w.Write(out)
+		}))
+	}
+
+	t.Run("metrics", func(t *testing.T) {
+		logs.Reset()
+		server := collector(&colmetricspb.ExportMetricsServiceResponse{
+			PartialSuccess: &colmetricspb.ExportMetricsPartialSuccess{RejectedDataPoints: 4, ErrorMessage: "stale"},
+		})
+		defer server.Close()
+
+		counter := converter.NewRecordCounter()
+		counter.Add(helloRecord())
+		exp := NewOTLPHTTPMetricsExporter(OTLPHTTPConfig{Endpoint: server.URL, Encoding: "protobuf", Logger: logger})
+		if err := exp.ExportMetrics(context.Background(), converter.ResourceMetric{ScopeMetrics: []converter.ScopeMetric{{Metrics: counter.Metrics()}}}); err != nil {
+			t.Fatalf("ExportMetrics() error = %v", err)
+		}
+
+		var req colmetricspb.ExportMetricsServiceRequest
+		if contentType != "application/x-protobuf" || proto.Unmarshal(body, &req) != nil {
+			t.Fatalf("request = %s %q, want an ExportMetricsServiceRequest protobuf", contentType, body)
+		}
+		if got := req.ResourceMetrics[0].ScopeMetrics[0].Metrics[0].Name; got != "aws.log.records" {
+			t.Errorf("metric = %q, want aws.log.records", got)
+		}
+		if !strings.Contains(logs.String(), `"rejected_data_points":4`) {
+			t.Errorf("log = %s, want the 4 rejected data points", logs.String())
+		}
+	})
+
+	t.Run("traces", func(t *testing.T) {
+		logs.Reset()
+		server := collector(&coltracepb.ExportTraceServiceResponse{
+			PartialSuccess: &coltracepb.ExportTracePartialSuccess{RejectedSpans: 2},
+		})
+		defer server.Close()
+
+		tracer := &tracing.Tracer{}
+		_, root := tracer.Root(context.Background(), "invocation")
+		root.End()
+		exp := NewOTLPHTTPTracesExporter(OTLPHTTPConfig{Endpoint: server.URL, Encoding: "protobuf", Logger: logger})
+		if err := exp.ExportSpans(context.Background(), converter.ResourceSpans{ScopeSpans: []converter.ScopeSpans{{Spans: tracer.Take()}}}); err != nil {
+			t.Fatalf("ExportSpans() error = %v", err)
+		}
+
+		var req coltracepb.ExportTraceServiceRequest
+		if contentType != "application/x-protobuf" || proto.Unmarshal(body, &req) != nil {
+			t.Fatalf("request = %s %q, want an ExportTraceServiceRequest protobuf", contentType, body)
+		}
+		if got := req.ResourceSpans[0].ScopeSpans[0].Spans[0].Name; got != "invocation" {
+			t.Errorf("span = %q, want invocation", got)
+		}
+		if !strings.Contains(logs.String(), `"rejected_spans":2`) {
+			t.Errorf("log = %s, want the 2 rejected spans", logs.String())
+		}
+	})
+}
+
+func TestOTLPHTTPExporter_SigV4(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 ") || !strings.Contains(auth, "/eu-west-1/execute-api/aws4_request") {
+			t.Errorf("Authorization = %q, want SigV4 for execute-api in eu-west-1", auth)
+		}
+		if !strings.Contains(auth, "content-encoding") {
+			t.Errorf("Authorization = %q, want Content-Encoding among the signed headers", auth)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	exp := NewOTLPHTTPExporter(OTLPHTTPConfig{
+		Endpoint:    server.URL,
+		Compression: "gzip",
+		SigV4: &SigV4Config{
+			Region:      "eu-west-1",
+			Service:     "execute-api",
+			Credentials: credentials.NewStaticCredentialsProvider("AKIDEXAMPLE", "secret", ""),
+		},
+		Logger: discardLogger,
+	})
+	if err := exp.Export(context.Background(), testResourceLog(helloRecord())); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+package exporter
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimiter paces export requests with two token buckets, one of requests per second and
+// one of request bytes per second, shared by every exporter and concurrent sender holding it.
+// Each bucket holds one second's worth, so short bursts pass and sustained load is spread
+// out. Retries are paced too, so a 429 does not turn into a burst of retries. A nil limiter
+// does not limit.
+type RateLimiter struct {
+	mu       sync.Mutex
+	requests tokenBucket
+	bytes    tokenBucket
+}
+
+// tokenBucket refills at rate tokens per second up to rate; a zero rate never limits
+type tokenBucket struct {
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+// reserve takes n tokens, letting the balance go negative, and returns how long to wait until
+// the balance is back to zero
+func (b *tokenBucket) reserve(n float64, now time.Time) time.Duration {
+	if b.rate <= 0 {
+		return 0
+	}
+	if !b.last.IsZero() {
+		b.tokens = min(b.rate, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	}
+	b.last = now
+	b.tokens -= n
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// NewRateLimiter creates a limiter of requestsPerSec requests and bytesPerSec bytes per
+// second; zero leaves that dimension unlimited, and both zero returns nil
+func NewRateLimiter(requestsPerSec float64, bytesPerSec int64) *RateLimiter {
+	if requestsPerSec <= 0 && bytesPerSec <= 0 {
+		return nil
+	}
+	return &RateLimiter{
+		requests: tokenBucket{rate: requestsPerSec, tokens: requestsPerSec},
+		bytes:    tokenBucket{rate: float64(bytesPerSec), tokens: float64(bytesPerSec)},
+	}
+}
+
+// Wait blocks until a request of size bytes may be sent, or until ctx is done. A request
+// larger than a second's worth of bytes is sent once the bucket has paid for it.
+func (l *RateLimiter) Wait(ctx context.Context, size int) error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	now := time.Now()
+	wait := max(l.requests.reserve(1, now), l.bytes.reserve(float64(size), now))
+	l.mu.Unlock()
+
+	if err := sleepContext(ctx, wait); err != nil {
+		// The request is not sent, so its tokens go back to the senders still waiting
+		l.mu.Lock()
+		l.requests.tokens++
+		l.bytes.tokens += float64(size)
+		l.mu.Unlock()
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package exporter
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter_Wait(t *testing.T) {
+	if NewRateLimiter(0, 0) != nil {
+		t.Error("NewRateLimiter(0, 0) is not nil")
+	}
+	var unlimited *RateLimiter
+	if err := unlimited.Wait(context.Background(), 1<<20); err != nil {
+		t.Errorf("nil limiter Wait() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		limiter  *RateLimiter
+		burst    []int
+		next     int
+		minDelay time.Duration
+	}{
+		// A second's worth passes at once, the next request waits for its token
+		{name: "requests", limiter: NewRateLimiter(20, 0), burst: make([]int, 20), next: 0, minDelay: 40 * time.Millisecond},
+		{name: "bytes", limiter: NewRateLimiter(0, 1000), burst: []int{600, 400}, next: 50, minDelay: 40 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Now()
+			for _, size := range tt.burst {
+				if err := tt.limiter.Wait(context.Background(), size); err != nil {
+					t.Fatalf("Wait() error = %v", err)
+				}
+			}
+			if elapsed := time.Since(start); elapsed > 20*time.Millisecond {
+				t.Errorf("burst took %v, want no wait", elapsed)
+			}
+			start = time.Now()
+			if err := tt.limiter.Wait(context.Background(), tt.next); err != nil {
+				t.Fatalf("Wait() error = %v", err)
+			}
+			if elapsed := time.Since(start); elapsed < tt.minDelay {
+				t.Errorf("request past the burst waited %v, want at least %v", elapsed, tt.minDelay)
+			}
+		})
+	}
+}
+
+func TestRateLimiter_WaitCanceled(t *testing.T) {
+	limiter := NewRateLimiter(1, 0)
+	limiter.Wait(context.Background(), 0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := limiter.Wait(ctx, 0); err == nil {
+		t.Fatal("Wait() past the burst returned before its token")
+	}
+	// The canceled request's token was returned, so the balance is one request short, not two
+	if tokens := limiter.requests.tokens; tokens < -0.1 {
+		t.Errorf("tokens = %v after the canceled wait, want about 0", tokens)
+	}
+}
@@ -0,0 +1,128 @@
+package exporter
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/compress/snappy"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+)
+
+// WriteRecords writes every log record of the batches to w as one flat row each, in the
+// S3 archive's schema, for tools like Athena and pandas:
+//
+//   - ndjson: one JSON object per line, attributes nested under "attributes" and "resource"
+//   - csv: a header row, then one column per attribute, named "attributes.<key>" or
+//     "resource.<key>"; a record without the attribute leaves the cell empty
+//   - parquet: snappy-compressed, attributes as string maps
+func WriteRecords(w io.Writer, format string, logs []converter.ResourceLog) error {
+	var records []flatRecord
+	for _, batch := range logs {
+		records = append(records, flattenRecords(batch)...)
+	}
+
+	switch format {
+	case "ndjson":
+		enc := json.NewEncoder(w)
+		for _, r := range records {
+			if err := enc.Encode(r); err != nil {
+				return fmt.Errorf("failed to marshal record: %w", err)
+			}
+		}
+		return nil
+	case "csv":
+		return writeCSV(w, records)
+	case "parquet":
+		return writeParquet(w, records)
+	}
+	return fmt.Errorf("unknown record format %q: want ndjson, csv or parquet", format)
+}
+
+// writeCSV writes the records with a column per attribute key seen in any record, sorted
+func writeCSV(w io.Writer, records []flatRecord) error {
+	attrKeys := make(map[string]bool)
+	resourceKeys := make(map[string]bool)
+	for _, r := range records {
+		for k := range r.Attributes {
+			attrKeys[k] = true
+		}
+		for k := range r.Resource {
+			resourceKeys[k] = true
+		}
+	}
+	attrCols, resourceCols := sortedKeys(attrKeys), sortedKeys(resourceKeys)
+
+	header := []string{"timestamp", "severity_text", "severity_number", "body", "trace_id", "span_id"}
+	for _, k := range attrCols {
+		header = append(header, "attributes."+k)
+	}
+	for _, k := range resourceCols {
+		header = append(header, "resource."+k)
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(header); err != nil {
+		return fmt.Errorf("failed to write csv header: %w", err)
+	}
+	for _, r := range records {
+		row := []string{r.Timestamp, r.SeverityText, strconv.Itoa(r.SeverityNumber), r.Body, r.TraceID, r.SpanID}
+		for _, k := range attrCols {
+			row = append(row, csvValue(r.Attributes, k))
+		}
+		for _, k := range resourceCols {
+			row = append(row, csvValue(r.Resource, k))
+		}
+		if err := cw.Write(row); err != nil {
+			return fmt.Errorf("failed to write csv row: %w", err)
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func csvValue(m map[string]any, key string) string {
+	if v, ok := m[key]; ok {
+		return textValue(v)
+	}
+	return ""
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// writeParquet writes the records as one snappy-compressed Parquet file
+func writeParquet(w io.Writer, records []flatRecord) error {
+	rows := make([]parquetRecord, len(records))
+	for i, r := range records {
+		rows[i] = parquetRecord{
+			Timestamp:      recordTime(r.Timestamp),
+			SeverityText:   r.SeverityText,
+			SeverityNumber: int32(r.SeverityNumber),
+			Body:           r.Body,
+			TraceID:        r.TraceID,
+			SpanID:         r.SpanID,
+			Attributes:     stringMap(r.Attributes),
+			Resource:       stringMap(r.Resource),
+		}
+	}
+	pw := parquet.NewGenericWriter[parquetRecord](w, parquet.Compression(&snappy.Codec{}))
+	if _, err := pw.Write(rows); err != nil {
+		return fmt.Errorf("failed to write parquet rows: %w", err)
+	}
+	if err := pw.Close(); err != nil {
+		return fmt.Errorf("failed to write parquet rows: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package exporter
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/parquet-go/parquet-go"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+)
+
+func recordsTestLogs() []converter.ResourceLog {
+	status := "503"
+	logs := archiveTestLogs()
+	tags := &converter.OTelArrayValue{Values: []converter.OTelAnyValue{{StringValue: aws.String("a")}, {StringValue: aws.String("b")}}}
+	logs.ScopeLogs[0].LogRecords[0].Attributes = []converter.OTelAttribute{{Key: "tags", Value: converter.OTelAnyValue{ArrayValue: tags}}}
+	logs.ScopeLogs[0].LogRecords[1].Attributes = []converter.OTelAttribute{{Key: "http.status_code", Value: converter.OTelAnyValue{IntValue: &status}}}
+	return []converter.ResourceLog{logs}
+}
+
+func TestWriteRecords(t *testing.T) {
+	t.Run("ndjson", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := WriteRecords(&buf, "ndjson", recordsTestLogs()); err != nil {
+			t.Fatalf("WriteRecords() error = %v", err)
+		}
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		if len(lines) != 2 {
+			t.Fatalf("got %d lines, want 2", len(lines))
+		}
+		var r flatRecord
+		if err := json.Unmarshal([]byte(lines[1]), &r); err != nil {
+			t.Fatal(err)
+		}
+		if r.Body != "day two" || r.Attributes["http.status_code"] != float64(503) || r.Resource["aws.lb.name"] != "app/my-alb/123" {
+			t.Errorf("record = %+v", r)
+		}
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := WriteRecords(&buf, "csv", recordsTestLogs()); err != nil {
+			t.Fatalf("WriteRecords() error = %v", err)
+		}
+		rows, err := csv.NewReader(&buf).ReadAll()
+		if err != nil {
+			t.Fatalf("Invalid csv: %v", err)
+		}
+		want := [][]string{
+			{"timestamp", "severity_text", "severity_number", "body", "trace_id", "span_id", "attributes.http.status_code", "attributes.tags", "resource.aws.lb.name"},
+			{"1700000000000000000", "INFO", "0", "day one", "", "", "", `["a","b"]`, "app/my-alb/123"},
+			{"1700100000000000000", "ERROR", "0", "day two", "", "", "503", "", "app/my-alb/123"},
+		}
+		if len(rows) != len(want) {
+			t.Fatalf("got %d rows, want %d", len(rows), len(want))
+		}
+		for i := range want {
+			if strings.Join(rows[i], ",") != strings.Join(want[i], ",") {
+				t.Errorf("row %d = %v, want %v", i, rows[i], want[i])
+			}
+		}
+	})
+
+	t.Run("parquet", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := WriteRecords(&buf, "parquet", recordsTestLogs()); err != nil {
+			t.Fatalf("WriteRecords() error = %v", err)
+		}
+		rows, err := parquet.Read[parquetRecord](bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		if err != nil {
+			t.Fatalf("Invalid parquet file: %v", err)
+		}
+		if len(rows) != 2 || rows[1].Attributes["http.status_code"] != "503" || rows[1].Timestamp.UnixNano() != 1700100000000000000 {
+			t.Errorf("rows = %+v", rows)
+		}
+	})
+
+	if err := WriteRecords(&bytes.Buffer{}, "xml", nil); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
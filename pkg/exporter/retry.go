@@ -0,0 +1,169 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc/codes"
+)
+
+// RetryPolicy controls how exporters retry failed batches
+type RetryPolicy struct {
+	// MaxRetries is the number of retries after the first attempt
+	MaxRetries int
+	// BaseDelay is the backoff before the first retry; it doubles on each subsequent one
+	BaseDelay time.Duration
+	// OnRetry, when set, is called before each retry with its attempt number (1-based)
+	OnRetry func(attempt int)
+	// Budget, when set, caps the backoff time of all retries sharing it
+	Budget *RetryBudget
+	// MaxRetryAfter, when positive, is the longest server Retry-After the policy waits for; a
+	// batch asked to wait longer fails instead
+	MaxRetryAfter time.Duration
+}
+
+// retrying reports a retry to OnRetry
+func (p RetryPolicy) retrying(attempt int) {
+	if p.OnRetry != nil {
+		p.OnRetry(attempt)
+	}
+}
+
+// wait backs off for d before the given retry attempt, spending d from the budget. It fails
+// without waiting when the budget cannot cover d, and early when ctx is done.
+func (p RetryPolicy) wait(ctx context.Context, attempt int, d time.Duration) error {
+	if p.Budget != nil && !p.Budget.take(d) {
+		return ErrRetryBudgetExhausted
+	}
+	p.retrying(attempt)
+	return sleepContext(ctx, d)
+}
+
+// ErrRetryBudgetExhausted fails a batch whose next backoff its RetryBudget cannot cover
+var ErrRetryBudgetExhausted = errors.New("retry budget exhausted")
+
+// RetryBudget is the total backoff time the retries of many batches may spend together, e.g.
+// in one Lambda invocation, so a backend brownout fails batches once the budget is spent
+// instead of multiplying per-batch retries into a timeout. It is safe for concurrent use.
+type RetryBudget struct {
+	total     time.Duration
+	remaining atomic.Int64
+}
+
+// NewRetryBudget creates a full budget of total backoff time
+func NewRetryBudget(total time.Duration) *RetryBudget {
+	b := &RetryBudget{total: total}
+	b.Reset()
+	return b
+}
+
+// Reset refills the budget, e.g. at the start of an invocation
+func (b *RetryBudget) Reset() {
+	b.remaining.Store(int64(b.total))
+}
+
+// Remaining returns the backoff time left
+func (b *RetryBudget) Remaining() time.Duration {
+	return time.Duration(b.remaining.Load())
+}
+
+// take spends d if that much remains
+func (b *RetryBudget) take(d time.Duration) bool {
+	for {
+		remaining := b.remaining.Load()
+		if remaining < int64(d) {
+			return false
+		}
+		if b.remaining.CompareAndSwap(remaining, remaining-int64(d)) {
+			return true
+		}
+	}
+}
+
+// Backoff returns the wait before the given retry attempt (1-based): exponential backoff
+// from BaseDelay with equal jitter, so concurrent batches don't retry in lockstep
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	delay := p.BaseDelay * time.Duration(1<<uint(attempt-1))
+	if delay <= 0 {
+		return 0
+	}
+	half := delay / 2
+	return half + rand.N(half+1)
+}
+
+// ErrRetryAfterTooLong fails a batch whose server asked it to retry later than MaxRetryAfter or
+// than ctx's deadline allows
+var ErrRetryAfterTooLong = errors.New("Retry-After exceeds the time the batch may wait")
+
+// checkRetryAfter reports whether a server's Retry-After can be honored in full: within
+// MaxRetryAfter and before ctx's deadline. The budget is checked when waiting.
+func (p RetryPolicy) checkRetryAfter(ctx context.Context, d time.Duration) error {
+	if p.MaxRetryAfter > 0 && d > p.MaxRetryAfter {
+		return fmt.Errorf("%w: asked for %v, at most %v", ErrRetryAfterTooLong, d, p.MaxRetryAfter)
+	}
+	if deadline, ok := ctx.Deadline(); ok && d > time.Until(deadline) {
+		return fmt.Errorf("%w: asked for %v past the deadline", ErrRetryAfterTooLong, d)
+	}
+	return nil
+}
+
+// IsRetryableStatus reports whether an HTTP export failure may succeed on retry.
+// Client errors other than 408/429 (bad payload, auth, body too large) never will.
+func IsRetryableStatus(code int) bool {
+	switch code {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests:
+		return true
+	}
+	return code >= 500
+}
+
+// IsRetryableCode is the gRPC equivalent of IsRetryableStatus, following the OTLP spec
+func IsRetryableCode(code codes.Code) bool {
+	switch code {
+	case codes.Canceled, codes.DeadlineExceeded, codes.Aborted, codes.OutOfRange,
+		codes.Unavailable, codes.DataLoss, codes.ResourceExhausted:
+		return true
+	}
+	return false
+}
+
+// ParseRetryAfter parses a Retry-After header given either as delay-seconds or an HTTP-date
+func ParseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(value); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := t.Sub(now); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// sleepContext waits for d or until ctx is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,105 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestIsRetryableStatus(t *testing.T) {
+	tests := map[int]bool{
+		400: false, 401: false, 403: false, 404: false, 413: false,
+		408: true, 429: true, 500: true, 502: true, 503: true, 504: true,
+	}
+	for code, want := range tests {
+		if got := IsRetryableStatus(code); got != want {
+			t.Errorf("IsRetryableStatus(%d) = %v, want %v", code, got, want)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{"", 0, false},
+		{"7", 7 * time.Second, true},
+		{"-1", 0, false},
+		{"Thu, 01 Jan 2026 00:00:30 GMT", 30 * time.Second, true},
+		{"Wed, 31 Dec 2025 23:59:00 GMT", 0, true},
+		{"soon", 0, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := ParseRetryAfter(tt.value, now)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("ParseRetryAfter(%q) = %v, %v, want %v, %v", tt.value, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestRetryPolicy_CheckRetryAfter(t *testing.T) {
+	policy := RetryPolicy{MaxRetries: 3, BaseDelay: time.Second}
+
+	// Longer than any backoff of the policy, still honored without a deadline
+	ctx := context.Background()
+	if err := policy.checkRetryAfter(ctx, time.Hour); err != nil {
+		t.Errorf("checkRetryAfter(1h) error = %v", err)
+	}
+
+	policy.MaxRetryAfter = time.Minute
+	if err := policy.checkRetryAfter(ctx, time.Hour); !errors.Is(err, ErrRetryAfterTooLong) {
+		t.Errorf("checkRetryAfter(1h) error = %v, want ErrRetryAfterTooLong past MaxRetryAfter", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	if err := policy.checkRetryAfter(ctx, 30*time.Second); !errors.Is(err, ErrRetryAfterTooLong) {
+		t.Errorf("checkRetryAfter(30s) error = %v, want ErrRetryAfterTooLong past the deadline", err)
+	}
+	if err := policy.checkRetryAfter(ctx, 10*time.Millisecond); err != nil {
+		t.Errorf("checkRetryAfter(10ms) error = %v", err)
+	}
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	policy := RetryPolicy{MaxRetries: 4, BaseDelay: time.Second}
+	for attempt := 1; attempt <= 4; attempt++ {
+		full := time.Duration(1<<uint(attempt-1)) * time.Second
+		for i := 0; i < 20; i++ {
+			got := policy.Backoff(attempt)
+			if got < full/2 || got > full {
+				t.Fatalf("Backoff(%d) = %v, want within [%v, %v]", attempt, got, full/2, full)
+			}
+		}
+	}
+}
+
+func TestRetryPolicy_WaitBudget(t *testing.T) {
+	retries := 0
+	budget := NewRetryBudget(25 * time.Millisecond)
+	policy := RetryPolicy{Budget: budget, OnRetry: func(int) { retries++ }}
+
+	// Two batches share the budget: 10ms + 10ms fit, the third 10ms wait does not
+	for i, want := range []error{nil, nil, ErrRetryBudgetExhausted} {
+		if err := policy.wait(context.Background(), i+1, 10*time.Millisecond); !errors.Is(err, want) {
+			t.Fatalf("wait #%d error = %v, want %v", i+1, err, want)
+		}
+	}
+	if retries != 2 {
+		t.Errorf("OnRetry called %d times, want 2 (not for the refused retry)", retries)
+	}
+	if got := budget.Remaining(); got != 5*time.Millisecond {
+		t.Errorf("Remaining() = %v, want 5ms", got)
+	}
+
+	budget.Reset()
+	if err := policy.wait(context.Background(), 1, 20*time.Millisecond); err != nil {
+		t.Errorf("wait after Reset error = %v", err)
+	}
+}
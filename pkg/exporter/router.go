@@ -0,0 +1,121 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+)
+
+// Route sends the records it matches to its own exporter, e.g. the OTLP endpoint of one team
+type Route struct {
+	Name string
+	// Match maps attribute keys to the values a record needs, e.g. {"aws.lb.name": "app/a/1"}.
+	// Keys are looked up in the resource attributes, then in the record's own attributes.
+	Match    map[string]string
+	Exporter Exporter
+}
+
+// Router sends each record to the exporter of the first route it matches, and records no
+// route matches to the fallback exporter. Routes are exported concurrently; the batch fails
+// when any of them fails. The routes can be replaced while batches are exported (SetRoutes).
+type Router struct {
+	fallback Exporter
+
+	mu     sync.RWMutex
+	routes []Route
+}
+
+// NewRouter creates a router over the given routes; fallback may be nil to drop unmatched records
+func NewRouter(fallback Exporter, routes ...Route) *Router {
+	return &Router{routes: routes, fallback: fallback}
+}
+
+// SetRoutes replaces the routes; batches already being exported keep the routes they started with
+func (r *Router) SetRoutes(routes ...Route) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.routes = routes
+}
+
+// Export partitions the batch by route and sends every part to its exporter
+func (r *Router) Export(ctx context.Context, logs converter.ResourceLog) error {
+	r.mu.RLock()
+	routes := r.routes
+	r.mu.RUnlock()
+
+	// parts[i] holds the records of route i; parts[len(routes)] those of the fallback
+	parts := make([]converter.ResourceLog, len(routes)+1)
+	for _, scope := range logs.ScopeLogs {
+		byRoute := make([][]converter.OTelLogRecord, len(parts))
+		for _, record := range scope.LogRecords {
+			i := route(routes, logs.Resource.Attributes, record)
+			byRoute[i] = append(byRoute[i], record)
+		}
+		for i, records := range byRoute {
+			if len(records) > 0 {
+				parts[i].ScopeLogs = append(parts[i].ScopeLogs, converter.ScopeLog{Scope: scope.Scope, LogRecords: records})
+			}
+		}
+	}
+
+	errs := make([]error, len(parts))
+	var wg sync.WaitGroup
+	for i, part := range parts {
+		if len(part.ScopeLogs) == 0 {
+			continue
+		}
+		name, exp := "fallback", r.fallback
+		if i < len(routes) {
+			name, exp = routes[i].Name, routes[i].Exporter
+		}
+		if exp == nil {
+			continue
+		}
+		part.Resource = logs.Resource
+
+		wg.Add(1)
+		go func(i int, name string, exp Exporter, part converter.ResourceLog) {
+			defer wg.Done()
+			if err := exp.Export(ctx, part); err != nil {
+				errs[i] = fmt.Errorf("route %s: %w", name, err)
+			}
+		}(i, name, exp, part)
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// route returns the index of the first of routes the record matches, or len(routes)
+func route(routes []Route, resource []converter.OTelAttribute, record converter.OTelLogRecord) int {
+	for i, route := range routes {
+		matched := true
+		for key, want := range route.Match {
+			value, ok := attributeText(resource, key)
+			if !ok {
+				value, _ = attributeText(record.Attributes, key)
+			}
+			if value != want {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return i
+		}
+	}
+	return len(routes)
+}
+
+// attributeText returns the value of the attribute with the given key as text
+func attributeText(attrs []converter.OTelAttribute, key string) (string, bool) {
+	for _, attr := range attrs {
+		if attr.Key == key {
+			return attr.Value.Text(), true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,114 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+)
+
+func TestRouter_Export(t *testing.T) {
+	var mu sync.Mutex
+	got := map[string][]string{}
+	recorder := func(name string) Exporter {
+		return ExporterFunc(func(ctx context.Context, logs converter.ResourceLog) error {
+			mu.Lock()
+			defer mu.Unlock()
+			if len(logs.Resource.Attributes) == 0 {
+				t.Errorf("%s got a batch without the resource", name)
+			}
+			for _, scope := range logs.ScopeLogs {
+				for _, record := range scope.LogRecords {
+					got[name] = append(got[name], record.Body.Text())
+				}
+			}
+			return nil
+		})
+	}
+
+	router := NewRouter(recorder("default"),
+		Route{Name: "team-a", Match: map[string]string{"aws.alb.target_group_arn": "tg-a"}, Exporter: recorder("team-a")},
+		Route{Name: "account", Match: map[string]string{"cloud.account.id": "111122223333"}, Exporter: recorder("account")},
+	)
+
+	record := func(body, targetGroup string) converter.OTelLogRecord {
+		return converter.OTelLogRecord{
+			Body:       converter.OTelAnyValue{StringValue: &body},
+			Attributes: []converter.OTelAttribute{{Key: "aws.alb.target_group_arn", Value: converter.OTelAnyValue{StringValue: &targetGroup}}},
+		}
+	}
+	account, other := "111122223333", "999988887777"
+	batch := func(accountID string, records ...converter.OTelLogRecord) converter.ResourceLog {
+		return converter.ResourceLog{
+			Resource:  converter.ResourceAttributes{Attributes: []converter.OTelAttribute{{Key: "cloud.account.id", Value: converter.OTelAnyValue{StringValue: &accountID}}}},
+			ScopeLogs: []converter.ScopeLog{{LogRecords: records}},
+		}
+	}
+
+	if err := router.Export(context.Background(), batch(account, record("a1", "tg-a"), record("b1", "tg-b"))); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if err := router.Export(context.Background(), batch(other, record("a2", "tg-a"), record("c1", "tg-c"))); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+
+	want := map[string][]string{"team-a": {"a1", "a2"}, "account": {"b1"}, "default": {"c1"}}
+	for name, bodies := range want {
+		sort.Strings(got[name])
+		if strings.Join(got[name], ",") != strings.Join(bodies, ",") {
+			t.Errorf("%s got %v, want %v", name, got[name], bodies)
+		}
+	}
+}
+
+func TestRouter_ExportError(t *testing.T) {
+	failing := ExporterFunc(func(ctx context.Context, logs converter.ResourceLog) error { return errors.New("unavailable") })
+	ok := ExporterFunc(func(ctx context.Context, logs converter.ResourceLog) error { return nil })
+	router := NewRouter(ok, Route{Name: "team-a", Match: map[string]string{"service.name": "a"}, Exporter: failing})
+
+	name := "a"
+	logs := converter.ResourceLog{
+		Resource:  converter.ResourceAttributes{Attributes: []converter.OTelAttribute{{Key: "service.name", Value: converter.OTelAnyValue{StringValue: &name}}}},
+		ScopeLogs: []converter.ScopeLog{{LogRecords: []converter.OTelLogRecord{{}}}},
+	}
+	err := router.Export(context.Background(), logs)
+	if err == nil || !strings.Contains(err.Error(), "route team-a") {
+		t.Errorf("Export() error = %v, want the failing route named", err)
+	}
+}
+
+func TestRouter_SetRoutes(t *testing.T) {
+	var routed []string
+	recorder := func(name string) Exporter {
+		return ExporterFunc(func(ctx context.Context, logs converter.ResourceLog) error {
+			routed = append(routed, name)
+			return nil
+		})
+	}
+	router := NewRouter(recorder("default"), Route{Name: "team-a", Match: map[string]string{"service.name": "a"}, Exporter: recorder("team-a")})
+
+	name := "a"
+	logs := converter.ResourceLog{
+		Resource:  converter.ResourceAttributes{Attributes: []converter.OTelAttribute{{Key: "service.name", Value: converter.OTelAnyValue{StringValue: &name}}}},
+		ScopeLogs: []converter.ScopeLog{{LogRecords: []converter.OTelLogRecord{{}}}},
+	}
+	if err := router.Export(context.Background(), logs); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	router.SetRoutes(Route{Name: "team-b", Match: map[string]string{"service.name": "a"}, Exporter: recorder("team-b")})
+	if err := router.Export(context.Background(), logs); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	router.SetRoutes()
+	if err := router.Export(context.Background(), logs); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+
+	if want := "team-a,team-b,default"; strings.Join(routed, ",") != want {
+		t.Errorf("routed to %v, want %s", routed, want)
+	}
+}
@@ -0,0 +1,199 @@
+package exporter
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+)
+
+// DefaultS3ArchivePartition is the Hive-style partition layout used when none is configured
+const DefaultS3ArchivePartition = "year=%{yyyy}/month=%{MM}/day=%{dd}/lb=%{aws.lb.name}"
+
+// S3ArchiveConfig configures an S3ArchiveExporter
+type S3ArchiveConfig struct {
+	Bucket string
+	// Prefix is prepended to every object key, e.g. "parsed/alb"
+	Prefix string
+	// Partition is a name template for the partition path (see nameTemplate); attribute values
+	// are made path-safe, so "app/my-alb/123" becomes "app_my-alb_123"
+	Partition string
+	// Format is "ndjson" (default, gzipped) or "parquet" (snappy-compressed)
+	Format string
+	Retry  RetryPolicy
+	Logger *slog.Logger
+}
+
+// S3API is the part of the S3 client the archive exporter and S3DeadLetterQueue use;
+// *s3.Client implements it
+type S3API interface {
+	PutObject(ctx context.Context, in *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+	GetObject(ctx context.Context, in *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+	ListObjectsV2(ctx context.Context, in *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error)
+	DeleteObject(ctx context.Context, in *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error)
+}
+
+// S3ArchiveExporter writes converted records back to S3 for querying with Athena. Each batch
+// becomes one object per partition it touches.
+type S3ArchiveExporter struct {
+	cfg       S3ArchiveConfig
+	client    S3API
+	partition nameTemplate
+	logger    *slog.Logger
+}
+
+// parquetRecord is the Parquet row schema; attribute values are stored as strings so the
+// columns map to Athena's map<string,string>
+type parquetRecord struct {
+	Timestamp      time.Time         `parquet:"timestamp,timestamp(millisecond)"`
+	SeverityText   string            `parquet:"severity_text,optional"`
+	SeverityNumber int32             `parquet:"severity_number"`
+	Body           string            `parquet:"body"`
+	TraceID        string            `parquet:"trace_id,optional"`
+	SpanID         string            `parquet:"span_id,optional"`
+	Attributes     map[string]string `parquet:"attributes"`
+	Resource       map[string]string `parquet:"resource"`
+}
+
+// NewS3ArchiveExporter creates an S3 archive exporter using the given client
+func NewS3ArchiveExporter(client S3API, cfg S3ArchiveConfig) (*S3ArchiveExporter, error) {
+	if cfg.Bucket == "" {
+		return nil, fmt.Errorf("s3 archive bucket is required")
+	}
+	if cfg.Partition == "" {
+		cfg.Partition = DefaultS3ArchivePartition
+	}
+	if cfg.Format == "" {
+		cfg.Format = "ndjson"
+	}
+	if cfg.Format != "ndjson" && cfg.Format != "parquet" {
+		return nil, fmt.Errorf("unknown s3 archive format %q: want ndjson or parquet", cfg.Format)
+	}
+
+	partition := parseNameTemplate(cfg.Partition)
+	partition.escape = func(v string) string { return strings.ReplaceAll(v, "/", "_") }
+
+	return &S3ArchiveExporter{cfg: cfg, client: client, partition: partition, logger: loggerOrDefault(cfg.Logger)}, nil
+}
+
+// Export writes the batch, one object per partition
+func (e *S3ArchiveExporter) Export(ctx context.Context, logs converter.ResourceLog) error {
+	resource := attributeMap(logs.Resource.Attributes)
+
+	partitions := make(map[string][]flatRecord)
+	for _, record := range flattenRecords(logs) {
+		p := e.partition.render(recordTime(record.Timestamp), resource)
+		partitions[p] = append(partitions[p], record)
+	}
+
+	// Sorted for a deterministic upload order
+	names := make([]string, 0, len(partitions))
+	for p := range partitions {
+		names = append(names, p)
+	}
+	sort.Strings(names)
+
+	for _, p := range names {
+		body, ext, err := e.encode(partitions[p])
+		if err != nil {
+			return err
+		}
+		key, err := e.objectKey(p, ext)
+		if err != nil {
+			return err
+		}
+		if err := e.putWithRetry(ctx, key, body); err != nil {
+			return err
+		}
+	}
+
+	e.logger.Info("Batch sent successfully", "exporter", "s3", "objects", len(names))
+	return nil
+}
+
+// encode serializes the records in the configured format and returns the object extension
+func (e *S3ArchiveExporter) encode(records []flatRecord) ([]byte, string, error) {
+	if e.cfg.Format == "parquet" {
+		var buf bytes.Buffer
+		if err := writeParquet(&buf, records); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), ".parquet", nil
+	}
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	enc := json.NewEncoder(gz)
+	for _, r := range records {
+		if err := enc.Encode(r); err != nil {
+			return nil, "", fmt.Errorf("failed to marshal record: %w", err)
+		}
+	}
+	if err := gz.Close(); err != nil {
+		return nil, "", fmt.Errorf("failed to compress records: %w", err)
+	}
+	return buf.Bytes(), ".json.gz", nil
+}
+
+// objectKey builds a unique key under the partition: <prefix>/<partition>/<unix-ms>-<random><ext>
+func (e *S3ArchiveExporter) objectKey(partition, ext string) (string, error) {
+	var id [8]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return "", fmt.Errorf("failed to generate object id: %w", err)
+	}
+	name := fmt.Sprintf("%d-%s%s", time.Now().UnixMilli(), hex.EncodeToString(id[:]), ext)
+
+	parts := []string{partition, name}
+	if prefix := strings.Trim(e.cfg.Prefix, "/"); prefix != "" {
+		parts = append([]string{prefix}, parts...)
+	}
+	return strings.Join(parts, "/"), nil
+}
+
+// putWithRetry uploads one object, retrying failed puts with backoff
+func (e *S3ArchiveExporter) putWithRetry(ctx context.Context, key string, body []byte) error {
+	var lastErr error
+	for attempt := 0; attempt <= e.cfg.Retry.MaxRetries; attempt++ {
+		if attempt > 0 {
+			if err := e.cfg.Retry.wait(ctx, attempt, e.cfg.Retry.Backoff(attempt)); err != nil {
+				return fmt.Errorf("export aborted after %d attempts: %w", attempt, err)
+			}
+		}
+
+		_, err := e.client.PutObject(ctx, &s3.PutObjectInput{
+			Bucket: aws.String(e.cfg.Bucket),
+			Key:    aws.String(key),
+			Body:   bytes.NewReader(body),
+		})
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+		e.logger.Warn("S3 put attempt failed", "attempt", attempt+1, "key", key, "error", err)
+	}
+	return fmt.Errorf("failed after %d attempts: %w", e.cfg.Retry.MaxRetries+1, lastErr)
+}
+
+// stringMap converts JSON attribute values to strings
+func stringMap(m map[string]any) map[string]string {
+	if len(m) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(m))
+	for k, v := range m {
+		out[k] = textValue(v)
+	}
+	return out
+}
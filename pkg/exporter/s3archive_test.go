@@ -0,0 +1,112 @@
+package exporter
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/parquet-go/parquet-go"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+)
+
+type fakeS3 struct {
+	S3API
+	objects  map[string][]byte
+	failOnce bool
+}
+
+func (f *fakeS3) PutObject(ctx context.Context, input *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	if f.failOnce {
+		f.failOnce = false
+		return nil, errors.New("SlowDown")
+	}
+	body, _ := io.ReadAll(input.Body)
+	if f.objects == nil {
+		f.objects = map[string][]byte{}
+	}
+	f.objects[aws.ToString(input.Key)] = body
+	return &s3.PutObjectOutput{}, nil
+}
+
+func archiveTestLogs() converter.ResourceLog {
+	lbName := "app/my-alb/123"
+	logs := testResourceLog(
+		converter.OTelLogRecord{TimeUnixNano: "1700000000000000000", SeverityText: "INFO", Body: textBody("day one")},
+		converter.OTelLogRecord{TimeUnixNano: "1700100000000000000", SeverityText: "ERROR", Body: textBody("day two")},
+	)
+	logs.Resource.Attributes = []converter.OTelAttribute{{Key: "aws.lb.name", Value: converter.OTelAnyValue{StringValue: &lbName}}}
+	return logs
+}
+
+func TestS3ArchiveExporter_NDJSON(t *testing.T) {
+	fake := &fakeS3{failOnce: true}
+	exp, err := NewS3ArchiveExporter(fake, S3ArchiveConfig{Bucket: "archive", Prefix: "/parsed/", Retry: RetryPolicy{MaxRetries: 1}, Logger: discardLogger})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := exp.Export(context.Background(), archiveTestLogs()); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+
+	if len(fake.objects) != 2 {
+		t.Fatalf("got %d objects, want one per day partition", len(fake.objects))
+	}
+	for key, body := range fake.objects {
+		if !strings.HasPrefix(key, "parsed/year=2023/month=11/day=") || !strings.Contains(key, "/lb=app_my-alb_123/") || !strings.HasSuffix(key, ".json.gz") {
+			t.Errorf("unexpected key %q", key)
+		}
+		gz, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			t.Fatalf("object %q is not gzipped: %v", key, err)
+		}
+		var record flatRecord
+		if err := json.NewDecoder(gz).Decode(&record); err != nil {
+			t.Fatalf("object %q: invalid NDJSON: %v", key, err)
+		}
+		wantBody := "day one"
+		if strings.Contains(key, "day=16") {
+			wantBody = "day two"
+		}
+		if record.Body != wantBody {
+			t.Errorf("object %q body = %q, want %q", key, record.Body, wantBody)
+		}
+	}
+}
+
+func TestS3ArchiveExporter_Parquet(t *testing.T) {
+	fake := &fakeS3{}
+	exp, err := NewS3ArchiveExporter(fake, S3ArchiveConfig{Bucket: "archive", Partition: "lb=%{aws.lb.name}", Format: "parquet", Logger: discardLogger})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := exp.Export(context.Background(), archiveTestLogs()); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+
+	if len(fake.objects) != 1 {
+		t.Fatalf("got %d objects, want 1", len(fake.objects))
+	}
+	for key, body := range fake.objects {
+		if !strings.HasPrefix(key, "lb=app_my-alb_123/") || !strings.HasSuffix(key, ".parquet") {
+			t.Errorf("unexpected key %q", key)
+		}
+		rows, err := parquet.Read[parquetRecord](bytes.NewReader(body), int64(len(body)))
+		if err != nil {
+			t.Fatalf("Invalid parquet object: %v", err)
+		}
+		if len(rows) != 2 || rows[1].SeverityText != "ERROR" || rows[1].Resource["aws.lb.name"] != "app/my-alb/123" {
+			t.Errorf("rows = %+v", rows)
+		}
+		if rows[0].Timestamp.Unix() != 1700000000 {
+			t.Errorf("timestamp = %v, want 2023-11-14T22:13:20Z", rows[0].Timestamp)
+		}
+	}
+}
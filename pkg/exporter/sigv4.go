@@ -0,0 +1,50 @@
+package exporter
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/config"
+)
+
+// SigV4Config enables AWS Signature Version 4 signing of export requests
+type SigV4Config struct {
+	// Region of the signed endpoint, e.g. us-east-1
+	Region string
+	// Service signing name, e.g. "es" (OpenSearch), "aoss" (OpenSearch Serverless) or "execute-api"
+	Service string
+	// Credentials used for signing; the SDK's default chain (Lambda role) when nil
+	Credentials aws.CredentialsProvider
+}
+
+// defaultCredentials is the SDK's default credential chain, loaded on first use
+var defaultCredentials = sync.OnceValues(func() (aws.CredentialsProvider, error) {
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	return cfg.Credentials, err
+})
+
+// requestSigner returns the request hook that signs with the configured credentials
+func (c SigV4Config) requestSigner() func(req *http.Request, body []byte) error {
+	signer := v4.NewSigner()
+	return func(req *http.Request, body []byte) error {
+		provider := c.Credentials
+		if provider == nil {
+			var err error
+			if provider, err = defaultCredentials(); err != nil {
+				return err
+			}
+		}
+		creds, err := provider.Retrieve(req.Context())
+		if err != nil {
+			return err
+		}
+		hash := sha256.Sum256(body)
+		return signer.SignHTTP(req.Context(), creds, req, hex.EncodeToString(hash[:]), c.Service, c.Region, time.Now())
+	}
+}
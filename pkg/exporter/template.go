@@ -0,0 +1,83 @@
+package exporter
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// recordTime parses a nanosecond timestamp string, falling back to now
+func recordTime(ns string) time.Time {
+	if n, err := strconv.ParseInt(ns, 10, 64); err == nil && n > 0 {
+		return time.Unix(0, n).UTC()
+	}
+	return time.Now().UTC()
+}
+
+// nameTemplate renders index and topic names such as "alb-logs-%{yyyy.MM.dd}" or
+// "%{service.name}-%{yyyy.MM}": date tokens come from the record time, others are resource attributes
+type nameTemplate struct {
+	parts []templatePart
+	// escape, when set, is applied to attribute values (e.g. to keep them to one path segment)
+	escape func(string) string
+}
+
+type templatePart struct {
+	literal    string
+	dateLayout string
+	attribute  string
+}
+
+var templatePlaceholder = regexp.MustCompile(`%\{([^}]+)\}`)
+
+// javaDateTokens maps the date pattern letters used by Logstash/Beats index names to Go layouts
+var javaDateTokens = strings.NewReplacer("yyyy", "2006", "yy", "06", "MM", "01", "dd", "02", "HH", "15")
+
+func parseNameTemplate(tmpl string) nameTemplate {
+	var t nameTemplate
+	last := 0
+	for _, m := range templatePlaceholder.FindAllStringSubmatchIndex(tmpl, -1) {
+		if m[0] > last {
+			t.parts = append(t.parts, templatePart{literal: tmpl[last:m[0]]})
+		}
+		name := tmpl[m[2]:m[3]]
+		if isDatePattern(name) {
+			t.parts = append(t.parts, templatePart{dateLayout: javaDateTokens.Replace(name)})
+		} else {
+			t.parts = append(t.parts, templatePart{attribute: name})
+		}
+		last = m[1]
+	}
+	if last < len(tmpl) {
+		t.parts = append(t.parts, templatePart{literal: tmpl[last:]})
+	}
+	return t
+}
+
+func isDatePattern(s string) bool {
+	return strings.Trim(s, "yMdH.-_") == ""
+}
+
+func (t nameTemplate) render(ts time.Time, resource map[string]any) string {
+	var b strings.Builder
+	for _, p := range t.parts {
+		switch {
+		case p.dateLayout != "":
+			b.WriteString(ts.Format(p.dateLayout))
+		case p.attribute != "":
+			value := "unknown"
+			if v, ok := resource[p.attribute]; ok {
+				value = strings.ToLower(fmt.Sprint(v))
+			}
+			if t.escape != nil {
+				value = t.escape(value)
+			}
+			b.WriteString(value)
+		default:
+			b.WriteString(p.literal)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,28 @@
+package exporter
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNameTemplate_Render(t *testing.T) {
+	service := "alb-log-parser"
+	resource := map[string]any{"service.name": service}
+	ts := time.Date(2024, 3, 7, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		template string
+		want     string
+	}{
+		{"alb-logs-%{yyyy.MM.dd}", "alb-logs-2024.03.07"},
+		{"%{service.name}-%{yyyy.MM}", "alb-log-parser-2024.03"},
+		{"logs-%{yyyy-MM-dd-HH}", "logs-2024-03-07-09"},
+		{"%{missing}-static", "unknown-static"},
+		{"static", "static"},
+	}
+	for _, tt := range tests {
+		if got := parseNameTemplate(tt.template).render(ts, resource); got != tt.want {
+			t.Errorf("render(%q) = %q, want %q", tt.template, got, tt.want)
+		}
+	}
+}
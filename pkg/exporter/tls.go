@@ -0,0 +1,46 @@
+package exporter
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+)
+
+// TLSMaterial holds the PEM blocks for a custom CA bundle and an optional client certificate
+type TLSMaterial struct {
+	// CAPEM is trusted in addition to the system roots
+	CAPEM []byte
+	// CertPEM and KeyPEM are presented to the server for mutual TLS
+	CertPEM []byte
+	KeyPEM  []byte
+}
+
+// NewTLSConfig builds a client TLS configuration from the material, or nil when it is empty
+func NewTLSConfig(m TLSMaterial) (*tls.Config, error) {
+	if len(m.CAPEM) == 0 && len(m.CertPEM) == 0 && len(m.KeyPEM) == 0 {
+		return nil, nil
+	}
+
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if len(m.CAPEM) > 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(m.CAPEM) {
+			return nil, fmt.Errorf("no certificates found in CA bundle")
+		}
+		cfg.RootCAs = pool
+	}
+
+	if len(m.CertPEM) > 0 || len(m.KeyPEM) > 0 {
+		cert, err := tls.X509KeyPair(m.CertPEM, m.KeyPEM)
+		if err != nil {
+			return nil, fmt.Errorf("invalid client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,90 @@
+package exporter
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// selfSignedPEM generates a self-signed client certificate and key
+func selfSignedPEM(t *testing.T) (certPEM, keyPEM []byte, cert *x509.Certificate) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "log-parser"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ = x509.ParseCertificate(der)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), cert
+}
+
+func TestOTLPHTTPExporter_MutualTLS(t *testing.T) {
+	certPEM, keyPEM, clientCert := selfSignedPEM(t)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.PeerCertificates) == 0 || r.TLS.PeerCertificates[0].Subject.CommonName != "log-parser" {
+			t.Error("client certificate not presented")
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCert)
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	server.StartTLS()
+	defer server.Close()
+
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	tlsConfig, err := NewTLSConfig(TLSMaterial{CAPEM: caPEM, CertPEM: certPEM, KeyPEM: keyPEM})
+	if err != nil {
+		t.Fatalf("NewTLSConfig() error = %v", err)
+	}
+
+	exp := NewOTLPHTTPExporter(OTLPHTTPConfig{Endpoint: server.URL, TLS: tlsConfig, Logger: discardLogger})
+	if err := exp.Export(context.Background(), testResourceLog(helloRecord())); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+
+	// Without the custom CA the server certificate is not trusted
+	untrusted := NewOTLPHTTPExporter(OTLPHTTPConfig{Endpoint: server.URL, Logger: discardLogger})
+	if err := untrusted.Export(context.Background(), testResourceLog(helloRecord())); err == nil {
+		t.Error("Export() without the CA bundle succeeded, want a certificate error")
+	}
+}
+
+func TestNewTLSConfig_Invalid(t *testing.T) {
+	if cfg, err := NewTLSConfig(TLSMaterial{}); cfg != nil || err != nil {
+		t.Errorf("NewTLSConfig(empty) = %v, %v, want nil, nil", cfg, err)
+	}
+	if _, err := NewTLSConfig(TLSMaterial{CAPEM: []byte("not a pem")}); err == nil {
+		t.Error("expected error for a CA bundle without certificates")
+	}
+	certPEM, _, _ := selfSignedPEM(t)
+	if _, err := NewTLSConfig(TLSMaterial{CertPEM: certPEM}); err == nil {
+		t.Error("expected error for a client certificate without key")
+	}
+}
@@ -0,0 +1,413 @@
+// Package filter evaluates small boolean expressions against log records, e.g.
+//
+//	elb_status_code == 200 && request_url contains "/healthz"
+//
+// Operands are field names, "double" or 'single' quoted strings, numbers, true and false.
+// Comparisons are ==, !=, <, <=, >, >=, contains, startsWith, endsWith, matches (a regular
+// expression) and in (a [list, of, literals]); they compare numerically when both sides are
+// numbers and as strings otherwise. Comparisons combine with && (and), || (or), ! (not) and
+// parentheses. A field on its own is true when it is set to something other than "" or "-".
+package filter
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Env resolves the field names of an expression for one record
+type Env interface {
+	Lookup(name string) (string, bool)
+}
+
+// MapEnv is an Env over a map, mostly useful in tests
+type MapEnv map[string]string
+
+func (m MapEnv) Lookup(name string) (string, bool) {
+	v, ok := m[name]
+	return v, ok
+}
+
+// Expr is a compiled expression; it is safe for concurrent use
+type Expr struct {
+	src  string
+	root node
+}
+
+// Compile parses an expression
+func Compile(src string) (*Expr, error) {
+	tokens, err := tokenize(src)
+	if err != nil {
+		return nil, fmt.Errorf("%q: %w", src, err)
+	}
+	p := &parser{tokens: tokens}
+	root, err := p.or()
+	if err == nil && p.pos < len(p.tokens) {
+		err = fmt.Errorf("unexpected %q", p.tokens[p.pos].text)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%q: %w", src, err)
+	}
+	return &Expr{src: src, root: root}, nil
+}
+
+// Match evaluates the expression for one record
+func (e *Expr) Match(env Env) bool {
+	return e.root.eval(env)
+}
+
+func (e *Expr) String() string {
+	return e.src
+}
+
+type tokenKind int
+
+const (
+	tokIdent tokenKind = iota
+	tokString
+	tokNumber
+	tokOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+// symbols are the punctuation operators, longest first
+var symbols = []string{"==", "!=", "<=", ">=", "&&", "||", "<", ">", "!", "(", ")", "[", "]", ","}
+
+func tokenize(src string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(src); {
+		c := rune(src[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '"' || c == '\'':
+			end := i + 1
+			for end < len(src) && src[end] != byte(c) {
+				if src[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(src) {
+				return nil, fmt.Errorf("unterminated string at %d", i)
+			}
+			text := src[i+1 : end]
+			if c == '"' {
+				unquoted, err := strconv.Unquote(src[i : end+1])
+				if err != nil {
+					return nil, fmt.Errorf("invalid string at %d: %w", i, err)
+				}
+				text = unquoted
+			}
+			tokens = append(tokens, token{tokString, text})
+			i = end + 1
+		case c >= '0' && c <= '9' || c == '-' && i+1 < len(src) && src[i+1] >= '0' && src[i+1] <= '9':
+			end := i + 1
+			for end < len(src) && (src[end] >= '0' && src[end] <= '9' || src[end] == '.') {
+				end++
+			}
+			tokens = append(tokens, token{tokNumber, src[i:end]})
+			i = end
+		case c == '_' || unicode.IsLetter(c):
+			end := i + 1
+			for end < len(src) && isIdentChar(rune(src[end])) {
+				end++
+			}
+			tokens = append(tokens, token{tokIdent, src[i:end]})
+			i = end
+		default:
+			matched := false
+			for _, sym := range symbols {
+				if strings.HasPrefix(src[i:], sym) {
+					tokens = append(tokens, token{tokOp, sym})
+					i += len(sym)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("unexpected character %q at %d", c, i)
+			}
+		}
+	}
+	return tokens, nil
+}
+
+// isIdentChar allows the dots of attribute keys and the dashes of CloudFront field names
+func isIdentChar(c rune) bool {
+	return c == '_' || c == '.' || c == '-' || unicode.IsLetter(c) || unicode.IsDigit(c)
+}
+
+// Word operators, matched case-insensitively
+var (
+	wordOps    = map[string]string{"and": "&&", "or": "||", "not": "!"}
+	comparison = map[string]bool{"==": true, "!=": true, "<": true, "<=": true, ">": true, ">=": true, "contains": true, "startswith": true, "endswith": true, "matches": true, "in": true}
+)
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// peekOp returns the operator at the current position, mapping word operators to symbols
+func (p *parser) peekOp() string {
+	if p.pos >= len(p.tokens) {
+		return ""
+	}
+	t := p.tokens[p.pos]
+	switch t.kind {
+	case tokOp:
+		return t.text
+	case tokIdent:
+		word := strings.ToLower(t.text)
+		if op, ok := wordOps[word]; ok {
+			return op
+		}
+		if comparison[word] {
+			return word
+		}
+	}
+	return ""
+}
+
+func (p *parser) or() (node, error) {
+	left, err := p.and()
+	for err == nil && p.peekOp() == "||" {
+		p.pos++
+		var right node
+		if right, err = p.and(); err == nil {
+			left = orNode{left, right}
+		}
+	}
+	return left, err
+}
+
+func (p *parser) and() (node, error) {
+	left, err := p.unary()
+	for err == nil && p.peekOp() == "&&" {
+		p.pos++
+		var right node
+		if right, err = p.unary(); err == nil {
+			left = andNode{left, right}
+		}
+	}
+	return left, err
+}
+
+func (p *parser) unary() (node, error) {
+	switch p.peekOp() {
+	case "!":
+		p.pos++
+		inner, err := p.unary()
+		return notNode{inner}, err
+	case "(":
+		p.pos++
+		inner, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.peekOp() != ")" {
+			return nil, fmt.Errorf("missing )")
+		}
+		p.pos++
+		return inner, nil
+	}
+	return p.comparison()
+}
+
+func (p *parser) comparison() (node, error) {
+	left, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	op := p.peekOp()
+	if !comparison[op] {
+		return truthyNode{left}, nil
+	}
+	p.pos++
+
+	switch op {
+	case "in":
+		list, err := p.list()
+		return inNode{left, list}, err
+	case "matches":
+		right, err := p.operand()
+		if err != nil {
+			return nil, err
+		}
+		lit, ok := right.(literal)
+		if !ok {
+			return nil, fmt.Errorf("matches needs a literal pattern")
+		}
+		re, err := regexp.Compile(string(lit))
+		if err != nil {
+			return nil, err
+		}
+		return matchNode{left, re}, nil
+	}
+	right, err := p.operand()
+	return compareNode{op, left, right}, err
+}
+
+func (p *parser) operand() (operand, error) {
+	if p.pos >= len(p.tokens) {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	t := p.tokens[p.pos]
+	p.pos++
+	switch t.kind {
+	case tokString, tokNumber:
+		return literal(t.text), nil
+	case tokIdent:
+		switch strings.ToLower(t.text) {
+		case "true", "false":
+			return literal(strings.ToLower(t.text)), nil
+		}
+		if word := strings.ToLower(t.text); wordOps[word] != "" || comparison[word] {
+			return nil, fmt.Errorf("unexpected %q", t.text)
+		}
+		return field(t.text), nil
+	}
+	return nil, fmt.Errorf("unexpected %q", t.text)
+}
+
+func (p *parser) list() ([]string, error) {
+	if p.peekOp() != "[" {
+		return nil, fmt.Errorf("in needs a [list]")
+	}
+	p.pos++
+	var items []string
+	for p.peekOp() != "]" {
+		if len(items) > 0 {
+			if p.peekOp() != "," {
+				return nil, fmt.Errorf("missing , in list")
+			}
+			p.pos++
+		}
+		item, err := p.operand()
+		if err != nil {
+			return nil, err
+		}
+		lit, ok := item.(literal)
+		if !ok {
+			return nil, fmt.Errorf("lists hold literals only")
+		}
+		items = append(items, string(lit))
+	}
+	p.pos++
+	return items, nil
+}
+
+type operand interface {
+	value(env Env) string
+}
+
+type literal string
+
+func (l literal) value(Env) string { return string(l) }
+
+type field string
+
+func (f field) value(env Env) string {
+	v, _ := env.Lookup(string(f))
+	return v
+}
+
+type node interface {
+	eval(env Env) bool
+}
+
+type orNode struct{ left, right node }
+
+func (n orNode) eval(env Env) bool { return n.left.eval(env) || n.right.eval(env) }
+
+type andNode struct{ left, right node }
+
+func (n andNode) eval(env Env) bool { return n.left.eval(env) && n.right.eval(env) }
+
+type notNode struct{ inner node }
+
+func (n notNode) eval(env Env) bool { return !n.inner.eval(env) }
+
+type truthyNode struct{ operand operand }
+
+func (n truthyNode) eval(env Env) bool {
+	switch n.operand.value(env) {
+	case "", "-", "false":
+		return false
+	}
+	return true
+}
+
+type compareNode struct {
+	op          string
+	left, right operand
+}
+
+func (n compareNode) eval(env Env) bool {
+	l, r := n.left.value(env), n.right.value(env)
+	switch n.op {
+	case "contains":
+		return strings.Contains(l, r)
+	case "startswith":
+		return strings.HasPrefix(l, r)
+	case "endswith":
+		return strings.HasSuffix(l, r)
+	}
+
+	cmp := strings.Compare(l, r)
+	if lf, err := strconv.ParseFloat(l, 64); err == nil {
+		if rf, err := strconv.ParseFloat(r, 64); err == nil {
+			switch {
+			case lf < rf:
+				cmp = -1
+			case lf > rf:
+				cmp = 1
+			default:
+				cmp = 0
+			}
+		}
+	}
+	switch n.op {
+	case "==":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	default:
+		return cmp >= 0
+	}
+}
+
+type matchNode struct {
+	left operand
+	re   *regexp.Regexp
+}
+
+func (n matchNode) eval(env Env) bool { return n.re.MatchString(n.left.value(env)) }
+
+type inNode struct {
+	left  operand
+	items []string
+}
+
+func (n inNode) eval(env Env) bool {
+	v := n.left.value(env)
+	for _, item := range n.items {
+		if (compareNode{"==", literal(v), literal(item)}).eval(env) {
+			return true
+		}
+	}
+	return false
+}
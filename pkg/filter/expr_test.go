@@ -0,0 +1,66 @@
+package filter
+
+import "testing"
+
+func TestExpr_Match(t *testing.T) {
+	env := MapEnv{
+		"elb_status_code":           "200",
+		"request_url":               "https://example.com:443/healthz?probe=1",
+		"http.response.status_code": "503",
+		"aws.waf.action":            "BLOCK",
+		"user_agent":                "ELB-HealthChecker/2.0",
+		"empty":                     "-",
+	}
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{`elb_status_code == 200 && request_url contains "/healthz"`, true},
+		{`elb_status_code == 200 and request_url contains '/login'`, false},
+		{`elb_status_code == "200.0"`, true},
+		{`http.response.status_code >= 500`, true},
+		{`http.response.status_code < 500 || aws.waf.action == "BLOCK"`, true},
+		{`!(aws.waf.action == "ALLOW")`, true},
+		{`not aws.waf.action in ["ALLOW", "COUNT"]`, true},
+		{`http.response.status_code in [500, 502, 503]`, true},
+		{`user_agent startsWith "ELB-HealthChecker"`, true},
+		{`user_agent endsWith "1.0"`, false},
+		{`user_agent matches "^ELB-Health.*/2\\."`, true},
+		{`request_url`, true},
+		{`empty`, false},
+		{`missing`, false},
+		{`missing == ""`, true},
+		{`elb_status_code != 200 || (missing && true)`, false},
+		{`true`, true},
+	}
+	for _, tt := range tests {
+		e, err := Compile(tt.expr)
+		if err != nil {
+			t.Errorf("Compile(%s) error = %v", tt.expr, err)
+			continue
+		}
+		if got := e.Match(env); got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestCompile_Errors(t *testing.T) {
+	for _, expr := range []string{
+		``,
+		`a ==`,
+		`(a == 1`,
+		`a == "unterminated`,
+		`a in b`,
+		`a in [b]`,
+		`a matches b`,
+		`a matches "("`,
+		`a == 1 b`,
+		`a # 1`,
+		`and == 1`,
+	} {
+		if _, err := Compile(expr); err == nil {
+			t.Errorf("Compile(%s) succeeded", expr)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package filter
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Rule actions
+const (
+	ActionDrop  = "drop"
+	ActionKeep  = "keep"
+	ActionRoute = "route"
+)
+
+// Rule is one filter rule: exactly one of Drop, Keep or Route holds its expression, and a
+// Route rule names the exporters its records go to in To
+type Rule struct {
+	Drop  string   `yaml:"drop"`
+	Keep  string   `yaml:"keep"`
+	Route string   `yaml:"route"`
+	To    []string `yaml:"to"`
+
+	action string
+	expr   *Expr
+}
+
+// Action is the rule's action, one of ActionDrop, ActionKeep and ActionRoute
+func (r *Rule) Action() string {
+	return r.action
+}
+
+func (r *Rule) compile() error {
+	set := 0
+	for action, src := range map[string]string{ActionDrop: r.Drop, ActionKeep: r.Keep, ActionRoute: r.Route} {
+		if src != "" {
+			r.action = action
+			set++
+		}
+	}
+	if set != 1 {
+		return fmt.Errorf("a rule needs exactly one of drop, keep or route")
+	}
+	if (r.action == ActionRoute) != (len(r.To) > 0) {
+		return fmt.Errorf("to goes with route rules only, and route rules need it")
+	}
+	var err error
+	r.expr, err = Compile(r.Drop + r.Keep + r.Route)
+	return err
+}
+
+// Rules are evaluated in order; the first one that matches decides
+type Rules []*Rule
+
+// Evaluate returns whether a record is kept and, when a route rule matched, the exporters
+// it goes to. Records no rule matches are kept and go to every exporter.
+func (rs Rules) Evaluate(env Env) (keep bool, routes []string) {
+	for _, r := range rs {
+		if !r.expr.Match(env) {
+			continue
+		}
+		switch r.action {
+		case ActionDrop:
+			return false, nil
+		case ActionRoute:
+			return true, r.To
+		}
+		return true, nil
+	}
+	return true, nil
+}
+
+// Config holds the rules of each processor, keyed by processor name ("alb", "cloudfront",
+// "kinesis", ...); the "*" rules apply to all of them
+type Config map[string]Rules
+
+// ParseConfig reads rules from YAML or JSON and compiles their expressions, e.g.
+//
+//	alb:
+//	  - drop: elb_status_code == 200 && request_url contains "/healthz"
+//	"*":
+//	  - route: http.response.status_code >= 500
+//	    to: [otlp, s3]
+func ParseConfig(data []byte) (Config, error) {
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, err
+	}
+	for name, rules := range cfg {
+		for i, r := range rules {
+			if r == nil {
+				return nil, fmt.Errorf("%s rule %d: empty rule", name, i+1)
+			}
+			if err := r.compile(); err != nil {
+				return nil, fmt.Errorf("%s rule %d: %w", name, i+1, err)
+			}
+		}
+	}
+	return cfg, nil
+}
+
+// For returns the processor's rules followed by the "*" rules, or nil when there are none
+func (c Config) For(processor string) Rules {
+	own, shared := c[processor], c["*"]
+	if len(shared) == 0 {
+		return own
+	}
+	if len(own) == 0 {
+		return shared
+	}
+	return append(append(Rules(nil), own...), shared...)
+}
+
+// Routes returns every exporter named by a route rule
+func (c Config) Routes() []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, rules := range c {
+		for _, r := range rules {
+			for _, name := range r.To {
+				if !seen[name] {
+					seen[name] = true
+					names = append(names, name)
+				}
+			}
+		}
+	}
+	return names
+}
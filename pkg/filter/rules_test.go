@@ -0,0 +1,67 @@
+package filter
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseConfig(t *testing.T) {
+	cfg, err := ParseConfig([]byte(`
+alb:
+  - keep: request_url contains "/healthz/deep"
+  - drop: request_url contains "/healthz"
+"*":
+  - route: http.response.status_code >= 500
+    to: [otlp, s3]
+  - route: aws.waf.action == "BLOCK"
+    to: [s3]
+`))
+	if err != nil {
+		t.Fatalf("ParseConfig() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		processor  string
+		env        MapEnv
+		wantKeep   bool
+		wantRoutes []string
+	}{
+		{"health check dropped", "alb", MapEnv{"request_url": "/healthz"}, false, nil},
+		{"keep wins over a later drop", "alb", MapEnv{"request_url": "/healthz/deep"}, true, nil},
+		{"shared route", "alb", MapEnv{"request_url": "/", "http.response.status_code": "502"}, true, []string{"otlp", "s3"}},
+		{"no match", "alb", MapEnv{"request_url": "/"}, true, nil},
+		{"other processor skips alb rules", "cloudfront", MapEnv{"request_url": "/healthz", "aws.waf.action": "BLOCK"}, true, []string{"s3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keep, routes := cfg.For(tt.processor).Evaluate(tt.env)
+			if keep != tt.wantKeep || !reflect.DeepEqual(routes, tt.wantRoutes) {
+				t.Errorf("Evaluate() = %v, %v; want %v, %v", keep, routes, tt.wantKeep, tt.wantRoutes)
+			}
+		})
+	}
+
+	if got := cfg.Routes(); len(got) != 2 {
+		t.Errorf("Routes() = %v, want otlp and s3", got)
+	}
+	if cfg.For("alb")[0].Action() != ActionKeep {
+		t.Errorf("first alb rule action = %q", cfg.For("alb")[0].Action())
+	}
+}
+
+func TestParseConfig_Invalid(t *testing.T) {
+	for _, data := range []string{
+		`alb: [{drop: "a == 1", keep: "b == 2"}]`,
+		`alb: [{}]`,
+		`alb: [{route: "a == 1"}]`,
+		`alb: [{drop: "a == 1", to: [s3]}]`,
+		`alb: [{drop: "a =="}]`,
+		`alb: [null]`,
+		`[1, 2]`,
+	} {
+		if _, err := ParseConfig([]byte(data)); err == nil {
+			t.Errorf("ParseConfig(%s) succeeded", data)
+		}
+	}
+}
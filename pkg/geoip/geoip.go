@@ -0,0 +1,129 @@
+// Package geoip looks up the location and network owner of IP addresses in MaxMind DB files,
+// such as GeoLite2-City, GeoLite2-Country and GeoLite2-ASN.
+package geoip
+
+import (
+	"net/netip"
+	"sync"
+)
+
+// Record is what the databases know about an address; fields they do not have are empty
+type Record struct {
+	CountryISOCode string
+	CityName       string
+	// ASN and ASOrganization come from ASN databases
+	ASN            uint64
+	ASOrganization string
+}
+
+// Empty reports whether no database had data for the address
+func (r Record) Empty() bool {
+	return r == Record{}
+}
+
+// cacheKey identifies a record: the database and the data section offset of its network
+type cacheKey struct {
+	db     int
+	offset uint
+}
+
+// DB merges lookups across several MaxMind DB files, typically a City or Country database and
+// an ASN database. It memoizes decoded records, so neighbouring addresses of the same network
+// are decoded once; at most CacheSize records are kept. It is safe for concurrent use.
+type DB struct {
+	Readers []*Reader
+	// CacheSize bounds the record cache; zero means 10000
+	CacheSize int
+
+	mu    sync.Mutex
+	cache map[cacheKey]Record
+}
+
+// New returns a DB over the given database files
+func New(files ...[]byte) (*DB, error) {
+	db := &DB{}
+	for _, buf := range files {
+		r, err := NewReader(buf)
+		if err != nil {
+			return nil, err
+		}
+		db.Readers = append(db.Readers, r)
+	}
+	return db, nil
+}
+
+// Lookup returns what the databases know about ip; an unparseable ip returns an empty record
+func (db *DB) Lookup(ip string) Record {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return Record{}
+	}
+	var rec Record
+	for i, r := range db.Readers {
+		off, ok := r.offset(addr)
+		if !ok {
+			continue
+		}
+		part := db.record(cacheKey{i, off}, r)
+		if rec.CountryISOCode == "" {
+			rec.CountryISOCode = part.CountryISOCode
+		}
+		if rec.CityName == "" {
+			rec.CityName = part.CityName
+		}
+		if rec.ASN == 0 {
+			rec.ASN, rec.ASOrganization = part.ASN, part.ASOrganization
+		}
+	}
+	return rec
+}
+
+func (db *DB) record(key cacheKey, r *Reader) Record {
+	db.mu.Lock()
+	rec, ok := db.cache[key]
+	db.mu.Unlock()
+	if ok {
+		return rec
+	}
+
+	// A record that fails to decode stays empty rather than failing the log record
+	if v, _, err := (decoder{buf: r.data}).decode(key.offset); err == nil {
+		rec = toRecord(v)
+	}
+	size := db.CacheSize
+	if size <= 0 {
+		size = 10000
+	}
+	db.mu.Lock()
+	if db.cache == nil || len(db.cache) >= size {
+		db.cache = make(map[cacheKey]Record)
+	}
+	db.cache[key] = rec
+	db.mu.Unlock()
+	return rec
+}
+
+// toRecord picks the fields of a GeoIP2/GeoLite2 City, Country or ASN record
+func toRecord(v any) Record {
+	var rec Record
+	rec.CountryISOCode, _ = path(v, "country", "iso_code").(string)
+	if rec.CountryISOCode == "" {
+		// Anonymous proxies and satellite providers only have a registered country
+		rec.CountryISOCode, _ = path(v, "registered_country", "iso_code").(string)
+	}
+	rec.CityName, _ = path(v, "city", "names", "en").(string)
+	rec.ASN, _ = path(v, "autonomous_system_number").(uint64)
+	rec.ASOrganization, _ = path(v, "autonomous_system_organization").(string)
+	return rec
+}
+
+func path(v any, keys ...string) any {
+	for _, key := range keys {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		v = m[key]
+	}
+	return v
+}
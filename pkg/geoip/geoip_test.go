@@ -0,0 +1,232 @@
+package geoip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net/netip"
+	"sort"
+	"testing"
+)
+
+// encode writes a value in the MaxMind DB data section format
+func encode(v any) []byte {
+	var out []byte
+	ctrl := func(typ int, size int) {
+		var c byte
+		var ext []byte
+		if typ > 7 {
+			ext = []byte{byte(typ - 7)}
+		} else {
+			c = byte(typ << 5)
+		}
+		switch {
+		case size < 29:
+			c |= byte(size)
+		case size < 285:
+			c |= 29
+			ext = append(ext, byte(size-29))
+		default:
+			c |= 30
+			ext = append(ext, byte((size-285)>>8), byte(size-285))
+		}
+		out = append(append(out, c), ext...)
+	}
+	switch v := v.(type) {
+	case string:
+		ctrl(typeString, len(v))
+		out = append(out, v...)
+	case uint64:
+		var b [8]byte
+		binary.BigEndian.PutUint64(b[:], v)
+		trimmed := bytes.TrimLeft(b[:], "\x00")
+		ctrl(typeUint64, len(trimmed))
+		out = append(out, trimmed...)
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		ctrl(typeMap, len(keys))
+		for _, k := range keys {
+			out = append(out, encode(k)...)
+			out = append(out, encode(v[k])...)
+		}
+	}
+	return out
+}
+
+type trieNode struct {
+	child [2]*trieNode
+	data  any
+}
+
+// buildDB writes an IPv6 MaxMind DB mapping each network to its data
+func buildDB(t *testing.T, recordSize int, dbType string, networks map[string]any) []byte {
+	t.Helper()
+	root := &trieNode{}
+	for cidr, data := range networks {
+		prefix := netip.MustParsePrefix(cidr)
+		ip, bits := prefix.Addr().As16(), prefix.Bits()
+		if prefix.Addr().Is4() {
+			// IPv4 networks live under ::/96
+			ip = [16]byte{}
+			copy(ip[12:], prefix.Addr().AsSlice())
+			bits += 96
+		}
+		n := root
+		for i := 0; i < bits; i++ {
+			bit := ip[i/8] >> (7 - i%8) & 1
+			if n.child[bit] == nil {
+				n.child[bit] = &trieNode{}
+			}
+			n = n.child[bit]
+		}
+		n.data = data
+	}
+
+	// Number the inner nodes, then lay out the data of the leaves
+	var nodes []*trieNode
+	index := map[*trieNode]int{}
+	var walk func(n *trieNode)
+	walk = func(n *trieNode) {
+		if n == nil || n.data != nil {
+			return
+		}
+		index[n] = len(nodes)
+		nodes = append(nodes, n)
+		walk(n.child[0])
+		walk(n.child[1])
+	}
+	walk(root)
+
+	var data []byte
+	offsets := map[*trieNode]int{}
+	for _, n := range nodes {
+		for _, c := range n.child {
+			if c != nil && c.data != nil {
+				offsets[c] = len(data)
+				data = append(data, encode(c.data)...)
+			}
+		}
+	}
+
+	value := func(c *trieNode) uint32 {
+		switch {
+		case c == nil:
+			return uint32(len(nodes))
+		case c.data != nil:
+			return uint32(len(nodes) + 16 + offsets[c])
+		}
+		return uint32(index[c])
+	}
+	var tree []byte
+	for _, n := range nodes {
+		l, r := value(n.child[0]), value(n.child[1])
+		switch recordSize {
+		case 24:
+			tree = append(tree, byte(l>>16), byte(l>>8), byte(l), byte(r>>16), byte(r>>8), byte(r))
+		case 28:
+			tree = append(tree, byte(l>>16), byte(l>>8), byte(l), byte(l>>20)&0xF0|byte(r>>24)&0x0F, byte(r>>16), byte(r>>8), byte(r))
+		case 32:
+			tree = binary.BigEndian.AppendUint32(tree, l)
+			tree = binary.BigEndian.AppendUint32(tree, r)
+		}
+	}
+
+	var buf []byte
+	buf = append(buf, tree...)
+	buf = append(buf, make([]byte, 16)...)
+	buf = append(buf, data...)
+	buf = append(buf, metadataMarker...)
+	buf = append(buf, encode(map[string]any{
+		"node_count":    uint64(len(nodes)),
+		"record_size":   uint64(recordSize),
+		"ip_version":    uint64(6),
+		"database_type": dbType,
+	})...)
+	return buf
+}
+
+func cityData(country, city string) map[string]any {
+	m := map[string]any{"country": map[string]any{"iso_code": country}}
+	if city != "" {
+		m["city"] = map[string]any{"names": map[string]any{"en": city, "de": city + "-de"}}
+	}
+	return m
+}
+
+func TestDB_Lookup(t *testing.T) {
+	for _, size := range []int{24, 28, 32} {
+		city := buildDB(t, size, "GeoLite2-City", map[string]any{
+			"203.0.113.0/24":  cityData("FR", "Paris"),
+			"198.51.100.0/25": cityData("DE", ""),
+			"2001:db8::/32":   cityData("JP", "Tokyo"),
+			"192.0.2.0/24": map[string]any{
+				"registered_country": map[string]any{"iso_code": "NL"},
+			},
+		})
+		asn := buildDB(t, size, "GeoLite2-ASN", map[string]any{
+			"203.0.113.0/25": map[string]any{
+				"autonomous_system_number":       uint64(64500),
+				"autonomous_system_organization": "Example Net",
+			},
+		})
+		db, err := New(city, asn)
+		if err != nil {
+			t.Fatalf("record size %d: New() error = %v", size, err)
+		}
+		if got := db.Readers[0].DatabaseType; got != "GeoLite2-City" {
+			t.Errorf("DatabaseType = %q", got)
+		}
+
+		tests := []struct {
+			ip   string
+			want Record
+		}{
+			{"203.0.113.10", Record{CountryISOCode: "FR", CityName: "Paris", ASN: 64500, ASOrganization: "Example Net"}},
+			{"203.0.113.200", Record{CountryISOCode: "FR", CityName: "Paris"}},
+			{"::ffff:203.0.113.10", Record{CountryISOCode: "FR", CityName: "Paris", ASN: 64500, ASOrganization: "Example Net"}},
+			{"198.51.100.1", Record{CountryISOCode: "DE"}},
+			{"198.51.100.200", Record{}},
+			{"192.0.2.1", Record{CountryISOCode: "NL"}},
+			{"2001:db8::1", Record{CountryISOCode: "JP", CityName: "Tokyo"}},
+			{"2001:db9::1", Record{}},
+			{"-", Record{}},
+		}
+		for _, tt := range tests {
+			// Twice, the second from the cache
+			for i := 0; i < 2; i++ {
+				if got := db.Lookup(tt.ip); got != tt.want {
+					t.Errorf("record size %d: Lookup(%q) = %+v, want %+v", size, tt.ip, got, tt.want)
+				}
+			}
+		}
+	}
+}
+
+func TestNewReader_Invalid(t *testing.T) {
+	if _, err := NewReader([]byte("not a database")); err == nil {
+		t.Error("NewReader() accepted a file without metadata")
+	}
+	bad := append(append([]byte(nil), metadataMarker...), encode(map[string]any{
+		"node_count": uint64(1), "record_size": uint64(20), "ip_version": uint64(6),
+	})...)
+	if _, err := NewReader(bad); err == nil {
+		t.Error("NewReader() accepted record size 20")
+	}
+}
+
+func TestDecoder_Pointer(t *testing.T) {
+	// A map whose value is a pointer back to the string at offset 0
+	buf := append(encode("shared"), 0xE1)
+	buf = append(buf, encode("key")...)
+	buf = append(buf, 0x20, 0x00)
+	v, _, err := decoder{buf: buf}.decode(7)
+	if err != nil {
+		t.Fatalf("decode() error = %v", err)
+	}
+	if got := path(v, "key"); got != "shared" {
+		t.Errorf("pointer decoded to %v, want shared", got)
+	}
+}
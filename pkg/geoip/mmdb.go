@@ -0,0 +1,298 @@
+package geoip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net/netip"
+)
+
+// metadataMarker precedes the metadata map at the end of every MaxMind DB file
+var metadataMarker = []byte("\xAB\xCD\xEFMaxMind.com")
+
+// Reader looks up networks in a MaxMind DB (.mmdb) file held in memory. It implements the
+// parts of the MaxMind DB format 2.0 the lookups need and is safe for concurrent use.
+type Reader struct {
+	// DatabaseType is the metadata's database_type, e.g. "GeoLite2-City" or "GeoLite2-ASN"
+	DatabaseType string
+
+	tree       []byte
+	data       []byte
+	nodeCount  uint
+	recordSize uint
+	ipVersion  uint
+	ipv4Start  uint
+}
+
+// NewReader parses the search tree and metadata of a MaxMind DB file
+func NewReader(buf []byte) (*Reader, error) {
+	start := bytes.LastIndex(buf, metadataMarker)
+	if start < 0 {
+		return nil, errors.New("not a MaxMind DB file: metadata marker not found")
+	}
+	meta, _, err := decoder{buf: buf[start+len(metadataMarker):]}.decode(0)
+	if err != nil {
+		return nil, fmt.Errorf("invalid metadata: %w", err)
+	}
+	fields, ok := meta.(map[string]any)
+	if !ok {
+		return nil, errors.New("invalid metadata: not a map")
+	}
+
+	r := &Reader{
+		nodeCount:  uintField(fields, "node_count"),
+		recordSize: uintField(fields, "record_size"),
+		ipVersion:  uintField(fields, "ip_version"),
+	}
+	r.DatabaseType, _ = fields["database_type"].(string)
+	switch r.recordSize {
+	case 24, 28, 32:
+	default:
+		return nil, fmt.Errorf("unsupported record size %d", r.recordSize)
+	}
+	if r.ipVersion != 4 && r.ipVersion != 6 {
+		return nil, fmt.Errorf("unsupported IP version %d", r.ipVersion)
+	}
+
+	// The search tree is followed by 16 zero bytes, then the data section
+	treeSize := r.nodeCount * r.recordSize / 4
+	if treeSize+16 > uint(start) {
+		return nil, errors.New("search tree exceeds the file")
+	}
+	r.tree = buf[:treeSize]
+	r.data = buf[treeSize+16 : start]
+
+	// IPv4 addresses live under ::/96 in an IPv6 tree
+	if r.ipVersion == 6 {
+		for i := 0; i < 96 && r.ipv4Start < r.nodeCount; i++ {
+			r.ipv4Start = r.record(r.ipv4Start, 0)
+		}
+	}
+	return r, nil
+}
+
+func uintField(fields map[string]any, key string) uint {
+	v, _ := fields[key].(uint64)
+	return uint(v)
+}
+
+// record returns the left (bit 0) or right (bit 1) record of a search tree node
+func (r *Reader) record(node uint, bit uint) uint {
+	off := node * r.recordSize / 4
+	b := r.tree[off : off+r.recordSize/4]
+	switch r.recordSize {
+	case 24:
+		b = b[bit*3:]
+		return uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+	case 28:
+		if bit == 0 {
+			return uint(b[3]&0xF0)<<20 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+		}
+		return uint(b[3]&0x0F)<<24 | uint(b[4])<<16 | uint(b[5])<<8 | uint(b[6])
+	default:
+		return uint(binary.BigEndian.Uint32(b[bit*4:]))
+	}
+}
+
+// offset walks the search tree and returns the data section offset of the network holding
+// addr, or false when the database has no data for it
+func (r *Reader) offset(addr netip.Addr) (uint, bool) {
+	addr = addr.Unmap()
+	var ip []byte
+	node := uint(0)
+	switch {
+	case addr.Is4():
+		ip = addr.AsSlice()
+		node = r.ipv4Start
+	case r.ipVersion == 6:
+		ip = addr.AsSlice()
+	default:
+		// An IPv4-only database knows nothing about IPv6
+		return 0, false
+	}
+
+	for i := 0; i < len(ip)*8 && node < r.nodeCount; i++ {
+		bit := uint(ip[i/8]>>(7-i%8)) & 1
+		node = r.record(node, bit)
+	}
+	if node <= r.nodeCount {
+		return 0, false
+	}
+	return node - r.nodeCount - 16, true
+}
+
+// Lookup returns the decoded data of the network holding addr, or nil when there is none
+func (r *Reader) Lookup(addr netip.Addr) (any, error) {
+	off, ok := r.offset(addr)
+	if !ok {
+		return nil, nil
+	}
+	v, _, err := decoder{buf: r.data}.decode(off)
+	return v, err
+}
+
+// Data section field types
+const (
+	typeExtended = iota
+	typePointer
+	typeString
+	typeDouble
+	typeBytes
+	typeUint16
+	typeUint32
+	typeMap
+	typeInt32
+	typeUint64
+	typeUint128
+	typeArray
+	typeContainer
+	typeEndMarker
+	typeBool
+	typeFloat
+)
+
+var errTruncated = errors.New("data section truncated")
+
+// decoder decodes data section fields into string, float64, []byte, uint64, int64, bool,
+// map[string]any and []any values. uint128 values are returned as their 16 big-endian bytes.
+type decoder struct {
+	buf []byte
+}
+
+// decode returns the field at off and the offset of the next field
+func (d decoder) decode(off uint) (any, uint, error) {
+	if off >= uint(len(d.buf)) {
+		return nil, 0, errTruncated
+	}
+	ctrl := d.buf[off]
+	off++
+	typ := uint(ctrl >> 5)
+
+	if typ == typePointer {
+		ptr, next, err := d.pointer(ctrl, off)
+		if err != nil {
+			return nil, 0, err
+		}
+		v, _, err := d.decode(ptr)
+		return v, next, err
+	}
+	if typ == typeExtended {
+		if off >= uint(len(d.buf)) {
+			return nil, 0, errTruncated
+		}
+		typ = 7 + uint(d.buf[off])
+		off++
+	}
+
+	size := uint(ctrl & 0x1F)
+	if size >= 29 {
+		n := size - 28
+		if off+n > uint(len(d.buf)) {
+			return nil, 0, errTruncated
+		}
+		extra := uint(0)
+		for _, b := range d.buf[off : off+n] {
+			extra = extra<<8 | uint(b)
+		}
+		off += n
+		switch size {
+		case 29:
+			size = 29 + extra
+		case 30:
+			size = 285 + extra
+		default:
+			size = 65821 + extra
+		}
+	}
+
+	switch typ {
+	case typeMap:
+		m := make(map[string]any, size)
+		for i := uint(0); i < size; i++ {
+			k, next, err := d.decode(off)
+			if err != nil {
+				return nil, 0, err
+			}
+			key, ok := k.(string)
+			if !ok {
+				return nil, 0, fmt.Errorf("map key of type %T", k)
+			}
+			if m[key], off, err = d.decode(next); err != nil {
+				return nil, 0, err
+			}
+		}
+		return m, off, nil
+	case typeArray:
+		a := make([]any, size)
+		for i := range a {
+			var err error
+			if a[i], off, err = d.decode(off); err != nil {
+				return nil, 0, err
+			}
+		}
+		return a, off, nil
+	case typeBool:
+		return size != 0, off, nil
+	case typeContainer, typeEndMarker:
+		return nil, off, nil
+	}
+
+	if off+size > uint(len(d.buf)) {
+		return nil, 0, errTruncated
+	}
+	b := d.buf[off : off+size]
+	off += size
+	switch typ {
+	case typeString:
+		return string(b), off, nil
+	case typeBytes, typeUint128:
+		return append([]byte(nil), b...), off, nil
+	case typeDouble:
+		if size != 8 {
+			return nil, 0, fmt.Errorf("double of %d bytes", size)
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), off, nil
+	case typeFloat:
+		if size != 4 {
+			return nil, 0, fmt.Errorf("float of %d bytes", size)
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), off, nil
+	case typeUint16, typeUint32, typeUint64:
+		v := uint64(0)
+		for _, c := range b {
+			v = v<<8 | uint64(c)
+		}
+		return v, off, nil
+	case typeInt32:
+		v := uint32(0)
+		for _, c := range b {
+			v = v<<8 | uint32(c)
+		}
+		return int64(int32(v)), off, nil
+	}
+	return nil, 0, fmt.Errorf("unknown field type %d", typ)
+}
+
+// pointer returns the data section offset a pointer field refers to and the offset after it
+func (d decoder) pointer(ctrl byte, off uint) (uint, uint, error) {
+	n := uint(ctrl>>3)&0x3 + 1
+	if off+n > uint(len(d.buf)) {
+		return 0, 0, errTruncated
+	}
+	v := uint(0)
+	if n < 4 {
+		v = uint(ctrl & 0x7)
+	}
+	for _, b := range d.buf[off : off+n] {
+		v = v<<8 | uint(b)
+	}
+	switch n {
+	case 2:
+		v += 2048
+	case 3:
+		v += 526336
+	}
+	return v, off + n, nil
+}
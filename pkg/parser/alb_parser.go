@@ -1,7 +1,6 @@
 package parser
 
 import (
-	"compress/gzip"
 	"fmt"
 	"io"
 	"os"
@@ -112,25 +111,24 @@ func ParseLogLine(line string) (*ALBLogEntry, error) {
 	return entry, nil
 }
 
-// ParseLogFile parses an ALB log file (supports gzip)
+// ParseLogFile parses an ALB log file (supports gzip, zstd and bzip2)
 func ParseLogFile(filePath string) ([]*ALBLogEntry, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open file: %w", err)
 	}
 	defer file.Close()
+	return ParseLogReader(file)
+}
 
-	var reader io.Reader = file
-
-	// Check if gzipped
-	if strings.HasSuffix(filePath, ".gz") {
-		gzReader, err := gzip.NewReader(file)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create gzip reader: %w", err)
-		}
-		defer gzReader.Close()
-		reader = gzReader
+// ParseLogReader parses ALB log lines from r, e.g. stdin (supports gzip, zstd and bzip2)
+func ParseLogReader(r io.Reader) ([]*ALBLogEntry, error) {
+	// Detect compression by magic bytes
+	reader, err := Decompress(r)
+	if err != nil {
+		return nil, err
 	}
+	defer reader.Close()
 
 	// Read all content
 	content, err := io.ReadAll(reader)
@@ -1,7 +1,6 @@
 package parser
 
 import (
-	"compress/gzip"
 	"fmt"
 	"io"
 	"os"
@@ -98,25 +97,25 @@ func ParseCloudFrontLogLine(line string) (*CloudFrontLogEntry, error) {
 	return entry, nil
 }
 
-// ParseCloudFrontLogFile parses a CloudFront log file (supports gzip)
+// ParseCloudFrontLogFile parses a CloudFront log file (supports gzip, zstd and bzip2)
 func ParseCloudFrontLogFile(filePath string) ([]*CloudFrontLogEntry, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open file: %w", err)
 	}
 	defer file.Close()
+	return ParseCloudFrontLogReader(file)
+}
 
-	var reader io.Reader = file
-
-	// Check if gzipped
-	if strings.HasSuffix(filePath, ".gz") {
-		gzReader, err := gzip.NewReader(file)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create gzip reader: %w", err)
-		}
-		defer gzReader.Close()
-		reader = gzReader
+// ParseCloudFrontLogReader parses CloudFront log lines from r, e.g. stdin (supports gzip, zstd
+// and bzip2)
+func ParseCloudFrontLogReader(r io.Reader) ([]*CloudFrontLogEntry, error) {
+	// Detect compression by magic bytes
+	reader, err := Decompress(r)
+	if err != nil {
+		return nil, err
 	}
+	defer reader.Close()
 
 	// Read all content
 	content, err := io.ReadAll(reader)
@@ -0,0 +1,123 @@
+package parser
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const testContent = "line one\nline two\n"
+
+// testBzip2 is testContent compressed with bzip2, which the standard library cannot write
+const testBzip2 = "425a68393141592653598c77bfde000004d1800010400002258480200031064c40c869a68f0b2c20989c278bb9229c2848463bdfef00"
+
+func compressed(t *testing.T, format string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	switch format {
+	case "gzip":
+		gz := gzip.NewWriter(&buf)
+		gz.Write([]byte(testContent))
+		gz.Close()
+	case "zstd":
+		zw, err := zstd.NewWriter(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		zw.Write([]byte(testContent))
+		zw.Close()
+	case "bzip2":
+		b, _ := hex.DecodeString(testBzip2)
+		buf.Write(b)
+	default:
+		buf.WriteString(testContent)
+	}
+	return buf.Bytes()
+}
+
+func TestDecompress(t *testing.T) {
+	for _, format := range []string{"gzip", "zstd", "bzip2", "plain"} {
+		t.Run(format, func(t *testing.T) {
+			r, err := Decompress(bytes.NewReader(compressed(t, format)))
+			if err != nil {
+				t.Fatalf("Decompress() error = %v", err)
+			}
+			defer r.Close()
+			got, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("ReadAll() error = %v", err)
+			}
+			if string(got) != testContent {
+				t.Errorf("got %q, want %q", got, testContent)
+			}
+		})
+	}
+}
+
+func TestDecompress_ShortInput(t *testing.T) {
+	for _, in := range []string{"", "x", "\x1f"} {
+		r, err := Decompress(bytes.NewReader([]byte(in)))
+		if err != nil {
+			t.Fatalf("Decompress(%q) error = %v", in, err)
+		}
+		if got, _ := io.ReadAll(r); string(got) != in {
+			t.Errorf("Decompress(%q) = %q", in, got)
+		}
+	}
+}
+
+func TestDecompress_CorruptGzip(t *testing.T) {
+	if _, err := Decompress(bytes.NewReader([]byte{0x1f, 0x8b, 0x00})); err == nil {
+		t.Error("expected an error for a truncated gzip header")
+	}
+}
+
+func TestParseLogFile_DetectsCompression(t *testing.T) {
+	line := "http 2018-07-02T22:23:00.186641Z app/my-loadbalancer/50dc6c495c0c9188 192.168.131.39:2817 10.0.0.1:80 0.000 0.001 0.000 200 200 34 366 \"GET http://www.example.com:80/ HTTP/1.1\" \"curl/7.46.0\" - - arn:aws:elasticloadbalancing:us-east-2:123456789012:targetgroup/my-targets/73e2d6bc24d8a067 \"Root=1-58337262-36d228ad5d99923122bbe354\" \"-\" \"-\" 0 2018-07-02T22:22:48.364000Z \"forward\" \"-\" \"-\" \"10.0.0.1:80\" \"200\" \"-\" \"-\" TID_1234\n"
+
+	var buf bytes.Buffer
+	zw, err := zstd.NewWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw.Write([]byte(line))
+	zw.Close()
+
+	// The name gives no hint of the compression
+	path := filepath.Join(t.TempDir(), "access.log")
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := ParseLogFile(path)
+	if err != nil {
+		t.Fatalf("ParseLogFile() error = %v", err)
+	}
+	if len(entries) != 1 || entries[0].ELB != "app/my-loadbalancer/50dc6c495c0c9188" {
+		t.Errorf("ParseLogFile() = %+v", entries)
+	}
+}
+
+func TestParseLogReader_Gzip(t *testing.T) {
+	line := "http 2018-07-02T22:23:00.186641Z app/my-loadbalancer/50dc6c495c0c9188 192.168.131.39:2817 10.0.0.1:80 0.000 0.001 0.000 200 200 34 366 \"GET http://www.example.com:80/ HTTP/1.1\" \"curl/7.46.0\" - - arn:aws:elasticloadbalancing:us-east-2:123456789012:targetgroup/my-targets/73e2d6bc24d8a067 \"Root=1-58337262-36d228ad5d99923122bbe354\" \"-\" \"-\" 0 2018-07-02T22:22:48.364000Z \"forward\" \"-\" \"-\" \"10.0.0.1:80\" \"200\" \"-\" \"-\" TID_1234\n"
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write([]byte(line + line))
+	gz.Close()
+
+	// A pipe gives no name to go by, only the magic bytes
+	entries, err := ParseLogReader(io.MultiReader(&buf))
+	if err != nil {
+		t.Fatalf("ParseLogReader() error = %v", err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("ParseLogReader() returned %d entries, want 2", len(entries))
+	}
+}
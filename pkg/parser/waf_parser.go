@@ -1,7 +1,6 @@
 package parser
 
 import (
-	"compress/gzip"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -89,25 +88,38 @@ type Label struct {
 	Name string `json:"name"`
 }
 
-// ParseWAFLogFile parses a WAF log file (supports gzip, handles concatenated JSON)
+// ParseWAFLogLine parses a single newline-delimited WAF JSON record
+func ParseWAFLogLine(line string) (*WAFLogEntry, error) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return nil, nil
+	}
+
+	var entry WAFLogEntry
+	if err := json.Unmarshal([]byte(line), &entry); err != nil {
+		return nil, fmt.Errorf("failed to decode WAF JSON: %w", err)
+	}
+	return &entry, nil
+}
+
+// ParseWAFLogFile parses a WAF log file (supports gzip, zstd and bzip2, handles concatenated JSON)
 func ParseWAFLogFile(filePath string) ([]*WAFLogEntry, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open file: %w", err)
 	}
 	defer file.Close()
+	return ParseWAFLogReader(file)
+}
 
-	var reader io.Reader = file
-
-	// Check if gzipped
-	if strings.HasSuffix(filePath, ".gz") {
-		gzReader, err := gzip.NewReader(file)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create gzip reader: %w", err)
-		}
-		defer gzReader.Close()
-		reader = gzReader
+// ParseWAFLogReader parses WAF log records from r, e.g. stdin (supports gzip, zstd and bzip2)
+func ParseWAFLogReader(r io.Reader) ([]*WAFLogEntry, error) {
+	// Detect compression by magic bytes
+	reader, err := Decompress(r)
+	if err != nil {
+		return nil, err
 	}
+	defer reader.Close()
 
 	// WAF logs are often concatenated JSON objects, effectively JSON Lines but sometimes just concatenated
 	// Using json.Decoder with More() handles this gracefully
@@ -46,3 +46,21 @@ func TestParseWAFLogFile(t *testing.T) {
 		t.Errorf("Second entry ClientIP = %v, want 1.2.3.4", entries[1].HTTPRequest.ClientIP)
 	}
 }
+
+func TestParseWAFLogLine(t *testing.T) {
+	entry, err := ParseWAFLogLine(`{"timestamp":1683355579981,"webaclId":"arn:aws:wafv2:eu-west-3:111122223333:regional/webacl/TEST-WEBACL/123","action":"BLOCK","httpRequest":{"clientIp":"52.46.82.45","httpMethod":"GET","uri":"/"}}`)
+	if err != nil {
+		t.Fatalf("ParseWAFLogLine() error = %v", err)
+	}
+	if entry.Action != "BLOCK" || entry.HTTPRequest.ClientIP != "52.46.82.45" {
+		t.Errorf("ParseWAFLogLine() = %+v", entry)
+	}
+
+	if entry, err := ParseWAFLogLine("   "); entry != nil || err != nil {
+		t.Errorf("ParseWAFLogLine(blank) = %v, %v, want nil, nil", entry, err)
+	}
+
+	if _, err := ParseWAFLogLine(`{"timestamp":`); err == nil {
+		t.Error("ParseWAFLogLine(truncated) expected error, got nil")
+	}
+}
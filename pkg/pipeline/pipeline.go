@@ -0,0 +1,277 @@
+// Package pipeline runs the parse, convert and export pipeline of the Lambda as a library, so
+// other Go services can ship AWS access logs to their own exporter without the CLI:
+//
+//	p, err := pipeline.New(pipeline.Config{Exporter: exp})
+//	if err != nil {
+//		return err
+//	}
+//	res, err := p.ProcessS3Object(ctx, "my-logs", "AWSLogs/123456789012/elasticloadbalancing/...log.gz")
+//
+// ProcessReader does the same for logs that are already at hand, such as a local file or an
+// HTTP upload. Compressed input is detected from its content in both cases.
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"github.com/pixelvide/otel-aws-log-parser/cmd/lambda/adapter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/exporter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/processor"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/version"
+)
+
+// ErrNoProcessor is returned by ProcessS3Object for keys no registered processor matches
+var ErrNoProcessor = errors.New("no processor matches the object")
+
+// DefaultBatchSize is the number of records exported per batch when Config.BatchSize is unset
+const DefaultBatchSize = 500
+
+// Config configures a Pipeline. Only Exporter is required.
+type Config struct {
+	// Exporter receives every batch, one resource per call
+	Exporter exporter.Exporter
+	// Store reads S3 objects; nil uses an S3Store on the SDK's default session
+	Store processor.ObjectStore
+	// Registry picks the processor of an S3 key; nil registers the ALB, NLB, CloudFront and
+	// WAF processors with ReadOptions
+	Registry *processor.Registry
+	// ReadOptions applies to ProcessReader and to the default registry's processors
+	ReadOptions processor.ReadOptions
+	// BatchSize caps the records of one resource sent in a single Export call
+	BatchSize int
+	// Logger receives progress and warnings; nil uses slog.Default()
+	Logger *slog.Logger
+}
+
+// Result counts what one call handed over to the exporter
+type Result struct {
+	Records int
+	Batches int
+}
+
+// Pipeline parses log objects, converts them to OTel log records and exports them. It is safe
+// for concurrent use when its exporter and store are.
+type Pipeline struct {
+	exp       exporter.Exporter
+	store     processor.ObjectStore
+	registry  *processor.Registry
+	opts      processor.ReadOptions
+	batchSize int
+	logger    *slog.Logger
+}
+
+// New builds a Pipeline from cfg, filling in the defaults documented on Config
+func New(cfg Config) (*Pipeline, error) {
+	if cfg.Exporter == nil {
+		return nil, fmt.Errorf("pipeline: Exporter is required")
+	}
+	p := &Pipeline{
+		exp:       cfg.Exporter,
+		store:     cfg.Store,
+		registry:  cfg.Registry,
+		opts:      cfg.ReadOptions,
+		batchSize: cfg.BatchSize,
+		logger:    cfg.Logger,
+	}
+	if p.opts.MaxBatchSize <= 0 {
+		p.opts.MaxBatchSize = DefaultBatchSize
+	}
+	if p.opts.MaxConcurrent <= 0 {
+		p.opts.MaxConcurrent = 10
+	}
+	if p.batchSize <= 0 {
+		p.batchSize = DefaultBatchSize
+	}
+	if p.logger == nil {
+		p.logger = slog.Default()
+	}
+	if p.registry == nil {
+		p.registry = defaultRegistry(p.opts)
+	}
+	if p.store == nil {
+		cfg, err := config.LoadDefaultConfig(context.Background())
+		if err != nil {
+			return nil, fmt.Errorf("pipeline: loading AWS config: %w", err)
+		}
+		p.store = &processor.S3Store{Client: s3.NewFromConfig(cfg)}
+	}
+	return p, nil
+}
+
+// defaultRegistry registers the built-in processors in the Lambda's order
+func defaultRegistry(opts processor.ReadOptions) *processor.Registry {
+	reg := processor.NewRegistry()
+	reg.Register(&processor.ALBProcessor{ReadOptions: opts})
+	reg.Register(&processor.NLBProcessor{ReadOptions: opts})
+	reg.Register(&processor.CloudFrontProcessor{ReadOptions: opts})
+	// WAF records embed full request headers and match details and routinely exceed 1MB
+	wafOpts := opts
+	if wafOpts.MaxLineBytes == 0 {
+		wafOpts.MaxLineBytes = 16 * 1024 * 1024
+	}
+	reg.Register(&processor.WAFProcessor{ReadOptions: wafOpts})
+	return reg
+}
+
+// ProcessS3Object reads s3://bucket/key with the processor the registry picks for the key and
+// exports its records. Keys no processor matches return ErrNoProcessor.
+func (p *Pipeline) ProcessS3Object(ctx context.Context, bucket, key string) (Result, error) {
+	proc := p.registry.Find(bucket, key)
+	if proc == nil {
+		return Result{}, fmt.Errorf("%w: s3://%s/%s", ErrNoProcessor, bucket, key)
+	}
+	logger := p.logger.With("bucket", bucket, "key", key, "processor", proc.Name())
+
+	sink := p.newSink(ctx)
+	if sp, ok := proc.(processor.StreamProcessor); ok {
+		if err := sp.ProcessStream(ctx, logger, p.store, bucket, key, sink.add, nil); err != nil {
+			return sink.result, err
+		}
+	} else {
+		entries, err := proc.Process(ctx, logger, p.store, bucket, key)
+		if err != nil {
+			return sink.result, err
+		}
+		for _, entry := range entries {
+			if err := sink.add(entry); err != nil {
+				return sink.result, err
+			}
+		}
+	}
+	err := sink.flush()
+	return sink.result, err
+}
+
+// ProcessReader parses r as logs of format ("alb", "nlb", "cloudfront", "waf" or "auto", which
+// detects the format of every line) and exports its records. Account and region are not known
+// without an S3 key and are left to the entries' own fields.
+func (p *Pipeline) ProcessReader(ctx context.Context, r io.Reader, format string) (Result, error) {
+	format = strings.ToLower(format)
+	if format != processor.FormatAuto && !slices.Contains(processor.Formats, format) {
+		return Result{}, fmt.Errorf("unknown log format %q", format)
+	}
+	parse := func(line string) (adapter.LogAdapter, error) {
+		return processor.AdapterForLine(format, line)
+	}
+	sink := p.newSink(ctx)
+	store := readerStore{r: r}
+	if err := processor.StreamAndParseObject(ctx, p.logger, store, "", "", p.opts, parse, sink.add, nil); err != nil {
+		return sink.result, err
+	}
+	err := sink.flush()
+	return sink.result, err
+}
+
+// readerStore serves r as the only object, whatever bucket and key are asked for. Its size is
+// unknown, reported as -1 so that it is not skipped as an empty object.
+type readerStore struct {
+	r io.Reader
+}
+
+func (s readerStore) GetObject(ctx context.Context, bucket, key string) (*processor.Object, error) {
+	return &processor.Object{Body: io.NopCloser(s.r), LastModified: time.Now(), Size: -1}, nil
+}
+
+// DefaultScope is the instrumentation scope of records whose processor reports none
+func DefaultScope() converter.Scope {
+	return converter.Scope{Name: "otel-aws-log-parser", Version: version.Info().String()}
+}
+
+// Group is the pending records of one resource. It is the unit the pipeline and the Lambda's
+// stream sink batch and export.
+type Group struct {
+	Scope         converter.Scope
+	ResourceAttrs []converter.OTelAttribute
+	LogRecords    []converter.OTelLogRecord
+}
+
+// NewGroup starts the group of entry's resource, in the entry's own scope when its processor
+// reports one and in scope otherwise
+func NewGroup(entry adapter.LogAdapter, scope converter.Scope) Group {
+	return Group{Scope: processor.ScopeOf(entry, scope), ResourceAttrs: entry.GetResourceAttributes()}
+}
+
+// ResourceLog wraps records of the group into one export payload
+func (g *Group) ResourceLog(records []converter.OTelLogRecord) converter.ResourceLog {
+	return converter.ResourceLog{
+		Resource:  converter.ResourceAttributes{Attributes: g.ResourceAttrs},
+		ScopeLogs: []converter.ScopeLog{{Scope: g.Scope, LogRecords: records}},
+	}
+}
+
+// Take removes and returns the first n records. The remaining records get a fresh backing
+// array, so the taken ones are released once exported.
+func (g *Group) Take(n int) []converter.OTelLogRecord {
+	taken := g.LogRecords[:n]
+	g.LogRecords = append([]converter.OTelLogRecord(nil), g.LogRecords[n:]...)
+	return taken
+}
+
+// sink groups entries by resource and exports a group whenever it reaches the batch size.
+// Emit functions are called from a single goroutine, so it needs no locking.
+type sink struct {
+	ctx    context.Context
+	p      *Pipeline
+	groups map[string]*Group
+	order  []string
+	result Result
+}
+
+func (p *Pipeline) newSink(ctx context.Context) *sink {
+	return &sink{
+		ctx:    ctx,
+		p:      p,
+		groups: map[string]*Group{},
+	}
+}
+
+func (s *sink) add(entry adapter.LogAdapter) error {
+	key := entry.GetResourceKey()
+	g, ok := s.groups[key]
+	if !ok {
+		group := NewGroup(entry, DefaultScope())
+		g = &group
+		s.groups[key] = g
+		s.order = append(s.order, key)
+	}
+	g.LogRecords = append(g.LogRecords, entry.ToOTel())
+	if len(g.LogRecords) >= s.p.batchSize {
+		return s.export(g)
+	}
+	return nil
+}
+
+// flush exports the remaining records of every group, in the order the groups appeared
+func (s *sink) flush() error {
+	for _, key := range s.order {
+		if err := s.export(s.groups[key]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *sink) export(g *Group) error {
+	n := len(g.LogRecords)
+	if n == 0 {
+		return nil
+	}
+	if err := s.p.exp.Export(s.ctx, g.ResourceLog(g.LogRecords)); err != nil {
+		return fmt.Errorf("exporting %d records: %w", n, err)
+	}
+	g.LogRecords = nil
+	s.result.Records += n
+	s.result.Batches++
+	return nil
+}
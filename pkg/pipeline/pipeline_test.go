@@ -0,0 +1,175 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/exporter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/processor"
+)
+
+const (
+	testALBLine = `http 2018-07-02T22:23:00.186641Z app/my-loadbalancer/50dc6c495c0c9188 192.168.131.39:2817 10.0.0.1:80 0.000 0.001 0.000 200 200 34 366 "GET http://www.example.com:80/ HTTP/1.1" "curl/7.46.0" - - arn:aws:elasticloadbalancing:us-east-2:123456789012:targetgroup/my-targets/73e2d6bc24d8a067 "Root=1-58337262-36d228ad5d99923122bbe354" "www.example.com" "-" 100 2018-07-02T22:22:48.364000Z "forward" "-" "-" "10.0.0.1:80" "200" "-" "-" -`
+	testNLBLine = "tls 2.0 2023-10-01T00:00:00.000000Z app/net-lb/1234567890abcdef listener/net-lb/1234567890abcdef/1234567890abcdef 1.2.3.4:12345 5.6.7.8:80 0.001 0.002 100 200 - arn:aws:acm:us-east-1:123456789012:certificate/12345678-1234-1234-1234-123456789012 - ECDHE-RSA-AES128-GCM-SHA256 TLSv1.2 - example.com h2 - - 2023-10-01T00:00:00.000000Z"
+)
+
+// recorder collects the batches handed to the exporter
+type recorder struct {
+	batches []converter.ResourceLog
+}
+
+func (r *recorder) exporter() exporter.Exporter {
+	return exporter.ExporterFunc(func(ctx context.Context, logs converter.ResourceLog) error {
+		r.batches = append(r.batches, logs)
+		return nil
+	})
+}
+
+func TestNew_RequiresExporter(t *testing.T) {
+	if _, err := New(Config{}); err == nil {
+		t.Error("New() without an exporter succeeded")
+	}
+}
+
+func TestProcessReader(t *testing.T) {
+	tests := []struct {
+		name        string
+		format      string
+		input       string
+		batchSize   int
+		wantRecords int
+		wantBatches int
+		wantErr     bool
+	}{
+		{
+			name:        "alb",
+			format:      "alb",
+			input:       strings.Repeat(testALBLine+"\n", 3),
+			wantRecords: 3,
+			wantBatches: 1,
+		},
+		{
+			name:        "batch size splits a resource",
+			format:      "alb",
+			input:       strings.Repeat(testALBLine+"\n", 5),
+			batchSize:   2,
+			wantRecords: 5,
+			wantBatches: 3,
+		},
+		{
+			name:        "auto groups each format's resources",
+			format:      "auto",
+			input:       "# comment\n" + testALBLine + "\n" + testNLBLine + "\n\n" + testALBLine + "\n",
+			wantRecords: 3,
+			wantBatches: 2,
+		},
+		{
+			name:    "unknown format",
+			format:  "elb",
+			input:   testALBLine,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := &recorder{}
+			p, err := New(Config{
+				Exporter:  rec.exporter(),
+				Store:     &processor.LocalStore{Root: t.TempDir()},
+				BatchSize: tt.batchSize,
+				Logger:    slog.New(slog.NewJSONHandler(io.Discard, nil)),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			res, err := p.ProcessReader(context.Background(), strings.NewReader(tt.input), tt.format)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ProcessReader() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if res.Records != tt.wantRecords || res.Batches != tt.wantBatches {
+				t.Errorf("ProcessReader() = %+v, want %d records in %d batches", res, tt.wantRecords, tt.wantBatches)
+			}
+			if len(rec.batches) != tt.wantBatches {
+				t.Errorf("exporter received %d batches, want %d", len(rec.batches), tt.wantBatches)
+			}
+		})
+	}
+}
+
+func TestProcessS3Object(t *testing.T) {
+	root := t.TempDir()
+	key := "AWSLogs/123456789012/elasticloadbalancing/us-east-2/2018/07/02/123456789012_elasticloadbalancing_us-east-2_app.my-loadbalancer.50dc6c495c0c9188_20180702T2225Z_10.0.0.1_abc.log"
+	path := filepath.Join(root, "logs", filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(testALBLine+"\n"+testALBLine+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := &recorder{}
+	p, err := New(Config{
+		Exporter: rec.exporter(),
+		Store:    &processor.LocalStore{Root: root},
+		Logger:   slog.New(slog.NewJSONHandler(io.Discard, nil)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := p.ProcessS3Object(context.Background(), "logs", key)
+	if err != nil {
+		t.Fatalf("ProcessS3Object() error = %v", err)
+	}
+	if res.Records != 2 || res.Batches != 1 {
+		t.Fatalf("ProcessS3Object() = %+v, want 2 records in 1 batch", res)
+	}
+	// The key supplies the account the reader path cannot know
+	var account string
+	for _, attr := range rec.batches[0].Resource.Attributes {
+		if attr.Key == "cloud.account.id" {
+			account = attr.Value.Text()
+		}
+	}
+	if account != "123456789012" {
+		t.Errorf("cloud.account.id = %q, want 123456789012", account)
+	}
+	if scope := rec.batches[0].ScopeLogs[0].Scope.Name; scope != "otel-aws-log-parser" {
+		t.Errorf("scope name = %q, want otel-aws-log-parser", scope)
+	}
+
+	if _, err := p.ProcessS3Object(context.Background(), "logs", "unknown/object.txt"); !errors.Is(err, ErrNoProcessor) {
+		t.Errorf("ProcessS3Object() of an unmatched key error = %v, want ErrNoProcessor", err)
+	}
+}
+
+func TestGroupTake(t *testing.T) {
+	g := Group{Scope: DefaultScope(), LogRecords: []converter.OTelLogRecord{{SeverityText: "a"}, {SeverityText: "b"}, {SeverityText: "c"}}}
+
+	taken := g.Take(2)
+	if len(taken) != 2 || taken[1].SeverityText != "b" {
+		t.Fatalf("Take(2) = %+v, want the first two records", taken)
+	}
+	if len(g.LogRecords) != 1 || g.LogRecords[0].SeverityText != "c" {
+		t.Fatalf("remaining = %+v, want the third record", g.LogRecords)
+	}
+
+	// The remaining records must not share the taken records' backing array
+	g.LogRecords = append(g.LogRecords, converter.OTelLogRecord{SeverityText: "d"})
+	if taken[1].SeverityText != "b" {
+		t.Error("appending to the group overwrote a taken record")
+	}
+
+	logs := g.ResourceLog(taken)
+	if logs.ScopeLogs[0].Scope != DefaultScope() || len(logs.ScopeLogs[0].LogRecords) != 2 {
+		t.Errorf("ResourceLog() = %+v, want the taken records in the default scope", logs)
+	}
+}
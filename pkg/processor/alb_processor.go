@@ -2,18 +2,19 @@ package processor
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
+	"slices"
 	"strings"
+	"sync/atomic"
 
-	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/pixelvide/otel-aws-log-parser/cmd/lambda/adapter"
 	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
 	"github.com/pixelvide/otel-aws-log-parser/pkg/parser"
 )
 
 type ALBProcessor struct {
-	MaxBatchSize  int
-	MaxConcurrent int
+	ReadOptions
 }
 
 func (p *ALBProcessor) Name() string {
@@ -24,11 +25,19 @@ func (p *ALBProcessor) Matches(bucket, key string) bool {
 	return strings.Contains(key, "/elasticloadbalancing/") && strings.Contains(key, "_app.")
 }
 
-func (p *ALBProcessor) Process(ctx context.Context, logger *slog.Logger, s3Client *s3.S3, bucket, key string) ([]adapter.LogAdapter, error) {
+func (p *ALBProcessor) Process(ctx context.Context, logger *slog.Logger, store ObjectStore, bucket, key string) ([]adapter.LogAdapter, error) {
+	return ReadAndParseObject(ctx, logger, store, bucket, key, p.ReadOptions, p.parseLine(key))
+}
+
+func (p *ALBProcessor) ProcessStream(ctx context.Context, logger *slog.Logger, store ObjectStore, bucket, key string, emit EmitFunc, cp *Checkpoint) error {
+	return StreamAndParseObject(ctx, logger, store, bucket, key, p.ReadOptions, p.parseLine(key), emit, cp)
+}
+
+func (p *ALBProcessor) parseLine(key string) ProcessLineFunc {
 	// Extract common attributes from S3 key
 	accountID, region := ParseRegionAccountFromS3Key(key)
 
-	return ReadAndParseFromS3(logger, s3Client, bucket, key, p.MaxBatchSize, p.MaxConcurrent, func(line string) (adapter.LogAdapter, error) {
+	return func(line string) (adapter.LogAdapter, error) {
 		entry, err := parser.ParseLogLine(line)
 		if err != nil {
 			return nil, err
@@ -38,7 +47,7 @@ func (p *ALBProcessor) Process(ctx context.Context, logger *slog.Logger, s3Clien
 			AccountID:   accountID,
 			Region:      region,
 		}, nil
-	})
+	}
 }
 
 // ALBAdapter implementation
@@ -48,16 +57,68 @@ type ALBAdapter struct {
 	Region    string
 }
 
+// ALB resource groupings: the records of one group share a resource and an export batch
+const (
+	// GroupByELB groups the records of a load balancer, whatever their listener or target group
+	GroupByELB = "elb"
+	// GroupByTargetGroup groups by target group; records without one group by load balancer
+	GroupByTargetGroup = "target_group"
+	// GroupByAccount groups the load balancers of an account and region
+	GroupByAccount = "account"
+)
+
+// ALBGroupings are the accepted ALB groupings
+var ALBGroupings = []string{GroupByELB, GroupByTargetGroup, GroupByAccount}
+
+var albGrouping atomic.Value
+
+// SetALBGrouping selects how ALB records are grouped into resources; the default is GroupByELB
+func SetALBGrouping(grouping string) error {
+	if !slices.Contains(ALBGroupings, grouping) {
+		return fmt.Errorf("unknown grouping %q, want one of %v", grouping, ALBGroupings)
+	}
+	albGrouping.Store(grouping)
+	return nil
+}
+
+func currentALBGrouping() string {
+	if grouping, ok := albGrouping.Load().(string); ok {
+		return grouping
+	}
+	return GroupByELB
+}
+
 func (a ALBAdapter) GetResourceKey() string {
-	arn := a.ALBLogEntry.TargetGroupARN
-	if arn == "" || arn == "-" {
-		arn = a.ALBLogEntry.ChosenCertARN
+	switch currentALBGrouping() {
+	case GroupByTargetGroup:
+		// Redirects and fixed responses are answered by the load balancer without a target group
+		if !converter.IsPlaceholder(a.ALBLogEntry.TargetGroupARN) {
+			return a.ALBLogEntry.TargetGroupARN
+		}
+	case GroupByAccount:
+		account, region := a.cloudAccount()
+		return account + "/" + region
 	}
-	return arn
+	return a.ALBLogEntry.ELB
+}
+
+// cloudAccount returns the account and region of the entry: those of its target group or
+// certificate ARN, else those of its S3 key
+func (a ALBAdapter) cloudAccount() (string, string) {
+	for _, arn := range []string{a.ALBLogEntry.TargetGroupARN, a.ALBLogEntry.ChosenCertARN} {
+		if parts := strings.SplitN(arn, ":", 6); len(parts) >= 5 && parts[4] != "" {
+			return parts[4], parts[3]
+		}
+	}
+	return a.AccountID, a.Region
 }
 
 func (a ALBAdapter) GetResourceAttributes() []converter.OTelAttribute {
 	attrs := converter.ExtractResourceAttributes(a.ALBLogEntry)
+	if currentALBGrouping() == GroupByAccount {
+		// An account's group spans load balancers, so the name moves to each record
+		attrs = slices.DeleteFunc(attrs, func(attr converter.OTelAttribute) bool { return attr.Key == "aws.lb.name" })
+	}
 
 	// Check if cloud attributes are missing and fill from S3 key context
 	hasAccount := false
@@ -82,5 +143,10 @@ func (a ALBAdapter) GetResourceAttributes() []converter.OTelAttribute {
 }
 
 func (a ALBAdapter) ToOTel() converter.OTelLogRecord {
-	return converter.ConvertToOTel(a.ALBLogEntry)
+	record := converter.ConvertToOTel(a.ALBLogEntry)
+	if currentALBGrouping() == GroupByAccount && !converter.IsPlaceholder(a.ALBLogEntry.ELB) {
+		elb := a.ALBLogEntry.ELB
+		record.Attributes = append(record.Attributes, converter.OTelAttribute{Key: "aws.lb.name", Value: converter.OTelAnyValue{StringValue: &elb}})
+	}
+	return record
 }
@@ -0,0 +1,61 @@
+package processor
+
+import (
+	"testing"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/parser"
+)
+
+func TestALBAdapter_GetResourceKey(t *testing.T) {
+	t.Cleanup(func() { SetALBGrouping(GroupByELB) })
+
+	tg := "arn:aws:elasticloadbalancing:us-east-1:123456789012:targetgroup/web/73e2d6bc24d8a067"
+	withTG := ALBAdapter{ALBLogEntry: &parser.ALBLogEntry{ELB: "app/my-alb/50dc6c495c0c9188", TargetGroupARN: tg, ChosenCertARN: "arn:aws:acm:us-east-1:123456789012:certificate/abc"}}
+	redirect := ALBAdapter{ALBLogEntry: &parser.ALBLogEntry{ELB: "app/my-alb/50dc6c495c0c9188", TargetGroupARN: "-", ChosenCertARN: "-"}, AccountID: "123456789012", Region: "us-east-1"}
+
+	tests := []struct {
+		grouping string
+		entry    ALBAdapter
+		want     string
+	}{
+		{GroupByELB, withTG, "app/my-alb/50dc6c495c0c9188"},
+		{GroupByELB, redirect, "app/my-alb/50dc6c495c0c9188"},
+		{GroupByTargetGroup, withTG, tg},
+		{GroupByTargetGroup, redirect, "app/my-alb/50dc6c495c0c9188"},
+		{GroupByAccount, withTG, "123456789012/us-east-1"},
+		{GroupByAccount, redirect, "123456789012/us-east-1"},
+	}
+	for _, tt := range tests {
+		if err := SetALBGrouping(tt.grouping); err != nil {
+			t.Fatalf("SetALBGrouping(%q) error = %v", tt.grouping, err)
+		}
+		if got := tt.entry.GetResourceKey(); got != tt.want {
+			t.Errorf("%s: GetResourceKey() = %q, want %q", tt.grouping, got, tt.want)
+		}
+	}
+
+	if err := SetALBGrouping("cert"); err == nil {
+		t.Error("SetALBGrouping(cert) succeeded")
+	}
+}
+
+func TestALBAdapter_AccountGroupingMovesLBName(t *testing.T) {
+	t.Cleanup(func() { SetALBGrouping(GroupByELB) })
+	if err := SetALBGrouping(GroupByAccount); err != nil {
+		t.Fatal(err)
+	}
+
+	entry := ALBAdapter{ALBLogEntry: &parser.ALBLogEntry{ELB: "app/my-alb/50dc6c495c0c9188"}, AccountID: "123456789012", Region: "us-east-1"}
+	for _, attr := range entry.GetResourceAttributes() {
+		if attr.Key == "aws.lb.name" {
+			t.Error("aws.lb.name is a resource attribute of an account group")
+		}
+	}
+	found := false
+	for _, attr := range entry.ToOTel().Attributes {
+		found = found || (attr.Key == "aws.lb.name" && attr.Value.Text() == "app/my-alb/50dc6c495c0c9188")
+	}
+	if !found {
+		t.Error("aws.lb.name missing from the record")
+	}
+}
@@ -0,0 +1,29 @@
+package processor
+
+import (
+	"github.com/pixelvide/otel-aws-log-parser/cmd/lambda/adapter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+)
+
+// AnonymizedAdapter pseudonymizes the client addresses of an adapter's record
+type AnonymizedAdapter struct {
+	adapter.LogAdapter
+	Anonymizer *converter.IPAnonymizer
+}
+
+func (a AnonymizedAdapter) Unwrap() adapter.LogAdapter { return a.LogAdapter }
+
+// ApplyAnonymizer wraps the entry with the anonymizer; a nil anonymizer returns the entry
+// unchanged
+func ApplyAnonymizer(entry adapter.LogAdapter, a *converter.IPAnonymizer) adapter.LogAdapter {
+	if a == nil || entry == nil {
+		return entry
+	}
+	return AnonymizedAdapter{LogAdapter: entry, Anonymizer: a}
+}
+
+func (a AnonymizedAdapter) ToOTel() converter.OTelLogRecord {
+	record := a.LogAdapter.ToOTel()
+	record.Attributes = a.Anonymizer.Records(record.Attributes)
+	return record
+}
@@ -2,85 +2,457 @@ package processor
 
 import (
 	"bufio"
-	"compress/gzip"
+	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"log/slog"
-	"strings"
+	"runtime/debug"
+	"strconv"
 	"sync"
+	"sync/atomic"
+	"time"
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/pixelvide/otel-aws-log-parser/cmd/lambda/adapter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/filter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/geoip"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/parser"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/reputation"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/tracing"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/useragent"
 )
 
 // ProcessLineFunc is a function that processes a single log line
 type ProcessLineFunc func(line string) (adapter.LogAdapter, error)
 
-// ReadAndParseFromS3 is a helper to stream and parse line-based logs
-func ReadAndParseFromS3(logger *slog.Logger, s3Client *s3.S3, bucket, key string, maxBatchSize, maxConcurrent int, parseFunc ProcessLineFunc) ([]adapter.LogAdapter, error) {
-	// Get object from S3
-	result, err := s3Client.GetObject(&s3.GetObjectInput{
-		Bucket: aws.String(bucket),
-		Key:    aws.String(key),
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to get S3 object: %w", err)
+// SourceAttributesMode controls where source provenance attributes are attached
+type SourceAttributesMode string
+
+const (
+	SourceAttributesOff      SourceAttributesMode = "off"
+	SourceAttributesResource SourceAttributesMode = "resource"
+	SourceAttributesRecord   SourceAttributesMode = "record"
+)
+
+// SourceLinkMode controls how records link back to their raw line in the source object
+type SourceLinkMode string
+
+const (
+	SourceLinkOff SourceLinkMode = "off"
+	// SourceLinkURI attaches the s3:// URI of the object plus the line's byte offset and length
+	SourceLinkURI SourceLinkMode = "uri"
+	// SourceLinkPresigned additionally attaches a short-lived presigned GET URL for the object
+	SourceLinkPresigned SourceLinkMode = "presigned"
+)
+
+// DefaultSourceLinkTTL is the presigned URL validity used when none is configured
+const DefaultSourceLinkTTL = 15 * time.Minute
+
+// Default line buffer limits used when a processor does not configure its own
+const (
+	DefaultScannerBufferBytes = 64 * 1024
+	DefaultMaxLineBytes       = 1024 * 1024
+)
+
+// ReadOptions controls how ReadAndParseObject streams and parses an object
+type ReadOptions struct {
+	MaxBatchSize  int
+	MaxConcurrent int
+	// ScannerBufferBytes is the initial read buffer size for the line splitter
+	ScannerBufferBytes int
+	// MaxLineBytes is the longest line accepted; longer lines are skipped
+	MaxLineBytes int
+	// RecordSequence tags every entry with its line number in the source object
+	RecordSequence bool
+	// Ordered hands entries to emit in the order of their lines in the object. Lines are still
+	// parsed in parallel, but a slow line holds back the ones after it, and at most
+	// 2 × max(MaxBatchSize, MaxConcurrent) lines are in flight, which lowers throughput.
+	Ordered bool
+	// SourceAttributes attaches the originating bucket, key and last-modified time
+	SourceAttributes SourceAttributesMode
+	// GroupKey overrides the resource grouping key; nil keeps the processor's default
+	GroupKey *GroupKey
+	// SourceLink attaches a pointer back to each record's raw line
+	SourceLink SourceLinkMode
+	// SourceLinkTTL is the validity of presigned URLs (SourceLinkPresigned)
+	SourceLinkTTL time.Duration
+	// Limits rejects objects that are too large or too old before any line is read
+	Limits ObjectLimits
+	// Stats, when set, accumulates line counts and timings of every object read
+	Stats *ReadStats
+	// Filter drops records or routes them to some exporters only; see package filter
+	Filter filter.Rules
+	// Sampler, when set, keeps a fraction of the records of each HTTP status class
+	Sampler *RecordSampler
+	// GeoIP, when set, enriches records with the location and network of client.address
+	GeoIP *geoip.DB
+	// Threats, when set, flags client addresses found on IP reputation lists
+	Threats *reputation.Feed
+	// UserAgents, when set, enriches records with the parsed user_agent.original
+	UserAgents *useragent.Cache
+	// Tags, when set, adds the tags of ALB and NLB load balancers and target groups to the
+	// resource attributes; it has no effect with a GroupKey
+	Tags *ResourceTags
+	// Distributions, when set, adds CloudFront distribution metadata to the resource attributes
+	// of standard CloudFront logs
+	Distributions *Distributions
+	// Anonymizer, when set, pseudonymizes client addresses after enrichment has used them
+	Anonymizer *converter.IPAnonymizer
+	// Mapping drops, renames and adds attributes of every record
+	Mapping *converter.AttributeMapping
+	// Body replaces the converter's record body; nil keeps it
+	Body *BodyFormat
+	// Identity overrides the service and scope the records are attributed to
+	Identity *Identity
+}
+
+// ObjectSource identifies the S3 object a record was read from
+type ObjectSource struct {
+	Bucket       string
+	Key          string
+	LastModified time.Time
+	// PresignedURL is a short-lived GET URL for the object, set only for SourceLinkPresigned
+	PresignedURL string
+}
+
+func (s ObjectSource) attributes() []converter.OTelAttribute {
+	attrs := []converter.OTelAttribute{
+		{Key: "aws.s3.bucket", Value: converter.OTelAnyValue{StringValue: aws.String(s.Bucket)}},
+		{Key: "aws.s3.key", Value: converter.OTelAnyValue{StringValue: aws.String(s.Key)}},
 	}
-	defer result.Body.Close()
+	if !s.LastModified.IsZero() {
+		attrs = append(attrs, converter.OTelAttribute{Key: "aws.s3.last_modified", Value: converter.OTelAnyValue{StringValue: aws.String(s.LastModified.UTC().Format(time.RFC3339))}})
+	}
+	return attrs
+}
+
+// SourceAdapter wraps an adapter with the provenance of the object it was read from
+type SourceAdapter struct {
+	adapter.LogAdapter
+	Source ObjectSource
+	Mode   SourceAttributesMode
+}
 
-	var reader io.Reader = result.Body
+func (a SourceAdapter) Unwrap() adapter.LogAdapter { return a.LogAdapter }
 
-	// Handle gzip compression
-	if strings.HasSuffix(key, ".gz") {
-		gzReader, err := gzip.NewReader(result.Body)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create gzip reader: %w", err)
+func (a SourceAdapter) GetResourceKey() string {
+	if a.Mode == SourceAttributesResource {
+		// Records from different objects carry different resource attributes
+		return a.LogAdapter.GetResourceKey() + "|s3://" + a.Source.Bucket + "/" + a.Source.Key
+	}
+	return a.LogAdapter.GetResourceKey()
+}
+
+func (a SourceAdapter) GetResourceAttributes() []converter.OTelAttribute {
+	attrs := a.LogAdapter.GetResourceAttributes()
+	if a.Mode == SourceAttributesResource {
+		attrs = append(attrs, a.Source.attributes()...)
+	}
+	return attrs
+}
+
+func (a SourceAdapter) ToOTel() converter.OTelLogRecord {
+	record := a.LogAdapter.ToOTel()
+	if a.Mode == SourceAttributesRecord {
+		record.Attributes = append(record.Attributes, a.Source.attributes()...)
+	}
+	return record
+}
+
+// decorate applies the optional per-record wrappers configured in opts. Entries the filter or
+// sampler drop come back nil, with the verdict saying which.
+func (opts ReadOptions) decorate(entry adapter.LogAdapter, src ObjectSource, line numberedLine) (adapter.LogAdapter, Verdict) {
+	adm := Admit(opts.Filter, opts.Sampler, entry)
+	if adm.Verdict != Admitted {
+		return nil, adm.Verdict
+	}
+	if opts.GroupKey != nil {
+		entry = NewGroupKeyAdapter(entry, opts.GroupKey)
+	} else {
+		// A group key keeps only the resource attributes its whole group shares, never tags
+		entry = ApplyResourceTags(entry, opts.Tags)
+	}
+	if opts.RecordSequence {
+		entry = SequencedAdapter{LogAdapter: entry, Sequence: line.num}
+	}
+	if opts.SourceLink == SourceLinkURI || opts.SourceLink == SourceLinkPresigned {
+		entry = SourceLinkAdapter{LogAdapter: entry, Source: src, Offset: line.offset, Length: int64(len(line.text))}
+	}
+	if opts.SourceAttributes == SourceAttributesResource || opts.SourceAttributes == SourceAttributesRecord {
+		entry = SourceAdapter{LogAdapter: entry, Source: src, Mode: opts.SourceAttributes}
+	}
+	entry = adm.Apply(entry)
+	entry = ApplyGeoIP(entry, opts.GeoIP)
+	entry = ApplyThreatIntel(entry, opts.Threats)
+	entry = ApplyUserAgents(entry, opts.UserAgents)
+	entry = ApplyAnonymizer(entry, opts.Anonymizer)
+	entry = ApplyMapping(entry, opts.Mapping)
+	entry = opts.Body.Apply(entry, line.text)
+	// Outermost, so the scope stays visible to the sink
+	return opts.Identity.Apply(entry), Admitted
+}
+
+// SequencedAdapter wraps an adapter with the position of its record in the source object
+type SequencedAdapter struct {
+	adapter.LogAdapter
+	Sequence int64
+}
+
+func (a SequencedAdapter) Unwrap() adapter.LogAdapter { return a.LogAdapter }
+
+func (a SequencedAdapter) ToOTel() converter.OTelLogRecord {
+	record := a.LogAdapter.ToOTel()
+	seq := strconv.FormatInt(a.Sequence, 10)
+	record.Attributes = append(record.Attributes, converter.OTelAttribute{Key: "log.record.sequence", Value: converter.OTelAnyValue{IntValue: &seq}})
+	return record
+}
+
+// SourceLinkAdapter wraps an adapter with a pointer to its raw line: the object URI, the byte
+// offset and length of the line (in the decompressed content for compressed objects) and, when
+// available, a presigned URL for fetching the object.
+type SourceLinkAdapter struct {
+	adapter.LogAdapter
+	Source ObjectSource
+	Offset int64
+	Length int64
+}
+
+func (a SourceLinkAdapter) Unwrap() adapter.LogAdapter { return a.LogAdapter }
+
+func (a SourceLinkAdapter) ToOTel() converter.OTelLogRecord {
+	record := a.LogAdapter.ToOTel()
+	offset := strconv.FormatInt(a.Offset, 10)
+	length := strconv.FormatInt(a.Length, 10)
+	record.Attributes = append(record.Attributes,
+		converter.OTelAttribute{Key: "log.record.source.uri", Value: converter.OTelAnyValue{StringValue: aws.String("s3://" + a.Source.Bucket + "/" + a.Source.Key)}},
+		converter.OTelAttribute{Key: "log.record.source.offset", Value: converter.OTelAnyValue{IntValue: &offset}},
+		converter.OTelAttribute{Key: "log.record.source.length", Value: converter.OTelAnyValue{IntValue: &length}},
+	)
+	if a.Source.PresignedURL != "" {
+		record.Attributes = append(record.Attributes, converter.OTelAttribute{Key: "log.record.source.url", Value: converter.OTelAnyValue{StringValue: aws.String(a.Source.PresignedURL)}})
+	}
+	return record
+}
+
+// numberedLine is a raw line paired with its 1-based line number and starting byte offset
+type numberedLine struct {
+	text   string
+	num    int64
+	offset int64
+	// seq counts the lines handed to the workers, without gaps, for Ordered
+	seq int64
+}
+
+// ReadAndParseObject is a helper to stream and parse line-based logs
+func ReadAndParseObject(ctx context.Context, logger *slog.Logger, store ObjectStore, bucket, key string, opts ReadOptions, parseFunc ProcessLineFunc) ([]adapter.LogAdapter, error) {
+	entries := make([]adapter.LogAdapter, 0)
+	err := StreamAndParseObject(ctx, logger, store, bucket, key, opts, parseFunc, func(entry adapter.LogAdapter) error {
+		entries = append(entries, entry)
+		return nil
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// parseLine parses and decorates one line, adding the time spent in parseFunc to parseTime. A
+// panic in the parser or an enrichment is returned as an error, so that one malformed record
+// counts as a parse failure instead of crashing the process from a worker goroutine.
+func parseLine(logger *slog.Logger, parseFunc ProcessLineFunc, opts ReadOptions, src ObjectSource, line numberedLine, parseTime *time.Duration) (entry adapter.LogAdapter, verdict Verdict, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error("Recovered from panic while parsing line", "line", line.num, "panic", r, "stack", string(debug.Stack()))
+			entry, verdict, err = nil, Admitted, fmt.Errorf("line %d: panic: %v", line.num, r)
 		}
-		defer gzReader.Close()
-		reader = gzReader
+	}()
+
+	start := time.Now()
+	entry, err = parseFunc(line.text)
+	*parseTime += time.Since(start)
+	if err != nil || entry == nil {
+		return nil, Admitted, err
 	}
+	entry, verdict = opts.decorate(entry, src, line)
+	return entry, verdict, nil
+}
+
+// StreamAndParseObject parses a line-based object and hands each entry to emit as soon as it
+// is parsed. emit is called from a single goroutine; while it blocks, the bounded line and
+// entry channels fill up and reading from S3 pauses, so memory stays flat regardless of the
+// object size. A non-nil error from emit stops the read and is returned.
+//
+// With a non-nil cp, the first cp.Lines lines are skipped and cp.Save is called each time
+// another cp.Every lines have been fully handed to emit.
+func StreamAndParseObject(ctx context.Context, logger *slog.Logger, store ObjectStore, bucket, key string, opts ReadOptions, parseFunc ProcessLineFunc, emit EmitFunc, cp *Checkpoint) error {
+	getCtx, get := tracing.Start(ctx, "s3.get_object")
+	get.SetKind(converter.SpanKindClient)
+	obj, err := store.GetObject(getCtx, bucket, key)
+	get.SetError(err)
+	get.End()
+	if err != nil {
+		return err
+	}
+	defer obj.Body.Close()
+
+	if err := opts.Limits.Check(obj, time.Now()); err != nil {
+		return err
+	}
+
+	// Zero-byte objects (folder markers, truncated uploads) have no lines to read
+	if obj.Size == 0 {
+		logger.Info("Skipping empty object", "bucket", bucket, "key", key)
+		return nil
+	}
+
+	src := ObjectSource{Bucket: bucket, Key: key, LastModified: obj.LastModified}
+	if opts.SourceLink == SourceLinkPresigned {
+		src.PresignedURL = presignSource(logger, store, bucket, key, opts.SourceLinkTTL)
+	}
+
+	// Reading, decompressing and parsing overlap, so they share one span; the time spent
+	// waiting on the body and in the parser are attributes
+	_, span := tracing.Start(ctx, "parse")
+	body := &timedReader{r: obj.Body}
+	var counts ReadCounts
+	var parseNanos atomic.Int64
+	defer func() {
+		counts.Objects = 1
+		counts.Bytes = body.bytes
+		counts.DownloadTime = body.elapsed
+		counts.ParseTime = time.Duration(parseNanos.Load())
+		opts.Stats.Add(counts)
+		objectStats(ctx).Add(counts)
+
+		span.SetInt("object.size", obj.Size)
+		span.SetInt("lines", counts.Lines)
+		span.SetInt("parse_failures", counts.ParseFailures)
+		span.SetInt("entries", counts.Entries)
+		span.SetInt("filtered", counts.Filtered)
+		span.SetInt("sampled_out", counts.SampledOut)
+		span.SetInt("download.wait_ms", counts.DownloadTime.Milliseconds())
+		span.SetInt("parse.busy_ms", counts.ParseTime.Milliseconds())
+		span.End()
+	}()
+
+	// Compression is detected from the content, since shippers recompress without renaming
+	reader, err := parser.Decompress(body)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	// Cancelled when emit fails so the reader and workers stop early
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
 	// Create channels for parallel processing
-	linesChan := make(chan string, maxBatchSize)
-	entriesChan := make(chan adapter.LogAdapter, maxBatchSize)
+	linesChan := make(chan numberedLine, opts.MaxBatchSize)
+	entriesChan := make(chan parsedLine, opts.MaxBatchSize)
 	var wg sync.WaitGroup
 
+	// The watermark tracks how far the object has been handed over, despite parallel workers
+	var resumeAt int64
+	if cp != nil {
+		resumeAt = cp.Lines
+		logger.Info("Resuming object from checkpoint", "lines", resumeAt)
+	}
+	wm := newLineWatermark(resumeAt)
+
 	// Start workers
-	numWorkers := maxConcurrent
+	numWorkers := opts.MaxConcurrent
 	if numWorkers < 1 {
 		numWorkers = 1
 	}
 
+	// In order, the reader waits for a slot before handing over a line, which bounds the
+	// entries held back behind a slow line
+	var window chan struct{}
+	if opts.Ordered {
+		window = make(chan struct{}, 2*max(opts.MaxBatchSize, numWorkers))
+	}
+
+	var parseFailures atomic.Int64
+	// Entries dropped by filter rules and by sampling, indexed by verdict
+	var dropped [SampledOut + 1]atomic.Int64
 	for i := 0; i < numWorkers; i++ {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
+			var parseTime time.Duration
+			defer func() { parseNanos.Add(int64(parseTime)) }()
 			for line := range linesChan {
-				if line == "" {
-					continue
+				var entry adapter.LogAdapter
+				if line.text != "" {
+					var verdict Verdict
+					var err error
+					entry, verdict, err = parseLine(logger, parseFunc, opts, src, line, &parseTime)
+					if err != nil {
+						parseFailures.Add(1)
+					}
+					if verdict != Admitted {
+						dropped[verdict].Add(1)
+					}
+				}
+				if entry == nil {
+					wm.complete(line.num)
+					// In order, the emitter also waits for the lines that produced no entry
+					if !opts.Ordered {
+						continue
+					}
 				}
-				entry, err := parseFunc(line)
-				if err == nil && entry != nil {
-					entriesChan <- entry
+				select {
+				case entriesChan <- parsedLine{entry: entry, num: line.num, seq: line.seq}:
+				case <-streamCtx.Done():
 				}
 			}
 		}()
 	}
 
-	// Start a goroutine to read lines and send to workers
+	// Start a goroutine to read lines and send to workers. A read error is kept in readErr,
+	// which is safe to use once entriesChan is closed.
+	var readErr error
 	go func() {
-		scanner := bufio.NewScanner(reader)
-		// Increase buffer size
-		buf := make([]byte, 0, 64*1024)
-		scanner.Buffer(buf, 1024*1024)
+		prev := int64(0)
+		seq := int64(0)
+		skipped, err := scanLines(reader, opts.ScannerBufferBytes, opts.MaxLineBytes, func(text string, num, offset int64) error {
+			// Lines dropped by scanLines for being too long never reach a worker
+			for n := prev + 1; n < num; n++ {
+				if n > resumeAt {
+					wm.complete(n)
+				}
+			}
+			prev = num
+			if num <= resumeAt {
+				return nil
+			}
+			counts.Lines++
+			if window != nil {
+				select {
+				case window <- struct{}{}:
+				case <-streamCtx.Done():
+					return streamCtx.Err()
+				}
+			}
+			select {
+			case linesChan <- numberedLine{text: text, num: num, offset: offset, seq: seq}:
+				seq++
+				return nil
+			case <-streamCtx.Done():
+				return streamCtx.Err()
+			}
+		})
 
-		for scanner.Scan() {
-			linesChan <- scanner.Text()
+		if skipped > 0 {
+			logger.Warn("Skipped lines exceeding max line length", "skipped", skipped, "max_line_bytes", opts.MaxLineBytes)
+			counts.Lines += int64(skipped)
+			parseFailures.Add(int64(skipped))
 		}
-
-		if err := scanner.Err(); err != nil {
+		if err != nil && streamCtx.Err() == nil {
 			logger.Error("Error scanning S3 object", "error", err)
+			readErr = err
 		}
 
 		close(linesChan)
@@ -92,12 +464,191 @@ func ReadAndParseFromS3(logger *slog.Logger, s3Client *s3.S3, bucket, key string
 		close(entriesChan)
 	}()
 
-	// Collect results
-	entries := make([]adapter.LogAdapter, 0)
-	for entry := range entriesChan {
-		entries = append(entries, entry)
+	// Hand entries over until the workers finish; after a failure keep draining so they exit
+	var emitErr error
+	count := 0
+	saved := resumeAt
+	// The reader and workers have finished once entriesChan is closed, so their counts are final
+	defer func() {
+		counts.ParseFailures = parseFailures.Load()
+		counts.Filtered = dropped[Filtered].Load()
+		counts.SampledOut = dropped[SampledOut].Load()
+		counts.Entries = int64(count)
+	}()
+	handOver := func(parsed parsedLine) {
+		if emitErr != nil {
+			return
+		}
+		if err := emit(parsed.entry); err != nil {
+			emitErr = err
+			cancel()
+			return
+		}
+		count++
+
+		done := wm.complete(parsed.num)
+		if cp != nil && cp.Every > 0 && done-saved >= cp.Every {
+			if err := cp.Save(ctx, done); err != nil {
+				logger.Warn("Failed to save checkpoint", "lines", done, "error", err)
+			} else {
+				saved = done
+			}
+		}
 	}
 
-	logger.Info("Parsed entries", "count", len(entries))
-	return entries, nil
+	// In order, lines that overtook an earlier one wait here until it has been handed over
+	var next int64
+	early := make(map[int64]parsedLine)
+	for parsed := range entriesChan {
+		if !opts.Ordered {
+			handOver(parsed)
+			continue
+		}
+		early[parsed.seq] = parsed
+		for {
+			parsed, ok := early[next]
+			if !ok {
+				break
+			}
+			delete(early, next)
+			next++
+			<-window
+			if parsed.entry != nil {
+				handOver(parsed)
+			}
+		}
+	}
+
+	if emitErr != nil {
+		return emitErr
+	}
+	if ctx.Err() != nil {
+		return context.Cause(ctx)
+	}
+	// The lines after a failed read were never seen, so the object is not complete
+	if readErr != nil {
+		return fmt.Errorf("reading object: %w", readErr)
+	}
+
+	logger.Info("Parsed entries", "count", count)
+	return nil
+}
+
+// parsedLine is a decorated entry paired with the line it was parsed from. In ordered reads,
+// lines without an entry are passed on with a nil entry to keep the sequence whole.
+type parsedLine struct {
+	entry adapter.LogAdapter
+	num   int64
+	seq   int64
+}
+
+// lineWatermark tracks the highest line number below which every line has been handled,
+// while workers complete lines out of order
+type lineWatermark struct {
+	mu      sync.Mutex
+	done    int64
+	pending map[int64]bool
+}
+
+func newLineWatermark(start int64) *lineWatermark {
+	return &lineWatermark{done: start, pending: make(map[int64]bool)}
+}
+
+// complete marks a line as handled and returns the new watermark
+func (w *lineWatermark) complete(num int64) int64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.pending[num] = true
+	for w.pending[w.done+1] {
+		delete(w.pending, w.done+1)
+		w.done++
+	}
+	return w.done
+}
+
+// presignSource returns a presigned URL for the object, or "" when the store cannot presign
+func presignSource(logger *slog.Logger, store ObjectStore, bucket, key string, ttl time.Duration) string {
+	presigner, ok := store.(ObjectPresigner)
+	if !ok {
+		return ""
+	}
+	if ttl <= 0 {
+		ttl = DefaultSourceLinkTTL
+	}
+	url, err := presigner.PresignGetObject(bucket, key, ttl)
+	if err != nil {
+		logger.Warn("Failed to presign source object", "bucket", bucket, "key", key, "error", err)
+		return ""
+	}
+	return url
+}
+
+// scanLines splits r into newline-delimited lines and calls emit with each line, its
+// 1-based line number and the byte offset where it starts. Unlike bufio.Scanner, a line
+// longer than maxLine does not abort the scan: it is discarded, counted in skipped, and
+// reading resumes with the next line. A non-nil error from emit stops the scan.
+func scanLines(r io.Reader, bufSize, maxLine int, emit func(text string, num, offset int64) error) (skipped int, err error) {
+	if bufSize <= 0 {
+		bufSize = DefaultScannerBufferBytes
+	}
+	if maxLine <= 0 {
+		maxLine = DefaultMaxLineBytes
+	}
+
+	br := bufio.NewReaderSize(r, bufSize)
+	var (
+		line      []byte
+		lineNum   int64
+		lineStart int64
+		consumed  int64
+		tooLong   bool
+	)
+
+	flush := func() error {
+		lineNum++
+		var err error
+		if tooLong {
+			skipped++
+		} else {
+			line = bytes.TrimSuffix(line, []byte("\r"))
+			err = emit(string(line), lineNum, lineStart)
+		}
+		line = line[:0]
+		tooLong = false
+		lineStart = consumed
+		return err
+	}
+
+	for {
+		chunk, readErr := br.ReadSlice('\n')
+		consumed += int64(len(chunk))
+		chunk = bytes.TrimSuffix(chunk, []byte("\n"))
+
+		if !tooLong {
+			if len(line)+len(chunk) > maxLine {
+				tooLong = true
+				line = line[:0]
+			} else {
+				line = append(line, chunk...)
+			}
+		}
+
+		switch readErr {
+		case nil:
+			if err := flush(); err != nil {
+				return skipped, err
+			}
+		case bufio.ErrBufferFull:
+			// Line continues beyond the buffer; keep accumulating
+		case io.EOF:
+			if len(line) > 0 || tooLong {
+				if err := flush(); err != nil {
+					return skipped, err
+				}
+			}
+			return skipped, nil
+		default:
+			return skipped, readErr
+		}
+	}
 }
@@ -0,0 +1,438 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/pixelvide/otel-aws-log-parser/cmd/lambda/adapter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/parser"
+)
+
+func TestScanLines(t *testing.T) {
+	input := "first\r\n" + strings.Repeat("x", 40) + "\nthird\n\nlast-without-newline"
+
+	var got []string
+	var nums, offsets []int64
+	skipped, err := scanLines(strings.NewReader(input), 16, 32, func(text string, num, offset int64) error {
+		got = append(got, text)
+		nums = append(nums, num)
+		offsets = append(offsets, offset)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("scanLines() error = %v", err)
+	}
+
+	want := []string{"first", "third", "", "last-without-newline"}
+	wantNums := []int64{1, 3, 4, 5}
+	wantOffsets := []int64{0, 48, 54, 55}
+
+	if skipped != 1 {
+		t.Errorf("skipped = %d, want 1", skipped)
+	}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("lines = %q, want %q", got, want)
+	}
+	for i := range wantNums {
+		if i < len(nums) && nums[i] != wantNums[i] {
+			t.Errorf("line %d number = %d, want %d", i, nums[i], wantNums[i])
+		}
+		if i < len(offsets) && offsets[i] != wantOffsets[i] {
+			t.Errorf("line %d offset = %d, want %d", i, offsets[i], wantOffsets[i])
+		}
+	}
+}
+
+func TestScanLines_LongLineAtEOF(t *testing.T) {
+	var got []string
+	skipped, err := scanLines(strings.NewReader("ok\n"+strings.Repeat("y", 100)), 16, 32, func(text string, num, offset int64) error {
+		got = append(got, text)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("scanLines() error = %v", err)
+	}
+	if skipped != 1 || len(got) != 1 || got[0] != "ok" {
+		t.Errorf("scanLines() = %q (skipped %d), want [ok] (skipped 1)", got, skipped)
+	}
+}
+
+func TestStreamAndParseObject_EmitErrorStops(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, "bucket", "big.log")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(strings.Repeat("line\n", 10000)), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	opts := ReadOptions{MaxBatchSize: 10, MaxConcurrent: 2}
+	parse := func(line string) (adapter.LogAdapter, error) {
+		return NLBAdapter{}, nil
+	}
+
+	emitted := 0
+	errStop := errors.New("stop")
+	err := StreamAndParseObject(context.Background(), logger, &LocalStore{Root: root}, "bucket", "big.log", opts, parse, func(adapter.LogAdapter) error {
+		emitted++
+		if emitted == 5 {
+			return errStop
+		}
+		return nil
+	}, nil)
+	if !errors.Is(err, errStop) {
+		t.Fatalf("StreamAndParseObject() error = %v, want %v", err, errStop)
+	}
+	if emitted != 5 {
+		t.Errorf("emit called %d times after failing, want 5", emitted)
+	}
+
+	emitted = 0
+	err = StreamAndParseObject(context.Background(), logger, &LocalStore{Root: root}, "bucket", "big.log", opts, parse, func(adapter.LogAdapter) error {
+		emitted++
+		return nil
+	}, nil)
+	if err != nil || emitted != 10000 {
+		t.Errorf("StreamAndParseObject() = %v with %d entries, want 10000", err, emitted)
+	}
+}
+
+func TestStreamAndParseObject_EmptyObject(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, "bucket", "empty.log.gz")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	opts := ReadOptions{MaxBatchSize: 10, MaxConcurrent: 2}
+	parse := func(line string) (adapter.LogAdapter, error) {
+		return NLBAdapter{}, nil
+	}
+
+	err := StreamAndParseObject(context.Background(), logger, &LocalStore{Root: root}, "bucket", "empty.log.gz", opts, parse, func(adapter.LogAdapter) error {
+		t.Error("emit called for an empty object")
+		return nil
+	}, nil)
+	if err != nil {
+		t.Errorf("StreamAndParseObject() error = %v, want empty gzip object skipped", err)
+	}
+}
+
+// truncatedStore serves an object whose body fails after content
+type truncatedStore struct {
+	content string
+	err     error
+}
+
+func (s truncatedStore) GetObject(ctx context.Context, bucket, key string) (*Object, error) {
+	body := io.MultiReader(strings.NewReader(s.content), iotest.ErrReader(s.err))
+	return &Object{Body: io.NopCloser(body), Size: int64(len(s.content)) * 2}, nil
+}
+
+func TestStreamAndParseObject_ReadError(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	opts := ReadOptions{MaxBatchSize: 10, MaxConcurrent: 2}
+	parse := func(line string) (adapter.LogAdapter, error) {
+		return NLBAdapter{}, nil
+	}
+
+	store := truncatedStore{content: strings.Repeat("line\n", 100), err: io.ErrUnexpectedEOF}
+	emitted := 0
+	err := StreamAndParseObject(context.Background(), logger, store, "bucket", "key", opts, parse, func(adapter.LogAdapter) error {
+		emitted++
+		return nil
+	}, nil)
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("StreamAndParseObject() error = %v, want the read error", err)
+	}
+	if emitted != 100 {
+		t.Errorf("emitted %d entries before the failure, want 100", emitted)
+	}
+}
+
+func TestStreamAndParseObject_ParserPanic(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	stats := &ReadStats{}
+	opts := ReadOptions{MaxBatchSize: 10, MaxConcurrent: 2, Stats: stats}
+	parse := func(line string) (adapter.LogAdapter, error) {
+		if line == "bad" {
+			panic("index out of range")
+		}
+		return NLBAdapter{}, nil
+	}
+
+	store := repeatStore{line: "good\nbad\ngood", n: 2}
+	emitted := 0
+	err := StreamAndParseObject(context.Background(), logger, store, "bucket", "key", opts, parse, func(adapter.LogAdapter) error {
+		emitted++
+		return nil
+	}, nil)
+	if err != nil {
+		t.Fatalf("StreamAndParseObject() error = %v", err)
+	}
+	if emitted != 4 {
+		t.Errorf("emitted %d entries, want 4", emitted)
+	}
+	if counts := stats.Take(); counts.ParseFailures != 2 {
+		t.Errorf("parse failures = %d, want the 2 panicking lines", counts.ParseFailures)
+	}
+}
+
+func TestStreamAndParseObject_Ordered(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, "bucket", "mixed.log")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	var content strings.Builder
+	var want []string
+	for i := 1; i <= 500; i++ {
+		switch {
+		case i%10 == 0:
+			content.WriteString("\n")
+		case i%7 == 0:
+			content.WriteString("drop\n")
+		default:
+			content.WriteString(strconv.Itoa(i) + "\n")
+			want = append(want, strconv.Itoa(i))
+		}
+	}
+	if err := os.WriteFile(path, []byte(content.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	opts := ReadOptions{MaxBatchSize: 4, MaxConcurrent: 8, Ordered: true}
+	parse := func(line string) (adapter.LogAdapter, error) {
+		if line == "drop" {
+			return nil, errors.New("unparsable")
+		}
+		// Lines overtake each other between workers
+		if n, _ := strconv.Atoi(line); n%3 == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		return NLBAdapter{NLBLogEntry: &parser.NLBLogEntry{ELB: line}}, nil
+	}
+
+	var got []string
+	err := StreamAndParseObject(context.Background(), logger, &LocalStore{Root: root}, "bucket", "mixed.log", opts, parse, func(entry adapter.LogAdapter) error {
+		got = append(got, entry.(NLBAdapter).ELB)
+		return nil
+	}, nil)
+	if err != nil {
+		t.Fatalf("StreamAndParseObject() error = %v", err)
+	}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("entries = %v, want source order %v", got, want)
+	}
+}
+
+func TestStreamAndParseObject_Checkpoint(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, "bucket", "big.log")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	// Every fourth line is blank and one line is too long, so the watermark has to step over lines without entries
+	var b strings.Builder
+	for i := 1; i <= 1000; i++ {
+		switch {
+		case i == 500:
+			b.WriteString(strings.Repeat("z", 100))
+		case i%4 != 0:
+			b.WriteString(strconv.Itoa(i))
+		}
+		b.WriteString("\n")
+	}
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	opts := ReadOptions{MaxBatchSize: 10, MaxConcurrent: 4, MaxLineBytes: 32}
+	var mu sync.Mutex
+	var seen []int
+	parse := func(line string) (adapter.LogAdapter, error) {
+		n, _ := strconv.Atoi(line)
+		mu.Lock()
+		seen = append(seen, n)
+		mu.Unlock()
+		return NLBAdapter{}, nil
+	}
+
+	var saves []int64
+	cp := &Checkpoint{Lines: 300, Every: 100, Save: func(ctx context.Context, lines int64) error {
+		saves = append(saves, lines)
+		return nil
+	}}
+	err := StreamAndParseObject(context.Background(), logger, &LocalStore{Root: root}, "bucket", "big.log", opts, parse, func(adapter.LogAdapter) error {
+		return nil
+	}, cp)
+	if err != nil {
+		t.Fatalf("StreamAndParseObject() error = %v", err)
+	}
+
+	for _, n := range seen {
+		if n <= 300 {
+			t.Fatalf("line %d parsed, want lines up to the checkpoint skipped", n)
+		}
+	}
+	if len(saves) < 6 || saves[len(saves)-1] < 900 {
+		t.Errorf("checkpoints saved at %v, want roughly every 100 lines up to the end", saves)
+	}
+	for i := 1; i < len(saves); i++ {
+		if saves[i]-saves[i-1] < 100 {
+			t.Errorf("checkpoints %d and %d are closer than Every", saves[i-1], saves[i])
+		}
+	}
+}
+
+func TestStreamAndParseObject_Limits(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, "bucket", "big.log")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(strings.Repeat("line\n", 100)), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	opts := ReadOptions{MaxBatchSize: 10, MaxConcurrent: 2, Limits: ObjectLimits{MaxBytes: 100}}
+	parse := func(line string) (adapter.LogAdapter, error) {
+		return NLBAdapter{}, nil
+	}
+
+	err := StreamAndParseObject(context.Background(), logger, &LocalStore{Root: root}, "bucket", "big.log", opts, parse, func(adapter.LogAdapter) error {
+		t.Error("emit called for an object over the size limit")
+		return nil
+	}, nil)
+	if !errors.Is(err, ErrObjectTooLarge) {
+		t.Errorf("StreamAndParseObject() error = %v, want ErrObjectTooLarge", err)
+	}
+}
+
+func TestStreamAndParseObject_Stats(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, "bucket", "mixed.log")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	content := "ok\nbad\nok\n\n" + strings.Repeat("x", 100) + "\nok\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	var stats ReadStats
+	opts := ReadOptions{MaxBatchSize: 10, MaxConcurrent: 2, ScannerBufferBytes: 16, MaxLineBytes: 50, Stats: &stats}
+	parse := func(line string) (adapter.LogAdapter, error) {
+		if line != "ok" {
+			return nil, errors.New("unparseable")
+		}
+		return NLBAdapter{}, nil
+	}
+
+	var object ReadStats
+	ctx := WithObjectStats(context.Background(), &object)
+	for i := 0; i < 2; i++ {
+		if err := StreamAndParseObject(ctx, logger, &LocalStore{Root: root}, "bucket", "mixed.log", opts, parse, func(adapter.LogAdapter) error { return nil }, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got := stats.Take()
+	// Per object: 6 lines, of which "bad" and the over-long line fail and the blank one is skipped
+	if got.Objects != 2 || got.Bytes != int64(2*len(content)) || got.Lines != 12 || got.ParseFailures != 4 || got.Entries != 6 {
+		t.Errorf("Take() = %+v", got)
+	}
+	if again := stats.Take(); again != (ReadCounts{}) {
+		t.Errorf("Take() after reset = %+v, want zero", again)
+	}
+	if perObject := object.Take(); perObject.Objects != 2 || perObject.Entries != 6 {
+		t.Errorf("per-object Take() = %+v, want the counts of both reads", perObject)
+	}
+}
+
+// repeatReader reads n copies of line without holding them in memory
+type repeatReader struct {
+	line   []byte
+	n, off int
+}
+
+func (r *repeatReader) Read(p []byte) (int, error) {
+	read := 0
+	for read < len(p) && r.n > 0 {
+		c := copy(p[read:], r.line[r.off:])
+		read += c
+		r.off += c
+		if r.off == len(r.line) {
+			r.off = 0
+			r.n--
+		}
+	}
+	if read == 0 {
+		return 0, io.EOF
+	}
+	return read, nil
+}
+
+// repeatStore serves every key as an object of n copies of line
+type repeatStore struct {
+	line string
+	n    int
+}
+
+func (s repeatStore) GetObject(ctx context.Context, bucket, key string) (*Object, error) {
+	line := []byte(s.line + "\n")
+	return &Object{Body: io.NopCloser(&repeatReader{line: line, n: s.n}), Size: int64(len(line)) * int64(s.n)}, nil
+}
+
+// BenchmarkStreamAndParseObject_1MLines parses and converts an ALB object of one million
+// lines, as one Lambda invocation would, and reports the allocations and collections it costs
+func BenchmarkStreamAndParseObject_1MLines(b *testing.B) {
+	const lines = 1_000_000
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := repeatStore{line: testALBLine, n: lines}
+	opts := ReadOptions{MaxBatchSize: 500, MaxConcurrent: 10}
+	parse := func(line string) (adapter.LogAdapter, error) {
+		return AdapterForLine("alb", line)
+	}
+	emit := func(entry adapter.LogAdapter) error {
+		_ = entry.ToOTel()
+		return nil
+	}
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := StreamAndParseObject(context.Background(), logger, store, "bucket", "alb.log", opts, parse, emit, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.StopTimer()
+	runtime.ReadMemStats(&after)
+
+	b.ReportMetric(float64(after.Mallocs-before.Mallocs)/float64(b.N*lines), "allocs/line")
+	b.ReportMetric(float64(after.TotalAlloc-before.TotalAlloc)/float64(b.N*lines), "B/line")
+	b.ReportMetric(float64(after.NumGC-before.NumGC)/float64(b.N), "gcs/op")
+}
@@ -0,0 +1,128 @@
+package processor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	"github.com/pixelvide/otel-aws-log-parser/cmd/lambda/adapter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+)
+
+// Log record body formats
+const (
+	// BodyDefault keeps the converter's summary line, e.g. "GET https://example.com/ HTTP/1.1"
+	BodyDefault = "default"
+	// BodyRaw is the original log line
+	BodyRaw = "raw"
+	// BodyKVMap is a map body holding the record's attributes
+	BodyKVMap = "kvmap"
+	// BodyJSON is the record's attributes as a JSON object string
+	BodyJSON = "json"
+	// BodyTemplate renders a Go template (see BodyTemplateData)
+	BodyTemplate = "template"
+)
+
+// BodyFormats lists the accepted LOG_BODY_FORMAT values
+var BodyFormats = []string{BodyDefault, BodyRaw, BodyKVMap, BodyJSON, BodyTemplate}
+
+// BodyFormat selects how the body of each record is built
+type BodyFormat struct {
+	Mode     string
+	Template *template.Template
+	// Anonymizer, when set, scrubs the addresses of the original line in raw and template bodies
+	Anonymizer *converter.IPAnonymizer
+}
+
+// ParseBodyFormat validates a body format; tmpl is only used (and required) for BodyTemplate.
+// The default format returns nil.
+func ParseBodyFormat(mode, tmpl string) (*BodyFormat, error) {
+	mode = strings.ToLower(mode)
+	switch mode {
+	case "", BodyDefault:
+		return nil, nil
+	case BodyRaw, BodyKVMap, BodyJSON:
+		return &BodyFormat{Mode: mode}, nil
+	case BodyTemplate:
+		if tmpl == "" {
+			return nil, fmt.Errorf("body format %q needs a template", mode)
+		}
+		t, err := template.New("body").Option("missingkey=zero").Parse(tmpl)
+		if err != nil {
+			return nil, fmt.Errorf("invalid body template: %w", err)
+		}
+		return &BodyFormat{Mode: mode, Template: t}, nil
+	}
+	return nil, fmt.Errorf("unknown body format %q (want one of %s)", mode, strings.Join(BodyFormats, ", "))
+}
+
+// Apply wraps the entry so its record gets this body; raw is the entry's original log line.
+// A nil format returns the entry unchanged.
+func (f *BodyFormat) Apply(entry adapter.LogAdapter, raw string) adapter.LogAdapter {
+	if f == nil || entry == nil {
+		return entry
+	}
+	return BodyAdapter{LogAdapter: entry, Format: f, Raw: raw}
+}
+
+// BodyTemplateData is what a body template is executed with, e.g.
+// `{{.Attr "http.request.method"}} {{.Attr "url.path"}} -> {{.Attr "http.response.status_code"}}`
+type BodyTemplateData struct {
+	// Body is the converter's default body
+	Body string
+	// Raw is the original log line
+	Raw          string
+	SeverityText string
+	Attributes   map[string]any
+}
+
+// Attr returns a record attribute, or "" when the record does not have it
+func (d BodyTemplateData) Attr(key string) any {
+	if v, ok := d.Attributes[key]; ok {
+		return v
+	}
+	return ""
+}
+
+// BodyAdapter replaces the body of an adapter's record according to a BodyFormat
+type BodyAdapter struct {
+	adapter.LogAdapter
+	Format *BodyFormat
+	Raw    string
+}
+
+func (a BodyAdapter) Unwrap() adapter.LogAdapter { return a.LogAdapter }
+
+// raw is the original line with its sensitive parameters redacted and addresses scrubbed
+func (a BodyAdapter) raw() string {
+	return a.Format.Anonymizer.Scrub(converter.ActiveRedactor().Text(a.Raw))
+}
+
+func (a BodyAdapter) ToOTel() converter.OTelLogRecord {
+	record := a.LogAdapter.ToOTel()
+	switch a.Format.Mode {
+	case BodyRaw:
+		record.Body = converter.OTelAnyValue{StringValue: aws.String(a.raw())}
+	case BodyKVMap:
+		values := append([]converter.OTelAttribute(nil), record.Attributes...)
+		record.Body = converter.OTelAnyValue{KvlistValue: &converter.OTelKeyValueList{Values: values}}
+	case BodyJSON:
+		list := converter.OTelKeyValueList{Values: record.Attributes}
+		if out, err := json.Marshal(list.Plain()); err == nil {
+			record.Body = converter.OTelAnyValue{StringValue: aws.String(string(out))}
+		}
+	case BodyTemplate:
+		list := converter.OTelKeyValueList{Values: record.Attributes}
+		data := BodyTemplateData{Body: record.Body.Text(), Raw: a.raw(), SeverityText: record.SeverityText, Attributes: list.Plain()}
+		var buf bytes.Buffer
+		// A record the template fails on keeps its default body
+		if err := a.Format.Template.Execute(&buf, data); err == nil {
+			record.Body = converter.OTelAnyValue{StringValue: aws.String(buf.String())}
+		}
+	}
+	return record
+}
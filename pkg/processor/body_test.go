@@ -0,0 +1,102 @@
+package processor
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+)
+
+func TestBodyFormat(t *testing.T) {
+	line := testALBLine
+	entry, err := AdapterForLine(FormatAuto, line)
+	if err != nil {
+		t.Fatalf("AdapterForLine() error = %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		mode  string
+		tmpl  string
+		check func(t *testing.T, body converter.OTelAnyValue)
+	}{
+		{"raw", BodyRaw, "", func(t *testing.T, body converter.OTelAnyValue) {
+			if body.Text() != line {
+				t.Errorf("body = %q, want the raw line", body.Text())
+			}
+		}},
+		{"kvmap", BodyKVMap, "", func(t *testing.T, body converter.OTelAnyValue) {
+			if body.KvlistValue == nil {
+				t.Fatalf("body = %+v, want a kvlist", body)
+			}
+			if got := body.KvlistValue.Plain()["http.response.status_code"]; got != int64(200) {
+				t.Errorf("status in body = %v, want 200", got)
+			}
+		}},
+		{"json", BodyJSON, "", func(t *testing.T, body converter.OTelAnyValue) {
+			var got map[string]any
+			if err := json.Unmarshal([]byte(body.Text()), &got); err != nil {
+				t.Fatalf("body %q is not JSON: %v", body.Text(), err)
+			}
+			if got["http.request.method"] != "GET" {
+				t.Errorf("method in body = %v, want GET", got["http.request.method"])
+			}
+		}},
+		{"template", BodyTemplate, `{{.SeverityText}} {{.Attr "http.request.method"}} {{.Attr "url.path"}} {{.Attr "http.response.status_code"}}{{.Attr "missing"}}`, func(t *testing.T, body converter.OTelAnyValue) {
+			if want := "INFO GET / 200"; body.Text() != want {
+				t.Errorf("body = %q, want %q", body.Text(), want)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format, err := ParseBodyFormat(tt.mode, tt.tmpl)
+			if err != nil {
+				t.Fatalf("ParseBodyFormat() error = %v", err)
+			}
+			tt.check(t, format.Apply(entry, line).ToOTel().Body)
+		})
+	}
+}
+
+func TestParseBodyFormat(t *testing.T) {
+	if f, err := ParseBodyFormat("", ""); f != nil || err != nil {
+		t.Errorf("ParseBodyFormat(\"\") = %v, %v, want nil, nil", f, err)
+	}
+	if f, err := ParseBodyFormat("DEFAULT", ""); f != nil || err != nil {
+		t.Errorf("ParseBodyFormat(DEFAULT) = %v, %v, want nil, nil", f, err)
+	}
+	for _, tt := range []struct{ mode, tmpl string }{
+		{"xml", ""},
+		{BodyTemplate, ""},
+		{BodyTemplate, "{{.Attr"},
+	} {
+		if _, err := ParseBodyFormat(tt.mode, tt.tmpl); err == nil {
+			t.Errorf("ParseBodyFormat(%q, %q) succeeded, want error", tt.mode, tt.tmpl)
+		}
+	}
+}
+
+func TestBodyFormat_Anonymized(t *testing.T) {
+	entry, err := AdapterForLine(FormatAuto, testALBLine)
+	if err != nil {
+		t.Fatalf("AdapterForLine() error = %v", err)
+	}
+	anonymizer := &converter.IPAnonymizer{Mode: converter.IPAnonymizationTruncate}
+	entry = ApplyAnonymizer(entry, anonymizer)
+
+	raw := &BodyFormat{Mode: BodyRaw, Anonymizer: anonymizer}
+	body := raw.Apply(entry, testALBLine).ToOTel().Body.Text()
+	if strings.Contains(body, "192.168.131.39") || !strings.Contains(body, "192.168.131.0:2817") {
+		t.Errorf("raw body not scrubbed: %s", body)
+	}
+
+	tmpl, err := ParseBodyFormat(BodyTemplate, `{{.Attr "client.address"}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := tmpl.Apply(entry, testALBLine).ToOTel().Body.Text(); got != "192.168.131.0" {
+		t.Errorf("template body = %q, want the truncated client.address", got)
+	}
+}
@@ -0,0 +1,103 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Checkpoint lets a large object resume where an earlier invocation stopped
+type Checkpoint struct {
+	// Lines is the number of leading lines whose entries were already exported
+	Lines int64
+	// Every is how many additional lines must complete before Save is called again
+	Every int64
+	// Save must make every entry emitted so far durable before recording lines
+	Save func(ctx context.Context, lines int64) error
+}
+
+// CheckpointStore persists checkpoints by object identity
+type CheckpointStore interface {
+	// LoadCheckpoint returns the saved line count, or 0 when there is none
+	LoadCheckpoint(ctx context.Context, id string) (int64, error)
+	SaveCheckpoint(ctx context.Context, id string, lines int64) error
+	DeleteCheckpoint(ctx context.Context, id string) error
+}
+
+// CheckpointID identifies one version of an object; a rewritten object gets a new ETag and
+// therefore starts from the beginning
+func CheckpointID(bucket, key, etag string) string {
+	return bucket + "/" + key + "#" + etag
+}
+
+// DynamoDBAPI is the part of the DynamoDB client the stores use; *dynamodb.Client implements it
+type DynamoDBAPI interface {
+	GetItem(ctx context.Context, in *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
+	PutItem(ctx context.Context, in *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+	DeleteItem(ctx context.Context, in *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error)
+}
+
+// DynamoCheckpointStore keeps checkpoints in a DynamoDB table with a string partition key
+// named "id". Items carry an "expires_at" epoch attribute for DynamoDB TTL.
+type DynamoCheckpointStore struct {
+	Client DynamoDBAPI
+	Table  string
+	// TTL bounds how long an abandoned checkpoint is kept
+	TTL time.Duration
+}
+
+func (s *DynamoCheckpointStore) LoadCheckpoint(ctx context.Context, id string) (int64, error) {
+	out, err := s.Client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(s.Table),
+		Key:            map[string]types.AttributeValue{"id": &types.AttributeValueMemberS{Value: id}},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to load checkpoint: %w", err)
+	}
+	attr, ok := out.Item["lines"].(*types.AttributeValueMemberN)
+	if !ok {
+		return 0, nil
+	}
+	lines, err := strconv.ParseInt(attr.Value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid checkpoint %q: %w", id, err)
+	}
+	return lines, nil
+}
+
+func (s *DynamoCheckpointStore) SaveCheckpoint(ctx context.Context, id string, lines int64) error {
+	now := time.Now()
+	item := map[string]types.AttributeValue{
+		"id":         &types.AttributeValueMemberS{Value: id},
+		"lines":      &types.AttributeValueMemberN{Value: strconv.FormatInt(lines, 10)},
+		"updated_at": &types.AttributeValueMemberS{Value: now.UTC().Format(time.RFC3339)},
+	}
+	if s.TTL > 0 {
+		item["expires_at"] = &types.AttributeValueMemberN{Value: strconv.FormatInt(now.Add(s.TTL).Unix(), 10)}
+	}
+	_, err := s.Client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(s.Table),
+		Item:      item,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save checkpoint: %w", err)
+	}
+	return nil
+}
+
+func (s *DynamoCheckpointStore) DeleteCheckpoint(ctx context.Context, id string) error {
+	_, err := s.Client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(s.Table),
+		Key:       map[string]types.AttributeValue{"id": &types.AttributeValueMemberS{Value: id}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete checkpoint: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package processor
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// fakeDynamo keeps items in memory keyed by their "id" attribute. Conditional writes only
+// understand the expressions used by DynamoDedupStore.
+type fakeDynamo struct {
+	items map[string]map[string]types.AttributeValue
+}
+
+// str returns the value of a string or number attribute
+func str(attr types.AttributeValue) string {
+	switch v := attr.(type) {
+	case *types.AttributeValueMemberS:
+		return v.Value
+	case *types.AttributeValueMemberN:
+		return v.Value
+	}
+	return ""
+}
+
+func (f *fakeDynamo) GetItem(ctx context.Context, in *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	return &dynamodb.GetItemOutput{Item: f.items[str(in.Key["id"])]}, nil
+}
+
+func (f *fakeDynamo) PutItem(ctx context.Context, in *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	id := str(in.Item["id"])
+	if existing, ok := f.items[id]; ok && in.ConditionExpression != nil {
+		now, _ := strconv.ParseInt(str(in.ExpressionAttributeValues[":now"]), 10, 64)
+		lease, _ := strconv.ParseInt(str(existing["lease_until"]), 10, 64)
+		if str(existing["status"]) != dedupInProgress || lease >= now {
+			return nil, &types.ConditionalCheckFailedException{Message: aws.String("condition failed")}
+		}
+	}
+	f.items[id] = in.Item
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func (f *fakeDynamo) DeleteItem(ctx context.Context, in *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+	id := str(in.Key["id"])
+	if existing, ok := f.items[id]; ok && in.ConditionExpression != nil && str(existing["status"]) != dedupInProgress {
+		return nil, &types.ConditionalCheckFailedException{Message: aws.String("condition failed")}
+	}
+	delete(f.items, id)
+	return &dynamodb.DeleteItemOutput{}, nil
+}
+
+func TestDynamoCheckpointStore(t *testing.T) {
+	db := &fakeDynamo{items: map[string]map[string]types.AttributeValue{}}
+	store := &DynamoCheckpointStore{Client: db, Table: "checkpoints", TTL: time.Hour}
+	ctx := context.Background()
+	id := CheckpointID("bucket", "AWSLogs/alb.log.gz", "abc123")
+
+	if lines, err := store.LoadCheckpoint(ctx, id); err != nil || lines != 0 {
+		t.Fatalf("LoadCheckpoint() = %d, %v, want 0 for a missing checkpoint", lines, err)
+	}
+
+	if err := store.SaveCheckpoint(ctx, id, 250000); err != nil {
+		t.Fatalf("SaveCheckpoint() error = %v", err)
+	}
+	if db.items[id]["expires_at"] == nil {
+		t.Error("saved checkpoint has no expires_at attribute")
+	}
+	if lines, err := store.LoadCheckpoint(ctx, id); err != nil || lines != 250000 {
+		t.Errorf("LoadCheckpoint() = %d, %v, want 250000", lines, err)
+	}
+
+	if err := store.DeleteCheckpoint(ctx, id); err != nil {
+		t.Fatalf("DeleteCheckpoint() error = %v", err)
+	}
+	if lines, _ := store.LoadCheckpoint(ctx, id); lines != 0 {
+		t.Errorf("LoadCheckpoint() after delete = %d, want 0", lines)
+	}
+}
@@ -6,7 +6,6 @@ import (
 	"regexp"
 	"strings"
 
-	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/pixelvide/otel-aws-log-parser/cmd/lambda/adapter"
 	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
 	"github.com/pixelvide/otel-aws-log-parser/pkg/parser"
@@ -20,8 +19,7 @@ import (
 var cloudFrontLogPattern = regexp.MustCompile(`[A-Z0-9]+\.\d{4}-\d{2}-\d{2}-\d{2}\.[a-zA-Z0-9]+\.gz$`)
 
 type CloudFrontProcessor struct {
-	MaxBatchSize  int
-	MaxConcurrent int
+	ReadOptions
 }
 
 func (p *CloudFrontProcessor) Name() string {
@@ -39,16 +37,25 @@ func (p *CloudFrontProcessor) Matches(bucket, key string) bool {
 		cloudFrontLogPattern.MatchString(key)
 }
 
-func (p *CloudFrontProcessor) Process(ctx context.Context, logger *slog.Logger, s3Client *s3.S3, bucket, key string) ([]adapter.LogAdapter, error) {
+func (p *CloudFrontProcessor) Process(ctx context.Context, logger *slog.Logger, store ObjectStore, bucket, key string) ([]adapter.LogAdapter, error) {
+	return ReadAndParseObject(ctx, logger, store, bucket, key, p.ReadOptions, p.parseLine(key))
+}
+
+func (p *CloudFrontProcessor) ProcessStream(ctx context.Context, logger *slog.Logger, store ObjectStore, bucket, key string, emit EmitFunc, cp *Checkpoint) error {
+	return StreamAndParseObject(ctx, logger, store, bucket, key, p.ReadOptions, p.parseLine(key), emit, cp)
+}
+
+func (p *CloudFrontProcessor) parseLine(key string) ProcessLineFunc {
 	// Attempt to parse account/region if they happen to be in the path (unlikely for standard CF logs, but harmless)
 	accountID, region := ParseRegionAccountFromS3Key(key)
+	dist := p.Distributions.Lookup(DistributionIDFromKey(key))
 
-	return ReadAndParseFromS3(logger, s3Client, bucket, key, p.MaxBatchSize, p.MaxConcurrent, func(line string) (adapter.LogAdapter, error) {
+	return func(line string) (adapter.LogAdapter, error) {
 		entry, err := parser.ParseCloudFrontLogLine(line)
 		if err != nil {
 			return nil, err
 		}
-		// If entry is nil (comment line), ReadAndParseFromS3 handles it if we return nil, nil?
+		// If entry is nil (comment line), ReadAndParseObject handles it if we return nil, nil?
 		// Looking at ALBProcessor: parser.ParseLogLine returns nil, nil for comments.
 		// CloudFront parser behaves similarly.
 		if entry == nil {
@@ -59,8 +66,9 @@ func (p *CloudFrontProcessor) Process(ctx context.Context, logger *slog.Logger,
 			CloudFrontLogEntry: entry,
 			AccountID:          accountID,
 			Region:             region,
+			Distribution:       dist,
 		}, nil
-	})
+	}
 }
 
 // CloudFrontAdapter implementation
@@ -68,6 +76,8 @@ type CloudFrontAdapter struct {
 	*parser.CloudFrontLogEntry
 	AccountID string
 	Region    string
+	// Distribution, when looked up, adds the distribution's metadata to the resource attributes
+	Distribution *Distribution
 }
 
 func (a CloudFrontAdapter) GetResourceKey() string {
@@ -96,6 +106,9 @@ func (a CloudFrontAdapter) GetResourceAttributes() []converter.OTelAttribute {
 	if !hasRegion && a.Region != "" {
 		attrs = append(attrs, converter.OTelAttribute{Key: "cloud.region", Value: converter.OTelAnyValue{StringValue: &a.Region}})
 	}
+	if a.Distribution != nil {
+		attrs = append(attrs, a.Distribution.attributes()...)
+	}
 
 	return attrs
 }
@@ -0,0 +1,113 @@
+package processor
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	"github.com/pixelvide/otel-aws-log-parser/cmd/lambda/adapter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+)
+
+// LogGroupSource identifies the CloudWatch Logs stream a subscription delivered a record from
+type LogGroupSource struct {
+	Owner     string
+	LogGroup  string
+	LogStream string
+}
+
+func (s LogGroupSource) resourceKey() string {
+	return "cwl:" + s.Owner + ":" + s.LogGroup + "/" + s.LogStream
+}
+
+func (s LogGroupSource) attributes() []converter.OTelAttribute {
+	attrs := []converter.OTelAttribute{
+		{Key: "aws.log.group.name", Value: converter.OTelAnyValue{StringValue: aws.String(s.LogGroup)}},
+		{Key: "aws.log.stream.name", Value: converter.OTelAnyValue{StringValue: aws.String(s.LogStream)}},
+	}
+	if s.Owner != "" {
+		attrs = append(attrs, converter.OTelAttribute{Key: "cloud.account.id", Value: converter.OTelAnyValue{StringValue: aws.String(s.Owner)}})
+	}
+	return attrs
+}
+
+// LogGroupAdapter wraps a parsed entry with the log group and stream it was delivered from
+type LogGroupAdapter struct {
+	adapter.LogAdapter
+	Source LogGroupSource
+}
+
+func (a LogGroupAdapter) Unwrap() adapter.LogAdapter { return a.LogAdapter }
+
+func (a LogGroupAdapter) GetResourceKey() string {
+	return a.LogAdapter.GetResourceKey() + "|" + a.Source.resourceKey()
+}
+
+func (a LogGroupAdapter) GetResourceAttributes() []converter.OTelAttribute {
+	attrs := a.LogAdapter.GetResourceAttributes()
+	for _, attr := range a.Source.attributes() {
+		// The entry's own account wins over the subscription owner
+		if attr.Key == "cloud.account.id" && hasAttr(attrs, attr.Key) {
+			continue
+		}
+		attrs = append(attrs, attr)
+	}
+	return attrs
+}
+
+// MessageAdapter exports a CloudWatch Logs message that matches no known format as a plain
+// text record
+type MessageAdapter struct {
+	Source    LogGroupSource
+	Timestamp time.Time
+	Message   string
+}
+
+func (a MessageAdapter) GetResourceKey() string {
+	return a.Source.resourceKey()
+}
+
+func (a MessageAdapter) GetResourceAttributes() []converter.OTelAttribute {
+	return append([]converter.OTelAttribute{
+		{Key: "cloud.provider", Value: converter.OTelAnyValue{StringValue: aws.String("aws")}},
+		{Key: "service.name", Value: converter.OTelAnyValue{StringValue: aws.String("cloudwatch-logs-parser")}},
+	}, a.Source.attributes()...)
+}
+
+func (a MessageAdapter) ToOTel() converter.OTelLogRecord {
+	now := time.Now()
+	ts := a.Timestamp
+	if ts.IsZero() {
+		ts = now
+	}
+	return converter.OTelLogRecord{
+		TimeUnixNano:         strconv.FormatInt(ts.UnixNano(), 10),
+		ObservedTimeUnixNano: strconv.FormatInt(now.UnixNano(), 10),
+		Body:                 converter.OTelAnyValue{StringValue: aws.String(a.Message)},
+		Attributes:           []converter.OTelAttribute{},
+	}
+}
+
+// AdapterForMessage parses a CloudWatch Logs message as format ("auto" to detect it) and tags
+// it with its log group and stream. Messages that do not parse become a MessageAdapter; blank
+// messages return nil.
+func AdapterForMessage(format string, src LogGroupSource, timestamp time.Time, message string) adapter.LogAdapter {
+	if entry, _ := AdapterForLine(format, message); entry != nil {
+		return LogGroupAdapter{LogAdapter: entry, Source: src}
+	}
+	if strings.TrimSpace(message) == "" {
+		return nil
+	}
+	return MessageAdapter{Source: src, Timestamp: timestamp, Message: message}
+}
+
+func hasAttr(attrs []converter.OTelAttribute, key string) bool {
+	for _, attr := range attrs {
+		if attr.Key == key {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package processor
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+)
+
+func attrValue(attrs []converter.OTelAttribute, key string) string {
+	for _, attr := range attrs {
+		if attr.Key == key && attr.Value.StringValue != nil {
+			return *attr.Value.StringValue
+		}
+	}
+	return ""
+}
+
+func TestAdapterForMessage(t *testing.T) {
+	src := LogGroupSource{Owner: "123456789012", LogGroup: "/aws/waf", LogStream: "eu-west-3_TEST-WEBACL_0"}
+	ts := time.UnixMilli(1683355580000)
+
+	t.Run("known format", func(t *testing.T) {
+		entry := AdapterForMessage(FormatAuto, src, ts, testWAFLine)
+		if _, ok := entry.(LogGroupAdapter); !ok {
+			t.Fatalf("AdapterForMessage() = %T, want LogGroupAdapter", entry)
+		}
+		attrs := entry.GetResourceAttributes()
+		if got := attrValue(attrs, "aws.log.group.name"); got != src.LogGroup {
+			t.Errorf("aws.log.group.name = %q, want %q", got, src.LogGroup)
+		}
+		if got := attrValue(attrs, "aws.log.stream.name"); got != src.LogStream {
+			t.Errorf("aws.log.stream.name = %q, want %q", got, src.LogStream)
+		}
+		if !strings.HasSuffix(entry.GetResourceKey(), src.LogStream) {
+			t.Errorf("resource key %q does not include the log stream", entry.GetResourceKey())
+		}
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		entry := AdapterForMessage(FormatAuto, src, ts, "START RequestId: 8f5c Version: $LATEST")
+		msg, ok := entry.(MessageAdapter)
+		if !ok {
+			t.Fatalf("AdapterForMessage() = %T, want MessageAdapter", entry)
+		}
+		record := msg.ToOTel()
+		if record.Body.Text() != msg.Message {
+			t.Errorf("body = %q, want the message", record.Body.Text())
+		}
+		if record.TimeUnixNano != "1683355580000000000" {
+			t.Errorf("timeUnixNano = %s, want the event timestamp", record.TimeUnixNano)
+		}
+		if got := attrValue(msg.GetResourceAttributes(), "cloud.account.id"); got != src.Owner {
+			t.Errorf("cloud.account.id = %q, want the subscription owner", got)
+		}
+	})
+
+	t.Run("blank message", func(t *testing.T) {
+		if entry := AdapterForMessage(FormatAuto, src, ts, "  "); entry != nil {
+			t.Errorf("AdapterForMessage() = %v, want nil", entry)
+		}
+	})
+}
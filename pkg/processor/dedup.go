@@ -0,0 +1,124 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// ClaimResult is the outcome of claiming an object version for processing
+type ClaimResult int
+
+const (
+	// ClaimAcquired means the caller owns the object until it calls Complete or Release
+	ClaimAcquired ClaimResult = iota
+	// ClaimDone means the object was already exported and can be skipped
+	ClaimDone
+	// ClaimBusy means another invocation holds an unexpired claim
+	ClaimBusy
+)
+
+// DedupStore suppresses duplicate processing of the same object version
+type DedupStore interface {
+	Claim(ctx context.Context, id string) (ClaimResult, error)
+	// Complete marks the object as exported
+	Complete(ctx context.Context, id string) error
+	// Release drops a claim so a retry can process the object
+	Release(ctx context.Context, id string) error
+}
+
+// DynamoDedupStore records object versions in a DynamoDB table with a string partition key
+// named "id" (see CheckpointID). Items carry an "expires_at" epoch attribute for DynamoDB TTL.
+type DynamoDedupStore struct {
+	Client DynamoDBAPI
+	Table  string
+	// TTL is how long a completed object is remembered
+	TTL time.Duration
+	// Lease is how long a claim blocks other invocations; it should cover the function timeout
+	Lease time.Duration
+}
+
+const (
+	dedupInProgress = "in_progress"
+	dedupDone       = "done"
+)
+
+func (s *DynamoDedupStore) Claim(ctx context.Context, id string) (ClaimResult, error) {
+	now := time.Now()
+	_, err := s.Client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(s.Table),
+		Item: map[string]types.AttributeValue{
+			"id":          &types.AttributeValueMemberS{Value: id},
+			"status":      &types.AttributeValueMemberS{Value: dedupInProgress},
+			"lease_until": &types.AttributeValueMemberN{Value: strconv.FormatInt(now.Add(s.Lease).Unix(), 10)},
+			"expires_at":  &types.AttributeValueMemberN{Value: strconv.FormatInt(now.Add(s.Lease+s.TTL).Unix(), 10)},
+		},
+		// A claim can be taken over once its lease expired, a completed object never
+		ConditionExpression:      aws.String("attribute_not_exists(id) OR (#status = :in_progress AND lease_until < :now)"),
+		ExpressionAttributeNames: map[string]string{"#status": "status"},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":in_progress": &types.AttributeValueMemberS{Value: dedupInProgress},
+			":now":         &types.AttributeValueMemberN{Value: strconv.FormatInt(now.Unix(), 10)},
+		},
+	})
+	if err == nil {
+		return ClaimAcquired, nil
+	}
+
+	var failed *types.ConditionalCheckFailedException
+	if !errors.As(err, &failed) {
+		return ClaimAcquired, fmt.Errorf("failed to claim object: %w", err)
+	}
+
+	out, err := s.Client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(s.Table),
+		Key:            map[string]types.AttributeValue{"id": &types.AttributeValueMemberS{Value: id}},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return ClaimAcquired, fmt.Errorf("failed to read object claim: %w", err)
+	}
+	if status, ok := out.Item["status"].(*types.AttributeValueMemberS); ok && status.Value == dedupDone {
+		return ClaimDone, nil
+	}
+	return ClaimBusy, nil
+}
+
+func (s *DynamoDedupStore) Complete(ctx context.Context, id string) error {
+	_, err := s.Client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(s.Table),
+		Item: map[string]types.AttributeValue{
+			"id":         &types.AttributeValueMemberS{Value: id},
+			"status":     &types.AttributeValueMemberS{Value: dedupDone},
+			"expires_at": &types.AttributeValueMemberN{Value: strconv.FormatInt(time.Now().Add(s.TTL).Unix(), 10)},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to mark object done: %w", err)
+	}
+	return nil
+}
+
+func (s *DynamoDedupStore) Release(ctx context.Context, id string) error {
+	_, err := s.Client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(s.Table),
+		Key:       map[string]types.AttributeValue{"id": &types.AttributeValueMemberS{Value: id}},
+		// Never drop a completion written by another invocation
+		ConditionExpression:      aws.String("#status = :in_progress"),
+		ExpressionAttributeNames: map[string]string{"#status": "status"},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":in_progress": &types.AttributeValueMemberS{Value: dedupInProgress},
+		},
+	})
+	var failed *types.ConditionalCheckFailedException
+	if err != nil && !errors.As(err, &failed) {
+		return fmt.Errorf("failed to release object claim: %w", err)
+	}
+	return nil
+}
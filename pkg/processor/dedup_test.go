@@ -0,0 +1,48 @@
+package processor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func TestDynamoDedupStore(t *testing.T) {
+	db := &fakeDynamo{items: map[string]map[string]types.AttributeValue{}}
+	store := &DynamoDedupStore{Client: db, Table: "dedup", TTL: time.Hour, Lease: time.Minute}
+	ctx := context.Background()
+	id := CheckpointID("bucket", "AWSLogs/alb.log.gz", "abc123")
+
+	claim := func(want ClaimResult) {
+		t.Helper()
+		got, err := store.Claim(ctx, id)
+		if err != nil || got != want {
+			t.Fatalf("Claim() = %v, %v, want %v", got, err, want)
+		}
+	}
+
+	claim(ClaimAcquired)
+	claim(ClaimBusy)
+
+	// A released claim can be taken again
+	if err := store.Release(ctx, id); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	claim(ClaimAcquired)
+
+	// An expired lease can be taken over
+	db.items[id]["lease_until"] = &types.AttributeValueMemberN{Value: "0"}
+	claim(ClaimAcquired)
+
+	if err := store.Complete(ctx, id); err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+	claim(ClaimDone)
+
+	// Releasing never removes a completion
+	if err := store.Release(ctx, id); err != nil {
+		t.Fatalf("Release() after Complete error = %v", err)
+	}
+	claim(ClaimDone)
+}
@@ -0,0 +1,143 @@
+package processor
+
+import (
+	"context"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudfront"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+)
+
+// Distribution is the metadata of a CloudFront distribution attached to its records
+type Distribution struct {
+	ARN        string
+	Aliases    []string
+	Comment    string
+	PriceClass string
+	Tags       map[string]string
+}
+
+// attributes returns the metadata as resource attributes; tags become aws.tag.<key> like the
+// tags of load balancers
+func (d *Distribution) attributes() []converter.OTelAttribute {
+	var attrs []converter.OTelAttribute
+	add := func(key, value string) {
+		if value != "" {
+			attrs = append(attrs, converter.OTelAttribute{Key: key, Value: converter.OTelAnyValue{StringValue: aws.String(value)}})
+		}
+	}
+	add("aws.cloudfront.distribution.arn", d.ARN)
+	add("aws.cloudfront.distribution.aliases", strings.Join(d.Aliases, ","))
+	add("aws.cloudfront.distribution.comment", d.Comment)
+	add("aws.cloudfront.distribution.price_class", d.PriceClass)
+	for _, key := range slices.Sorted(maps.Keys(d.Tags)) {
+		add("aws.tag."+key, d.Tags[key])
+	}
+	return attrs
+}
+
+// CloudFrontAPI is the part of the CloudFront client Distributions uses; *cloudfront.Client
+// implements it
+type CloudFrontAPI interface {
+	GetDistribution(ctx context.Context, in *cloudfront.GetDistributionInput, optFns ...func(*cloudfront.Options)) (*cloudfront.GetDistributionOutput, error)
+	ListTagsForResource(ctx context.Context, in *cloudfront.ListTagsForResourceInput, optFns ...func(*cloudfront.Options)) (*cloudfront.ListTagsForResourceOutput, error)
+}
+
+// Distributions looks up CloudFront distributions with cloudfront:GetDistribution and, for
+// TagKeys, cloudfront:ListTagsForResource, keeping them in memory for TTL. Failed lookups are
+// remembered for TTL as well.
+type Distributions struct {
+	Client CloudFrontAPI
+	// TagKeys are the tags attached; "*" attaches every tag, none skips the tag lookup
+	TagKeys []string
+	// TTL defaults to DefaultTagTTL
+	TTL time.Duration
+	// OnError, when set, is told about failed lookups
+	OnError func(id string, err error)
+
+	mu    sync.Mutex
+	cache map[string]cachedDistribution
+}
+
+type cachedDistribution struct {
+	dist    *Distribution
+	expires time.Time
+}
+
+// Lookup returns the distribution with the given ID, or nil when it is unknown or d is nil
+func (d *Distributions) Lookup(id string) *Distribution {
+	if d == nil || id == "" {
+		return nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.cache == nil {
+		d.cache = make(map[string]cachedDistribution)
+	}
+
+	now := time.Now()
+	if c, ok := d.cache[id]; ok && now.Before(c.expires) {
+		return c.dist
+	}
+	ttl := d.TTL
+	if ttl <= 0 {
+		ttl = DefaultTagTTL
+	}
+	dist, err := d.fetch(id)
+	if err != nil && d.OnError != nil {
+		d.OnError(id, err)
+	}
+	d.cache[id] = cachedDistribution{dist: dist, expires: now.Add(ttl)}
+	return dist
+}
+
+func (d *Distributions) fetch(id string) (*Distribution, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), tagLookupTimeout)
+	defer cancel()
+
+	out, err := d.Client.GetDistribution(ctx, &cloudfront.GetDistributionInput{Id: aws.String(id)})
+	if err != nil {
+		return nil, err
+	}
+	dist := &Distribution{ARN: aws.ToString(out.Distribution.ARN)}
+	if cfg := out.Distribution.DistributionConfig; cfg != nil {
+		if cfg.Aliases != nil {
+			dist.Aliases = cfg.Aliases.Items
+		}
+		dist.Comment = aws.ToString(cfg.Comment)
+		dist.PriceClass = string(cfg.PriceClass)
+	}
+	if len(d.TagKeys) == 0 {
+		return dist, nil
+	}
+
+	tags, err := d.Client.ListTagsForResource(ctx, &cloudfront.ListTagsForResourceInput{Resource: out.Distribution.ARN})
+	if err != nil {
+		// The distribution itself is still worth attaching
+		return dist, err
+	}
+	dist.Tags = map[string]string{}
+	for _, tag := range tags.Tags.Items {
+		key := aws.ToString(tag.Key)
+		if slices.Contains(d.TagKeys, "*") || slices.Contains(d.TagKeys, key) {
+			dist.Tags[key] = aws.ToString(tag.Value)
+		}
+	}
+	return dist, nil
+}
+
+// DistributionIDFromKey returns the distribution ID a standard log file is named after, e.g.
+// E2K55636F2K7 for AWSLogs/123456789012/CloudFront/E2K55636F2K7.2019-12-04-21.d111111abcdef8.gz
+func DistributionIDFromKey(key string) string {
+	if !cloudFrontLogPattern.MatchString(key) {
+		return ""
+	}
+	id, _, _ := strings.Cut(path.Base(key), ".")
+	return id
+}
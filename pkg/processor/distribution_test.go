@@ -0,0 +1,93 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudfront"
+	"github.com/aws/aws-sdk-go-v2/service/cloudfront/types"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/parser"
+)
+
+type fakeCloudFront struct {
+	gets    int
+	tagsErr error
+}
+
+func (f *fakeCloudFront) GetDistribution(ctx context.Context, in *cloudfront.GetDistributionInput, optFns ...func(*cloudfront.Options)) (*cloudfront.GetDistributionOutput, error) {
+	f.gets++
+	if aws.ToString(in.Id) != "E2K55636F2K7" {
+		return nil, errors.New("NoSuchDistribution")
+	}
+	return &cloudfront.GetDistributionOutput{Distribution: &types.Distribution{
+		ARN: aws.String("arn:aws:cloudfront::123456789012:distribution/E2K55636F2K7"),
+		DistributionConfig: &types.DistributionConfig{
+			Aliases:    &types.Aliases{Items: []string{"www.example.com", "example.com"}},
+			Comment:    aws.String("storefront"),
+			PriceClass: types.PriceClassPriceClass100,
+		},
+	}}, nil
+}
+
+func (f *fakeCloudFront) ListTagsForResource(ctx context.Context, in *cloudfront.ListTagsForResourceInput, optFns ...func(*cloudfront.Options)) (*cloudfront.ListTagsForResourceOutput, error) {
+	if f.tagsErr != nil {
+		return nil, f.tagsErr
+	}
+	return &cloudfront.ListTagsForResourceOutput{Tags: &types.Tags{Items: []types.Tag{
+		{Key: aws.String("team"), Value: aws.String("web")},
+		{Key: aws.String("cost-center"), Value: aws.String("42")},
+	}}}, nil
+}
+
+func TestDistributions_Lookup(t *testing.T) {
+	client := &fakeCloudFront{}
+	var failed []string
+	dists := &Distributions{Client: client, TagKeys: []string{"team"}, OnError: func(id string, err error) { failed = append(failed, id) }}
+
+	key := "AWSLogs/123456789012/CloudFront/E2K55636F2K7.2019-12-04-21.d111111abcdef8.gz"
+	id := DistributionIDFromKey(key)
+	if id != "E2K55636F2K7" {
+		t.Fatalf("DistributionIDFromKey() = %q", id)
+	}
+	dist := dists.Lookup(id)
+	dists.Lookup(id)
+	if client.gets != 1 {
+		t.Errorf("GetDistribution calls = %d, want 1 (cached)", client.gets)
+	}
+
+	entry := CloudFrontAdapter{CloudFrontLogEntry: &parser.CloudFrontLogEntry{CSHost: "d111111abcdef8.cloudfront.net"}, Distribution: dist}
+	attrs := map[string]string{}
+	for _, attr := range entry.GetResourceAttributes() {
+		attrs[attr.Key] = attr.Value.Text()
+	}
+	want := map[string]string{
+		"aws.cloudfront.distribution.arn":         "arn:aws:cloudfront::123456789012:distribution/E2K55636F2K7",
+		"aws.cloudfront.distribution.aliases":     "www.example.com,example.com",
+		"aws.cloudfront.distribution.comment":     "storefront",
+		"aws.cloudfront.distribution.price_class": "PriceClass_100",
+		"aws.tag.team": "web",
+	}
+	for k, v := range want {
+		if attrs[k] != v {
+			t.Errorf("%s = %q, want %q", k, attrs[k], v)
+		}
+	}
+	if _, ok := attrs["aws.tag.cost-center"]; ok {
+		t.Error("unselected tag cost-center attached")
+	}
+
+	if dists.Lookup("EUNKNOWN") != nil || len(failed) != 1 {
+		t.Errorf("unknown distribution: failed = %v, want one reported failure", failed)
+	}
+	if DistributionIDFromKey("custom/prefix/access.log") != "" {
+		t.Error("DistributionIDFromKey() found an ID in a non-standard key")
+	}
+
+	// A failed tag lookup still attaches the distribution
+	partial := &Distributions{Client: &fakeCloudFront{tagsErr: errors.New("AccessDenied")}, TagKeys: []string{"*"}}
+	if dist := partial.Lookup(id); dist == nil || dist.Comment != "storefront" || len(dist.Tags) != 0 {
+		t.Errorf("Lookup() without tag permission = %+v, want the distribution without tags", dist)
+	}
+}
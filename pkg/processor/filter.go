@@ -0,0 +1,134 @@
+package processor
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	"github.com/pixelvide/otel-aws-log-parser/cmd/lambda/adapter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/filter"
+)
+
+// fieldPaths caches the normalized field path of each name used in filter expressions
+var fieldPaths sync.Map
+
+func fieldPath(name string) []string {
+	if path, ok := fieldPaths.Load(name); ok {
+		return path.([]string)
+	}
+	var path []string
+	for _, part := range strings.Split(name, ".") {
+		path = append(path, normalizeFieldName(part))
+	}
+	fieldPaths.Store(name, path)
+	return path
+}
+
+// entryEnv resolves filter names against an entry: its parsed fields first, matched like
+// group key fields (elb_status_code, cs-uri-stem), then the attributes of its record
+// (http.response.status_code). The record is only converted when a name needs it.
+type entryEnv struct {
+	entry     adapter.LogAdapter
+	converted bool
+	rec       converter.OTelLogRecord
+}
+
+func (e *entryEnv) record() converter.OTelLogRecord {
+	if !e.converted {
+		e.rec, e.converted = e.entry.ToOTel(), true
+	}
+	return e.rec
+}
+
+func (e *entryEnv) Lookup(name string) (string, bool) {
+	if v, ok := lookupField(reflect.ValueOf(e.entry), fieldPath(name)); ok {
+		return formatField(v), true
+	}
+	for _, attr := range e.record().Attributes {
+		if attr.Key == name {
+			return attr.Value.Text(), true
+		}
+	}
+	return "", false
+}
+
+// FilterEnv resolves the names of a filter expression against an entry exactly like the
+// filter rules do, for evaluating expressions outside the pipeline
+func FilterEnv(entry adapter.LogAdapter) filter.Env {
+	return &entryEnv{entry: entry}
+}
+
+// Verdict is what admission decided for an entry
+type Verdict int
+
+const (
+	Admitted Verdict = iota
+	// Filtered entries were dropped by a filter rule
+	Filtered
+	// SampledOut entries were dropped by the record sampler
+	SampledOut
+)
+
+// Admission is the outcome of running an entry through the filter rules and record sampler
+type Admission struct {
+	Verdict Verdict
+	// Routes are the exporters a route rule sent the entry to; none means all of them
+	Routes []string
+	// SampleRate is the rate the entry was sampled in at, 1 when it was not sampled
+	SampleRate float64
+}
+
+// Admit runs an entry through the filter rules, then the record sampler
+func Admit(rules filter.Rules, sampler *RecordSampler, entry adapter.LogAdapter) Admission {
+	adm := Admission{SampleRate: 1}
+	if len(rules) == 0 && sampler == nil {
+		return adm
+	}
+	env := &entryEnv{entry: entry}
+	keep, routes := rules.Evaluate(env)
+	if !keep {
+		adm.Verdict = Filtered
+		return adm
+	}
+	adm.Routes = routes
+	if sampler != nil {
+		kept, rate := sampler.Keep(env.record())
+		if !kept {
+			adm.Verdict = SampledOut
+			return adm
+		}
+		adm.SampleRate = rate
+	}
+	return adm
+}
+
+// Apply wraps an admitted entry with its routes and sample rate. The wrapper hides the
+// entry's fields from reflection, so it goes on after the group key has been evaluated.
+func (a Admission) Apply(entry adapter.LogAdapter) adapter.LogAdapter {
+	if entry == nil || len(a.Routes) == 0 && a.SampleRate >= 1 {
+		return entry
+	}
+	return AdmittedAdapter{LogAdapter: entry, Routes: a.Routes, SampleRate: a.SampleRate}
+}
+
+// AdmittedAdapter limits the exporters an adapter's record goes to and records the rate it was
+// sampled at as sampling.rate, so queries can weight sampled records by 1/rate
+type AdmittedAdapter struct {
+	adapter.LogAdapter
+	Routes     []string
+	SampleRate float64
+}
+
+func (a AdmittedAdapter) Unwrap() adapter.LogAdapter { return a.LogAdapter }
+
+func (a AdmittedAdapter) ToOTel() converter.OTelLogRecord {
+	record := a.LogAdapter.ToOTel()
+	record.Routes = a.Routes
+	if a.SampleRate < 1 {
+		record.Attributes = append(record.Attributes, converter.OTelAttribute{Key: "sampling.rate", Value: converter.OTelAnyValue{DoubleValue: aws.Float64(a.SampleRate)}})
+	}
+	return record
+}
@@ -0,0 +1,92 @@
+package processor
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pixelvide/otel-aws-log-parser/cmd/lambda/adapter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/filter"
+)
+
+func TestStreamAndParseObject_Filter(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, "bucket", "alb.log")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	health := strings.Replace(testALBLine, "http://www.example.com:80/ ", "http://www.example.com:80/healthz ", 1)
+	failed := strings.Replace(testALBLine, " 200 200 ", " 502 502 ", 1)
+	content := strings.Join([]string{testALBLine, health, failed, health}, "\n") + "\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := filter.ParseConfig([]byte(`
+alb:
+  - drop: elb_status_code == 200 && request_url contains "/healthz"
+  - route: http.response.status_code >= 500
+    to: [s3]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stats ReadStats
+	opts := ReadOptions{MaxBatchSize: 10, MaxConcurrent: 2, Stats: &stats, Filter: cfg.For("alb")}
+
+	var routes [][]string
+	emit := func(entry adapter.LogAdapter) error {
+		routes = append(routes, entry.ToOTel().Routes)
+		return nil
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	if err := StreamAndParseObject(context.Background(), logger, &LocalStore{Root: root}, "bucket", "alb.log", opts, func(line string) (adapter.LogAdapter, error) {
+		return AdapterForLine(FormatAuto, line)
+	}, emit, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := stats.Take(); got.Entries != 2 || got.Filtered != 2 {
+		t.Errorf("Take() = %+v, want 2 entries and 2 filtered", got)
+	}
+	routed := 0
+	for _, r := range routes {
+		if reflect.DeepEqual(r, []string{"s3"}) {
+			routed++
+		}
+	}
+	if len(routes) != 2 || routed != 1 {
+		t.Errorf("routes = %v, want one record routed to s3", routes)
+	}
+}
+
+func TestFilterEnv(t *testing.T) {
+	entry, err := AdapterForLine(FormatAuto, strings.Replace(testALBLine, " 200 200 ", " 502 502 ", 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{`elb_status_code >= 500`, true},
+		{`elb_status_code >= 500 && target_processing_time > 1.0`, false},
+		// Names that are not fields resolve against the converted record's attributes
+		{`http.response.status_code == 502`, true},
+		{`no_such_field`, false},
+	}
+	for _, tt := range tests {
+		expr, err := filter.Compile(tt.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := expr.Match(FilterEnv(entry)); got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package processor
+
+import (
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	"github.com/pixelvide/otel-aws-log-parser/cmd/lambda/adapter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/geoip"
+)
+
+// GeoIPAdapter adds client.geo.country_iso_code, client.geo.city_name, client.as.number and
+// client.as.organization.name looked up from a record's client.address
+type GeoIPAdapter struct {
+	adapter.LogAdapter
+	DB *geoip.DB
+}
+
+func (a GeoIPAdapter) Unwrap() adapter.LogAdapter { return a.LogAdapter }
+
+// ApplyGeoIP wraps the entry for GeoIP enrichment; a nil database returns the entry unchanged
+func ApplyGeoIP(entry adapter.LogAdapter, db *geoip.DB) adapter.LogAdapter {
+	if db == nil || entry == nil {
+		return entry
+	}
+	return GeoIPAdapter{LogAdapter: entry, DB: db}
+}
+
+func (a GeoIPAdapter) ToOTel() converter.OTelLogRecord {
+	record := a.LogAdapter.ToOTel()
+	client := ""
+	// Attributes the log itself carries, like WAF's country, win over the database
+	present := map[string]bool{}
+	for _, attr := range record.Attributes {
+		present[attr.Key] = true
+		if attr.Key == "client.address" && attr.Value.StringValue != nil {
+			client = *attr.Value.StringValue
+		}
+	}
+	if client == "" {
+		return record
+	}
+
+	geo := a.DB.Lookup(client)
+	for _, field := range []struct{ key, value string }{
+		{"client.geo.country_iso_code", geo.CountryISOCode},
+		{"client.geo.city_name", geo.CityName},
+		{"client.as.organization.name", geo.ASOrganization},
+	} {
+		if field.value != "" && !present[field.key] {
+			record.Attributes = append(record.Attributes, converter.OTelAttribute{Key: field.key, Value: converter.OTelAnyValue{StringValue: aws.String(field.value)}})
+		}
+	}
+	if geo.ASN != 0 && !present["client.as.number"] {
+		record.Attributes = append(record.Attributes, converter.OTelAttribute{Key: "client.as.number", Value: converter.OTelAnyValue{IntValue: aws.String(strconv.FormatUint(geo.ASN, 10))}})
+	}
+	return record
+}
@@ -0,0 +1,22 @@
+package processor
+
+import (
+	"testing"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/geoip"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/parser"
+)
+
+func TestApplyGeoIP(t *testing.T) {
+	entry := ALBAdapter{ALBLogEntry: &parser.ALBLogEntry{ClientIP: "203.0.113.10"}}
+	if got := ApplyGeoIP(entry, nil); got != entry {
+		t.Errorf("nil database wrapped the entry: %T", got)
+	}
+
+	// An address no database knows adds nothing
+	wrapped := ApplyGeoIP(entry, &geoip.DB{})
+	before, after := entry.ToOTel().Attributes, wrapped.ToOTel().Attributes
+	if len(after) != len(before) {
+		t.Errorf("unknown address added %d attributes", len(after)-len(before))
+	}
+}
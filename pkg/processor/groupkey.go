@@ -0,0 +1,154 @@
+package processor
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/pixelvide/otel-aws-log-parser/cmd/lambda/adapter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+)
+
+// GroupKey is a resource grouping key expression: a comma-separated list of entry fields,
+// e.g. "domain_name", "cs-host" or "webaclId,httpSourceId". Field names are matched against
+// the entry's Go fields ignoring case, "_", "-" and parentheses, so the AWS documentation
+// names (cs(Host), httpSourceId) and snake_case both work; "." walks into nested structs.
+type GroupKey struct {
+	names []string
+	paths [][]string
+}
+
+// ParseGroupKey parses a grouping key expression; an empty expression returns nil (default grouping)
+func ParseGroupKey(expr string) (*GroupKey, error) {
+	if strings.TrimSpace(expr) == "" {
+		return nil, nil
+	}
+	g := &GroupKey{}
+	for _, name := range strings.Split(expr, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return nil, fmt.Errorf("invalid group key %q: empty field", expr)
+		}
+		var path []string
+		for _, part := range strings.Split(name, ".") {
+			path = append(path, normalizeFieldName(part))
+		}
+		g.names = append(g.names, name)
+		g.paths = append(g.paths, path)
+	}
+	return g, nil
+}
+
+// Validate checks that every field of the expression exists on the sample entry
+func (g *GroupKey) Validate(sample adapter.LogAdapter) error {
+	for i, path := range g.paths {
+		if _, ok := lookupField(reflect.ValueOf(sample), path); !ok {
+			return fmt.Errorf("unknown group key field %q", g.names[i])
+		}
+	}
+	return nil
+}
+
+// values evaluates the expression against an entry; missing or empty fields become "-"
+func (g *GroupKey) values(entry adapter.LogAdapter) []string {
+	out := make([]string, len(g.paths))
+	for i, path := range g.paths {
+		out[i] = "-"
+		if v, ok := lookupField(reflect.ValueOf(entry), path); ok {
+			if s := formatField(v); s != "" {
+				out[i] = s
+			}
+		}
+	}
+	return out
+}
+
+// GroupKeyAdapter replaces an adapter's resource identity with a GroupKey. Its resource
+// attributes keep only the cloud.* and service.name attributes shared by the whole group
+// and add one aws.group.<field> attribute per key field that has a value, so records grouped together
+// never carry another record's resource attributes.
+type GroupKeyAdapter struct {
+	adapter.LogAdapter
+	Key    *GroupKey
+	Values []string
+}
+
+func (a GroupKeyAdapter) Unwrap() adapter.LogAdapter { return a.LogAdapter }
+
+// NewGroupKeyAdapter evaluates the key once for the entry
+func NewGroupKeyAdapter(entry adapter.LogAdapter, key *GroupKey) GroupKeyAdapter {
+	return GroupKeyAdapter{LogAdapter: entry, Key: key, Values: key.values(entry)}
+}
+
+func (a GroupKeyAdapter) GetResourceKey() string {
+	return strings.Join(a.Values, "|")
+}
+
+func (a GroupKeyAdapter) GetResourceAttributes() []converter.OTelAttribute {
+	var attrs []converter.OTelAttribute
+	for _, attr := range a.LogAdapter.GetResourceAttributes() {
+		if strings.HasPrefix(attr.Key, "cloud.") || attr.Key == "service.name" {
+			attrs = append(attrs, attr)
+		}
+	}
+	for i, name := range a.Key.names {
+		if converter.IsPlaceholder(a.Values[i]) {
+			// Still part of the key, but a missing field has no value to export
+			continue
+		}
+		key := "aws.group." + strings.NewReplacer("-", "_", ".", "_", "(", "_", ")", "").Replace(name)
+		attrs = append(attrs, converter.OTelAttribute{Key: key, Value: converter.OTelAnyValue{StringValue: aws.String(a.Values[i])}})
+	}
+	return attrs
+}
+
+func normalizeFieldName(name string) string {
+	return strings.ToLower(strings.NewReplacer("_", "", "-", "", "(", "", ")", "").Replace(name))
+}
+
+// lookupField resolves a normalized field path, following pointers and promoted fields of embedded structs
+func lookupField(v reflect.Value, path []string) (reflect.Value, bool) {
+	for _, name := range path {
+		for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.Struct {
+			return reflect.Value{}, false
+		}
+		f, ok := v.Type().FieldByNameFunc(func(field string) bool { return normalizeFieldName(field) == name })
+		if !ok {
+			return reflect.Value{}, false
+		}
+		fv, err := v.FieldByIndexErr(f.Index)
+		if err != nil {
+			return reflect.Value{}, false
+		}
+		v = fv
+	}
+	return v, true
+}
+
+func formatField(v reflect.Value) string {
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64)
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	}
+	return fmt.Sprint(v.Interface())
+}
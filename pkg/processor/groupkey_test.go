@@ -0,0 +1,83 @@
+package processor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pixelvide/otel-aws-log-parser/cmd/lambda/adapter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/parser"
+)
+
+func TestParseGroupKey(t *testing.T) {
+	if g, err := ParseGroupKey(""); g != nil || err != nil {
+		t.Errorf("ParseGroupKey(\"\") = %v, %v, want nil, nil", g, err)
+	}
+	if _, err := ParseGroupKey("elb,,domain_name"); err == nil {
+		t.Error("ParseGroupKey with empty field should fail")
+	}
+}
+
+func TestGroupKey_Validate(t *testing.T) {
+	tests := []struct {
+		expr    string
+		sample  adapter.LogAdapter
+		wantErr bool
+	}{
+		{"domain_name", ALBAdapter{ALBLogEntry: &parser.ALBLogEntry{}}, false},
+		{"elb, domain_name", NLBAdapter{NLBLogEntry: &parser.NLBLogEntry{}}, false},
+		{"cs(Host)", CloudFrontAdapter{CloudFrontLogEntry: &parser.CloudFrontLogEntry{}}, false},
+		{"httpSourceId,httpRequest.country", &WAFAdapter{WAFLogEntry: &parser.WAFLogEntry{}}, false},
+		{"no_such_field", ALBAdapter{ALBLogEntry: &parser.ALBLogEntry{}}, true},
+	}
+
+	for _, tt := range tests {
+		g, err := ParseGroupKey(tt.expr)
+		if err != nil {
+			t.Fatalf("ParseGroupKey(%q) error = %v", tt.expr, err)
+		}
+		validateErr := g.Validate(tt.sample)
+		if (validateErr != nil) != tt.wantErr {
+			t.Errorf("Validate(%q) error = %v, wantErr %v", tt.expr, validateErr, tt.wantErr)
+		}
+	}
+}
+
+func TestGroupKeyAdapter(t *testing.T) {
+	g, _ := ParseGroupKey("httpSourceId,httpRequest.country")
+	entry := &WAFAdapter{WAFLogEntry: &parser.WAFLogEntry{
+		WebACLID:     "arn:aws:wafv2:us-east-1:123456789012:regional/webacl/test/abc",
+		HTTPSourceID: "123456789012:app/my-alb",
+		HTTPRequest:  parser.HTTPRequest{Country: "IN"},
+	}}
+
+	a := NewGroupKeyAdapter(entry, g)
+	if got := a.GetResourceKey(); got != "123456789012:app/my-alb|IN" {
+		t.Errorf("GetResourceKey() = %q, want 123456789012:app/my-alb|IN", got)
+	}
+
+	attrs := map[string]string{}
+	for _, attr := range a.GetResourceAttributes() {
+		if attr.Value.StringValue != nil {
+			attrs[attr.Key] = *attr.Value.StringValue
+		}
+	}
+	if attrs["aws.group.httpSourceId"] != "123456789012:app/my-alb" || attrs["aws.group.httpRequest_country"] != "IN" {
+		t.Errorf("group attributes missing: %v", attrs)
+	}
+	if _, ok := attrs["aws.waf.web_acl_id"]; ok {
+		t.Error("per-entry resource attribute aws.waf.web_acl_id should be dropped")
+	}
+	if attrs["cloud.provider"] != "aws" {
+		t.Error("cloud.provider should be kept")
+	}
+
+	empty := NewGroupKeyAdapter(&WAFAdapter{WAFLogEntry: &parser.WAFLogEntry{}}, g)
+	if got := empty.GetResourceKey(); got != "-|-" {
+		t.Errorf("GetResourceKey() of empty entry = %q, want -|-", got)
+	}
+	for _, attr := range empty.GetResourceAttributes() {
+		if strings.HasPrefix(attr.Key, "aws.group.") {
+			t.Errorf("missing field exported as %s", attr.Key)
+		}
+	}
+}
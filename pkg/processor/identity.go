@@ -0,0 +1,87 @@
+package processor
+
+import (
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	"github.com/pixelvide/otel-aws-log-parser/cmd/lambda/adapter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+)
+
+// Identity attributes records to a service and instrumentation scope. Empty fields keep what
+// the adapter reports, e.g. the processor's default service.name.
+type Identity struct {
+	ServiceName      string
+	ServiceNamespace string
+	Environment      string
+	Scope            converter.Scope
+}
+
+// Empty reports whether the identity changes nothing
+func (id *Identity) Empty() bool {
+	return id == nil || *id == Identity{}
+}
+
+// Apply wraps the entry with the identity; an empty identity returns the entry unchanged
+func (id *Identity) Apply(entry adapter.LogAdapter) adapter.LogAdapter {
+	if id.Empty() || entry == nil {
+		return entry
+	}
+	return IdentityAdapter{LogAdapter: entry, Identity: id}
+}
+
+// ScopedAdapter is implemented by adapters that name their own instrumentation scope
+type ScopedAdapter interface {
+	GetScope() converter.Scope
+}
+
+// ScopeOf returns the scope of the entry or of the outermost adapter it wraps that has one,
+// with fallback filling any field it leaves empty
+func ScopeOf(entry adapter.LogAdapter, fallback converter.Scope) converter.Scope {
+	scoped, ok := findAdapter[ScopedAdapter](entry)
+	if !ok {
+		return fallback
+	}
+	scope := scoped.GetScope()
+	if scope.Name == "" {
+		scope.Name = fallback.Name
+	}
+	if scope.Version == "" {
+		scope.Version = fallback.Version
+	}
+	return scope
+}
+
+// IdentityAdapter sets service.name, service.namespace and deployment.environment on an
+// adapter's resource attributes and reports the identity's scope
+type IdentityAdapter struct {
+	adapter.LogAdapter
+	Identity *Identity
+}
+
+func (a IdentityAdapter) Unwrap() adapter.LogAdapter { return a.LogAdapter }
+
+func (a IdentityAdapter) GetResourceAttributes() []converter.OTelAttribute {
+	// Copied so replacing an attribute never writes to the wrapped adapter's slice
+	attrs := append([]converter.OTelAttribute(nil), a.LogAdapter.GetResourceAttributes()...)
+	set := func(key, value string) {
+		if value == "" {
+			return
+		}
+		attr := converter.OTelAttribute{Key: key, Value: converter.OTelAnyValue{StringValue: aws.String(value)}}
+		for i := range attrs {
+			if attrs[i].Key == key {
+				attrs[i] = attr
+				return
+			}
+		}
+		attrs = append(attrs, attr)
+	}
+	set("service.name", a.Identity.ServiceName)
+	set("service.namespace", a.Identity.ServiceNamespace)
+	set("deployment.environment", a.Identity.Environment)
+	return attrs
+}
+
+func (a IdentityAdapter) GetScope() converter.Scope {
+	return a.Identity.Scope
+}
@@ -0,0 +1,56 @@
+package processor
+
+import (
+	"testing"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/parser"
+)
+
+func TestIdentity_Apply(t *testing.T) {
+	entry := NLBAdapter{NLBLogEntry: &parser.NLBLogEntry{}}
+	fallback := converter.Scope{Name: "otel-aws-log-parser", Version: "dev"}
+
+	var none *Identity
+	if got := none.Apply(entry); got != entry {
+		t.Errorf("nil identity wrapped the entry: %T", got)
+	}
+	if got := ScopeOf(entry, fallback); got != fallback {
+		t.Errorf("ScopeOf(unwrapped) = %+v, want %+v", got, fallback)
+	}
+
+	id := &Identity{ServiceName: "edge-nlb", Environment: "prod", Scope: converter.Scope{Name: "nlb"}}
+	wrapped := id.Apply(entry)
+	attrs := wrapped.GetResourceAttributes()
+
+	if got := attrValue(attrs, "service.name"); got != "edge-nlb" {
+		t.Errorf("service.name = %q, want edge-nlb", got)
+	}
+	if got := attrValue(attrs, "deployment.environment"); got != "prod" {
+		t.Errorf("deployment.environment = %q, want prod", got)
+	}
+	if got := attrValue(attrs, "service.namespace"); got != "" {
+		t.Errorf("service.namespace = %q, want unset", got)
+	}
+	count := 0
+	for _, attr := range attrs {
+		if attr.Key == "service.name" {
+			count++
+		}
+	}
+	if count != 1 {
+		t.Errorf("service.name set %d times, want 1", count)
+	}
+	if got := attrValue(entry.GetResourceAttributes(), "service.name"); got != "nlb-log-parser" {
+		t.Errorf("wrapped entry's service.name = %q, want nlb-log-parser", got)
+	}
+
+	want := converter.Scope{Name: "nlb", Version: "dev"}
+	if got := ScopeOf(wrapped, fallback); got != want {
+		t.Errorf("ScopeOf() = %+v, want %+v", got, want)
+	}
+	// Decorators added outside the identity do not hide its scope
+	if got := ScopeOf(SequencedAdapter{LogAdapter: wrapped, Sequence: 1}, fallback); got != want {
+		t.Errorf("ScopeOf(decorated) = %+v, want %+v", got, want)
+	}
+}
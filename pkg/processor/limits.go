@@ -0,0 +1,37 @@
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrObjectTooLarge is returned for objects over ObjectLimits.MaxBytes
+	ErrObjectTooLarge = errors.New("object exceeds the size limit")
+	// ErrObjectTooOld is returned for objects last modified before ObjectLimits.MaxAge
+	ErrObjectTooOld = errors.New("object is older than the age limit")
+)
+
+// ObjectLimits guards against objects the function should not read, such as a bucket-wide
+// re-delivery of months-old logs. Zero values disable a limit.
+type ObjectLimits struct {
+	// MaxBytes is the largest object size accepted, as stored (before decompression)
+	MaxBytes int64
+	// MaxAge is the oldest LastModified accepted, relative to now
+	MaxAge time.Duration
+}
+
+// Check returns ErrObjectTooLarge or ErrObjectTooOld, wrapped with the offending value, when
+// obj breaks a limit
+func (l ObjectLimits) Check(obj *Object, now time.Time) error {
+	if l.MaxBytes > 0 && obj.Size > l.MaxBytes {
+		return fmt.Errorf("%w: %d bytes, limit %d", ErrObjectTooLarge, obj.Size, l.MaxBytes)
+	}
+	if l.MaxAge > 0 && !obj.LastModified.IsZero() {
+		if age := now.Sub(obj.LastModified); age > l.MaxAge {
+			return fmt.Errorf("%w: last modified %s ago, limit %s", ErrObjectTooOld, age.Round(time.Second), l.MaxAge)
+		}
+	}
+	return nil
+}
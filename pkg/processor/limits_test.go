@@ -0,0 +1,31 @@
+package processor
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestObjectLimits_Check(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		limits ObjectLimits
+		obj    Object
+		want   error
+	}{
+		{"no limits", ObjectLimits{}, Object{Size: 1 << 40, LastModified: now.AddDate(-5, 0, 0)}, nil},
+		{"within limits", ObjectLimits{MaxBytes: 100, MaxAge: time.Hour}, Object{Size: 100, LastModified: now.Add(-time.Minute)}, nil},
+		{"too large", ObjectLimits{MaxBytes: 100}, Object{Size: 101, LastModified: now}, ErrObjectTooLarge},
+		{"too old", ObjectLimits{MaxAge: time.Hour}, Object{Size: 1, LastModified: now.Add(-2 * time.Hour)}, ErrObjectTooOld},
+		{"unknown age", ObjectLimits{MaxAge: time.Hour}, Object{Size: 1}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.limits.Check(&tt.obj, now)
+			if !errors.Is(err, tt.want) || (tt.want == nil && err != nil) {
+				t.Errorf("Check() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
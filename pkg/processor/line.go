@@ -0,0 +1,100 @@
+package processor
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pixelvide/otel-aws-log-parser/cmd/lambda/adapter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/parser"
+)
+
+// Formats accepted by ParseLine and AdapterForLine
+var Formats = []string{"alb", "nlb", "cloudfront", "waf"}
+
+// FormatAuto makes ParseLine pick the format of each line with DetectFormat
+const FormatAuto = "auto"
+
+// albTypes are the connection types an ALB log line starts with
+var albTypes = map[string]bool{"http": true, "https": true, "h2": true, "grpcs": true, "ws": true, "wss": true}
+
+// DetectFormat guesses the format of a single log line from its shape: WAF records are JSON
+// objects, ALB and NLB lines start with their connection type, and CloudFront lines are
+// tab-separated. It returns "" when the line matches none of them.
+func DetectFormat(line string) string {
+	line = strings.TrimSpace(line)
+	switch {
+	case strings.HasPrefix(line, "{"):
+		if strings.Contains(line, `"webaclId"`) {
+			return "waf"
+		}
+	case strings.Count(line, "\t") >= 32:
+		return "cloudfront"
+	default:
+		first, _, _ := strings.Cut(line, " ")
+		if albTypes[first] {
+			return "alb"
+		}
+		if first == "tls" {
+			return "nlb"
+		}
+	}
+	return ""
+}
+
+// ParseLine parses a single log line of the given format ("alb", "nlb", "cloudfront", "waf"
+// or "auto"). It returns a nil entry and no error for blank and comment lines.
+func ParseLine(format, line string) (any, error) {
+	if strings.EqualFold(format, FormatAuto) {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			return nil, nil
+		}
+		if format = DetectFormat(trimmed); format == "" {
+			return nil, fmt.Errorf("unrecognized log line format")
+		}
+	}
+
+	switch strings.ToLower(format) {
+	case "alb":
+		if entry, err := parser.ParseLogLine(line); entry != nil || err != nil {
+			return entry, err
+		}
+	case "nlb":
+		if entry, err := parser.ParseNLBLogLine(line); entry != nil || err != nil {
+			return entry, err
+		}
+	case "cloudfront":
+		if entry, err := parser.ParseCloudFrontLogLine(line); entry != nil || err != nil {
+			return entry, err
+		}
+	case "waf":
+		if entry, err := parser.ParseWAFLogLine(line); entry != nil || err != nil {
+			return entry, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown log format %q", format)
+	}
+	return nil, nil
+}
+
+// AdapterForLine parses a single line and wraps it in the same adapter the processors use,
+// so callers outside the Lambda get identical resource attributes and OTel records.
+// Account and region are not known without the S3 key and are left to the entry's own fields.
+func AdapterForLine(format, line string) (adapter.LogAdapter, error) {
+	entry, err := ParseLine(format, line)
+	if err != nil || entry == nil {
+		return nil, err
+	}
+
+	switch e := entry.(type) {
+	case *parser.ALBLogEntry:
+		return ALBAdapter{ALBLogEntry: e}, nil
+	case *parser.NLBLogEntry:
+		return NLBAdapter{e}, nil
+	case *parser.CloudFrontLogEntry:
+		return CloudFrontAdapter{CloudFrontLogEntry: e}, nil
+	case *parser.WAFLogEntry:
+		return &WAFAdapter{WAFLogEntry: e}, nil
+	}
+	return nil, fmt.Errorf("unsupported entry type %T", entry)
+}
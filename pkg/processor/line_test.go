@@ -0,0 +1,52 @@
+package processor
+
+import (
+	"strings"
+	"testing"
+)
+
+const (
+	testALBLine = `http 2018-07-02T22:23:00.186641Z app/my-loadbalancer/50dc6c495c0c9188 192.168.131.39:2817 10.0.0.1:80 0.000 0.001 0.000 200 200 34 366 "GET http://www.example.com:80/ HTTP/1.1" "curl/7.46.0" - - arn:aws:elasticloadbalancing:us-east-2:123456789012:targetgroup/my-targets/73e2d6bc24d8a067 "Root=1-58337262-36d228ad5d99923122bbe354" "www.example.com" "-" 100 2018-07-02T22:22:48.364000Z "forward" "-" "-" "10.0.0.1:80" "200" "-" "-" -`
+	testNLBLine = "tls 2.0 2023-10-01T00:00:00.000000Z app/net-lb/1234567890abcdef listener/net-lb/1234567890abcdef/1234567890abcdef 1.2.3.4:12345 5.6.7.8:80 0.001 0.002 100 200 - arn:aws:acm:us-east-1:123456789012:certificate/12345678-1234-1234-1234-123456789012 - ECDHE-RSA-AES128-GCM-SHA256 TLSv1.2 - example.com h2 - - 2023-10-01T00:00:00.000000Z"
+	testWAFLine = `{"timestamp":1683355580000,"formatVersion":1,"webaclId":"arn:aws:wafv2:eu-west-3:111122223333:regional/webacl/TEST-WEBACL/123","terminatingRuleId":"Default_Action","terminatingRuleType":"REGULAR","action":"ALLOW","httpSourceName":"APIGW","httpSourceId":"EXAMPLE11","httpRequest":{"clientIp":"1.2.3.4","country":"US","headers":[],"uri":"/valid","args":"","httpVersion":"HTTP/1.1","httpMethod":"GET","requestId":"request-2"}}`
+)
+
+func TestDetectFormat(t *testing.T) {
+	cloudfront := strings.Join(append([]string{"2019-12-04", "21:02:31"}, strings.Split(strings.Repeat("-", 31), "")...), "\t")
+
+	tests := []struct {
+		name string
+		line string
+		want string
+	}{
+		{"alb", testALBLine, "alb"},
+		{"nlb", testNLBLine, "nlb"},
+		{"waf", testWAFLine, "waf"},
+		{"cloudfront", cloudfront, "cloudfront"},
+		{"other json", `{"level":"info"}`, ""},
+		{"plain text", "hello world", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectFormat(tt.line); got != tt.want {
+				t.Errorf("DetectFormat() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAdapterForLine_Auto(t *testing.T) {
+	for _, line := range []string{testALBLine, testNLBLine, testWAFLine} {
+		entry, err := AdapterForLine(FormatAuto, line)
+		if err != nil || entry == nil {
+			t.Errorf("AdapterForLine(auto, %.20q) = %v, %v", line, entry, err)
+		}
+	}
+
+	if entry, err := AdapterForLine(FormatAuto, "# comment"); entry != nil || err != nil {
+		t.Errorf("comment line = %v, %v, want nil, nil", entry, err)
+	}
+	if _, err := AdapterForLine(FormatAuto, "hello world"); err == nil {
+		t.Error("unrecognized line: want error")
+	}
+}
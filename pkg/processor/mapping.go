@@ -0,0 +1,32 @@
+package processor
+
+import (
+	"github.com/pixelvide/otel-aws-log-parser/cmd/lambda/adapter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+)
+
+// MappedAdapter applies an attribute mapping to an adapter's record and resource attributes
+type MappedAdapter struct {
+	adapter.LogAdapter
+	Mapping *converter.AttributeMapping
+}
+
+func (a MappedAdapter) Unwrap() adapter.LogAdapter { return a.LogAdapter }
+
+// ApplyMapping wraps the entry with the mapping; a nil mapping returns the entry unchanged
+func ApplyMapping(entry adapter.LogAdapter, m *converter.AttributeMapping) adapter.LogAdapter {
+	if m == nil || entry == nil {
+		return entry
+	}
+	return MappedAdapter{LogAdapter: entry, Mapping: m}
+}
+
+func (a MappedAdapter) GetResourceAttributes() []converter.OTelAttribute {
+	return a.Mapping.Resource(a.LogAdapter.GetResourceAttributes())
+}
+
+func (a MappedAdapter) ToOTel() converter.OTelLogRecord {
+	record := a.LogAdapter.ToOTel()
+	record.Attributes = a.Mapping.Records(record.Attributes)
+	return record
+}
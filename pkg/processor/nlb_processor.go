@@ -5,15 +5,13 @@ import (
 	"log/slog"
 	"strings"
 
-	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/pixelvide/otel-aws-log-parser/cmd/lambda/adapter"
 	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
 	"github.com/pixelvide/otel-aws-log-parser/pkg/parser"
 )
 
 type NLBProcessor struct {
-	MaxBatchSize  int
-	MaxConcurrent int
+	ReadOptions
 }
 
 func (p *NLBProcessor) Name() string {
@@ -24,14 +22,20 @@ func (p *NLBProcessor) Matches(bucket, key string) bool {
 	return strings.Contains(key, "/elasticloadbalancing/") && strings.Contains(key, "_net.")
 }
 
-func (p *NLBProcessor) Process(ctx context.Context, logger *slog.Logger, s3Client *s3.S3, bucket, key string) ([]adapter.LogAdapter, error) {
-	return ReadAndParseFromS3(logger, s3Client, bucket, key, p.MaxBatchSize, p.MaxConcurrent, func(line string) (adapter.LogAdapter, error) {
-		entry, err := parser.ParseNLBLogLine(line)
-		if err != nil {
-			return nil, err
-		}
-		return NLBAdapter{entry}, nil
-	})
+func (p *NLBProcessor) Process(ctx context.Context, logger *slog.Logger, store ObjectStore, bucket, key string) ([]adapter.LogAdapter, error) {
+	return ReadAndParseObject(ctx, logger, store, bucket, key, p.ReadOptions, parseNLBLine)
+}
+
+func (p *NLBProcessor) ProcessStream(ctx context.Context, logger *slog.Logger, store ObjectStore, bucket, key string, emit EmitFunc, cp *Checkpoint) error {
+	return StreamAndParseObject(ctx, logger, store, bucket, key, p.ReadOptions, parseNLBLine, emit, cp)
+}
+
+func parseNLBLine(line string) (adapter.LogAdapter, error) {
+	entry, err := parser.ParseNLBLogLine(line)
+	if err != nil {
+		return nil, err
+	}
+	return NLBAdapter{entry}, nil
 }
 
 // NLBAdapter implementation
@@ -0,0 +1,80 @@
+package processor
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// ParseS3Notification extracts the S3 objects of an SQS message body: an EventBridge S3 event
+// or an S3 event notification, either of them optionally wrapped in an SNS notification. S3
+// test events and notifications without records yield no objects and no error.
+func ParseS3Notification(logger *slog.Logger, body []byte) ([]events.S3EventRecord, error) {
+	var probe struct {
+		Type    string             `json:"Type"`
+		Message string             `json:"Message"`
+		Event   string             `json:"Event"`
+		Bucket  string             `json:"Bucket"`
+		Records *[]json.RawMessage `json:"Records"`
+	}
+	if err := json.Unmarshal(body, &probe); err == nil {
+		switch {
+		case probe.Type == "Notification" && probe.Message != "":
+			// SNS fan-out delivers the S3 event as a string inside the SNS envelope
+			return ParseS3Notification(logger, []byte(probe.Message))
+		case probe.Event == "s3:TestEvent":
+			// Sent once when a bucket notification is (re)configured
+			logger.Info("Skipping S3 test event", "bucket", probe.Bucket)
+			return nil, nil
+		case probe.Records != nil && len(*probe.Records) == 0:
+			logger.Info("Skipping S3 notification without records")
+			return nil, nil
+		}
+	}
+
+	// Try EventBridge S3 Event (common in SQS)
+	var ebEvent EventBridgeS3Event
+	if err := json.Unmarshal(body, &ebEvent); err == nil {
+		if ebEvent.Source == "aws.s3" && ebEvent.Detail.Bucket.Name != "" {
+			return []events.S3EventRecord{{
+				S3: events.S3Entity{
+					Bucket: events.S3Bucket{Name: ebEvent.Detail.Bucket.Name},
+					Object: events.S3Object{Key: ebEvent.Detail.Object.Key, ETag: ebEvent.Detail.Object.ETag},
+				},
+				AWSRegion: ebEvent.Region,
+			}}, nil
+		}
+	}
+
+	// Try an S3 event notification delivered straight to SQS, which may carry several objects
+	var s3Event events.S3Event
+	if err := json.Unmarshal(body, &s3Event); err == nil && len(s3Event.Records) > 0 {
+		records := make([]events.S3EventRecord, 0, len(s3Event.Records))
+		for _, record := range s3Event.Records {
+			// Notification keys are URL-encoded
+			record.S3.Object.Key = record.S3.Object.URLDecodedKey
+			records = append(records, record)
+		}
+		return records, nil
+	}
+
+	return nil, fmt.Errorf("body does not match EventBridge or S3 notification format")
+}
+
+// EventBridgeS3Event is an S3 event delivered through EventBridge
+type EventBridgeS3Event struct {
+	Source     string `json:"source"`
+	DetailType string `json:"detail-type"`
+	Region     string `json:"region"`
+	Detail     struct {
+		Bucket struct {
+			Name string `json:"name"`
+		} `json:"bucket"`
+		Object struct {
+			Key  string `json:"key"`
+			ETag string `json:"etag"`
+		} `json:"object"`
+	} `json:"detail"`
+}
@@ -0,0 +1,93 @@
+package processor
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+)
+
+func TestParseS3Notification(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	tests := []struct {
+		name        string
+		input       string
+		wantCount   int
+		wantBucket  string
+		wantKey     string
+		expectError bool
+	}{
+		{
+			name:       "EventBridge S3 Event",
+			input:      `{"version":"0","id":"d1a0...","detail-type":"Object Created","source":"aws.s3","account":"123","time":"2026-01-01T00:00:00Z","region":"ap-south-1","detail":{"bucket":{"name":"sqs-eb-bucket"},"object":{"key":"sqs-eb-key"}}}`,
+			wantCount:  1,
+			wantBucket: "sqs-eb-bucket",
+			wantKey:    "sqs-eb-key",
+		},
+		{
+			name:       "S3 notification with several objects",
+			input:      `{"Records":[{"eventSource":"aws:s3","s3":{"bucket":{"name":"notify-bucket"},"object":{"key":"AWSLogs/my+log%3D1.gz","eTag":"abc"}}},{"eventSource":"aws:s3","s3":{"bucket":{"name":"notify-bucket"},"object":{"key":"other.gz"}}}]}`,
+			wantCount:  2,
+			wantBucket: "notify-bucket",
+			wantKey:    "AWSLogs/my log=1.gz",
+		},
+		{
+			name:       "EventBridge S3 Event through SNS",
+			input:      `{"Type":"Notification","MessageId":"1","TopicArn":"arn:aws:sns:ap-south-1:123:logs","Message":"{\"source\":\"aws.s3\",\"detail\":{\"bucket\":{\"name\":\"sns-bucket\"},\"object\":{\"key\":\"sns-key\"}}}"}`,
+			wantCount:  1,
+			wantBucket: "sns-bucket",
+			wantKey:    "sns-key",
+		},
+		{
+			name:      "S3 test event",
+			input:     `{"Service":"Amazon S3","Event":"s3:TestEvent","Time":"2026-01-01T00:00:00.000Z","Bucket":"notify-bucket","RequestId":"5582815E1AEA5ADF","HostId":"8cLeGAmw098X5cv4Zkwcmo8vvZa3eH3eKxsPzbB9wrR+YstdA6Knx4Ip8EXAMPLE"}`,
+			wantCount: 0,
+		},
+		{
+			name:      "S3 test event through SNS",
+			input:     `{"Type":"Notification","Message":"{\"Service\":\"Amazon S3\",\"Event\":\"s3:TestEvent\",\"Bucket\":\"notify-bucket\"}"}`,
+			wantCount: 0,
+		},
+		{
+			name:      "Notification without records",
+			input:     `{"Records":[]}`,
+			wantCount: 0,
+		},
+		{
+			name:        "Invalid JSON",
+			input:       `{ "foo": "bar" }`,
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseS3Notification(logger, []byte(tt.input))
+
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("ParseS3Notification() expected error, got nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("ParseS3Notification() unexpected error: %v", err)
+				return
+			}
+
+			if len(got) != tt.wantCount {
+				t.Errorf("ParseS3Notification() got %d records, want %d", len(got), tt.wantCount)
+			}
+
+			if len(got) > 0 {
+				if got[0].S3.Bucket.Name != tt.wantBucket {
+					t.Errorf("Bucket = %s, want %s", got[0].S3.Bucket.Name, tt.wantBucket)
+				}
+				if got[0].S3.Object.Key != tt.wantKey {
+					t.Errorf("Key = %s, want %s", got[0].S3.Object.Key, tt.wantKey)
+				}
+			}
+		})
+	}
+}
@@ -2,9 +2,11 @@ package processor
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
+	"regexp"
+	"strings"
 
-	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/pixelvide/otel-aws-log-parser/cmd/lambda/adapter"
 )
 
@@ -15,18 +17,38 @@ type LogProcessor interface {
 	// Matches returns true if this processor should handle the given S3 object
 	Matches(bucket, key string) bool
 	// Process handles the log file and returns OTel-ready adapters
-	Process(ctx context.Context, logger *slog.Logger, s3Client *s3.S3, bucket, key string) ([]adapter.LogAdapter, error)
+	Process(ctx context.Context, logger *slog.Logger, store ObjectStore, bucket, key string) ([]adapter.LogAdapter, error)
+}
+
+// EmitFunc receives parsed entries one at a time; a non-nil error stops processing
+type EmitFunc func(entry adapter.LogAdapter) error
+
+// StreamProcessor is implemented by processors that can hand entries over while the object
+// is still being read, instead of returning them all at once. cp may be nil.
+type StreamProcessor interface {
+	ProcessStream(ctx context.Context, logger *slog.Logger, store ObjectStore, bucket, key string, emit EmitFunc, cp *Checkpoint) error
 }
 
 // Registry manages the available processors
 type Registry struct {
 	processors []LogProcessor
+	// forced maps a bucket to the processor of all of its objects
+	forced map[string]LogProcessor
+	// patterns are tried in order before the processors' own Matches
+	patterns []keyPattern
+}
+
+// keyPattern sends the objects whose key matches re to proc
+type keyPattern struct {
+	re   *regexp.Regexp
+	proc LogProcessor
 }
 
 // NewRegistry creates a new processor registry
 func NewRegistry() *Registry {
 	return &Registry{
 		processors: make([]LogProcessor, 0),
+		forced:     make(map[string]LogProcessor),
 	}
 }
 
@@ -35,8 +57,72 @@ func (r *Registry) Register(p LogProcessor) {
 	r.processors = append(r.processors, p)
 }
 
-// Find returns the first processor that matches the bucket and key
+// lookup returns the registered processor of the given name, compared case-insensitively
+func (r *Registry) lookup(name string) (LogProcessor, error) {
+	for _, p := range r.processors {
+		if strings.EqualFold(p.Name(), name) {
+			return p, nil
+		}
+	}
+	return nil, fmt.Errorf("processor %q is not enabled", name)
+}
+
+// MatchKeys sends the objects whose key matches pattern, a regular expression, to the named
+// processor, for logs delivered under a prefix its own Matches does not recognize. Patterns
+// are tried in the order they were added, before any processor's Matches.
+func (r *Registry) MatchKeys(name, pattern string) error {
+	p, err := r.lookup(name)
+	if err != nil {
+		return err
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return fmt.Errorf("invalid %s key pattern: %w", name, err)
+	}
+	r.patterns = append(r.patterns, keyPattern{re: re, proc: p})
+	return nil
+}
+
+// Force sends every object of bucket to the named processor, whatever its key
+func (r *Registry) Force(bucket, name string) error {
+	p, err := r.lookup(name)
+	if err != nil {
+		return err
+	}
+	r.forced[bucket] = p
+	return nil
+}
+
+// ParseBucketProcessors parses a comma-separated list of "bucket=processor" entries, e.g.
+// "legacy-alb-logs=alb". An empty spec maps nothing.
+func ParseBucketProcessors(spec string) (map[string]string, error) {
+	forced := make(map[string]string)
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		bucket, name, ok := strings.Cut(part, "=")
+		bucket, name = strings.TrimSpace(bucket), strings.TrimSpace(name)
+		if !ok || bucket == "" || name == "" {
+			return nil, fmt.Errorf("invalid forced processor %q: want <bucket>=<processor>", part)
+		}
+		forced[bucket] = name
+	}
+	return forced, nil
+}
+
+// Find returns the processor forced for the bucket, else the processor of the first key pattern
+// the key matches, else the first processor that matches the bucket and key
 func (r *Registry) Find(bucket, key string) LogProcessor {
+	if p, ok := r.forced[bucket]; ok {
+		return p
+	}
+	for _, pattern := range r.patterns {
+		if pattern.re.MatchString(key) {
+			return pattern.proc
+		}
+	}
 	for _, p := range r.processors {
 		if p.Matches(bucket, key) {
 			return p
@@ -3,6 +3,8 @@ package processor_test
 import (
 	"testing"
 
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/parser"
 	"github.com/pixelvide/otel-aws-log-parser/pkg/processor"
 )
 
@@ -47,3 +49,152 @@ func TestProcessorMatching(t *testing.T) {
 		})
 	}
 }
+
+func TestRegistry_Overrides(t *testing.T) {
+	reg := processor.NewRegistry()
+	reg.Register(&processor.ALBProcessor{})
+	reg.Register(&processor.WAFProcessor{})
+	if err := reg.MatchKeys("alb", `^lb-logs/`); err != nil {
+		t.Fatalf("MatchKeys() error = %v", err)
+	}
+	if err := reg.Force("legacy-waf", "WAF"); err != nil {
+		t.Fatalf("Force() error = %v", err)
+	}
+
+	albKey := "AWSLogs/123/elasticloadbalancing/us-east-1/2023/01/01/123_elasticloadbalancing_us-east-1_app.my-lb.123_20230101T0000Z_1.2.3.4_123.log.gz"
+	tests := []struct {
+		name   string
+		bucket string
+		key    string
+		want   string
+	}{
+		{name: "key pattern", bucket: "logs", key: "lb-logs/2023/01/01/access.log.gz", want: "ALB"},
+		{name: "forced bucket", bucket: "legacy-waf", key: albKey, want: "WAF"},
+		{name: "built-in matching", bucket: "logs", key: albKey, want: "ALB"},
+		{name: "no match", bucket: "logs", key: "other/access.log.gz", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ""
+			if p := reg.Find(tt.bucket, tt.key); p != nil {
+				got = p.Name()
+			}
+			if got != tt.want {
+				t.Errorf("Find() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if err := reg.MatchKeys("nlb", `.*`); err == nil {
+		t.Error("MatchKeys() error = nil, want an error for a processor that is not enabled")
+	}
+	if err := reg.MatchKeys("alb", `(`); err == nil {
+		t.Error("MatchKeys() error = nil, want an error for an invalid pattern")
+	}
+	if err := reg.Force("bucket", "cloudfront"); err == nil {
+		t.Error("Force() error = nil, want an error for a processor that is not enabled")
+	}
+}
+
+func TestParseBucketProcessors(t *testing.T) {
+	got, err := processor.ParseBucketProcessors(" legacy-alb = alb ,waf-logs=waf,")
+	if err != nil {
+		t.Fatalf("ParseBucketProcessors() error = %v", err)
+	}
+	if len(got) != 2 || got["legacy-alb"] != "alb" || got["waf-logs"] != "waf" {
+		t.Errorf("ParseBucketProcessors() = %v", got)
+	}
+	for _, spec := range []string{"legacy-alb", "=alb", "legacy-alb="} {
+		if _, err := processor.ParseBucketProcessors(spec); err == nil {
+			t.Errorf("ParseBucketProcessors(%q) error = nil, want an error", spec)
+		}
+	}
+}
+
+func TestSequencedAdapter_ToOTel(t *testing.T) {
+	entry := processor.SequencedAdapter{
+		LogAdapter: processor.NLBAdapter{NLBLogEntry: &parser.NLBLogEntry{Type: "tls", ELB: "net/my-lb/123"}},
+		Sequence:   42,
+	}
+
+	record := entry.ToOTel()
+
+	found := false
+	for _, attr := range record.Attributes {
+		if attr.Key == "log.record.sequence" {
+			found = true
+			if attr.Value.IntValue == nil || *attr.Value.IntValue != "42" {
+				t.Errorf("log.record.sequence = %v, want 42", attr.Value.IntValue)
+			}
+		}
+	}
+	if !found {
+		t.Error("log.record.sequence attribute not found")
+	}
+}
+
+func TestSourceAdapter(t *testing.T) {
+	inner := processor.NLBAdapter{NLBLogEntry: &parser.NLBLogEntry{Type: "tls", ELB: "net/my-lb/123", ListenerID: "listener-1"}}
+	src := processor.ObjectSource{Bucket: "logs", Key: "AWSLogs/123/file.log.gz"}
+
+	hasSourceAttr := func(attrs []converter.OTelAttribute) bool {
+		for _, attr := range attrs {
+			if attr.Key == "aws.s3.key" && attr.Value.StringValue != nil && *attr.Value.StringValue == src.Key {
+				return true
+			}
+		}
+		return false
+	}
+
+	resourceLevel := processor.SourceAdapter{LogAdapter: inner, Source: src, Mode: processor.SourceAttributesResource}
+	if !hasSourceAttr(resourceLevel.GetResourceAttributes()) {
+		t.Error("resource mode: aws.s3.key missing from resource attributes")
+	}
+	if hasSourceAttr(resourceLevel.ToOTel().Attributes) {
+		t.Error("resource mode: aws.s3.key unexpectedly present on log record")
+	}
+	if resourceLevel.GetResourceKey() == inner.GetResourceKey() {
+		t.Error("resource mode: resource key should include the source object")
+	}
+
+	recordLevel := processor.SourceAdapter{LogAdapter: inner, Source: src, Mode: processor.SourceAttributesRecord}
+	if hasSourceAttr(recordLevel.GetResourceAttributes()) {
+		t.Error("record mode: aws.s3.key unexpectedly present in resource attributes")
+	}
+	if !hasSourceAttr(recordLevel.ToOTel().Attributes) {
+		t.Error("record mode: aws.s3.key missing from log record")
+	}
+	if recordLevel.GetResourceKey() != inner.GetResourceKey() {
+		t.Error("record mode: resource key should be unchanged")
+	}
+}
+
+func TestSourceLinkAdapter(t *testing.T) {
+	inner := processor.NLBAdapter{NLBLogEntry: &parser.NLBLogEntry{Type: "tls", ELB: "net/my-lb/123", ListenerID: "listener-1"}}
+
+	attrs := func(a processor.SourceLinkAdapter) map[string]string {
+		got := map[string]string{}
+		for _, attr := range a.ToOTel().Attributes {
+			switch {
+			case attr.Value.StringValue != nil:
+				got[attr.Key] = *attr.Value.StringValue
+			case attr.Value.IntValue != nil:
+				got[attr.Key] = *attr.Value.IntValue
+			}
+		}
+		return got
+	}
+
+	uriOnly := attrs(processor.SourceLinkAdapter{LogAdapter: inner, Source: processor.ObjectSource{Bucket: "logs", Key: "a/file.log.gz"}, Offset: 120, Length: 42})
+	if uriOnly["log.record.source.uri"] != "s3://logs/a/file.log.gz" || uriOnly["log.record.source.offset"] != "120" || uriOnly["log.record.source.length"] != "42" {
+		t.Errorf("uri mode attributes = %v", uriOnly)
+	}
+	if _, ok := uriOnly["log.record.source.url"]; ok {
+		t.Error("uri mode: unexpected presigned url")
+	}
+
+	presigned := attrs(processor.SourceLinkAdapter{LogAdapter: inner, Source: processor.ObjectSource{Bucket: "logs", Key: "a/file.log.gz", PresignedURL: "https://logs.s3.amazonaws.com/a/file.log.gz?X-Amz-Signature=abc"}})
+	if presigned["log.record.source.url"] == "" {
+		t.Error("presigned mode: log.record.source.url missing")
+	}
+}
@@ -0,0 +1,145 @@
+package processor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
+)
+
+// rangedReader reads an object as consecutive byte ranges, fetching up to concurrency of them
+// in parallel while handing the bytes out strictly in order. Memory is bounded by
+// concurrency × part size. Every range is conditional on the ETag of the first one, so an
+// object overwritten mid-read fails instead of mixing versions.
+type rangedReader struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	cur    io.ReadCloser
+	parts  chan *objectPart
+	err    error
+}
+
+// objectPart is one range being fetched; done is closed once data or err is set
+type objectPart struct {
+	done chan struct{}
+	data []byte
+	err  error
+}
+
+// newRangedReader continues reading after first, the body of the range [0, offset), up to size
+func newRangedReader(ctx context.Context, client *s3.Client, input s3.GetObjectInput, etag string, first io.ReadCloser, offset, size, partSize int64, concurrency int) *rangedReader {
+	ctx, cancel := context.WithCancel(ctx)
+	r := &rangedReader{
+		ctx:    ctx,
+		cancel: cancel,
+		cur:    first,
+		// The part being read and the ones queued here are all that is held in memory
+		parts: make(chan *objectPart, concurrency-1),
+	}
+	input.IfMatch = aws.String(etag)
+
+	go func() {
+		defer close(r.parts)
+		for start := offset; start < size; start += partSize {
+			end := min(start+partSize, size) - 1
+			part := &objectPart{done: make(chan struct{})}
+			select {
+			case r.parts <- part:
+			case <-ctx.Done():
+				return
+			}
+			go func(in s3.GetObjectInput) {
+				defer close(part.done)
+				in.Range = aws.String(fmt.Sprintf("bytes=%d-%d", start, end))
+				part.data, part.err = fetchRange(ctx, client, &in, end-start+1)
+			}(input)
+		}
+	}()
+	return r
+}
+
+func fetchRange(ctx context.Context, client *s3.Client, input *s3.GetObjectInput, want int64) ([]byte, error) {
+	out, err := client.GetObject(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get S3 object %s: %w", aws.ToString(input.Range), err)
+	}
+	defer out.Body.Close()
+	data := make([]byte, 0, want)
+	buf := bytes.NewBuffer(data)
+	if _, err := io.Copy(buf, out.Body); err != nil {
+		return nil, fmt.Errorf("failed to read S3 object %s: %w", aws.ToString(input.Range), err)
+	}
+	if int64(buf.Len()) != want {
+		return nil, fmt.Errorf("short read of S3 object %s: got %d bytes", aws.ToString(input.Range), buf.Len())
+	}
+	return buf.Bytes(), nil
+}
+
+func (r *rangedReader) Read(p []byte) (int, error) {
+	for r.err == nil {
+		if r.cur != nil {
+			n, err := r.cur.Read(p)
+			if err == io.EOF {
+				r.cur.Close()
+				r.cur = nil
+				if n == 0 {
+					continue
+				}
+				err = nil
+			}
+			return n, err
+		}
+
+		part, ok := <-r.parts
+		if !ok {
+			if r.ctx.Err() != nil {
+				r.err = context.Cause(r.ctx)
+				break
+			}
+			r.err = io.EOF
+			break
+		}
+		<-part.done
+		if part.err != nil {
+			r.err = part.err
+			break
+		}
+		r.cur = io.NopCloser(bytes.NewReader(part.data))
+	}
+	return 0, r.err
+}
+
+// Close stops the fetches still in flight
+func (r *rangedReader) Close() error {
+	r.cancel()
+	if r.cur != nil {
+		r.cur.Close()
+	}
+	for range r.parts {
+	}
+	return nil
+}
+
+// objectSizeFromRange returns the total size from a Content-Range header such as
+// "bytes 0-8388607/2147483648"
+func objectSizeFromRange(contentRange string) (int64, bool) {
+	_, total, ok := strings.Cut(contentRange, "/")
+	if !ok {
+		return 0, false
+	}
+	size, err := strconv.ParseInt(total, 10, 64)
+	return size, err == nil
+}
+
+// isInvalidRange reports whether S3 rejected a range, which happens for zero-byte objects
+func isInvalidRange(err error) bool {
+	var apiErr smithy.APIError
+	return errors.As(err, &apiErr) && apiErr.ErrorCode() == "InvalidRange"
+}
@@ -0,0 +1,113 @@
+package processor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// rangeServer serves objects from a map with S3's Range and If-Match semantics
+func rangeServer(t *testing.T, objects map[string][]byte, requests *atomic.Int32) *s3.Client {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		data := objects[r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]]
+		w.Header().Set("ETag", `"v1"`)
+		if m := r.Header.Get("If-Match"); m != "" && m != `"v1"` {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			io.WriteString(w, `<Error><Code>PreconditionFailed</Code><Message>At least one of the pre-conditions you specified did not hold</Message></Error>`)
+			return
+		}
+		rng := r.Header.Get("Range")
+		if rng == "" {
+			w.Write(data)
+			return
+		}
+		var start, end int64
+		fmt.Sscanf(rng, "bytes=%d-%d", &start, &end)
+		if start >= int64(len(data)) {
+			w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+			io.WriteString(w, `<Error><Code>InvalidRange</Code><Message>The requested range is not satisfiable</Message></Error>`)
+			return
+		}
+		end = min(end, int64(len(data))-1)
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(data)))
+		w.Header().Set("Content-Length", fmt.Sprint(end-start+1))
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write(data[start : end+1])
+	}))
+	t.Cleanup(srv.Close)
+
+	return s3.New(s3.Options{
+		Region:       "us-east-1",
+		BaseEndpoint: aws.String(srv.URL),
+		UsePathStyle: true,
+		Credentials:  credentials.NewStaticCredentialsProvider("id", "secret", ""),
+	})
+}
+
+func TestS3Store_RangedGet(t *testing.T) {
+	big := bytes.Repeat([]byte("0123456789abcdef\n"), 1000) // 17000 bytes
+	objects := map[string][]byte{"big": big, "small": []byte("hello\n"), "empty": {}}
+	var requests atomic.Int32
+	store := &S3Store{Client: rangeServer(t, objects, &requests), PartSize: 1024, PartConcurrency: 4}
+
+	tests := []struct {
+		key          string
+		wantRequests int32
+	}{
+		{"big", 17},
+		{"small", 1},
+		{"empty", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			requests.Store(0)
+			obj, err := store.GetObject(context.Background(), "bucket", tt.key)
+			if err != nil {
+				t.Fatalf("GetObject() error = %v", err)
+			}
+			data, err := io.ReadAll(obj.Body)
+			obj.Body.Close()
+			if err != nil {
+				t.Fatalf("read error = %v", err)
+			}
+			if !bytes.Equal(data, objects[tt.key]) || obj.Size != int64(len(objects[tt.key])) {
+				t.Errorf("read %d bytes (size %d), want %d", len(data), obj.Size, len(objects[tt.key]))
+			}
+			if got := requests.Load(); got != tt.wantRequests {
+				t.Errorf("made %d requests, want %d", got, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestS3Store_RangedGetCloseEarly(t *testing.T) {
+	big := bytes.Repeat([]byte("x"), 64*1024)
+	var requests atomic.Int32
+	store := &S3Store{Client: rangeServer(t, map[string][]byte{"big": big}, &requests), PartSize: 1024, PartConcurrency: 2}
+
+	obj, err := store.GetObject(context.Background(), "bucket", "big")
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 10)
+	if _, err := io.ReadFull(obj.Body, buf); err != nil {
+		t.Fatal(err)
+	}
+	obj.Body.Close()
+	// Only the parts that fit the read-ahead window were requested
+	if got := requests.Load(); got > 4 {
+		t.Errorf("made %d requests after closing early, want at most 4", got)
+	}
+}
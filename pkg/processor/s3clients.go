@@ -0,0 +1,139 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// bucketRegionTimeout bounds the lookup of a bucket's region
+const bucketRegionTimeout = 5 * time.Second
+
+// S3ClientPool hands out one S3 client per IAM role and region, so a single function can read
+// log buckets that live in other accounts and regions of an organization. Assumed-role
+// credentials are refreshed by the SDK before they expire.
+type S3ClientPool struct {
+	// Config is the base of every client: its region, credentials and retry settings
+	Config aws.Config
+	// Role is assumed for buckets without an entry in BucketRoles; empty uses the function's own role
+	Role string
+	// BucketRoles maps bucket names to the role to assume for them
+	BucketRoles map[string]string
+	// ExternalID is passed when assuming a role, if the trust policy requires one
+	ExternalID string
+	// DiscoverRegion looks up the region of a bucket no event reported one for; nil uses
+	// manager.GetBucketRegion
+	DiscoverRegion func(ctx context.Context, bucket string) (string, error)
+
+	mu      sync.Mutex
+	clients map[string]*s3.Client
+	regions map[string]string
+}
+
+// NoteBucketRegion records the region an event reported for a bucket, sparing a lookup. It is
+// a no-op on a nil pool.
+func (p *S3ClientPool) NoteBucketRegion(bucket, region string) {
+	if p == nil || region == "" {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.regions == nil {
+		p.regions = make(map[string]string)
+	}
+	p.regions[bucket] = region
+}
+
+// Client returns the client for reading bucket, in the bucket's region
+func (p *S3ClientPool) Client(bucket string) *s3.Client {
+	role, ok := p.BucketRoles[bucket]
+	if !ok {
+		role = p.Role
+	}
+	region := p.bucketRegion(bucket)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	id := role + "|" + region
+	if client, ok := p.clients[id]; ok {
+		return client
+	}
+	if p.clients == nil {
+		p.clients = make(map[string]*s3.Client)
+	}
+
+	client := s3.NewFromConfig(p.Config, func(o *s3.Options) {
+		if region != "" {
+			o.Region = region
+		}
+		if role != "" {
+			o.Credentials = aws.NewCredentialsCache(stscreds.NewAssumeRoleProvider(sts.NewFromConfig(p.Config), role, func(ar *stscreds.AssumeRoleOptions) {
+				ar.RoleSessionName = "otel-aws-log-parser"
+				if p.ExternalID != "" {
+					ar.ExternalID = aws.String(p.ExternalID)
+				}
+			}))
+		}
+	})
+	p.clients[id] = client
+	return client
+}
+
+// bucketRegion returns the noted or discovered region of a bucket. A failed lookup falls back
+// to the configured region ("") until an event reports the bucket's region.
+func (p *S3ClientPool) bucketRegion(bucket string) string {
+	p.mu.Lock()
+	region, ok := p.regions[bucket]
+	p.mu.Unlock()
+	if ok {
+		return region
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), bucketRegionTimeout)
+	defer cancel()
+	discover := p.DiscoverRegion
+	if discover == nil {
+		discover = func(ctx context.Context, bucket string) (string, error) {
+			return manager.GetBucketRegion(ctx, s3.NewFromConfig(p.Config), bucket)
+		}
+	}
+	region, err := discover(ctx, bucket)
+	if err != nil {
+		region = ""
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.regions == nil {
+		p.regions = make(map[string]string)
+	}
+	p.regions[bucket] = region
+	return region
+}
+
+// ParseBucketRoles parses a comma-separated list of "bucket=role-arn" entries,
+// e.g. "org-alb-logs=arn:aws:iam::111122223333:role/log-reader". An empty spec maps nothing.
+func ParseBucketRoles(spec string) (map[string]string, error) {
+	roles := make(map[string]string)
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		bucket, role, ok := strings.Cut(part, "=")
+		bucket, role = strings.TrimSpace(bucket), strings.TrimSpace(role)
+		if !ok || bucket == "" || !strings.HasPrefix(role, "arn:") {
+			return nil, fmt.Errorf("invalid bucket role %q: want <bucket>=<role-arn>", part)
+		}
+		roles[bucket] = role
+	}
+	return roles, nil
+}
@@ -0,0 +1,95 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+func TestParseBucketRoles(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    map[string]string
+		wantErr bool
+	}{
+		{"", map[string]string{}, false},
+		{"a=arn:aws:iam::1:role/r, b = arn:aws:iam::2:role/r", map[string]string{"a": "arn:aws:iam::1:role/r", "b": "arn:aws:iam::2:role/r"}, false},
+		{"a", nil, true},
+		{"a=role-name", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseBucketRoles(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseBucketRoles(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			continue
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("ParseBucketRoles(%q) = %v, want %v", tt.spec, got, tt.want)
+		}
+		for bucket, role := range tt.want {
+			if got[bucket] != role {
+				t.Errorf("ParseBucketRoles(%q)[%s] = %q, want %q", tt.spec, bucket, got[bucket], role)
+			}
+		}
+	}
+}
+
+func TestS3ClientPool(t *testing.T) {
+	pool := &S3ClientPool{
+		Config:      aws.Config{Region: "us-east-1"},
+		BucketRoles: map[string]string{"archive-a": "arn:aws:iam::1:role/r", "archive-b": "arn:aws:iam::1:role/r"},
+		DiscoverRegion: func(ctx context.Context, bucket string) (string, error) {
+			return "us-east-1", nil
+		},
+	}
+
+	own := pool.Client("local-logs")
+	if own != pool.Client("other-local-logs") {
+		t.Error("buckets without a role should share the default client")
+	}
+	assumed := pool.Client("archive-a")
+	if assumed == own {
+		t.Error("a bucket with a role should not use the default client")
+	}
+	if assumed != pool.Client("archive-b") {
+		t.Error("buckets with the same role should share a client")
+	}
+}
+
+func TestS3ClientPool_Regions(t *testing.T) {
+	lookups := 0
+	pool := &S3ClientPool{
+		Config: aws.Config{Region: "us-east-1"},
+		DiscoverRegion: func(ctx context.Context, bucket string) (string, error) {
+			lookups++
+			if bucket == "unknown" {
+				return "", errors.New("forbidden")
+			}
+			return "eu-west-1", nil
+		},
+	}
+
+	pool.NoteBucketRegion("noted", "ap-south-1")
+	tests := []struct {
+		bucket string
+		want   string
+	}{
+		{"noted", "ap-south-1"},
+		{"discovered", "eu-west-1"},
+		{"discovered", "eu-west-1"},
+		{"unknown", "us-east-1"},
+	}
+	for _, tt := range tests {
+		if got := pool.Client(tt.bucket).Options().Region; got != tt.want {
+			t.Errorf("Client(%s) region = %s, want %s", tt.bucket, got, tt.want)
+		}
+	}
+	if lookups != 2 {
+		t.Errorf("DiscoverRegion called %d times, want 2 (noted and cached buckets skip it)", lookups)
+	}
+	if pool.Client("discovered") == pool.Client("noted") {
+		t.Error("buckets in different regions should not share a client")
+	}
+}
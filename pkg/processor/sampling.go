@@ -0,0 +1,152 @@
+package processor
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+)
+
+// ObjectSampler keeps only every Nth object under configured prefixes. Selection is a
+// deterministic hash of the object key, so retries and replays make the same decision.
+type ObjectSampler struct {
+	rules []samplingRule
+}
+
+type samplingRule struct {
+	prefix string // matched against "bucket/key"
+	every  uint64
+}
+
+// ParseObjectSampling parses a comma-separated list of "bucket/prefix=N" rules,
+// e.g. "flow-logs/AWSLogs/=10,my-alb-logs=2". An empty spec disables sampling.
+func ParseObjectSampling(spec string) (*ObjectSampler, error) {
+	s := &ObjectSampler{}
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		prefix, n, ok := strings.Cut(part, "=")
+		if !ok || prefix == "" {
+			return nil, fmt.Errorf("invalid object sampling rule %q: want <bucket/prefix>=<N>", part)
+		}
+		every, err := strconv.ParseUint(strings.TrimSpace(n), 10, 64)
+		if err != nil || every == 0 {
+			return nil, fmt.Errorf("invalid object sampling rate in %q: want a positive integer", part)
+		}
+		s.rules = append(s.rules, samplingRule{prefix: strings.TrimSpace(prefix), every: every})
+	}
+	return s, nil
+}
+
+// Keep reports whether the object should be processed. The longest matching prefix
+// wins; objects matching no rule are always kept.
+func (s *ObjectSampler) Keep(bucket, key string) bool {
+	if s == nil {
+		return true
+	}
+	path := bucket + "/" + key
+
+	var rule *samplingRule
+	for i := range s.rules {
+		r := &s.rules[i]
+		if strings.HasPrefix(path, r.prefix) && (rule == nil || len(r.prefix) > len(rule.prefix)) {
+			rule = r
+		}
+	}
+	if rule == nil || rule.every == 1 {
+		return true
+	}
+
+	h := fnv.New64a()
+	h.Write([]byte(path))
+	return h.Sum64()%rule.every == 0
+}
+
+// StatusClasses are the HTTP status classes RecordSampler has rates for
+var StatusClasses = []string{"2xx", "3xx", "4xx", "5xx"}
+
+// RecordSampler keeps a fraction of the records of each HTTP status class, e.g. 5% of 2xx and
+// every 5xx. The decision is a deterministic hash of the request (the CloudFront request ID,
+// the ALB trace ID), so retries make the same decision and ALB records of one trace are kept
+// or dropped together. Records without a status code are always kept.
+type RecordSampler struct {
+	// Rates maps a status class ("2xx") to the fraction of its records kept, from 0 to 1
+	Rates map[string]float64
+}
+
+// NewRecordSampler validates the rates; it returns nil when every record would be kept
+func NewRecordSampler(rates map[string]float64) (*RecordSampler, error) {
+	sampling := false
+	for class, rate := range rates {
+		if rate < 0 || rate > 1 {
+			return nil, fmt.Errorf("invalid %s sample rate %v: want 0 to 1", class, rate)
+		}
+		sampling = sampling || rate < 1
+	}
+	if !sampling {
+		return nil, nil
+	}
+	return &RecordSampler{Rates: rates}, nil
+}
+
+// Rate returns the fraction of records like this one that are kept
+func (s *RecordSampler) Rate(record converter.OTelLogRecord) float64 {
+	if s == nil {
+		return 1
+	}
+	for _, attr := range record.Attributes {
+		if attr.Key != "http.response.status_code" {
+			continue
+		}
+		status, err := strconv.Atoi(attr.Value.Text())
+		if err != nil || status < 100 {
+			return 1
+		}
+		if rate, ok := s.Rates[strconv.Itoa(status/100)+"xx"]; ok {
+			return rate
+		}
+		return 1
+	}
+	return 1
+}
+
+// Keep reports whether the record is sampled in, and at which rate
+func (s *RecordSampler) Keep(record converter.OTelLogRecord) (bool, float64) {
+	rate := s.Rate(record)
+	if rate >= 1 {
+		return true, 1
+	}
+	h := fnv.New64a()
+	h.Write([]byte(sampleKey(record)))
+	// FNV's high bits barely change between similar keys; the finalizer of MurmurHash3 spreads
+	// them, so the top 53 bits are uniform in [0, 1)
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return float64(x>>11)/(1<<53) < rate, rate
+}
+
+// sampleKey identifies the request of a record. CloudFront trace IDs are random, so the
+// request ID stands in for them; ALB trace IDs come from X-Amzn-Trace-Id.
+func sampleKey(record converter.OTelLogRecord) string {
+	fromLog := false
+	for _, attr := range record.Attributes {
+		switch attr.Key {
+		case "aws.cloudfront.request_id":
+			return attr.Value.Text()
+		case "aws.alb.trace_id":
+			fromLog = true
+		}
+	}
+	if fromLog && record.TraceID != "" {
+		return record.TraceID
+	}
+	return record.TimeUnixNano + " " + record.Body.Text()
+}
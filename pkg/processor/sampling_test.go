@@ -0,0 +1,144 @@
+package processor
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/parser"
+)
+
+func TestParseObjectSampling(t *testing.T) {
+	tests := []struct {
+		spec    string
+		wantErr bool
+	}{
+		{"", false},
+		{"flow-logs/AWSLogs/=10", false},
+		{"flow-logs=10, alb-logs/prod/=2", false},
+		{"flow-logs", true},
+		{"=10", true},
+		{"flow-logs=0", true},
+		{"flow-logs=abc", true},
+	}
+
+	for _, tt := range tests {
+		_, err := ParseObjectSampling(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseObjectSampling(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+		}
+	}
+}
+
+func TestObjectSampler_Keep(t *testing.T) {
+	s, err := ParseObjectSampling("flow-logs=10,flow-logs/critical/=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kept := 0
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("AWSLogs/123/vpcflowlogs/file-%d.log.gz", i)
+		first := s.Keep("flow-logs", key)
+		if first != s.Keep("flow-logs", key) {
+			t.Fatalf("Keep(%q) is not deterministic", key)
+		}
+		if first {
+			kept++
+		}
+	}
+	if kept < 50 || kept > 150 {
+		t.Errorf("kept %d of 1000 objects at 1/10, want roughly 100", kept)
+	}
+
+	// Longest prefix wins
+	for i := 0; i < 20; i++ {
+		if !s.Keep("flow-logs", fmt.Sprintf("critical/file-%d.gz", i)) {
+			t.Errorf("object under 1/1 prefix was sampled out")
+		}
+	}
+
+	// Unmatched objects are always kept
+	if !s.Keep("alb-logs", "anything.gz") {
+		t.Error("object without a matching rule was sampled out")
+	}
+
+	var disabled *ObjectSampler
+	if !disabled.Keep("flow-logs", "x") {
+		t.Error("nil sampler should keep every object")
+	}
+}
+
+func statusRecord(status, requestID string) converter.OTelLogRecord {
+	return converter.OTelLogRecord{Attributes: []converter.OTelAttribute{
+		{Key: "http.response.status_code", Value: converter.OTelAnyValue{IntValue: aws.String(status)}},
+		{Key: "aws.cloudfront.request_id", Value: converter.OTelAnyValue{StringValue: aws.String(requestID)}},
+	}}
+}
+
+func TestRecordSampler(t *testing.T) {
+	if s, err := NewRecordSampler(map[string]float64{"2xx": 1}); s != nil || err != nil {
+		t.Errorf("NewRecordSampler(all 1) = %v, %v; want nil", s, err)
+	}
+	if _, err := NewRecordSampler(map[string]float64{"2xx": 1.5}); err == nil {
+		t.Error("NewRecordSampler() accepted a rate above 1")
+	}
+
+	s, err := NewRecordSampler(map[string]float64{"2xx": 0.05, "4xx": 0.5, "5xx": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	kept := map[string]int{}
+	for i := 0; i < 10000; i++ {
+		id := fmt.Sprintf("req-%d", i)
+		for _, status := range []string{"200", "404", "503", "302"} {
+			record := statusRecord(status, id)
+			keep, rate := s.Keep(record)
+			if again, _ := s.Keep(record); again != keep {
+				t.Fatalf("Keep(%s, %s) is not deterministic", status, id)
+			}
+			if keep {
+				kept[status]++
+			}
+			if status == "200" && rate != 0.05 {
+				t.Fatalf("2xx rate = %v", rate)
+			}
+		}
+	}
+	if kept["503"] != 10000 || kept["302"] != 10000 {
+		t.Errorf("kept %d 5xx and %d 3xx of 10000, want all", kept["503"], kept["302"])
+	}
+	if kept["200"] < 400 || kept["200"] > 600 {
+		t.Errorf("kept %d 2xx of 10000, want about 500", kept["200"])
+	}
+	if kept["404"] < 4700 || kept["404"] > 5300 {
+		t.Errorf("kept %d 4xx of 10000, want about 5000", kept["404"])
+	}
+
+	// Records without a status are never sampled
+	if keep, rate := s.Keep(converter.OTelLogRecord{Body: converter.OTelAnyValue{StringValue: aws.String("NLB")}}); !keep || rate != 1 {
+		t.Errorf("Keep(no status) = %v, %v", keep, rate)
+	}
+}
+
+func TestAdmission_Apply(t *testing.T) {
+	entry := NLBAdapter{NLBLogEntry: &parser.NLBLogEntry{}}
+	if got := (Admission{SampleRate: 1}).Apply(entry); got != entry {
+		t.Errorf("unsampled, unrouted admission wrapped the entry: %T", got)
+	}
+	record := Admission{Routes: []string{"s3"}, SampleRate: 0.25}.Apply(entry).ToOTel()
+	if len(record.Routes) != 1 || record.Routes[0] != "s3" {
+		t.Errorf("Routes = %v", record.Routes)
+	}
+	var rate *float64
+	for _, attr := range record.Attributes {
+		if attr.Key == "sampling.rate" {
+			rate = attr.Value.DoubleValue
+		}
+	}
+	if rate == nil || *rate != 0.25 {
+		t.Errorf("sampling.rate = %v, want 0.25", rate)
+	}
+}
@@ -0,0 +1,94 @@
+package processor
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// ReadCounts summarize the objects read by StreamAndParseObject. The durations are summed
+// over all objects and workers, so they can exceed the wall-clock time.
+type ReadCounts struct {
+	Objects int64
+	// Bytes is the size read from the store, before decompression
+	Bytes int64
+	Lines int64
+	// ParseFailures counts lines that did not parse or exceeded MaxLineBytes
+	ParseFailures int64
+	Entries       int64
+	// Filtered counts records dropped by filter rules, SampledOut the ones dropped by sampling
+	Filtered   int64
+	SampledOut int64
+	// DownloadTime is the time spent waiting on the object body
+	DownloadTime time.Duration
+	ParseTime    time.Duration
+}
+
+// ReadStats accumulates ReadCounts across objects read concurrently. A nil *ReadStats
+// discards them.
+type ReadStats struct {
+	mu     sync.Mutex
+	counts ReadCounts
+}
+
+// Add accumulates c; lines parsed outside StreamAndParseObject (e.g. Kinesis records) are
+// reported through it
+func (s *ReadStats) Add(c ReadCounts) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.counts.Objects += c.Objects
+	s.counts.Bytes += c.Bytes
+	s.counts.Lines += c.Lines
+	s.counts.ParseFailures += c.ParseFailures
+	s.counts.Entries += c.Entries
+	s.counts.Filtered += c.Filtered
+	s.counts.SampledOut += c.SampledOut
+	s.counts.DownloadTime += c.DownloadTime
+	s.counts.ParseTime += c.ParseTime
+}
+
+// Take returns the counts accumulated since the last call and resets them
+func (s *ReadStats) Take() ReadCounts {
+	if s == nil {
+		return ReadCounts{}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := s.counts
+	s.counts = ReadCounts{}
+	return c
+}
+
+// timedReader counts the bytes read from r and the time spent in its Read calls
+type timedReader struct {
+	r       io.Reader
+	bytes   int64
+	elapsed time.Duration
+}
+
+func (t *timedReader) Read(p []byte) (int, error) {
+	start := time.Now()
+	n, err := t.r.Read(p)
+	t.elapsed += time.Since(start)
+	t.bytes += int64(n)
+	return n, err
+}
+
+// objectStatsKey carries the ReadStats of a single object read in its context
+type objectStatsKey struct{}
+
+// WithObjectStats makes StreamAndParseObject add the counts of the object read with ctx to stats
+// as well as to ReadOptions.Stats, for per-object reporting
+func WithObjectStats(ctx context.Context, stats *ReadStats) context.Context {
+	return context.WithValue(ctx, objectStatsKey{}, stats)
+}
+
+// objectStats returns the per-object stats of ctx, or nil
+func objectStats(ctx context.Context) *ReadStats {
+	stats, _ := ctx.Value(objectStatsKey{}).(*ReadStats)
+	return stats
+}
@@ -0,0 +1,233 @@
+package processor
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+)
+
+// Object is an opened log object
+type Object struct {
+	Body         io.ReadCloser
+	LastModified time.Time
+	Size         int64
+}
+
+// ObjectStore fetches log objects by bucket and key
+type ObjectStore interface {
+	GetObject(ctx context.Context, bucket, key string) (*Object, error)
+}
+
+// IsS3URI reports whether location is an s3:// URI rather than a local path
+func IsS3URI(location string) bool {
+	return strings.HasPrefix(location, "s3://")
+}
+
+// ParseS3URI splits an s3://bucket/key URI
+func ParseS3URI(uri string) (bucket, key string, err error) {
+	rest, ok := strings.CutPrefix(uri, "s3://")
+	if ok {
+		bucket, key, ok = strings.Cut(rest, "/")
+	}
+	if !ok || bucket == "" || key == "" {
+		return "", "", fmt.Errorf("invalid S3 URI %q, want s3://bucket/key", uri)
+	}
+	return bucket, key, nil
+}
+
+// ObjectPresigner is implemented by stores that can hand out temporary URLs for an object
+type ObjectPresigner interface {
+	PresignGetObject(bucket, key string, ttl time.Duration) (string, error)
+}
+
+// S3Store reads objects from Amazon S3
+type S3Store struct {
+	Client *s3.Client
+	// ClientFor, when set, picks the client per bucket instead of Client (see S3ClientPool)
+	ClientFor func(bucket string) *s3.Client
+	// SSECustomerKey is the base64-encoded 256-bit key for objects encrypted with SSE-C, as
+	// S3 expects it in the request header. It is only sent for objects S3 reports as SSE-C
+	// encrypted, so other objects read as usual.
+	SSECustomerKey string
+	// PartSize and PartConcurrency enable parallel ranged GETs: objects are requested in
+	// PartSize ranges, up to PartConcurrency at a time. Objects no larger than one part take a
+	// single request. PartConcurrency <= 1 reads every object with one plain GET.
+	PartSize        int64
+	PartConcurrency int
+}
+
+func (s *S3Store) client(bucket string) *s3.Client {
+	if s.ClientFor != nil {
+		return s.ClientFor(bucket)
+	}
+	return s.Client
+}
+
+func (s *S3Store) GetObject(ctx context.Context, bucket, key string) (*Object, error) {
+	client := s.client(bucket)
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	}
+	ranged := s.PartSize > 0 && s.PartConcurrency > 1
+	if ranged {
+		input.Range = aws.String(fmt.Sprintf("bytes=0-%d", s.PartSize-1))
+	}
+
+	result, err := s.get(ctx, client, input)
+	if ranged && isInvalidRange(err) {
+		// Zero-byte objects have no range to satisfy
+		ranged, input.Range = false, nil
+		result, err = s.get(ctx, client, input)
+	}
+	if err != nil {
+		return nil, s.explainGetError(ctx, client, bucket, key, err)
+	}
+
+	obj := &Object{
+		Body:         result.Body,
+		LastModified: aws.ToTime(result.LastModified),
+		Size:         aws.ToInt64(result.ContentLength),
+	}
+	if size, ok := objectSizeFromRange(aws.ToString(result.ContentRange)); ranged && ok {
+		if first := aws.ToInt64(result.ContentLength); size > first {
+			input.Range = nil
+			obj.Body = newRangedReader(ctx, client, *input, aws.ToString(result.ETag), result.Body, first, size, s.PartSize, s.PartConcurrency)
+		}
+		obj.Size = size
+	}
+	return obj, nil
+}
+
+// get issues a GET, repeating it with the customer key when the object turns out to be SSE-C
+// encrypted; input keeps the key for any further ranges
+func (s *S3Store) get(ctx context.Context, client *s3.Client, input *s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+	result, err := client.GetObject(ctx, input)
+	if err != nil && isSSECRequired(err) && s.SSECustomerKey != "" {
+		input.SSECustomerAlgorithm = aws.String(string(types.ServerSideEncryptionAes256))
+		input.SSECustomerKey = aws.String(s.SSECustomerKey)
+		input.SSECustomerKeyMD5 = aws.String(sseCustomerKeyMD5(s.SSECustomerKey))
+		result, err = client.GetObject(ctx, input)
+	}
+	return result, err
+}
+
+// sseCustomerKeyMD5 returns the base64-encoded MD5 of the raw key, which S3 uses to check the
+// key arrived intact. Unlike v1, aws-sdk-go-v2 sends the key headers as given and computes
+// neither.
+func sseCustomerKeyMD5(key string) string {
+	raw, _ := base64.StdEncoding.DecodeString(key)
+	sum := md5.Sum(raw)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// isSSECRequired reports whether S3 refused a GET because the object is encrypted with SSE-C
+func isSSECRequired(err error) bool {
+	var apiErr smithy.APIError
+	return errors.As(err, &apiErr) && apiErr.ErrorCode() == "InvalidRequest" && strings.Contains(apiErr.ErrorMessage(), "Server Side Encryption")
+}
+
+// explainGetError adds a hint to errors whose cause is the object's encryption, which S3
+// otherwise reports as a bare AccessDenied or InvalidRequest
+func (s *S3Store) explainGetError(ctx context.Context, client *s3.Client, bucket, key string, err error) error {
+	if isSSECRequired(err) {
+		if s.SSECustomerKey == "" {
+			return fmt.Errorf("failed to get S3 object: object is encrypted with SSE-C and no customer key is configured: %w", err)
+		}
+		return fmt.Errorf("failed to get S3 object: object is encrypted with SSE-C and the configured customer key does not match: %w", err)
+	}
+
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) || apiErr.ErrorCode() != "AccessDenied" {
+		return fmt.Errorf("failed to get S3 object: %w", err)
+	}
+	// Reading the metadata of an SSE-KMS object needs no kms:Decrypt, so HEAD tells whether
+	// the key is what denied the GET
+	head, headErr := client.HeadObject(ctx, &s3.HeadObjectInput{Bucket: aws.String(bucket), Key: aws.String(key)})
+	if headErr == nil && strings.HasPrefix(string(head.ServerSideEncryption), string(types.ServerSideEncryptionAwsKms)) {
+		return fmt.Errorf("failed to get S3 object: object is encrypted with KMS key %s; the function role needs kms:Decrypt on it and the key policy must allow the role: %w",
+			aws.ToString(head.SSEKMSKeyId), err)
+	}
+	return fmt.Errorf("failed to get S3 object: %w", err)
+}
+
+// PresignGetObject returns a GET URL for the object valid for ttl. SSE-C objects cannot be
+// fetched through the URL alone, since the key has to be sent as headers. URLs signed with the
+// Lambda role's (or an assumed role's) session credentials stop working when that session
+// expires, whichever is first.
+func (s *S3Store) PresignGetObject(bucket, key string, ttl time.Duration) (string, error) {
+	req, err := s3.NewPresignClient(s.client(bucket)).PresignGetObject(context.Background(), &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	}, s3.WithPresignExpires(ttl))
+	if err != nil {
+		return "", err
+	}
+	return req.URL, nil
+}
+
+// LocalStore reads objects from a directory laid out as <Root>/<bucket>/<key>.
+// It lets the pipeline run against log fixtures without an AWS account.
+type LocalStore struct {
+	Root string
+}
+
+func (s *LocalStore) GetObject(ctx context.Context, bucket, key string) (*Object, error) {
+	path, err := s.path(bucket, key)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open local object: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to stat local object: %w", err)
+	}
+
+	return &Object{Body: f, LastModified: info.ModTime(), Size: info.Size()}, nil
+}
+
+// List returns every bucket/key pair under Root
+func (s *LocalStore) List() ([][2]string, error) {
+	var objects [][2]string
+	err := filepath.WalkDir(s.Root, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(s.Root, path)
+		if err != nil {
+			return err
+		}
+		parts := strings.SplitN(filepath.ToSlash(rel), "/", 2)
+		if len(parts) == 2 {
+			objects = append(objects, [2]string{parts[0], parts[1]})
+		}
+		return nil
+	})
+	return objects, err
+}
+
+func (s *LocalStore) path(bucket, key string) (string, error) {
+	path := filepath.Join(s.Root, bucket, filepath.FromSlash(key))
+	// Reject keys that escape the root (e.g. "../../etc/passwd")
+	if rel, err := filepath.Rel(s.Root, path); err != nil || strings.HasPrefix(rel, "..") {
+		return "", fmt.Errorf("object %s/%s is outside %s", bucket, key, s.Root)
+	}
+	return path, nil
+}
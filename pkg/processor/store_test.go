@@ -0,0 +1,147 @@
+package processor
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func TestParseS3URI(t *testing.T) {
+	bucket, key, err := ParseS3URI("s3://logs/AWSLogs/123/elb.log.gz")
+	if err != nil || bucket != "logs" || key != "AWSLogs/123/elb.log.gz" {
+		t.Errorf("ParseS3URI() = %q, %q, %v", bucket, key, err)
+	}
+	for _, uri := range []string{"s3://logs", "s3://logs/", "s3:///key", "/tmp/elb.log"} {
+		if _, _, err := ParseS3URI(uri); err == nil {
+			t.Errorf("ParseS3URI(%q) error = nil, want an error", uri)
+		}
+	}
+}
+
+func TestLocalStore(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, "my-bucket", "AWSLogs", "123", "file.log")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("hello\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	store := &LocalStore{Root: root}
+
+	objects, err := store.List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(objects) != 1 || objects[0][0] != "my-bucket" || objects[0][1] != "AWSLogs/123/file.log" {
+		t.Fatalf("List() = %v", objects)
+	}
+
+	obj, err := store.GetObject(context.Background(), "my-bucket", "AWSLogs/123/file.log")
+	if err != nil {
+		t.Fatalf("GetObject() error = %v", err)
+	}
+	defer obj.Body.Close()
+
+	data, _ := io.ReadAll(obj.Body)
+	if string(data) != "hello\n" || obj.Size != 6 || obj.LastModified.IsZero() {
+		t.Errorf("GetObject() = %q (size %d, modified %v)", data, obj.Size, obj.LastModified)
+	}
+
+	if _, err := store.GetObject(context.Background(), "my-bucket", "../../etc/passwd"); err == nil {
+		t.Error("GetObject() allowed a key outside the root")
+	}
+}
+
+// fakeS3Server serves GETs of "plain", "ssec" (needs the customer key) and "kms" (denied, but
+// HEAD reports the KMS key) under any bucket. Like S3, it rejects customer keys that are not
+// base64 of 256 bits or do not match their MD5 header.
+func fakeS3Server(t *testing.T) *s3.Client {
+	t.Helper()
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+		writeErr := func(status int, code, msg string) {
+			w.WriteHeader(status)
+			fmt.Fprintf(w, `<Error><Code>%s</Code><Message>%s</Message></Error>`, code, msg)
+		}
+		switch {
+		case key == "ssec" && r.Header.Get("x-amz-server-side-encryption-customer-algorithm") == "":
+			writeErr(http.StatusBadRequest, "InvalidRequest", "The object was stored using a form of Server Side Encryption. The correct parameters must be provided to retrieve the object.")
+		case key == "ssec" && !validCustomerKey(r.Header):
+			writeErr(http.StatusBadRequest, "InvalidArgument", "The calculated MD5 hash of the key did not match the hash that was provided.")
+		case key == "kms" && r.Method == http.MethodHead:
+			w.Header().Set("x-amz-server-side-encryption", "aws:kms")
+			w.Header().Set("x-amz-server-side-encryption-aws-kms-key-id", "arn:aws:kms:us-east-1:1:key/abc")
+		case key == "kms":
+			writeErr(http.StatusForbidden, "AccessDenied", "Access Denied")
+		default:
+			io.WriteString(w, "hello\n")
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	return s3.New(s3.Options{
+		Region:       "us-east-1",
+		BaseEndpoint: aws.String(srv.URL),
+		UsePathStyle: true,
+		HTTPClient:   srv.Client(),
+		Credentials:  credentials.NewStaticCredentialsProvider("id", "secret", ""),
+	})
+}
+
+// validCustomerKey reports whether the SSE-C key header is base64 of a 256-bit key whose MD5
+// matches the key MD5 header
+func validCustomerKey(h http.Header) bool {
+	key, err := base64.StdEncoding.DecodeString(h.Get("x-amz-server-side-encryption-customer-key"))
+	if err != nil || len(key) != 32 {
+		return false
+	}
+	sum := md5.Sum(key)
+	return h.Get("x-amz-server-side-encryption-customer-key-MD5") == base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func TestS3Store_Encryption(t *testing.T) {
+	client := fakeS3Server(t)
+	customerKey := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", 32)))
+
+	tests := []struct {
+		name    string
+		key     string
+		sseKey  string
+		wantErr string
+	}{
+		{"plain object", "plain", customerKey, ""},
+		{"sse-c object with key", "ssec", customerKey, ""},
+		{"sse-c object without key", "ssec", "", "no customer key is configured"},
+		{"kms object denied", "kms", "", "kms:Decrypt"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &S3Store{Client: client, SSECustomerKey: tt.sseKey}
+			obj, err := store.GetObject(context.Background(), "bucket", tt.key)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("GetObject() error = %v", err)
+				}
+				obj.Body.Close()
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("GetObject() error = %v, want hint %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package processor
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	elbv2 "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
+	"github.com/pixelvide/otel-aws-log-parser/cmd/lambda/adapter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+)
+
+// DefaultTagTTL is how long ResourceTags remembers the tags of a resource
+const DefaultTagTTL = time.Hour
+
+// tagLookupTimeout bounds one DescribeTags call
+const tagLookupTimeout = 5 * time.Second
+
+// ELBV2API is the part of the ELBv2 client ResourceTags uses; *elbv2.Client implements it
+type ELBV2API interface {
+	DescribeTags(ctx context.Context, in *elbv2.DescribeTagsInput, optFns ...func(*elbv2.Options)) (*elbv2.DescribeTagsOutput, error)
+}
+
+// ResourceTags looks up the tags of the load balancers and target groups in ALB and NLB
+// entries with elbv2:DescribeTags, keeping them in memory for TTL. Lookups that fail are
+// remembered as untagged for TTL too, so a missing permission costs one call per resource.
+type ResourceTags struct {
+	// ClientFor returns the ELBv2 client of a region
+	ClientFor func(region string) ELBV2API
+	// Keys are the tags attached; "*" attaches every tag
+	Keys []string
+	// TTL defaults to DefaultTagTTL
+	TTL time.Duration
+	// OnError, when set, is told about failed lookups
+	OnError func(arns []string, err error)
+
+	mu    sync.Mutex
+	cache map[string]cachedTags
+}
+
+type cachedTags struct {
+	tags    map[string]string
+	expires time.Time
+}
+
+// Lookup returns the selected tags of each ARN, fetching those not cached in one call per region
+func (t *ResourceTags) Lookup(arns ...string) []map[string]string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.cache == nil {
+		t.cache = make(map[string]cachedTags)
+	}
+
+	now := time.Now()
+	missing := map[string][]string{}
+	for _, arn := range arns {
+		if c, ok := t.cache[arn]; !ok || now.After(c.expires) {
+			region := arnRegion(arn)
+			missing[region] = append(missing[region], arn)
+		}
+	}
+	for region, batch := range missing {
+		t.fetch(region, batch, now)
+	}
+
+	out := make([]map[string]string, len(arns))
+	for i, arn := range arns {
+		out[i] = t.cache[arn].tags
+	}
+	return out
+}
+
+// fetch describes the tags of the ARNs of one region. Callers must hold t.mu.
+func (t *ResourceTags) fetch(region string, arns []string, now time.Time) {
+	ttl := t.TTL
+	if ttl <= 0 {
+		ttl = DefaultTagTTL
+	}
+	for _, arn := range arns {
+		t.cache[arn] = cachedTags{expires: now.Add(ttl)}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), tagLookupTimeout)
+	defer cancel()
+	out, err := t.ClientFor(region).DescribeTags(ctx, &elbv2.DescribeTagsInput{ResourceArns: arns})
+	if err != nil {
+		if t.OnError != nil {
+			t.OnError(arns, err)
+		}
+		return
+	}
+	for _, desc := range out.TagDescriptions {
+		tags := map[string]string{}
+		for _, tag := range desc.Tags {
+			key := aws.ToString(tag.Key)
+			if t.selected(key) {
+				tags[key] = aws.ToString(tag.Value)
+			}
+		}
+		t.cache[aws.ToString(desc.ResourceArn)] = cachedTags{tags: tags, expires: now.Add(ttl)}
+	}
+}
+
+func (t *ResourceTags) selected(key string) bool {
+	for _, k := range t.Keys {
+		if k == "*" || k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// arnRegion returns the region field of an ARN
+func arnRegion(arn string) string {
+	parts := strings.SplitN(arn, ":", 5)
+	if len(parts) < 5 {
+		return ""
+	}
+	return parts[3]
+}
+
+// TaggedAdapter adds the tags of an entry's load balancer and target group to its resource
+// attributes as aws.tag.<key>; target group tags win over load balancer tags of the same key.
+// The load balancer joins the resource key, so a group never mixes the tags of two of them.
+type TaggedAdapter struct {
+	adapter.LogAdapter
+	LoadBalancer string
+	Tags         map[string]string
+}
+
+func (a TaggedAdapter) Unwrap() adapter.LogAdapter { return a.LogAdapter }
+
+func (a TaggedAdapter) GetResourceKey() string {
+	key := a.LogAdapter.GetResourceKey()
+	if key == a.LoadBalancer {
+		return key
+	}
+	return key + "|" + a.LoadBalancer
+}
+
+func (a TaggedAdapter) GetResourceAttributes() []converter.OTelAttribute {
+	attrs := a.LogAdapter.GetResourceAttributes()
+	for _, key := range slices.Sorted(maps.Keys(a.Tags)) {
+		value := a.Tags[key]
+		attrs = append(attrs, converter.OTelAttribute{Key: "aws.tag." + key, Value: converter.OTelAnyValue{StringValue: &value}})
+	}
+	return attrs
+}
+
+// ApplyResourceTags wraps ALB and NLB entries whose resources have selected tags; other entries
+// and a nil tags are returned unchanged. The entry may already be decorated; the load balancer
+// is read from the entry its processor returned.
+func ApplyResourceTags(entry adapter.LogAdapter, tags *ResourceTags) adapter.LogAdapter {
+	if tags == nil {
+		return entry
+	}
+
+	var elb, targetGroup, region, account string
+	switch e := Unwrap(entry).(type) {
+	case ALBAdapter:
+		elb, targetGroup, region, account = e.ELB, e.TargetGroupARN, e.Region, e.AccountID
+	case NLBAdapter:
+		elb = e.ELB
+		for _, attr := range e.GetResourceAttributes() {
+			switch attr.Key {
+			case "cloud.region":
+				region = attr.Value.Text()
+			case "cloud.account.id":
+				account = attr.Value.Text()
+			}
+		}
+	default:
+		return entry
+	}
+	if converter.IsPlaceholder(targetGroup) {
+		targetGroup = ""
+	}
+	partition := "aws"
+	if parts := strings.SplitN(targetGroup, ":", 6); len(parts) == 6 {
+		// The target group ARN is authoritative for the partition, region and account
+		partition, region, account = parts[1], parts[3], parts[4]
+	}
+	if converter.IsPlaceholder(elb) || region == "" || account == "" {
+		return entry
+	}
+
+	arns := []string{"arn:" + partition + ":elasticloadbalancing:" + region + ":" + account + ":loadbalancer/" + elb}
+	if targetGroup != "" {
+		arns = append(arns, targetGroup)
+	}
+	merged := map[string]string{}
+	for _, resourceTags := range tags.Lookup(arns...) {
+		for key, value := range resourceTags {
+			merged[key] = value
+		}
+	}
+	if len(merged) == 0 {
+		return entry
+	}
+	return TaggedAdapter{LogAdapter: entry, LoadBalancer: elb, Tags: merged}
+}
@@ -0,0 +1,99 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	elbv2 "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
+	"github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2/types"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/parser"
+)
+
+type fakeELBv2 struct {
+	tags  map[string]map[string]string
+	calls int
+	err   error
+}
+
+func (f *fakeELBv2) DescribeTags(ctx context.Context, in *elbv2.DescribeTagsInput, optFns ...func(*elbv2.Options)) (*elbv2.DescribeTagsOutput, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	out := &elbv2.DescribeTagsOutput{}
+	for _, arn := range in.ResourceArns {
+		desc := types.TagDescription{ResourceArn: aws.String(arn)}
+		for k, v := range f.tags[arn] {
+			desc.Tags = append(desc.Tags, types.Tag{Key: aws.String(k), Value: aws.String(v)})
+		}
+		out.TagDescriptions = append(out.TagDescriptions, desc)
+	}
+	return out, nil
+}
+
+func TestApplyResourceTags(t *testing.T) {
+	const (
+		lbARN = "arn:aws:elasticloadbalancing:eu-west-1:111122223333:loadbalancer/app/web/50dc6c495c0c9188"
+		tgARN = "arn:aws:elasticloadbalancing:eu-west-1:111122223333:targetgroup/web/73e2d6bc24d8a067"
+	)
+	client := &fakeELBv2{tags: map[string]map[string]string{
+		lbARN: {"team": "platform", "service": "edge", "cost-center": "42"},
+		tgARN: {"service": "checkout"},
+	}}
+	var regions []string
+	tags := &ResourceTags{
+		ClientFor: func(region string) ELBV2API {
+			regions = append(regions, region)
+			return client
+		},
+		Keys: []string{"team", "service"},
+	}
+
+	entry := ALBAdapter{ALBLogEntry: &parser.ALBLogEntry{ELB: "app/web/50dc6c495c0c9188", TargetGroupARN: tgARN}}
+	tagged := ApplyResourceTags(entry, tags)
+	ApplyResourceTags(entry, tags)
+	if client.calls != 1 || len(regions) != 1 || regions[0] != "eu-west-1" {
+		t.Errorf("DescribeTags calls = %d in %v, want one cached call in eu-west-1", client.calls, regions)
+	}
+
+	attrs := map[string]string{}
+	for _, attr := range tagged.GetResourceAttributes() {
+		attrs[attr.Key] = attr.Value.Text()
+	}
+	if attrs["aws.tag.team"] != "platform" || attrs["aws.tag.service"] != "checkout" {
+		t.Errorf("tags = %v, want the target group's service over the load balancer's", attrs)
+	}
+	if _, ok := attrs["aws.tag.cost-center"]; ok {
+		t.Error("unselected tag cost-center attached")
+	}
+	if tagged.GetResourceKey() != "app/web/50dc6c495c0c9188" {
+		t.Errorf("resource key = %q, want the load balancer", tagged.GetResourceKey())
+	}
+
+	// Decorated entries are tagged by the load balancer of the entry they wrap
+	sequenced := SequencedAdapter{LogAdapter: entry, Sequence: 7}
+	decorated := ApplyResourceTags(SourceLinkAdapter{LogAdapter: sequenced}, tags)
+	if attrValue(decorated.GetResourceAttributes(), "aws.tag.team") != "platform" {
+		t.Errorf("decorated entry not tagged: %#v", decorated)
+	}
+	if got, ok := findAdapter[SequencedAdapter](decorated); !ok || got.Sequence != 7 {
+		t.Errorf("decorators lost when tagging: %#v", decorated)
+	}
+
+	// Entries of other processors and untagged resources pass through
+	waf := &WAFAdapter{}
+	if got := ApplyResourceTags(waf, tags); got != waf {
+		t.Errorf("WAF entry wrapped: %#v", got)
+	}
+	failing := &ResourceTags{ClientFor: func(string) ELBV2API { return &fakeELBv2{err: errors.New("AccessDenied")} }, Keys: []string{"*"}}
+	var failed []string
+	failing.OnError = func(arns []string, err error) { failed = arns }
+	if got := ApplyResourceTags(entry, failing); got != entry {
+		t.Errorf("entry wrapped although the lookup failed: %#v", got)
+	}
+	if len(failed) != 2 {
+		t.Errorf("OnError got %v, want both ARNs", failed)
+	}
+}
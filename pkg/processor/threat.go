@@ -0,0 +1,56 @@
+package processor
+
+import (
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	"github.com/pixelvide/otel-aws-log-parser/cmd/lambda/adapter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/reputation"
+)
+
+// ThreatAdapter adds threat.ip.reputation, the comma-separated labels of the reputation lists
+// naming a record's client.address, and threat.ip.tor_exit for TOR exit nodes. Addresses no
+// list names get neither.
+type ThreatAdapter struct {
+	adapter.LogAdapter
+	Feed *reputation.Feed
+}
+
+func (a ThreatAdapter) Unwrap() adapter.LogAdapter { return a.LogAdapter }
+
+// ApplyThreatIntel wraps the entry for reputation checks; a nil feed returns the entry unchanged
+func ApplyThreatIntel(entry adapter.LogAdapter, feed *reputation.Feed) adapter.LogAdapter {
+	if feed == nil || entry == nil {
+		return entry
+	}
+	return ThreatAdapter{LogAdapter: entry, Feed: feed}
+}
+
+func (a ThreatAdapter) ToOTel() converter.OTelLogRecord {
+	record := a.LogAdapter.ToOTel()
+	client := ""
+	for _, attr := range record.Attributes {
+		if attr.Key == "client.address" && attr.Value.StringValue != nil {
+			client = *attr.Value.StringValue
+			break
+		}
+	}
+	if client == "" {
+		return record
+	}
+
+	labels := a.Feed.Lookup(client)
+	if len(labels) == 0 {
+		return record
+	}
+	record.Attributes = append(record.Attributes, converter.OTelAttribute{Key: "threat.ip.reputation", Value: converter.OTelAnyValue{StringValue: aws.String(strings.Join(labels, ","))}})
+	for _, label := range labels {
+		if label == reputation.LabelTOR {
+			record.Attributes = append(record.Attributes, converter.OTelAttribute{Key: "threat.ip.tor_exit", Value: converter.OTelAnyValue{BoolValue: aws.Bool(true)}})
+			break
+		}
+	}
+	return record
+}
@@ -0,0 +1,55 @@
+package processor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/parser"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/reputation"
+)
+
+func TestApplyThreatIntel(t *testing.T) {
+	feed := &reputation.Feed{Load: func(ctx context.Context) (*reputation.List, error) {
+		list := reputation.NewList()
+		_, err := list.Add("blocklist", []byte("203.0.113.10\nExitAddress 203.0.113.10 2024-01-01 01:00:00\n"))
+		return list, err
+	}}
+	if err := feed.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	entry := &WAFAdapter{WAFLogEntry: &parser.WAFLogEntry{}}
+	if got := ApplyThreatIntel(entry, nil); got != entry {
+		t.Errorf("nil feed wrapped the entry: %T", got)
+	}
+
+	tests := []struct {
+		name       string
+		clientIP   string
+		reputation string
+		torExit    bool
+	}{
+		{name: "listed", clientIP: "203.0.113.10", reputation: "blocklist,tor", torExit: true},
+		{name: "unlisted", clientIP: "192.0.2.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := &WAFAdapter{WAFLogEntry: &parser.WAFLogEntry{}}
+			entry.HTTPRequest.ClientIP = tt.clientIP
+			record := ApplyThreatIntel(entry, feed).ToOTel()
+			var reputation string
+			var torExit bool
+			for _, attr := range record.Attributes {
+				switch attr.Key {
+				case "threat.ip.reputation":
+					reputation = attr.Value.Text()
+				case "threat.ip.tor_exit":
+					torExit = attr.Value.BoolValue != nil && *attr.Value.BoolValue
+				}
+			}
+			if reputation != tt.reputation || torExit != tt.torExit {
+				t.Errorf("threat.ip.reputation = %q, tor_exit = %v, want %q, %v", reputation, torExit, tt.reputation, tt.torExit)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package processor
+
+import (
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	"github.com/pixelvide/otel-aws-log-parser/cmd/lambda/adapter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/useragent"
+)
+
+// UserAgentAdapter adds user_agent.name, user_agent.version, user_agent.os.name,
+// user_agent.os.version and user_agent.is_bot parsed from a record's user_agent.original
+type UserAgentAdapter struct {
+	adapter.LogAdapter
+	Cache *useragent.Cache
+}
+
+func (a UserAgentAdapter) Unwrap() adapter.LogAdapter { return a.LogAdapter }
+
+// ApplyUserAgents wraps the entry for user agent enrichment; a nil cache returns the entry unchanged
+func ApplyUserAgents(entry adapter.LogAdapter, cache *useragent.Cache) adapter.LogAdapter {
+	if cache == nil || entry == nil {
+		return entry
+	}
+	return UserAgentAdapter{LogAdapter: entry, Cache: cache}
+}
+
+func (a UserAgentAdapter) ToOTel() converter.OTelLogRecord {
+	record := a.LogAdapter.ToOTel()
+	original := ""
+	for _, attr := range record.Attributes {
+		if attr.Key == "user_agent.original" && attr.Value.StringValue != nil {
+			original = *attr.Value.StringValue
+			break
+		}
+	}
+	if original == "" {
+		return record
+	}
+
+	info := a.Cache.Parse(original)
+	for _, field := range []struct{ key, value string }{
+		{"user_agent.name", info.Name},
+		{"user_agent.version", info.Version},
+		{"user_agent.os.name", info.OSName},
+		{"user_agent.os.version", info.OSVersion},
+	} {
+		if field.value != "" {
+			record.Attributes = append(record.Attributes, converter.OTelAttribute{Key: field.key, Value: converter.OTelAnyValue{StringValue: aws.String(field.value)}})
+		}
+	}
+	record.Attributes = append(record.Attributes, converter.OTelAttribute{Key: "user_agent.is_bot", Value: converter.OTelAnyValue{BoolValue: aws.Bool(info.IsBot)}})
+	return record
+}
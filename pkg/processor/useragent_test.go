@@ -0,0 +1,72 @@
+package processor
+
+import (
+	"testing"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/parser"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/useragent"
+)
+
+func isBot(attrs []converter.OTelAttribute) *bool {
+	for _, attr := range attrs {
+		if attr.Key == "user_agent.is_bot" {
+			return attr.Value.BoolValue
+		}
+	}
+	return nil
+}
+
+func TestApplyUserAgents(t *testing.T) {
+	cache := &useragent.Cache{}
+	tests := []struct {
+		name    string
+		ua      string
+		want    map[string]string
+		wantBot *bool
+	}{
+		{
+			name: "browser",
+			ua:   "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+			want: map[string]string{
+				"user_agent.name":       "Chrome",
+				"user_agent.version":    "120.0.0.0",
+				"user_agent.os.name":    "Windows",
+				"user_agent.os.version": "10.0",
+			},
+			wantBot: new(bool),
+		},
+		{
+			name:    "library",
+			ua:      "curl/8.4.0",
+			want:    map[string]string{"user_agent.name": "curl", "user_agent.version": "8.4.0", "user_agent.os.name": ""},
+			wantBot: func() *bool { b := true; return &b }(),
+		},
+		{
+			name: "placeholder",
+			ua:   "-",
+			want: map[string]string{"user_agent.name": ""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := ALBAdapter{ALBLogEntry: &parser.ALBLogEntry{UserAgent: tt.ua}}
+			attrs := ApplyUserAgents(entry, cache).ToOTel().Attributes
+			for key, want := range tt.want {
+				if got := attrValue(attrs, key); got != want {
+					t.Errorf("%s = %q, want %q", key, got, want)
+				}
+			}
+			got := isBot(attrs)
+			if (got == nil) != (tt.wantBot == nil) || (got != nil && *got != *tt.wantBot) {
+				t.Errorf("user_agent.is_bot = %v, want %v", got, tt.wantBot)
+			}
+		})
+	}
+
+	entry := ALBAdapter{ALBLogEntry: &parser.ALBLogEntry{}}
+	if got := ApplyUserAgents(entry, nil); got != entry {
+		t.Errorf("nil cache wrapped the entry: %T", got)
+	}
+}
@@ -2,22 +2,17 @@ package processor
 
 import (
 	"context"
-	"fmt"
-	"io"
 	"log/slog"
-	"os"
 	"strings"
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/pixelvide/otel-aws-log-parser/cmd/lambda/adapter"
 	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
 	"github.com/pixelvide/otel-aws-log-parser/pkg/parser"
 )
 
 type WAFProcessor struct {
-	// WAF processor might not need batch/concurrent config for streaming parser yet
-	// but keeping them for consistency or future use
+	ReadOptions
 }
 
 func (p *WAFProcessor) Name() string {
@@ -28,56 +23,32 @@ func (p *WAFProcessor) Matches(bucket, key string) bool {
 	return strings.HasPrefix(bucket, "aws-waf-logs-") && strings.Contains(key, "/WAFLogs/") && strings.Contains(key, "_waflogs_")
 }
 
-func (p *WAFProcessor) Process(ctx context.Context, logger *slog.Logger, s3Client *s3.S3, bucket, key string) ([]adapter.LogAdapter, error) {
-	// For WAF, we currently download and parse the whole file.
-	// (Unless we already refactored to streaming? The plan mentioned streaming refactor,
-	// but I am following the "Parser Identification" refactor plan now.
-	// I will implement the download-to-temp logic here as it is what currently exists in main.go)
+// WAF delivers newline-delimited JSON, so it streams through the shared line reader.
+// Individual records can be very large (full header/body match details), which is why
+// WAF gets its own, larger MaxLineBytes default.
+func (p *WAFProcessor) Process(ctx context.Context, logger *slog.Logger, store ObjectStore, bucket, key string) ([]adapter.LogAdapter, error) {
+	return ReadAndParseObject(ctx, logger, store, bucket, key, p.ReadOptions, p.parseLine(key))
+}
 
-	// Note: If I already implemented streaming in previous steps, I should use that.
-	// But I checked the history, and I only *planned* streaming refactor in Step 714, but didn't implement it yet.
-	// So I will stick to the temp file approach for now to match current main.go behavior.
+func (p *WAFProcessor) ProcessStream(ctx context.Context, logger *slog.Logger, store ObjectStore, bucket, key string, emit EmitFunc, cp *Checkpoint) error {
+	return StreamAndParseObject(ctx, logger, store, bucket, key, p.ReadOptions, p.parseLine(key), emit, cp)
+}
 
+func (p *WAFProcessor) parseLine(key string) ProcessLineFunc {
 	// Extract common attributes from S3 key
 	accountID, region := ParseRegionAccountFromS3Key(key)
 
-	result, err := s3Client.GetObject(&s3.GetObjectInput{
-		Bucket: aws.String(bucket),
-		Key:    aws.String(key),
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to get S3 object: %w", err)
-	}
-	defer result.Body.Close()
-
-	// Download to temp file
-	tmpFile, err := os.CreateTemp("", "waf-log-*.json.gz")
-	if err != nil {
-		return nil, fmt.Errorf("failed to create temp file: %w", err)
-	}
-	defer os.Remove(tmpFile.Name()) // clean up
-	defer tmpFile.Close()
-
-	if _, err := io.Copy(tmpFile, result.Body); err != nil {
-		return nil, fmt.Errorf("failed to write temp file: %w", err)
-	}
-	// Close file to flush writes before parsing
-	tmpFile.Close()
-
-	wafEntries, err := parser.ParseWAFLogFile(tmpFile.Name())
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse WAF log: %w", err)
-	}
-
-	adapters := make([]adapter.LogAdapter, len(wafEntries))
-	for i, e := range wafEntries {
-		adapters[i] = &WAFAdapter{
-			WAFLogEntry: e,
+	return func(line string) (adapter.LogAdapter, error) {
+		entry, err := parser.ParseWAFLogLine(line)
+		if err != nil || entry == nil {
+			return nil, err
+		}
+		return &WAFAdapter{
+			WAFLogEntry: entry,
 			AccountID:   accountID,
 			Region:      region,
-		}
+		}, nil
 	}
-	return adapters, nil
 }
 
 // WAFAdapter implementation
@@ -96,6 +67,7 @@ func (a *WAFAdapter) GetResourceAttributes() []converter.OTelAttribute {
 		{Key: "cloud.provider", Value: converter.OTelAnyValue{StringValue: aws.String("aws")}},
 		{Key: "cloud.platform", Value: converter.OTelAnyValue{StringValue: aws.String("aws_waf")}},
 		{Key: "cloud.service", Value: converter.OTelAnyValue{StringValue: aws.String("waf")}},
+		{Key: "service.name", Value: converter.OTelAnyValue{StringValue: aws.String("waf-log-parser")}},
 		{Key: "aws.waf.web_acl_id", Value: converter.OTelAnyValue{StringValue: aws.String(a.WAFLogEntry.WebACLID)}},
 	}
 
@@ -0,0 +1,35 @@
+package processor
+
+import "github.com/pixelvide/otel-aws-log-parser/cmd/lambda/adapter"
+
+// WrappingAdapter is implemented by the adapters that decorate another adapter, so the entry a
+// processor returned stays reachable under any decorators added later
+type WrappingAdapter interface {
+	Unwrap() adapter.LogAdapter
+}
+
+// Unwrap returns the entry under all of its decorators
+func Unwrap(entry adapter.LogAdapter) adapter.LogAdapter {
+	for {
+		wrapper, ok := entry.(WrappingAdapter)
+		if !ok {
+			return entry
+		}
+		entry = wrapper.Unwrap()
+	}
+}
+
+// findAdapter returns the outermost adapter in entry's decorator chain that is a T
+func findAdapter[T any](entry adapter.LogAdapter) (T, bool) {
+	for {
+		if found, ok := entry.(T); ok {
+			return found, true
+		}
+		wrapper, ok := entry.(WrappingAdapter)
+		if !ok {
+			var zero T
+			return zero, false
+		}
+		entry = wrapper.Unwrap()
+	}
+}
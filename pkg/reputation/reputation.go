@@ -0,0 +1,175 @@
+// Package reputation matches client addresses against IP reputation lists, such as known-bad
+// address feeds and the TOR exit node list, kept in memory and refreshed periodically.
+package reputation
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"net/netip"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// LabelTOR is the label of TOR exit nodes; lists in the TOR exit-addresses format get it
+const LabelTOR = "tor"
+
+// List maps addresses and networks to the labels of the lists naming them
+type List struct {
+	addrs map[netip.Addr][]string
+	// nets holds the networks by prefix length, so a lookup masks the address once per length
+	nets map[int]map[netip.Prefix][]string
+	bits []int
+}
+
+// NewList creates an empty list
+func NewList() *List {
+	return &List{addrs: map[netip.Addr][]string{}, nets: map[int]map[netip.Prefix][]string{}}
+}
+
+// Add reads one list and returns the number of entries it held. Each line holds an address or
+// CIDR network, optionally followed by a label (separated by a comma or whitespace) that
+// replaces label; "#" starts a comment. The TOR exit-addresses format ("ExitAddress <ip> ...")
+// is recognized and labelled LabelTOR. Other lines are skipped; a list without any entry is an
+// error, as it is most likely not a list.
+func (l *List) Add(label string, data []byte) (int, error) {
+	entries := 0
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.FieldsFunc(line, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' || r == ';' })
+		if len(fields) == 0 {
+			continue
+		}
+		lineLabel := label
+		if fields[0] == "ExitAddress" && len(fields) > 1 {
+			fields, lineLabel = fields[1:2], LabelTOR
+		} else if len(fields) > 1 {
+			lineLabel = strings.ToLower(fields[1])
+		}
+		if l.insert(fields[0], lineLabel) {
+			entries++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return entries, err
+	}
+	if entries == 0 {
+		return 0, fmt.Errorf("no addresses found")
+	}
+	return entries, nil
+}
+
+func (l *List) insert(value, label string) bool {
+	if addr, err := netip.ParseAddr(value); err == nil {
+		addr = addr.Unmap()
+		if !slices.Contains(l.addrs[addr], label) {
+			l.addrs[addr] = append(l.addrs[addr], label)
+		}
+		return true
+	}
+	prefix, err := netip.ParsePrefix(value)
+	if err != nil {
+		return false
+	}
+	prefix = prefix.Masked()
+	prefix = netip.PrefixFrom(prefix.Addr().Unmap(), prefix.Bits()).Masked()
+	bits := prefix.Bits()
+	nets, ok := l.nets[bits]
+	if !ok {
+		nets = map[netip.Prefix][]string{}
+		l.nets[bits] = nets
+		l.bits = append(l.bits, bits)
+	}
+	if !slices.Contains(nets[prefix], label) {
+		nets[prefix] = append(nets[prefix], label)
+	}
+	return true
+}
+
+// Lookup returns the sorted labels of the lists naming the address, or nil
+func (l *List) Lookup(ip string) []string {
+	if l == nil {
+		return nil
+	}
+	addr, err := netip.ParseAddr(strings.TrimSpace(ip))
+	if err != nil {
+		return nil
+	}
+	addr = addr.Unmap()
+
+	labels := slices.Clone(l.addrs[addr])
+	for _, bits := range l.bits {
+		if bits > addr.BitLen() {
+			continue
+		}
+		prefix, err := addr.Prefix(bits)
+		if err != nil {
+			continue
+		}
+		for _, label := range l.nets[bits][prefix] {
+			if !slices.Contains(labels, label) {
+				labels = append(labels, label)
+			}
+		}
+	}
+	slices.Sort(labels)
+	return labels
+}
+
+// Feed keeps a List loaded and reloads it in the background once it is older than Refresh,
+// so lookups never wait for a download. A failed reload keeps the previous list.
+type Feed struct {
+	// Load builds a fresh list, e.g. from files in S3
+	Load func(ctx context.Context) (*List, error)
+	// Refresh is how old the list may get; zero never reloads
+	Refresh time.Duration
+	// OnError, when set, is told about failed reloads
+	OnError func(error)
+
+	list    atomic.Pointer[List]
+	loaded  atomic.Int64
+	loading atomic.Bool
+}
+
+// Start loads the first list; its failure is returned so a misconfigured feed fails fast
+func (f *Feed) Start(ctx context.Context) error {
+	list, err := f.Load(ctx)
+	if err != nil {
+		return err
+	}
+	f.list.Store(list)
+	f.loaded.Store(time.Now().UnixNano())
+	return nil
+}
+
+// Lookup returns the labels of an address in the current list, starting a reload when it is stale
+func (f *Feed) Lookup(ip string) []string {
+	if f == nil {
+		return nil
+	}
+	if f.Refresh > 0 && time.Since(time.Unix(0, f.loaded.Load())) > f.Refresh && f.loading.CompareAndSwap(false, true) {
+		go f.reload()
+	}
+	return f.list.Load().Lookup(ip)
+}
+
+func (f *Feed) reload() {
+	defer f.loading.Store(false)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	list, err := f.Load(ctx)
+	if err != nil {
+		// Retry after another Refresh rather than on every lookup
+		f.loaded.Store(time.Now().UnixNano())
+		if f.OnError != nil {
+			f.OnError(err)
+		}
+		return
+	}
+	f.list.Store(list)
+	f.loaded.Store(time.Now().UnixNano())
+}
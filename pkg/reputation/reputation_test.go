@@ -0,0 +1,77 @@
+package reputation
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestList_Lookup(t *testing.T) {
+	list := NewList()
+	if _, err := list.Add("blocklist", []byte("# known bad\n198.51.100.7\n203.0.113.0/24, scanner\n2001:db8::/32\nnot an address\n")); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	tor := "ExitNode 0011BD2485AD45D984EC4159C88FC066E5E3300E\nPublished 2024-01-01 00:00:00\nExitAddress 198.51.100.7 2024-01-01 01:00:00\n"
+	if n, err := list.Add("exits", []byte(tor)); err != nil || n != 1 {
+		t.Fatalf("Add(tor) = %d, %v, want 1 entry", n, err)
+	}
+
+	tests := []struct {
+		ip   string
+		want string
+	}{
+		{"198.51.100.7", "blocklist,tor"},
+		{"::ffff:198.51.100.7", "blocklist,tor"},
+		{"203.0.113.99", "scanner"},
+		{"2001:db8::1", "blocklist"},
+		{"192.0.2.1", ""},
+		{"-", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			if got := strings.Join(list.Lookup(tt.ip), ","); got != tt.want {
+				t.Errorf("Lookup(%q) = %q, want %q", tt.ip, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestList_AddEmpty(t *testing.T) {
+	if _, err := NewList().Add("empty", []byte("<html>not found</html>\n")); err == nil {
+		t.Error("Add() of a list without addresses succeeded")
+	}
+}
+
+func TestFeed_Refresh(t *testing.T) {
+	var loads atomic.Int32
+	feed := &Feed{
+		Load: func(ctx context.Context) (*List, error) {
+			if loads.Add(1) > 1 {
+				return nil, errors.New("unavailable")
+			}
+			list := NewList()
+			_, err := list.Add("bad", []byte("198.51.100.7"))
+			return list, err
+		},
+		Refresh: time.Nanosecond,
+		OnError: func(error) {},
+	}
+	if err := feed.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	time.Sleep(time.Millisecond)
+	// A stale list keeps answering while, and after, the reload fails
+	for i := 0; i < 3; i++ {
+		if got := feed.Lookup("198.51.100.7"); len(got) != 1 {
+			t.Fatalf("Lookup() = %v, want the loaded list", got)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if loads.Load() < 2 {
+		t.Errorf("Load called %d times, want a background reload", loads.Load())
+	}
+}
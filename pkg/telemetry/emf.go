@@ -0,0 +1,113 @@
+package telemetry
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// EMF writes snapshots as CloudWatch embedded metric format documents, one JSON line per set
+// of attributes. CloudWatch Logs turns them into metrics when written to a Lambda's stdout.
+type EMF struct {
+	Writer    io.Writer
+	Namespace string
+	// Dimensions are added to every document, e.g. the function name
+	Dimensions []Attr
+}
+
+type emfMetric struct {
+	Name string `json:"Name"`
+	Unit string `json:"Unit"`
+}
+
+type emfDirective struct {
+	Namespace  string      `json:"Namespace"`
+	Dimensions [][]string  `json:"Dimensions"`
+	Metrics    []emfMetric `json:"Metrics"`
+}
+
+type emfMetadata struct {
+	Timestamp         int64          `json:"Timestamp"`
+	CloudWatchMetrics []emfDirective `json:"CloudWatchMetrics"`
+}
+
+// Write emits the snapshot. Histograms are written as their raw values, from which CloudWatch
+// derives statistics and percentiles.
+func (e *EMF) Write(snap Snapshot) error {
+	type document struct {
+		attrs   []Attr
+		metrics []emfMetric
+		values  map[string]any
+	}
+	var docs []*document
+	byAttrs := make(map[string]*document)
+	doc := func(attrs []Attr) *document {
+		key := seriesKey("", attrs)
+		d, ok := byAttrs[key]
+		if !ok {
+			d = &document{attrs: attrs, values: make(map[string]any)}
+			byAttrs[key] = d
+			docs = append(docs, d)
+		}
+		return d
+	}
+
+	for _, c := range snap.Counters {
+		d := doc(c.Attrs)
+		d.metrics = append(d.metrics, emfMetric{Name: c.Name, Unit: emfUnit(c.Unit)})
+		d.values[c.Name] = c.Value
+	}
+	for _, h := range snap.Histograms {
+		d := doc(h.Attrs)
+		d.metrics = append(d.metrics, emfMetric{Name: h.Name, Unit: emfUnit(h.Unit)})
+		d.values[h.Name] = h.Values
+	}
+
+	for _, d := range docs {
+		attrs := append(append([]Attr(nil), e.Dimensions...), d.attrs...)
+		dims := make([]string, 0, len(attrs))
+		out := make(map[string]any, len(d.values)+len(attrs)+1)
+		for _, a := range attrs {
+			dims = append(dims, a.Key)
+			out[a.Key] = a.Value
+		}
+		for name, v := range d.values {
+			if _, clash := out[name]; clash {
+				return fmt.Errorf("metric %q clashes with a dimension", name)
+			}
+			out[name] = v
+		}
+		out["_aws"] = emfMetadata{
+			Timestamp: snap.End.UnixMilli(),
+			CloudWatchMetrics: []emfDirective{{
+				Namespace:  e.Namespace,
+				Dimensions: [][]string{dims},
+				Metrics:    d.metrics,
+			}},
+		}
+
+		line, err := json.Marshal(out)
+		if err != nil {
+			return err
+		}
+		if _, err := e.Writer.Write(append(line, '\n')); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// emfUnit maps an OTLP unit to its CloudWatch name
+func emfUnit(unit string) string {
+	switch unit {
+	case UnitBytes:
+		return "Bytes"
+	case UnitMilliseconds:
+		return "Milliseconds"
+	}
+	if unit == UnitCount || strings.HasPrefix(unit, "{") {
+		return "Count"
+	}
+	return "None"
+}
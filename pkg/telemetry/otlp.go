@@ -0,0 +1,74 @@
+package telemetry
+
+import (
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+)
+
+// Metrics converts the snapshot into OTLP delta sums and histograms
+func (s Snapshot) Metrics() []converter.Metric {
+	start := strconv.FormatInt(s.Start.UnixNano(), 10)
+	end := strconv.FormatInt(s.End.UnixNano(), 10)
+
+	// Series of one metric share a metric with one data point each
+	var metrics []converter.Metric
+	index := make(map[string]int)
+	metric := func(name, unit string) *converter.Metric {
+		i, ok := index[name]
+		if !ok {
+			i = len(metrics)
+			index[name] = i
+			metrics = append(metrics, converter.Metric{Name: name, Unit: unit})
+		}
+		return &metrics[i]
+	}
+
+	for _, c := range s.Counters {
+		m := metric(c.Name, c.Unit)
+		if m.Sum == nil {
+			m.Sum = &converter.Sum{AggregationTemporality: converter.AggregationTemporalityDelta, IsMonotonic: true}
+		}
+		m.Sum.DataPoints = append(m.Sum.DataPoints, converter.NumberDataPoint{
+			Attributes:        otelAttrs(c.Attrs),
+			StartTimeUnixNano: start,
+			TimeUnixNano:      end,
+			AsInt:             strconv.FormatInt(c.Value, 10),
+		})
+	}
+	for _, h := range s.Histograms {
+		m := metric(h.Name, h.Unit)
+		if m.Histogram == nil {
+			m.Histogram = &converter.Histogram{AggregationTemporality: converter.AggregationTemporalityDelta}
+		}
+		counts := make([]string, len(h.BucketCounts))
+		for i, n := range h.BucketCounts {
+			counts[i] = strconv.FormatInt(n, 10)
+		}
+		m.Histogram.DataPoints = append(m.Histogram.DataPoints, converter.HistogramDataPoint{
+			Attributes:        otelAttrs(h.Attrs),
+			StartTimeUnixNano: start,
+			TimeUnixNano:      end,
+			Count:             strconv.FormatInt(h.Count, 10),
+			Sum:               h.Sum,
+			Min:               h.Min,
+			Max:               h.Max,
+			BucketCounts:      counts,
+			ExplicitBounds:    h.Bounds,
+		})
+	}
+	return metrics
+}
+
+func otelAttrs(attrs []Attr) []converter.OTelAttribute {
+	if len(attrs) == 0 {
+		return nil
+	}
+	out := make([]converter.OTelAttribute, 0, len(attrs))
+	for _, a := range attrs {
+		out = append(out, converter.OTelAttribute{Key: a.Key, Value: converter.OTelAnyValue{StringValue: aws.String(a.Value)}})
+	}
+	return out
+}
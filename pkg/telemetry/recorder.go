@@ -0,0 +1,159 @@
+// Package telemetry records the parser's own processing metrics and flushes them as CloudWatch
+// embedded metric format (EMF) log lines or as OTLP metrics.
+package telemetry
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Units understood by both flush formats
+const (
+	UnitCount        = "1"
+	UnitBytes        = "By"
+	UnitMilliseconds = "ms"
+)
+
+// DefaultBounds are the histogram bucket bounds, suited to latencies in milliseconds
+var DefaultBounds = []float64{5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000, 30000}
+
+// maxValues is the number of raw observations kept per histogram, the most an EMF metric
+// accepts
+const maxValues = 100
+
+// Attr is a metric attribute, written as a dimension in EMF
+type Attr struct {
+	Key   string
+	Value string
+}
+
+// Counter is the sum of the values added to one series
+type Counter struct {
+	Name  string
+	Unit  string
+	Attrs []Attr
+	Value int64
+}
+
+// Histogram is the distribution of the values observed for one series. Values holds the
+// first observations, up to 100.
+type Histogram struct {
+	Name         string
+	Unit         string
+	Attrs        []Attr
+	Count        int64
+	Sum          float64
+	Min          float64
+	Max          float64
+	Bounds       []float64
+	BucketCounts []int64
+	Values       []float64
+}
+
+// Snapshot is everything recorded between Start and End
+type Snapshot struct {
+	Start      time.Time
+	End        time.Time
+	Counters   []Counter
+	Histograms []Histogram
+}
+
+// Empty reports whether nothing was recorded
+func (s Snapshot) Empty() bool {
+	return len(s.Counters) == 0 && len(s.Histograms) == 0
+}
+
+// Recorder accumulates counters and histograms from concurrent callers. A nil *Recorder
+// discards everything, so instrumented code needs no checks when telemetry is off.
+type Recorder struct {
+	mu         sync.Mutex
+	start      time.Time
+	counters   map[string]*Counter
+	histograms map[string]*Histogram
+}
+
+// NewRecorder creates an empty recorder
+func NewRecorder() *Recorder {
+	return &Recorder{start: time.Now(), counters: make(map[string]*Counter), histograms: make(map[string]*Histogram)}
+}
+
+// Add adds value to the counter of the series; zero values still create the series
+func (r *Recorder) Add(name, unit string, value int64, attrs ...Attr) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := seriesKey(name, attrs)
+	c, ok := r.counters[key]
+	if !ok {
+		c = &Counter{Name: name, Unit: unit, Attrs: attrs}
+		r.counters[key] = c
+	}
+	c.Value += value
+}
+
+// Observe records one value in the histogram of the series
+func (r *Recorder) Observe(name, unit string, value float64, attrs ...Attr) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := seriesKey(name, attrs)
+	h, ok := r.histograms[key]
+	if !ok {
+		h = &Histogram{Name: name, Unit: unit, Attrs: attrs, Min: value, Max: value, Bounds: DefaultBounds, BucketCounts: make([]int64, len(DefaultBounds)+1)}
+		r.histograms[key] = h
+	}
+	h.Count++
+	h.Sum += value
+	h.Min = min(h.Min, value)
+	h.Max = max(h.Max, value)
+	h.BucketCounts[sort.SearchFloat64s(h.Bounds, value)]++
+	if len(h.Values) < maxValues {
+		h.Values = append(h.Values, value)
+	}
+}
+
+// Take returns what was recorded since the last call, sorted by series, and resets the recorder
+func (r *Recorder) Take() Snapshot {
+	if r == nil {
+		return Snapshot{}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	snap := Snapshot{Start: r.start, End: now}
+	for _, key := range sortedKeys(r.counters) {
+		snap.Counters = append(snap.Counters, *r.counters[key])
+	}
+	for _, key := range sortedKeys(r.histograms) {
+		snap.Histograms = append(snap.Histograms, *r.histograms[key])
+	}
+	r.start = now
+	r.counters = make(map[string]*Counter)
+	r.histograms = make(map[string]*Histogram)
+	return snap
+}
+
+func seriesKey(name string, attrs []Attr) string {
+	var b strings.Builder
+	b.WriteString(name)
+	for _, a := range attrs {
+		b.WriteString("|" + a.Key + "=" + a.Value)
+	}
+	return b.String()
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
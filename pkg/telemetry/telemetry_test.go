@@ -0,0 +1,119 @@
+package telemetry
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecorder(t *testing.T) {
+	r := NewRecorder()
+	alb := Attr{Key: "processor", Value: "alb"}
+	r.Add("lines", UnitCount, 10, alb)
+	r.Add("lines", UnitCount, 5, alb)
+	r.Add("lines", UnitCount, 7, Attr{Key: "processor", Value: "waf"})
+	for _, v := range []float64{3, 10, 40, 20000, 60000} {
+		r.Observe("latency", UnitMilliseconds, v)
+	}
+
+	snap := r.Take()
+	if len(snap.Counters) != 2 || snap.Counters[0].Value != 15 || snap.Counters[1].Value != 7 {
+		t.Fatalf("Counters = %+v", snap.Counters)
+	}
+	h := snap.Histograms[0]
+	if h.Count != 5 || h.Sum != 80053 || h.Min != 3 || h.Max != 60000 || len(h.Values) != 5 {
+		t.Errorf("Histogram = %+v", h)
+	}
+	// 3 -> (,5], 10 -> (5,10], 40 -> (25,50], 20000 -> (10000,30000], 60000 -> overflow
+	want := []int64{1, 1, 0, 1, 0, 0, 0, 0, 0, 0, 0, 1, 1}
+	for i := range want {
+		if h.BucketCounts[i] != want[i] {
+			t.Fatalf("BucketCounts = %v, want %v", h.BucketCounts, want)
+		}
+	}
+
+	if again := r.Take(); !again.Empty() || !again.Start.Equal(snap.End) {
+		t.Errorf("Take() after reset = %+v", again)
+	}
+
+	var off *Recorder
+	off.Add("lines", UnitCount, 1)
+	off.Observe("latency", UnitMilliseconds, 1)
+	if !off.Take().Empty() {
+		t.Error("nil recorder recorded something")
+	}
+}
+
+func TestEMF_Write(t *testing.T) {
+	r := NewRecorder()
+	r.Add("lines", UnitCount, 12, Attr{Key: "processor", Value: "alb"})
+	r.Add("bytes_in", UnitBytes, 2048, Attr{Key: "processor", Value: "alb"})
+	r.Add("retries", UnitCount, 1)
+	r.Observe("export.duration", UnitMilliseconds, 42.5)
+	snap := r.Take()
+	snap.End = time.UnixMilli(1700000000000)
+
+	var buf bytes.Buffer
+	emf := &EMF{Writer: &buf, Namespace: "Test", Dimensions: []Attr{{Key: "FunctionName", Value: "parser"}}}
+	if err := emf.Write(snap); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("wrote %d documents, want one per attribute set:\n%s", len(lines), buf.String())
+	}
+
+	docs := make(map[string]map[string]any)
+	for _, line := range lines {
+		var doc map[string]any
+		if err := json.Unmarshal([]byte(line), &doc); err != nil {
+			t.Fatal(err)
+		}
+		key, _ := doc["processor"].(string)
+		docs[key] = doc
+	}
+
+	alb := docs["alb"]
+	if alb["lines"] != 12.0 || alb["bytes_in"] != 2048.0 || alb["FunctionName"] != "parser" {
+		t.Errorf("alb document = %v", alb)
+	}
+	meta := alb["_aws"].(map[string]any)
+	directive := meta["CloudWatchMetrics"].([]any)[0].(map[string]any)
+	if meta["Timestamp"] != 1700000000000.0 || directive["Namespace"] != "Test" {
+		t.Errorf("_aws = %v", meta)
+	}
+	if dims, _ := json.Marshal(directive["Dimensions"]); string(dims) != `[["FunctionName","processor"]]` {
+		t.Errorf("Dimensions = %s", dims)
+	}
+	if units, _ := json.Marshal(directive["Metrics"]); !strings.Contains(string(units), `{"Name":"bytes_in","Unit":"Bytes"}`) {
+		t.Errorf("Metrics = %s", units)
+	}
+
+	plain := docs[""]
+	if values, ok := plain["export.duration"].([]any); !ok || len(values) != 1 || values[0] != 42.5 || plain["retries"] != 1.0 {
+		t.Errorf("document without attributes = %v", plain)
+	}
+}
+
+func TestSnapshot_Metrics(t *testing.T) {
+	r := NewRecorder()
+	r.Add("lines", UnitCount, 3, Attr{Key: "processor", Value: "alb"})
+	r.Add("lines", UnitCount, 4, Attr{Key: "processor", Value: "nlb"})
+	r.Observe("export.duration", UnitMilliseconds, 7)
+
+	metrics := r.Take().Metrics()
+	if len(metrics) != 2 {
+		t.Fatalf("Metrics() = %+v, want one metric per name", metrics)
+	}
+	lines := metrics[0]
+	if lines.Name != "lines" || lines.Sum == nil || len(lines.Sum.DataPoints) != 2 || lines.Sum.DataPoints[1].AsInt != "4" {
+		t.Errorf("lines = %+v", lines)
+	}
+	hist := metrics[1].Histogram
+	if hist == nil || hist.DataPoints[0].Count != "1" || len(hist.DataPoints[0].BucketCounts) != len(DefaultBounds)+1 || hist.DataPoints[0].BucketCounts[1] != "1" {
+		t.Errorf("export.duration = %+v", metrics[1])
+	}
+}
@@ -0,0 +1,165 @@
+// Package tracing records spans of the parser's own work and hands them out as OTLP spans,
+// so slow invocations can be broken down by object, stage and export batch.
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+	"math/rand/v2"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+)
+
+// Tracer collects the spans finished since the last Take
+type Tracer struct {
+	mu    sync.Mutex
+	spans []converter.Span
+}
+
+// Span is an operation in progress, used by one goroutine at a time. A nil *Span ignores
+// every call, which is what Start returns when the context carries no trace.
+type Span struct {
+	tracer  *Tracer
+	traceID [16]byte
+	spanID  [8]byte
+	parent  [8]byte
+	name    string
+	kind    int
+	start   time.Time
+	attrs   []converter.OTelAttribute
+	err     error
+}
+
+type spanKey struct{}
+
+// Root starts a new trace with a server span, e.g. one per invocation
+func (t *Tracer) Root(ctx context.Context, name string) (context.Context, *Span) {
+	if t == nil {
+		return ctx, nil
+	}
+	s := &Span{tracer: t, name: name, kind: converter.SpanKindServer, start: time.Now()}
+	fillRandom(s.traceID[:])
+	fillRandom(s.spanID[:])
+	return context.WithValue(ctx, spanKey{}, s), s
+}
+
+// Start starts a child of the span in ctx. Without one, tracing is off and it returns ctx and nil.
+func Start(ctx context.Context, name string) (context.Context, *Span) {
+	parent := FromContext(ctx)
+	if parent == nil {
+		return ctx, nil
+	}
+	s := &Span{tracer: parent.tracer, traceID: parent.traceID, parent: parent.spanID, name: name, kind: converter.SpanKindInternal, start: time.Now()}
+	fillRandom(s.spanID[:])
+	return context.WithValue(ctx, spanKey{}, s), s
+}
+
+// StartAt is Start for work that began earlier, e.g. time summed over many calls whose extent
+// is only known afterwards; finish such spans with EndAt
+func StartAt(ctx context.Context, name string, start time.Time) (context.Context, *Span) {
+	ctx, s := Start(ctx, name)
+	if s != nil {
+		s.start = start
+	}
+	return ctx, s
+}
+
+// FromContext returns the span in ctx, or nil
+func FromContext(ctx context.Context) *Span {
+	s, _ := ctx.Value(spanKey{}).(*Span)
+	return s
+}
+
+// Traceparent returns the W3C traceparent header of the span in ctx, or "" without one
+func Traceparent(ctx context.Context) string {
+	s := FromContext(ctx)
+	if s == nil {
+		return ""
+	}
+	return "00-" + hex.EncodeToString(s.traceID[:]) + "-" + hex.EncodeToString(s.spanID[:]) + "-01"
+}
+
+// SetKind overrides the span kind, e.g. converter.SpanKindClient for outgoing requests
+func (s *Span) SetKind(kind int) {
+	if s != nil {
+		s.kind = kind
+	}
+}
+
+// SetString sets a string attribute
+func (s *Span) SetString(key, value string) {
+	if s != nil {
+		s.attrs = append(s.attrs, converter.OTelAttribute{Key: key, Value: converter.OTelAnyValue{StringValue: aws.String(value)}})
+	}
+}
+
+// SetInt sets an integer attribute
+func (s *Span) SetInt(key string, value int64) {
+	if s != nil {
+		v := strconv.FormatInt(value, 10)
+		s.attrs = append(s.attrs, converter.OTelAttribute{Key: key, Value: converter.OTelAnyValue{IntValue: &v}})
+	}
+}
+
+// SetError marks the span as failed; nil errors are ignored
+func (s *Span) SetError(err error) {
+	if s != nil && err != nil {
+		s.err = err
+	}
+}
+
+// End finishes the span and hands it to the tracer
+func (s *Span) End() {
+	s.EndAt(time.Now())
+}
+
+// EndAt finishes the span at the given time
+func (s *Span) EndAt(end time.Time) {
+	if s == nil {
+		return
+	}
+	span := converter.Span{
+		TraceID:           hex.EncodeToString(s.traceID[:]),
+		SpanID:            hex.EncodeToString(s.spanID[:]),
+		Name:              s.name,
+		Kind:              s.kind,
+		StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(end.UnixNano(), 10),
+		Attributes:        s.attrs,
+	}
+	if s.parent != [8]byte{} {
+		span.ParentSpanID = hex.EncodeToString(s.parent[:])
+	}
+	if s.err != nil {
+		span.Status = &converter.SpanStatus{Code: converter.StatusCodeError, Message: s.err.Error()}
+	}
+
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.tracer.spans = append(s.tracer.spans, span)
+}
+
+// Take returns the spans finished since the last call
+func (t *Tracer) Take() []converter.Span {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	spans := t.spans
+	t.spans = nil
+	return spans
+}
+
+func fillRandom(b []byte) {
+	for i := range b {
+		b[i] = byte(rand.Uint32())
+	}
+	// All-zero IDs are invalid
+	b[0] |= 1
+}
@@ -0,0 +1,77 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+)
+
+func TestTracer_ParentChild(t *testing.T) {
+	tracer := &Tracer{}
+	ctx, root := tracer.Root(context.Background(), "invocation")
+	_, child := Start(ctx, "s3.get_object")
+	child.SetKind(converter.SpanKindClient)
+	child.SetInt("bytes", 42)
+	child.SetError(errors.New("access denied"))
+	child.End()
+	root.End()
+
+	spans := tracer.Take()
+	if len(spans) != 2 {
+		t.Fatalf("Take() returned %d spans, want 2", len(spans))
+	}
+	got, parent := spans[0], spans[1]
+	if got.TraceID != parent.TraceID {
+		t.Errorf("child trace ID = %s, want %s", got.TraceID, parent.TraceID)
+	}
+	if got.ParentSpanID != parent.SpanID || parent.ParentSpanID != "" {
+		t.Errorf("parent IDs = %q/%q, want %q/\"\"", got.ParentSpanID, parent.ParentSpanID, parent.SpanID)
+	}
+	if got.Kind != converter.SpanKindClient || parent.Kind != converter.SpanKindServer {
+		t.Errorf("kinds = %d/%d", got.Kind, parent.Kind)
+	}
+	if got.Status == nil || got.Status.Code != converter.StatusCodeError || got.Status.Message != "access denied" {
+		t.Errorf("child status = %+v", got.Status)
+	}
+	if len(got.Attributes) != 1 || *got.Attributes[0].Value.IntValue != "42" {
+		t.Errorf("child attributes = %+v", got.Attributes)
+	}
+
+	if spans := tracer.Take(); len(spans) != 0 {
+		t.Errorf("second Take() returned %d spans, want 0", len(spans))
+	}
+}
+
+func TestTraceparent(t *testing.T) {
+	if tp := Traceparent(context.Background()); tp != "" {
+		t.Errorf("Traceparent() without span = %q, want empty", tp)
+	}
+
+	ctx, _ := (&Tracer{}).Root(context.Background(), "invocation")
+	tp := Traceparent(ctx)
+	if !regexp.MustCompile(`^00-[0-9a-f]{32}-[0-9a-f]{16}-01$`).MatchString(tp) {
+		t.Errorf("Traceparent() = %q", tp)
+	}
+}
+
+func TestNilTracer(t *testing.T) {
+	var tracer *Tracer
+	ctx, root := tracer.Root(context.Background(), "invocation")
+	if root != nil {
+		t.Fatal("nil tracer returned a span")
+	}
+	_, child := Start(ctx, "parse")
+	if child != nil {
+		t.Fatal("Start() without a parent returned a span")
+	}
+	// Calls on nil spans are no-ops
+	child.SetString("key", "value")
+	child.SetError(errors.New("boom"))
+	child.End()
+	if spans := tracer.Take(); spans != nil {
+		t.Errorf("Take() = %v, want nil", spans)
+	}
+}
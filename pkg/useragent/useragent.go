@@ -0,0 +1,145 @@
+// Package useragent extracts the client, its version and operating system from User-Agent
+// strings. It recognizes the common browsers, crawlers and HTTP libraries, which covers the
+// bulk of load balancer and CDN traffic, and is cheap enough to run on every record.
+package useragent
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Info is what Parse recognized; fields it could not determine are empty
+type Info struct {
+	Name      string
+	Version   string
+	OSName    string
+	OSVersion string
+	// IsBot marks crawlers, monitors, headless browsers and HTTP libraries
+	IsBot bool
+}
+
+// browser matches a product token; the first matching entry names the client
+type browser struct {
+	name    string
+	pattern *regexp.Regexp
+}
+
+// Order matters: Edge and Opera also send Chrome/ and Safari/, Chrome sends Safari/
+var browsers = []browser{
+	{"HeadlessChrome", regexp.MustCompile(`\bHeadlessChrome/([\d.]+)`)},
+	{"Edge", regexp.MustCompile(`\bEdg(?:e|A|iOS)?/([\d.]+)`)},
+	{"Opera", regexp.MustCompile(`\b(?:OPR|Opera)/([\d.]+)`)},
+	{"Samsung Internet", regexp.MustCompile(`\bSamsungBrowser/([\d.]+)`)},
+	{"Firefox", regexp.MustCompile(`\b(?:Firefox|FxiOS)/([\d.]+)`)},
+	{"Chrome", regexp.MustCompile(`\b(?:Chrome|CriOS)/([\d.]+)`)},
+	{"Safari", regexp.MustCompile(`\bVersion/([\d.]+).*\bSafari/`)},
+	{"Internet Explorer", regexp.MustCompile(`\b(?:MSIE |Trident/.*\brv:)([\d.]+)`)},
+}
+
+var (
+	// botPattern matches automated clients by their self-description
+	botPattern = regexp.MustCompile(`(?i)bot\b|bot/|crawl|spider|slurp|headless|facebookexternalhit|monitor|uptime|pingdom|lighthouse|scanner|preview`)
+
+	// libraryPattern matches command line tools and HTTP client libraries
+	libraryPattern = regexp.MustCompile(`(?i)^(curl|wget|python-requests|python-urllib|go-http-client|okhttp|java|apache-httpclient|axios|node-fetch|libwww-perl|aws-sdk-[a-z]+|Boto3|ELB-HealthChecker)/`)
+
+	// productPattern is the leading "name/version" token
+	productPattern = regexp.MustCompile(`^([A-Za-z][\w.\- ]*?)/([\w.]+)`)
+
+	// crawlerPattern names the crawler in "...compatible; Googlebot/2.1; ..."
+	crawlerPattern = regexp.MustCompile(`(?i)\b([\w\-]*(?:bot|crawler|spider|slurp)[\w\-]*)/([\d.]+)`)
+)
+
+type osRule struct {
+	name    string
+	pattern *regexp.Regexp
+}
+
+var operatingSystems = []osRule{
+	{"iOS", regexp.MustCompile(`\b(?:iPhone|iPad|iPod).*? OS ([\d_]+)`)},
+	{"Android", regexp.MustCompile(`\bAndroid ?([\d.]*)`)},
+	{"Windows", regexp.MustCompile(`\bWindows NT ([\d.]+)`)},
+	{"macOS", regexp.MustCompile(`\bMac OS X ?([\d_.]*)`)},
+	{"Chrome OS", regexp.MustCompile(`\bCrOS \w+ ([\d.]+)`)},
+	{"Linux", regexp.MustCompile(`\bLinux\b()`)},
+}
+
+// Parse recognizes a User-Agent string
+func Parse(ua string) Info {
+	ua = strings.TrimSpace(ua)
+	var info Info
+	if ua == "" || ua == "-" {
+		return info
+	}
+
+	for _, rule := range operatingSystems {
+		if m := rule.pattern.FindStringSubmatch(ua); m != nil {
+			info.OSName = rule.name
+			info.OSVersion = strings.ReplaceAll(m[1], "_", ".")
+			break
+		}
+	}
+
+	if libraryPattern.MatchString(ua) {
+		info.IsBot = true
+		if p := productPattern.FindStringSubmatch(ua); p != nil {
+			info.Name, info.Version = p[1], p[2]
+		}
+		return info
+	}
+
+	if botPattern.MatchString(ua) {
+		info.IsBot = true
+		if m := crawlerPattern.FindStringSubmatch(ua); m != nil {
+			info.Name, info.Version = m[1], m[2]
+			return info
+		}
+	}
+
+	for _, b := range browsers {
+		if m := b.pattern.FindStringSubmatch(ua); m != nil {
+			info.Name, info.Version = b.name, m[1]
+			return info
+		}
+	}
+
+	// Anything else: the leading product token, unless it is the generic Mozilla/x.0
+	if p := productPattern.FindStringSubmatch(ua); p != nil && p[1] != "Mozilla" {
+		info.Name, info.Version = p[1], p[2]
+	}
+	return info
+}
+
+// Cache memoizes Parse; clients send the same few User-Agent strings over and over. It holds
+// at most Size entries and starts over once full. The zero value is ready to use.
+type Cache struct {
+	// Size bounds the cache; zero means 10000
+	Size int
+
+	mu      sync.Mutex
+	entries map[string]Info
+}
+
+// Parse returns the cached result for ua, parsing it on a miss
+func (c *Cache) Parse(ua string) Info {
+	c.mu.Lock()
+	info, ok := c.entries[ua]
+	c.mu.Unlock()
+	if ok {
+		return info
+	}
+
+	info = Parse(ua)
+	size := c.Size
+	if size <= 0 {
+		size = 10000
+	}
+	c.mu.Lock()
+	if c.entries == nil || len(c.entries) >= size {
+		c.entries = make(map[string]Info)
+	}
+	c.entries[ua] = info
+	c.mu.Unlock()
+	return info
+}
@@ -0,0 +1,50 @@
+package useragent
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		ua   string
+		want Info
+	}{
+		{"Chrome on Windows", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.6099.109 Safari/537.36",
+			Info{Name: "Chrome", Version: "120.0.6099.109", OSName: "Windows", OSVersion: "10.0"}},
+		{"Edge", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36 Edg/120.0.2210.91",
+			Info{Name: "Edge", Version: "120.0.2210.91", OSName: "Windows", OSVersion: "10.0"}},
+		{"Safari on iPhone", "Mozilla/5.0 (iPhone; CPU iPhone OS 17_1_2 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.1.2 Mobile/15E148 Safari/604.1",
+			Info{Name: "Safari", Version: "17.1.2", OSName: "iOS", OSVersion: "17.1.2"}},
+		{"Firefox on macOS", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10.15; rv:121.0) Gecko/20100101 Firefox/121.0",
+			Info{Name: "Firefox", Version: "121.0", OSName: "macOS", OSVersion: "10.15"}},
+		{"Chrome on Android", "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.6099.144 Mobile Safari/537.36",
+			Info{Name: "Chrome", Version: "120.0.6099.144", OSName: "Android", OSVersion: "14"}},
+		{"Googlebot", "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+			Info{Name: "Googlebot", Version: "2.1", IsBot: true}},
+		{"Headless Chrome", "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) HeadlessChrome/120.0.0.0 Safari/537.36",
+			Info{Name: "HeadlessChrome", Version: "120.0.0.0", OSName: "Linux", IsBot: true}},
+		{"curl", "curl/7.46.0", Info{Name: "curl", Version: "7.46.0", IsBot: true}},
+		{"Go client", "Go-http-client/1.1", Info{Name: "Go-http-client", Version: "1.1", IsBot: true}},
+		{"ELB health check", "ELB-HealthChecker/2.0", Info{Name: "ELB-HealthChecker", Version: "2.0", IsBot: true}},
+		{"unknown app", "MyApp/3.2 (iPhone; iOS 17.0)", Info{Name: "MyApp", Version: "3.2"}},
+		{"placeholder", "-", Info{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Parse(tt.ua); got != tt.want {
+				t.Errorf("Parse() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCache(t *testing.T) {
+	c := &Cache{Size: 2}
+	for _, ua := range []string{"curl/8.0", "Wget/1.21", "curl/8.0", "okhttp/4.12.0"} {
+		if got, want := c.Parse(ua), Parse(ua); got != want {
+			t.Errorf("Cache.Parse(%q) = %+v, want %+v", ua, got, want)
+		}
+	}
+	if len(c.entries) > 2 {
+		t.Errorf("cache holds %d entries, want at most 2", len(c.entries))
+	}
+}
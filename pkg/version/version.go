@@ -0,0 +1,87 @@
+// Package version reports the build identity of the parser binaries.
+//
+// Version, Commit and BuildDate are injected at link time, e.g.
+//
+//	go build -ldflags "-X github.com/pixelvide/otel-aws-log-parser/pkg/version.Version=v1.2.3"
+//
+// When they are not set, Info falls back to the VCS metadata embedded by the Go toolchain.
+package version
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"sync"
+)
+
+// Build identity, overridden via -ldflags at build time
+var (
+	Version   = "dev"
+	Commit    = ""
+	BuildDate = ""
+)
+
+// BuildInfo describes the running build
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	BuildDate string `json:"buildDate,omitempty"`
+	GoVersion string `json:"goVersion"`
+}
+
+var (
+	infoOnce sync.Once
+	info     BuildInfo
+)
+
+// Info returns the build identity of the running binary.
+//
+// The result is computed once and is stable for the lifetime of the process,
+// so it is safe to attach to every exported record.
+func Info() BuildInfo {
+	infoOnce.Do(func() {
+		info = BuildInfo{
+			Version:   Version,
+			Commit:    Commit,
+			BuildDate: BuildDate,
+			GoVersion: runtime.Version(),
+		}
+
+		if bi, ok := debug.ReadBuildInfo(); ok {
+			for _, s := range bi.Settings {
+				switch s.Key {
+				case "vcs.revision":
+					if info.Commit == "" {
+						info.Commit = s.Value
+					}
+				case "vcs.time":
+					if info.BuildDate == "" {
+						info.BuildDate = s.Value
+					}
+				}
+			}
+		}
+	})
+	return info
+}
+
+// String returns the version followed by the short commit, e.g. "v1.2.3+abc1234"
+func (b BuildInfo) String() string {
+	if b.Commit == "" {
+		return b.Version
+	}
+	commit := b.Commit
+	if len(commit) > 7 {
+		commit = commit[:7]
+	}
+	return b.Version + "+" + commit
+}
+
+// Handler serves the build info as JSON, for mounting at /version
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(Info())
+	})
+}
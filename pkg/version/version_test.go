@@ -0,0 +1,37 @@
+package version
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBuildInfoString(t *testing.T) {
+	tests := []struct {
+		info BuildInfo
+		want string
+	}{
+		{BuildInfo{Version: "v1.2.3"}, "v1.2.3"},
+		{BuildInfo{Version: "v1.2.3", Commit: "abc1234def5678"}, "v1.2.3+abc1234"},
+		{BuildInfo{Version: "dev", Commit: "abc"}, "dev+abc"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.info.String(); got != tt.want {
+			t.Errorf("String() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/version", nil))
+
+	var got BuildInfo
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if got.Version != Info().Version || got.GoVersion == "" {
+		t.Errorf("Handler() returned %+v, want %+v", got, Info())
+	}
+}
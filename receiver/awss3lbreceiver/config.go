@@ -0,0 +1,88 @@
+package awss3lbreceiver
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Config is the receiver's collector configuration. Exactly one of sqs and s3 selects how new
+// log objects are found.
+type Config struct {
+	// Region is the AWS region of the queue and bucket; empty uses the SDK's default chain
+	Region string `mapstructure:"region"`
+	// SQS receives S3 event notifications, directly, through EventBridge or through SNS
+	SQS SQSConfig `mapstructure:"sqs"`
+	// S3 lists a bucket prefix for objects written since the receiver started
+	S3 S3Config `mapstructure:"s3"`
+	// MaxConcurrent is the number of goroutines parsing the lines of one object
+	MaxConcurrent int `mapstructure:"max_concurrent"`
+	// BatchSize caps the records of one resource handed to the next consumer at once
+	BatchSize int `mapstructure:"batch_size"`
+	// Ordered keeps the records of an object in the order of its lines
+	Ordered bool `mapstructure:"ordered"`
+}
+
+// SQSConfig configures reading S3 event notifications from a queue
+type SQSConfig struct {
+	QueueURL string `mapstructure:"queue_url"`
+	// WaitTime is the long-poll duration of each ReceiveMessage call, at most 20s
+	WaitTime time.Duration `mapstructure:"wait_time"`
+	// MaxMessages is the number of messages received per call, 1 to 10
+	MaxMessages int `mapstructure:"max_messages"`
+	// VisibilityTimeout overrides the queue's; it must cover reading the largest object
+	VisibilityTimeout time.Duration `mapstructure:"visibility_timeout"`
+}
+
+// S3Config configures polling a bucket prefix
+type S3Config struct {
+	Bucket       string        `mapstructure:"bucket"`
+	Prefix       string        `mapstructure:"prefix"`
+	PollInterval time.Duration `mapstructure:"poll_interval"`
+}
+
+func createDefaultConfig() *Config {
+	return &Config{
+		SQS: SQSConfig{
+			WaitTime:    20 * time.Second,
+			MaxMessages: 10,
+		},
+		S3: S3Config{
+			PollInterval: time.Minute,
+		},
+		MaxConcurrent: 10,
+		BatchSize:     500,
+	}
+}
+
+// Validate checks the configuration when the collector loads it
+func (cfg *Config) Validate() error {
+	var errs []error
+	switch {
+	case cfg.SQS.QueueURL == "" && cfg.S3.Bucket == "":
+		errs = append(errs, errors.New("one of sqs.queue_url and s3.bucket is required"))
+	case cfg.SQS.QueueURL != "" && cfg.S3.Bucket != "":
+		errs = append(errs, errors.New("sqs.queue_url and s3.bucket are mutually exclusive"))
+	}
+	if cfg.SQS.QueueURL != "" {
+		if cfg.SQS.WaitTime < 0 || cfg.SQS.WaitTime > 20*time.Second {
+			errs = append(errs, fmt.Errorf("sqs.wait_time must be between 0s and 20s, got %s", cfg.SQS.WaitTime))
+		}
+		if cfg.SQS.MaxMessages < 1 || cfg.SQS.MaxMessages > 10 {
+			errs = append(errs, fmt.Errorf("sqs.max_messages must be between 1 and 10, got %d", cfg.SQS.MaxMessages))
+		}
+		if cfg.SQS.VisibilityTimeout < 0 || cfg.SQS.VisibilityTimeout > 12*time.Hour {
+			errs = append(errs, fmt.Errorf("sqs.visibility_timeout must be between 0s and 12h, got %s", cfg.SQS.VisibilityTimeout))
+		}
+	}
+	if cfg.S3.Bucket != "" && cfg.S3.PollInterval <= 0 {
+		errs = append(errs, fmt.Errorf("s3.poll_interval must be positive, got %s", cfg.S3.PollInterval))
+	}
+	if cfg.MaxConcurrent < 1 {
+		errs = append(errs, fmt.Errorf("max_concurrent must be at least 1, got %d", cfg.MaxConcurrent))
+	}
+	if cfg.BatchSize < 1 {
+		errs = append(errs, fmt.Errorf("batch_size must be at least 1, got %d", cfg.BatchSize))
+	}
+	return errors.Join(errs...)
+}
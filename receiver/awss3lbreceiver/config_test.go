@@ -0,0 +1,78 @@
+package awss3lbreceiver
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(*Config)
+		wantErr bool
+	}{
+		{
+			name:   "sqs",
+			modify: func(cfg *Config) { cfg.SQS.QueueURL = "https://sqs.us-east-1.amazonaws.com/123456789012/logs" },
+		},
+		{
+			name:   "s3",
+			modify: func(cfg *Config) { cfg.S3.Bucket = "logs" },
+		},
+		{
+			name:    "neither source",
+			modify:  func(cfg *Config) {},
+			wantErr: true,
+		},
+		{
+			name: "both sources",
+			modify: func(cfg *Config) {
+				cfg.SQS.QueueURL = "https://sqs.us-east-1.amazonaws.com/123456789012/logs"
+				cfg.S3.Bucket = "logs"
+			},
+			wantErr: true,
+		},
+		{
+			name: "wait time above the SQS maximum",
+			modify: func(cfg *Config) {
+				cfg.SQS.QueueURL = "https://sqs.us-east-1.amazonaws.com/123456789012/logs"
+				cfg.SQS.WaitTime = time.Minute
+			},
+			wantErr: true,
+		},
+		{
+			name: "too many messages per call",
+			modify: func(cfg *Config) {
+				cfg.SQS.QueueURL = "https://sqs.us-east-1.amazonaws.com/123456789012/logs"
+				cfg.SQS.MaxMessages = 11
+			},
+			wantErr: true,
+		},
+		{
+			name: "zero poll interval",
+			modify: func(cfg *Config) {
+				cfg.S3.Bucket = "logs"
+				cfg.S3.PollInterval = 0
+			},
+			wantErr: true,
+		},
+		{
+			name: "zero batch size",
+			modify: func(cfg *Config) {
+				cfg.S3.Bucket = "logs"
+				cfg.BatchSize = 0
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := createDefaultConfig()
+			tt.modify(cfg)
+			if err := cfg.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,13 @@
+package awss3lbreceiver
+
+import (
+	"go.opentelemetry.io/collector/pdata/plog"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+)
+
+// toLogs converts one batch of the pipeline into pdata. It is the conversion the OTLP exporters
+// marshal, so records are identical to the Lambda's down to the attribute types.
+func toLogs(logs converter.ResourceLog) (plog.Logs, error) {
+	return converter.ToLogs(converter.OTLPPayload{ResourceLogs: []converter.ResourceLog{logs}})
+}
@@ -0,0 +1,50 @@
+package awss3lbreceiver
+
+import (
+	"testing"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/processor"
+)
+
+const testALBLine = `http 2018-07-02T22:23:00.186641Z app/my-loadbalancer/50dc6c495c0c9188 192.168.131.39:2817 10.0.0.1:80 0.000 0.001 0.000 200 200 34 366 "GET http://www.example.com:80/ HTTP/1.1" "curl/7.46.0" - - arn:aws:elasticloadbalancing:us-east-2:123456789012:targetgroup/my-targets/73e2d6bc24d8a067 "Root=1-58337262-36d228ad5d99923122bbe354" "www.example.com" "-" 100 2018-07-02T22:22:48.364000Z "forward" "-" "-" "10.0.0.1:80" "200" "-" "-" -`
+
+func TestToLogs(t *testing.T) {
+	entry, err := processor.AdapterForLine("alb", testALBLine)
+	if err != nil {
+		t.Fatal(err)
+	}
+	record := entry.ToOTel()
+	logs, err := toLogs(converter.ResourceLog{
+		Resource: converter.ResourceAttributes{Attributes: entry.GetResourceAttributes()},
+		ScopeLogs: []converter.ScopeLog{{
+			Scope:      converter.Scope{Name: "otel-aws-log-parser"},
+			LogRecords: []converter.OTelLogRecord{record},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("toLogs() error = %v", err)
+	}
+
+	if logs.LogRecordCount() != 1 {
+		t.Fatalf("LogRecordCount() = %d, want 1", logs.LogRecordCount())
+	}
+	rl := logs.ResourceLogs().At(0)
+	if v, ok := rl.Resource().Attributes().Get("cloud.provider"); !ok || v.Str() != "aws" {
+		t.Errorf("cloud.provider = %v, want aws", v.AsString())
+	}
+	sl := rl.ScopeLogs().At(0)
+	if sl.Scope().Name() != "otel-aws-log-parser" {
+		t.Errorf("scope name = %q", sl.Scope().Name())
+	}
+	lr := sl.LogRecords().At(0)
+	if v, ok := lr.Attributes().Get("http.response.status_code"); !ok || v.Int() != 200 {
+		t.Errorf("http.response.status_code = %v, want 200", v.AsString())
+	}
+	if lr.SeverityText() != record.SeverityText || lr.Timestamp() == 0 {
+		t.Errorf("severity %q and timestamp %d not carried over", lr.SeverityText(), lr.Timestamp())
+	}
+	if lr.TraceID().IsEmpty() {
+		t.Error("trace ID from the ALB trace header was lost")
+	}
+}
@@ -0,0 +1,29 @@
+// Package awss3lbreceiver is an OpenTelemetry Collector receiver that reads ALB, NLB,
+// CloudFront and WAF access logs from S3 and produces the same log records as the Lambda.
+// New objects are found through S3 event notifications on an SQS queue or by polling a bucket
+// prefix; see README.md for the configuration.
+package awss3lbreceiver
+
+import (
+	"context"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/receiver"
+)
+
+// typeStr is the receiver's key in the collector configuration
+const typeStr = "awss3lb"
+
+// NewFactory returns the receiver factory to list in a collector build
+func NewFactory() receiver.Factory {
+	return receiver.NewFactory(
+		component.MustNewType(typeStr),
+		func() component.Config { return createDefaultConfig() },
+		receiver.WithLogs(createLogsReceiver, component.StabilityLevelAlpha),
+	)
+}
+
+func createLogsReceiver(_ context.Context, set receiver.Settings, cfg component.Config, next consumer.Logs) (receiver.Logs, error) {
+	return newLogsReceiver(cfg.(*Config), set.Logger, next), nil
+}
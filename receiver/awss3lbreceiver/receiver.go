@@ -0,0 +1,246 @@
+package awss3lbreceiver
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer"
+	"go.uber.org/zap"
+	"go.uber.org/zap/exp/zapslog"
+
+	"github.com/pixelvide/otel-aws-log-parser/pkg/converter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/exporter"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/pipeline"
+	"github.com/pixelvide/otel-aws-log-parser/pkg/processor"
+)
+
+// retryDelay is the pause after a failed ReceiveMessage or ListObjectsV2 call
+const retryDelay = 5 * time.Second
+
+type logsReceiver struct {
+	cfg    *Config
+	logger *zap.Logger
+	next   consumer.Logs
+
+	sqs      *sqs.Client
+	s3       *s3.Client
+	pipeline *pipeline.Pipeline
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func newLogsReceiver(cfg *Config, logger *zap.Logger, next consumer.Logs) *logsReceiver {
+	return &logsReceiver{cfg: cfg, logger: logger, next: next}
+}
+
+// Start creates the AWS clients and starts polling in the background. The clients retry in the
+// adaptive mode, so a throttled bucket or queue also slows down the first attempts.
+func (r *logsReceiver) Start(ctx context.Context, _ component.Host) error {
+	awsCfg, err := config.LoadDefaultConfig(ctx,
+		config.WithRegion(r.cfg.Region),
+		config.WithRetryer(func() aws.Retryer { return retry.NewAdaptiveMode() }),
+	)
+	if err != nil {
+		return err
+	}
+	r.s3 = s3.NewFromConfig(awsCfg)
+	r.sqs = sqs.NewFromConfig(awsCfg)
+	r.pipeline, err = pipeline.New(pipeline.Config{
+		Exporter:    exporter.ExporterFunc(r.consume),
+		Store:       &processor.S3Store{Client: r.s3},
+		ReadOptions: processor.ReadOptions{MaxConcurrent: r.cfg.MaxConcurrent, Ordered: r.cfg.Ordered},
+		BatchSize:   r.cfg.BatchSize,
+		Logger:      slog.New(zapslog.NewHandler(r.logger.Core())),
+	})
+	if err != nil {
+		return err
+	}
+
+	// The collector's start context ends with Start, so polling runs on its own
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		if r.cfg.SQS.QueueURL != "" {
+			r.pollQueue(ctx)
+		} else {
+			r.pollBucket(ctx, time.Now())
+		}
+	}()
+	return nil
+}
+
+// Shutdown stops polling and waits for the object being read to finish or be abandoned
+func (r *logsReceiver) Shutdown(_ context.Context) error {
+	if r.cancel != nil {
+		r.cancel()
+	}
+	r.wg.Wait()
+	return nil
+}
+
+// consume hands one batch of the pipeline to the next consumer
+func (r *logsReceiver) consume(ctx context.Context, logs converter.ResourceLog) error {
+	ld, err := toLogs(logs)
+	if err != nil {
+		return err
+	}
+	return r.next.ConsumeLogs(ctx, ld)
+}
+
+// processObject reads one object through the pipeline. Keys no processor matches are logged
+// and reported as done, so that their notifications are not redelivered forever.
+func (r *logsReceiver) processObject(ctx context.Context, bucket, key string) error {
+	res, err := r.pipeline.ProcessS3Object(ctx, bucket, key)
+	if errors.Is(err, pipeline.ErrNoProcessor) {
+		r.logger.Warn("Skipping object no processor matches", zap.String("bucket", bucket), zap.String("key", key))
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	r.logger.Debug("Processed object", zap.String("bucket", bucket), zap.String("key", key),
+		zap.Int("records", res.Records), zap.Int("batches", res.Batches))
+	return nil
+}
+
+// pollQueue long-polls the queue until ctx is cancelled. A message is deleted once all of its
+// objects were read; otherwise it becomes visible again after the visibility timeout.
+func (r *logsReceiver) pollQueue(ctx context.Context) {
+	input := &sqs.ReceiveMessageInput{
+		QueueUrl:            aws.String(r.cfg.SQS.QueueURL),
+		MaxNumberOfMessages: int32(r.cfg.SQS.MaxMessages),
+		WaitTimeSeconds:     int32(r.cfg.SQS.WaitTime / time.Second),
+	}
+	if r.cfg.SQS.VisibilityTimeout > 0 {
+		input.VisibilityTimeout = int32(r.cfg.SQS.VisibilityTimeout / time.Second)
+	}
+	slogger := slog.New(zapslog.NewHandler(r.logger.Core()))
+
+	for ctx.Err() == nil {
+		out, err := r.sqs.ReceiveMessage(ctx, input)
+		if err != nil {
+			if ctx.Err() == nil {
+				r.logger.Warn("Failed to receive messages", zap.Error(err))
+				sleep(ctx, retryDelay)
+			}
+			continue
+		}
+		for _, msg := range out.Messages {
+			if !r.handleMessage(ctx, slogger, aws.ToString(msg.Body)) {
+				continue
+			}
+			_, err := r.sqs.DeleteMessage(ctx, &sqs.DeleteMessageInput{
+				QueueUrl:      input.QueueUrl,
+				ReceiptHandle: msg.ReceiptHandle,
+			})
+			if err != nil && ctx.Err() == nil {
+				r.logger.Warn("Failed to delete message", zap.String("message_id", aws.ToString(msg.MessageId)), zap.Error(err))
+			}
+		}
+	}
+}
+
+// handleMessage reads the objects of one notification and reports whether the message can be
+// deleted. Bodies that are not S3 notifications are dropped, as the Lambda does.
+func (r *logsReceiver) handleMessage(ctx context.Context, logger *slog.Logger, body string) bool {
+	records, err := processor.ParseS3Notification(logger, []byte(body))
+	if err != nil {
+		r.logger.Warn("Skipping message that is not an S3 notification", zap.Error(err))
+		return true
+	}
+	for _, record := range records {
+		bucket, key := record.S3.Bucket.Name, record.S3.Object.Key
+		if err := r.processObject(ctx, bucket, key); err != nil {
+			if ctx.Err() == nil {
+				r.logger.Error("Failed to process object", zap.String("bucket", bucket), zap.String("key", key), zap.Error(err))
+			}
+			return false
+		}
+	}
+	return true
+}
+
+// pollBucket lists the prefix every poll interval and reads the objects last modified after
+// since, oldest first. A failed object stops the pass and is retried at the next poll.
+func (r *logsReceiver) pollBucket(ctx context.Context, since time.Time) {
+	// seen holds the keys already read whose LastModified equals since, as several objects can
+	// share a timestamp
+	seen := map[string]bool{}
+	ticker := time.NewTicker(r.cfg.S3.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		var objects []types.Object
+		var err error
+		pages := s3.NewListObjectsV2Paginator(r.s3, &s3.ListObjectsV2Input{
+			Bucket: aws.String(r.cfg.S3.Bucket),
+			Prefix: aws.String(r.cfg.S3.Prefix),
+		})
+		for pages.HasMorePages() {
+			var page *s3.ListObjectsV2Output
+			if page, err = pages.NextPage(ctx); err != nil {
+				break
+			}
+			for _, obj := range page.Contents {
+				modified := aws.ToTime(obj.LastModified)
+				if modified.After(since) || (modified.Equal(since) && !seen[aws.ToString(obj.Key)]) {
+					objects = append(objects, obj)
+				}
+			}
+		}
+		if err != nil {
+			if ctx.Err() == nil {
+				r.logger.Warn("Failed to list objects", zap.String("bucket", r.cfg.S3.Bucket), zap.Error(err))
+			}
+			continue
+		}
+
+		slices.SortStableFunc(objects, func(a, b types.Object) int {
+			return aws.ToTime(a.LastModified).Compare(aws.ToTime(b.LastModified))
+		})
+		for _, obj := range objects {
+			key := aws.ToString(obj.Key)
+			if err := r.processObject(ctx, r.cfg.S3.Bucket, key); err != nil {
+				if ctx.Err() == nil {
+					r.logger.Error("Failed to process object", zap.String("bucket", r.cfg.S3.Bucket), zap.String("key", key), zap.Error(err))
+				}
+				break
+			}
+			if modified := aws.ToTime(obj.LastModified); modified.After(since) {
+				since = modified
+				clear(seen)
+			}
+			seen[key] = true
+		}
+	}
+}
+
+// sleep waits for d or until ctx is cancelled
+func sleep(ctx context.Context, d time.Duration) {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+	case <-t.C:
+	}
+}